@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// fieldAliases maps legacy request field names, still sent by older
+// integrations, to their current names. An alias is accepted wherever the
+// current name is a field of the request (at any depth) and reported back
+// as a deprecation warning in the response meta.
+var fieldAliases = map[string]string{
+	"budget":    "budget_gbp",
+	"max_price": "max_price_gbp",
+	"min_eco":   "min_eco_score",
+}
+
+// ResponseMeta carries non-fatal notes about how a request was handled.
+type ResponseMeta struct {
+	Warnings []string `json:"warnings"`
+}
+
+// responseMeta returns the request's warnings, or nil when there are none so
+// that meta is omitted from the response.
+func responseMeta(ctx context.Context) *ResponseMeta {
+	o, ok := ctx.Value(outcomeKey{}).(*requestOutcome)
+	if !ok {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.warnings) == 0 {
+		return nil
+	}
+	return &ResponseMeta{Warnings: append([]string(nil), o.warnings...)}
+}
+
+func addWarnings(ctx context.Context, msgs ...string) {
+	if o, ok := ctx.Value(outcomeKey{}).(*requestOutcome); ok {
+		o.mu.Lock()
+		o.warnings = append(o.warnings, msgs...)
+		o.mu.Unlock()
+	}
+}
+
+// rewriteAliases renames legacy fields in raw to the names dst declares and
+// returns the rewritten body with one warning per alias used. raw is
+// returned unchanged when it uses no aliases.
+func rewriteAliases(raw []byte, dst any) ([]byte, []string, error) {
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return raw, nil, nil // let the real decode report the syntax error
+	}
+	var warnings []string
+	if err := renameAliases(doc, reflect.TypeOf(dst), "", &warnings); err != nil {
+		return nil, nil, err
+	}
+	if len(warnings) == 0 {
+		return raw, nil, nil
+	}
+	out, err := json.Marshal(doc)
+	return out, warnings, err
+}
+
+func renameAliases(v any, t reflect.Type, path string, warnings *[]string) error {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return nil
+	}
+	switch doc := v.(type) {
+	case []any:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return nil
+		}
+		base := strings.TrimSuffix(path, ".")
+		for i, item := range doc {
+			if err := renameAliases(item, t.Elem(), fmt.Sprintf("%s[%d].", base, i), warnings); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+		keys := make([]string, 0, len(doc))
+		for k := range doc {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if to, ok := fieldAliases[k]; ok {
+				if _, known := jsonFieldType(t, to); known {
+					if _, both := doc[to]; both {
+						return fmt.Errorf("both %q and %q given; send only %q", path+k, path+to, path+to)
+					}
+					doc[to] = doc[k]
+					delete(doc, k)
+					*warnings = append(*warnings, fmt.Sprintf("field %q is deprecated; use %q", path+k, path+to))
+					k = to
+				}
+			}
+			if ft, ok := jsonFieldType(t, k); ok {
+				if err := renameAliases(doc[k], ft, path+k+".", warnings); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonFieldType finds the field of struct t that decodes the JSON key name,
+// looking through embedded structs the way encoding/json does.
+func jsonFieldType(t reflect.Type, name string) (reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if f.Anonymous && tag == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if typ, ok := jsonFieldType(ft, name); ok {
+					return typ, true
+				}
+			}
+			continue
+		}
+		// embedded structs count even when their type is unexported, as in
+		// encoding/json; other unexported fields are never decoded
+		if !f.IsExported() {
+			continue
+		}
+		n, _, _ := strings.Cut(tag, ",")
+		if n == "-" {
+			continue
+		}
+		if n == "" {
+			n = f.Name
+		}
+		if strings.EqualFold(n, name) {
+			return f.Type, true
+		}
+	}
+	return nil, false
+}
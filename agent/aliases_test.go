@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+type aliasPerson struct {
+	Name      string  `json:"name"`
+	BudgetGBP float64 `json:"budget_gbp"`
+}
+
+type aliasFilters struct {
+	MaxPriceGBP float64 `json:"max_price_gbp"`
+}
+
+type aliasReq struct {
+	aliasFilters                // embedded: its fields are top-level keys
+	MinEcoScore  int            `json:"min_eco_score"`
+	People       []aliasPerson  `json:"people"`
+	Lead         *aliasPerson   `json:"lead"`
+	Extra        map[string]any `json:"extra"`
+}
+
+func TestRewriteAliases(t *testing.T) {
+	for _, tc := range []struct {
+		name, in, want string
+		warnings       []string
+		err            string
+	}{
+		{name: "no aliases", in: `{"min_eco_score": 50}`, want: `{"min_eco_score": 50}`},
+		{name: "top level", in: `{"min_eco": 50}`, want: `{"min_eco_score":50}`,
+			warnings: []string{`field "min_eco" is deprecated; use "min_eco_score"`}},
+		{name: "embedded struct", in: `{"max_price": 80}`, want: `{"max_price_gbp":80}`,
+			warnings: []string{`field "max_price" is deprecated; use "max_price_gbp"`}},
+		{name: "slice of structs", in: `{"people": [{"name": "a", "budget": 40}, {"name": "b", "budget_gbp": 60}]}`,
+			want:     `{"people":[{"budget_gbp":40,"name":"a"},{"budget_gbp":60,"name":"b"}]}`,
+			warnings: []string{`field "people[0].budget" is deprecated; use "people[0].budget_gbp"`}},
+		{name: "pointer to struct", in: `{"lead": {"budget": 10}}`, want: `{"lead":{"budget_gbp":10}}`,
+			warnings: []string{`field "lead.budget" is deprecated; use "lead.budget_gbp"`}},
+		{name: "alias where the current name isn't a field", in: `{"budget": 10}`, want: `{"budget": 10}`},
+		{name: "inside a map, which has no fields", in: `{"extra": {"budget": 10}}`, want: `{"extra": {"budget": 10}}`},
+		{name: "wrong shape is left for the decoder", in: `{"people": {"budget": 10}}`, want: `{"people": {"budget": 10}}`},
+		{name: "both names", in: `{"min_eco": 1, "min_eco_score": 2}`,
+			err: `both "min_eco" and "min_eco_score" given; send only "min_eco_score"`},
+		{name: "both names nested", in: `{"people": [{"budget": 1, "budget_gbp": 2}]}`,
+			err: `both "people[0].budget" and "people[0].budget_gbp" given`},
+		{name: "invalid JSON passes through", in: `{"min_eco": `, want: `{"min_eco": `},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out, warnings, err := rewriteAliases([]byte(tc.in), &aliasReq{})
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("err = %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tc.want {
+				t.Errorf("body = %s, want %s", out, tc.want)
+			}
+			if !reflect.DeepEqual(warnings, tc.warnings) {
+				t.Errorf("warnings = %q, want %q", warnings, tc.warnings)
+			}
+		})
+	}
+}
+
+func TestRewriteAliasesDecodes(t *testing.T) {
+	out, _, err := rewriteAliases([]byte(`{"min_eco": 50, "max_price": 80, "people": [{"budget": 40}]}`), &aliasReq{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req aliasReq
+	if err := json.Unmarshal(out, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.MinEcoScore != 50 || req.MaxPriceGBP != 80 || len(req.People) != 1 || req.People[0].BudgetGBP != 40 {
+		t.Errorf("decoded %+v", req)
+	}
+}
+
+func TestDecodeJSONLimitsBody(t *testing.T) {
+	useTestConfig(t, nil)
+	body := `{"query": "` + strings.Repeat("a", maxJSONBody) + `"}`
+	r := httptest.NewRequest("POST", "/search", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	var req SearchReq
+	err := decodeJSON(w, r, &req)
+	httpapi.BadRequest(w, err)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// AttrFilters are structured attribute constraints shared by /search and
+// /complete-outfit. Empty fields don't filter. Matching is case-insensitive.
+type AttrFilters struct {
+	Size     string `json:"size,omitempty"` // an in-stock variant in this size, see sizes.go
+	Color    string `json:"color,omitempty"`
+	Brand    string `json:"brand,omitempty"`
+	Material string `json:"material,omitempty"` // substring match, e.g. "cotton" matches "organic cotton"
+
+	// NearestSize also accepts products whose Size is sold out but a
+	// neighbouring size is in stock; those hits carry suggested_size.
+	NearestSize bool `json:"nearest_size,omitempty"`
+
+	// Certifications requires every listed eco label, e.g. ["organic", "b_corp"].
+	Certifications []string `json:"certifications,omitempty"`
+
+	// VerifiedEcoOnly skips products whose eco score was estimated rather
+	// than supplied by the merchant.
+	VerifiedEcoOnly bool `json:"verified_eco_only,omitempty"`
+
+	// Attributes filters on any indexed attribute, e.g. {"material": "linen",
+	// "sleeve": "long"}. A list value matches any of its entries.
+	Attributes map[string]any `json:"attributes,omitempty"`
+
+	// Negative constraints, e.g. "no leather", "not pink"; see exclusions.go.
+	ExcludeTerms     []string `json:"exclude_terms,omitempty"`     // whole words in the title, colors, material or any attribute
+	ExcludeMaterials []string `json:"exclude_materials,omitempty"` // substring match, so "wool" excludes "merino wool"
+}
+
+// Limits on free-form attribute filters.
+const (
+	maxAttributeFilters = 10
+	maxAttributeValues  = 20
+)
+
+var attributeKeyPattern = regexp.MustCompile(`^[a-z0-9_]{1,40}$`)
+
+// EcoLabel is a sustainability label or certification the UI renders as a badge.
+type EcoLabel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ecoLabelNames are the labels we recognise, keyed by ID.
+var ecoLabelNames = map[string]string{
+	"recycled":   "Recycled materials",
+	"organic":    "Organic",
+	"b_corp":     "B Corp",
+	"fair_trade": "Fair Trade",
+	"gots":       "GOTS certified",
+	"fsc":        "FSC certified",
+	"vegan":      "Vegan",
+	"bluesign":   "bluesign",
+	"oeko_tex":   "OEKO-TEX",
+}
+
+// ecoLabelAliases maps free-form tag/metadata spellings to label IDs.
+var ecoLabelAliases = map[string]string{
+	"recycled": "recycled", "recycled materials": "recycled",
+	"organic": "organic", "organic cotton": "organic",
+	"b corp": "b_corp", "b-corp": "b_corp", "bcorp": "b_corp", "b_corp": "b_corp",
+	"fair trade": "fair_trade", "fairtrade": "fair_trade", "fair_trade": "fair_trade",
+	"gots": "gots", "fsc": "fsc", "vegan": "vegan", "bluesign": "bluesign",
+	"oeko-tex": "oeko_tex", "oeko tex": "oeko_tex", "oekotex": "oeko_tex", "oeko_tex": "oeko_tex",
+}
+
+// ecoLabelID normalises a label spelling, returning "" if it isn't recognised.
+func ecoLabelID(s string) string {
+	return ecoLabelAliases[strings.ToLower(strings.TrimSpace(s))]
+}
+
+func ecoLabelsFromIDs(ids []string) []EcoLabel {
+	out := make([]EcoLabel, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := ecoLabelNames[id]; ok {
+			out = append(out, EcoLabel{ID: id, Name: name})
+		}
+	}
+	return out
+}
+
+func (f AttrFilters) validate() error {
+	for _, c := range f.Certifications {
+		if ecoLabelID(c) == "" {
+			ids := make([]string, 0, len(ecoLabelNames))
+			for id := range ecoLabelNames {
+				ids = append(ids, id)
+			}
+			sort.Strings(ids)
+			return httpapi.InvalidField("certifications", "unknown certification %q; known: %s", c, strings.Join(ids, ", "))
+		}
+	}
+	if len(f.Attributes) > maxAttributeFilters {
+		return httpapi.InvalidField("attributes", "at most %d attributes filters", maxAttributeFilters)
+	}
+	for k, v := range f.Attributes {
+		if !attributeKeyPattern.MatchString(attributeKey(k)) {
+			return httpapi.InvalidField("attributes", "attributes: invalid key %q", k)
+		}
+		vals, ok := attributeValues(v)
+		if !ok || len(vals) == 0 {
+			return httpapi.InvalidField("attributes."+k, "attributes.%s must be a string, number, boolean, or a list of them", k)
+		}
+		if len(vals) > maxAttributeValues {
+			return httpapi.InvalidField("attributes."+k, "attributes.%s: at most %d values", k, maxAttributeValues)
+		}
+	}
+	if f.NearestSize && strings.TrimSpace(f.Size) == "" {
+		return httpapi.InvalidField("size", "nearest_size needs size")
+	}
+	if err := validateExclusions("exclude_terms", f.ExcludeTerms); err != nil {
+		return err
+	}
+	return validateExclusions("exclude_materials", f.ExcludeMaterials)
+}
+
+// attributeKey normalises an attribute name: "Sleeve Length" -> "sleeve_length".
+func attributeKey(k string) string {
+	k = strings.ToLower(strings.TrimSpace(k))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(k)
+}
+
+// attributeValues flattens a scalar or list of scalars into normalised
+// strings; ok is false for objects and nested lists.
+func attributeValues(v any) (vals []string, ok bool) {
+	scalar := func(x any) (string, bool) {
+		switch t := x.(type) {
+		case string:
+			return strings.ToLower(strings.TrimSpace(t)), true
+		case float64:
+			return strconv.FormatFloat(t, 'f', -1, 64), true
+		case int:
+			return strconv.Itoa(t), true
+		case bool:
+			return strconv.FormatBool(t), true
+		}
+		return "", false
+	}
+	if list, isList := v.([]any); isList {
+		for _, x := range list {
+			s, ok := scalar(x)
+			if !ok {
+				return nil, false
+			}
+			vals = append(vals, s)
+		}
+		return normalizeValues(vals), true
+	}
+	s, ok := scalar(v)
+	if !ok {
+		return nil, false
+	}
+	return normalizeValues([]string{s}), true
+}
+
+// productAttrs are the structured attributes extracted at index time.
+type productAttrs struct {
+	Sizes     []string
+	Colors    []string
+	Brand     string
+	Material  string
+	EcoLabels []string // label IDs, see ecoLabelNames
+	GiftWrap  bool
+	FinalSale bool
+
+	// All is every attribute by normalised key, including the fields above;
+	// stored as the attributes JSONB column.
+	All map[string][]string
+}
+
+// extractAttributes derives attributes from catalog product options, the
+// material field, tags, and metadata. Metadata wins when a merchant set it explicitly.
+func extractAttributes(options []catalog.Option, material string, tags []string, meta map[string]any) productAttrs {
+	var a productAttrs
+	for _, o := range options {
+		values := o.Values
+		switch t := strings.ToLower(strings.TrimSpace(o.Title)); {
+		case isSizeOption(t):
+			a.Sizes = append(a.Sizes, values...)
+		case t == "color" || t == "colour" || t == "colors" || t == "colours":
+			a.Colors = append(a.Colors, values...)
+		}
+	}
+	a.Material = material
+
+	if s := catalog.MetaString(meta, "brand"); s != "" {
+		a.Brand = s
+	}
+	if s := catalog.MetaString(meta, "material"); s != "" {
+		a.Material = s
+	}
+	if s := catalog.MetaString(meta, "color"); s != "" {
+		a.Colors = append(a.Colors, s)
+	}
+
+	// eco labels: explicit metadata, product tags, and material wording
+	var labels []string
+	switch v := meta["eco_labels"].(type) {
+	case string:
+		labels = append(labels, strings.Split(v, ",")...)
+	case []any:
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				labels = append(labels, s)
+			}
+		}
+	}
+	labels = append(labels, tags...)
+	for _, word := range []string{"recycled", "organic"} {
+		if strings.Contains(strings.ToLower(a.Material), word) {
+			labels = append(labels, word)
+		}
+	}
+	for _, l := range labels {
+		if id := ecoLabelID(l); id != "" {
+			a.EcoLabels = append(a.EcoLabels, id)
+		}
+	}
+	a.EcoLabels = normalizeValues(a.EcoLabels)
+
+	a.GiftWrap = metaBool(meta, "gift_wrap")
+	a.FinalSale = metaBool(meta, "final_sale")
+
+	for i, s := range a.Sizes {
+		a.Sizes[i] = normalizeSize(s)
+	}
+	a.Sizes = normalizeValues(a.Sizes)
+	a.Colors = normalizeValues(a.Colors)
+	a.Brand = strings.ToLower(strings.TrimSpace(a.Brand))
+	a.Material = strings.ToLower(strings.TrimSpace(a.Material))
+
+	// everything else the catalog knows, for free-form attribute filters
+	a.All = map[string][]string{}
+	for k, v := range meta {
+		if vals, ok := attributeValues(v); ok && len(vals) > 0 {
+			a.All[attributeKey(k)] = vals
+		}
+	}
+	for _, o := range options {
+		if vals := normalizeValues(o.Values); len(vals) > 0 {
+			a.All[attributeKey(o.Title)] = vals
+		}
+	}
+	for k, vals := range map[string][]string{
+		"size": a.Sizes, "color": a.Colors, "brand": {a.Brand}, "material": {a.Material}, "eco_labels": a.EcoLabels,
+	} {
+		if vals = normalizeValues(vals); len(vals) > 0 {
+			a.All[k] = vals
+		}
+	}
+	return a
+}
+
+func metaBool(m map[string]any, key string) bool {
+	switch v := m[key].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true" || v == "yes" || v == "1"
+	}
+	return false
+}
+
+// normalizeValues lowercases, trims, and de-duplicates attribute values.
+func normalizeValues(in []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, v := range in {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// sqlArgs returns the filter values in the order searchHits binds them.
+func (f AttrFilters) sqlArgs() []any {
+	var certs any
+	if len(f.Certifications) > 0 {
+		ids := make([]string, 0, len(f.Certifications))
+		for _, c := range f.Certifications {
+			ids = append(ids, ecoLabelID(c))
+		}
+		certs = ids
+	}
+	return []any{
+		search.NullList(f.acceptedSizes()),
+		search.NullText(strings.ToLower(strings.TrimSpace(f.Color))),
+		search.NullText(strings.ToLower(strings.TrimSpace(f.Brand))),
+		search.NullContains(strings.ToLower(strings.TrimSpace(f.Material))),
+		certs,
+	}
+}
+
+// attributesSQL compiles Attributes and the exclusions into WHERE clauses
+// whose keys and values are bound as parameters from $next on; only the
+// fixed clause text is ever formatted into the query.
+func (f AttrFilters) attributesSQL(next int) (string, []any) {
+	keys := make([]string, 0, len(f.Attributes))
+	for k := range f.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	var args []any
+	for _, k := range keys {
+		vals, _ := attributeValues(f.Attributes[k])
+		fmt.Fprintf(&b, "\n  AND attributes -> $%d::text ?| $%d::text[]", next, next+1)
+		args = append(args, attributeKey(k), vals)
+		next += 2
+	}
+	excl, exclArgs := f.exclusionsSQL(next)
+	b.WriteString(excl)
+	return b.String(), append(args, exclArgs...)
+}
+
+// cardLines renders attributes for the embedded product card.
+func (a productAttrs) cardLines() string {
+	var b strings.Builder
+	if a.Brand != "" {
+		b.WriteString("\nBRAND: " + a.Brand)
+	}
+	if a.Material != "" {
+		b.WriteString("\nMATERIAL: " + a.Material)
+	}
+	if len(a.Colors) > 0 {
+		b.WriteString("\nCOLORS: " + strings.Join(a.Colors, ", "))
+	}
+	if len(a.Sizes) > 0 {
+		b.WriteString("\nSIZES: " + strings.Join(a.Sizes, ", "))
+	}
+	if len(a.EcoLabels) > 0 {
+		names := make([]string, 0, len(a.EcoLabels))
+		for _, l := range ecoLabelsFromIDs(a.EcoLabels) {
+			names = append(names, l.Name)
+		}
+		b.WriteString("\nCERTIFICATIONS: " + strings.Join(names, ", "))
+	}
+	return b.String()
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// Audit log: with CSA_AUDIT_LOG on, every /search, /complete-outfit and
+// /explain-outfit answer is written to recommendation_audit with the
+// request as the handler read it, the facts that shaped the answer (intent
+// routing, session constraints, degraded search, cache use) and each hit
+// with its similarity and ranking scores, so a merchandiser can see why a
+// product was shown. GET /admin/audit lists the tenant's entries, newest
+// first. Entries are deleted after CSA_AUDIT_RETENTION; sandbox traffic
+// isn't recorded.
+
+// Audited sources.
+const (
+	auditSearch  = "search"
+	auditOutfit  = "complete-outfit"
+	auditExplain = "explain-outfit"
+)
+
+var auditSources = []string{auditSearch, auditOutfit, auditExplain}
+
+const (
+	defaultAuditLimit = 20
+	maxAuditLimit     = 100
+)
+
+// auditSweepInterval is how often expired entries are deleted.
+const auditSweepInterval = time.Hour
+
+// AuditEntry is one recorded answer.
+type AuditEntry struct {
+	ID          int64           `json:"id"`
+	At          time.Time       `json:"at"`
+	Source      string          `json:"source"`
+	RequestID   string          `json:"request_id,omitempty"`
+	SessionID   string          `json:"session_id,omitempty"`
+	KeyID       string          `json:"key_id,omitempty"` // "" for anonymous callers
+	Query       string          `json:"query,omitempty"`
+	Request     json.RawMessage `json:"request"`
+	Details     map[string]any  `json:"details,omitempty"`
+	Hits        []AuditHit      `json:"hits"`
+	Explanation []string        `json:"explanation,omitempty"`
+}
+
+// AuditHit is one product an answer showed, in the order shown.
+type AuditHit struct {
+	ProductID  string          `json:"product_id"`
+	Slot       string          `json:"slot,omitempty"` // outfits: the slot it filled
+	Rank       int             `json:"rank"`           // 1-based, within its slot
+	Title      string          `json:"title"`
+	PriceGBP   float64         `json:"price_gbp"`
+	EcoScore   int             `json:"eco_score"`
+	Similarity float64         `json:"similarity"`
+	Scores     *ScoreBreakdown `json:"scores,omitempty"`
+	Reason     string          `json:"reason,omitempty"`
+}
+
+// auditHits lists hits in rank order for slot ("" outside outfits).
+func auditHits(slot string, hits []Hit) []AuditHit {
+	out := make([]AuditHit, len(hits))
+	for i, h := range hits {
+		out[i] = AuditHit{ProductID: h.ProductID, Slot: slot, Rank: i + 1, Title: h.Title, PriceGBP: h.PriceGBP,
+			EcoScore: h.EcoScore, Similarity: h.Similarity, Scores: h.Scores, Reason: h.Reason}
+	}
+	return out
+}
+
+// outfitAuditHits lists every slot's hits of an outfit.
+func outfitAuditHits(resp CompleteOutfitResp) []AuditHit {
+	var out []AuditHit
+	for _, sr := range resp.Results {
+		out = append(out, auditHits(sr.Slot, sr.Hits)...)
+	}
+	return out
+}
+
+// recordAudit writes e for the request's tenant. Failures are logged, not
+// returned: the shopper's answer doesn't depend on the audit trail.
+func recordAudit(ctx context.Context, pool *pgxpool.Pool, e AuditEntry) {
+	if !cfg().AuditLog || sandboxFrom(ctx) {
+		return
+	}
+	if p := principalFrom(ctx); p != nil {
+		e.KeyID = p.KeyID
+	}
+	if s := sessionFrom(ctx); s != nil {
+		e.SessionID = s.ID
+	}
+	if e.Hits == nil {
+		e.Hits = []AuditHit{}
+	}
+	ids := make([]string, 0, len(e.Hits))
+	for _, h := range e.Hits {
+		if !slices.Contains(ids, h.ProductID) {
+			ids = append(ids, h.ProductID)
+		}
+	}
+	hits, _ := json.Marshal(e.Hits)
+	details, _ := json.Marshal(e.Details)
+	if e.Request == nil {
+		e.Request = json.RawMessage(`{}`)
+	}
+	_, err := pool.Exec(ctx, `
+INSERT INTO recommendation_audit (tenant_id, source, request_id, session_id, key_id, query, request, details, hits, product_ids, explanation, residency_zone)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+`, tenantFromContext(ctx), e.Source, requestID(ctx), e.SessionID, e.KeyID, e.Query, []byte(e.Request), details, hits, ids,
+		append([]string{}, e.Explanation...), residencyFrom(ctx))
+	if err != nil {
+		slog.WarnContext(ctx, "audit: record", "source", e.Source, "err", err)
+	}
+}
+
+// auditRequest is v as the entry's request document.
+func auditRequest(v any) json.RawMessage {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+type auditFilter struct {
+	Source    string
+	ProductID string
+	SessionID string
+	Query     string // substring of the query, case-insensitive
+	Since     *time.Time
+	Until     *time.Time
+	Before    int64 // cursor: entries with a smaller id
+	Limit     int
+}
+
+func parseAuditFilter(q url.Values) (auditFilter, error) {
+	f := auditFilter{Source: q.Get("source"), ProductID: q.Get("product_id"), SessionID: q.Get("session_id"),
+		Query: strings.TrimSpace(q.Get("q")), Limit: defaultAuditLimit}
+	if f.Source != "" && !slices.Contains(auditSources, f.Source) {
+		return f, fmt.Errorf("source must be one of %v", auditSources)
+	}
+	for _, t := range []struct {
+		name string
+		dst  **time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if v := q.Get(t.name); v != "" {
+			ts, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Errorf("%s must be an RFC 3339 time", t.name)
+			}
+			*t.dst = &ts
+		}
+	}
+	if v := q.Get("cursor"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return f, fmt.Errorf("invalid cursor")
+		}
+		f.Before = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxAuditLimit {
+			return f, fmt.Errorf("limit must be 1-%d", maxAuditLimit)
+		}
+		f.Limit = n
+	}
+	return f, nil
+}
+
+// AuditPage is a page of GET /admin/audit.
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// auditLog lists tenantID's entries matching f, newest first.
+func auditLog(ctx context.Context, pool *pgxpool.Pool, tenantID string, f auditFilter) (AuditPage, error) {
+	page := AuditPage{Entries: []AuditEntry{}}
+	var before any
+	if f.Before > 0 {
+		before = f.Before
+	}
+	rows, err := pool.Query(ctx, `
+SELECT id, created_at, source, request_id, session_id, key_id, query, request, details, hits, explanation
+FROM recommendation_audit
+WHERE tenant_id = $1
+  AND ($2::text IS NULL OR source = $2)
+  AND ($3::text IS NULL OR product_ids @> ARRAY[$3::text])
+  AND ($4::text IS NULL OR session_id = $4)
+  AND ($5::text IS NULL OR query ILIKE $5)
+  AND ($6::timestamptz IS NULL OR created_at >= $6)
+  AND ($7::timestamptz IS NULL OR created_at < $7)
+  AND ($8::bigint IS NULL OR id < $8)
+ORDER BY id DESC
+LIMIT $9
+`, tenantID, search.NullText(f.Source), search.NullText(f.ProductID), search.NullText(f.SessionID), search.NullContains(f.Query), f.Since, f.Until, before, f.Limit+1)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e AuditEntry
+		var request, details, hits []byte
+		if err := rows.Scan(&e.ID, &e.At, &e.Source, &e.RequestID, &e.SessionID, &e.KeyID, &e.Query, &request, &details, &hits, &e.Explanation); err != nil {
+			return page, err
+		}
+		e.Request = request
+		if err := json.Unmarshal(details, &e.Details); err != nil {
+			return page, fmt.Errorf("audit: entry %d: %w", e.ID, err)
+		}
+		if err := json.Unmarshal(hits, &e.Hits); err != nil {
+			return page, fmt.Errorf("audit: entry %d: %w", e.ID, err)
+		}
+		page.Entries = append(page.Entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+	if len(page.Entries) > f.Limit {
+		page.Entries = page.Entries[:f.Limit]
+		page.NextCursor = strconv.FormatInt(page.Entries[f.Limit-1].ID, 10)
+	}
+	return page, nil
+}
+
+// auditSweepLoop deletes entries older than CSA_AUDIT_RETENTION, on one
+// replica per interval.
+func auditSweepLoop(ctx context.Context, pool *pgxpool.Pool) {
+	t := time.NewTicker(auditSweepInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		slot := time.Now().Truncate(auditSweepInterval)
+		if _, err := runScheduled(ctx, pool, "audit_sweep", slot, func(ctx context.Context) error {
+			_, err := pool.Exec(ctx, `DELETE FROM recommendation_audit WHERE created_at < now() - make_interval(secs => $1)`,
+				cfg().AuditRetention.Seconds())
+			return err
+		}); err != nil {
+			slog.ErrorContext(ctx, "audit: sweep", "err", err)
+		}
+	}
+}
+
+// searchAudit is the entry for a /search answer.
+func searchAudit(req SearchReq, query string, resp SearchResp, cacheHit bool) AuditEntry {
+	d := map[string]any{"cache_hit": cacheHit}
+	if resp.Intent != nil {
+		d["intent"] = resp.Intent
+	}
+	if len(resp.RoutedCategories) > 0 {
+		d["routed_categories"] = resp.RoutedCategories
+	}
+	if resp.Degraded {
+		d["degraded"] = true
+	}
+	if len(resp.SessionConstraints) > 0 {
+		d["session_constraints"] = resp.SessionConstraints
+	}
+	if resp.ResponseID != "" {
+		d["response_id"] = resp.ResponseID
+	}
+	return AuditEntry{Source: auditSearch, Query: query, Request: auditRequest(req), Details: d, Hits: auditHits("", resp.Hits)}
+}
+
+// outfitAudit is the entry for a /complete-outfit answer.
+func outfitAudit(req CompleteOutfitReq, resp CompleteOutfitResp, cacheHit bool) AuditEntry {
+	d := map[string]any{"cache_hit": cacheHit, "mission": resp.Mission, "missing_slots": resp.MissingSlots}
+	if resp.Intent != nil {
+		d["intent"] = resp.Intent
+	}
+	if len(resp.SessionConstraints) > 0 {
+		d["session_constraints"] = resp.SessionConstraints
+	}
+	var slots []map[string]any
+	for _, sr := range resp.Results {
+		s := map[string]any{"slot": sr.Slot}
+		if sr.Reason != "" {
+			s["reason"] = sr.Reason
+		}
+		if sr.Confidence != nil {
+			s["confidence"] = sr.Confidence
+		}
+		slots = append(slots, s)
+	}
+	if slots != nil {
+		d["slots"] = slots
+	}
+	return AuditEntry{Source: auditOutfit, Query: req.Query, Request: auditRequest(req), Details: d, Hits: outfitAuditHits(resp)}
+}
+
+// explainAudit is the entry for an /explain-outfit answer. The outfit
+// itself is summarized; its hits are listed as hits.
+func explainAudit(outfit CompleteOutfitResp, engine string, bullets []string) AuditEntry {
+	req := map[string]any{"mission": outfit.Mission, "budget_gbp": outfit.BudgetGBP, "min_eco_score": outfit.MinEcoScore,
+		"missing_slots": outfit.MissingSlots}
+	return AuditEntry{Source: auditExplain, Request: auditRequest(req), Details: map[string]any{"engine": engine},
+		Hits: outfitAuditHits(outfit), Explanation: bullets}
+}
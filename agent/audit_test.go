@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseAuditFilter(t *testing.T) {
+	f, err := parseAuditFilter(url.Values{"source": {"search"}, "product_id": {"p1"}, "since": {"2026-10-01T00:00:00Z"}, "cursor": {"42"}, "limit": {"5"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Source != auditSearch || f.ProductID != "p1" || f.Since == nil || f.Before != 42 || f.Limit != 5 {
+		t.Errorf("filter = %+v", f)
+	}
+	for _, bad := range []url.Values{
+		{"source": {"substitutes"}},
+		{"since": {"yesterday"}},
+		{"cursor": {"-1"}},
+		{"limit": {"101"}},
+	} {
+		if _, err := parseAuditFilter(bad); err == nil {
+			t.Errorf("%v: want an error", bad)
+		}
+	}
+}
+
+func TestOutfitAuditListsSlotsInOrder(t *testing.T) {
+	resp := CompleteOutfitResp{Mission: "smart_casual", MissingSlots: []string{"shoes", "top"}, Results: []SlotRecs{
+		{Slot: "shoes", Reason: "closest to the mission", Hits: []Hit{{ProductID: "s1", Similarity: 0.8}, {ProductID: "s2", Similarity: 0.7}}},
+		{Slot: "top", Hits: []Hit{{ProductID: "t1", Similarity: 0.9, Scores: &ScoreBreakdown{Semantic: 0.9, Total: 0.85}}}},
+	}}
+	e := outfitAudit(CompleteOutfitReq{Mission: "smart_casual", Query: "wedding"}, resp, true)
+	if e.Source != auditOutfit || e.Query != "wedding" || len(e.Hits) != 3 {
+		t.Fatalf("entry = %+v", e)
+	}
+	if h := e.Hits[1]; h.ProductID != "s2" || h.Slot != "shoes" || h.Rank != 2 {
+		t.Errorf("second hit = %+v", h)
+	}
+	if h := e.Hits[2]; h.Slot != "top" || h.Rank != 1 || h.Scores == nil || h.Scores.Total != 0.85 {
+		t.Errorf("top hit = %+v", h)
+	}
+	if e.Details["cache_hit"] != true || e.Details["mission"] != "smart_casual" {
+		t.Errorf("details = %v", e.Details)
+	}
+
+	x := explainAudit(resp, explainEngineTemplate, []string{"Fits the budget"})
+	if len(x.Hits) != 3 || x.Explanation[0] != "Fits the budget" || x.Details["engine"] != explainEngineTemplate {
+		t.Errorf("explain entry = %+v", x)
+	}
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// API key scopes. admin implies write, write implies read.
+const (
+	scopeRead  = "read"
+	scopeWrite = "write"
+	scopeAdmin = "admin"
+)
+
+var scopeRank = map[string]int{scopeRead: 1, scopeWrite: 2, scopeAdmin: 3}
+
+const apiKeyPrefix = "csa_"
+
+var errInvalidAPIKey = errors.New("invalid or revoked API key")
+
+// principal is the authenticated caller stored in the request context.
+type principal struct {
+	KeyID    string
+	TenantID string
+	Scopes   []string
+	Sandbox  bool // runs as sandboxTenant; see sandbox.go
+}
+
+// bootstrapKeyID is the KeyID of the CSA_ADMIN_API_KEY principal.
+const bootstrapKeyID = "bootstrap"
+
+// bootstrap reports whether p is the CSA_ADMIN_API_KEY principal, the only
+// one allowed to act across tenants.
+func (p *principal) bootstrap() bool {
+	return p != nil && p.KeyID == bootstrapKeyID
+}
+
+func (p *principal) has(scope string) bool {
+	if p == nil {
+		return false
+	}
+	for _, s := range p.Scopes {
+		if scopeRank[s] >= scopeRank[scope] {
+			return true
+		}
+	}
+	return false
+}
+
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	TenantID   string     `json:"tenant_id"`
+	Scopes     []string   `json:"scopes"`
+	Prefix     string     `json:"prefix"`
+	Sandbox    bool       `json:"sandbox,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CreateAPIKeyReq struct {
+	Name     string   `json:"name"`
+	TenantID string   `json:"tenant_id"` // defaults to the caller's tenant
+	Scopes   []string `json:"scopes"`
+	Sandbox  bool     `json:"sandbox,omitempty"` // read-only key for the sandbox catalog
+}
+
+type CreateAPIKeyResp struct {
+	APIKey
+	Key string `json:"key"` // plaintext, only ever returned once
+}
+
+// keyCache avoids a DB round trip per request. Entries expire so revocations
+// made by other replicas take effect within keyCacheTTL.
+const keyCacheTTL = time.Minute
+
+type cachedKey struct {
+	p       *principal
+	expires time.Time
+}
+
+var keyCache sync.Map // key hash -> cachedKey
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func newAPIKey() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return apiKeyPrefix + hex.EncodeToString(b)
+}
+
+// apiKeyFromRequest reads "Authorization: Bearer <key>" or "X-API-Key".
+func apiKeyFromRequest(r *http.Request) string {
+	if k := strings.TrimSpace(r.Header.Get("X-API-Key")); k != "" {
+		return k
+	}
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+	}
+	return ""
+}
+
+// authenticate resolves a presented key to a principal. The bootstrap key
+// from CSA_ADMIN_API_KEY is an admin for whichever tenant X-Tenant-ID names.
+func authenticate(ctx context.Context, pool *pgxpool.Pool, r *http.Request, key string) (*principal, error) {
+	if cfg().AdminAPIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(cfg().AdminAPIKey)) == 1 {
+		tenant := strings.TrimSpace(r.Header.Get("X-Tenant-ID"))
+		if tenant == "" {
+			tenant = defaultTenant
+		}
+		return &principal{KeyID: bootstrapKeyID, TenantID: tenant, Scopes: []string{scopeAdmin}}, nil
+	}
+
+	hash := hashAPIKey(key)
+	if c, ok := keyCache.Load(hash); ok && time.Now().Before(c.(cachedKey).expires) {
+		return checkSandbox(c.(cachedKey).p)
+	}
+
+	p := &principal{}
+	err := pool.QueryRow(ctx, `
+UPDATE api_keys SET last_used_at=now()
+WHERE key_hash=$1 AND revoked_at IS NULL
+RETURNING id::text, tenant_id, scopes, sandbox
+`, hash).Scan(&p.KeyID, &p.TenantID, &p.Scopes, &p.Sandbox)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.Sandbox {
+		// the key row keeps its creator's tenant so they can list and revoke it
+		p.TenantID = sandboxTenant
+	}
+	keyCache.Store(hash, cachedKey{p: p, expires: time.Now().Add(keyCacheTTL)})
+	return checkSandbox(p)
+}
+
+// checkSandbox rejects sandbox keys while CSA_SANDBOX is off.
+func checkSandbox(p *principal) (*principal, error) {
+	if p.Sandbox && !cfg().Sandbox {
+		return nil, fmt.Errorf("%w: %w", errInvalidAPIKey, errSandboxDisabled)
+	}
+	return p, nil
+}
+
+func principalFrom(ctx context.Context) *principal {
+	p, _ := ctx.Value(ctxPrincipal).(*principal)
+	return p
+}
+
+// requireScope guards a route. Read routes are open unless
+// CSA_REQUIRE_READ_AUTH is set; write and admin routes always need a key.
+func requireScope(scope string, h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if scope == scopeRead && !cfg().RequireReadAuth {
+			h(w, r)
+			return
+		}
+		p := principalFrom(r.Context())
+		if p == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="csa"`)
+			httpapi.WriteError(w, "API key required", http.StatusUnauthorized)
+			return
+		}
+		if !p.has(scope) {
+			httpapi.WriteError(w, fmt.Sprintf("API key lacks %q scope", scope), http.StatusForbidden)
+			return
+		}
+		h(w, r)
+	})
+}
+
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("scopes is required (read, write, admin)")
+	}
+	for _, s := range scopes {
+		if _, ok := scopeRank[s]; !ok {
+			return fmt.Errorf("unknown scope %q (read, write, admin)", s)
+		}
+	}
+	return nil
+}
+
+func createAPIKey(ctx context.Context, pool *pgxpool.Pool, req CreateAPIKeyReq) (CreateAPIKeyResp, error) {
+	key := newAPIKey()
+	resp := CreateAPIKeyResp{Key: key}
+	resp.Name, resp.TenantID, resp.Scopes = req.Name, req.TenantID, slices.Compact(slices.Sorted(slices.Values(req.Scopes)))
+	resp.Prefix, resp.Sandbox = key[:len(apiKeyPrefix)+8], req.Sandbox
+
+	err := pool.QueryRow(ctx, `
+INSERT INTO api_keys (name, tenant_id, scopes, key_hash, prefix, sandbox)
+VALUES ($1,$2,$3,$4,$5,$6)
+RETURNING id::text, created_at
+`, resp.Name, resp.TenantID, resp.Scopes, hashAPIKey(key), resp.Prefix, resp.Sandbox).Scan(&resp.ID, &resp.CreatedAt)
+	return resp, err
+}
+
+func listAPIKeys(ctx context.Context, pool *pgxpool.Pool, tenantID string) ([]APIKey, error) {
+	rows, err := pool.Query(ctx, `
+SELECT id::text, name, tenant_id, scopes, prefix, sandbox, created_at, last_used_at, revoked_at
+FROM api_keys
+WHERE tenant_id=$1
+ORDER BY created_at
+`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []APIKey{}
+	for rows.Next() {
+		var k APIKey
+		if err := rows.Scan(&k.ID, &k.Name, &k.TenantID, &k.Scopes, &k.Prefix, &k.Sandbox, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// revokeAPIKey marks a key revoked and drops it from the local cache.
+func revokeAPIKey(ctx context.Context, pool *pgxpool.Pool, tenantID, id string) (bool, error) {
+	var hash string
+	err := pool.QueryRow(ctx, `
+UPDATE api_keys SET revoked_at=now()
+WHERE id::text=$1 AND tenant_id=$2 AND revoked_at IS NULL
+RETURNING key_hash
+`, id, tenantID).Scan(&hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	keyCache.Delete(hash)
+	return true, nil
+}
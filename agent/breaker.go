@@ -0,0 +1,188 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Circuit breakers: each external dependency (OpenAI, Azure OpenAI, Medusa)
+// has one, wrapped around httpClient. CSA_BREAKER_FAILURES consecutive
+// failures (transport errors, 429s and 5xx answers) open it, and calls to
+// the dependency then fail at once with errCircuitOpen instead of waiting
+// on a service that is down. Callers already fall back on those errors:
+// /search to keyword matching, explanations to the template, outfit
+// intents to the explicit fields. After CSA_BREAKER_COOLDOWN the breaker
+// half-opens and lets one call through as a probe: success closes it,
+// failure opens it for another cooldown. States are in /healthz/ready.
+
+var errCircuitOpen = errors.New("circuit breaker open")
+
+// Breaker states.
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half_open"
+)
+
+// Dependencies with a breaker; also their /healthz/ready check names.
+const (
+	depOpenAI      = "openai"
+	depAzureOpenAI = "azure_openai"
+	depMedusa      = "medusa"
+)
+
+type breaker struct {
+	name string
+
+	mu       sync.Mutex
+	state    string
+	failures int // consecutive
+	openedAt time.Time
+	probing  bool // the half-open probe is in flight
+}
+
+// BreakerStatus is one breaker in the /healthz/ready report.
+type BreakerStatus struct {
+	State               string     `json:"state"` // closed | open | half_open
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	ProbeAt             *time.Time `json:"probe_at,omitempty"` // when an open breaker lets a probe through
+}
+
+var breakers = map[string]*breaker{
+	depOpenAI:      {name: depOpenAI, state: breakerClosed},
+	depAzureOpenAI: {name: depAzureOpenAI, state: breakerClosed},
+	depMedusa:      {name: depMedusa, state: breakerClosed},
+}
+
+// breakerFor is the breaker guarding calls to host, nil for hosts that are
+// not a guarded dependency.
+func breakerFor(host string) *breaker {
+	return breakers[dependencyFor(host)]
+}
+
+// dependencyFor names the dependency served at host: one of the dep
+// constants, or "" for any other host.
+func dependencyFor(host string) string {
+	for name, base := range map[string]string{
+		depOpenAI:      cfg().OpenAI.BaseURL,
+		depAzureOpenAI: cfg().Azure.Endpoint,
+		depMedusa:      cfg().Medusa.BaseURL,
+	} {
+		if u, err := url.Parse(base); err == nil && u.Host != "" && u.Host == host {
+			return name
+		}
+	}
+	return ""
+}
+
+// allow reports whether a call may go ahead, moving an open breaker whose
+// cooldown is over to half-open for the call to probe.
+func (b *breaker) allow() error {
+	if cfg().BreakerFailures == 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if wait := time.Until(b.openedAt.Add(cfg().BreakerCooldown)); wait > 0 {
+			return fmt.Errorf("%s: %w, next probe in %s", b.name, errCircuitOpen, wait.Round(time.Second))
+		}
+		b.state, b.probing = breakerHalfOpen, true
+		slog.Info("breaker: half-open, probing", "dependency", b.name)
+	case breakerHalfOpen:
+		if b.probing {
+			return fmt.Errorf("%s: %w, probe in flight", b.name, errCircuitOpen)
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// done records the outcome of an allowed call; err nil is a success.
+func (b *breaker) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if err == nil {
+		if b.state != breakerClosed {
+			slog.Info("breaker: closed", "dependency", b.name)
+		}
+		b.state, b.failures = breakerClosed, 0
+		return
+	}
+	b.failures++
+	switch {
+	case b.state == breakerHalfOpen:
+		b.state, b.openedAt = breakerOpen, time.Now()
+		slog.Warn("breaker: probe failed, reopened", "dependency", b.name, "err", err)
+	case b.state == breakerClosed && cfg().BreakerFailures > 0 && b.failures >= cfg().BreakerFailures:
+		b.state, b.openedAt = breakerOpen, time.Now()
+		slog.Warn("breaker: opened", "dependency", b.name, "failures", b.failures, "err", err)
+	}
+}
+
+// release ends an allowed call that says nothing about the dependency,
+// such as one its caller cancelled.
+func (b *breaker) release() {
+	b.mu.Lock()
+	b.probing = false
+	b.mu.Unlock()
+}
+
+func (b *breaker) status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := BreakerStatus{State: b.state, ConsecutiveFailures: b.failures}
+	if b.state != breakerClosed {
+		opened, probe := b.openedAt, b.openedAt.Add(cfg().BreakerCooldown)
+		st.OpenedAt, st.ProbeAt = &opened, &probe
+	}
+	return st
+}
+
+// breakerStatuses reports every breaker, or nil when breaking is off.
+func breakerStatuses() map[string]BreakerStatus {
+	if cfg().BreakerFailures == 0 {
+		return nil
+	}
+	out := make(map[string]BreakerStatus, len(breakers))
+	for name, b := range breakers {
+		out[name] = b.status()
+	}
+	return out
+}
+
+// breakerTransport runs requests to guarded dependencies through their
+// breaker.
+type breakerTransport struct {
+	next http.RoundTripper
+}
+
+func (t breakerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	b := breakerFor(r.URL.Host)
+	if b == nil {
+		return t.next.RoundTrip(r)
+	}
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+	res, err := t.next.RoundTrip(r)
+	switch {
+	case err != nil && r.Context().Err() != nil:
+		b.release()
+	case err != nil:
+		b.done(err)
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500:
+		b.done(fmt.Errorf("status %d", res.StatusCode))
+	default:
+		b.done(nil)
+	}
+	return res, err
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// resetBreakers closes every breaker, so one test's failures don't leak
+// into the next.
+func resetBreakers() {
+	for _, b := range breakers {
+		b.mu.Lock()
+		b.state, b.failures, b.probing = breakerClosed, 0, false
+		b.mu.Unlock()
+	}
+}
+
+func TestBreakerOpensAndProbes(t *testing.T) {
+	var down atomic.Bool
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound) // a 4xx is the caller's problem, not the dependency's
+	}))
+	defer srv.Close()
+	c := useTestConfig(t, map[string]string{"MEDUSA_BASE_URL": srv.URL, "CSA_BREAKER_FAILURES": "3"})
+
+	get := func() error {
+		res, err := httpClient.Get(srv.URL + "/store/carts/x")
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+	for range 5 {
+		if err := get(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if st := breakers[depMedusa].status(); st.State != breakerClosed {
+		t.Fatalf("after 404s: %+v", st)
+	}
+
+	down.Store(true)
+	for range 3 {
+		get()
+	}
+	before := calls.Load()
+	if err := get(); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("after 3 failures: err = %v, want errCircuitOpen", err)
+	}
+	if calls.Load() != before {
+		t.Error("an open breaker let a call through")
+	}
+	if st := breakerStatuses()[depMedusa]; st.State != breakerOpen || st.ConsecutiveFailures != 3 || st.ProbeAt == nil {
+		t.Errorf("status = %+v", st)
+	}
+
+	// after the cooldown one probe goes through; failing, it reopens
+	c.BreakerCooldown = time.Millisecond
+	time.Sleep(2 * time.Millisecond)
+	if err := get(); err != nil || calls.Load() != before+1 {
+		t.Fatalf("probe: err = %v, calls = %d", err, calls.Load()-before)
+	}
+	if st := breakers[depMedusa].status(); st.State != breakerOpen {
+		t.Errorf("after failed probe: %+v", st)
+	}
+
+	// a successful probe closes it
+	down.Store(false)
+	time.Sleep(2 * time.Millisecond)
+	if err := get(); err != nil {
+		t.Fatal(err)
+	}
+	if st := breakers[depMedusa].status(); st.State != breakerClosed || st.ConsecutiveFailures != 0 {
+		t.Errorf("after good probe: %+v", st)
+	}
+}
+
+func TestBreakerHalfOpenAllowsOneProbe(t *testing.T) {
+	c := useTestConfig(t, nil)
+	b := breakers[depOpenAI]
+	for range c.BreakerFailures {
+		b.done(errors.New("boom"))
+	}
+	if err := b.allow(); !errors.Is(err, errCircuitOpen) {
+		t.Fatalf("open: %v", err)
+	}
+	c.BreakerCooldown = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if err := b.allow(); err != nil {
+		t.Fatalf("first probe: %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, errCircuitOpen) {
+		t.Errorf("second call while probing: %v", err)
+	}
+	b.release() // the probe was cancelled; the next call probes instead
+	if err := b.allow(); err != nil {
+		t.Errorf("after release: %v", err)
+	}
+
+	c.BreakerFailures = 0
+	resetBreakers()
+	for range 10 {
+		b.done(errors.New("boom"))
+	}
+	if err := b.allow(); err != nil || breakerStatuses() != nil {
+		t.Errorf("disabled breaker: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/text/language"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+)
+
+// Multilingual catalogues carry translations in the product's metadata:
+//
+//	"translations": {"fr": {"title": "...", "description": "..."}, ...}
+//
+// With CSA_CARD_LANGUAGE, every product's card (the text embedded for
+// search) is built from its translation to that language, when it has one,
+// so a catalogue mixing languages still gets comparable vectors. Each of
+// CSA_CARD_LANGUAGES also gets a card of its own in product_cards. Searches
+// from shoppers whose Accept-Language prefers one of those rank by that
+// language's cards and show translated titles. Products without a
+// translation reuse their main vector there, so they are still found.
+
+// cardText is the translatable part of a product card.
+type cardText struct {
+	Title       string
+	Description string
+}
+
+// localCard is one product's card for one of CSA_CARD_LANGUAGES.
+type localCard struct {
+	lang      string
+	title     string // empty when the product has no translation
+	card      string // text that gets embedded; empty reuses the main vector
+	embedding string // vector literal
+}
+
+// translationsFromMeta reads metadata.translations, keyed by lowercase
+// language code. Entries without a title are skipped.
+func translationsFromMeta(meta map[string]any) map[string]cardText {
+	raw, ok := meta["translations"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	out := map[string]cardText{}
+	for lang, v := range raw {
+		m, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		t := cardText{Title: catalog.MetaString(m, "title"), Description: catalog.MetaString(m, "description")}
+		if t.Title != "" {
+			out[strings.ToLower(lang)] = t
+		}
+	}
+	return out
+}
+
+// productCard is the embedded text of a product.
+func productCard(t cardText, category string, eco int, price float64, attrs productAttrs) string {
+	return fmt.Sprintf("TITLE: %s\nCATEGORY: %s\nDESCRIPTION: %s\nSUSTAINABILITY: eco_score=%d\nPRICE_GBP: %.2f%s",
+		t.Title, category, t.Description, eco, price, attrs.cardLines())
+}
+
+// localCardLanguages are the languages with cards of their own. The
+// canonical language is left out: the main card already is in it.
+func localCardLanguages() []string {
+	var out []string
+	for _, l := range cfg().Embed.CardLanguages {
+		if l != cfg().Embed.CardLanguage {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+type cardLangKey struct{}
+
+// withCardLanguage picks the card language for the request from
+// Accept-Language: the shopper's most preferred language that has cards of
+// its own. A preference for the canonical language, or for none with
+// cards, searches the main cards.
+func withCardLanguage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(localCardLanguages()) > 0 {
+			w.Header().Add("Vary", "Accept-Language")
+		}
+		if lang := cardLanguageFor(r.Header.Get("Accept-Language")); lang != "" {
+			r = r.WithContext(context.WithValue(r.Context(), cardLangKey{}, lang))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func cardLanguageFor(acceptLanguage string) string {
+	local := localCardLanguages()
+	if acceptLanguage == "" || len(local) == 0 {
+		return ""
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil {
+		return ""
+	}
+	for _, t := range tags { // most preferred first
+		base, _ := t.Base()
+		lang := base.String()
+		if slices.Contains(local, lang) {
+			return lang
+		}
+		if lang == cfg().Embed.CardLanguage {
+			return ""
+		}
+	}
+	return ""
+}
+
+// cardLanguage is the request's card language; empty means the main cards.
+func cardLanguage(ctx context.Context) string {
+	lang, _ := ctx.Value(cardLangKey{}).(string)
+	return lang
+}
+
+// cardIndex names the HNSW index over lang's cards for metric. It is
+// partial, so a search in one language doesn't walk the others' vectors.
+func cardIndex(lang, metric string) string {
+	return "idx_product_cards_" + lang + "_" + metric
+}
+
+// cardNearestSQL is nearestSQL for a search of lang's cards: from joins
+// product_cards and keeps lang's rows. Cards are searched at full precision
+// whatever CSA_VECTOR_STORAGE says.
+func cardNearestSQL(cols, from, metric, lang string) string {
+	return "SELECT " + cols + "\n" + from + "\n  AND lang = '" + lang + "'\nORDER BY card_embedding " +
+		ranking.Operator(metric) + " $1::vector\nLIMIT $2"
+}
+
+// ensureCardIndexes builds the card index of each of CSA_CARD_LANGUAGES for
+// CSA_DISTANCE_METRIC, like ensureVectorIndex does for the main cards.
+func ensureCardIndexes(ctx context.Context, pool *pgxpool.Pool) error {
+	metric := cfg().Embed.Metric
+	for _, lang := range localCardLanguages() {
+		name := cardIndex(lang, metric)
+		var exists bool
+		if err := pool.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		start := time.Now()
+		slog.Info("cards: building index", "lang", lang, "metric", metric, "index", name)
+		// lang is a validated language code, safe to inline
+		if _, err := pool.Exec(ctx, `CREATE INDEX IF NOT EXISTS `+name+`
+  ON product_cards USING hnsw (card_embedding `+ranking.OpClass(metric)+`) WHERE lang = '`+lang+`'`); err != nil {
+			return err
+		}
+		slog.Info("cards: index built", "index", name, "elapsed", time.Since(start))
+	}
+	return nil
+}
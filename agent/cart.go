@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// how much the cart's style pulls each slot query toward the cart items
+const cartStyleWeight = 0.4
+
+// CartSummary reports what /complete-outfit read from a Medusa cart.
+type CartSummary struct {
+	CartID             string   `json:"cart_id"`
+	ProductIDs         []string `json:"product_ids"`
+	SpentGBP           float64  `json:"spent_gbp"`
+	RemainingBudgetGBP float64  `json:"remaining_budget_gbp,omitempty"`
+}
+
+// outfitCart is a resolved cart: the summary plus the indexed items' slots
+// and mean embedding.
+type outfitCart struct {
+	Summary CartSummary
+	Slots   []string
+	Style   []float64 // nil when no cart item is indexed
+}
+
+type medusaCartResp struct {
+	Cart struct {
+		ID           string `json:"id"`
+		CurrencyCode string `json:"currency_code"`
+		Items        []struct {
+			ProductID string   `json:"product_id"`
+			Quantity  float64  `json:"quantity"`
+			UnitPrice float64  `json:"unit_price"`
+			Total     *float64 `json:"total"`
+		} `json:"items"`
+	} `json:"cart"`
+}
+
+// errCartNotFound is returned by loadCart when Medusa has no such cart.
+var errCartNotFound = errors.New("cart not found")
+
+// cartIDPattern matches Medusa cart IDs (cart_ and a ULID) with room to spare.
+var cartIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+// loadCart fetches a Medusa cart through the store API, as the storefront
+// would, and looks its products up in the index. Spent is in GBP; a cart in
+// another currency is reported as a warning and spends nothing, since
+// budgets are GBP.
+func loadCart(ctx context.Context, pool *pgxpool.Pool, cartID string) (*outfitCart, error) {
+	res, err := medusaStoreGet(ctx, "/store/carts/"+url.PathEscape(cartID)+
+		"?fields=id,currency_code,items.product_id,items.quantity,items.unit_price,items.total")
+	var se *medusaStatusError
+	switch {
+	case errors.As(err, &se) && se.Status == http.StatusNotFound:
+		return nil, errCartNotFound
+	case errors.As(err, &se) && se.Status == http.StatusBadRequest:
+		return nil, httpapi.InvalidField("cart_id", "medusa rejected cart %q", cartID)
+	case err != nil:
+		return nil, fmt.Errorf("fetch cart: %w", err)
+	}
+	defer res.Body.Close()
+	var cr medusaCartResp
+	if err := json.NewDecoder(res.Body).Decode(&cr); err != nil {
+		return nil, fmt.Errorf("decode cart: %w", err)
+	}
+
+	c := &outfitCart{Summary: CartSummary{CartID: cartID, ProductIDs: []string{}}}
+	gbp := strings.EqualFold(cr.Cart.CurrencyCode, priceCurrency)
+	if !gbp {
+		addWarnings(ctx, fmt.Sprintf("cart currency %q is not GBP; cart spend was not deducted from the budget", cr.Cart.CurrencyCode))
+	}
+	for _, it := range cr.Cart.Items {
+		if it.ProductID != "" && !slices.Contains(c.Summary.ProductIDs, it.ProductID) {
+			c.Summary.ProductIDs = append(c.Summary.ProductIDs, it.ProductID)
+		}
+		if !gbp {
+			continue
+		}
+		if it.Total != nil {
+			c.Summary.SpentGBP += *it.Total
+		} else {
+			c.Summary.SpentGBP += it.UnitPrice * it.Quantity
+		}
+	}
+	if len(c.Summary.ProductIDs) == 0 {
+		return c, nil
+	}
+
+	rows, err := pool.Query(ctx, `
+SELECT product_id, category FROM product_embeddings WHERE product_id = ANY($1)
+`, c.Summary.ProductIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	indexed := map[string]bool{}
+	for rows.Next() {
+		var id, category string
+		if err := rows.Scan(&id, &category); err != nil {
+			return nil, err
+		}
+		indexed[id] = true
+		if category != "" && !slices.Contains(c.Slots, category) {
+			c.Slots = append(c.Slots, category)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, id := range c.Summary.ProductIDs {
+		if !indexed[id] {
+			addWarnings(ctx, fmt.Sprintf("cart product %s is not indexed; its slot is unknown", id))
+		}
+	}
+
+	// pgvector averages vectors natively
+	var style *string
+	err = pool.QueryRow(ctx, `
+SELECT avg(embedding)::text FROM product_embeddings
+WHERE product_id = ANY($1) AND embedding IS NOT NULL
+`, c.Summary.ProductIDs).Scan(&style)
+	if err != nil {
+		return nil, err
+	}
+	if style != nil {
+		if c.Style, err = parseVector(*style); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// parseVector reads pgvector's text form, e.g. "[0.1,0.2]".
+func parseVector(s string) ([]float64, error) {
+	s = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "["), "]")
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	v := make([]float64, len(parts))
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector component %q", p)
+		}
+		v[i] = f
+	}
+	return v, nil
+}
+
+// blendStyle pulls a query embedding toward the cart's mean embedding.
+func blendStyle(q, style []float64) []float64 {
+	if len(style) != len(q) {
+		return q
+	}
+	out := make([]float64, len(q))
+	for i := range q {
+		out[i] = (1-cartStyleWeight)*q[i] + cartStyleWeight*style[i]
+	}
+	return out
+}
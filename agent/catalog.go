@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+)
+
+var catalogProviders = map[string]catalog.Provider{
+	config.CatalogMedusa:  medusaCatalog{},
+	config.CatalogShopify: shopifyCatalog{},
+}
+
+// catalogProvider is the provider CSA_CATALOG_PROVIDER picks.
+func catalogProvider() catalog.Provider {
+	return catalogProviders[cfg().CatalogProvider]
+}
+
+// Index modes for POST /index-products.
+const (
+	indexFull        = "full"
+	indexIncremental = "incremental"
+)
+
+type IndexResult struct {
+	Provider string     `json:"provider"`
+	Mode     string     `json:"mode"`
+	Since    *time.Time `json:"since,omitempty"` // incremental lower bound
+	Fetched  int        `json:"fetched"`
+	Indexed  int        `json:"indexed"`
+	// Budget is set when the run had a token budget; see index_budget.go.
+	Budget *IndexBudget `json:"budget,omitempty"`
+}
+
+// indexCatalog fetches products from the configured provider and indexes
+// them for tenantID. Incremental runs only fetch products updated since the
+// previous successful run; the first one is a full run. With maxTokens
+// above 0, only the most important products that fit are embedded, and a
+// run that leaves some doesn't count as a sync, so the next incremental run
+// fetches them again.
+func indexCatalog(ctx context.Context, pool *pgxpool.Pool, tenantID, mode string, maxTokens int) (IndexResult, error) {
+	p := catalogProvider()
+	res := IndexResult{Provider: p.Name(), Mode: mode}
+	if err := p.Configured(); err != nil {
+		return res, err
+	}
+
+	// index jobs run outside a request, so set the tenant (its embedding
+	// tokens are counted against it) and look the zone up here
+	ctx = context.WithValue(ctx, ctxTenant, tenantID)
+	ctx, err := withResidency(ctx, pool, tenantID)
+	if err != nil {
+		return res, err
+	}
+
+	started := time.Now()
+	var since time.Time
+	if mode == indexIncremental {
+		last, err := lastCatalogSync(ctx, pool, tenantID, p.Name())
+		if err != nil {
+			return res, err
+		}
+		if last.IsZero() {
+			res.Mode = indexFull
+		} else {
+			since = last
+			res.Since = &since
+		}
+	}
+
+	products, err := p.Products(ctx, since)
+	if err != nil {
+		return res, fmt.Errorf("%s: %w", p.Name(), err)
+	}
+	res.Fetched = len(products)
+
+	rows := make([]productRow, 0, len(products))
+	for _, cp := range products {
+		rows = append(rows, productRowFor(ctx, cp, tenantID))
+	}
+	if rows, res.Budget, err = applyIndexBudget(ctx, pool, rows, maxTokens); err != nil {
+		return res, err
+	}
+	if res.Indexed, err = indexProducts(ctx, pool, rows); err != nil {
+		return res, err
+	}
+	if res.Budget != nil && res.Budget.Remaining > 0 {
+		slog.WarnContext(ctx, "index: token budget reached", "provider", p.Name(), "max_tokens", maxTokens,
+			"indexed", res.Indexed, "remaining", res.Budget.Remaining)
+		return res, nil
+	}
+	// record the start time so products edited during the run are refetched
+	return res, saveCatalogSync(ctx, pool, tenantID, p.Name(), started)
+}
+
+// catalogSyncLoop runs an incremental index on the CSA_CATALOG_SYNC_CRON
+// schedule until ctx is cancelled. Each scheduled time runs on one replica
+// only. A failed run is retried at the next slot.
+func catalogSyncLoop(ctx context.Context, pool *pgxpool.Pool) {
+	sched := cfg().CatalogSync
+	for {
+		next := sched.Next(time.Now())
+		if next.IsZero() {
+			slog.ErrorContext(ctx, "catalog: sync schedule never fires, scheduler stopped")
+			return
+		}
+		slog.DebugContext(ctx, "catalog: next scheduled sync", "at", next)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		start := time.Now()
+		var res IndexResult
+		ran, err := runScheduled(ctx, pool, "catalog_sync", next, func(ctx context.Context) (err error) {
+			res, err = indexCatalog(ctx, pool, cfg().CatalogSyncTenant, indexIncremental, cfg().IndexTokenBudget)
+			return err
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "catalog: scheduled sync failed", "provider", res.Provider, "err", err)
+			continue
+		}
+		if !ran {
+			continue // another replica has it
+		}
+		slog.InfoContext(ctx, "catalog: scheduled sync", "provider", res.Provider, "mode", res.Mode,
+			"fetched", res.Fetched, "indexed", res.Indexed, "took", time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// productRowFor turns a catalog product into an index row.
+func productRowFor(ctx context.Context, p catalog.Product, tenantID string) productRow {
+	category := catalog.SlotFromMeta(p.Metadata)
+	if category == "" {
+		category = classifySlot(ctx, p)
+		slog.DebugContext(ctx, "index: classified category", "product_id", p.ID, "category", category)
+	}
+	eco, ecoSource := catalog.EcoFromMeta(p.Metadata), ""
+	if _, ok := p.Metadata["eco_score"]; ok {
+		ecoSource = ecoSourceMerchant
+	}
+	price := p.PriceGBP
+	if !p.HasPrice {
+		// catalogs from before variant pricing kept the price in metadata
+		price = catalog.PriceFromMetaGBP(p.Metadata)
+		slog.DebugContext(ctx, "index: no GBP variant price, using metadata", "product_id", p.ID, "price_gbp", price)
+	}
+	lifecycle := p.Lifecycle
+	if lifecycle == "" {
+		lifecycle = lifecycleFromMeta(p.Metadata)
+	}
+	var shipsAt *time.Time
+	if lifecycle == lifecyclePreorder {
+		shipsAt = shipsAtFromMeta(p.Metadata)
+	}
+	stockQty, stockSummary := summarizeStock(p.Variants)
+	attrs := extractAttributes(p.Options, p.Material, p.Tags, p.Metadata)
+	origin := p.Origin
+	if origin == "" {
+		origin = catalog.MetaString(p.Metadata, "origin_country")
+	}
+	if ecoSource == "" && cfg().EcoEstimator == config.EcoEstimatorLLM {
+		if est, err := estimateEcoScore(ctx, p, attrs, origin); err != nil {
+			slog.WarnContext(ctx, "index: eco score estimate failed", "product_id", p.ID, "err", err)
+		} else {
+			eco, ecoSource = est, ecoSourceEstimated
+		}
+	}
+
+	text := cardText{Title: p.Title, Description: p.Description}
+	translations := translationsFromMeta(p.Metadata)
+	if t, ok := translations[cfg().Embed.CardLanguage]; ok {
+		text = t
+	}
+	var local []localCard
+	for _, lang := range localCardLanguages() {
+		c := localCard{lang: lang}
+		if t, ok := translations[lang]; ok {
+			c.title, c.card = t.Title, productCard(t, category, eco, price, attrs)
+		}
+		local = append(local, c)
+	}
+
+	return productRow{
+		ProductID:    p.ID,
+		Category:     category,
+		Title:        p.Title,
+		Thumbnail:    p.Thumbnail,
+		EcoScore:     eco,
+		EcoSource:    ecoSource,
+		PriceGBP:     price,
+		StockQty:     stockQty,
+		StockSummary: stockSummary,
+		Attrs:        attrs,
+		Origin:       normalizeCountry(origin),
+		Lifecycle:    lifecycle,
+		ShipsAt:      shipsAt,
+		TenantID:     tenantID,
+		card:         productCard(text, category, eco, price, attrs),
+		localCards:   local,
+	}
+}
+
+func lastCatalogSync(ctx context.Context, pool *pgxpool.Pool, tenantID, provider string) (time.Time, error) {
+	var t time.Time
+	err := pool.QueryRow(ctx, `
+SELECT last_synced_at FROM catalog_sync WHERE tenant_id=$1 AND provider=$2
+`, tenantID, provider).Scan(&t)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return t, err
+}
+
+func saveCatalogSync(ctx context.Context, pool *pgxpool.Pool, tenantID, provider string, at time.Time) error {
+	_, err := pool.Exec(ctx, `
+INSERT INTO catalog_sync (tenant_id, provider, last_synced_at) VALUES ($1,$2,$3)
+ON CONFLICT (tenant_id, provider) DO UPDATE SET last_synced_at=EXCLUDED.last_synced_at
+`, tenantID, provider, at)
+	return err
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+)
+
+// Upload limits for POST /import-catalog.
+const (
+	maxImportUpload = 32 << 20
+	maxImportRows   = 20000
+)
+
+// Import file formats.
+const (
+	importCSV   = "csv"
+	importJSONL = "jsonl"
+)
+
+// importRecord is one catalog row. CSV headers use the same names as the
+// JSON keys; extra columns are ignored.
+type importRecord struct {
+	ProductID   string   `json:"product_id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Category    string   `json:"category"`
+	Price       *float64 `json:"price"` // GBP
+	EcoScore    *int     `json:"eco_score"`
+	Thumbnail   string   `json:"thumbnail"`
+
+	line int // in the upload, for ImportError
+}
+
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type ImportResult struct {
+	Format   string        `json:"format"`
+	Rows     int           `json:"rows"`
+	Indexed  int           `json:"indexed"`
+	Rejected []ImportError `json:"rejected"`
+}
+
+func (rec importRecord) validate() error {
+	switch {
+	case rec.ProductID == "":
+		return errors.New("product_id is required")
+	case rec.Title == "":
+		return errors.New("title is required")
+	case rec.Price == nil:
+		return errors.New("price is required")
+	case math.IsNaN(*rec.Price) || math.IsInf(*rec.Price, 0):
+		return errors.New("price must be a finite number")
+	case *rec.Price < 0:
+		return errors.New("price must not be negative")
+	case rec.EcoScore != nil && (*rec.EcoScore < 0 || *rec.EcoScore > 100):
+		return errors.New("eco_score must be between 0 and 100")
+	}
+	return nil
+}
+
+// catalogProduct maps the record onto the fields a provider would fill, so
+// imported products get the same embedding card as Medusa or Shopify ones.
+func (rec importRecord) catalogProduct() catalog.Product {
+	meta := map[string]any{}
+	if slot := strings.ToLower(strings.TrimSpace(rec.Category)); slot != "" {
+		if s := categoryWords[slot]; s != "" {
+			slot = s // "tops", "footwear", ...
+		}
+		meta["slot"] = slot
+	}
+	if rec.EcoScore != nil {
+		meta["eco_score"] = *rec.EcoScore
+	}
+	return catalog.Product{
+		ID:          rec.ProductID,
+		Title:       rec.Title,
+		Thumbnail:   rec.Thumbnail,
+		Description: rec.Description,
+		Metadata:    meta,
+		PriceGBP:    *rec.Price,
+		HasPrice:    true,
+	}
+}
+
+// readImportUpload returns the uploaded file and its format. It accepts a
+// multipart "file" field or a raw body; the format comes from ?format=,
+// then the content type, then the file extension.
+func readImportUpload(w http.ResponseWriter, r *http.Request) ([]byte, string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportUpload)
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	ctype, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var body []byte
+	if ctype == "multipart/form-data" {
+		if err := r.ParseMultipartForm(maxImportUpload); err != nil {
+			return nil, "", err
+		}
+		f, fh, err := r.FormFile("file")
+		if err != nil {
+			return nil, "", errors.New("multipart field \"file\" is required")
+		}
+		defer f.Close()
+		if body, err = io.ReadAll(f); err != nil {
+			return nil, "", err
+		}
+		ctype = fh.Header.Get("Content-Type")
+		if format == "" {
+			format = formatFromExt(fh.Filename)
+		}
+	} else {
+		var err error
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if format == "" {
+		switch ctype {
+		case "text/csv":
+			format = importCSV
+		case "application/jsonl", "application/x-ndjson", "application/x-jsonlines":
+			format = importJSONL
+		}
+	}
+	switch format {
+	case importCSV, importJSONL:
+	case "":
+		return nil, "", errors.New("can't tell the file format: pass ?format=csv or ?format=jsonl")
+	default:
+		return nil, "", fmt.Errorf("format must be %q or %q", importCSV, importJSONL)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, "", errors.New("empty upload")
+	}
+	return body, format, nil
+}
+
+func formatFromExt(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return importCSV
+	case ".jsonl", ".ndjson":
+		return importJSONL
+	}
+	return ""
+}
+
+// parseImport decodes every row. Rows that fail to parse or validate are
+// reported with their line number; the rest are returned.
+func parseImport(body []byte, format string) ([]importRecord, []ImportError, error) {
+	var recs []importRecord
+	rejected := []ImportError{}
+	add := func(line int, rec importRecord, err error) {
+		if err == nil {
+			err = rec.validate()
+		}
+		if err != nil {
+			rejected = append(rejected, ImportError{Line: line, Error: err.Error()})
+			return
+		}
+		rec.line = line
+		recs = append(recs, rec)
+	}
+
+	if format == importJSONL {
+		sc := bufio.NewScanner(bytes.NewReader(body))
+		sc.Buffer(make([]byte, 0, 64<<10), 1<<20)
+		for line := 1; sc.Scan(); line++ {
+			if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+				continue
+			}
+			var rec importRecord
+			err := json.Unmarshal(sc.Bytes(), &rec)
+			add(line, rec, err)
+			if len(recs)+len(rejected) > maxImportRows {
+				return nil, nil, fmt.Errorf("at most %d rows per import", maxImportRows)
+			}
+		}
+		return recs, rejected, sc.Err()
+	}
+
+	cr := csv.NewReader(bytes.NewReader(body))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("csv header: %w", err)
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	for _, required := range []string{"product_id", "title", "price"} {
+		if _, ok := col[required]; !ok {
+			return nil, nil, fmt.Errorf("csv header is missing %q", required)
+		}
+	}
+	for {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var pe *csv.ParseError
+			if !errors.As(err, &pe) {
+				return nil, nil, err
+			}
+			add(pe.StartLine, importRecord{}, err)
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		rec, err := csvRecord(fields, col)
+		add(line, rec, err)
+		if len(recs)+len(rejected) > maxImportRows {
+			return nil, nil, fmt.Errorf("at most %d rows per import", maxImportRows)
+		}
+	}
+	return recs, rejected, nil
+}
+
+func csvRecord(fields []string, col map[string]int) (importRecord, error) {
+	get := func(name string) string {
+		if i, ok := col[name]; ok && i < len(fields) {
+			return strings.TrimSpace(fields[i])
+		}
+		return ""
+	}
+	rec := importRecord{
+		ProductID:   get("product_id"),
+		Title:       get("title"),
+		Description: get("description"),
+		Category:    get("category"),
+		Thumbnail:   get("thumbnail"),
+	}
+	if s := strings.TrimPrefix(get("price"), "£"); s != "" {
+		p, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return rec, fmt.Errorf("price %q is not a number", s)
+		}
+		rec.Price = &p
+	}
+	if s := get("eco_score"); s != "" {
+		e, err := strconv.Atoi(s)
+		if err != nil {
+			return rec, fmt.Errorf("eco_score %q is not an integer", s)
+		}
+		rec.EcoScore = &e
+	}
+	return rec, nil
+}
+
+// importCatalog embeds and upserts the valid rows for tenantID. A product ID
+// repeated in the file keeps its last row. Rows whose product ID is indexed
+// for another tenant are not imported but returned as rejected: product IDs
+// are unique across tenants, so the upsert would take the product over.
+func importCatalog(ctx context.Context, pool *pgxpool.Pool, tenantID string, recs []importRecord) (int, []ImportError, error) {
+	ids := make([]string, len(recs))
+	for i, rec := range recs {
+		ids[i] = rec.ProductID
+	}
+	rows, err := pool.Query(ctx, `
+SELECT product_id FROM product_embeddings WHERE product_id = ANY($1) AND tenant_id IS DISTINCT FROM $2
+`, ids, tenantID)
+	if err != nil {
+		return 0, nil, fmt.Errorf("db error: %w", err)
+	}
+	owned, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, nil, fmt.Errorf("db error: %w", err)
+	}
+	taken := map[string]bool{}
+	for _, id := range owned {
+		taken[id] = true
+	}
+
+	var rejected []ImportError
+	seen := map[string]int{}
+	prows := make([]productRow, 0, len(recs))
+	for _, rec := range recs {
+		if taken[rec.ProductID] {
+			rejected = append(rejected, ImportError{Line: rec.line, Error: fmt.Sprintf("product_id %q belongs to another tenant", rec.ProductID)})
+			continue
+		}
+		row := productRowFor(ctx, rec.catalogProduct(), tenantID)
+		if i, ok := seen[rec.ProductID]; ok {
+			prows[i] = row
+			continue
+		}
+		seen[rec.ProductID] = len(prows)
+		prows = append(prows, row)
+	}
+	n, err := indexProducts(ctx, pool, prows)
+	return n, rejected, err
+}
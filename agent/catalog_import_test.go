@@ -0,0 +1,114 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseImport(t *testing.T) {
+	type row struct {
+		id    string
+		price float64
+		line  int
+	}
+	for _, tc := range []struct {
+		name, format, body string
+		rows               []row
+		rejected           []ImportError // Error is a substring of the message
+		err                string
+	}{
+		{
+			name:   "csv",
+			format: importCSV,
+			body: "\ufeffProduct_ID,title,price,category,eco_score,extra\n" +
+				"p1,Linen Shirt,£45.50,tops,80,x\n" +
+				"p2,\"Chinos, slim\",55,bottom,,\n",
+			rows:     []row{{"p1", 45.5, 2}, {"p2", 55, 3}},
+			rejected: []ImportError{},
+		},
+		{
+			name:   "csv bad rows",
+			format: importCSV,
+			body: "product_id,title,price,eco_score\n" +
+				"p1,Shirt,NaN,50\n" +
+				"p2,Shirt,Inf,50\n" +
+				"p3,Shirt,-1,50\n" +
+				"p4,Shirt,ten,50\n" +
+				"p5,Shirt,10,101\n" +
+				"p6,Shirt,10,high\n" +
+				",Shirt,10,50\n" +
+				"p8,,10,50\n" +
+				"p9,Shirt,,50\n" +
+				"p10,Shirt,10,50\n",
+			rows: []row{{"p10", 10, 11}},
+			rejected: []ImportError{
+				{2, "price must be a finite number"},
+				{3, "price must be a finite number"},
+				{4, "price must not be negative"},
+				{5, `price "ten" is not a number`},
+				{6, "eco_score must be between 0 and 100"},
+				{7, `eco_score "high" is not an integer`},
+				{8, "product_id is required"},
+				{9, "title is required"},
+				{10, "price is required"},
+			},
+		},
+		{
+			name:   "csv header missing price",
+			format: importCSV,
+			body:   "product_id,title\np1,Shirt\n",
+			err:    `csv header is missing "price"`,
+		},
+		{
+			name:   "jsonl",
+			format: importJSONL,
+			body: `{"product_id": "p1", "title": "Shirt", "price": 45}` + "\n\n" +
+				`{"product_id": "p2", "title": "Boots", "price": "cheap"}` + "\n" +
+				`{"product_id": "p3", "title": "Scarf", "price": 1e400}` + "\n" +
+				`not json` + "\n" +
+				`{"product_id": "p5", "title": "Belt", "price": 0}` + "\n",
+			rows: []row{{"p1", 45, 1}, {"p5", 0, 6}},
+			rejected: []ImportError{
+				{3, "cannot unmarshal string"},
+				{4, "cannot unmarshal number 1e400"},
+				{5, "invalid character"},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			recs, rejected, err := parseImport([]byte(tc.body), tc.format)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("err = %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []row
+			for _, r := range recs {
+				got = append(got, row{r.ProductID, *r.Price, r.line})
+			}
+			if !reflect.DeepEqual(got, tc.rows) {
+				t.Errorf("rows = %v, want %v", got, tc.rows)
+			}
+			if len(rejected) != len(tc.rejected) {
+				t.Fatalf("rejected = %v, want %v", rejected, tc.rejected)
+			}
+			for i, want := range tc.rejected {
+				if got := rejected[i]; got.Line != want.Line || !strings.Contains(got.Error, want.Error) {
+					t.Errorf("rejected[%d] = %+v, want line %d with %q", i, got, want.Line, want.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestParseImportRowLimit(t *testing.T) {
+	body := "product_id,title,price\n" + strings.Repeat("p,Shirt,1\n", maxImportRows+1)
+	if _, _, err := parseImport([]byte(body), importCSV); err == nil {
+		t.Errorf("%d rows: want an error", maxImportRows+1)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+)
+
+// Medusa only returns inventory_quantity when asked for it explicitly.
+const medusaInventoryFields = "fields=%2Bvariants.inventory_quantity"
+
+// admin API page size
+const medusaPageSize = 100
+
+// medusaCatalog reads the Medusa v2 admin API.
+type medusaCatalog struct{}
+
+func (medusaCatalog) Name() string { return config.CatalogMedusa }
+
+func (medusaCatalog) Configured() error {
+	if !medusaAuthConfigured() {
+		return errMedusaAuthNotConfigured
+	}
+	return nil
+}
+
+// medusaVariant is a Medusa admin variant as returned by the API.
+type medusaVariant struct {
+	ID                string `json:"id"`
+	Title             string `json:"title"`
+	SKU               string `json:"sku"`
+	InventoryQuantity int    `json:"inventory_quantity"`
+	ManageInventory   bool   `json:"manage_inventory"`
+	AllowBackorder    bool   `json:"allow_backorder"`
+	Options           []struct {
+		OptionID string `json:"option_id"`
+		Value    string `json:"value"`
+	} `json:"options"`
+}
+
+// medusaOption is a product option such as Size or Color with its values.
+type medusaOption struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Values []struct {
+		Value string `json:"value"`
+	} `json:"values"`
+}
+
+type medusaProduct struct {
+	ID          string         `json:"id"`
+	Title       string         `json:"title"`
+	Thumbnail   string         `json:"thumbnail"`
+	Description string         `json:"description"`
+	Material    string         `json:"material"`
+	Origin      string         `json:"origin_country"`
+	Status      string         `json:"status"` // draft | proposed | published | rejected
+	Options     []medusaOption `json:"options"`
+	Tags        []struct {
+		Value string `json:"value"`
+	} `json:"tags"`
+	Metadata map[string]any `json:"metadata"`
+	Variants []struct {
+		medusaVariant
+		Prices []medusaPrice `json:"prices"`
+	} `json:"variants"`
+}
+
+// eachMedusaPage pages through /admin/products with the given query.
+func eachMedusaPage(ctx context.Context, query string, fn func([]medusaProduct)) error {
+	for offset := 0; ; offset += medusaPageSize {
+		path := fmt.Sprintf("/admin/products?limit=%d&offset=%d&%s", medusaPageSize, offset, query)
+		slog.DebugContext(ctx, "catalog: fetching medusa products", "url", cfg().Medusa.BaseURL+path)
+		res, err := medusaGet(ctx, path)
+		if err != nil {
+			return err
+		}
+		var page struct {
+			Products []medusaProduct `json:"products"`
+			Count    int             `json:"count"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+		fn(page.Products)
+		if len(page.Products) < medusaPageSize || offset+medusaPageSize >= page.Count {
+			return nil
+		}
+	}
+}
+
+func (medusaCatalog) Products(ctx context.Context, since time.Time) ([]catalog.Product, error) {
+	query := medusaProductFields
+	if !since.IsZero() {
+		query += "&" + url.QueryEscape("updated_at[$gte]") + "=" + url.QueryEscape(since.UTC().Format(time.RFC3339))
+	}
+	var out []catalog.Product
+	err := eachMedusaPage(ctx, query, func(page []medusaProduct) {
+		for _, p := range page {
+			out = append(out, p.catalogProduct())
+		}
+	})
+	return out, err
+}
+
+func (p medusaProduct) catalogProduct() catalog.Product {
+	cp := catalog.Product{
+		ID:          p.ID,
+		Title:       p.Title,
+		Thumbnail:   p.Thumbnail,
+		Description: p.Description,
+		Material:    p.Material,
+		Origin:      p.Origin,
+		Metadata:    p.Metadata,
+		Lifecycle:   lifecycleFromMedusa(p.Status, p.Metadata),
+	}
+	for _, o := range p.Options {
+		co := catalog.Option{Title: o.Title}
+		for _, v := range o.Values {
+			co.Values = append(co.Values, v.Value)
+		}
+		cp.Options = append(cp.Options, co)
+	}
+	for _, t := range p.Tags {
+		cp.Tags = append(cp.Tags, t.Value)
+	}
+	prices := make([][]medusaPrice, 0, len(p.Variants))
+	for _, v := range p.Variants {
+		cp.Variants = append(cp.Variants, p.variant(v.medusaVariant))
+		prices = append(prices, v.Prices)
+	}
+	cp.PriceGBP, cp.HasPrice = variantPriceGBP(prices)
+	return cp
+}
+
+// variant converts v, reading its size from the product's size option.
+func (p medusaProduct) variant(v medusaVariant) catalog.Variant {
+	cv := catalog.Variant{
+		ID: v.ID, Title: v.Title, SKU: v.SKU, InventoryQuantity: v.InventoryQuantity,
+		ManageInventory: v.ManageInventory, AllowBackorder: v.AllowBackorder,
+	}
+	for _, o := range p.Options {
+		if !isSizeOption(o.Title) {
+			continue
+		}
+		for _, vo := range v.Options {
+			if vo.OptionID == o.ID {
+				cv.Size = vo.Value
+			}
+		}
+	}
+	return cv
+}
+
+func (medusaCatalog) Stock(ctx context.Context) (map[string][]catalog.Variant, error) {
+	out := map[string][]catalog.Variant{}
+	err := eachMedusaPage(ctx, medusaInventoryFields, func(page []medusaProduct) {
+		for _, p := range page {
+			variants := make([]catalog.Variant, 0, len(p.Variants))
+			for _, v := range p.Variants {
+				variants = append(variants, p.variant(v.medusaVariant))
+			}
+			out[p.ID] = variants
+		}
+	})
+	return out, err
+}
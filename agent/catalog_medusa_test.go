@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func medusaFixture(id, title, status string, meta map[string]any) map[string]any {
+	return map[string]any{
+		"id": id, "title": title, "status": status, "metadata": meta,
+		"options": []map[string]any{{"id": "opt_size", "title": "Size", "values": []map[string]any{{"value": "M"}, {"value": "L"}}}},
+		"variants": []map[string]any{
+			{
+				"id": id + "_m", "title": "M", "inventory_quantity": 3, "manage_inventory": true,
+				"options": []map[string]any{{"option_id": "opt_size", "value": "M"}},
+				"prices": []map[string]any{
+					{"amount": 40, "currency_code": "gbp"},
+					{"amount": 35, "currency_code": "gbp", "rules": map[string]any{"region_id": "reg_uk"}},
+					{"amount": 50, "currency_code": "eur"},
+				},
+			},
+			{
+				"id": id + "_l", "title": "L", "inventory_quantity": 0, "manage_inventory": true,
+				"options": []map[string]any{{"option_id": "opt_size", "value": "L"}},
+				"prices":  []map[string]any{{"amount": 38, "currency_code": "gbp"}},
+			},
+		},
+	}
+}
+
+func TestMedusaCatalogProducts(t *testing.T) {
+	m := newFakeMedusa(t)
+	m.Products = []map[string]any{
+		medusaFixture("prod_chinos", "Linen Chinos", "published", map[string]any{"slot": "bottom", "eco_score": 80}),
+		medusaFixture("prod_boots", "Rain Boots", "published", map[string]any{"lifecycle": "preorder", "ships_at": "2026-11-01"}),
+		medusaFixture("prod_draft", "Sample Scarf", "draft", nil),
+	}
+	env := m.env()
+	env["CSA_MEDUSA_REGION_ID"] = "reg_uk"
+	useTestConfig(t, env)
+
+	products, err := medusaCatalog{}.Products(context.Background(), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(products) != 3 {
+		t.Fatalf("got %d products, want 3", len(products))
+	}
+
+	chinos := products[0]
+	if chinos.ID != "prod_chinos" || chinos.Title != "Linen Chinos" || chinos.Lifecycle != lifecycleActive {
+		t.Errorf("chinos = %+v", chinos)
+	}
+	// the region price wins over the base price; the cheapest variant is the "from" price
+	if !chinos.HasPrice || chinos.PriceGBP != 35 {
+		t.Errorf("chinos price = %v (has %v), want 35", chinos.PriceGBP, chinos.HasPrice)
+	}
+	var sizes []string
+	for _, v := range chinos.Variants {
+		sizes = append(sizes, fmt.Sprintf("%s:%d", v.Size, v.InventoryQuantity))
+	}
+	if want := []string{"M:3", "L:0"}; !reflect.DeepEqual(sizes, want) {
+		t.Errorf("variant sizes = %v, want %v", sizes, want)
+	}
+	if got := products[1].Lifecycle; got != lifecyclePreorder {
+		t.Errorf("boots lifecycle = %q, want preorder", got)
+	}
+	if got := products[2].Lifecycle; got != lifecycleDraft {
+		t.Errorf("draft lifecycle = %q, want draft", got)
+	}
+	if m.logins != 1 {
+		t.Errorf("logged in %d times, want 1", m.logins)
+	}
+}
+
+func TestMedusaCatalogPages(t *testing.T) {
+	m := newFakeMedusa(t)
+	for i := range medusaPageSize + 20 {
+		m.Products = append(m.Products, medusaFixture(fmt.Sprintf("prod_%03d", i), "Tee", "published", nil))
+	}
+	useTestConfig(t, m.env())
+
+	stock, err := medusaCatalog{}.Stock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stock) != medusaPageSize+20 {
+		t.Errorf("stock for %d products, want %d", len(stock), medusaPageSize+20)
+	}
+	if v := stock["prod_110"]; len(v) != 2 || v[0].Size != "M" {
+		t.Errorf("prod_110 variants = %+v", v)
+	}
+}
+
+func TestMedusaLogsInAgainWhenSessionRejected(t *testing.T) {
+	m := newFakeMedusa(t)
+	m.Products = []map[string]any{medusaFixture("prod_tee", "Tee", "published", nil)}
+	useTestConfig(t, m.env())
+	ctx := context.Background()
+
+	if _, err := (medusaCatalog{}).Products(ctx, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	m.expireSession()
+	if _, err := (medusaCatalog{}).Products(ctx, time.Time{}); err != nil {
+		t.Fatalf("after the session was revoked: %v", err)
+	}
+	if m.logins != 2 {
+		t.Errorf("logged in %d times, want 2", m.logins)
+	}
+}
+
+func TestMedusaNotConfigured(t *testing.T) {
+	useTestConfig(t, nil)
+	if err := (medusaCatalog{}).Configured(); err != errMedusaAuthNotConfigured {
+		t.Errorf("Configured() = %v, want errMedusaAuthNotConfigured", err)
+	}
+}
+
+func TestLoadCartNotFound(t *testing.T) {
+	m := newFakeMedusa(t)
+	useTestConfig(t, m.env())
+
+	_, err := loadCart(context.Background(), nil, "cart_missing")
+	if !errors.Is(err, errCartNotFound) {
+		t.Errorf("loadCart = %v, want errCartNotFound", err)
+	}
+}
+
+func TestLoadCartUsesStoreAPI(t *testing.T) {
+	m := newFakeMedusa(t)
+	m.Carts["cart_1"] = map[string]any{"id": "cart_1", "currency_code": "gbp", "items": []any{}}
+	env := m.env()
+	useTestConfig(t, env)
+
+	if _, err := loadCart(context.Background(), nil, "cart_1"); err != nil {
+		t.Fatal(err)
+	}
+	if m.logins != 0 {
+		t.Errorf("logged in to the admin API %d times reading a cart", m.logins)
+	}
+
+	env["MEDUSA_PUBLISHABLE_KEY"] = ""
+	useTestConfig(t, env)
+	if _, err := loadCart(context.Background(), nil, "cart_1"); !errors.Is(err, errMedusaStoreNotConfigured) {
+		t.Errorf("without a publishable key: %v, want errMedusaStoreNotConfigured", err)
+	}
+}
+
+func TestLoadCartEmpty(t *testing.T) {
+	m := newFakeMedusa(t)
+	m.Carts["cart_1"] = map[string]any{"id": "cart_1", "currency_code": "gbp", "items": []any{}}
+	useTestConfig(t, m.env())
+
+	// an empty cart is answered without looking anything up in the index
+	c, err := loadCart(context.Background(), nil, "cart_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Summary.CartID != "cart_1" || len(c.Summary.ProductIDs) != 0 || c.Summary.SpentGBP != 0 {
+		t.Errorf("cart = %+v", c.Summary)
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+)
+
+// retries when Shopify throttles a query (the leaky-bucket cost limit)
+const shopifyMaxThrottleRetries = 5
+
+// shopifyCatalog reads the Shopify Admin GraphQL API.
+type shopifyCatalog struct{}
+
+func (shopifyCatalog) Name() string { return config.CatalogShopify }
+
+func (shopifyCatalog) Configured() error {
+	if cfg().Shopify.ShopDomain == "" || cfg().Shopify.AdminToken == "" {
+		return errors.New("shopify catalog not configured: set SHOPIFY_SHOP_DOMAIN and SHOPIFY_ADMIN_TOKEN")
+	}
+	return nil
+}
+
+// Shopify rejects queries whose requested cost is over 1000 points: each
+// object costs 1 and a connection 2 plus first times its node cost. Pages are
+// sized to stay under that (about 820 points for products, 830 for stock,
+// 250 for variants); products with more variants than a page holds have the
+// rest fetched by shopifyVariantsQuery. TestShopifyQueryCosts checks them.
+
+const shopifyVariantFields = `
+        nodes {
+          id title sku inventoryQuantity inventoryPolicy
+          selectedOptions { name value }
+          inventoryItem { tracked countryCodeOfOrigin }
+          contextualPricing(context: {country: $country}) { price { amount currencyCode } }
+        }`
+
+const shopifyProductsQuery = `
+query Products($cursor: String, $query: String, $country: CountryCode!, $ns: String!) {
+  products(first: 10, after: $cursor, query: $query) {
+    pageInfo { hasNextPage endCursor }
+    nodes {
+      id title description vendor productType tags status
+      featuredImage { url }
+      options { name values }
+      metafields(first: 25, namespace: $ns) { nodes { key value } }
+      variants(first: 10) {
+        pageInfo { hasNextPage endCursor }` + shopifyVariantFields + `
+      }
+    }
+  }
+}`
+
+const shopifyStockQuery = `
+query Stock($cursor: String) {
+  products(first: 25, after: $cursor) {
+    pageInfo { hasNextPage endCursor }
+    nodes {
+      id
+      variants(first: 10) {
+        pageInfo { hasNextPage endCursor }
+        nodes { id title sku inventoryQuantity inventoryPolicy selectedOptions { name value } inventoryItem { tracked } }
+      }
+    }
+  }
+}`
+
+const shopifyVariantsQuery = `
+query Variants($id: ID!, $cursor: String, $country: CountryCode!) {
+  product(id: $id) {
+    variants(first: 50, after: $cursor) {
+      pageInfo { hasNextPage endCursor }` + shopifyVariantFields + `
+    }
+  }
+}`
+
+type shopifyVariant struct {
+	ID                string `json:"id"`
+	Title             string `json:"title"`
+	SKU               string `json:"sku"`
+	InventoryQuantity int    `json:"inventoryQuantity"`
+	InventoryPolicy   string `json:"inventoryPolicy"` // DENY | CONTINUE (sell when out of stock)
+	SelectedOptions   []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"selectedOptions"`
+	InventoryItem struct {
+		Tracked             bool   `json:"tracked"`
+		CountryCodeOfOrigin string `json:"countryCodeOfOrigin"`
+	} `json:"inventoryItem"`
+	ContextualPricing struct {
+		Price struct {
+			Amount       string `json:"amount"`
+			CurrencyCode string `json:"currencyCode"`
+		} `json:"price"`
+	} `json:"contextualPricing"`
+}
+
+func (v shopifyVariant) catalogVariant() catalog.Variant {
+	cv := catalog.Variant{
+		ID:                v.ID,
+		Title:             v.Title,
+		SKU:               v.SKU,
+		InventoryQuantity: v.InventoryQuantity,
+		ManageInventory:   v.InventoryItem.Tracked,
+		AllowBackorder:    v.InventoryPolicy == "CONTINUE",
+	}
+	for _, o := range v.SelectedOptions {
+		if isSizeOption(o.Name) {
+			cv.Size = o.Value
+		}
+	}
+	return cv
+}
+
+type shopifyProduct struct {
+	ID            string   `json:"id"`
+	Title         string   `json:"title"`
+	Description   string   `json:"description"`
+	Vendor        string   `json:"vendor"`
+	ProductType   string   `json:"productType"`
+	Tags          []string `json:"tags"`
+	Status        string   `json:"status"` // ACTIVE | ARCHIVED | DRAFT
+	FeaturedImage *struct {
+		URL string `json:"url"`
+	} `json:"featuredImage"`
+	Options []struct {
+		Name   string   `json:"name"`
+		Values []string `json:"values"`
+	} `json:"options"`
+	Metafields struct {
+		Nodes []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"nodes"`
+	} `json:"metafields"`
+	Variants shopifyVariants `json:"variants"`
+}
+
+type shopifyVariants struct {
+	PageInfo shopifyPageInfo  `json:"pageInfo"`
+	Nodes    []shopifyVariant `json:"nodes"`
+}
+
+type shopifyPageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+func (shopifyCatalog) Products(ctx context.Context, since time.Time) ([]catalog.Product, error) {
+	vars := map[string]any{"country": cfg().Shopify.PriceCountry, "ns": cfg().Shopify.Namespace}
+	if !since.IsZero() {
+		vars["query"] = fmt.Sprintf("updated_at:>='%s'", since.UTC().Format(time.RFC3339))
+	}
+	var out []catalog.Product
+	for {
+		var data struct {
+			Products struct {
+				PageInfo shopifyPageInfo  `json:"pageInfo"`
+				Nodes    []shopifyProduct `json:"nodes"`
+			} `json:"products"`
+		}
+		if err := shopifyQuery(ctx, shopifyProductsQuery, vars, &data); err != nil {
+			return nil, err
+		}
+		for _, p := range data.Products.Nodes {
+			if err := p.Variants.fetchRest(ctx, p.ID); err != nil {
+				return nil, err
+			}
+			out = append(out, p.catalogProduct())
+		}
+		if !data.Products.PageInfo.HasNextPage {
+			return out, nil
+		}
+		vars["cursor"] = data.Products.PageInfo.EndCursor
+	}
+}
+
+func (p shopifyProduct) catalogProduct() catalog.Product {
+	cp := catalog.Product{
+		ID:          p.ID,
+		Title:       p.Title,
+		Description: p.Description,
+		Tags:        p.Tags,
+		Metadata:    map[string]any{},
+	}
+	if p.FeaturedImage != nil {
+		cp.Thumbnail = p.FeaturedImage.URL
+	}
+	for _, o := range p.Options {
+		cp.Options = append(cp.Options, catalog.Option{Title: o.Name, Values: o.Values})
+	}
+	for _, m := range p.Metafields.Nodes {
+		cp.Metadata[m.Key] = metafieldValue(m.Value)
+	}
+	// fill the fields Medusa keeps in metadata from Shopify's own fields
+	if _, ok := cp.Metadata["brand"]; !ok && p.Vendor != "" {
+		cp.Metadata["brand"] = p.Vendor
+	}
+	if _, ok := cp.Metadata["slot"]; !ok {
+		if slot := categoryWords[strings.ToLower(strings.TrimSpace(p.ProductType))]; slot != "" {
+			cp.Metadata["slot"] = slot
+		}
+	}
+	cp.Material = catalog.MetaString(cp.Metadata, "material")
+	cp.Lifecycle = lifecycleFromShopify(p.Status, cp.Metadata)
+
+	price := math.Inf(1)
+	for _, v := range p.Variants.Nodes {
+		cp.Variants = append(cp.Variants, v.catalogVariant())
+		if cp.Origin == "" {
+			cp.Origin = v.InventoryItem.CountryCodeOfOrigin
+		}
+		pr := v.ContextualPricing.Price
+		if !strings.EqualFold(pr.CurrencyCode, priceCurrency) {
+			continue
+		}
+		if amt, err := strconv.ParseFloat(pr.Amount, 64); err == nil && amt < price {
+			price, cp.HasPrice = amt, true
+		}
+	}
+	if cp.HasPrice {
+		cp.PriceGBP = price
+	}
+	return cp
+}
+
+// metafieldValue decodes JSON-typed metafields (numbers, booleans, lists)
+// so they read like Medusa metadata; anything else stays a string.
+func metafieldValue(v string) any {
+	var x any
+	if err := json.Unmarshal([]byte(v), &x); err == nil {
+		return x
+	}
+	return v
+}
+
+func (shopifyCatalog) Stock(ctx context.Context) (map[string][]catalog.Variant, error) {
+	out := map[string][]catalog.Variant{}
+	vars := map[string]any{}
+	for {
+		var data struct {
+			Products struct {
+				PageInfo shopifyPageInfo `json:"pageInfo"`
+				Nodes    []struct {
+					ID       string          `json:"id"`
+					Variants shopifyVariants `json:"variants"`
+				} `json:"nodes"`
+			} `json:"products"`
+		}
+		if err := shopifyQuery(ctx, shopifyStockQuery, vars, &data); err != nil {
+			return nil, err
+		}
+		for _, p := range data.Products.Nodes {
+			if err := p.Variants.fetchRest(ctx, p.ID); err != nil {
+				return nil, err
+			}
+			variants := make([]catalog.Variant, 0, len(p.Variants.Nodes))
+			for _, v := range p.Variants.Nodes {
+				variants = append(variants, v.catalogVariant())
+			}
+			out[p.ID] = variants
+		}
+		if !data.Products.PageInfo.HasNextPage {
+			return out, nil
+		}
+		vars["cursor"] = data.Products.PageInfo.EndCursor
+	}
+}
+
+// fetchRest appends the product's variants past the first page.
+func (vs *shopifyVariants) fetchRest(ctx context.Context, productID string) error {
+	vars := map[string]any{"id": productID, "country": cfg().Shopify.PriceCountry}
+	for vs.PageInfo.HasNextPage {
+		vars["cursor"] = vs.PageInfo.EndCursor
+		var data struct {
+			Product *struct {
+				Variants shopifyVariants `json:"variants"`
+			} `json:"product"`
+		}
+		if err := shopifyQuery(ctx, shopifyVariantsQuery, vars, &data); err != nil {
+			return fmt.Errorf("variants of %s: %w", productID, err)
+		}
+		if data.Product == nil { // deleted since the product page was read
+			return nil
+		}
+		vs.Nodes = append(vs.Nodes, data.Product.Variants.Nodes...)
+		vs.PageInfo = data.Product.Variants.PageInfo
+	}
+	return nil
+}
+
+// shopifyQuery runs one Admin GraphQL query, waiting and retrying when the
+// shop's query cost budget is exhausted.
+func shopifyQuery(ctx context.Context, query string, vars map[string]any, data any) error {
+	url := fmt.Sprintf("https://%s/admin/api/%s/graphql.json", cfg().Shopify.ShopDomain, cfg().Shopify.APIVersion)
+	headers := map[string]string{"X-Shopify-Access-Token": cfg().Shopify.AdminToken}
+	for attempt := 0; ; attempt++ {
+		var resp struct {
+			Data   json.RawMessage `json:"data"`
+			Errors []struct {
+				Message    string `json:"message"`
+				Extensions struct {
+					Code string `json:"code"`
+				} `json:"extensions"`
+			} `json:"errors"`
+		}
+		if err := postJSON(ctx, url, headers, map[string]any{"query": query, "variables": vars}, &resp); err != nil {
+			return err
+		}
+		if len(resp.Errors) > 0 {
+			e := resp.Errors[0]
+			if e.Extensions.Code == "THROTTLED" && attempt < shopifyMaxThrottleRetries {
+				wait := time.Duration(attempt+1) * time.Second
+				slog.WarnContext(ctx, "shopify: throttled, retrying", "wait", wait)
+				select {
+				case <-time.After(wait):
+					continue
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return fmt.Errorf("graphql error: %s", e.Message)
+		}
+		return json.Unmarshal(resp.Data, data)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// shopifyCost estimates a query's requested cost the way Shopify does: 1 per
+// object, and 2 plus first times the node cost per connection. nodes and
+// pageInfo are free wrappers; scalars are free.
+func shopifyCost(t *testing.T, query string) int {
+	t.Helper()
+	toks := regexp.MustCompile(`[A-Za-z_$][A-Za-z0-9_]*|\d+|[{}():]`).FindAllString(query, -1)
+	i := 0
+	var selection func() int
+	selection = func() int { // at the token after "{"
+		cost := 0
+		for i < len(toks) && toks[i] != "}" {
+			name := toks[i]
+			i++
+			first := 0
+			if i < len(toks) && toks[i] == "(" {
+				for depth := 0; ; i++ {
+					switch toks[i] {
+					case "(":
+						depth++
+					case ")":
+						depth--
+					case "first":
+						first, _ = strconv.Atoi(toks[i+2])
+					}
+					if depth == 0 {
+						i++
+						break
+					}
+				}
+			}
+			if i >= len(toks) || toks[i] != "{" {
+				continue // scalar
+			}
+			i++
+			inner := selection()
+			switch {
+			case first > 0:
+				cost += 2 + first*inner
+			case name == "nodes":
+				cost += 1 + inner // the node itself; the connection multiplies it
+			case name == "pageInfo":
+			default:
+				cost += 1 + inner
+			}
+		}
+		i++ // "}"
+		return cost
+	}
+	for toks[i] != "{" {
+		if toks[i] == "(" { // the operation's variables
+			for toks[i] != ")" {
+				i++
+			}
+		}
+		i++
+	}
+	i++
+	return selection()
+}
+
+func TestShopifyQueryCosts(t *testing.T) {
+	for name, q := range map[string]string{
+		"products": shopifyProductsQuery,
+		"stock":    shopifyStockQuery,
+		"variants": shopifyVariantsQuery,
+	} {
+		switch c := shopifyCost(t, q); {
+		case c > 1000:
+			t.Errorf("%s query costs %d, want at most 1000", name, c)
+		case c < 100:
+			t.Errorf("%s query costs %d; the estimate missed its connections", name, c)
+		}
+	}
+}
+
+// fakeShopify answers Admin GraphQL queries with recorded responses, keyed
+// by operation name and cursor.
+type fakeShopify struct {
+	*httptest.Server
+	responses map[string]string // "Products:" + cursor, "Variants:" + id + ":" + cursor, ...
+	calls     []string
+}
+
+func newFakeShopify(t *testing.T) *fakeShopify {
+	t.Helper()
+	f := &fakeShopify{responses: map[string]string{}}
+	op := regexp.MustCompile(`query (\w+)`)
+	f.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/api/2025-01/graphql.json" || r.Header.Get("X-Shopify-Access-Token") != "shpat_test" {
+			http.Error(w, `{"errors":"not found"}`, http.StatusNotFound)
+			return
+		}
+		var body struct {
+			Query     string            `json:"query"`
+			Variables map[string]string `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		key := op.FindStringSubmatch(body.Query)[1] + ":"
+		if id := body.Variables["id"]; id != "" {
+			key += id + ":"
+		}
+		key += body.Variables["cursor"]
+		f.calls = append(f.calls, key)
+		resp, ok := f.responses[key]
+		if !ok {
+			t.Errorf("unexpected query %s", key)
+			resp = `{"errors":[{"message":"unexpected"}]}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(f.Close)
+
+	prev := httpClient
+	httpClient = f.Client()
+	t.Cleanup(func() { httpClient = prev })
+	useTestConfig(t, map[string]string{
+		"SHOPIFY_SHOP_DOMAIN": strings.TrimPrefix(f.URL, "https://"),
+		"SHOPIFY_ADMIN_TOKEN": "shpat_test",
+		"SHOPIFY_API_VERSION": "2025-01",
+	})
+	return f
+}
+
+func shopifyVariantFixture(id, size string, qty int, policy, amount, currency string) string {
+	return fmt.Sprintf(`{"id": "gid://shopify/ProductVariant/%s", "title": %q, "sku": "", "inventoryQuantity": %d, "inventoryPolicy": %q,
+  "selectedOptions": [{"name": "Size", "value": %q}], "inventoryItem": {"tracked": true, "countryCodeOfOrigin": "PT"},
+  "contextualPricing": {"price": {"amount": %q, "currencyCode": %q}}}`, id, size, qty, policy, size, amount, currency)
+}
+
+func TestShopifyCatalogProducts(t *testing.T) {
+	f := newFakeShopify(t)
+	f.responses["Products:"] = `{"data": {"products": {
+  "pageInfo": {"hasNextPage": true, "endCursor": "c1"},
+  "nodes": [{
+    "id": "gid://shopify/Product/1", "title": "Linen Shirt", "description": "Airy.", "vendor": "Acme",
+    "productType": "Tops", "tags": ["summer"], "status": "ACTIVE", "featuredImage": {"url": "https://cdn/x.jpg"},
+    "options": [{"name": "Size", "values": ["S", "M", "L"]}],
+    "metafields": {"nodes": [{"key": "eco_score", "value": "72"}, {"key": "material", "value": "linen"}]},
+    "variants": {"pageInfo": {"hasNextPage": true, "endCursor": "v1"}, "nodes": [` +
+		shopifyVariantFixture("11", "S", 0, "DENY", "45.00", "GBP") + `]}
+  }]}}}`
+	f.responses["Variants:gid://shopify/Product/1:v1"] = `{"data": {"product": {"variants": {
+  "pageInfo": {"hasNextPage": false, "endCursor": "v2"}, "nodes": [` +
+		shopifyVariantFixture("12", "M", 4, "DENY", "39.00", "GBP") + `, ` +
+		shopifyVariantFixture("13", "L", 0, "CONTINUE", "35.00", "EUR") + `]}}}}`
+	f.responses["Products:c1"] = `{"data": {"products": {
+  "pageInfo": {"hasNextPage": false, "endCursor": "c2"},
+  "nodes": [{
+    "id": "gid://shopify/Product/2", "title": "Old Boots", "description": "", "vendor": "",
+    "productType": "", "tags": [], "status": "ARCHIVED", "featuredImage": null, "options": [],
+    "metafields": {"nodes": [{"key": "slot", "value": "shoes"}]},
+    "variants": {"pageInfo": {"hasNextPage": false, "endCursor": ""}, "nodes": []}
+  }]}}}`
+
+	products, err := shopifyCatalog{}.Products(context.Background(), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Products:", "Variants:gid://shopify/Product/1:v1", "Products:c1"}; !reflect.DeepEqual(f.calls, want) {
+		t.Errorf("queries = %v, want %v", f.calls, want)
+	}
+	if len(products) != 2 {
+		t.Fatalf("got %d products, want 2", len(products))
+	}
+
+	shirt := products[0]
+	if len(shirt.Variants) != 3 {
+		t.Fatalf("shirt variants = %+v, want all 3 pages' worth", shirt.Variants)
+	}
+	// the cheapest GBP price; the EUR one is ignored
+	if !shirt.HasPrice || shirt.PriceGBP != 39 {
+		t.Errorf("shirt price = %v (has %v), want 39", shirt.PriceGBP, shirt.HasPrice)
+	}
+	if shirt.Metadata["brand"] != "Acme" || shirt.Metadata["slot"] != "top" || shirt.Metadata["eco_score"] != 72.0 {
+		t.Errorf("shirt metadata = %v", shirt.Metadata)
+	}
+	if shirt.Material != "linen" || shirt.Origin != "PT" || shirt.Thumbnail != "https://cdn/x.jpg" {
+		t.Errorf("shirt = %+v", shirt)
+	}
+	if l := shirt.Variants[2]; l.Size != "L" || !l.AllowBackorder || !l.ManageInventory {
+		t.Errorf("size L = %+v", l)
+	}
+
+	boots := products[1]
+	if boots.Metadata["slot"] != "shoes" || boots.HasPrice || boots.Lifecycle == lifecycleActive {
+		t.Errorf("boots = %+v", boots)
+	}
+}
+
+func TestShopifyCatalogStock(t *testing.T) {
+	f := newFakeShopify(t)
+	f.responses["Stock:"] = `{"data": {"products": {
+  "pageInfo": {"hasNextPage": false, "endCursor": "c1"},
+  "nodes": [{"id": "gid://shopify/Product/1", "variants": {"pageInfo": {"hasNextPage": true, "endCursor": "v1"}, "nodes": [` +
+		shopifyVariantFixture("11", "S", 2, "DENY", "45.00", "GBP") + `]}}]}}}`
+	f.responses["Variants:gid://shopify/Product/1:v1"] = `{"data": {"product": null}}`
+
+	stock, err := shopifyCatalog{}.Stock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a product deleted between the pages keeps the variants already read
+	if vs := stock["gid://shopify/Product/1"]; len(vs) != 1 || vs[0].InventoryQuantity != 2 || vs[0].Size != "S" {
+		t.Errorf("stock = %+v", stock)
+	}
+}
+
+func TestShopifyQueryError(t *testing.T) {
+	f := newFakeShopify(t)
+	f.responses["Stock:"] = `{"errors": [{"message": "Query cost is 2003, which exceeds the single query max cost limit (1000).",
+  "extensions": {"code": "MAX_COST_EXCEEDED"}}]}`
+
+	_, err := shopifyCatalog{}.Stock(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "exceeds the single query max cost limit") {
+		t.Errorf("err = %v, want the cost error", err)
+	}
+	if len(f.calls) != 1 {
+		t.Errorf("retried a query that can never succeed: %v", f.calls)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// routedCategories is how many categories an ambiguous query is split across.
+const routedCategories = 2
+
+type categoryCentroid struct {
+	category string
+	vec      []float64
+}
+
+// centroids caches mv_category_centroids per catalog (real or sandbox) until
+// the next view refresh is due.
+var centroids = struct {
+	sync.Mutex
+	sets map[bool]centroidSet
+}{sets: map[bool]centroidSet{}}
+
+type centroidSet struct {
+	list   []categoryCentroid
+	loaded time.Time
+}
+
+func loadCentroids(ctx context.Context, pool *pgxpool.Pool) ([]categoryCentroid, error) {
+	sandbox := sandboxFrom(ctx)
+	centroids.Lock()
+	set, ok := centroids.sets[sandbox]
+	centroids.Unlock()
+	if ok && time.Since(set.loaded) < cfg().MVRefreshInterval {
+		return set.list, nil
+	}
+
+	rows, err := pool.Query(ctx, `SELECT category, centroid::text FROM mv_category_centroids WHERE sandbox = $1`, sandbox)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []categoryCentroid
+	for rows.Next() {
+		var c categoryCentroid
+		var vec string
+		if err := rows.Scan(&c.category, &vec); err != nil {
+			return nil, err
+		}
+		if c.vec, err = parseVector(vec); err != nil {
+			return nil, fmt.Errorf("centroid %s: %w", c.category, err)
+		}
+		list = append(list, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	centroids.Lock()
+	centroids.sets[sandbox] = centroidSet{list: list, loaded: time.Now()}
+	centroids.Unlock()
+	return list, nil
+}
+
+// ambiguousCategories returns the two categories closest to the query when
+// neither is ahead by CSA_CATEGORY_ROUTING_MARGIN, or nil when the query
+// clearly points at one category (or there are too few to choose from).
+func ambiguousCategories(ctx context.Context, pool *pgxpool.Pool, qVec []float64) ([]string, error) {
+	list, err := loadCentroids(ctx, pool)
+	if err != nil || len(list) < routedCategories {
+		return nil, err
+	}
+	type scored struct {
+		category string
+		sim      float64
+	}
+	sims := make([]scored, 0, len(list))
+	for _, c := range list {
+		if len(c.vec) == len(qVec) {
+			sims = append(sims, scored{c.category, cosine(qVec, c.vec)})
+		}
+	}
+	if len(sims) < routedCategories {
+		return nil, nil
+	}
+	sort.Slice(sims, func(i, j int) bool { return sims[i].sim > sims[j].sim })
+	if sims[0].sim-sims[1].sim >= cfg().CategoryRouting {
+		return nil, nil
+	}
+	return []string{sims[0].category, sims[1].category}, nil
+}
+
+// searchRouted is searchHits for /search. A semantic query without a
+// category that sits between two categories is searched in each of them and
+// the results merged by ranking score, so a vague "something warm" finds both
+// jumpers and coats rather than whichever category the nearest few products
+// happen to share. Refinements are never routed: their products are already
+// chosen. It returns the categories searched, if routed.
+func searchRouted(ctx context.Context, pool *pgxpool.Pool, p searchParams) ([]Hit, []string, error) {
+	if p.vectorless() || p.Category != "" || p.Within != nil || cfg().CategoryRouting == 0 {
+		hits, err := searchHits(ctx, pool, p)
+		return hits, nil, err
+	}
+	qEmb, err := embedQuery(ctx, p.Query)
+	if err != nil {
+		return nil, nil, err
+	}
+	cats, err := ambiguousCategories(ctx, pool, qEmb)
+	if err != nil {
+		// routing only helps recall; search unrouted rather than fail
+		slog.WarnContext(ctx, "centroids: routing failed", "err", err)
+	}
+	if len(cats) == 0 {
+		hits, err := searchHits(ctx, pool, p)
+		return hits, nil, err
+	}
+
+	var merged []Hit
+	seen := map[string]bool{}
+	for _, c := range cats {
+		cp := p
+		cp.Category = c
+		hits, err := searchHits(ctx, pool, cp)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, h := range hits {
+			if !seen[h.ProductID] {
+				seen[h.ProductID] = true
+				merged = append(merged, h)
+			}
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].rankScore() > merged[j].rankScore() })
+	if merged, err = applySort(ctx, pool, merged, p.SortBy); err != nil {
+		return nil, nil, err
+	}
+	if len(merged) > p.Limit {
+		merged = merged[:p.Limit]
+	}
+	return merged, cats, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+)
+
+// slotAccessory is only ever assigned by the classifier; no built-in mission
+// needs it, though a tenant mission (see missions.go) may.
+const slotAccessory = "accessory"
+
+var classifierSlots = []string{"top", "bottom", "shoes", "outerwear", slotAccessory}
+
+// slotKeywords maps product-type words to slots. Unlike categoryWords these
+// include specific types, since here they name what a product is.
+var slotKeywords = map[string]string{
+	"t-shirt": "top", "tshirt": "top", "tee": "top", "shirt": "top", "blouse": "top", "top": "top",
+	"jumper": "top", "sweater": "top", "sweatshirt": "top", "hoodie": "top", "polo": "top",
+	"cardigan": "top", "tank": "top", "camisole": "top", "knit": "top", "pullover": "top",
+
+	"jeans": "bottom", "trousers": "bottom", "chinos": "bottom", "shorts": "bottom", "skirt": "bottom",
+	"leggings": "bottom", "joggers": "bottom", "pants": "bottom", "culottes": "bottom", "trouser": "bottom",
+
+	"trainer": "shoes", "trainers": "shoes", "sneaker": "shoes", "sneakers": "shoes", "boot": "shoes",
+	"boots": "shoes", "loafer": "shoes", "loafers": "shoes", "sandal": "shoes", "sandals": "shoes",
+	"shoe": "shoes", "shoes": "shoes", "brogues": "shoes", "heels": "shoes", "espadrilles": "shoes",
+	"slippers": "shoes", "mules": "shoes",
+
+	"jacket": "outerwear", "coat": "outerwear", "parka": "outerwear", "raincoat": "outerwear",
+	"gilet": "outerwear", "anorak": "outerwear", "blazer": "outerwear", "windbreaker": "outerwear",
+	"mac": "outerwear", "puffer": "outerwear", "overcoat": "outerwear", "trench": "outerwear",
+
+	"bag": slotAccessory, "backpack": slotAccessory, "tote": slotAccessory, "belt": slotAccessory,
+	"scarf": slotAccessory, "hat": slotAccessory, "cap": slotAccessory, "beanie": slotAccessory,
+	"gloves": slotAccessory, "sunglasses": slotAccessory, "watch": slotAccessory, "wallet": slotAccessory,
+	"socks": slotAccessory, "tie": slotAccessory, "umbrella": slotAccessory,
+}
+
+var wordPattern = regexp.MustCompile(`[a-z]+(?:-[a-z]+)?`)
+
+// keywordSlot picks a slot from the title, where the last product word wins
+// ("shirt jacket" is a jacket), then from the description's most frequent one.
+func keywordSlot(title, description string) string {
+	slot := ""
+	for _, w := range wordPattern.FindAllString(strings.ToLower(title), -1) {
+		if s := slotKeywords[w]; s != "" {
+			slot = s
+		}
+	}
+	if slot != "" {
+		return slot
+	}
+
+	counts := map[string]int{}
+	for _, w := range wordPattern.FindAllString(strings.ToLower(description), -1) {
+		if s := slotKeywords[w]; s != "" {
+			counts[s]++
+		}
+	}
+	best := 0
+	for _, s := range classifierSlots { // fixed order keeps ties deterministic
+		if counts[s] > best {
+			slot, best = s, counts[s]
+		}
+	}
+	return slot
+}
+
+// clipUTF8 cuts s to at most n bytes without splitting a character.
+func clipUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+var categorySchema = &outputSchema{Name: "category", Schema: map[string]any{
+	"type":                 "object",
+	"properties":           map[string]any{"category": map[string]any{"type": "string", "enum": append(classifierSlots, "other")}},
+	"required":             []string{"category"},
+	"additionalProperties": false,
+}}
+
+// llmSlot asks the default chat provider for the product's slot; "" means
+// the model chose "other".
+func llmSlot(ctx context.Context, title, description string) (string, error) {
+	description = clipUTF8(description, 1000)
+	prompt := fmt.Sprintf(`Classify this clothing store product into one outfit slot.
+Slots: top (shirts, knitwear), bottom (trousers, skirts, shorts), shoes, outerwear (jackets, coats), accessory (bags, hats, belts, jewellery), other.
+Return only a JSON object {"category": "<slot>"}.
+
+Title: %q
+Description: %q`, title, description)
+
+	raw, err := llmChat(ctx, "", config.LLMCategory, prompt, categorySchema)
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		Category string `json:"category"`
+	}
+	if err := json.Unmarshal([]byte(ranking.StripCodeFence(raw)), &out); err != nil {
+		return "", fmt.Errorf("category classifier: %w", err)
+	}
+	switch c := strings.ToLower(strings.TrimSpace(out.Category)); {
+	case c == "other":
+		return "", nil
+	case categoryWords[c] != "":
+		return categoryWords[c], nil
+	case c == slotAccessory:
+		return c, nil
+	}
+	return "", fmt.Errorf("category classifier: unexpected category %q", out.Category)
+}
+
+// classifySlot assigns a slot to a product whose metadata has none, per
+// CSA_CATEGORY_CLASSIFIER. It returns "" when nothing fits.
+func classifySlot(ctx context.Context, p catalog.Product) string {
+	switch cfg().CategoryClassifier {
+	case config.ClassifierLLM:
+		slot, err := llmSlot(ctx, p.Title, p.Description)
+		if err == nil {
+			return slot
+		}
+		slog.WarnContext(ctx, "index: llm category classification failed, using keywords", "product_id", p.ID, "err", err)
+	case config.ClassifierOff:
+		return ""
+	}
+	return keywordSlot(p.Title, p.Description)
+}
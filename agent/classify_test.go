@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestKeywordSlot(t *testing.T) {
+	for _, tc := range []struct {
+		title, description, want string
+	}{
+		{"Linen Shirt", "", "top"},
+		{"Organic Cotton T-Shirt", "", "top"},
+		{"RAIN BOOTS", "", "shoes"},
+		{"Slim Chinos", "A jacket-friendly trouser.", "bottom"},
+		// the last product word in the title wins
+		{"Shirt Jacket", "", "outerwear"},
+		{"Jacket Shirt", "", "top"},
+		{"Leather Belt Bag", "", slotAccessory},
+		// then the description's most frequent product word
+		{"The Everyday", "Wear it with jeans, a tee, or your favourite jeans.", "bottom"},
+		{"The Everyday", "Pairs with boots and shorts.", "bottom"}, // ties go to the earlier slot
+		{"The Everyday", "Looks sharp over a shirt, with boots.", "top"},
+		// whole words only
+		{"Capsule Wardrobe Set", "", ""},
+		{"Macbook Sleeve", "", ""},
+		{"Tie-Dye Throw", "", ""},
+		{"Topshop Exclusive", "", ""},
+		{"", "", ""},
+		{"Gift Card", "Spend it on anything.", ""},
+	} {
+		if got := keywordSlot(tc.title, tc.description); got != tc.want {
+			t.Errorf("keywordSlot(%q, %q) = %q, want %q", tc.title, tc.description, got, tc.want)
+		}
+	}
+}
+
+func TestClipUTF8(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello", 3, "hel"},
+		{"héllo", 2, "h"}, // é is 2 bytes
+		{"héllo", 3, "hé"},
+		{"日本語", 4, "日"},
+		{"日本語", 2, ""},
+		{"👟👟", 5, "👟"},
+		{"abc", 0, ""},
+	} {
+		got := clipUTF8(tc.s, tc.n)
+		if got != tc.want || !utf8.ValidString(got) {
+			t.Errorf("clipUTF8(%q, %d) = %q, want %q", tc.s, tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestLLMSlotClipsDescription(t *testing.T) {
+	useTestConfig(t, nil)
+	chat := useCannedChat(t, &cannedChat{def: `{"category": "shoes"}`})
+
+	// 999 bytes, then a 3-byte character straddling the cut
+	desc := strings.Repeat("a", 999) + "語" + strings.Repeat("b", 100)
+	slot, err := llmSlot(context.Background(), "Trainers", desc)
+	if err != nil || slot != "shoes" {
+		t.Fatalf("llmSlot = %q, %v", slot, err)
+	}
+	prompt := chat.calls()[0].Prompt
+	if !utf8.ValidString(prompt) || strings.Contains(prompt, "\\x") || strings.Contains(prompt, "語") {
+		t.Errorf("prompt has a split character or too much description: %q", prompt[len(prompt)-40:])
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// Clearance modes for recommendation requests.
+const (
+	clearancePrefer = "prefer" // rank clearance stock first among matching products
+	clearanceOnly   = "only"   // recommend clearance stock only
+)
+
+// ranking weight of the clearance flag in prefer mode; high enough to lift
+// clearance items over close matches, not over clearly better ones
+const clearancePreferBoost = 0.25
+
+const maxMerchandisingPerRequest = 1000
+
+// ClearancePrefs bias recommendations toward clearance stock. Shopper
+// filters (budget, eco score, attributes) still apply in every mode.
+type ClearancePrefs struct {
+	ClearanceMode string   `json:"clearance_mode,omitempty"` // prefer | only
+	MinMarginPct  *float64 `json:"min_margin_pct,omitempty"` // skip products below this margin; unknown margins pass
+}
+
+func (c ClearancePrefs) validate() error {
+	switch c.ClearanceMode {
+	case "", clearancePrefer, clearanceOnly:
+	default:
+		return httpapi.InvalidField("clearance_mode", "clearance_mode must be %q or %q", clearancePrefer, clearanceOnly)
+	}
+	if c.MinMarginPct != nil && (*c.MinMarginPct < -100 || *c.MinMarginPct > 100) {
+		return httpapi.InvalidField("min_margin_pct", "min_margin_pct must be between -100 and 100")
+	}
+	return nil
+}
+
+// checkMarginFloor refuses min_margin_pct from callers without write scope.
+// Margins are merchant data, and on an open read route anyone could vary the
+// floor and watch which products drop out to learn each one's margin.
+func (c ClearancePrefs) checkMarginFloor(ctx context.Context) error {
+	if c.MinMarginPct != nil && !principalFrom(ctx).has(scopeWrite) {
+		return errMarginFloorScope
+	}
+	return nil
+}
+
+var errMarginFloorScope = fmt.Errorf("min_margin_pct needs an API key with %q scope", scopeWrite)
+
+// MerchandisingReq sets clearance flags and margins for indexed products.
+// Omitted fields are left unchanged.
+type MerchandisingReq struct {
+	Products []struct {
+		ProductID string   `json:"product_id"`
+		Clearance *bool    `json:"clearance,omitempty"`
+		MarginPct *float64 `json:"margin_pct,omitempty"`
+	} `json:"products"`
+}
+
+type MerchandisingResp struct {
+	Updated int      `json:"updated"`
+	Unknown []string `json:"unknown"` // product IDs not indexed for the tenant
+}
+
+func (m MerchandisingReq) validate() error {
+	if len(m.Products) == 0 {
+		return httpapi.InvalidField("products", "products is required")
+	}
+	if len(m.Products) > maxMerchandisingPerRequest {
+		return httpapi.InvalidField("products", "at most %d products per request", maxMerchandisingPerRequest)
+	}
+	for i, p := range m.Products {
+		if p.ProductID == "" {
+			return httpapi.InvalidField(fmt.Sprintf("products[%d].product_id", i), "products[%d] needs product_id", i)
+		}
+		if p.MarginPct != nil && (*p.MarginPct < -100 || *p.MarginPct > 100) {
+			return httpapi.InvalidField(fmt.Sprintf("products[%d].margin_pct", i), "products[%d].margin_pct must be between -100 and 100", i)
+		}
+	}
+	return nil
+}
+
+func saveMerchandising(ctx context.Context, pool *pgxpool.Pool, tenantID string, req MerchandisingReq) (MerchandisingResp, error) {
+	resp := MerchandisingResp{Unknown: []string{}}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return resp, err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, p := range req.Products {
+		tag, err := tx.Exec(ctx, `
+UPDATE product_embeddings
+SET clearance=COALESCE($3, clearance), margin_pct=COALESCE($4, margin_pct)
+WHERE product_id=$1 AND COALESCE(tenant_id, $5)=$2
+`, p.ProductID, tenantID, p.Clearance, p.MarginPct, defaultTenant)
+		if err != nil {
+			return resp, err
+		}
+		if tag.RowsAffected() == 0 {
+			resp.Unknown = append(resp.Unknown, p.ProductID)
+			continue
+		}
+		resp.Updated++
+	}
+	return resp, tx.Commit(ctx)
+}
+
+// applyClearance marks clearance hits and, in prefer mode, ranks them up.
+func applyClearance(ctx context.Context, pool *pgxpool.Pool, hits []Hit, prefs ClearancePrefs) ([]Hit, error) {
+	if len(hits) == 0 || prefs.ClearanceMode == "" {
+		return hits, nil
+	}
+	if prefs.ClearanceMode == clearanceOnly {
+		for i := range hits {
+			hits[i].Clearance = true // the query already filtered
+		}
+		return hits, nil
+	}
+
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ProductID
+	}
+	rows, err := pool.Query(ctx, `
+SELECT product_id FROM product_embeddings WHERE product_id = ANY($1) AND clearance
+`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	onClearance := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		onClearance[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	score := func(h Hit) float64 {
+		s := (1 - clearancePreferBoost) * h.rankScore()
+		if h.Clearance {
+			s += clearancePreferBoost * 100
+		}
+		return s
+	}
+	for i := range hits {
+		hits[i].Clearance = onClearance[hits[i].ProductID]
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return score(hits[i]) > score(hits[j]) })
+	return hits, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCheckMarginFloor(t *testing.T) {
+	floor := 20.0
+	as := func(scopes ...string) context.Context {
+		return context.WithValue(context.Background(), ctxPrincipal, &principal{KeyID: "k1", TenantID: "t", Scopes: scopes})
+	}
+	for _, tc := range []struct {
+		name  string
+		ctx   context.Context
+		prefs ClearancePrefs
+		ok    bool
+	}{
+		{"no floor, anonymous", context.Background(), ClearancePrefs{ClearanceMode: clearanceOnly}, true},
+		{"floor, anonymous", context.Background(), ClearancePrefs{MinMarginPct: &floor}, false},
+		{"floor, read key", as(scopeRead), ClearancePrefs{MinMarginPct: &floor}, false},
+		{"floor, write key", as(scopeWrite), ClearancePrefs{MinMarginPct: &floor}, true},
+		{"floor, admin key", as(scopeAdmin), ClearancePrefs{MinMarginPct: &floor}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.prefs.checkMarginFloor(tc.ctx)
+			if (err == nil) != tc.ok {
+				t.Errorf("checkMarginFloor = %v, want ok=%v", err, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func newIndexCmd(c *client) *cobra.Command {
+	var mode string
+	var maxTokens int
+	var maxCost float64
+	cmd := &cobra.Command{
+		Use:   "index",
+		Short: "Index the tenant's catalog (POST /index-products)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			q := url.Values{}
+			if mode != "" {
+				q.Set("mode", mode)
+			}
+			if maxTokens > 0 {
+				q.Set("max_tokens", strconv.Itoa(maxTokens))
+			}
+			if maxCost > 0 {
+				q.Set("max_cost_usd", strconv.FormatFloat(maxCost, 'f', -1, 64))
+			}
+			path := "/index-products"
+			if len(q) > 0 {
+				path += "?" + q.Encode()
+			}
+			resp, err := c.do(cmd.Context(), http.MethodPost, path, nil)
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd.OutOrStdout(), resp)
+		},
+	}
+	cmd.Flags().StringVar(&mode, "mode", "", "full or incremental (default full)")
+	cmd.Flags().IntVar(&maxTokens, "max-tokens", 0, "embedding token budget for the run")
+	cmd.Flags().Float64Var(&maxCost, "max-cost-usd", 0, "embedding budget for the run in USD")
+	return cmd
+}
+
+func newSearchCmd(c *client) *cobra.Command {
+	var body string
+	cmd := &cobra.Command{
+		Use:   "search [query]",
+		Short: "Search the catalog (POST /search)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req, err := requestBody(body, cmd.LocalNonPersistentFlags())
+			if err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				req["query"] = args[0]
+			}
+			resp, err := c.do(cmd.Context(), http.MethodPost, "/search", req)
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd.OutOrStdout(), resp)
+		},
+	}
+	cmd.Flags().StringVar(&body, "body", "", "JSON request body to start from: a file, or - for stdin")
+	cmd.Flags().Int("limit", 0, "hits to return")
+	cmd.Flags().String("category", "", "only this category")
+	cmd.Flags().Float64("max-price-gbp", 0, "price ceiling")
+	cmd.Flags().Int("min-eco-score", 0, "eco score floor")
+	cmd.Flags().String("sort-by", "", "price_asc, price_desc, eco_desc, newest or popularity")
+	cmd.Flags().String("within", "", "response_id of an earlier search to refine")
+	return cmd
+}
+
+func newCompleteOutfitCmd(c *client) *cobra.Command {
+	var body string
+	cmd := &cobra.Command{
+		Use:   "complete-outfit",
+		Short: "Recommend items to complete an outfit (POST /complete-outfit)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			req, err := requestBody(body, cmd.LocalNonPersistentFlags())
+			if err != nil {
+				return err
+			}
+			resp, err := c.do(cmd.Context(), http.MethodPost, "/complete-outfit", req)
+			if err != nil {
+				return err
+			}
+			return printJSON(cmd.OutOrStdout(), resp)
+		},
+	}
+	cmd.Flags().StringVar(&body, "body", "", "JSON request body to start from: a file, or - for stdin")
+	cmd.Flags().String("mission", "", "mission, e.g. smart_casual (see GET /missions)")
+	cmd.Flags().Float64("budget-gbp", 0, "budget for the added items")
+	cmd.Flags().StringSlice("cart-slots", nil, "slots already in the cart, e.g. top,outerwear")
+	cmd.Flags().String("cart-id", "", "Medusa cart ID, instead of --cart-slots")
+	cmd.Flags().Int("limit-per-slot", 0, "hits per slot")
+	cmd.Flags().Int("min-eco-score", 0, "eco score floor")
+	cmd.Flags().String("query", "", `free text, e.g. "something for a rainy hike"`)
+	cmd.Flags().Bool("debug", false, "show slot thresholds and similarity margins")
+	return cmd
+}
+
+func newExportCmd(c *client) *cobra.Command {
+	var out, category string
+	var images bool
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write the tenant's embeddings as JSONL (GET /export-embeddings)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			q := url.Values{}
+			if category != "" {
+				q.Set("category", category)
+			}
+			if images {
+				q.Set("images", "true")
+			}
+			path := "/export-embeddings"
+			if len(q) > 0 {
+				path += "?" + q.Encode()
+			}
+			resp, err := c.do(cmd.Context(), http.MethodGet, path, nil)
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			w := cmd.OutOrStdout()
+			var f *os.File
+			if out != "-" {
+				if f, err = os.Create(out); err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			// a failed export ends the stream early, so this is how it shows
+			if n, err := io.Copy(w, resp.Body); err != nil {
+				return fmt.Errorf("export interrupted after %d bytes: %w", n, err)
+			}
+			if f != nil {
+				return f.Close()
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&out, "out", "o", "-", "output JSONL file (- = stdout)")
+	cmd.Flags().StringVar(&category, "category", "", "only this category")
+	cmd.Flags().BoolVar(&images, "images", false, "include image embeddings")
+	return cmd
+}
+
+// requestBody builds a JSON body from a --body file (or stdin), then sets
+// every flag given on the command line under its JSON name (--max-price-gbp
+// is max_price_gbp), so flags override the file.
+func requestBody(path string, flags *pflag.FlagSet) (map[string]any, error) {
+	req := map[string]any{}
+	if path != "" {
+		var b []byte
+		var err error
+		if path == "-" {
+			b, err = io.ReadAll(os.Stdin)
+		} else {
+			b, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &req); err != nil {
+			return nil, fmt.Errorf("--body: %w", err)
+		}
+	}
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if !f.Changed || f.Name == "body" || err != nil {
+			return
+		}
+		name := strings.ReplaceAll(f.Name, "-", "_")
+		switch f.Value.Type() {
+		case "string":
+			req[name] = f.Value.String()
+		case "stringSlice":
+			req[name], err = flags.GetStringSlice(f.Name)
+		default: // numbers and bools are valid JSON as printed
+			req[name] = json.RawMessage(f.Value.String())
+		}
+	})
+	return req, err
+}
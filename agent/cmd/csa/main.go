@@ -0,0 +1,165 @@
+// Command csa is a command-line client for the shopping agent, for ops
+// scripts and local development. index, search, complete-outfit and export
+// call a running agent's HTTP API, so they behave exactly as the API does;
+// migrate applies the schema straight to the database, with the migrations
+// the agent embeds.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/spf13/cobra"
+)
+
+// client calls the agent's HTTP API.
+type client struct {
+	baseURL string
+	apiKey  string
+	tenant  string
+	timeout time.Duration // for the agent to start answering; exports then stream as long as they take
+}
+
+func main() {
+	godotenv.Load()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := newRootCmd().ExecuteContext(ctx); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	c := &client{}
+	root := &cobra.Command{
+		Use:   "csa",
+		Short: "Command-line client for the contextual shopping agent",
+		Long: "csa calls a running agent's HTTP API (index, search, complete-outfit, export)\n" +
+			"or migrates its database (migrate). Settings also come from the environment or .env.",
+		SilenceUsage: true,
+	}
+	root.PersistentFlags().StringVar(&c.baseURL, "url", defaultURL(), "agent base URL (CSA_URL)")
+	root.PersistentFlags().StringVar(&c.apiKey, "api-key", firstSet(os.Getenv("CSA_API_KEY"), os.Getenv("CSA_ADMIN_API_KEY")),
+		"API key to send (CSA_API_KEY, else CSA_ADMIN_API_KEY)")
+	root.PersistentFlags().StringVar(&c.tenant, "tenant", os.Getenv("CSA_TENANT"), "tenant to act for, for keys that may choose (CSA_TENANT)")
+
+	root.PersistentFlags().DurationVar(&c.timeout, "timeout", 2*time.Minute, "how long to wait for the agent to start answering (0 waits forever)")
+
+	root.AddCommand(newIndexCmd(c), newSearchCmd(c), newCompleteOutfitCmd(c), newExportCmd(c), newMigrateCmd())
+	return root
+}
+
+// defaultURL is CSA_URL, else the agent on this machine at CSA_PORT.
+func defaultURL() string {
+	if u := os.Getenv("CSA_URL"); u != "" {
+		return u
+	}
+	return "http://localhost:" + firstSet(os.Getenv("CSA_PORT"), "8181")
+}
+
+// firstSet returns the first of vs that isn't empty.
+func firstSet(vs ...string) string {
+	for _, v := range vs {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// httpClient connects within 10s and waits up to c.timeout for the
+// response headers. The body has no deadline, so a long export isn't cut
+// off halfway.
+func (c *client) httpClient() *http.Client {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	t.ResponseHeaderTimeout = c.timeout
+	return &http.Client{Transport: t}
+}
+
+// do sends a request to the agent and returns the response for the caller
+// to read and close. Error statuses become errors carrying the agent's
+// message.
+func (c *client) do(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.baseURL, "/")+path, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	if c.tenant != "" {
+		req.Header.Set("X-Tenant-ID", c.tenant)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, apiError(resp)
+	}
+	return resp, nil
+}
+
+// apiError describes an error response: the agent's message and field
+// errors when it sent its usual JSON, else the raw body.
+func apiError(resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	var e struct {
+		Message     string `json:"message"`
+		FieldErrors []struct {
+			Field   string `json:"field"`
+			Message string `json:"message"`
+		} `json:"field_errors"`
+	}
+	if json.Unmarshal(b, &e) != nil || e.Message == "" {
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+	msg := resp.Status + ": " + e.Message
+	for _, fe := range e.FieldErrors {
+		msg += fmt.Sprintf("\n  %s: %s", fe.Field, fe.Message)
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// printJSON sends the response to out, indented when it is JSON.
+func printJSON(out io.Writer, resp *http.Response) error {
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if json.Indent(&buf, b, "", "  ") != nil {
+		_, err = out.Write(b)
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err = buf.WriteTo(out)
+	return err
+}
@@ -0,0 +1,60 @@
+package main
+
+import "math"
+
+// Confidence blends how well the top pick matches, how far it leads the
+// runner-up and how much room the constraints left. A pick that only just
+// fits the budget or eco floor was likely chosen because little else did.
+const (
+	confidenceMatchWeight = 0.5
+	confidenceGapWeight   = 0.2
+	confidenceSlackWeight = 0.3
+
+	// a top pick scoring this fraction above the runner-up is a clear lead
+	confidenceClearLead = 0.1
+)
+
+// SlotConfidence says how sure the agent is of a slot's top pick. Low
+// picks are best shown as ideas rather than recommendations.
+type SlotConfidence struct {
+	Score float64 `json:"score"` // 0-1
+	Low   bool    `json:"low"`   // below CSA_LOW_CONFIDENCE
+	Match float64 `json:"match"` // 0-1: the top pick's ranking score
+	Gap   float64 `json:"gap"`   // 0-1: its lead over the second pick
+	Slack float64 `json:"slack"` // 0-1: headroom under the slot budget and over the eco floor
+}
+
+// slotConfidence scores hits, ranked best first, against the slot budget and
+// minimum eco score they were chosen under (0 = none). It is nil for a slot
+// with no hits.
+func slotConfidence(hits []Hit, slotBudget float64, minEco int) *SlotConfidence {
+	if len(hits) == 0 {
+		return nil
+	}
+	top := hits[0]
+	c := &SlotConfidence{Match: clamp01(top.rankScore() / 100)}
+
+	c.Gap = 0.5 // a lone hit has no rival to lead; call it neither way
+	if len(hits) > 1 && top.rankScore() > 0 {
+		lead := (top.rankScore() - hits[1].rankScore()) / top.rankScore()
+		c.Gap = clamp01(lead / confidenceClearLead)
+	}
+
+	c.Slack = 1
+	if slotBudget > 0 {
+		c.Slack = min(c.Slack, clamp01(1-top.PriceGBP/slotBudget))
+	}
+	if minEco > 0 && minEco < 100 {
+		c.Slack = min(c.Slack, clamp01(float64(top.EcoScore-minEco)/float64(100-minEco)))
+	}
+
+	score := confidenceMatchWeight*c.Match + confidenceGapWeight*c.Gap + confidenceSlackWeight*c.Slack
+	c.Score = round2(score)
+	c.Match, c.Gap, c.Slack = round2(c.Match), round2(c.Gap), round2(c.Slack)
+	c.Low = score < cfg().LowConfidence
+	return c
+}
+
+func clamp01(v float64) float64 { return min(max(v, 0), 1) }
+
+func round2(v float64) float64 { return math.Round(v*100) / 100 }
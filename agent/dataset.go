@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Feedback signals stored in session_events payloads of kind "feedback".
+const (
+	signalUp        = "up"
+	signalDown      = "down"
+	signalAddToCart = "add_to_cart"
+)
+
+// feedbackEvent is the payload of a session feedback event.
+type feedbackEvent struct {
+	ProductID string `json:"product_id"`
+	Signal    string `json:"signal"`
+}
+
+func (f feedbackEvent) positive() bool {
+	return f.Signal == signalUp || f.Signal == signalAddToCart
+}
+
+// TrainingTriple is one line of the dataset: for an (anonymized) query the
+// shopper chose one product over the rejected ones.
+type TrainingTriple struct {
+	Query    string   `json:"query"`
+	Chosen   string   `json:"chosen"`
+	Rejected []string `json:"rejected"`
+	Source   string   `json:"source"` // route the products were shown on
+	Group    string   `json:"group"`  // salted hash of the session; keeps splits leak-free
+}
+
+// datasetOpts are the build-dataset filters.
+type datasetOpts struct {
+	Tenant      string
+	Since       time.Time
+	Until       time.Time
+	Sources     []string // routes, e.g. "POST /search"; empty = all
+	Rejected    string   // explicit: only down-voted; shown: every other product shown
+	MinRejected int
+	Salt        string
+	Limit       int
+}
+
+// runBuildDataset implements `agent build-dataset`. Only sessions whose
+// shopper consented to training are read, and queries are scrubbed of PII.
+func runBuildDataset(ctx context.Context, pool *pgxpool.Pool, args []string) error {
+	fs := flag.NewFlagSet("build-dataset", flag.ContinueOnError)
+	var opts datasetOpts
+	var out, since, until, sources string
+	fs.StringVar(&out, "out", "-", "output JSONL file (- = stdout)")
+	fs.StringVar(&opts.Tenant, "tenant", defaultTenant, "tenant to export")
+	fs.StringVar(&since, "since", "", "only sessions active at or after this time (RFC 3339)")
+	fs.StringVar(&until, "until", "", "only sessions active before this time (RFC 3339)")
+	fs.StringVar(&sources, "sources", "", `comma-separated routes, e.g. "POST /search,POST /complete-outfit"`)
+	fs.StringVar(&opts.Rejected, "rejected", "shown", "explicit = only down-voted products; shown = every other product shown")
+	fs.IntVar(&opts.MinRejected, "min-rejected", 1, "skip triples with fewer rejected products")
+	fs.StringVar(&opts.Salt, "salt", "", "salt for the session group hash (required; keep it secret)")
+	fs.IntVar(&opts.Limit, "limit", 0, "stop after this many triples (0 = no limit)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if opts.Salt == "" {
+		return fmt.Errorf("-salt is required so group hashes can't be reversed to session IDs")
+	}
+	if opts.Rejected != "explicit" && opts.Rejected != "shown" {
+		return fmt.Errorf("-rejected must be explicit or shown")
+	}
+	for _, t := range []struct {
+		v   string
+		dst *time.Time
+	}{{since, &opts.Since}, {until, &opts.Until}} {
+		if t.v == "" {
+			continue
+		}
+		var err error
+		if *t.dst, err = time.Parse(time.RFC3339, t.v); err != nil {
+			return fmt.Errorf("invalid time %q: %w", t.v, err)
+		}
+	}
+	for _, s := range strings.Split(sources, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			opts.Sources = append(opts.Sources, s)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "-" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	n, err := buildDataset(ctx, pool, opts, bw)
+	if ferr := bw.Flush(); err == nil {
+		err = ferr
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d triples\n", n)
+	return err
+}
+
+func buildDataset(ctx context.Context, pool *pgxpool.Pool, opts datasetOpts, w io.Writer) (int, error) {
+	rows, err := pool.Query(ctx, `
+SELECT e.session_id, e.kind, e.payload
+FROM session_events e
+JOIN sessions s ON s.tenant_id = e.tenant_id AND s.id = e.session_id
+WHERE e.tenant_id = $1 AND s.consent_training
+  AND e.kind IN ('turn', 'feedback')
+  AND ($2::timestamptz IS NULL OR s.last_seen_at >= $2)
+  AND ($3::timestamptz IS NULL OR s.last_seen_at < $3)
+ORDER BY e.session_id, e.id
+`, opts.Tenant, nullTime(opts.Since), nullTime(opts.Until))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	enc := json.NewEncoder(w)
+	written := 0
+	var cur string
+	var turns []*datasetTurn
+	flush := func() error {
+		for _, t := range triplesFor(turns, opts, sessionGroup(opts.Salt, opts.Tenant, cur)) {
+			if opts.Limit > 0 && written >= opts.Limit {
+				return nil
+			}
+			if err := enc.Encode(t); err != nil {
+				return err
+			}
+			written++
+		}
+		return nil
+	}
+
+	for rows.Next() {
+		var sid, kind string
+		var payload []byte
+		if err := rows.Scan(&sid, &kind, &payload); err != nil {
+			return written, err
+		}
+		if sid != cur {
+			if err := flush(); err != nil {
+				return written, err
+			}
+			cur, turns = sid, nil
+		}
+		switch kind {
+		case eventTurn:
+			if t := parseDatasetTurn(payload); t != nil {
+				turns = append(turns, t)
+			}
+		case eventFeedback:
+			var f feedbackEvent
+			if json.Unmarshal(payload, &f) != nil || f.ProductID == "" {
+				continue
+			}
+			// attribute feedback to the latest turn that showed the product
+			for i := len(turns) - 1; i >= 0; i-- {
+				if slices.Contains(turns[i].shown, f.ProductID) {
+					turns[i].feedback = append(turns[i].feedback, f)
+					break
+				}
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return written, err
+	}
+	return written, flush()
+}
+
+// datasetTurn is a successful recommendation turn from a transcript.
+type datasetTurn struct {
+	route    string
+	query    string
+	shown    []string // in display order
+	feedback []feedbackEvent
+}
+
+func parseDatasetTurn(payload []byte) *datasetTurn {
+	var turn struct {
+		Route   string `json:"route"`
+		Status  int    `json:"status"`
+		Request struct {
+			Query   string `json:"query"`
+			Mission string `json:"mission"`
+		} `json:"request"`
+		Response struct {
+			Hits    []Hit `json:"hits"`
+			Results []struct {
+				Hits []Hit `json:"hits"`
+			} `json:"results"`
+		} `json:"response"`
+	}
+	if json.Unmarshal(payload, &turn) != nil || turn.Status != 200 {
+		return nil
+	}
+	t := &datasetTurn{route: turn.Route, query: turn.Request.Query}
+	if t.query == "" {
+		t.query = strings.ReplaceAll(turn.Request.Mission, "_", " ")
+	}
+	for _, h := range turn.Response.Hits {
+		t.shown = append(t.shown, h.ProductID)
+	}
+	for _, r := range turn.Response.Results {
+		for _, h := range r.Hits {
+			t.shown = append(t.shown, h.ProductID)
+		}
+	}
+	if t.query == "" || len(t.shown) == 0 {
+		return nil
+	}
+	return t
+}
+
+func triplesFor(turns []*datasetTurn, opts datasetOpts, group string) []TrainingTriple {
+	var out []TrainingTriple
+	for _, t := range turns {
+		if len(opts.Sources) > 0 && !slices.Contains(opts.Sources, t.route) {
+			continue
+		}
+		var chosen, down []string
+		for _, f := range t.feedback {
+			if f.positive() {
+				chosen = append(chosen, f.ProductID)
+			} else if f.Signal == signalDown {
+				down = append(down, f.ProductID)
+			}
+		}
+		rejected := down
+		if opts.Rejected == "shown" {
+			rejected = nil
+			for _, id := range t.shown {
+				if !slices.Contains(chosen, id) {
+					rejected = append(rejected, id)
+				}
+			}
+		}
+		rejected = slices.Compact(slices.Sorted(slices.Values(rejected)))
+		if len(rejected) < opts.MinRejected {
+			continue
+		}
+		for _, c := range slices.Compact(slices.Sorted(slices.Values(chosen))) {
+			out = append(out, TrainingTriple{
+				Query:    scrubPII(t.query),
+				Chosen:   c,
+				Rejected: rejected,
+				Source:   t.route,
+				Group:    group,
+			})
+		}
+	}
+	return out
+}
+
+func sessionGroup(salt, tenant, sessionID string) string {
+	sum := sha256.Sum256([]byte(salt + "\x00" + tenant + "\x00" + sessionID))
+	return hex.EncodeToString(sum[:8])
+}
+
+// PII patterns replaced in free-text queries, most specific first.
+var piiPatterns = []struct {
+	re          *regexp.Regexp
+	placeholder string
+}{
+	{regexp.MustCompile(`(?i)[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`), "<email>"},
+	{regexp.MustCompile(`(?i)\bhttps?://\S+`), "<url>"},
+	{regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), "<card>"},
+	// 10 to 15 digits, so size runs and year ranges are left alone
+	{regexp.MustCompile(`\+?\d(?:[ ()-]*\d){9,14}`), "<phone>"},
+	{regexp.MustCompile(`(?i)\b[a-z]{1,2}\d[a-z\d]?\s*\d[a-z]{2}\b`), "<postcode>"},
+}
+
+// scrubPII masks emails, URLs, card and phone numbers and UK postcodes.
+func scrubPII(s string) string {
+	for _, p := range piiPatterns {
+		s = p.re.ReplaceAllString(s, p.placeholder)
+	}
+	return s
+}
+
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestScrubPII(t *testing.T) {
+	for _, tc := range []struct {
+		name, in, want string
+	}{
+		{"email", "send it to jo.bloggs+shop@example.co.uk please", "send it to <email> please"},
+		{"url", "like this one https://shop.example.com/p/123?ref=x thanks", "like this one <url> thanks"},
+		{"card spaced", "pay with 4111 1111 1111 1111", "pay with <card>"},
+		{"card dashed", "card 5500-0000-0000-0004 expired", "card <card> expired"},
+		{"card plain", "4111111111111111", "<card>"},
+		{"uk mobile", "call me on 07700 900123", "call me on <phone>"},
+		{"international", "ring +44 (0)20 7946 0958 after 6", "ring <phone> after 6"},
+		{"postcode", "deliver to SW1A 1AA by friday", "deliver to <postcode> by friday"},
+		{"postcode lower no space", "near m11ae", "near <postcode>"},
+		{"postcode short", "in B1 1BB", "in <postcode>"},
+		{"several", "me@x.io or 07700900123", "<email> or <phone>"},
+
+		// shopping queries that only look like PII
+		{"sizes", "dress in size 8 10 or 12", "dress in size 8 10 or 12"},
+		{"size run", "boots sizes 12 14 16 18", "boots sizes 12 14 16 18"},
+		{"price", "jacket under £150 for 2024", "jacket under £150 for 2024"},
+		{"jeans fit", "w32 l34 straight jeans", "w32 l34 straight jeans"},
+		{"year range", "vintage 1990-1999 denim", "vintage 1990-1999 denim"},
+		{"model number", "air max 90 trainers", "air max 90 trainers"},
+		{"at sign", "shoes @ party", "shoes @ party"},
+		{"no scheme", "shop.example.com style", "shop.example.com style"},
+		{"empty", "", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := scrubPII(tc.in); got != tc.want {
+				t.Errorf("scrubPII(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// maxJSONBody bounds a JSON request body; a larger one is answered 413.
+const maxJSONBody = 1 << 20
+
+// decodeJSON decodes the request body into dst. By default unknown fields are
+// rejected so a typo like "budget_gpb" fails loudly instead of silently
+// defaulting. Set CSA_LENIENT_JSON=true to accept them and only log a warning.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxJSONBody))
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return io.EOF
+	}
+
+	raw, deprecated, err := rewriteAliases(raw, dst)
+	if err != nil {
+		return err
+	}
+	if len(deprecated) > 0 {
+		addWarnings(r.Context(), deprecated...)
+		logOutcome(r.Context(), slog.Any("deprecated_fields", deprecated))
+	}
+
+	unknown, err := httpapi.Decode(raw, dst, cfg().LenientJSON)
+	if len(unknown) > 0 {
+		slog.WarnContext(r.Context(), "decode: ignoring unknown fields", "method", r.Method, "path", r.URL.Path, "fields", unknown)
+	}
+	return err
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// dedupNeighbours is how many nearest products each newly indexed product is
+// compared with.
+const dedupNeighbours = 5
+
+// DuplicateHit is another listing of the same product as the hit it is
+// attached to.
+type DuplicateHit struct {
+	ProductID string  `json:"product_id"`
+	Title     string  `json:"title"`
+	Thumbnail string  `json:"thumbnail"`
+	PriceGBP  float64 `json:"price_gbp"`
+}
+
+// markDuplicates groups freshly indexed products with near-identical
+// listings in the same category: embeddings at least CSA_DEDUP_SIMILARITY
+// alike and titles at least CSA_DEDUP_TITLE_SIMILARITY alike. Each group's
+// canonical product is the one indexed first (then the lowest ID); the rest
+// point at it through duplicate_of. Re-indexed products are re-grouped from
+// scratch, since their listing may have changed. Drafts are never grouped, so
+// one can't hide a listing that is on sale; a canonical product that became
+// a draft releases its duplicates.
+func markDuplicates(ctx context.Context, pool *pgxpool.Pool, ids []string) error {
+	if cfg().Dedup.Similarity == 0 || len(ids) == 0 {
+		return nil
+	}
+	if _, err := pool.Exec(ctx, `
+UPDATE product_embeddings SET duplicate_of = NULL
+WHERE product_id = ANY($1)
+   OR duplicate_of IN (SELECT product_id FROM product_embeddings WHERE product_id = ANY($1) AND lifecycle = 'draft')
+`, ids); err != nil {
+		return err
+	}
+
+	rows, err := pool.Query(ctx, `
+SELECT p.product_id, COALESCE(p.title, ''), COALESCE(p.first_indexed_at, 'epoch'),
+       n.root, COALESCE(n.title, ''), COALESCE(r.first_indexed_at, 'epoch'), n.cos
+FROM product_embeddings p
+CROSS JOIN LATERAL (
+  SELECT q.title, COALESCE(q.duplicate_of, q.product_id) AS root, 1 - (q.embedding <=> p.embedding) AS cos
+  FROM product_embeddings q
+  WHERE q.product_id <> p.product_id AND q.embedding IS NOT NULL AND q.lifecycle <> 'draft'
+    AND q.sandbox = p.sandbox AND q.category IS NOT DISTINCT FROM p.category
+  ORDER BY q.embedding <-> p.embedding
+  LIMIT $2
+) n
+JOIN product_embeddings r ON r.product_id = n.root
+WHERE p.product_id = ANY($1) AND p.embedding IS NOT NULL AND p.lifecycle <> 'draft'
+ORDER BY p.product_id, n.cos DESC
+`, ids, dedupNeighbours)
+	if err != nil {
+		return err
+	}
+	type listing struct {
+		id    string
+		first time.Time
+	}
+	type match struct{ product, root listing }
+	var matches []match
+	matched := map[string]bool{}
+	for rows.Next() {
+		var p, root listing
+		var title, rootTitle string
+		var cos float64
+		if err := rows.Scan(&p.id, &title, &p.first, &root.id, &rootTitle, &root.first, &cos); err != nil {
+			rows.Close()
+			return err
+		}
+		if matched[p.id] || root.id == p.id || cos < cfg().Dedup.Similarity || titleSimilarity(title, rootTitle) < cfg().Dedup.TitleSimilarity {
+			continue
+		}
+		matched[p.id] = true // the closest match decides the group
+		matches = append(matches, match{p, root})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// earlier matches in this batch may have merged groups; follow them
+	moved := map[string]listing{}
+	resolve := func(l listing) listing {
+		for {
+			next, ok := moved[l.id]
+			if !ok || next.id == l.id {
+				return l
+			}
+			l = next
+		}
+	}
+	older := func(a, b listing) bool {
+		if !a.first.Equal(b.first) {
+			return a.first.Before(b.first)
+		}
+		return a.id < b.id
+	}
+	for _, m := range matches {
+		p, root := resolve(m.product), resolve(m.root)
+		if p.id == root.id {
+			continue
+		}
+		canon, dup := root, p
+		if older(p, root) {
+			canon, dup = p, root
+		}
+		if _, err := pool.Exec(ctx, `
+UPDATE product_embeddings SET duplicate_of = $1
+WHERE product_id = $2 OR duplicate_of = $2
+`, canon.id, dup.id); err != nil {
+			return err
+		}
+		moved[dup.id] = canon
+	}
+	return nil
+}
+
+// titleSimilarity is the Jaccard similarity of the titles' character
+// trigrams, ignoring case and punctuation: 1 for the same words, near 0 for
+// unrelated titles. "Organic Tee - Navy" and "Organic Tee (Black)" score
+// about 0.5.
+func titleSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// trigrams pads each word like pg_trgm does, so short words still count.
+func trigrams(s string) map[string]bool {
+	out := map[string]bool{}
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	for _, w := range words {
+		r := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			out[string(r[i:i+3])] = true
+		}
+	}
+	return out
+}
+
+// attachDuplicates lists each hit's duplicate listings in Hit.Duplicates.
+func attachDuplicates(ctx context.Context, pool *pgxpool.Pool, hits []Hit) error {
+	if len(hits) == 0 {
+		return nil
+	}
+	ids := make([]string, len(hits))
+	idx := make(map[string]int, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ProductID
+		idx[h.ProductID] = i
+	}
+	rows, err := pool.Query(ctx, `
+SELECT duplicate_of, product_id, COALESCE(title, ''), COALESCE(thumbnail, ''), COALESCE(price_gbp, 0)::float8
+FROM product_embeddings
+WHERE duplicate_of = ANY($1) AND `+sandboxSQL(ctx, "")+`
+ORDER BY duplicate_of, price_gbp, product_id
+`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var canon string
+		var d DuplicateHit
+		if err := rows.Scan(&canon, &d.ProductID, &d.Title, &d.Thumbnail, &d.PriceGBP); err != nil {
+			return err
+		}
+		h := &hits[idx[canon]]
+		h.Duplicates = append(h.Duplicates, d)
+	}
+	return rows.Err()
+}
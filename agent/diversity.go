@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// DiversityPrefs trade relevance for variety, so the first hits aren't the
+// same tee in five colours.
+type DiversityPrefs struct {
+	MMRLambda *float64 `json:"mmr_lambda,omitempty"` // 0-1; 1 = relevance only, lower = more varied; default CSA_MMR_LAMBDA
+}
+
+func (d DiversityPrefs) validate() error {
+	if d.MMRLambda != nil && (*d.MMRLambda < 0 || *d.MMRLambda > 1) {
+		return httpapi.InvalidField("mmr_lambda", "mmr_lambda must be between 0 and 1")
+	}
+	return nil
+}
+
+// lambda is the effective relevance weight.
+func (d DiversityPrefs) lambda() float64 {
+	if d.MMRLambda != nil {
+		return *d.MMRLambda
+	}
+	return cfg().MMRLambda
+}
+
+// diversify reorders hits by maximal marginal relevance: each next hit
+// maximises lambda × relevance − (1−lambda) × its highest cosine similarity
+// to a hit already picked. Relevance is the ranking score on a 0-1 scale.
+// Only the first limit positions are chosen this way; the rest keep their
+// order. Similarities come from pgvector, so embeddings never leave the
+// database.
+func diversify(ctx context.Context, pool *pgxpool.Pool, hits []Hit, prefs DiversityPrefs, limit int) ([]Hit, error) {
+	lambda := prefs.lambda()
+	if lambda >= 1 || len(hits) < 3 {
+		return hits, nil
+	}
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ProductID
+	}
+	rows, err := pool.Query(ctx, `
+SELECT a.product_id, b.product_id, 1 - (a.embedding <=> b.embedding)
+FROM product_embeddings a
+JOIN product_embeddings b ON a.product_id < b.product_id
+WHERE a.product_id = ANY($1) AND b.product_id = ANY($1)
+  AND a.embedding IS NOT NULL AND b.embedding IS NOT NULL
+`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	type pair struct{ a, b string }
+	sims := map[pair]float64{}
+	for rows.Next() {
+		var a, b string
+		var s float64
+		if err := rows.Scan(&a, &b, &s); err != nil {
+			return nil, err
+		}
+		sims[pair{a, b}] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sim := func(a, b string) float64 {
+		if a > b {
+			a, b = b, a
+		}
+		return sims[pair{a, b}]
+	}
+
+	picked := make([]Hit, 0, len(hits))
+	rest := append([]Hit(nil), hits...)
+	for len(picked) < min(limit, len(hits)) {
+		best, bestScore := 0, 0.0
+		for i, h := range rest {
+			redundancy := 0.0
+			for _, p := range picked {
+				redundancy = max(redundancy, sim(h.ProductID, p.ProductID))
+			}
+			score := lambda*h.rankScore()/100 - (1-lambda)*redundancy
+			if i == 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		picked = append(picked, rest[best])
+		rest = append(rest[:best], rest[best+1:]...)
+	}
+	return append(picked, rest...), nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+)
+
+// Provenance of a product's eco score (eco_score_source).
+const (
+	ecoSourceMerchant  = "merchant"  // set in catalog metadata
+	ecoSourceEstimated = "estimated" // inferred by estimateEcoScore
+)
+
+var ecoEstimateSchema = &outputSchema{Name: "eco_estimate", Schema: map[string]any{
+	"type":                 "object",
+	"properties":           map[string]any{"eco_score": map[string]any{"type": "integer", "minimum": 0, "maximum": 100}},
+	"required":             []string{"eco_score"},
+	"additionalProperties": false,
+}}
+
+// estimateEcoScore asks the default chat provider for a 0-100 eco score
+// from what the catalog says about materials, certifications and origin.
+// Estimates are conservative by instruction: unknowns score low, not average.
+func estimateEcoScore(ctx context.Context, p catalog.Product, attrs productAttrs, origin string) (int, error) {
+	desc := clipUTF8(p.Description, 1500)
+	labels := make([]string, 0, len(attrs.EcoLabels))
+	for _, l := range ecoLabelsFromIDs(attrs.EcoLabels) {
+		labels = append(labels, l.Name)
+	}
+	prompt := fmt.Sprintf(`Estimate an eco score from 0 (worst) to 100 (best) for this clothing product.
+Judge only the evidence below: material (recycled, organic or natural fibres score higher; virgin synthetics lower), certifications, and country of origin.
+Missing information is not evidence of sustainability: with little to go on, stay below 40.
+Return only a JSON object {"eco_score": <integer>}.
+
+Title: %q
+Material: %q
+Certifications: %q
+Origin country: %q
+Description: %q`, p.Title, attrs.Material, strings.Join(labels, ", "), origin, desc)
+
+	raw, err := llmChat(ctx, "", config.LLMEcoEstimate, prompt, ecoEstimateSchema)
+	if err != nil {
+		return 0, err
+	}
+	var out struct {
+		EcoScore *float64 `json:"eco_score"`
+	}
+	if err := json.Unmarshal([]byte(ranking.StripCodeFence(raw)), &out); err != nil {
+		return 0, fmt.Errorf("eco estimate: %w", err)
+	}
+	if out.EcoScore == nil || *out.EcoScore < 0 || *out.EcoScore > 100 {
+		return 0, fmt.Errorf("eco estimate: score out of range in %q", raw)
+	}
+	return int(*out.EcoScore + 0.5), nil
+}
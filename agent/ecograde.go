@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+)
+
+var ecoGrades = []string{"A", "B", "C", "D", "E"}
+
+// EcoGrade summarises how sustainable a whole outfit is.
+type EcoGrade struct {
+	Grade  string  `json:"grade"` // A (best) to E
+	Score  float64 `json:"score"` // weighted mean eco score of the graded items, 0-100
+	Items  int     `json:"items"`
+	Capped bool    `json:"capped,omitempty"` // lowered by the worst-item cap
+	Rubric string  `json:"rubric"`
+}
+
+// gradeFor maps a 0-100 score to a grade index using the rubric thresholds.
+func gradeFor(score float64, thresholds [4]float64) int {
+	for i, t := range thresholds {
+		if score >= t {
+			return i
+		}
+	}
+	return len(ecoGrades) - 1
+}
+
+// outfitEcoGrade grades the top pick of each slot under cfg().EcoGrade:
+// 1. score = mean item eco score, weighted by price (or equally);
+// 2. grade = the first of A-D whose threshold the score reaches, else E;
+// 3. with the worst-item cap, the grade is at most one better than the
+// worst item's own grade, so one poor item can't hide behind the rest.
+// Cart items aren't graded; the agent only knows their IDs and prices.
+func outfitEcoGrade(resp CompleteOutfitResp) *EcoGrade {
+	rubric := cfg().EcoGrade
+	var picks []Hit
+	for _, r := range resp.Results {
+		if len(r.Hits) > 0 {
+			picks = append(picks, r.Hits[0])
+		}
+	}
+	if len(picks) == 0 {
+		return nil
+	}
+
+	sum, weights, worst := 0.0, 0.0, picks[0].EcoScore
+	for _, h := range picks {
+		w := 1.0
+		if rubric.WeightByPrice && h.PriceGBP > 0 {
+			w = h.PriceGBP
+		}
+		sum += w * float64(h.EcoScore)
+		weights += w
+		worst = min(worst, h.EcoScore)
+	}
+	score := math.Round(sum/weights*10) / 10
+
+	g := EcoGrade{Score: score, Items: len(picks), Rubric: ecoRubricText(rubric)}
+	i := gradeFor(score, rubric.Thresholds)
+	if rubric.WorstItemCap {
+		if limit := gradeFor(float64(worst), rubric.Thresholds) - 1; i < limit {
+			i, g.Capped = limit, true
+		}
+	}
+	g.Grade = ecoGrades[i]
+	return &g
+}
+
+// ecoRubricText describes the rubric in force, e.g.
+// "price-weighted; A>=80 B>=65 C>=50 D>=35 else E; worst-item cap".
+func ecoRubricText(r config.EcoGradeRubric) string {
+	var b strings.Builder
+	if r.WeightByPrice {
+		b.WriteString("price-weighted;")
+	} else {
+		b.WriteString("equal-weighted;")
+	}
+	for i, t := range r.Thresholds {
+		fmt.Fprintf(&b, " %s>=%g", ecoGrades[i], t)
+	}
+	b.WriteString(" else E")
+	if r.WorstItemCap {
+		b.WriteString("; worst-item cap")
+	}
+	return b.String()
+}
+
+// ecoGradeSentence states the grade for explanations.
+func ecoGradeSentence(g *EcoGrade) string {
+	if g == nil {
+		return ""
+	}
+	s := fmt.Sprintf("The outfit earns an overall eco grade of %s (%.0f/100).", g.Grade, g.Score)
+	if g.Capped {
+		s = fmt.Sprintf("The outfit earns an overall eco grade of %s (%.0f/100), held back by its lowest-scoring item.", g.Grade, g.Score)
+	}
+	return s
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/embeddings"
+)
+
+// localEmbed is the ONNX model, loaded at startup when CSA_LOCAL_EMBED_MODEL
+// is set. It serves all embeddings when CSA_EMBED_BACKEND=local and is
+// otherwise only used by the embedding benchmark.
+var localEmbed embeddings.Model
+
+func initLocalEmbedder() error {
+	if cfg().Embed.ModelDir == "" {
+		if cfg().Embed.Backend == config.EmbedLocal {
+			return fmt.Errorf("CSA_EMBED_BACKEND=local needs CSA_LOCAL_EMBED_MODEL")
+		}
+		return nil
+	}
+	e, err := embeddings.NewLocal(cfg().Embed)
+	if err != nil {
+		return err
+	}
+	localEmbed = e
+	slog.Info("embed: local model loaded", "model", e.Name(), "backend", cfg().Embed.Backend)
+	return nil
+}
+
+// embedBackends maps CSA_EMBED_BACKEND to the provider serving it.
+var embedBackends = map[string]func() embeddings.Provider{
+	config.EmbedOpenAI:      openAIEmbedder,
+	config.EmbedAzureOpenAI: azureEmbedder,
+	config.EmbedLocal:       func() embeddings.Provider { return localProvider{} },
+}
+
+// embedder is the provider for the request: the fake one for sandbox keys,
+// else the configured backend, or the tenant's residency zone's endpoint
+// when the configured one is elsewhere.
+func embedder(ctx context.Context) (embeddings.Provider, error) {
+	if sandboxFrom(ctx) {
+		return embeddings.Fake{}, nil
+	}
+	name, err := residentEmbedBackend(residencyFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	backend, ok := embedBackends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown embedding backend %q", name)
+	}
+	return backend(), nil
+}
+
+// openAIEmbedder is the OpenAI embeddings client under the current settings.
+func openAIEmbedder() embeddings.Provider {
+	return embeddings.OpenAI{
+		BaseURL: cfg().OpenAI.BaseURL,
+		APIKey:  cfg().OpenAI.APIKey,
+		Model:   cfg().OpenAI.EmbedModel,
+		Client:  httpClient,
+		Usage:   recordEmbedUsage,
+	}
+}
+
+// azureEmbedder is the Azure OpenAI embeddings deployment.
+func azureEmbedder() embeddings.Provider {
+	return embeddings.Azure{
+		Endpoint:   cfg().Azure.Endpoint,
+		APIKey:     cfg().Azure.APIKey,
+		APIVersion: cfg().Azure.APIVersion,
+		Deployment: cfg().Azure.EmbedDeployment,
+		Client:     httpClient,
+		Usage:      recordEmbedUsage,
+	}
+}
+
+// embedText embeds one query or product card with the configured backend.
+func embedText(ctx context.Context, text string) ([]float64, error) {
+	embs, err := embedTexts(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embs[0], nil
+}
+
+func embedTexts(ctx context.Context, texts []string) ([][]float64, error) {
+	p, err := embedder(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, local := p.(localProvider); !local {
+		if err := checkUsageCap(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return p.Embed(ctx, texts)
+}
+
+// localProvider runs the local model and zero-pads its output to the column
+// width; see embeddings.Pad. Reindex after switching CSA_EMBED_BACKEND.
+type localProvider struct{}
+
+func (localProvider) Name() string {
+	if localEmbed == nil {
+		return "local"
+	}
+	return localEmbed.Name()
+}
+
+func (localProvider) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if localEmbed == nil {
+		return nil, fmt.Errorf("local embedding model not loaded")
+	}
+	ctx, span := startSpan(ctx, "embed.local", "embed.model", localEmbed.Name(), "embed.inputs", strconv.Itoa(len(texts)))
+	defer span.End()
+
+	embs, err := localEmbed.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if err := embeddings.Pad(embs); err != nil {
+		return nil, fmt.Errorf("local %w", err)
+	}
+	return embs, nil
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EmbedBenchResp compares embedding backends on the tenant's golden set.
+// Each backend embeds the judged products and the judged queries, ranks
+// the products by cosine similarity per query, and is scored against the
+// human grades. The candidate pool is the judged products only, so scores
+// compare backends with each other rather than predict live search quality.
+type EmbedBenchResp struct {
+	K        int                        `json:"k"`
+	Queries  int                        `json:"queries"`
+	Products int                        `json:"products"`
+	Backends map[string]EmbedBenchScore `json:"backends"`
+}
+
+type EmbedBenchScore struct {
+	RecallAtK float64 `json:"recall_at_k"` // share of products graded >= 2 found in the top k
+	NDCGAtK   float64 `json:"ndcg_at_k"`
+	MSPerText float64 `json:"ms_per_text"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// grades at or above this count as relevant for recall
+const benchRelevantGrade = 2
+
+type benchProduct struct {
+	id   string
+	card string
+}
+
+type embedFunc func(ctx context.Context, texts []string) ([][]float64, error)
+
+// Benchmark errors the handler answers with a specific status; anything
+// else is a database failure.
+var (
+	errBenchNoModel     = errors.New("local embedding model not loaded; set CSA_LOCAL_EMBED_MODEL")
+	errBenchNoJudgments = errors.New("no relevance judgments for indexed products; label some via /admin/relevance-judgments")
+	errBenchBackends    = errors.New("every embedding backend failed")
+)
+
+func embeddingBenchmark(ctx context.Context, pool *pgxpool.Pool, tenantID string, k int) (EmbedBenchResp, error) {
+	resp := EmbedBenchResp{K: k, Backends: map[string]EmbedBenchScore{}}
+	if localEmbed == nil {
+		return resp, errBenchNoModel
+	}
+
+	gs, err := goldenSet(ctx, pool, tenantID)
+	if err != nil {
+		return resp, err
+	}
+	products, err := benchProducts(ctx, pool, tenantID)
+	if err != nil {
+		return resp, err
+	}
+	if len(gs.Queries) == 0 || len(products) == 0 {
+		return resp, errBenchNoJudgments
+	}
+	queries := make([]string, 0, len(gs.Queries))
+	for q := range gs.Queries {
+		queries = append(queries, q)
+	}
+	sort.Strings(queries)
+	resp.Queries, resp.Products = len(queries), len(products)
+
+	backends := map[string]embedFunc{
+		"openai": openAIEmbedder().Embed,
+		"local":  localEmbed.Embed,
+	}
+	var failed []string
+	for name, embed := range backends {
+		score, err := benchBackend(ctx, embed, gs, queries, products, k)
+		if err != nil {
+			score.Error = err.Error()
+			failed = append(failed, name+": "+err.Error())
+		}
+		resp.Backends[name] = score
+	}
+	if len(failed) == len(backends) {
+		sort.Strings(failed)
+		return resp, fmt.Errorf("%w (%s)", errBenchBackends, strings.Join(failed, "; "))
+	}
+	return resp, nil
+}
+
+// benchProducts rebuilds a card for every judged product from the indexed
+// columns; descriptions aren't stored, so cards are shorter than at index time.
+func benchProducts(ctx context.Context, pool *pgxpool.Pool, tenantID string) ([]benchProduct, error) {
+	rows, err := pool.Query(ctx, `
+SELECT p.product_id, COALESCE(p.title,''), COALESCE(p.category,''), COALESCE(p.brand,''),
+       COALESCE(p.material,''), COALESCE(p.colors, '{}')
+FROM product_embeddings p
+WHERE p.product_id IN (SELECT product_id FROM relevance_judgments WHERE tenant_id=$1)
+ORDER BY p.product_id
+`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []benchProduct
+	for rows.Next() {
+		var id, title, category, brand, material string
+		var colors []string
+		if err := rows.Scan(&id, &title, &category, &brand, &material, &colors); err != nil {
+			return nil, err
+		}
+		card := fmt.Sprintf("TITLE: %s\nCATEGORY: %s", title, category)
+		if brand != "" {
+			card += "\nBRAND: " + brand
+		}
+		if material != "" {
+			card += "\nMATERIAL: " + material
+		}
+		if len(colors) > 0 {
+			card += "\nCOLORS: " + strings.Join(colors, ", ")
+		}
+		out = append(out, benchProduct{id: id, card: card})
+	}
+	return out, rows.Err()
+}
+
+func benchBackend(ctx context.Context, embed embedFunc, gs GoldenSet, queries []string, products []benchProduct, k int) (EmbedBenchScore, error) {
+	var score EmbedBenchScore
+	texts := make([]string, 0, len(products)+len(queries))
+	for _, p := range products {
+		texts = append(texts, p.card)
+	}
+	texts = append(texts, queries...)
+
+	start := time.Now()
+	vecs := make([][]float64, 0, len(texts))
+	for i := 0; i < len(texts); i += cfg().IndexBatchSize {
+		embs, err := embed(ctx, texts[i:min(i+cfg().IndexBatchSize, len(texts))])
+		if err != nil {
+			return score, err
+		}
+		vecs = append(vecs, embs...)
+	}
+	score.MSPerText = float64(time.Since(start).Microseconds()) / 1000 / float64(len(texts))
+	productVecs, queryVecs := vecs[:len(products)], vecs[len(products):]
+
+	scored := 0
+	for qi, q := range queries {
+		grades := gs.Queries[q]
+		ranked := make([]int, len(products))
+		sims := make([]float64, len(products))
+		for i := range products {
+			ranked[i] = i
+			sims[i] = cosine(queryVecs[qi], productVecs[i])
+		}
+		sort.SliceStable(ranked, func(a, b int) bool { return sims[ranked[a]] > sims[ranked[b]] })
+		top := ranked[:min(k, len(ranked))]
+
+		relevant, found := 0, 0
+		for _, g := range grades {
+			if g >= benchRelevantGrade {
+				relevant++
+			}
+		}
+		if relevant == 0 {
+			continue
+		}
+		var dcg float64
+		for rank, i := range top {
+			g := grades[products[i].id]
+			if g >= benchRelevantGrade {
+				found++
+			}
+			dcg += (math.Pow(2, float64(g)) - 1) / math.Log2(float64(rank+2))
+		}
+		score.RecallAtK += float64(found) / float64(relevant)
+		score.NDCGAtK += dcg / idealDCG(grades, k)
+		scored++
+	}
+	if scored > 0 {
+		score.RecallAtK /= float64(scored)
+		score.NDCGAtK /= float64(scored)
+	}
+	return score, nil
+}
+
+func idealDCG(grades map[string]int, k int) float64 {
+	gs := make([]int, 0, len(grades))
+	for _, g := range grades {
+		gs = append(gs, g)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(gs)))
+	var dcg float64
+	for rank, g := range gs[:min(k, len(gs))] {
+		dcg += (math.Pow(2, float64(g)) - 1) / math.Log2(float64(rank+2))
+	}
+	return dcg
+}
+
+func cosine(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/embeddings"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/events"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+type EmbedReq struct {
+	ProductID string  `json:"product_id"`
+	Category  string  `json:"category"` // top|bottom|shoes|outerwear
+	Text      string  `json:"text"`
+	EcoScore  int     `json:"eco_score"`
+	PriceGBP  float64 `json:"price_gbp"`
+	// return this many nearest products (at most 20) to check the new vector
+	Neighbours int `json:"neighbours,omitempty"`
+}
+
+// maxEmbedNeighbours bounds EmbedReq.Neighbours.
+const maxEmbedNeighbours = 20
+
+// EmbedResp answers /embed-product when neighbours were asked for.
+type EmbedResp struct {
+	ProductID  string `json:"product_id"`
+	Neighbours []Hit  `json:"neighbours"` // nearest searchable products, closest first
+}
+
+func (r EmbedReq) validate() error {
+	if r.ProductID == "" || len(r.ProductID) > 128 {
+		return httpapi.InvalidField("product_id", "product_id is required, at most 128 characters")
+	}
+	if r.Text == "" {
+		return httpapi.InvalidField("text", "text is required")
+	}
+	if r.Neighbours < 0 || r.Neighbours > maxEmbedNeighbours {
+		return httpapi.InvalidField("neighbours", "neighbours must be between 0 and %d", maxEmbedNeighbours)
+	}
+	return nil
+}
+
+// embedProduct embeds and stores one product. Once it returns, the product
+// is searchable: the row is committed, and so are its entries in the
+// vector indexes, and every tenant's cached searches (they all search the
+// same catalog) and this replica's cached outfits showing the product have
+// been dropped. It returns the
+// stored vector.
+func embedProduct(ctx context.Context, pool *pgxpool.Pool, tenantID string, req EmbedReq) (string, error) {
+	embedding, err := embedText(ctx, req.Text)
+	if err != nil {
+		return "", err
+	}
+	vec := embeddings.Literal(embedding)
+
+	if _, err := pool.Exec(ctx, `
+INSERT INTO product_embeddings (product_id, category, embedding, eco_score, price_gbp, tenant_id, sandbox, residency_zone, indexed_at)
+VALUES ($1, $2, $3::vector, $4, $5, $6, $7, $8, now())
+ON CONFLICT (product_id) DO UPDATE
+SET category=EXCLUDED.category,
+    embedding=EXCLUDED.embedding,
+    eco_score=EXCLUDED.eco_score,
+    price_gbp=EXCLUDED.price_gbp,
+    residency_zone=EXCLUDED.residency_zone,
+    indexed_at=EXCLUDED.indexed_at
+`, req.ProductID, search.NullText(catalog.NormalizeCategory(req.Category)), vec, req.EcoScore, req.PriceGBP, tenantID, sandboxFrom(ctx), residencyFrom(ctx)); err != nil {
+		return "", fmt.Errorf("db error: %w", err)
+	}
+	// no translations come with the request, so every language reuses the vector
+	if _, err := pool.Exec(ctx, `
+INSERT INTO product_cards (product_id, lang, card_embedding)
+SELECT $1, unnest($2::text[]), $3::vector
+ON CONFLICT (product_id, lang) DO UPDATE SET card_title=NULL, card_embedding=EXCLUDED.card_embedding
+`, req.ProductID, localCardLanguages(), vec); err != nil {
+		return "", fmt.Errorf("db error: %w", err)
+	}
+
+	// dropped here rather than by the event's subscribers, which run later
+	outfits.invalidate([]string{req.ProductID})
+	invalidateSearchCache(ctx)
+	bus.Publish(events.ProductIndexed, tenantID, events.ProductIndexedData{ProductIDs: []string{req.ProductID}})
+	return vec, nil
+}
+
+// productNeighbours returns the n products nearest to vec that /search
+// could return, leaving out productID itself. It searches the way /search
+// does, through the ANN index at the default quality, so it shows what
+// shoppers will see rather than the exact nearest products.
+func productNeighbours(ctx context.Context, pool *pgxpool.Pool, productID, vec string, n int) ([]Hit, error) {
+	var p searchParams
+	metric := p.metric()
+	from := `FROM product_embeddings
+WHERE embedding IS NOT NULL
+  AND product_id <> $3
+  AND ` + sandboxSQL(ctx, "") + `
+  AND ` + lifecycleSQL("", false) + `
+  AND duplicate_of IS NULL`
+	hits, err := queryNearest(ctx, pool, p.quality(), vectorStorage().Rows(n), metric, vectorStorage().NearestSQL(search.HitColumns(metric), from, metric), vec, n, productID)
+	if err != nil {
+		return nil, err
+	}
+	if hits == nil {
+		hits = []Hit{}
+	}
+	return hits, nil
+}
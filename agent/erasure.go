@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Erasure: DELETE /users/{id}/data removes everything stored about a
+// shopper. Their personal data is what hangs off the sessions the
+// storefront tagged with their X-User-ID: the sessions, their transcripts
+// (turns, tool calls, recommendations, feedback events), session
+// constraints, feedback sent with those sessions, audit log entries and
+// responses stored for idempotent requests made in them. Webhook deliveries
+// of their events are kept, for the merchant's history and replay, with the
+// session ID and query removed from the payload. Everything goes in one
+// transaction, which also stores a receipt, so either all of it is erased
+// and a receipt exists or nothing changed.
+// Aggregates that name no one (feedback tallies, trending counts, token
+// usage) are kept.
+
+// ErasureReceipt is returned to the caller and stored in data_erasures.
+type ErasureReceipt struct {
+	ReceiptID string         `json:"receipt_id"`
+	TenantID  string         `json:"tenant_id"`
+	UserID    string         `json:"user_id"`
+	ErasedAt  time.Time      `json:"erased_at"`
+	Erased    map[string]int `json:"erased"` // rows deleted (webhook_outbox: redacted) per table
+	Sessions  []string       `json:"sessions"`
+}
+
+// erasureTables are deleted from, in order, for the user's sessions;
+// sessions itself goes last.
+var erasureTables = []string{"session_events", "session_constraints", "feedback", "recommendation_audit", "idempotency_keys"}
+
+// userHash identifies an erased user in data_erasures.
+func userHash(tenantID, userID string) string {
+	sum := sha256.Sum256([]byte(tenantID + "\x00" + userID))
+	return hex.EncodeToString(sum[:])
+}
+
+func eraseUserData(ctx context.Context, pool *pgxpool.Pool, tenantID, userID string) (ErasureReceipt, error) {
+	rec := ErasureReceipt{TenantID: tenantID, UserID: userID, Erased: map[string]int{}, Sessions: []string{}}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return rec, err
+	}
+	defer tx.Rollback(ctx)
+
+	// locking the sessions keeps new events from landing on them mid-erasure
+	rows, err := tx.Query(ctx, `SELECT id FROM sessions WHERE tenant_id=$1 AND user_id=$2 ORDER BY id FOR UPDATE`, tenantID, userID)
+	if err != nil {
+		return rec, err
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return rec, err
+		}
+		rec.Sessions = append(rec.Sessions, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return rec, err
+	}
+
+	for _, table := range erasureTables {
+		tag, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE tenant_id=$1 AND session_id = ANY($2)`, tenantID, rec.Sessions)
+		if err != nil {
+			return rec, err
+		}
+		rec.Erased[table] = int(tag.RowsAffected())
+	}
+	tag, err := tx.Exec(ctx, `
+UPDATE webhook_outbox SET payload = payload #- '{data,session_id}' #- '{data,query}'
+WHERE tenant_id=$1 AND payload->'data' ? 'session_id' AND payload->'data'->>'session_id' = ANY($2)
+`, tenantID, rec.Sessions)
+	if err != nil {
+		return rec, err
+	}
+	rec.Erased["webhook_outbox"] = int(tag.RowsAffected())
+	tag, err = tx.Exec(ctx, `DELETE FROM sessions WHERE tenant_id=$1 AND id = ANY($2)`, tenantID, rec.Sessions)
+	if err != nil {
+		return rec, err
+	}
+	rec.Erased["sessions"] = int(tag.RowsAffected())
+
+	var keyID string
+	if p := principalFrom(ctx); p != nil {
+		keyID = p.KeyID
+	}
+	erased, _ := json.Marshal(rec.Erased)
+	err = tx.QueryRow(ctx, `
+INSERT INTO data_erasures (tenant_id, user_hash, key_id, erased) VALUES ($1,$2,$3,$4)
+RETURNING id::text, erased_at
+`, tenantID, userHash(tenantID, userID), keyID, erased).Scan(&rec.ReceiptID, &rec.ErasedAt)
+	if err != nil {
+		return rec, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return rec, err
+	}
+	// the receipt ID, not the user ID, goes to the logs
+	slog.InfoContext(ctx, "erasure: user data erased", "receipt_id", rec.ReceiptID, "sessions", len(rec.Sessions))
+	return rec, nil
+}
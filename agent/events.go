@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/events"
+)
+
+// bus carries domain events between handlers and the features reacting to
+// them. Publishing on a nil bus (commands, tests) does nothing.
+var bus *events.Bus
+
+// newEventBus starts the bus with the configured forwarder and this
+// process's subscribers.
+func newEventBus() (*events.Bus, error) {
+	ec := cfg().Events
+	var fwd events.Forwarder
+	switch ec.Backend {
+	case config.EventsNATS:
+		n, err := events.NewNATS(ec.NATSURL, ec.NATSPrefix)
+		if err != nil {
+			return nil, err
+		}
+		fwd = n
+	case config.EventsKafka:
+		if len(ec.KafkaBrokers) == 0 {
+			return nil, errors.New("CSA_EVENT_BACKEND=kafka needs CSA_KAFKA_BROKERS")
+		}
+		fwd = events.NewKafka(ec.KafkaBrokers, ec.KafkaTopic)
+	}
+	b := events.New(fwd, ec.Buffer)
+	subscribeEvents(b)
+	slog.Info("events: bus started", "backend", ec.Backend)
+	return b, nil
+}
+
+// subscribeEvents registers the in-process subscribers.
+func subscribeEvents(b *events.Bus) {
+	// a re-indexed product may have new stock, price or attributes, so
+	// outfits built with it are rebuilt on next request
+	b.Subscribe(events.ProductIndexed, func(ctx context.Context, e events.Event) {
+		if d, ok := e.Data.(events.ProductIndexedData); ok {
+			outfits.invalidate(d.ProductIDs)
+		}
+	})
+}
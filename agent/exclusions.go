@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+)
+
+// Limits on exclude_terms and exclude_materials.
+const (
+	maxExclusions      = 10
+	maxExclusionLength = 40
+)
+
+func validateExclusions(field string, terms []string) error {
+	if len(terms) > maxExclusions {
+		return httpapi.InvalidField(field, "%s: at most %d entries", field, maxExclusions)
+	}
+	for _, t := range terms {
+		if t = strings.TrimSpace(t); t == "" || len(t) > maxExclusionLength {
+			return httpapi.InvalidField(field, "%s: entries must be 1-%d characters", field, maxExclusionLength)
+		}
+	}
+	return nil
+}
+
+func (f AttrFilters) hasExclusions() bool {
+	return len(f.ExcludeTerms) > 0 || len(f.ExcludeMaterials) > 0
+}
+
+// exclusionsSQL compiles the exclusions into WHERE clauses bound from $next
+// on. Terms are whole-word, case-insensitive regexes (\m and \M are word
+// boundaries in Postgres) over the title, colors, material and attribute
+// values; materials are substring matches on the material column.
+func (f AttrFilters) exclusionsSQL(next int) (string, []any) {
+	var b strings.Builder
+	var args []any
+	if terms := normalizeValues(f.ExcludeTerms); len(terms) > 0 {
+		patterns := make([]string, len(terms))
+		for i, t := range terms {
+			patterns[i] = `\m` + regexp.QuoteMeta(t) + `\M`
+		}
+		fmt.Fprintf(&b, `
+  AND NOT (title ~* ANY($%[1]d::text[])
+       OR COALESCE(material, '') ~* ANY($%[1]d::text[])
+       OR array_to_string(COALESCE(colors, '{}'), ' ') ~* ANY($%[1]d::text[])
+       OR EXISTS (SELECT 1 FROM jsonb_each(attributes) a, jsonb_array_elements_text(a.value) v(val)
+                  WHERE v.val ~* ANY($%[1]d::text[])))`, next)
+		args = append(args, patterns)
+		next++
+	}
+	if materials := normalizeValues(f.ExcludeMaterials); len(materials) > 0 {
+		escape := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+		patterns := make([]string, len(materials))
+		for i, m := range materials {
+			patterns[i] = "%" + escape.Replace(m) + "%"
+		}
+		fmt.Fprintf(&b, "\n  AND (material IS NULL OR NOT material LIKE ANY($%d::text[]))", next)
+		args = append(args, patterns)
+	}
+	return b.String(), args
+}
+
+func exclusionSchema(ids []string) *outputSchema {
+	return &outputSchema{Name: "exclusions", Schema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"violations": map[string]any{"type": "array", "items": map[string]any{"type": "string", "enum": ids}},
+		},
+		"required":             []string{"violations"},
+		"additionalProperties": false,
+	}}
+}
+
+// checkExclusions catches what the SQL filters can't: synonyms and kinds,
+// such as suede for "no leather" or merino for "no wool". It asks the chat
+// model which hits break an exclusion and drops them. On error the hits are
+// returned as they are; the SQL filters still applied.
+func checkExclusions(ctx context.Context, pool *pgxpool.Pool, hits []Hit, f AttrFilters) []Hit {
+	if len(hits) == 0 || !f.hasExclusions() || cfg().ExclusionCheck != config.ExclusionCheckLLM {
+		return hits
+	}
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ProductID
+	}
+	rows, err := pool.Query(ctx, `
+SELECT product_id, title, COALESCE(material, ''), COALESCE(colors, '{}')
+FROM product_embeddings WHERE product_id = ANY($1)
+`, ids)
+	if err != nil {
+		slog.WarnContext(ctx, "exclusions: product lookup failed", "err", err)
+		return hits
+	}
+	var lines []string
+	for rows.Next() {
+		var id, title, material string
+		var colors []string
+		if err := rows.Scan(&id, &title, &material, &colors); err != nil {
+			rows.Close()
+			slog.WarnContext(ctx, "exclusions: product lookup failed", "err", err)
+			return hits
+		}
+		lines = append(lines, fmt.Sprintf("- %s: %q, material %q, colors %q", id, title, material, strings.Join(colors, ", ")))
+	}
+	rows.Close()
+
+	var rules []string
+	if len(f.ExcludeTerms) > 0 {
+		rules = append(rules, "must not be, contain or be made of: "+strings.Join(f.ExcludeTerms, ", "))
+	}
+	if len(f.ExcludeMaterials) > 0 {
+		rules = append(rules, "must not contain these materials or any kind of them: "+strings.Join(f.ExcludeMaterials, ", "))
+	}
+	prompt := fmt.Sprintf(`A shopper excluded some things. Products %s.
+Count kinds and synonyms, e.g. suede and nubuck are leather, merino and cashmere are wool, fuchsia is pink.
+List the IDs of products that break an exclusion; list none if unsure.
+Return only a JSON object {"violations": [...]}.
+
+Products:
+%s`, strings.Join(rules, "; and "), strings.Join(lines, "\n"))
+
+	raw, err := llmChat(ctx, "", config.LLMExclusions, prompt, exclusionSchema(ids))
+	if err != nil {
+		slog.WarnContext(ctx, "exclusions: llm check failed", "err", err)
+		return hits
+	}
+	var out struct {
+		Violations []string `json:"violations"`
+	}
+	if err := json.Unmarshal([]byte(ranking.StripCodeFence(raw)), &out); err != nil {
+		slog.WarnContext(ctx, "exclusions: llm check failed", "err", err)
+		return hits
+	}
+	kept := slices.DeleteFunc(hits, func(h Hit) bool { return slices.Contains(out.Violations, h.ProductID) })
+	logOutcome(ctx, slog.Int("excluded_by_llm", len(ids)-len(kept)))
+	return kept
+}
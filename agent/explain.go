@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+)
+
+func explainOutfitWithFallback(ctx context.Context, resp CompleteOutfitResp, ts TenantSettings) ([]string, error) {
+	opts := ts.ExplainOptions
+	// Deterministic message if nothing found anywhere
+	anyHits := false
+	for _, r := range resp.Results {
+		if len(r.Hits) > 0 {
+			anyHits = true
+			break
+		}
+	}
+	if !anyHits {
+		return fallbackExplain(resp, opts), nil
+	}
+
+	bullets, explainErr := llmExplain(ctx, ts.LLMProvider, resp)
+	if explainErr != nil || len(bullets) == 0 {
+		slog.WarnContext(ctx, "explain: using fallback", "err", explainErr, "bullets", len(bullets))
+		return fallbackExplain(resp, opts), nil
+	}
+	return bullets, nil
+
+}
+
+// fallbackExplain builds deterministic bullets. opts controls which facts
+// are included, whether budget or eco is stated first, and the bullet cap.
+func fallbackExplain(resp CompleteOutfitResp, opts ExplainOptions) []string {
+	opts = opts.withDefaults()
+
+	facts := map[string][]string{
+		explainFactMissingSlots: {fmt.Sprintf("Missing slots detected: %v.", resp.MissingSlots)},
+		explainFactMethod:       {"Items were retrieved by semantic similarity for each slot, then filtered by price and eco constraints."},
+	}
+
+	var picks []Hit
+	for _, r := range resp.Results {
+		if len(r.Hits) == 0 {
+			facts[explainFactPicks] = append(facts[explainFactPicks], fmt.Sprintf("No results for %s: %s", r.Slot, r.Reason))
+			continue
+		}
+		h := r.Hits[0]
+		picks = append(picks, h)
+		line := fmt.Sprintf("Top %s pick fits constraints: Eco=%d, Price=£%.2f.", r.Slot, h.EcoScore, h.PriceGBP)
+		if opts.Priority == explainPriorityBudget {
+			line = fmt.Sprintf("Top %s pick fits constraints: Price=£%.2f, Eco=%d.", r.Slot, h.PriceGBP, h.EcoScore)
+		}
+		if r.Confidence != nil && r.Confidence.Low {
+			line += " Low confidence: treat it as an idea."
+		}
+		if note := preorderNote(h); note != "" {
+			line += " " + note
+		}
+		facts[explainFactPicks] = append(facts[explainFactPicks], line)
+	}
+
+	if len(picks) > 0 {
+		total, minEco := 0.0, picks[0].EcoScore
+		for _, h := range picks {
+			total += h.PriceGBP
+			minEco = min(minEco, h.EcoScore)
+		}
+		if resp.BudgetGBP > 0 {
+			facts[explainFactBudget] = []string{fmt.Sprintf("Top picks total £%.2f against a £%.2f budget.", total, resp.BudgetGBP)}
+		} else {
+			facts[explainFactBudget] = []string{fmt.Sprintf("Top picks total £%.2f.", total)}
+		}
+		facts[explainFactEco] = []string{fmt.Sprintf("Every top pick has an eco score of at least %d.", minEco)}
+		if line := ecoGradeSentence(resp.EcoGrade); line != "" {
+			facts[explainFactEco] = append(facts[explainFactEco], line)
+		}
+	}
+	if line := bundleSentence(resp.Bundle); line != "" {
+		facts[explainFactBundle] = []string{line}
+	}
+	if line := weatherSentence(resp.Forecast); line != "" {
+		facts[explainFactForecast] = []string{line}
+	}
+
+	included := map[string]bool{}
+	for _, f := range opts.Facts {
+		included[f] = true
+	}
+
+	var out []string
+	for _, f := range opts.factOrder() {
+		if included[f] {
+			out = append(out, facts[f]...)
+		}
+	}
+
+	if len(out) > opts.MaxBullets {
+		out = out[:opts.MaxBullets]
+	}
+	return out
+}
+
+// explainSchema is the structured output requested from every provider.
+var explainSchema = &outputSchema{Name: "explanation", Schema: map[string]any{
+	"type":                 "object",
+	"properties":           map[string]any{"bullets": map[string]any{"type": "array", "items": map[string]any{"type": "string"}}},
+	"required":             []string{"bullets"},
+	"additionalProperties": false,
+}}
+
+func llmExplain(ctx context.Context, provider string, resp CompleteOutfitResp) ([]string, error) {
+	b, _ := json.Marshal(resp)
+
+	prompt := fmt.Sprintf(`
+You are a precise shopping assistant.
+
+Given this JSON result, write 3-5 concise bullet points explaining the selection.
+
+Rules:
+- Write like a helpful shopping assistant, not a technical report.
+- Avoid repeating field names (do not say “eco score for bottom”).
+- Combine eco + price naturally in the same sentence.
+- First bullet MUST state the missing slots exactly as provided in input_json.missing_slots.
+- Mention mission, eco_score, and price/budget fit.
+- If a slot has zero hits, clearly explain why using the reason field.
+- If eco_grade is present, state the outfit's overall eco grade (A best, E worst) once; if eco_grade.capped, say its lowest-scoring item holds it back.
+- If a result's confidence.low is true, hedge its pick ("could work", "one idea") rather than recommending it outright.
+- If a top pick's lifecycle is "preorder", say it is a preorder and give its ships_at date when present.
+- If forecast is present, say how the picks suit it (rain, temperature, wind) using forecast.summary and the hits' reason fields.
+- If bundle.promotion is present, say the picks qualify for it and state bundle.total_gbp; if bundle.suggestion is present, suggest adding that item and state the saving.
+- Each bullet must be <= 18 words.
+- Write in natural language (no "Eco score for bottom:" labels).
+- Do NOT invent information.
+- When referencing an item, use its title from INPUT_JSON exactly.
+- Return ONLY a JSON object {"bullets": [...]} with the bullets as strings. No extra text.
+- Base every statement strictly on INPUT_JSON. Do not generalise beyond it.
+
+INPUT_JSON:
+%s
+`, string(b))
+
+	raw, err := llmChat(ctx, provider, config.LLMExplain, prompt, explainSchema)
+
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "explain: llm output", "raw", raw)
+
+	bullets, err := ranking.ParseBullets(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(bullets) > 5 {
+		bullets = bullets[:5]
+	}
+	return bullets, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// templateExplain is the deterministic explanation engine for tenants that
+// don't allow LLM-generated shopper copy. Every sentence is built from
+// fields in resp, so it can never claim something the data doesn't support.
+func templateExplain(resp CompleteOutfitResp) []string {
+	mission := humanizeMission(resp.Mission)
+
+	if len(resp.MissingSlots) == 0 {
+		return []string{fmt.Sprintf("Your %s outfit is already complete, so nothing needs adding.", mission)}
+	}
+
+	var out []string
+
+	// mission fit
+	line := fmt.Sprintf("For a %s outfit you still need %s", mission, joinWords(resp.MissingSlots))
+	if len(resp.CartSlots) > 0 {
+		line += fmt.Sprintf(" to go with your %s", joinWords(resp.CartSlots))
+	}
+	out = append(out, line+".")
+	if line := weatherSentence(resp.Forecast); line != "" {
+		out = append(out, line)
+	}
+
+	// per-slot picks
+	var picks []Hit
+	for _, r := range resp.Results {
+		if len(r.Hits) == 0 {
+			reason := r.Reason
+			if reason == "" {
+				reason = "nothing matched your constraints."
+			}
+			out = append(out, fmt.Sprintf("We couldn't find %s: %s", r.Slot, reason))
+			continue
+		}
+		h := r.Hits[0]
+		picks = append(picks, h)
+		if r.Confidence != nil && r.Confidence.Low {
+			out = append(out, fmt.Sprintf("For %s, %s at £%.2f could work, though nothing matched closely.", r.Slot, h.Title, h.PriceGBP))
+		} else {
+			out = append(out, fmt.Sprintf("For %s, %s at £%.2f is the closest match.", r.Slot, h.Title, h.PriceGBP))
+		}
+		if note := preorderNote(h); note != "" {
+			out = append(out, note)
+		}
+	}
+	if len(picks) == 0 {
+		return out
+	}
+
+	// budget math
+	total := 0.0
+	for _, h := range picks {
+		total += h.PriceGBP
+	}
+	switch {
+	case resp.BudgetGBP <= 0:
+		out = append(out, fmt.Sprintf("Together the top picks come to £%.2f.", total))
+	case total <= resp.BudgetGBP:
+		out = append(out, fmt.Sprintf("Together the top picks come to £%.2f, leaving £%.2f of your £%.2f budget.",
+			total, resp.BudgetGBP-total, resp.BudgetGBP))
+	default:
+		out = append(out, fmt.Sprintf("Together the top picks come to £%.2f, £%.2f over your £%.2f budget.",
+			total, total-resp.BudgetGBP, resp.BudgetGBP))
+	}
+	if line := bundleSentence(resp.Bundle); line != "" {
+		out = append(out, line)
+	}
+
+	// eco highlight
+	best := picks[0]
+	for _, h := range picks[1:] {
+		if h.EcoScore > best.EcoScore {
+			best = h
+		}
+	}
+	if resp.MinEcoScore > 0 {
+		out = append(out, fmt.Sprintf("Every pick meets your minimum eco score of %d, led by %s at %d/100.",
+			resp.MinEcoScore, best.Title, best.EcoScore))
+	} else if best.EcoScore > 0 {
+		out = append(out, fmt.Sprintf("%s has the strongest eco credentials at %d/100.", best.Title, best.EcoScore))
+	}
+	if line := ecoGradeSentence(resp.EcoGrade); line != "" {
+		out = append(out, line)
+	}
+
+	// coherence
+	if len(picks) > 1 {
+		sim := 0.0
+		for _, h := range picks {
+			sim += h.Similarity
+		}
+		out = append(out, fmt.Sprintf("All picks were chosen for the same %s brief (average match %.0f%%), so they work as one outfit.",
+			mission, sim/float64(len(picks))))
+	}
+
+	return out
+}
+
+func humanizeMission(m string) string {
+	if m == "" {
+		m = "smart_casual"
+	}
+	return strings.ReplaceAll(m, "_", " ")
+}
+
+// joinWords renders ["a","b","c"] as "a, b and c".
+func joinWords(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func sampleOutfit() CompleteOutfitResp {
+	return CompleteOutfitResp{
+		Mission:      "smart_casual",
+		BudgetGBP:    120,
+		MissingSlots: []string{"bottom", "shoes"},
+		Results: []SlotRecs{
+			{Slot: "bottom", Hits: []Hit{{Title: "Linen Chinos", EcoScore: 80, PriceGBP: 45}}},
+			{Slot: "shoes", Hits: []Hit{{Title: "Canvas Trainers", EcoScore: 65, PriceGBP: 55.5}}},
+		},
+	}
+}
+
+func TestFallbackExplain(t *testing.T) {
+	tests := []struct {
+		name string
+		resp CompleteOutfitResp
+		opts ExplainOptions
+		want []string
+	}{
+		{
+			name: "defaults match original output",
+			resp: sampleOutfit(),
+			want: []string{
+				"Missing slots detected: [bottom shoes].",
+				"Items were retrieved by semantic similarity for each slot, then filtered by price and eco constraints.",
+				"Top bottom pick fits constraints: Eco=80, Price=£45.00.",
+				"Top shoes pick fits constraints: Eco=65, Price=£55.50.",
+			},
+		},
+		{
+			name: "max bullets truncates",
+			resp: sampleOutfit(),
+			opts: ExplainOptions{MaxBullets: 2},
+			want: []string{
+				"Missing slots detected: [bottom shoes].",
+				"Items were retrieved by semantic similarity for each slot, then filtered by price and eco constraints.",
+			},
+		},
+		{
+			name: "budget priority puts budget first",
+			resp: sampleOutfit(),
+			opts: ExplainOptions{Priority: explainPriorityBudget, Facts: []string{explainFactEco, explainFactBudget, explainFactPicks}},
+			want: []string{
+				"Top picks total £100.50 against a £120.00 budget.",
+				"Every top pick has an eco score of at least 65.",
+				"Top bottom pick fits constraints: Price=£45.00, Eco=80.",
+				"Top shoes pick fits constraints: Price=£55.50, Eco=65.",
+			},
+		},
+		{
+			name: "eco priority puts eco first",
+			resp: sampleOutfit(),
+			opts: ExplainOptions{Facts: []string{explainFactBudget, explainFactEco}},
+			want: []string{
+				"Every top pick has an eco score of at least 65.",
+				"Top picks total £100.50 against a £120.00 budget.",
+			},
+		},
+		{
+			name: "empty slot keeps reason",
+			resp: CompleteOutfitResp{
+				MissingSlots: []string{"shoes"},
+				Results:      []SlotRecs{{Slot: "shoes", Hits: []Hit{}, Reason: "too expensive"}},
+			},
+			opts: ExplainOptions{Facts: []string{explainFactBudget, explainFactPicks}},
+			want: []string{"No results for shoes: too expensive"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fallbackExplain(tt.resp, tt.opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fallbackExplain() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExplainOptionsValidate(t *testing.T) {
+	tests := []struct {
+		opts    ExplainOptions
+		wantErr bool
+	}{
+		{ExplainOptions{}, false},
+		{ExplainOptions{MaxBullets: 3, Priority: explainPriorityBudget, Facts: []string{explainFactPicks}}, false},
+		{ExplainOptions{MaxBullets: 11}, true},
+		{ExplainOptions{Priority: "price"}, true},
+		{ExplainOptions{Facts: []string{"weather"}}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("validate(%+v) err=%v, wantErr %v", tt.opts, err, tt.wantErr)
+		}
+	}
+}
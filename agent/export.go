@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// rows written between flushes of a streamed export
+const exportFlushEvery = 500
+
+// EmbeddingExport is one line of GET /export-embeddings.
+type EmbeddingExport struct {
+	ProductID      string         `json:"product_id"`
+	Metadata       ExportMetadata `json:"metadata"`
+	Vector         []float64      `json:"vector"`
+	ImageEmbedding []float64      `json:"image_vector,omitempty"`
+}
+
+type ExportMetadata struct {
+	Title          string     `json:"title"`
+	Category       string     `json:"category"`
+	Thumbnail      string     `json:"thumbnail,omitempty"`
+	EcoScore       int        `json:"eco_score"`
+	PriceGBP       float64    `json:"price_gbp"`
+	Brand          string     `json:"brand,omitempty"`
+	Material       string     `json:"material,omitempty"`
+	Sizes          []string   `json:"sizes,omitempty"`
+	Colors         []string   `json:"colors,omitempty"`
+	EcoLabels      []string   `json:"eco_labels,omitempty"`
+	OriginCountry  string     `json:"origin_country,omitempty"`
+	Clearance      bool       `json:"clearance"`
+	StockQty       *int       `json:"stock_qty,omitempty"`
+	FirstIndexedAt *time.Time `json:"first_indexed_at,omitempty"`
+	IndexedAt      *time.Time `json:"indexed_at,omitempty"`
+}
+
+type exportOpts struct {
+	Tenant   string
+	Category string
+	Images   bool // include image embeddings
+}
+
+// exportEmbeddings writes the tenant's indexed products as JSONL, one row at
+// a time so large catalogs don't have to fit in memory.
+func exportEmbeddings(ctx context.Context, pool *pgxpool.Pool, opts exportOpts, w io.Writer) (int, error) {
+	rows, err := pool.Query(ctx, `
+SELECT product_id, COALESCE(title, ''), COALESCE(category, ''), COALESCE(thumbnail, ''), COALESCE(eco_score, 0), COALESCE(price_gbp, 0)::float8,
+       COALESCE(brand, ''), COALESCE(material, ''), COALESCE(sizes, '{}'), COALESCE(colors, '{}'), COALESCE(eco_labels, '{}'),
+       COALESCE(origin_country, ''), COALESCE(clearance, false), stock_qty, first_indexed_at, indexed_at,
+       embedding::text, CASE WHEN $3::bool THEN image_embedding::text END
+FROM product_embeddings
+WHERE embedding IS NOT NULL
+  AND COALESCE(tenant_id, $4) = $1
+  AND ($2::text IS NULL OR category = $2)
+ORDER BY product_id
+`, opts.Tenant, search.NullText(opts.Category), opts.Images, defaultTenant)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	// a ResponseController reaches the connection through the middleware's
+	// wrappers, which an http.Flusher assertion wouldn't
+	flush := func() {}
+	if rw, ok := w.(http.ResponseWriter); ok {
+		rc := http.NewResponseController(rw)
+		flush = func() { rc.Flush() }
+	}
+	enc := json.NewEncoder(w)
+	n := 0
+	for rows.Next() {
+		var e EmbeddingExport
+		var vec string
+		var img *string
+		m := &e.Metadata
+		if err := rows.Scan(&e.ProductID, &m.Title, &m.Category, &m.Thumbnail, &m.EcoScore, &m.PriceGBP,
+			&m.Brand, &m.Material, &m.Sizes, &m.Colors, &m.EcoLabels,
+			&m.OriginCountry, &m.Clearance, &m.StockQty, &m.FirstIndexedAt, &m.IndexedAt,
+			&vec, &img); err != nil {
+			return n, err
+		}
+		if e.Vector, err = parseVector(vec); err != nil {
+			return n, err
+		}
+		if img != nil {
+			if e.ImageEmbedding, err = parseVector(*img); err != nil {
+				return n, err
+			}
+		}
+		if err := enc.Encode(e); err != nil {
+			return n, err
+		}
+		n++
+		if n%exportFlushEvery == 0 {
+			flush()
+		}
+	}
+	return n, rows.Err()
+}
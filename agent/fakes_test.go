@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/embeddings"
+)
+
+// Test doubles for the agent's outside dependencies, so tests run offline:
+// embeddings.Fake stands in for the embedding backend, cannedChat for the
+// LLM providers, fakeMedusa for the Medusa API and miniredis for Redis.
+
+// useTestConfig makes settings loaded from env (plus the required ones) the
+// live configuration for the rest of the test, with the fake embedding
+// backend and the canned chat provider selected.
+func useTestConfig(t *testing.T, env map[string]string) *config.Config {
+	t.Helper()
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	t.Setenv("OPENAI_BASE_URL", "http://openai.invalid") // nothing may call it
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	c, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Embed.Backend = "fake"
+	c.LLMProvider = "canned"
+
+	prev := liveConfig.Swap(c)
+	embedBackends["fake"] = func() embeddings.Provider { return embeddings.Fake{} }
+	resetBreakers()
+	t.Cleanup(func() {
+		liveConfig.Store(prev)
+		delete(embedBackends, "fake")
+		resetBreakers()
+	})
+	return c
+}
+
+// useFakeRedis makes an in-process Redis the agent's cache for the rest of
+// the test.
+func useFakeRedis(t *testing.T) *miniredis.Miniredis {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	prev := redisCache
+	redisCache = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		redisCache.Close()
+		redisCache = prev
+	})
+	return mr
+}
+
+// cannedChat is a chatProvider with scripted replies: by schema name for
+// structured requests, else the first reply whose key the prompt contains,
+// else def. It records every request.
+type cannedChat struct {
+	replies map[string]string
+	def     string
+	err     error // returned instead of a reply when set
+
+	mu       sync.Mutex
+	requests []chatRequest
+}
+
+func (c *cannedChat) chat(_ context.Context, req chatRequest) (chatResult, error) {
+	c.mu.Lock()
+	c.requests = append(c.requests, req)
+	c.mu.Unlock()
+	if c.err != nil {
+		return chatResult{}, c.err
+	}
+	text, ok := "", false
+	if req.Schema != nil {
+		text, ok = c.replies[req.Schema.Name]
+	}
+	for key, reply := range c.replies {
+		if !ok && strings.Contains(req.Prompt, key) {
+			text, ok = reply, true
+		}
+	}
+	if !ok {
+		text = c.def
+	}
+	return chatResult{Text: text, PromptTokens: len(req.Prompt) / 4, CompletionTokens: len(text) / 4}, nil
+}
+
+func (c *cannedChat) defaultModel() string { return "canned" }
+func (c *cannedChat) configured() bool     { return true }
+
+func (c *cannedChat) calls() []chatRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]chatRequest(nil), c.requests...)
+}
+
+// useCannedChat registers c as the "canned" provider, which useTestConfig
+// makes the default, for the rest of the test.
+func useCannedChat(t *testing.T, c *cannedChat) *cannedChat {
+	t.Helper()
+	chatProviders["canned"] = c
+	t.Cleanup(func() { delete(chatProviders, "canned") })
+	return c
+}
+
+// fakeMedusa serves the parts of the Medusa v2 API the agent calls: admin
+// products (paged), store carts, promotions and email/password login.
+type fakeMedusa struct {
+	*httptest.Server
+	Products   []map[string]any
+	Carts      map[string]map[string]any
+	Promotions []map[string]any
+	Email      string
+	Password   string
+	// PublishableKey is what the store routes (carts) require
+	PublishableKey string
+
+	mu       sync.Mutex
+	token    string // the current admin session; "" before login
+	logins   int
+	requests []string // method and path of every call
+}
+
+func newFakeMedusa(t *testing.T) *fakeMedusa {
+	t.Helper()
+	m := &fakeMedusa{Carts: map[string]map[string]any{}, Email: "admin@example.com", Password: "secret", PublishableKey: "pk_test"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /auth/user/emailpass", m.login)
+	mux.HandleFunc("GET /admin/products", m.admin(m.products))
+	mux.HandleFunc("GET /admin/promotions", m.admin(func(w http.ResponseWriter, r *http.Request) {
+		writeFakeJSON(w, map[string]any{"promotions": m.Promotions, "count": len(m.Promotions)})
+	}))
+	mux.HandleFunc("GET /store/carts/{id}", m.store(func(w http.ResponseWriter, r *http.Request) {
+		cart, ok := m.Carts[r.PathValue("id")]
+		if !ok {
+			http.Error(w, `{"message":"cart not found"}`, http.StatusNotFound)
+			return
+		}
+		writeFakeJSON(w, map[string]any{"cart": cart})
+	}))
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.requests = append(m.requests, r.Method+" "+r.URL.Path)
+		m.mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(m.Close)
+
+	prev := medusa
+	medusa = &medusaSession{}
+	t.Cleanup(func() { medusa = prev })
+	return m
+}
+
+// env is the configuration that points the agent at the fake, logging in
+// with email and password.
+func (m *fakeMedusa) env() map[string]string {
+	return map[string]string{
+		"MEDUSA_BASE_URL":        m.URL,
+		"MEDUSA_ADMIN_EMAIL":     m.Email,
+		"MEDUSA_ADMIN_PASSWORD":  m.Password,
+		"MEDUSA_PUBLISHABLE_KEY": m.PublishableKey,
+	}
+}
+
+// expireSession makes the fake reject the agent's current token, as when an
+// admin session is revoked.
+func (m *fakeMedusa) expireSession() {
+	m.mu.Lock()
+	m.token = ""
+	m.mu.Unlock()
+}
+
+func (m *fakeMedusa) login(w http.ResponseWriter, r *http.Request) {
+	var body struct{ Email, Password string }
+	if json.NewDecoder(r.Body).Decode(&body) != nil || body.Email != m.Email || body.Password != m.Password {
+		http.Error(w, `{"message":"invalid credentials"}`, http.StatusUnauthorized)
+		return
+	}
+	m.mu.Lock()
+	m.logins++
+	m.token = "token-" + strconv.Itoa(m.logins) // no exp claim: valid until expired
+	tok := m.token
+	m.mu.Unlock()
+	writeFakeJSON(w, map[string]string{"token": tok})
+}
+
+// admin rejects calls without the current session token.
+func (m *fakeMedusa) admin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		ok := m.token != "" && r.Header.Get("Authorization") == "Bearer "+m.token
+		m.mu.Unlock()
+		if !ok {
+			http.Error(w, `{"message":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// store rejects calls without the publishable key, and calls that carry
+// admin credentials, which a storefront never has.
+func (m *fakeMedusa) store(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-publishable-api-key") != m.PublishableKey || r.Header.Get("Authorization") != "" {
+			http.Error(w, `{"message":"invalid publishable key"}`, http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (m *fakeMedusa) products(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	page := []map[string]any{}
+	if offset < len(m.Products) {
+		page = m.Products[offset:min(offset+limit, len(m.Products))]
+	}
+	writeFakeJSON(w, map[string]any{"products": page, "count": len(m.Products), "offset": offset, "limit": limit})
+}
+
+func writeFakeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
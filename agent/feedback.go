@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/events"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// feedbackSignals are the signals a storefront can send for a hit.
+var feedbackSignals = []string{signalUp, signalDown, signalAddToCart}
+
+// feedbackPrior damps products with little feedback: a single thumbs-up
+// gives a signal of 1/6, not 1.
+const feedbackPrior = 5
+
+// FeedbackReq is one shopper reaction to a recommended product.
+type FeedbackReq struct {
+	ProductID string `json:"product_id"`
+	Signal    string `json:"signal"`           // up | down | add_to_cart
+	Query     string `json:"query,omitempty"`  // the search or outfit request the hit answered
+	Source    string `json:"source,omitempty"` // search | complete-outfit | group-outfits | substitutes
+}
+
+func (f FeedbackReq) validate() error {
+	if f.ProductID == "" || len(f.ProductID) > 128 {
+		return httpapi.InvalidField("product_id", "product_id is required, at most 128 characters")
+	}
+	if !slices.Contains(feedbackSignals, f.Signal) {
+		return httpapi.InvalidField("signal", "signal must be one of %v", feedbackSignals)
+	}
+	if len(f.Query) > maxIntentText {
+		return httpapi.InvalidField("query", "query must be at most %d characters", maxIntentText)
+	}
+	if f.Source != "" && !slices.Contains(historySources, f.Source) {
+		return httpapi.InvalidField("source", "source must be one of %v", historySources)
+	}
+	return nil
+}
+
+// errFeedbackUnknownProduct is returned by saveFeedback for a product the
+// tenant hasn't indexed.
+var errFeedbackUnknownProduct = httpapi.InvalidField("product_id", "product_id is not an indexed product")
+
+// saveFeedback stores the reaction and, with X-Session-ID, adds it to the
+// session transcript, where build-dataset pairs it with the turn that
+// showed the product. A session's repeat of a reaction it already gave is
+// accepted but not stored again.
+func saveFeedback(ctx context.Context, pool *pgxpool.Pool, tenantID string, f FeedbackReq) error {
+	var indexed bool
+	err := pool.QueryRow(ctx, `
+SELECT EXISTS (SELECT 1 FROM product_embeddings WHERE product_id=$1 AND COALESCE(tenant_id, $3)=$2)
+`, f.ProductID, tenantID, defaultTenant).Scan(&indexed)
+	if err != nil {
+		return err
+	}
+	if !indexed {
+		return errFeedbackUnknownProduct
+	}
+
+	var sessionID any
+	data := events.FeedbackReceivedData{ProductID: f.ProductID, Signal: f.Signal, Query: f.Query, Source: f.Source}
+	if s := sessionFrom(ctx); s != nil {
+		sessionID = s.ID
+		data.SessionID = s.ID
+	}
+	tag, err := pool.Exec(ctx, `
+INSERT INTO feedback (tenant_id, product_id, signal, query, query_norm, source, session_id, residency_zone)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+ON CONFLICT (tenant_id, session_id, product_id, signal) WHERE session_id IS NOT NULL DO NOTHING
+`, tenantID, f.ProductID, f.Signal, f.Query, normalizeQuery(f.Query), f.Source, sessionID, residencyFrom(ctx))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		logOutcome(ctx, slog.Bool("duplicate", true))
+		return nil
+	}
+	sessionFrom(ctx).record(ctx, eventFeedback, f)
+	bus.Publish(events.FeedbackReceived, tenantID, data)
+	return nil
+}
+
+// feedbackTally counts the signals for one product, overall or for a query.
+type feedbackTally struct{ ups, downs, carts int }
+
+// signal is between -1 (only thumbs down) and 1 (only positive). Adding to
+// the cart counts twice as much as a thumbs up.
+func (t feedbackTally) signal() float64 {
+	pos, neg := float64(t.ups+2*t.carts), float64(t.downs)
+	return (pos - neg) / (pos + neg + feedbackPrior)
+}
+
+// applyFeedback moves hits up or down by what shoppers thought of them,
+// from mv_feedback_scores. Feedback given for the same query counts twice as
+// much as the product's feedback overall. CSA_FEEDBACK_BOOST caps the shift
+// at that fraction of the similarity scale.
+func applyFeedback(ctx context.Context, pool *pgxpool.Pool, hits []Hit, query string) ([]Hit, error) {
+	if len(hits) == 0 || cfg().FeedbackBoost <= 0 || sandboxFrom(ctx) {
+		return hits, nil
+	}
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ProductID
+	}
+	q := normalizeQuery(query)
+	rows, err := pool.Query(ctx, `
+SELECT product_id, query_norm, ups, downs, carts FROM mv_feedback_scores
+WHERE tenant_id = $1 AND product_id = ANY($2) AND query_norm IN ('', $3)
+`, tenantFromContext(ctx), ids, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	overall, forQuery := map[string]feedbackTally{}, map[string]feedbackTally{}
+	for rows.Next() {
+		var id, qn string
+		var t feedbackTally
+		if err := rows.Scan(&id, &qn, &t.ups, &t.downs, &t.carts); err != nil {
+			return nil, err
+		}
+		if qn == "" {
+			overall[id] = t
+		} else {
+			forQuery[id] = t
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(overall) == 0 {
+		return hits, nil
+	}
+
+	signals := make(map[string]float64, len(overall))
+	for i := range hits {
+		id := hits[i].ProductID
+		t, ok := overall[id]
+		if !ok {
+			continue
+		}
+		s := t.signal()
+		if qt, ok := forQuery[id]; ok && q != "" {
+			s = (s + 2*qt.signal()) / 3
+		}
+		s = math.Round(s*100) / 100
+		signals[id] = s
+		hits[i].FeedbackScore = &s
+	}
+	score := func(h Hit) float64 { return h.rankScore() + cfg().FeedbackBoost*100*signals[h.ProductID] }
+	sort.SliceStable(hits, func(i, j int) bool { return score(hits[i]) > score(hits[j]) })
+	return hits, nil
+}
+
+// FeedbackCounts are signal totals.
+type FeedbackCounts struct {
+	Up        int `json:"up"`
+	Down      int `json:"down"`
+	AddToCart int `json:"add_to_cart"`
+}
+
+type ProductFeedback struct {
+	ProductID string  `json:"product_id"`
+	Title     string  `json:"title,omitempty"`
+	Score     float64 `json:"score"` // -1..1, as used in ranking
+	FeedbackCounts
+}
+
+type QueryFeedback struct {
+	Query string `json:"query"`
+	FeedbackCounts
+}
+
+// FeedbackReport is GET /admin/feedback: what shoppers reacted to since
+// Since, the products and queries with the most feedback first.
+type FeedbackReport struct {
+	Since    time.Time         `json:"since"`
+	Totals   FeedbackCounts    `json:"totals"`
+	Products []ProductFeedback `json:"products"`
+	Queries  []QueryFeedback   `json:"queries"`
+}
+
+func feedbackReport(ctx context.Context, pool *pgxpool.Pool, tenantID string, since time.Time, limit int) (FeedbackReport, error) {
+	rep := FeedbackReport{Since: since, Products: []ProductFeedback{}, Queries: []QueryFeedback{}}
+	counts := `count(*) FILTER (WHERE signal = 'up')::int, count(*) FILTER (WHERE signal = 'down')::int,
+       count(*) FILTER (WHERE signal = 'add_to_cart')::int`
+	if err := pool.QueryRow(ctx, `SELECT `+counts+` FROM feedback WHERE tenant_id = $1 AND created_at >= $2`,
+		tenantID, since).Scan(&rep.Totals.Up, &rep.Totals.Down, &rep.Totals.AddToCart); err != nil {
+		return rep, err
+	}
+
+	rows, err := pool.Query(ctx, `
+SELECT f.product_id, COALESCE(max(p.title), ''), `+counts+`
+FROM feedback f
+LEFT JOIN product_embeddings p ON p.product_id = f.product_id
+WHERE f.tenant_id = $1 AND f.created_at >= $2
+GROUP BY f.product_id
+ORDER BY count(*) DESC, f.product_id
+LIMIT $3
+`, tenantID, since, limit)
+	if err != nil {
+		return rep, err
+	}
+	for rows.Next() {
+		var p ProductFeedback
+		if err := rows.Scan(&p.ProductID, &p.Title, &p.Up, &p.Down, &p.AddToCart); err != nil {
+			rows.Close()
+			return rep, err
+		}
+		p.Score = math.Round(feedbackTally{p.Up, p.Down, p.AddToCart}.signal()*100) / 100
+		rep.Products = append(rep.Products, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return rep, err
+	}
+
+	rows, err = pool.Query(ctx, `
+SELECT query_norm, `+counts+`
+FROM feedback
+WHERE tenant_id = $1 AND created_at >= $2 AND query_norm <> ''
+GROUP BY query_norm
+ORDER BY count(*) DESC, query_norm
+LIMIT $3
+`, tenantID, since, limit)
+	if err != nil {
+		return rep, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var q QueryFeedback
+		if err := rows.Scan(&q.Query, &q.Up, &q.Down, &q.AddToCart); err != nil {
+			return rep, err
+		}
+		rep.Queries = append(rep.Queries, q)
+	}
+	return rep, rows.Err()
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// FreshnessPrefs promote new stock. Both fields are optional; without them
+// CSA_RECENCY_BOOST applies and no filter is added.
+type FreshnessPrefs struct {
+	NewArrivals  bool     `json:"new_arrivals,omitempty"`  // only products first indexed within CSA_NEW_ARRIVAL_DAYS
+	RecencyBoost *float64 `json:"recency_boost,omitempty"` // 0-1 weight of newness in ranking
+}
+
+func (f FreshnessPrefs) validate() error {
+	if f.RecencyBoost != nil && (*f.RecencyBoost < 0 || *f.RecencyBoost > 1) {
+		return httpapi.InvalidField("recency_boost", "recency_boost must be between 0 and 1")
+	}
+	return nil
+}
+
+// boost is the effective recency weight.
+func (f FreshnessPrefs) boost() float64 {
+	if f.RecencyBoost != nil {
+		return *f.RecencyBoost
+	}
+	return cfg().RecencyBoost
+}
+
+// freshnessFactor is 1 for a product indexed today, halving every
+// CSA_NEW_ARRIVAL_DAYS.
+func freshnessFactor(age time.Duration) float64 {
+	window := time.Duration(cfg().NewArrivalDays) * 24 * time.Hour
+	return math.Exp2(-max(age, 0).Hours() / window.Hours())
+}
+
+// applyFreshness marks new arrivals and, with a recency boost, re-ranks
+// hits by blending similarity with newness. It only costs a query when the
+// request asks for freshness in some way.
+func applyFreshness(ctx context.Context, pool *pgxpool.Pool, hits []Hit, prefs FreshnessPrefs) ([]Hit, error) {
+	b := prefs.boost()
+	if len(hits) == 0 || (b == 0 && !prefs.NewArrivals) {
+		return hits, nil
+	}
+
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ProductID
+	}
+	rows, err := pool.Query(ctx, `
+SELECT product_id, first_indexed_at FROM product_embeddings
+WHERE product_id = ANY($1) AND first_indexed_at IS NOT NULL
+`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ages := make(map[string]time.Duration, len(hits))
+	for rows.Next() {
+		var id string
+		var first time.Time
+		if err := rows.Scan(&id, &first); err != nil {
+			return nil, err
+		}
+		ages[id] = time.Since(first)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	window := time.Duration(cfg().NewArrivalDays) * 24 * time.Hour
+	factors := make(map[string]float64, len(hits))
+	for i := range hits {
+		age, ok := ages[hits[i].ProductID]
+		if !ok {
+			continue
+		}
+		hits[i].NewArrival = age <= window
+		factors[hits[i].ProductID] = freshnessFactor(age)
+	}
+
+	if b > 0 {
+		score := func(h Hit) float64 { return (1-b)*h.rankScore() + b*factors[h.ProductID]*100 }
+		sort.SliceStable(hits, func(i, j int) bool { return score(hits[i]) > score(hits[j]) })
+	}
+	return hits, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// GiftOptions turn /complete-outfit into gift mode: only gift-eligible items
+// (gift wrap available, not final sale), wrapping priced into the budget,
+// and a suggested gift message.
+type GiftOptions struct {
+	Recipient   string  `json:"recipient,omitempty"` // e.g. "my brother"
+	Occasion    string  `json:"occasion,omitempty"`  // e.g. "birthday"
+	WrapCostGBP float64 `json:"wrap_cost_gbp,omitempty"`
+}
+
+// GiftSummary is returned on CompleteOutfitResp in gift mode.
+type GiftSummary struct {
+	WrapCostPerItemGBP  float64 `json:"wrap_cost_per_item_gbp"`
+	WrapCostTotalGBP    float64 `json:"wrap_cost_total_gbp"`
+	BudgetForItemsGBP   float64 `json:"budget_for_items_gbp,omitempty"`
+	MessageSuggestion   string  `json:"message_suggestion"`
+	MessageFromTemplate bool    `json:"message_from_template,omitempty"`
+}
+
+func (g *GiftOptions) validate() error {
+	if g == nil {
+		return nil
+	}
+	if g.WrapCostGBP < 0 {
+		return httpapi.InvalidField("gift.wrap_cost_gbp", "gift.wrap_cost_gbp must not be negative")
+	}
+	if len(g.Recipient) > 80 || len(g.Occasion) > 80 {
+		return httpapi.InvalidField("gift", "gift.recipient and gift.occasion must be at most 80 characters")
+	}
+	return nil
+}
+
+func (g *GiftOptions) wrapCost() float64 {
+	if g.WrapCostGBP > 0 {
+		return g.WrapCostGBP
+	}
+	return cfg().GiftWrapGBP
+}
+
+// giftBudget removes wrapping for each item to be bought from the budget.
+func giftBudget(budget float64, items int, g *GiftOptions) (itemsBudget float64, summary *GiftSummary) {
+	wrap := g.wrapCost()
+	summary = &GiftSummary{
+		WrapCostPerItemGBP: wrap,
+		WrapCostTotalGBP:   wrap * float64(items),
+	}
+	if budget <= 0 {
+		return budget, summary
+	}
+	itemsBudget = max(budget-summary.WrapCostTotalGBP, 0)
+	summary.BudgetForItemsGBP = itemsBudget
+	return itemsBudget, summary
+}
+
+// giftMessage suggests a card message. Tenants on the template explanation
+// engine never get LLM copy; otherwise the LLM is tried first.
+func giftMessage(ctx context.Context, pool *pgxpool.Pool, g *GiftOptions, resp CompleteOutfitResp) (string, bool) {
+	ts, err := loadTenantSettings(ctx, pool, tenantFromContext(ctx))
+	if err == nil && ts.ExplainEngine != explainEngineTemplate {
+		var titles []string
+		for _, r := range resp.Results {
+			if len(r.Hits) > 0 {
+				titles = append(titles, r.Hits[0].Title)
+			}
+		}
+		prompt := fmt.Sprintf(`Write one warm gift card message (max 30 words) for a %s outfit gift.
+Recipient: %q. Occasion: %q. Items: %s.
+Do not mention prices. Return only the message text.`,
+			humanizeMission(resp.Mission), g.Recipient, g.Occasion, strings.Join(titles, ", "))
+		msg, err := llmChat(ctx, ts.LLMProvider, config.LLMGiftMessage, prompt, nil)
+		if msg = strings.Trim(strings.TrimSpace(msg), `"`); err == nil && msg != "" {
+			return msg, false
+		}
+		slog.WarnContext(ctx, "gift: using template message", "err", err)
+	}
+	return templateGiftMessage(g, resp), true
+}
+
+func templateGiftMessage(g *GiftOptions, resp CompleteOutfitResp) string {
+	to := "you"
+	if g.Recipient != "" {
+		to = g.Recipient
+	}
+	occasion := "a little something"
+	if g.Occasion != "" {
+		occasion = "a " + g.Occasion + " treat"
+	}
+	return fmt.Sprintf("For %s: %s to complete your %s look. Enjoy wearing it!", to, occasion, humanizeMission(resp.Mission))
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/embeddings"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/outfit"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// GroupOutfitReq plans coordinated outfits for several people (e.g. a
+// wedding party) that share one color palette and one combined budget.
+type GroupOutfitReq struct {
+	Mission      string        `json:"mission"`
+	BudgetGBP    float64       `json:"budget_gbp"` // combined budget for everyone
+	MinEcoScore  int           `json:"min_eco_score"`
+	Palette      []string      `json:"palette,omitempty"`      // shared colors; derived from the catalog when empty
+	PaletteSize  int           `json:"palette_size,omitempty"` // colors to derive, default 3
+	LimitPerSlot int           `json:"limit_per_slot"`         // default 3
+	People       []GroupPerson `json:"people"`
+	OriginPrefs
+}
+
+type GroupPerson struct {
+	Name      string   `json:"name"`
+	CartSlots []string `json:"cart_slots"`
+	Size      string   `json:"size,omitempty"`
+	BudgetGBP float64  `json:"budget_gbp,omitempty"` // fixed share; the rest is split evenly
+}
+
+type GroupOutfitResp struct {
+	Mission          string         `json:"mission"`
+	BudgetGBP        float64        `json:"budget_gbp,omitempty"`
+	Palette          []string       `json:"palette"`
+	PaletteRationale string         `json:"palette_rationale"`
+	People           []PersonOutfit `json:"people"`
+	TopPicksGBP      float64        `json:"top_picks_gbp"` // cost of everyone's first pick per slot
+	Meta             *ResponseMeta  `json:"meta,omitempty"`
+}
+
+type PersonOutfit struct {
+	Name        string             `json:"name"`
+	BudgetGBP   float64            `json:"budget_gbp,omitempty"`
+	Outfit      CompleteOutfitResp `json:"outfit"`
+	TopPicksGBP float64            `json:"top_picks_gbp"`
+}
+
+const (
+	maxGroupSize       = 20
+	defaultPaletteSize = 3
+	// catalog items closest to the mission that vote on a derived palette
+	paletteSampleSize = 100
+)
+
+func (g *GroupOutfitReq) validate() error {
+	if len(g.People) == 0 {
+		return httpapi.InvalidField("people", "people is required")
+	}
+	if len(g.People) > maxGroupSize {
+		return httpapi.InvalidField("people", "at most %d people per group", maxGroupSize)
+	}
+	if g.PaletteSize < 0 || g.PaletteSize > 8 {
+		return httpapi.InvalidField("palette_size", "palette_size must be between 1 and 8")
+	}
+	var fixed float64
+	for i, p := range g.People {
+		if p.BudgetGBP < 0 {
+			return httpapi.InvalidField(fmt.Sprintf("people[%d].budget_gbp", i), "people[%d].budget_gbp must not be negative", i)
+		}
+		fixed += p.BudgetGBP
+	}
+	if fixed > 0 && g.BudgetGBP > 0 && fixed > g.BudgetGBP {
+		return httpapi.InvalidField("people", "per-person budgets (£%.2f) exceed budget_gbp (£%.2f)", fixed, g.BudgetGBP)
+	}
+	return g.OriginPrefs.validate()
+}
+
+// derivePalette picks the colors most common among the catalog items closest
+// to the mission, so every person can realistically be dressed from it.
+func derivePalette(ctx context.Context, pool *pgxpool.Pool, mission string, minEco, n int) ([]string, string, error) {
+	qEmb, err := embedQuery(ctx, mission+" outfit")
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := pool.Query(ctx, `
+SELECT c, count(*) AS n
+FROM (
+  SELECT unnest(colors) AS c
+  FROM (
+    SELECT colors FROM product_embeddings
+    WHERE embedding IS NOT NULL AND colors IS NOT NULL AND `+sandboxSQL(ctx, "")+`
+      AND ($3::int IS NULL OR eco_score >= $3)
+    ORDER BY embedding `+ranking.Operator(cfg().Embed.Metric)+` $1::vector
+    LIMIT $4
+  ) nearest
+) cs
+GROUP BY c
+ORDER BY n DESC, c
+LIMIT $2
+`, embeddings.Literal(qEmb), n, search.NullInt(minEco), paletteSampleSize)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var palette, counts []string
+	for rows.Next() {
+		var c string
+		var cnt int
+		if err := rows.Scan(&c, &cnt); err != nil {
+			return nil, "", err
+		}
+		palette = append(palette, c)
+		counts = append(counts, fmt.Sprintf("%s %d", c, cnt))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	if len(palette) == 0 {
+		return nil, "No color data is indexed for items matching this mission, so outfits are not color-coordinated.", nil
+	}
+	rationale := fmt.Sprintf("Palette %s is the most common among the %d catalog items closest to %q (%s), so every person can be dressed from it.",
+		joinWords(palette), paletteSampleSize, humanizeMission(mission), strings.Join(counts, ", "))
+	return palette, rationale, nil
+}
+
+func runGroupOutfits(ctx context.Context, pool *pgxpool.Pool, req GroupOutfitReq) (GroupOutfitResp, error) {
+	palette := normalizeValues(req.Palette)
+	rationale := ""
+	if len(palette) > 0 {
+		rationale = fmt.Sprintf("Palette %s was requested; every pick is limited to these colors.", joinWords(palette))
+	} else {
+		n := req.PaletteSize
+		if n <= 0 {
+			n = defaultPaletteSize
+		}
+		var err error
+		palette, rationale, err = derivePalette(ctx, pool, req.Mission, req.MinEcoScore, n)
+		if err != nil {
+			return GroupOutfitResp{}, err
+		}
+	}
+
+	resp := GroupOutfitResp{
+		Mission:          req.Mission,
+		BudgetGBP:        req.BudgetGBP,
+		Palette:          palette,
+		PaletteRationale: rationale,
+		People:           make([]PersonOutfit, 0, len(req.People)),
+	}
+	if resp.Palette == nil {
+		resp.Palette = []string{}
+	}
+
+	fixed := make([]float64, len(req.People))
+	for i, p := range req.People {
+		fixed[i] = p.BudgetGBP
+	}
+	budgets := outfit.PersonBudgets(req.BudgetGBP, fixed)
+	for i, p := range req.People {
+		name := p.Name
+		if name == "" {
+			name = fmt.Sprintf("person %d", i+1)
+		}
+		slots := p.CartSlots
+		if slots == nil {
+			slots = []string{}
+		}
+
+		out, err := runCompleteOutfit(ctx, pool, CompleteOutfitReq{
+			Mission:      req.Mission,
+			BudgetGBP:    budgets[i],
+			MinEcoScore:  req.MinEcoScore,
+			CartSlots:    slots,
+			LimitPerSlot: req.LimitPerSlot,
+			AttrFilters:  AttrFilters{Size: p.Size},
+			OriginPrefs:  req.OriginPrefs,
+			palette:      palette,
+		})
+		if err != nil {
+			return GroupOutfitResp{}, err
+		}
+
+		po := PersonOutfit{Name: name, BudgetGBP: budgets[i], Outfit: out}
+		for _, sr := range out.Results {
+			if len(sr.Hits) > 0 {
+				po.TopPicksGBP += sr.Hits[0].PriceGBP
+			}
+		}
+		po.TopPicksGBP = math.Round(po.TopPicksGBP*100) / 100
+		resp.TopPicksGBP += po.TopPicksGBP
+		resp.People = append(resp.People, po)
+	}
+	resp.TopPicksGBP = math.Round(resp.TopPicksGBP*100) / 100
+	return resp, nil
+}
@@ -0,0 +1,244 @@
+package main
+
+//go:generate protoc --go_out=module=github.com/yourusername/contextual-shopping-agent/agent:. --go-grpc_out=module=github.com/yourusername/contextual-shopping-agent/agent:. proto/csa/v1/agent.proto
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/csapb"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// The gRPC server (CSA_GRPC_PORT) answers each call by running the HTTP
+// endpoint it mirrors in-process, through the same middleware: the request
+// message becomes the JSON body and the JSON response becomes the reply.
+// Auth, scopes, tenants, validation, caching, idempotency and request logs
+// are therefore exactly those of the HTTP API. Call metadata is passed on
+// as request headers (authorization, x-api-key, idempotency-key,
+// accept-language, ...), and response headers come back as header
+// metadata.
+
+// grpcAPI implements csapb.ShoppingAgentServer over the HTTP handler.
+type grpcAPI struct {
+	csapb.UnimplementedShoppingAgentServer
+	handler http.Handler
+}
+
+// newGRPCServer returns a server for the ShoppingAgent service, with
+// reflection so grpcurl and similar tools can list it.
+func newGRPCServer(handler http.Handler) *grpc.Server {
+	s := grpc.NewServer()
+	csapb.RegisterShoppingAgentServer(s, &grpcAPI{handler: handler})
+	reflection.Register(s)
+	return s
+}
+
+// serveGRPC serves s on addr until it is stopped.
+func serveGRPC(s *grpc.Server, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	slog.Info("grpc running", "addr", addr)
+	return s.Serve(lis)
+}
+
+// stopGRPC lets in-flight calls finish until ctx is done, then cuts them
+// off.
+func stopGRPC(ctx context.Context, s *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.Stop()
+	}
+}
+
+func (g *grpcAPI) Search(ctx context.Context, req *csapb.SearchRequest) (*csapb.SearchResponse, error) {
+	resp := &csapb.SearchResponse{}
+	return resp, g.call(ctx, "/search", nil, req, resp)
+}
+
+func (g *grpcAPI) CompleteOutfit(ctx context.Context, req *csapb.CompleteOutfitRequest) (*csapb.CompleteOutfitResponse, error) {
+	resp := &csapb.CompleteOutfitResponse{}
+	return resp, g.call(ctx, "/complete-outfit", nil, req, resp)
+}
+
+func (g *grpcAPI) ExplainOutfit(ctx context.Context, req *csapb.ExplainOutfitRequest) (*csapb.ExplainOutfitResponse, error) {
+	resp := &csapb.ExplainOutfitResponse{}
+	// the endpoint takes the outfit itself as the body
+	outfit := req.GetOutfit()
+	if outfit == nil {
+		outfit = &csapb.CompleteOutfitResponse{}
+	}
+	return resp, g.call(ctx, "/explain-outfit", nil, outfit, resp)
+}
+
+func (g *grpcAPI) IndexProducts(ctx context.Context, req *csapb.IndexProductsRequest) (*csapb.IndexProductsResponse, error) {
+	q := url.Values{}
+	if req.Mode != "" {
+		q.Set("mode", req.Mode)
+	}
+	if req.MaxTokens != 0 {
+		q.Set("max_tokens", strconv.Itoa(int(req.MaxTokens)))
+	}
+	if req.MaxCostUsd != 0 {
+		q.Set("max_cost_usd", strconv.FormatFloat(req.MaxCostUsd, 'f', -1, 64))
+	}
+	resp := &csapb.IndexProductsResponse{}
+	return resp, g.call(ctx, "/index-products", q, nil, resp)
+}
+
+// call POSTs body, as JSON, to path on the HTTP handler and decodes the
+// response into out. A nil body sends none.
+func (g *grpcAPI) call(ctx context.Context, path string, query url.Values, body, out proto.Message) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = grpcRequestJSON(body); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	target := path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(payload))
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, vs := range md {
+			// pseudo-headers and gRPC's own
+			if strings.HasPrefix(k, ":") || strings.HasPrefix(k, "grpc-") || k == "content-type" || k == "user-agent" {
+				continue
+			}
+			for _, v := range vs {
+				r.Header.Add(k, v)
+			}
+		}
+	}
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		r.RemoteAddr = p.Addr.String()
+	}
+
+	rec := httptest.NewRecorder()
+	g.handler.ServeHTTP(rec, r)
+
+	md := metadata.MD{}
+	for k, vs := range rec.Header() {
+		switch k {
+		case "Content-Type", "Content-Length", "X-Content-Type-Options", "Vary":
+			continue
+		}
+		md.Append(k, vs...)
+	}
+	grpc.SetHeader(ctx, md)
+
+	if rec.Code >= 300 {
+		return grpcError(rec.Code, rec.Body.Bytes())
+	}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(rec.Body.Bytes(), out); err != nil {
+		return status.Errorf(codes.Internal, "decoding %s response: %v", path, err)
+	}
+	return nil
+}
+
+// grpcRequestJSON encodes a request message as the endpoint's JSON body.
+// Fields keep their proto (snake_case) names, which are the JSON names, and
+// the Filters message is spread into the top level, where the HTTP API has
+// its fields.
+func grpcRequestJSON(m proto.Message) ([]byte, error) {
+	b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	if raw, ok := fields["filters"]; ok {
+		delete(fields, "filters")
+		var filters map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &filters); err != nil {
+			return nil, err
+		}
+		for k, v := range filters {
+			fields[k] = v
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// grpcError turns an error response into a gRPC status. Field errors travel
+// as a google.rpc.BadRequest detail.
+func grpcError(httpStatus int, body []byte) error {
+	var e httpapi.ErrorResp
+	if err := json.Unmarshal(body, &e); err != nil || e.Message == "" {
+		e.Message = strings.TrimSpace(string(body))
+	}
+	st := status.New(grpcCode(httpStatus), e.Message)
+	if len(e.FieldErrors) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, fe := range e.FieldErrors {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field: fe.Field, Description: fe.Message,
+			})
+		}
+		if withDetails, err := st.WithDetails(br); err == nil {
+			st = withDetails
+		}
+	}
+	return st.Err()
+}
+
+// grpcCode is the gRPC code for an HTTP error status.
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusUnprocessableEntity:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	return codes.Internal
+}
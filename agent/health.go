@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+)
+
+// DependencyStatus is one entry of the /healthz/ready report. The probe is
+// unauthenticated, so why a check failed is logged rather than reported.
+type DependencyStatus struct {
+	Status    string  `json:"status"` // ok | fail | skipped
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Cached    bool    `json:"cached,omitempty"`
+}
+
+type ReadinessResp struct {
+	Status string                      `json:"status"` // ok | degraded | fail
+	Checks map[string]DependencyStatus `json:"checks"`
+	// circuit breakers of external dependencies; see breaker.go
+	Breakers map[string]BreakerStatus `json:"breakers,omitempty"`
+}
+
+const (
+	healthCheckTimeout = 2 * time.Second
+	// external APIs are probed at most this often; probes hit billed or
+	// rate-limited services, and readiness is polled every few seconds
+	externalHealthTTL = time.Minute
+)
+
+type dependencyCheck struct {
+	name     string
+	critical bool
+	cacheFor time.Duration
+	run      func(ctx context.Context) error
+}
+
+var (
+	healthCacheMu sync.Mutex
+	healthCache   = map[string]cachedHealth{}
+)
+
+type cachedHealth struct {
+	status  DependencyStatus
+	expires time.Time
+}
+
+func readinessChecks(pool *pgxpool.Pool) []dependencyCheck {
+	checks := []dependencyCheck{
+		{name: "database", critical: true, run: func(ctx context.Context) error {
+			return pool.Ping(ctx)
+		}},
+		{name: "pgvector", critical: true, run: func(ctx context.Context) error {
+			var ext string
+			return pool.QueryRow(ctx, "SELECT extname FROM pg_extension WHERE extname='vector'").Scan(&ext)
+		}},
+		// OpenAI is critical unless embeddings run locally; chat has a template fallback
+		{name: depOpenAI, critical: cfg().Embed.Backend == config.EmbedOpenAI, cacheFor: externalHealthTTL, run: func(ctx context.Context) error {
+			return probeHTTP(ctx, cfg().OpenAI.BaseURL+"/models", "Bearer "+cfg().OpenAI.APIKey)
+		}},
+	}
+	if cfg().Azure.Configured() {
+		checks = append(checks, dependencyCheck{name: depAzureOpenAI, critical: cfg().Embed.Backend == config.EmbedAzureOpenAI, cacheFor: externalHealthTTL, run: func(ctx context.Context) error {
+			return probeAzure(ctx)
+		}})
+	}
+	// the catalog is only needed for indexing and inventory sync
+	switch cfg().CatalogProvider {
+	case config.CatalogMedusa:
+		checks = append(checks, dependencyCheck{name: depMedusa, cacheFor: externalHealthTTL, run: func(ctx context.Context) error {
+			return probeHTTP(ctx, cfg().Medusa.BaseURL+"/health", "")
+		}})
+	case config.CatalogShopify:
+		checks = append(checks, dependencyCheck{name: "shopify", cacheFor: externalHealthTTL, run: func(ctx context.Context) error {
+			if err := (shopifyCatalog{}).Configured(); err != nil {
+				return err
+			}
+			var data struct{}
+			return shopifyQuery(ctx, `{ shop { name } }`, nil, &data)
+		}})
+	}
+	if cfg().CacheWarm {
+		// keeps new replicas out of rotation until the first shoppers would hit warm caches
+		checks = append(checks, dependencyCheck{name: "cache_warm", critical: true, run: func(ctx context.Context) error {
+			if !cachesWarm.Load() {
+				return errors.New("warming caches")
+			}
+			return nil
+		}})
+	}
+	if redisCache != nil {
+		// without it searches are slower, not wrong
+		checks = append(checks, dependencyCheck{name: "redis", run: func(ctx context.Context) error {
+			return redisCache.Ping(ctx).Err()
+		}})
+	}
+	if imageEmbeddingsEnabled() {
+		checks = append(checks, dependencyCheck{name: "image_embed", cacheFor: externalHealthTTL,
+			run: func(ctx context.Context) error {
+				// any HTTP answer means the service is up; only transport errors fail
+				req, _ := http.NewRequestWithContext(ctx, http.MethodHead, cfg().ImageEmbed.URL, nil)
+				res, err := httpClient.Do(req)
+				if err != nil {
+					return err
+				}
+				res.Body.Close()
+				return nil
+			}})
+	}
+	return checks
+}
+
+func probeHTTP(ctx context.Context, url, auth string) error {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// probeAzure lists the Azure OpenAI resource's models, which needs a valid
+// key but no deployment.
+func probeAzure(ctx context.Context) error {
+	a := cfg().Azure
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, a.Endpoint+"/openai/models?api-version="+url.QueryEscape(a.APIVersion), nil)
+	req.Header.Set("api-key", a.APIKey)
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// readiness runs every check in parallel. Any critical failure makes the
+// service not ready; non-critical failures only degrade it.
+func readiness(ctx context.Context, pool *pgxpool.Pool) ReadinessResp {
+	checks := readinessChecks(pool)
+	resp := ReadinessResp{Status: "ok", Checks: make(map[string]DependencyStatus, len(checks)), Breakers: breakerStatuses()}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			st := runCheck(ctx, c)
+			mu.Lock()
+			resp.Checks[c.name] = st
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	for _, st := range resp.Checks {
+		if st.Status != "fail" {
+			continue
+		}
+		if st.Critical {
+			resp.Status = "fail"
+		} else if resp.Status == "ok" {
+			resp.Status = "degraded"
+		}
+	}
+	return resp
+}
+
+func runCheck(ctx context.Context, c dependencyCheck) DependencyStatus {
+	if c.cacheFor > 0 {
+		healthCacheMu.Lock()
+		hit, ok := healthCache[c.name]
+		healthCacheMu.Unlock()
+		if ok && time.Now().Before(hit.expires) {
+			hit.status.Cached = true
+			return hit.status
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	err := c.run(ctx)
+	st := DependencyStatus{
+		Status:    "ok",
+		Critical:  c.critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		st.Status = "fail"
+		slog.WarnContext(ctx, "health: check failed", "check", c.name, "critical", c.critical, "err", err)
+	}
+
+	if c.cacheFor > 0 {
+		healthCacheMu.Lock()
+		healthCache[c.name] = cachedHealth{status: st, expires: time.Now().Add(c.cacheFor)}
+		healthCacheMu.Unlock()
+	}
+	return st
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// Page sizes for /users/{id}/history.
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// historySources are the recordServed sources a history can be filtered by.
+var historySources = []string{"search", "complete-outfit", "group-outfits", "substitutes"}
+
+// HistoryEntry is one set of products shown to a user, read from the
+// recommendation events of their sessions.
+type HistoryEntry struct {
+	ID         int64            `json:"id"`
+	At         time.Time        `json:"at"`
+	SessionID  string           `json:"session_id"`
+	Source     string           `json:"source"`
+	Query      string           `json:"query,omitempty"`
+	Mission    string           `json:"mission,omitempty"`
+	Slot       string           `json:"slot,omitempty"`
+	ProductID  string           `json:"product_id,omitempty"` // substitutes: the product they replace
+	ProductIDs []string         `json:"product_ids"`          // in the order shown
+	Products   []HistoryProduct `json:"products"`             // those still indexed
+}
+
+type HistoryProduct struct {
+	ProductID string  `json:"product_id"`
+	Title     string  `json:"title"`
+	Thumbnail string  `json:"thumbnail"`
+	PriceGBP  float64 `json:"price_gbp"`
+}
+
+type HistoryPage struct {
+	UserID     string         `json:"user_id"`
+	Entries    []HistoryEntry `json:"entries"`
+	NextCursor string         `json:"next_cursor,omitempty"` // pass as ?cursor= for older entries
+}
+
+type historyFilter struct {
+	Source    string
+	ProductID string
+	Query     string // substring of the query or mission
+	Since     *time.Time
+	Until     *time.Time
+	Before    int64 // cursor: entries with a smaller ID
+	Limit     int
+}
+
+// parseHistoryFilter reads ?source, product_id, q, since, until (RFC 3339),
+// cursor and limit.
+func parseHistoryFilter(q url.Values) (historyFilter, error) {
+	f := historyFilter{Source: q.Get("source"), ProductID: q.Get("product_id"), Query: strings.TrimSpace(q.Get("q")), Limit: defaultHistoryLimit}
+	if f.Source != "" && !slices.Contains(historySources, f.Source) {
+		return f, fmt.Errorf("source must be one of %v", historySources)
+	}
+	for _, t := range []struct {
+		name string
+		dst  **time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if v := q.Get(t.name); v != "" {
+			ts, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Errorf("%s must be an RFC 3339 time", t.name)
+			}
+			*t.dst = &ts
+		}
+	}
+	if v := q.Get("cursor"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			return f, fmt.Errorf("invalid cursor")
+		}
+		f.Before = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxHistoryLimit {
+			return f, fmt.Errorf("limit must be 1-%d", maxHistoryLimit)
+		}
+		f.Limit = n
+	}
+	return f, nil
+}
+
+// userHistory lists what was recommended to userID across the sessions the
+// storefront tagged with X-User-ID, newest first.
+func userHistory(ctx context.Context, pool *pgxpool.Pool, tenantID, userID string, f historyFilter) (HistoryPage, error) {
+	page := HistoryPage{UserID: userID, Entries: []HistoryEntry{}}
+	var before any
+	if f.Before > 0 {
+		before = f.Before
+	}
+	rows, err := pool.Query(ctx, `
+SELECT e.id, e.created_at, e.session_id, e.payload
+FROM session_events e
+JOIN sessions s ON s.tenant_id = e.tenant_id AND s.id = e.session_id
+WHERE s.tenant_id = $1 AND s.user_id = $2 AND e.kind = 'recommendation'
+  AND ($3::text IS NULL OR e.payload->>'source' = $3)
+  AND ($4::text IS NULL OR e.payload->'product_ids' ? $4)
+  AND ($5::text IS NULL OR e.payload->>'query' ILIKE $5 OR e.payload->>'mission' ILIKE $5)
+  AND ($6::timestamptz IS NULL OR e.created_at >= $6)
+  AND ($7::timestamptz IS NULL OR e.created_at < $7)
+  AND ($8::bigint IS NULL OR e.id < $8)
+ORDER BY e.id DESC
+LIMIT $9
+`, tenantID, userID, search.NullText(f.Source), search.NullText(f.ProductID), search.NullContains(f.Query), f.Since, f.Until, before, f.Limit+1)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var e HistoryEntry
+		var payload []byte
+		if err := rows.Scan(&e.ID, &e.At, &e.SessionID, &payload); err != nil {
+			return page, err
+		}
+		var p struct {
+			Source     string   `json:"source"`
+			Query      string   `json:"query"`
+			Mission    string   `json:"mission"`
+			Slot       string   `json:"slot"`
+			ProductID  string   `json:"product_id"`
+			ProductIDs []string `json:"product_ids"`
+		}
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return page, fmt.Errorf("history: event %d: %w", e.ID, err)
+		}
+		e.Source, e.Query, e.Mission, e.Slot, e.ProductID, e.ProductIDs = p.Source, p.Query, p.Mission, p.Slot, p.ProductID, p.ProductIDs
+		page.Entries = append(page.Entries, e)
+		ids = append(ids, p.ProductIDs...)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+	if len(page.Entries) > f.Limit {
+		page.Entries = page.Entries[:f.Limit]
+		page.NextCursor = strconv.FormatInt(page.Entries[f.Limit-1].ID, 10)
+	}
+
+	products, err := historyProducts(ctx, pool, ids)
+	if err != nil {
+		return page, err
+	}
+	for i := range page.Entries {
+		e := &page.Entries[i]
+		e.Products = []HistoryProduct{}
+		for _, id := range e.ProductIDs {
+			if p, ok := products[id]; ok {
+				e.Products = append(e.Products, p)
+			}
+		}
+	}
+	return page, nil
+}
+
+func historyProducts(ctx context.Context, pool *pgxpool.Pool, ids []string) (map[string]HistoryProduct, error) {
+	out := map[string]HistoryProduct{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	rows, err := pool.Query(ctx, `
+SELECT product_id, COALESCE(title, ''), COALESCE(thumbnail, ''), COALESCE(price_gbp, 0)::float8
+FROM product_embeddings WHERE product_id = ANY($1) AND `+sandboxSQL(ctx, ""), slices.Compact(slices.Sorted(slices.Values(ids))))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p HistoryProduct
+		if err := rows.Scan(&p.ProductID, &p.Title, &p.Thumbnail, &p.PriceGBP); err != nil {
+			return nil, err
+		}
+		out[p.ProductID] = p
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// Mutating endpoints accept an Idempotency-Key header, so a frontend that
+// retries or double-submits doesn't run the work twice. The first request
+// with a key claims it; once it finishes, its response is stored and
+// replayed to every retry with the same key until CSA_IDEMPOTENCY_TTL
+// passes. Keys are per tenant.
+
+// maxIdempotencyKey bounds the header; clients typically send a UUID.
+const maxIdempotencyKey = 255
+
+// idempotencyInFlight is how long a claimed key may go without a response
+// before it counts as abandoned (its replica died) and a retry may take it
+// over. Full reindexes of large catalogs are the slowest requests.
+const idempotencyInFlight = 30 * time.Minute
+
+// maxIdempotentResponse bounds what is stored; larger responses are sent
+// but not kept, and the key is released.
+const maxIdempotentResponse = 1 << 20
+
+// idempotencySweepInterval is how often expired keys are deleted.
+const idempotencySweepInterval = time.Hour
+
+type storedResponse struct {
+	status      int
+	contentType string
+	location    string
+	body        []byte
+}
+
+// idempotent makes h safe to retry with an Idempotency-Key. Requests
+// without the header run as before. A key reused with a different request
+// gets 422, and one whose first request is still running gets 409.
+// Responses that may differ on a retry (5xx, 409, 429) are not stored.
+func idempotent(pool *pgxpool.Pool, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			h(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			httpapi.WriteError(w, "Idempotency-Key must be at most 255 characters", 400)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportUpload))
+		if err != nil {
+			httpapi.WriteError(w, "reading body: "+err.Error(), 400)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx, tenantID := r.Context(), tenantFromRequest(r)
+		sum := sha256.New()
+		io.WriteString(sum, r.Method+" "+r.URL.RequestURI()+"\n")
+		sum.Write(body)
+		hash := hex.EncodeToString(sum.Sum(nil))
+
+		stored, claimed, err := claimIdempotencyKey(ctx, pool, tenantID, key, hash)
+		switch {
+		case errors.Is(err, errIdempotencyMismatch):
+			httpapi.WriteError(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case errors.Is(err, errIdempotencyInFlight):
+			w.Header().Set("Retry-After", "5")
+			httpapi.WriteError(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		case !claimed:
+			logOutcome(ctx, slog.Bool("idempotent_replay", true))
+			if stored.contentType != "" {
+				w.Header().Set("Content-Type", stored.contentType)
+			}
+			if stored.location != "" {
+				w.Header().Set("Location", stored.location)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.status)
+			w.Write(stored.body)
+			return
+		}
+
+		rec := &responseCapture{ResponseWriter: w}
+		// a panic or a client gone mid-request must not leave the key claimed
+		completed := false
+		defer func() {
+			if !completed {
+				releaseIdempotencyKey(context.WithoutCancel(ctx), pool, tenantID, key)
+			}
+		}()
+		h(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		switch {
+		case rec.status >= 500, rec.status == http.StatusConflict, rec.status == http.StatusTooManyRequests:
+			return
+		case rec.overflow:
+			slog.WarnContext(ctx, "idempotency: response too large to store; key released", "limit", maxIdempotentResponse)
+			return
+		}
+		resp := storedResponse{status: rec.status, contentType: w.Header().Get("Content-Type"),
+			location: w.Header().Get("Location"), body: rec.body.Bytes()}
+		if err := storeIdempotentResponse(context.WithoutCancel(ctx), pool, tenantID, key, resp); err != nil {
+			slog.WarnContext(ctx, "idempotency: storing response failed; key released", "err", err)
+			return
+		}
+		completed = true
+	}
+}
+
+var (
+	errIdempotencyMismatch = errors.New("Idempotency-Key was already used with a different request")
+	errIdempotencyInFlight = errors.New("a request with this Idempotency-Key is still running")
+)
+
+// claimIdempotencyKey claims key for a request hashing to hash. claimed is
+// false when the key already has a stored response, which is returned.
+func claimIdempotencyKey(ctx context.Context, pool *pgxpool.Pool, tenantID, key, hash string) (storedResponse, bool, error) {
+	var resp storedResponse
+	// the session is kept so erasing its shopper's data takes the response too
+	var sessionID any
+	if s := sessionFrom(ctx); s != nil {
+		sessionID = s.ID
+	}
+	tag, err := pool.Exec(ctx, `
+INSERT INTO idempotency_keys (tenant_id, key, request_hash, session_id) VALUES ($1, $2, $3, $6)
+ON CONFLICT (tenant_id, key) DO UPDATE
+  SET request_hash = EXCLUDED.request_hash, session_id = EXCLUDED.session_id, created_at = now(),
+      status = NULL, content_type = NULL, location = NULL, body = NULL, completed_at = NULL
+WHERE idempotency_keys.completed_at < now() - make_interval(secs => $4)
+   OR (idempotency_keys.status IS NULL AND idempotency_keys.created_at < now() - make_interval(secs => $5))
+`, tenantID, key, hash, cfg().IdempotencyTTL.Seconds(), idempotencyInFlight.Seconds(), sessionID)
+	if err != nil {
+		return resp, false, err
+	}
+	if tag.RowsAffected() == 1 {
+		return resp, true, nil // new, expired or abandoned
+	}
+
+	var storedHash string
+	var status *int
+	var contentType, location *string
+	err = pool.QueryRow(ctx, `
+SELECT request_hash, status, content_type, location, body FROM idempotency_keys WHERE tenant_id = $1 AND key = $2
+`, tenantID, key).Scan(&storedHash, &status, &contentType, &location, &resp.body)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// released between the two statements; the client can retry
+		return resp, false, errIdempotencyInFlight
+	}
+	if err != nil {
+		return resp, false, err
+	}
+	if storedHash != hash {
+		return resp, false, errIdempotencyMismatch
+	}
+	if status == nil {
+		return resp, false, errIdempotencyInFlight
+	}
+	resp.status = *status
+	if contentType != nil {
+		resp.contentType = *contentType
+	}
+	if location != nil {
+		resp.location = *location
+	}
+	return resp, false, nil
+}
+
+func storeIdempotentResponse(ctx context.Context, pool *pgxpool.Pool, tenantID, key string, resp storedResponse) error {
+	_, err := pool.Exec(ctx, `
+UPDATE idempotency_keys SET status = $3, content_type = $4, location = $5, body = $6, completed_at = now()
+WHERE tenant_id = $1 AND key = $2
+`, tenantID, key, resp.status, search.NullText(resp.contentType), search.NullText(resp.location), resp.body)
+	return err
+}
+
+// releaseIdempotencyKey forgets a claim, so a retry runs the request again.
+func releaseIdempotencyKey(ctx context.Context, pool *pgxpool.Pool, tenantID, key string) {
+	if _, err := pool.Exec(ctx, `DELETE FROM idempotency_keys WHERE tenant_id = $1 AND key = $2 AND status IS NULL`, tenantID, key); err != nil {
+		slog.WarnContext(ctx, "idempotency: releasing key failed", "err", err)
+	}
+}
+
+// idempotencySweepLoop deletes expired keys every hour until ctx is
+// cancelled. Expired keys are already ignored; this keeps the table small.
+func idempotencySweepLoop(ctx context.Context, pool *pgxpool.Pool) {
+	t := time.NewTicker(idempotencySweepInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		slot := time.Now().Truncate(idempotencySweepInterval)
+		if _, err := runScheduled(ctx, pool, "idempotency_sweep", slot, func(ctx context.Context) error {
+			_, err := pool.Exec(ctx, `
+DELETE FROM idempotency_keys
+WHERE completed_at < now() - make_interval(secs => $1)
+   OR (status IS NULL AND created_at < now() - make_interval(secs => $2))
+`, cfg().IdempotencyTTL.Seconds(), idempotencyInFlight.Seconds())
+			return err
+		}); err != nil {
+			slog.ErrorContext(ctx, "idempotency: sweep", "err", err)
+		}
+	}
+}
+
+// responseCapture passes a response through while keeping a copy of it.
+type responseCapture struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (rc *responseCapture) WriteHeader(code int) {
+	if rc.status == 0 {
+		rc.status = code
+	}
+	rc.ResponseWriter.WriteHeader(code)
+}
+
+func (rc *responseCapture) Write(b []byte) (int, error) {
+	if rc.status == 0 {
+		rc.status = http.StatusOK
+	}
+	if !rc.overflow {
+		if rc.body.Len()+len(b) > maxIdempotentResponse {
+			rc.overflow = true
+			rc.body.Reset()
+		} else {
+			rc.body.Write(b)
+		}
+	}
+	return rc.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rc *responseCapture) Unwrap() http.ResponseWriter {
+	return rc.ResponseWriter
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/embeddings"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// Max accepted upload for /search-by-image.
+const maxImageUpload = 8 << 20
+
+type ImageSearchReq struct {
+	ImageURL string `json:"image_url"`
+	Limit    int    `json:"limit"`
+	Category string `json:"category"`
+}
+
+// imageEmbeddingsEnabled reports whether an image embedding service is configured.
+func imageEmbeddingsEnabled() bool {
+	return cfg().ImageEmbed.URL != ""
+}
+
+// imageEmbed calls a CLIP-style embedding service. The service takes either
+// {"image_url": "..."} or {"image_b64": "..."} and returns {"embedding": [...]}.
+func imageEmbed(ctx context.Context, imageURL string, image []byte) ([]float64, error) {
+	endpoint := cfg().ImageEmbed.URL
+	if endpoint == "" {
+		return nil, fmt.Errorf("CSA_IMAGE_EMBED_URL not set")
+	}
+
+	body := map[string]any{}
+	if len(image) > 0 {
+		body["image_b64"] = base64.StdEncoding.EncodeToString(image)
+	} else {
+		body["image_url"] = imageURL
+	}
+	b, _ := json.Marshal(body)
+
+	req, _ := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	if key := cfg().ImageEmbed.APIKey; key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		raw, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("image embed error: %s", string(raw))
+	}
+
+	var parsed struct {
+		Embedding []float64 `json:"embedding"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+	if len(parsed.Embedding) == 0 {
+		return nil, fmt.Errorf("no image embedding returned")
+	}
+	if dim := cfg().ImageEmbed.Dim; len(parsed.Embedding) != dim {
+		return nil, fmt.Errorf("image embedding has %d dims, expected %d", len(parsed.Embedding), dim)
+	}
+	return parsed.Embedding, nil
+}
+
+func searchByImage(ctx context.Context, pool *pgxpool.Pool, emb []float64, limit int, category string) ([]Hit, error) {
+	rows, err := pool.Query(ctx, `
+SELECT product_id, title, thumbnail, eco_score, price_gbp,
+       (image_embedding <=> $1::vector) AS distance
+FROM product_embeddings
+WHERE image_embedding IS NOT NULL
+  AND `+sandboxSQL(ctx, "")+`
+  AND `+lifecycleSQL("", false)+`
+  AND ($3::text IS NULL OR category = $3)
+ORDER BY image_embedding <=> $1::vector
+LIMIT $2
+`, embeddings.Literal(emb), limit, search.NullText(category))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []Hit{}
+	for rows.Next() {
+		var h Hit
+		if err := rows.Scan(&h.ProductID, &h.Title, &h.Thumbnail, &h.EcoScore, &h.PriceGBP, &h.Distance); err != nil {
+			return nil, err
+		}
+		// cosine distance is in [0,2]; map to 0-100
+		h.Similarity = math.Max(0, 1-h.Distance) * 100
+		h.Distance = math.Round(h.Distance*100) / 100
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
+
+// parseImageSearch accepts either a JSON body with image_url or a multipart
+// upload with an "image" file plus optional limit/category form fields.
+func parseImageSearch(w http.ResponseWriter, r *http.Request) (ImageSearchReq, []byte, error) {
+	var req ImageSearchReq
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := decodeJSON(w, r, &req); err != nil {
+			return req, nil, err
+		}
+		if req.ImageURL == "" {
+			return req, nil, errors.New("image_url is required (or upload an image as multipart field \"image\")")
+		}
+		return req, nil, nil
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageUpload)
+	if err := r.ParseMultipartForm(maxImageUpload); err != nil {
+		return req, nil, err
+	}
+	req.ImageURL = r.FormValue("image_url")
+	req.Category = r.FormValue("category")
+	req.Limit, _ = strconv.Atoi(r.FormValue("limit"))
+
+	f, _, err := r.FormFile("image")
+	if err != nil {
+		if req.ImageURL != "" {
+			return req, nil, nil
+		}
+		return req, nil, errors.New("multipart field \"image\" is required")
+	}
+	defer f.Close()
+
+	img, err := io.ReadAll(f)
+	if err != nil {
+		return req, nil, err
+	}
+	return req, img, nil
+}
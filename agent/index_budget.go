@@ -0,0 +1,210 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// An index run can be given a budget of embedding tokens (?max_tokens= or
+// ?max_cost_usd= on /index-products, else CSA_INDEX_TOKEN_BUDGET). Products
+// are then embedded most important first: ones never indexed, then ones
+// whose card text changed, then the rest, each by how often they were
+// served in the last 30 days. The run stops before the first product that
+// would overrun the budget and reports the ones it left.
+//
+// Tokens are estimated from the card text before embedding, so the run
+// never spends what it hasn't checked; the estimate errs high.
+
+// maxRemainingIDs caps IndexBudget.RemainingIDs.
+const maxRemainingIDs = 100
+
+// popularityWindow is how far back served counts rank products of a tier.
+const popularityWindow = "30 days"
+
+// IndexBudget reports how an index run spent its token budget.
+type IndexBudget struct {
+	MaxTokens int     `json:"max_tokens"`
+	Tokens    int     `json:"tokens"`   // estimated tokens embedded
+	CostUSD   float64 `json:"cost_usd"` // Tokens at CSA_EMBED_COST_PER_MTOK
+	// Remaining products were fetched but not indexed; a later run picks
+	// them up first. RemainingIDs lists up to 100, most important first.
+	Remaining    int      `json:"remaining"`
+	RemainingIDs []string `json:"remaining_ids,omitempty"`
+}
+
+// Priority tiers of a budgeted run, most important first.
+const (
+	tierNew = iota
+	tierChanged
+	tierUnchanged
+)
+
+// estimateTokens over-estimates the tokens text embeds as: BPE tokenizers
+// average about four bytes per token on English product text.
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// embedTokens is the estimate for all the cards of p, translations
+// included.
+func (p productRow) embedTokens() int {
+	n := estimateTokens(p.card)
+	for _, c := range p.localCards {
+		if c.card != "" {
+			n += estimateTokens(c.card)
+		}
+	}
+	return n
+}
+
+// cardHash identifies the text embedded for p, so a later run can tell
+// whether it changed.
+func (p productRow) cardHash() string {
+	h := sha256.New()
+	io.WriteString(h, p.card)
+	for _, c := range p.localCards {
+		fmt.Fprintf(h, "\x00%s\x00%s", c.lang, c.card)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// tokensForCost converts a USD budget to tokens at CSA_EMBED_COST_PER_MTOK.
+func tokensForCost(usd float64) int {
+	return int(math.Floor(usd / cfg().EmbedCostPerMTok * 1e6))
+}
+
+// tokenCost prices tokens in USD, to the micro-dollar.
+func tokenCost(tokens int) float64 {
+	return math.Round(float64(tokens)*cfg().EmbedCostPerMTok) / 1e6
+}
+
+// indexBudgetFromQuery reads an index run's budget in tokens from
+// max_tokens or max_cost_usd, defaulting to CSA_INDEX_TOKEN_BUDGET. 0 is no
+// budget.
+func indexBudgetFromQuery(q url.Values) (int, error) {
+	tokens, cost := q.Get("max_tokens"), q.Get("max_cost_usd")
+	switch {
+	case tokens != "" && cost != "":
+		return 0, httpapi.InvalidField("max_tokens", "send max_tokens or max_cost_usd, not both")
+	case tokens != "":
+		n, err := strconv.Atoi(tokens)
+		if err != nil || n <= 0 {
+			return 0, httpapi.InvalidField("max_tokens", "max_tokens must be a positive whole number")
+		}
+		return n, nil
+	case cost != "":
+		f, err := strconv.ParseFloat(cost, 64)
+		if err != nil || f <= 0 || math.IsInf(f, 0) {
+			return 0, httpapi.InvalidField("max_cost_usd", "max_cost_usd must be a positive number")
+		}
+		return max(tokensForCost(f), 1), nil
+	}
+	return cfg().IndexTokenBudget, nil
+}
+
+// applyIndexBudget orders rows by priority and keeps those that fit in
+// maxTokens. It returns rows unchanged and a nil report when maxTokens is 0.
+func applyIndexBudget(ctx context.Context, pool *pgxpool.Pool, rows []productRow, maxTokens int) ([]productRow, *IndexBudget, error) {
+	if maxTokens <= 0 {
+		return rows, nil, nil
+	}
+	ids := make([]string, len(rows))
+	for i := range rows {
+		ids[i] = rows[i].ProductID
+	}
+	hashes, served, err := indexPriorities(ctx, pool, ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("index budget: %w", err)
+	}
+	type ranked struct {
+		row          productRow
+		tier, served int
+	}
+	order := make([]ranked, len(rows))
+	for i, r := range rows {
+		tier := tierUnchanged
+		if hash, ok := hashes[r.ProductID]; !ok {
+			tier = tierNew
+		} else if hash != r.cardHash() {
+			tier = tierChanged
+		}
+		order[i] = ranked{r, tier, served[r.ProductID]}
+	}
+	slices.SortStableFunc(order, func(a, b ranked) int {
+		return cmp.Or(cmp.Compare(a.tier, b.tier), cmp.Compare(b.served, a.served))
+	})
+
+	budget := &IndexBudget{MaxTokens: maxTokens}
+	keep := make([]productRow, 0, len(order))
+	for i, o := range order {
+		n := o.row.embedTokens()
+		if budget.Tokens+n > maxTokens {
+			budget.Remaining = len(order) - i
+			for _, left := range order[i:min(i+maxRemainingIDs, len(order))] {
+				budget.RemainingIDs = append(budget.RemainingIDs, left.row.ProductID)
+			}
+			break
+		}
+		budget.Tokens += n
+		keep = append(keep, o.row)
+	}
+	budget.CostUSD = tokenCost(budget.Tokens)
+	return keep, budget, nil
+}
+
+// indexPriorities returns the stored card hash of each of ids already
+// indexed ("" when indexed before hashes were kept) and how often each was
+// served recently.
+func indexPriorities(ctx context.Context, pool *pgxpool.Pool, ids []string) (map[string]string, map[string]int, error) {
+	hashes := make(map[string]string, len(ids))
+	rows, err := pool.Query(ctx, `
+SELECT product_id, COALESCE(card_hash, '') FROM product_embeddings WHERE product_id = ANY($1)
+`, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var id, hash string
+		if err := rows.Scan(&id, &hash); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		hashes[id] = hash
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	served := make(map[string]int)
+	rows, err = pool.Query(ctx, `
+SELECT product_id, count(*) FROM recommendation_events
+WHERE served_at >= now() - interval '`+popularityWindow+`' AND product_id = ANY($1)
+GROUP BY product_id
+`, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, nil, err
+		}
+		served[id] = n
+	}
+	return hashes, served, rows.Err()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IndexStats summarizes the indexed catalog so catalog managers can spot gaps.
+type IndexStats struct {
+	Total         int            `json:"total"`
+	ByCategory    map[string]int `json:"by_category"`     // "" = uncategorized
+	ByEcoBand     map[string]int `json:"by_eco_band"`     // see ecoBandSQL
+	Missing       MissingCounts  `json:"missing"`         // rows lacking a field
+	LastIndexedAt *time.Time     `json:"last_indexed_at"` // nil before the first index run
+}
+
+type MissingCounts struct {
+	Embedding      int `json:"embedding"`
+	ImageEmbedding int `json:"image_embedding"`
+	Thumbnail      int `json:"thumbnail"`
+	Price          int `json:"price"` // NULL or 0, which indexing stores when Medusa has no price
+}
+
+// ecoBandSQL buckets eco_score (0-100); 0 means the product has no score.
+const ecoBandSQL = `
+CASE
+  WHEN COALESCE(eco_score, 0) <= 0 THEN 'unscored'
+  WHEN eco_score < 40 THEN '1-39'
+  WHEN eco_score < 60 THEN '40-59'
+  WHEN eco_score < 80 THEN '60-79'
+  ELSE '80-100'
+END`
+
+func indexStats(ctx context.Context, pool *pgxpool.Pool, tenantID string) (IndexStats, error) {
+	st := IndexStats{ByCategory: map[string]int{}, ByEcoBand: map[string]int{}}
+
+	// rows indexed before tenant tracking belong to the default tenant
+	err := pool.QueryRow(ctx, `
+SELECT count(*),
+       count(*) FILTER (WHERE embedding IS NULL),
+       count(*) FILTER (WHERE image_embedding IS NULL),
+       count(*) FILTER (WHERE COALESCE(thumbnail, '') = ''),
+       count(*) FILTER (WHERE COALESCE(price_gbp, 0) = 0),
+       max(indexed_at)
+FROM product_embeddings
+WHERE COALESCE(tenant_id, $2) = $1
+`, tenantID, defaultTenant).Scan(&st.Total, &st.Missing.Embedding, &st.Missing.ImageEmbedding,
+		&st.Missing.Thumbnail, &st.Missing.Price, &st.LastIndexedAt)
+	if err != nil {
+		return st, err
+	}
+
+	rows, err := pool.Query(ctx, `
+SELECT 'category', COALESCE(category, ''), count(*)
+FROM product_embeddings WHERE COALESCE(tenant_id, $2) = $1
+GROUP BY 2
+UNION ALL
+SELECT 'eco', `+ecoBandSQL+`, count(*)
+FROM product_embeddings WHERE COALESCE(tenant_id, $2) = $1
+GROUP BY 2
+`, tenantID, defaultTenant)
+	if err != nil {
+		return st, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind, key string
+		var n int
+		if err := rows.Scan(&kind, &key, &n); err != nil {
+			return st, err
+		}
+		if kind == "category" {
+			st.ByCategory[key] = n
+		} else {
+			st.ByEcoBand[key] = n
+		}
+	}
+	return st, rows.Err()
+}
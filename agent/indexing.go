@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/embeddings"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/events"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// productRow is one product ready to be written to product_embeddings.
+type productRow struct {
+	ProductID    string
+	Category     string
+	Title        string
+	Thumbnail    string
+	EcoScore     int
+	EcoSource    string // ecoSourceMerchant | ecoSourceEstimated | "" (no score)
+	PriceGBP     float64
+	StockQty     *int
+	StockSummary []VariantStock
+	Attrs        productAttrs
+	Origin       string
+	Lifecycle    string     // lifecycleActive, lifecyclePreorder, ...
+	ShipsAt      *time.Time // preorders: expected ship date
+	TenantID     string
+	Sandbox      bool // part of the seeded sandbox catalog
+
+	card       string // text that gets embedded
+	embedding  string // vector literal
+	imageEmb   any    // vector literal or nil
+	localCards []localCard
+}
+
+const upsertProductSQL = `
+INSERT INTO product_embeddings (product_id, category, title, thumbnail, embedding, eco_score, price_gbp, image_embedding,
+                                stock_qty, variant_availability, stock_synced_at, sizes, colors, brand, material, eco_labels, origin_country, gift_wrap, final_sale, tenant_id, attributes, eco_score_source, sandbox, lifecycle, ships_at, card_hash, residency_zone, indexed_at)
+VALUES ($1,$2,$3,$4,$5::vector,$6,$7,$8::vector,$9,$10,now(),$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26,now())
+ON CONFLICT (product_id) DO UPDATE
+SET category=EXCLUDED.category,
+    title=EXCLUDED.title,
+    thumbnail=EXCLUDED.thumbnail,
+    embedding=EXCLUDED.embedding,
+    eco_score=EXCLUDED.eco_score,
+    price_gbp=EXCLUDED.price_gbp,
+    image_embedding=COALESCE(EXCLUDED.image_embedding, product_embeddings.image_embedding),
+    stock_qty=EXCLUDED.stock_qty,
+    variant_availability=EXCLUDED.variant_availability,
+    stock_synced_at=EXCLUDED.stock_synced_at,
+    sizes=EXCLUDED.sizes,
+    colors=EXCLUDED.colors,
+    brand=EXCLUDED.brand,
+    material=EXCLUDED.material,
+    eco_labels=EXCLUDED.eco_labels,
+    origin_country=EXCLUDED.origin_country,
+    gift_wrap=EXCLUDED.gift_wrap,
+    final_sale=EXCLUDED.final_sale,
+    tenant_id=EXCLUDED.tenant_id,
+    attributes=EXCLUDED.attributes,
+    eco_score_source=EXCLUDED.eco_score_source,
+    sandbox=EXCLUDED.sandbox,
+    lifecycle=EXCLUDED.lifecycle,
+    ships_at=EXCLUDED.ships_at,
+    card_hash=EXCLUDED.card_hash,
+    residency_zone=EXCLUDED.residency_zone,
+    indexed_at=EXCLUDED.indexed_at
+`
+
+// indexProducts embeds and upserts rows in chunks of CSA_INDEX_BATCH_SIZE:
+// one embeddings call and one pipelined batch per chunk instead of a round
+// trip of each per product.
+func indexProducts(ctx context.Context, pool *pgxpool.Pool, rows []productRow) (int, error) {
+	indexed := 0
+	for start := 0; start < len(rows); start += cfg().IndexBatchSize {
+		chunk := rows[start:min(start+cfg().IndexBatchSize, len(rows))]
+
+		// translated cards are embedded in the same call, after the main ones
+		cards := make([]string, len(chunk))
+		type cardRef struct{ row, card int }
+		var translated []cardRef
+		for i := range chunk {
+			cards[i] = chunk[i].card
+			for j, c := range chunk[i].localCards {
+				if c.card != "" {
+					translated = append(translated, cardRef{i, j})
+				}
+			}
+		}
+		for _, t := range translated {
+			cards = append(cards, chunk[t.row].localCards[t.card].card)
+		}
+		embs, err := embedTexts(ctx, cards)
+		if err != nil {
+			return indexed, err
+		}
+		for k, t := range translated {
+			chunk[t.row].localCards[t.card].embedding = embeddings.Literal(embs[len(chunk)+k])
+		}
+		for i := range chunk {
+			chunk[i].embedding = embeddings.Literal(embs[i])
+			for j := range chunk[i].localCards {
+				if chunk[i].localCards[j].embedding == "" {
+					chunk[i].localCards[j].embedding = chunk[i].embedding
+				}
+			}
+
+			// image embedding is best effort; a broken thumbnail shouldn't fail the run
+			if imageEmbeddingsEnabled() && chunk[i].Thumbnail != "" {
+				imgEmb, err := imageEmbed(ctx, chunk[i].Thumbnail, nil)
+				if err != nil {
+					slog.WarnContext(ctx, "index: image embed failed", "product_id", chunk[i].ProductID, "err", err)
+				} else {
+					chunk[i].imageEmb = embeddings.Literal(imgEmb)
+				}
+			}
+		}
+
+		if err := upsertProducts(ctx, pool, chunk); err != nil {
+			return indexed, fmt.Errorf("db upsert: %w", err)
+		}
+		ids := make([]string, len(chunk))
+		for i := range chunk {
+			ids[i] = chunk[i].ProductID
+		}
+		// grouping is a search nicety; a failure leaves the products ungrouped
+		if err := markDuplicates(ctx, pool, ids); err != nil {
+			slog.WarnContext(ctx, "index: dedup failed", "err", err)
+		}
+		// dropped before returning, so the products are searchable at once
+		invalidateSearchCache(ctx)
+		bus.Publish(events.ProductIndexed, chunk[0].TenantID, events.ProductIndexedData{ProductIDs: ids})
+		indexed += len(chunk)
+	}
+	return indexed, nil
+}
+
+const upsertCardSQL = `
+INSERT INTO product_cards (product_id, lang, card_title, card_embedding) VALUES ($1, $2, $3, $4::vector)
+ON CONFLICT (product_id, lang) DO UPDATE
+SET card_title=EXCLUDED.card_title,
+    card_embedding=EXCLUDED.card_embedding
+`
+
+// upsertProducts writes rows in a single pipelined batch. COPY can't do
+// ON CONFLICT without a staging table, and a batch is already one round
+// trip, so it's the simpler of the two.
+func upsertProducts(ctx context.Context, pool *pgxpool.Pool, rows []productRow) error {
+	batch := &pgx.Batch{}
+	for _, p := range rows {
+		a := p.Attrs
+		batch.Queue(upsertProductSQL, p.ProductID, p.Category, p.Title, p.Thumbnail, p.embedding, p.EcoScore, p.PriceGBP,
+			p.imageEmb, p.StockQty, p.StockSummary, a.Sizes, a.Colors, search.NullText(a.Brand), search.NullText(a.Material), a.EcoLabels,
+			search.NullText(p.Origin), a.GiftWrap, a.FinalSale, p.TenantID, a.All, search.NullText(p.EcoSource), p.Sandbox,
+			p.Lifecycle, p.ShipsAt, p.cardHash(), residencyFrom(ctx))
+		langs := make([]string, len(p.localCards))
+		for i, c := range p.localCards {
+			langs[i] = c.lang
+			batch.Queue(upsertCardSQL, p.ProductID, c.lang, search.NullText(c.title), c.embedding)
+		}
+		// languages dropped from CSA_CARD_LANGUAGES
+		batch.Queue(`DELETE FROM product_cards WHERE product_id = $1 AND NOT (lang = ANY($2))`, p.ProductID, langs)
+	}
+	// the whole batch runs in one implicit transaction
+	return pool.SendBatch(ctx, batch).Close()
+}
@@ -0,0 +1,804 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/embeddings"
+	"github.com/yourusername/contextual-shopping-agent/agent/migrations"
+)
+
+// Integration tests run the real handlers and SQL against Postgres with
+// pgvector: the database in CSA_TEST_DATABASE_URL when set (the tests wipe
+// it), else a throwaway pgvector container started through Docker. The
+// schema comes from the embedded migrations, so a query that drifts from
+// them fails here. Embeddings, chat and Medusa are the fakes from
+// fakes_test.go. The tests are skipped with -short and when there is
+// neither a database URL nor a Docker daemon.
+
+// integrationImage is the container the tests start; keep its Postgres
+// major version in step with production.
+const integrationImage, integrationTag = "pgvector/pgvector", "pg16"
+
+// testAdminKey is CSA_ADMIN_API_KEY in integration tests.
+const testAdminKey = "csa-integration-test-admin-key"
+
+var integration struct {
+	once  sync.Once
+	url   string
+	purge func()
+	err   error
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if integration.purge != nil {
+		integration.purge()
+	}
+	os.Exit(code)
+}
+
+// startPostgres runs a pgvector container and waits until it accepts
+// connections.
+func startPostgres() (url string, purge func(), err error) {
+	dp, err := dockertest.NewPool("")
+	if err != nil {
+		return "", nil, err
+	}
+	if err := dp.Client.Ping(); err != nil {
+		return "", nil, fmt.Errorf("docker: %w", err)
+	}
+	res, err := dp.RunWithOptions(&dockertest.RunOptions{
+		Repository: integrationImage,
+		Tag:        integrationTag,
+		Env:        []string{"POSTGRES_PASSWORD=csa", "POSTGRES_DB=csa"},
+	}, func(hc *docker.HostConfig) {
+		hc.AutoRemove = true
+		hc.RestartPolicy = docker.RestartPolicy{Name: "no"}
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	purge = func() { dp.Purge(res) }
+	res.Expire(600) // a crashed test run must not leave it behind
+
+	url = fmt.Sprintf("postgres://postgres:csa@%s/csa?sslmode=disable", res.GetHostPort("5432/tcp"))
+	dp.MaxWait = time.Minute
+	if err := dp.Retry(func() error {
+		conn, err := pgx.Connect(context.Background(), url)
+		if err != nil {
+			return err
+		}
+		defer conn.Close(context.Background())
+		return conn.Ping(context.Background())
+	}); err != nil {
+		purge()
+		return "", nil, err
+	}
+	return url, purge, nil
+}
+
+// prepareDatabase migrates the database at url and builds the indexes the
+// agent builds on start.
+func prepareDatabase(ctx context.Context, url string) error {
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	if _, err := migrate(ctx, pool); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	if err := ensureVectorIndex(ctx, pool); err != nil {
+		return err
+	}
+	return ensureCardIndexes(ctx, pool)
+}
+
+// integrationDB returns a pool on the migrated test database with every
+// table emptied, and the test config from useTestConfig(t, env). The
+// bootstrap admin key is testAdminKey.
+func integrationDB(t *testing.T, env map[string]string) (*pgxpool.Pool, *config.Config) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("integration test")
+	}
+	if env == nil {
+		env = map[string]string{}
+	}
+	env["CSA_ADMIN_API_KEY"] = testAdminKey
+	c := useTestConfig(t, env)
+
+	integration.once.Do(func() {
+		integration.url = os.Getenv("CSA_TEST_DATABASE_URL")
+		if integration.url == "" {
+			integration.url, integration.purge, integration.err = startPostgres()
+			if integration.err != nil {
+				integration.err = fmt.Errorf("no CSA_TEST_DATABASE_URL and no pgvector container: %w", integration.err)
+				return
+			}
+		}
+		integration.err = prepareDatabase(context.Background(), integration.url)
+	})
+	if integration.err != nil {
+		if integration.url == "" {
+			t.Skip(integration.err)
+		}
+		t.Fatal(integration.err)
+	}
+
+	pool, err := pgxpool.New(context.Background(), integration.url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	truncateAll(t, pool)
+	return pool, c
+}
+
+// truncateAll empties every table but goose's.
+func truncateAll(t *testing.T, pool *pgxpool.Pool) {
+	t.Helper()
+	_, err := pool.Exec(context.Background(), `
+DO $$
+DECLARE t text;
+BEGIN
+  FOR t IN SELECT tablename FROM pg_tables WHERE schemaname = 'public' AND tablename <> 'goose_db_version' LOOP
+    EXECUTE format('TRUNCATE %I CASCADE', t);
+  END LOOP;
+END $$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+var nonTenantChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// testAPI serves the agent's full HTTP handler on pool. Every call is made
+// as the bootstrap admin of a tenant named after the test, so in-process
+// caches keyed by tenant never carry over between tests.
+type testAPI struct {
+	t      *testing.T
+	h      http.Handler
+	pool   *pgxpool.Pool
+	tenant string
+}
+
+func newTestAPI(t *testing.T, pool *pgxpool.Pool) *testAPI {
+	return &testAPI{t: t, h: newHandler(pool), pool: pool, tenant: "it-" + strings.Trim(nonTenantChars.ReplaceAllString(strings.ToLower(t.Name()), "-"), "-")}
+}
+
+// call sends body as JSON (or as is, for a string) with the extra headers
+// given as name, value pairs, and decodes the answer into out unless it is
+// nil. It returns the status.
+func (a *testAPI) call(method, path string, body, out any, header ...string) int {
+	a.t.Helper()
+	var rd *bytes.Reader
+	switch b := body.(type) {
+	case nil:
+		rd = bytes.NewReader(nil)
+	case string:
+		rd = bytes.NewReader([]byte(b))
+	default:
+		j, err := json.Marshal(b)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		rd = bytes.NewReader(j)
+	}
+	r := httptest.NewRequest(method, path, rd)
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-API-Key", testAdminKey)
+	r.Header.Set("X-Tenant-ID", a.tenant)
+	for i := 0; i+1 < len(header); i += 2 {
+		r.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	a.h.ServeHTTP(w, r)
+	// session events are written in the background; save them now so the
+	// next call sees them
+	if err := sessionLog.flush(context.Background(), a.pool); err != nil {
+		a.t.Fatal(err)
+	}
+	if out != nil && w.Code < 300 {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			a.t.Fatalf("%s %s: decoding %q: %v", method, path, w.Body.String(), err)
+		}
+	}
+	if w.Code >= 500 {
+		a.t.Logf("%s %s: %d %s", method, path, w.Code, w.Body.String())
+	}
+	return w.Code
+}
+
+// catalogProduct is a published Medusa product in slot with one in-stock
+// size M variant at price GBP.
+func catalogProduct(id, title, slot string, price float64, eco int) map[string]any {
+	return map[string]any{
+		"id": id, "title": title, "status": "published",
+		"metadata": map[string]any{"slot": slot, "eco_score": eco},
+		"options":  []map[string]any{{"id": "opt_size", "title": "Size", "values": []map[string]any{{"value": "M"}}}},
+		"variants": []map[string]any{{
+			"id": id + "_m", "title": "M", "inventory_quantity": 5, "manage_inventory": true,
+			"options": []map[string]any{{"option_id": "opt_size", "value": "M"}},
+			"prices":  []map[string]any{{"amount": price, "currency_code": "gbp"}},
+		}},
+	}
+}
+
+// integrationCatalog is a small wardrobe covering smart_casual's slots.
+func integrationCatalog() []map[string]any {
+	return []map[string]any{
+		catalogProduct("it_shirt", "Linen Oxford Shirt", "top", 45, 80),
+		catalogProduct("it_tee", "Organic Cotton Tee", "top", 20, 90),
+		catalogProduct("it_chinos", "Stretch Cotton Chinos", "bottom", 55, 70),
+		catalogProduct("it_jeans", "Selvedge Denim Jeans", "bottom", 90, 60),
+		catalogProduct("it_derby", "Leather Lace-up Derby Shoes", "shoes", 120, 50),
+		catalogProduct("it_loafer", "Suede Slip-on Loafers", "shoes", 95, 55),
+		catalogProduct("it_trainer", "Canvas Trainers", "shoes", 40, 75),
+	}
+}
+
+// indexIntegrationCatalog indexes integrationCatalog through
+// /index-products from a fake Medusa.
+func indexIntegrationCatalog(t *testing.T, api *testAPI) {
+	t.Helper()
+	var res struct {
+		Indexed int `json:"indexed"`
+	}
+	if code := api.call("POST", "/index-products", nil, &res); code != http.StatusOK {
+		t.Fatalf("/index-products = %d", code)
+	}
+	if res.Indexed != len(integrationCatalog()) {
+		t.Fatalf("indexed %d products, want %d", res.Indexed, len(integrationCatalog()))
+	}
+}
+
+func hitIDs(hits []Hit) []string {
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ProductID
+	}
+	return ids
+}
+
+func TestIntegrationMigrationsRoundTrip(t *testing.T) {
+	pool, _ := integrationDB(t, nil)
+	ctx := context.Background()
+
+	st, err := migrationStatus(ctx, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range st.Migrations {
+		if m.State != "applied" {
+			t.Errorf("migration %d %s is %s", m.Version, m.Name, m.State)
+		}
+	}
+
+	// every Down must undo its Up, or rollbacks break in production
+	p, err := migrations.NewProvider(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if _, err := p.DownTo(ctx, 0); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if err := prepareDatabase(ctx, integration.url); err != nil {
+		t.Fatalf("up again: %v", err)
+	}
+}
+
+func TestIntegrationIndexAndSearch(t *testing.T) {
+	m := newFakeMedusa(t)
+	m.Products = integrationCatalog()
+	pool, _ := integrationDB(t, m.env())
+	api := newTestAPI(t, pool)
+	indexIntegrationCatalog(t, api)
+
+	var rows int
+	if err := pool.QueryRow(context.Background(), `SELECT count(*) FROM product_embeddings WHERE tenant_id=$1 AND embedding IS NOT NULL`, api.tenant).Scan(&rows); err != nil {
+		t.Fatal(err)
+	}
+	if rows != len(m.Products) {
+		t.Errorf("stored %d embedded products, want %d", rows, len(m.Products))
+	}
+
+	t.Run("semantic", func(t *testing.T) {
+		var resp SearchResp
+		if code := api.call("POST", "/search", SearchReq{Query: "linen oxford shirt", Limit: 3}, &resp); code != http.StatusOK {
+			t.Fatalf("status %d", code)
+		}
+		if len(resp.Hits) == 0 || resp.Hits[0].ProductID != "it_shirt" {
+			t.Errorf("hits = %v, want it_shirt first", hitIDs(resp.Hits))
+		}
+		if resp.ResponseID == "" || resp.Degraded {
+			t.Errorf("response_id %q, degraded %v", resp.ResponseID, resp.Degraded)
+		}
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		var resp SearchResp
+		req := SearchReq{Query: "shoes for the office", Category: "shoes", MaxPriceGBP: 100, Limit: 10}
+		if code := api.call("POST", "/search", req, &resp); code != http.StatusOK {
+			t.Fatalf("status %d", code)
+		}
+		ids := hitIDs(resp.Hits)
+		slices.Sort(ids)
+		if !slices.Equal(ids, []string{"it_loafer", "it_trainer"}) {
+			t.Errorf("hits = %v, want the shoes under £100", ids)
+		}
+	})
+
+	t.Run("structured", func(t *testing.T) {
+		var resp SearchResp
+		if code := api.call("POST", "/search", SearchReq{Query: "shoes under £50"}, &resp); code != http.StatusOK {
+			t.Fatalf("status %d", code)
+		}
+		if resp.Intent == nil || resp.Intent.Route != routeStructured {
+			t.Errorf("intent = %+v, want the structured route", resp.Intent)
+		}
+		if !slices.Equal(hitIDs(resp.Hits), []string{"it_trainer"}) {
+			t.Errorf("hits = %v", hitIDs(resp.Hits))
+		}
+	})
+
+	t.Run("refine", func(t *testing.T) {
+		var first, refined SearchResp
+		api.call("POST", "/search", SearchReq{Query: "cotton", Limit: 5}, &first)
+		if first.ResponseID == "" {
+			t.Fatal("no response_id")
+		}
+		if code := api.call("POST", "/search", SearchReq{Within: first.ResponseID, MaxPriceGBP: 30}, &refined); code != http.StatusOK {
+			t.Fatalf("status %d", code)
+		}
+		for _, h := range refined.Hits {
+			if !slices.Contains(hitIDs(first.Hits), h.ProductID) || h.PriceGBP > 30 {
+				t.Errorf("refined hit %s (£%v) is not a cheap hit of the first search", h.ProductID, h.PriceGBP)
+			}
+		}
+	})
+
+	t.Run("keyword fallback", func(t *testing.T) {
+		embedBackends["fake"] = func() embeddings.Provider { return downEmbedder{} }
+		defer func() { embedBackends["fake"] = func() embeddings.Provider { return embeddings.Fake{} } }()
+		var resp SearchResp
+		if code := api.call("POST", "/search", SearchReq{Query: "selvedge denim"}, &resp); code != http.StatusOK {
+			t.Fatalf("status %d", code)
+		}
+		if !resp.Degraded || len(resp.Hits) == 0 || resp.Hits[0].ProductID != "it_jeans" {
+			t.Errorf("degraded %v, hits %v; want keyword hits with it_jeans first", resp.Degraded, hitIDs(resp.Hits))
+		}
+	})
+}
+
+func TestIntegrationCompleteOutfit(t *testing.T) {
+	pool, _ := integrationDB(t, nil)
+	api := newTestAPI(t, pool)
+
+	// indexing from an upload rather than the catalog
+	upload := strings.Join([]string{
+		`{"product_id": "up_shirt", "title": "Linen Oxford Shirt", "category": "top", "price": 45, "eco_score": 80}`,
+		`{"product_id": "up_chinos", "title": "Stretch Cotton Chinos", "category": "bottom", "price": 55, "eco_score": 70}`,
+		`{"product_id": "up_jeans", "title": "Selvedge Denim Jeans", "category": "bottom", "price": 90, "eco_score": 60}`,
+		`{"product_id": "up_loafer", "title": "Suede Loafers", "category": "shoes", "price": 95, "eco_score": 55}`,
+		`{"product_id": "up_trainer", "title": "Canvas Trainers", "category": "shoes", "price": 40, "eco_score": 75}`,
+		`{"product_id": "up_bad", "title": "", "category": "shoes"}`,
+	}, "\n")
+	var imp ImportResult
+	if code := api.call("POST", "/import-catalog?format=jsonl", upload, &imp); code != http.StatusOK {
+		t.Fatalf("/import-catalog = %d", code)
+	}
+	if imp.Indexed != 5 || len(imp.Rejected) != 1 {
+		t.Fatalf("import = %+v, want 5 indexed and 1 rejected", imp)
+	}
+
+	var resp CompleteOutfitResp
+	req := CompleteOutfitReq{Mission: "smart_casual", CartSlots: []string{"top"}, LimitPerSlot: 2}
+	if code := api.call("POST", "/complete-outfit", req, &resp); code != http.StatusOK {
+		t.Fatalf("/complete-outfit = %d", code)
+	}
+	if !slices.Equal(resp.MissingSlots, []string{"bottom", "shoes"}) {
+		t.Errorf("missing_slots = %v", resp.MissingSlots)
+	}
+	for _, sr := range resp.Results {
+		if len(sr.Hits) == 0 {
+			t.Errorf("slot %s: no hits (%s)", sr.Slot, sr.Reason)
+		}
+		for _, h := range sr.Hits {
+			if !strings.HasPrefix(h.ProductID, "up_") {
+				t.Errorf("slot %s: hit %s from outside the upload", sr.Slot, h.ProductID)
+			}
+		}
+	}
+
+	// served products are recorded for trending and history
+	var served int
+	if err := pool.QueryRow(context.Background(), `SELECT count(*) FROM recommendation_events`).Scan(&served); err != nil {
+		t.Fatal(err)
+	}
+	if served == 0 {
+		t.Error("no recommendation events recorded")
+	}
+	if err := refreshViews(context.Background(), pool); err != nil {
+		t.Errorf("refreshing views: %v", err)
+	}
+}
+
+func TestIntegrationSubstitutes(t *testing.T) {
+	m := newFakeMedusa(t)
+	m.Products = integrationCatalog()
+	pool, _ := integrationDB(t, m.env())
+	api := newTestAPI(t, pool)
+	indexIntegrationCatalog(t, api)
+	ctx := context.Background()
+
+	var res SubstitutesResp
+	if code := api.call("GET", "/products/it_loafer/substitutes?in_stock=false", nil, &res); code != http.StatusOK {
+		t.Fatalf("substitutes = %d", code)
+	}
+	if res.Slot != "shoes" || !slices.Contains(hitIDs(res.Substitutes), "it_trainer") {
+		t.Errorf("it_loafer substitutes = %+v", res)
+	}
+
+	// a product missing its category, price and eco score has no slot to fill
+	if _, err := pool.Exec(ctx, `UPDATE product_embeddings SET category=NULL, price_gbp=NULL, eco_score=NULL WHERE product_id='it_tee'`); err != nil {
+		t.Fatal(err)
+	}
+	if code := api.call("GET", "/products/it_tee/substitutes", nil, &res); code != http.StatusOK || res.Slot != "" || len(res.Substitutes) != 0 {
+		t.Errorf("NULL-category product = %d %+v", code, res)
+	}
+
+	// a sandbox product isn't found by a live key
+	if _, err := pool.Exec(ctx, `UPDATE product_embeddings SET sandbox=true WHERE product_id='it_derby'`); err != nil {
+		t.Fatal(err)
+	}
+	if code := api.call("GET", "/products/it_derby/substitutes", nil, nil); code != http.StatusNotFound {
+		t.Errorf("sandbox product from a live key = %d, want 404", code)
+	}
+}
+
+func TestIntegrationSessionConstraints(t *testing.T) {
+	m := newFakeMedusa(t)
+	m.Products = integrationCatalog()
+	pool, _ := integrationDB(t, m.env())
+	useCannedChat(t, &cannedChat{replies: map[string]string{
+		"session_constraint": `{"slots": ["shoes"], "exclude_terms": ["lace-up", "laces"], "exclude_materials": [], "attributes": [], "hours": 24}`,
+	}})
+	api := newTestAPI(t, pool)
+	indexIntegrationCatalog(t, api)
+
+	var c SessionConstraint
+	if code := api.call("POST", "/sessions/s1/constraints", ConstraintReq{Text: "on crutches today, no laces"}, &c); code != http.StatusCreated {
+		t.Fatalf("add constraint = %d", code)
+	}
+	if c.ID == "" || time.Until(c.ExpiresAt) < 23*time.Hour {
+		t.Errorf("constraint = %+v", c)
+	}
+
+	req := SearchReq{Query: "leather derby shoes", Category: "shoes", Limit: 10}
+	var open, constrained SearchResp
+	api.call("POST", "/search", req, &open)
+	api.call("POST", "/search", req, &constrained, "X-Session-ID", "s1")
+	if !slices.Contains(hitIDs(open.Hits), "it_derby") {
+		t.Fatalf("without the session: hits = %v", hitIDs(open.Hits))
+	}
+	if slices.Contains(hitIDs(constrained.Hits), "it_derby") || len(constrained.SessionConstraints) != 1 {
+		t.Errorf("with the session: hits = %v, constraints = %v", hitIDs(constrained.Hits), constrained.SessionConstraints)
+	}
+
+	// the constraint is about shoes; tops are searched as usual
+	var tops SearchResp
+	api.call("POST", "/search", SearchReq{Query: "linen oxford shirt", Category: "top"}, &tops, "X-Session-ID", "s1")
+	if len(tops.Hits) == 0 || tops.Hits[0].ProductID != "it_shirt" {
+		t.Errorf("tops with the session: hits = %v", hitIDs(tops.Hits))
+	}
+
+	if code := api.call("DELETE", "/sessions/s1/constraints/"+c.ID, nil, nil); code != http.StatusNoContent {
+		t.Errorf("delete = %d", code)
+	}
+	var list struct {
+		Constraints []SessionConstraint `json:"constraints"`
+	}
+	api.call("GET", "/sessions/s1/constraints", nil, &list)
+	if len(list.Constraints) != 0 {
+		t.Errorf("after delete: %v", list.Constraints)
+	}
+}
+
+func TestIntegrationUsageAndCaps(t *testing.T) {
+	m := newFakeMedusa(t)
+	m.Products = integrationCatalog()
+	pool, _ := integrationDB(t, m.env())
+	useCannedChat(t, &cannedChat{def: "ok"})
+	api := newTestAPI(t, pool)
+	indexIntegrationCatalog(t, api)
+	useFreshUsage(t) // count from here, whatever indexing used
+
+	ctx := context.WithValue(context.Background(), ctxTenant, api.tenant)
+	for range 2 {
+		if _, err := llmChat(ctx, "", config.LLMExplain, strings.Repeat("word ", 40), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var rep UsageReport
+	if code := api.call("GET", "/admin/usage?days=7", nil, &rep); code != http.StatusOK {
+		t.Fatalf("/admin/usage = %d", code)
+	}
+	if len(rep.Days) != 1 || rep.Days[0].Kind != usageChat || rep.Days[0].Calls != 2 || rep.Days[0].PromptTokens != 100 {
+		t.Fatalf("days = %+v", rep.Days)
+	}
+	if rep.Month.Tokens != 100 || rep.Month.Cap != 0 || rep.Month.CapReached {
+		t.Errorf("month = %+v", rep.Month)
+	}
+
+	// a cap below what was used stops new LLM work
+	if code := api.call("PUT", "/admin/tenant-settings", map[string]any{"monthly_token_cap": 80}, nil); code != http.StatusOK {
+		t.Fatalf("set cap = %d", code)
+	}
+	api.call("GET", "/admin/usage", nil, &rep)
+	if !rep.Month.CapReached || rep.Month.Remaining == nil || *rep.Month.Remaining != 0 {
+		t.Errorf("capped month = %+v", rep.Month)
+	}
+	if code := api.call("POST", "/index-products", nil, nil); code != http.StatusTooManyRequests {
+		t.Errorf("index over the cap = %d, want 429", code)
+	}
+	var res SearchResp
+	api.call("POST", "/search", SearchReq{Query: "something smart for a summer wedding", Limit: 5}, &res)
+	if !res.Degraded {
+		t.Errorf("search over the cap should fall back to keywords: %+v", res)
+	}
+}
+
+func TestIntegrationAuditLog(t *testing.T) {
+	m := newFakeMedusa(t)
+	m.Products = integrationCatalog()
+	pool, _ := integrationDB(t, m.env())
+	api := newTestAPI(t, pool)
+	indexIntegrationCatalog(t, api)
+
+	var first, second SearchResp
+	api.call("POST", "/search", SearchReq{Query: "linen for a summer wedding", Limit: 3}, &first)
+	api.call("POST", "/search", SearchReq{Query: "warm winter coat", Limit: 3}, &second)
+	if len(first.Hits) == 0 {
+		t.Fatal("search found nothing to audit")
+	}
+
+	var page AuditPage
+	if code := api.call("GET", "/admin/audit?source=search&limit=1", nil, &page); code != http.StatusOK {
+		t.Fatalf("/admin/audit = %d", code)
+	}
+	if len(page.Entries) != 1 || page.Entries[0].Query != "warm winter coat" || page.NextCursor == "" {
+		t.Fatalf("newest page = %+v", page)
+	}
+	api.call("GET", "/admin/audit?source=search&limit=1&cursor="+page.NextCursor, nil, &page)
+	if len(page.Entries) != 1 || page.Entries[0].Query != "linen for a summer wedding" {
+		t.Fatalf("next page = %+v", page)
+	}
+	e := page.Entries[0]
+	if len(e.Hits) != len(first.Hits) || e.Hits[0].ProductID != first.Hits[0].ProductID || e.Hits[0].Rank != 1 {
+		t.Errorf("hits = %+v, want %+v", e.Hits, first.Hits)
+	}
+
+	// "why was this shown": every answer that included the product
+	api.call("GET", "/admin/audit?product_id="+first.Hits[0].ProductID, nil, &page)
+	if len(page.Entries) == 0 {
+		t.Error("no entries for a product that was shown")
+	}
+	for _, e := range page.Entries {
+		if !slices.ContainsFunc(e.Hits, func(h AuditHit) bool { return h.ProductID == first.Hits[0].ProductID }) {
+			t.Errorf("entry %d does not include the product", e.ID)
+		}
+	}
+	if code := api.call("GET", "/admin/audit?source=nope", nil, nil); code != http.StatusBadRequest {
+		t.Errorf("bad source = %d, want 400", code)
+	}
+}
+
+func TestIntegrationFeedback(t *testing.T) {
+	m := newFakeMedusa(t)
+	m.Products = integrationCatalog()
+	pool, _ := integrationDB(t, m.env())
+	api := newTestAPI(t, pool)
+	indexIntegrationCatalog(t, api)
+
+	send := func(product, signal, session string) int {
+		fb := map[string]any{"product_id": product, "signal": signal}
+		return api.call("POST", "/feedback", fb, nil, "X-Session-ID", session)
+	}
+	if code := send("not_a_product", "up", "s1"); code != http.StatusBadRequest {
+		t.Errorf("unknown product = %d, want 400", code)
+	}
+	for _, fb := range []struct{ product, signal, session string }{
+		{"it_shirt", "up", "s1"},
+		{"it_shirt", "up", "s1"}, // a repeat: accepted, not stored
+		{"it_shirt", "add_to_cart", "s1"},
+		{"it_shirt", "up", "s2"},
+	} {
+		if code := send(fb.product, fb.signal, fb.session); code != http.StatusAccepted {
+			t.Fatalf("%+v = %d", fb, code)
+		}
+	}
+	var n int
+	pool.QueryRow(context.Background(), `SELECT count(*) FROM feedback WHERE tenant_id=$1`, api.tenant).Scan(&n)
+	if n != 3 {
+		t.Errorf("feedback rows = %d, want 3", n)
+	}
+}
+
+func TestIntegrationUserErasure(t *testing.T) {
+	m := newFakeMedusa(t)
+	m.Products = integrationCatalog()
+	pool, _ := integrationDB(t, m.env())
+	api := newTestAPI(t, pool)
+	indexIntegrationCatalog(t, api)
+
+	var res SearchResp
+	for _, s := range []struct{ session, user string }{{"s1", "u1"}, {"s2", "u1"}, {"s3", "u2"}} {
+		api.call("POST", "/search", SearchReq{Query: "linen shirt", Limit: 3}, &res, "X-Session-ID", s.session, "X-User-ID", s.user)
+		if len(res.Hits) == 0 {
+			t.Fatal("search found nothing")
+		}
+		fb := map[string]any{"product_id": res.Hits[0].ProductID, "signal": "up", "query": "linen shirt", "source": "search"}
+		if code := api.call("POST", "/feedback", fb, nil, "X-Session-ID", s.session); code != http.StatusAccepted {
+			t.Fatalf("/feedback = %d", code)
+		}
+	}
+	api.call("POST", "/sessions/s1/constraints", map[string]any{"text": "no laces today"}, nil, "X-Session-ID", "s1")
+	// a session stays with the user it was first tagged with
+	if code := api.call("POST", "/search", SearchReq{Query: "linen shirt", Limit: 3}, nil, "X-Session-ID", "s1", "X-User-ID", "u2"); code != http.StatusConflict {
+		t.Errorf("retagging s1 for u2 = %d, want 409", code)
+	}
+
+	// a webhook delivery and a stored idempotent response for s1 and for s3
+	ctx := context.Background()
+	var hook string
+	if err := pool.QueryRow(ctx, `INSERT INTO webhooks (tenant_id, url, secret) VALUES ($1, 'https://hooks.example.com', '\x00') RETURNING id::text`, api.tenant).Scan(&hook); err != nil {
+		t.Fatal(err)
+	}
+	for _, session := range []string{"s1", "s3"} {
+		payload := `{"type": "feedback.received", "data": {"product_id": "it_shirt", "signal": "up", "query": "linen shirt", "session_id": "` + session + `"}}`
+		if _, err := pool.Exec(ctx, `INSERT INTO webhook_outbox (webhook_id, tenant_id, event_id, event_type, payload) VALUES ($1, $2, $3, 'feedback.received', $4)`,
+			hook, api.tenant, "ev-"+session, payload); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pool.Exec(ctx, `INSERT INTO idempotency_keys (tenant_id, key, request_hash, session_id, status, body) VALUES ($1, $2, 'h', $3, 201, '{}')`,
+			api.tenant, "key-"+session, session); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var rec ErasureReceipt
+	if code := api.call("DELETE", "/users/u1/data", nil, &rec); code != http.StatusOK {
+		t.Fatalf("erase = %d", code)
+	}
+	if rec.ReceiptID == "" || !slices.Equal(rec.Sessions, []string{"s1", "s2"}) || rec.Erased["sessions"] != 2 {
+		t.Fatalf("receipt = %+v", rec)
+	}
+	if rec.Erased["feedback"] != 2 || rec.Erased["recommendation_audit"] != 2 || rec.Erased["session_events"] == 0 {
+		t.Errorf("erased = %v", rec.Erased)
+	}
+	if rec.Erased["webhook_outbox"] != 1 || rec.Erased["idempotency_keys"] != 1 {
+		t.Errorf("erased = %v", rec.Erased)
+	}
+	var redacted, kept string
+	pool.QueryRow(ctx, `SELECT payload->>'data' FROM webhook_outbox WHERE event_id='ev-s1'`).Scan(&redacted)
+	pool.QueryRow(ctx, `SELECT payload->>'data' FROM webhook_outbox WHERE event_id='ev-s3'`).Scan(&kept)
+	if strings.Contains(redacted, "session_id") || strings.Contains(redacted, "linen") || !strings.Contains(redacted, "it_shirt") {
+		t.Errorf("erased session's delivery = %s", redacted)
+	}
+	if !strings.Contains(kept, `"s3"`) {
+		t.Errorf("another session's delivery = %s", kept)
+	}
+	rows, _ := pool.Query(ctx, `SELECT key FROM idempotency_keys WHERE tenant_id=$1 ORDER BY key`, api.tenant)
+	keys, _ := pgx.CollectRows(rows, pgx.RowTo[string])
+	if !slices.Equal(keys, []string{"key-s3"}) {
+		t.Errorf("idempotency keys left = %v", keys)
+	}
+
+	var hist HistoryPage
+	api.call("GET", "/users/u1/history", nil, &hist)
+	if len(hist.Entries) != 0 {
+		t.Errorf("history after erasure = %+v", hist.Entries)
+	}
+	api.call("GET", "/users/u2/history", nil, &hist)
+	if len(hist.Entries) != 1 {
+		t.Errorf("another user's history = %+v", hist.Entries)
+	}
+	var left int
+	pool.QueryRow(context.Background(), `SELECT count(*) FROM feedback WHERE tenant_id=$1`, api.tenant).Scan(&left)
+	if left != 1 {
+		t.Errorf("feedback rows left = %d, want 1", left)
+	}
+	var stored string
+	pool.QueryRow(context.Background(), `SELECT user_hash FROM data_erasures WHERE id::text=$1`, rec.ReceiptID).Scan(&stored)
+	if stored != userHash(api.tenant, "u1") {
+		t.Errorf("stored receipt hash = %q", stored)
+	}
+
+	// erasing again finds nothing but still answers with a receipt
+	if code := api.call("DELETE", "/users/u1/data", nil, &rec); code != http.StatusOK || rec.Erased["sessions"] != 0 {
+		t.Errorf("second erasure = %d %+v", code, rec)
+	}
+}
+
+func TestIntegrationEmbedProductReadYourWritesAcrossTenants(t *testing.T) {
+	m := newFakeMedusa(t)
+	m.Products = integrationCatalog()
+	pool, _ := integrationDB(t, m.env())
+	useFakeRedis(t)
+	writer := newTestAPI(t, pool)
+	indexIntegrationCatalog(t, writer)
+	reader := &testAPI{t: t, h: writer.h, pool: pool, tenant: writer.tenant + "-reader"}
+
+	query := SearchReq{Query: "seersucker camp collar shirt", Limit: 3}
+	var res SearchResp
+	if code := reader.call("POST", "/search", query, &res); code != http.StatusOK {
+		t.Fatalf("/search = %d", code)
+	}
+	for _, h := range res.Hits {
+		if h.ProductID == "it_camp" {
+			t.Fatal("it_camp found before it was embedded")
+		}
+	}
+
+	// the fake embeds equal texts to equal vectors, so it_camp is the best match
+	embed := EmbedReq{ProductID: "it_camp", Category: "top", Text: query.Query, EcoScore: 70, PriceGBP: 40}
+	if code := writer.call("POST", "/embed-product", embed, nil); code != http.StatusOK {
+		t.Fatalf("/embed-product = %d", code)
+	}
+	if code := reader.call("POST", "/search", query, &res); code != http.StatusOK {
+		t.Fatalf("/search = %d", code)
+	}
+	if len(res.Hits) == 0 || res.Hits[0].ProductID != "it_camp" {
+		t.Errorf("another tenant's search after /embed-product = %+v, want it_camp first", res.Hits)
+	}
+}
+
+func TestIntegrationTenantIsolation(t *testing.T) {
+	m := newFakeMedusa(t)
+	m.Products = integrationCatalog()
+	pool, _ := integrationDB(t, m.env())
+	owner := newTestAPI(t, pool)
+	indexIntegrationCatalog(t, owner)
+	other := &testAPI{t: t, h: owner.h, pool: pool, tenant: owner.tenant + "-other"}
+
+	if code := other.call("DELETE", "/products/it_shirt", nil, nil); code != http.StatusNotFound {
+		t.Errorf("deleting another tenant's product = %d, want 404", code)
+	}
+
+	// an import can't take over another tenant's product ID
+	var imp ImportResult
+	upload := "product_id,title,price\nit_shirt,Knock-off Shirt,1\nother_hat,Hat,10\n"
+	if code := other.call("POST", "/import-catalog?format=csv", upload, &imp); code != http.StatusOK {
+		t.Fatalf("/import-catalog = %d", code)
+	}
+	if imp.Indexed != 1 || len(imp.Rejected) != 1 || imp.Rejected[0].Line != 2 {
+		t.Errorf("import = %+v, want other_hat indexed and line 2 rejected", imp)
+	}
+	var title string
+	if err := pool.QueryRow(context.Background(), `SELECT title FROM product_embeddings WHERE product_id='it_shirt'`).Scan(&title); err != nil || title == "Knock-off Shirt" {
+		t.Errorf("it_shirt title = %q, %v; want the owner's", title, err)
+	}
+	if code := owner.call("DELETE", "/products/it_shirt", nil, nil); code != http.StatusNoContent {
+		t.Errorf("deleting its own product = %d, want 204", code)
+	}
+}
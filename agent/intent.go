@@ -0,0 +1,112 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Query routes chosen by classifyQuery.
+const (
+	routeStructured = "structured" // filters only; plain SQL, no model calls
+	routeSemantic   = "semantic"   // embedding + vector search
+)
+
+// QueryIntent is what the heuristic router read from a free-text query.
+type QueryIntent struct {
+	Route       string  `json:"route"`
+	Category    string  `json:"category,omitempty"`
+	MaxPriceGBP float64 `json:"max_price_gbp,omitempty"`
+	Color       string  `json:"color,omitempty"`
+}
+
+// Words that name a whole category. Product types ("jeans", "trainers")
+// are deliberately absent: they narrow a category, which needs semantics.
+var categoryWords = map[string]string{
+	"top": "top", "tops": "top",
+	"bottom": "bottom", "bottoms": "bottom",
+	"shoe": "shoes", "shoes": "shoes", "footwear": "shoes",
+	"outerwear": "outerwear",
+}
+
+var colorWords = map[string]bool{
+	"black": true, "white": true, "grey": true, "gray": true, "navy": true, "blue": true,
+	"green": true, "red": true, "pink": true, "purple": true, "yellow": true, "orange": true,
+	"brown": true, "beige": true, "cream": true,
+}
+
+// filler words that carry no intent
+var fillerWords = map[string]bool{
+	"show": true, "me": true, "all": true, "any": true, "the": true, "a": true, "some": true,
+	"in": true, "please": true, "find": true, "list": true, "get": true, "i": true, "want": true,
+	"gbp": true, "pounds": true, "£": true,
+}
+
+var pricePattern = regexp.MustCompile(`(?i)\b(?:under|below|less than|cheaper than|max(?:imum)?|up to|upto|<=?)\s*£?\s*(\d+(?:\.\d{1,2})?)\s*(?:gbp|pounds|quid)?\b`)
+
+// classifyQuery routes a query to the structured fast path only when every
+// word is accounted for by a category, a price cap, a color or filler, and
+// a category was named. Anything else (styles, occasions, product types,
+// typos) goes to semantic search.
+func classifyQuery(q string) QueryIntent {
+	semantic := QueryIntent{Route: routeSemantic}
+	in := QueryIntent{Route: routeStructured}
+
+	rest := strings.ToLower(q)
+	if m := pricePattern.FindStringSubmatch(rest); m != nil {
+		price, err := strconv.ParseFloat(m[1], 64)
+		if err != nil || price <= 0 {
+			return semantic
+		}
+		in.MaxPriceGBP = price
+		rest = strings.Replace(rest, m[0], " ", 1)
+	}
+
+	for _, w := range strings.FieldsFunc(rest, func(r rune) bool {
+		return r == ' ' || r == ',' || r == '.' || r == '!' || r == '?'
+	}) {
+		switch {
+		case fillerWords[w]:
+		case categoryWords[w] != "":
+			if in.Category != "" && in.Category != categoryWords[w] {
+				return semantic
+			}
+			in.Category = categoryWords[w]
+		case colorWords[w]:
+			if in.Color != "" {
+				return semantic // "black or white" needs an OR the filters can't express
+			}
+			in.Color = w
+		default:
+			return semantic
+		}
+	}
+	if in.Category == "" {
+		return semantic
+	}
+	return in
+}
+
+// applyIntent merges a structured intent into explicit request filters.
+// Explicit values win; a conflict means the query says something the
+// filters don't, so the request goes semantic.
+func applyIntent(in QueryIntent, p *searchParams) bool {
+	if in.Route != routeStructured {
+		return false
+	}
+	if p.Category != "" && p.Category != in.Category {
+		return false
+	}
+	if in.Color != "" && p.Attrs.Color != "" && !strings.EqualFold(p.Attrs.Color, in.Color) {
+		return false
+	}
+	p.Category = in.Category
+	if in.Color != "" {
+		p.Attrs.Color = in.Color
+	}
+	if in.MaxPriceGBP > 0 && (p.MaxPriceGBP == 0 || in.MaxPriceGBP < p.MaxPriceGBP) {
+		p.MaxPriceGBP = in.MaxPriceGBP
+	}
+	p.Structured = true
+	return true
+}
@@ -0,0 +1,59 @@
+// Package catalog is the agent's view of a merchant's commerce backend:
+// the Provider interface products, prices and stock are indexed from, the
+// source-neutral product form providers return, and readers for the
+// merchant metadata fields products carry. Providers for Medusa and Shopify
+// implement it in the agent.
+package catalog
+
+import (
+	"context"
+	"time"
+)
+
+// Provider is a commerce backend products, prices and stock are indexed
+// from. CSA_CATALOG_PROVIDER picks one.
+type Provider interface {
+	Name() string
+	// Configured returns why the provider can't be used, or nil.
+	Configured() error
+	// Products returns products updated at or after since; zero means all.
+	Products(ctx context.Context, since time.Time) ([]Product, error)
+	// Stock returns current variant inventory keyed by product ID.
+	Stock(ctx context.Context) (map[string][]Variant, error)
+}
+
+// Product is a product in source-neutral form. Metadata carries the
+// merchant fields the agent reads (slot, eco_score, brand, gift_wrap, ...):
+// Medusa product metadata, or Shopify metafields.
+type Product struct {
+	ID          string
+	Title       string
+	Thumbnail   string
+	Description string
+	Material    string
+	Origin      string
+	Options     []Option
+	Tags        []string
+	Metadata    map[string]any
+	Variants    []Variant
+	PriceGBP    float64
+	HasPrice    bool   // false when no variant has a GBP price
+	Lifecycle   string // lifecycle state from the provider's status; empty = from metadata
+}
+
+// Option is a product option such as Size or Color with its values.
+type Option struct {
+	Title  string
+	Values []string
+}
+
+// Variant is the subset of a variant we care about for stock.
+type Variant struct {
+	ID                string
+	Title             string
+	SKU               string
+	InventoryQuantity int
+	ManageInventory   bool
+	AllowBackorder    bool
+	Size              string // value of the variant's size option, if any
+}
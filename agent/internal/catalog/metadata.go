@@ -0,0 +1,67 @@
+package catalog
+
+// MetaString reads a string metadata field; anything else reads as "".
+func MetaString(m map[string]any, key string) string {
+	if m == nil {
+		return ""
+	}
+	if s, ok := m[key].(string); ok {
+		return s
+	}
+	return ""
+}
+
+// EcoFromMeta reads metadata.eco_score, 0 when unset.
+func EcoFromMeta(m map[string]any) int {
+	if m == nil {
+		return 0
+	}
+	if v, ok := m["eco_score"]; ok {
+		switch t := v.(type) {
+		case float64:
+			return int(t)
+		case int:
+			return t
+		}
+	}
+	return 0
+}
+
+// PriceFromMetaGBP reads metadata.price_gbp, where catalogs from before
+// variant pricing kept the price; 0 when unset.
+func PriceFromMetaGBP(m map[string]any) float64 {
+	if m == nil {
+		return 0
+	}
+	if v, ok := m["price_gbp"]; ok {
+		switch t := v.(type) {
+		case float64:
+			return t
+		case int:
+			return float64(t)
+		}
+	}
+	return 0
+}
+
+// SlotFromMeta reads metadata.slot, the outfit slot a merchant assigned.
+func SlotFromMeta(m map[string]any) string {
+	return MetaString(m, "slot")
+}
+
+// NormalizeCategory lowercases the built-in slot names; other categories
+// are kept as given.
+func NormalizeCategory(name string) string {
+	switch name {
+	case "top", "Top":
+		return "top"
+	case "bottom", "Bottom":
+		return "bottom"
+	case "shoes", "Shoes":
+		return "shoes"
+	case "outerwear", "Outerwear":
+		return "outerwear"
+	default:
+		return name
+	}
+}
@@ -0,0 +1,171 @@
+// Package cron parses standard five-field cron expressions
+// (minute hour day-of-month month day-of-week) and computes run times.
+//
+// Fields accept *, numbers, ranges (1-5), lists (1,15) and steps (*/15,
+// 0-30/10). Month and weekday names are not supported; Sunday is 0 or 7.
+// The @hourly, @daily (@midnight), @weekly and @monthly shorthands are.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // bit n set = value n allowed
+
+	// cron runs a job when either day field matches if both are restricted
+	domStar, dowStar bool
+}
+
+var shorthands = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+type bounds struct {
+	name     string
+	min, max int
+}
+
+var fieldBounds = []bounds{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Parse parses expr.
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if s, ok := shorthands[expr]; ok {
+		expr = s
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(fieldBounds) {
+		return Schedule{}, fmt.Errorf("cron: want 5 fields (minute hour day month weekday), got %d", len(fields))
+	}
+	var bits [5]uint64
+	for i, f := range fields {
+		b, err := parseField(f, fieldBounds[i])
+		if err != nil {
+			return Schedule{}, err
+		}
+		bits[i] = b
+	}
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1 // 7 is Sunday too
+	}
+	return Schedule{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domStar: fields[2] == "*", dowStar: fields[4] == "*",
+	}, nil
+}
+
+func parseField(f string, b bounds) (uint64, error) {
+	var out uint64
+	for _, part := range strings.Split(f, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("cron: %s: invalid step in %q", b.name, part)
+			}
+			rng, step = part[:i], n
+		}
+
+		lo, hi := b.min, b.max
+		if rng != "*" {
+			var err error
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			if lo, err = strconv.Atoi(loStr); err != nil {
+				return 0, fmt.Errorf("cron: %s: invalid value %q", b.name, part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiStr); err != nil {
+					return 0, fmt.Errorf("cron: %s: invalid value %q", b.name, part)
+				}
+			} else if step > 1 {
+				hi = b.max // "5/10" means from 5 to the end
+			}
+		}
+		if lo < b.min || hi > b.max || lo > hi {
+			return 0, fmt.Errorf("cron: %s: %q out of range %d-%d", b.name, part, b.min, b.max)
+		}
+		for v := lo; v <= hi; v += step {
+			out |= 1 << v
+		}
+	}
+	return out, nil
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	switch {
+	case s.domStar && s.dowStar:
+		return true
+	case s.domStar:
+		return dow
+	case s.dowStar:
+		return dom
+	}
+	return dom || dow
+}
+
+// Next returns the first run time strictly after t, in t's location. It
+// returns the zero time if the expression never matches (e.g. 30 February).
+//
+// Across clock changes it follows cron: a run whose time is skipped when the
+// clocks go forward happens as soon as they have, and when they go back, a
+// job with fixed hours runs once while one with every hour runs in both.
+func (s Schedule) Next(t time.Time) time.Time {
+	prev := t.Truncate(time.Minute)
+	t = prev.Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.skipped(prev, t) {
+			return t
+		}
+		prev = t
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// skipped reports whether the clocks went forward between from and to past
+// a minute the schedule matches.
+func (s Schedule) skipped(from, to time.Time) bool {
+	gap := wall(to).Sub(wall(from)) - to.Sub(from)
+	for w := wall(to).Add(-gap); w.Before(wall(to)); w = w.Add(time.Minute) {
+		if s.month&(1<<int(w.Month())) != 0 && s.dayMatches(w) && s.hour&(1<<w.Hour()) != 0 && s.minute&(1<<w.Minute()) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// wall is t's clock reading as a UTC time, so readings on either side of a
+// clock change can be compared.
+func wall(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
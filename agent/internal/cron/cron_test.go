@@ -0,0 +1,130 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct{ expr, want string }{
+		{"", "want 5 fields"},
+		{"* * * *", "want 5 fields"},
+		{"* * * * * *", "want 5 fields"},
+		{"@yearly", "want 5 fields"},
+		{"60 * * * *", "minute: \"60\" out of range 0-59"},
+		{"* 24 * * *", "hour: \"24\" out of range 0-23"},
+		{"* * 0 * *", "day of month: \"0\" out of range 1-31"},
+		{"* * * 13 *", "month: \"13\" out of range 1-12"},
+		{"* * * * 8", "day of week: \"8\" out of range 0-7"},
+		{"5-1 * * * *", "out of range"},
+		{"*/0 * * * *", "minute: invalid step"},
+		{"*/x * * * *", "minute: invalid step"},
+		{"a * * * *", "minute: invalid value \"a\""},
+		{"1-x * * * *", "minute: invalid value"},
+		{"* * * JAN *", "month: invalid value"},
+		{"1,,2 * * * *", "minute: invalid value \"\""},
+	} {
+		_, err := Parse(tc.expr)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("Parse(%q) = %v, want an error containing %q", tc.expr, err, tc.want)
+		}
+	}
+
+	for _, expr := range []string{"* * * * *", " 0 0 * * * ", "*/15 0-6/2 1,15 1-12 1-5", "5/10 * * * 7", "@hourly", "@daily", "@midnight", "@weekly", "@monthly"} {
+		if _, err := Parse(expr); err != nil {
+			t.Errorf("Parse(%q) = %v", expr, err)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	utc := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	lon := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, london)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	for _, tc := range []struct {
+		name, expr string
+		from       time.Time
+		want       []time.Time // successive runs
+	}{
+		{"every minute", "* * * * *", utc("2026-05-01 10:00").Add(30 * time.Second),
+			[]time.Time{utc("2026-05-01 10:01"), utc("2026-05-01 10:02")}},
+		{"strictly after", "0 10 * * *", utc("2026-05-01 10:00"),
+			[]time.Time{utc("2026-05-02 10:00")}},
+		{"steps", "*/20 9 * * *", utc("2026-05-01 09:30"),
+			[]time.Time{utc("2026-05-01 09:40"), utc("2026-05-02 09:00"), utc("2026-05-02 09:20")}},
+		{"offset step", "5/20 * * * *", utc("2026-05-01 09:00"),
+			[]time.Time{utc("2026-05-01 09:05"), utc("2026-05-01 09:25"), utc("2026-05-01 09:45"), utc("2026-05-01 10:05")}},
+		{"month boundary", "30 23 * * *", utc("2026-04-30 23:45"),
+			[]time.Time{utc("2026-05-01 23:30")}},
+		{"year boundary", "@monthly", utc("2026-12-15 08:00"),
+			[]time.Time{utc("2027-01-01 00:00"), utc("2027-02-01 00:00")}},
+		{"new year's eve", "59 23 31 12 *", utc("2026-12-31 23:59"),
+			[]time.Time{utc("2027-12-31 23:59")}},
+		{"31st skips short months", "0 0 31 * *", utc("2026-01-31 12:00"),
+			[]time.Time{utc("2026-03-31 00:00"), utc("2026-05-31 00:00")}},
+		{"leap day", "0 0 29 2 *", utc("2026-01-01 00:00"),
+			[]time.Time{utc("2028-02-29 00:00"), utc("2032-02-29 00:00")}},
+		{"sunday as 7", "0 12 * * 7", utc("2026-05-01 00:00"), // a Friday
+			[]time.Time{utc("2026-05-03 12:00"), utc("2026-05-10 12:00")}},
+		{"weekdays", "0 9 * * 1-5", utc("2026-05-01 10:00"),
+			[]time.Time{utc("2026-05-04 09:00"), utc("2026-05-05 09:00")}},
+		// both day fields restricted: either matching is enough
+		{"day of month or week", "0 0 13 * 5", utc("2026-02-10 00:00"),
+			[]time.Time{utc("2026-02-13 00:00"), utc("2026-02-20 00:00"), utc("2026-02-27 00:00"), utc("2026-03-06 00:00"), utc("2026-03-13 00:00")}},
+		{"never", "0 0 30 2 *", utc("2026-01-01 00:00"), []time.Time{{}}},
+
+		// clocks go forward at 01:00 GMT on 29 March 2026: 01:30 doesn't
+		// happen, so the run is as soon as the clocks have changed
+		{"skipped by spring forward", "30 1 * * *", lon("2026-03-28 12:00"),
+			[]time.Time{lon("2026-03-29 02:00"), lon("2026-03-30 01:30")}},
+		{"spring forward from just before", "30 1 * * *", lon("2026-03-29 00:59"),
+			[]time.Time{lon("2026-03-29 02:00")}},
+		{"minute steps over spring forward", "0,30 0-2 * * *", lon("2026-03-29 00:30"),
+			[]time.Time{lon("2026-03-29 02:00"), lon("2026-03-29 02:30")}},
+		{"hourly over spring forward", "0 * * * *", lon("2026-03-29 00:30"),
+			[]time.Time{lon("2026-03-29 02:00"), lon("2026-03-29 03:00")}},
+		{"other days unaffected", "30 1 * * 1", lon("2026-03-28 12:00"),
+			[]time.Time{lon("2026-03-30 01:30")}},
+
+		// clocks go back at 02:00 BST on 25 October 2026: 01:00-01:59 happens
+		// twice; a daily job runs once, an hourly one in both hours
+		{"daily over fall back", "30 1 * * *", lon("2026-10-24 12:00"),
+			[]time.Time{time.Date(2026, 10, 25, 1, 30, 0, 0, time.FixedZone("GMT", 0)), lon("2026-10-26 01:30")}},
+		{"hourly over fall back", "0 * * * *", lon("2026-10-25 00:30"),
+			[]time.Time{utc("2026-10-25 00:00"), utc("2026-10-25 01:00"), utc("2026-10-25 02:00")}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := Parse(tc.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			at := tc.from
+			for i, want := range tc.want {
+				at = s.Next(at)
+				if !at.Equal(want) {
+					t.Fatalf("run %d = %v, want %v", i+1, at, want)
+				}
+				if !want.IsZero() && at.Location() != tc.from.Location() {
+					t.Errorf("run %d in %v, want %v", i+1, at.Location(), tc.from.Location())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,3468 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v3.5.1-go
+// source: proto/csa/v1/agent.proto
+
+// gRPC interface of the contextual shopping agent. Each call runs the HTTP
+// endpoint named in its comment, with the same auth, validation, caching
+// and logging, so messages mirror the JSON bodies field for field. Fields
+// left out of a message are the ones gRPC callers haven't needed yet.
+//
+// Regenerate the Go code with `go generate ./...` from agent/.
+
+package csapb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Filters shared by searches and outfits; see the README for each. In the
+// JSON body they sit at the top level, next to the other fields.
+type Filters struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Size             string                 `protobuf:"bytes,1,opt,name=size,proto3" json:"size,omitempty"`
+	Color            string                 `protobuf:"bytes,2,opt,name=color,proto3" json:"color,omitempty"`
+	Brand            string                 `protobuf:"bytes,3,opt,name=brand,proto3" json:"brand,omitempty"`
+	Material         string                 `protobuf:"bytes,4,opt,name=material,proto3" json:"material,omitempty"`
+	NearestSize      bool                   `protobuf:"varint,5,opt,name=nearest_size,json=nearestSize,proto3" json:"nearest_size,omitempty"`
+	Certifications   []string               `protobuf:"bytes,6,rep,name=certifications,proto3" json:"certifications,omitempty"`
+	VerifiedEcoOnly  bool                   `protobuf:"varint,7,opt,name=verified_eco_only,json=verifiedEcoOnly,proto3" json:"verified_eco_only,omitempty"`
+	Attributes       *structpb.Struct       `protobuf:"bytes,8,opt,name=attributes,proto3" json:"attributes,omitempty"`
+	ExcludeTerms     []string               `protobuf:"bytes,9,rep,name=exclude_terms,json=excludeTerms,proto3" json:"exclude_terms,omitempty"`
+	ExcludeMaterials []string               `protobuf:"bytes,10,rep,name=exclude_materials,json=excludeMaterials,proto3" json:"exclude_materials,omitempty"`
+	ShopperRegion    string                 `protobuf:"bytes,11,opt,name=shopper_region,json=shopperRegion,proto3" json:"shopper_region,omitempty"`
+	LocalBoost       float64                `protobuf:"fixed64,12,opt,name=local_boost,json=localBoost,proto3" json:"local_boost,omitempty"`
+	NewArrivals      bool                   `protobuf:"varint,13,opt,name=new_arrivals,json=newArrivals,proto3" json:"new_arrivals,omitempty"`
+	RecencyBoost     *float64               `protobuf:"fixed64,14,opt,name=recency_boost,json=recencyBoost,proto3,oneof" json:"recency_boost,omitempty"`
+	ClearanceMode    string                 `protobuf:"bytes,15,opt,name=clearance_mode,json=clearanceMode,proto3" json:"clearance_mode,omitempty"`
+	MinMarginPct     *float64               `protobuf:"fixed64,16,opt,name=min_margin_pct,json=minMarginPct,proto3,oneof" json:"min_margin_pct,omitempty"`
+	RankWeights      *RankWeights           `protobuf:"bytes,17,opt,name=rank_weights,json=rankWeights,proto3" json:"rank_weights,omitempty"`
+	MmrLambda        *float64               `protobuf:"fixed64,18,opt,name=mmr_lambda,json=mmrLambda,proto3,oneof" json:"mmr_lambda,omitempty"`
+	IncludePreorder  bool                   `protobuf:"varint,19,opt,name=include_preorder,json=includePreorder,proto3" json:"include_preorder,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Filters) Reset() {
+	*x = Filters{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Filters) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Filters) ProtoMessage() {}
+
+func (x *Filters) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Filters.ProtoReflect.Descriptor instead.
+func (*Filters) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Filters) GetSize() string {
+	if x != nil {
+		return x.Size
+	}
+	return ""
+}
+
+func (x *Filters) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+func (x *Filters) GetBrand() string {
+	if x != nil {
+		return x.Brand
+	}
+	return ""
+}
+
+func (x *Filters) GetMaterial() string {
+	if x != nil {
+		return x.Material
+	}
+	return ""
+}
+
+func (x *Filters) GetNearestSize() bool {
+	if x != nil {
+		return x.NearestSize
+	}
+	return false
+}
+
+func (x *Filters) GetCertifications() []string {
+	if x != nil {
+		return x.Certifications
+	}
+	return nil
+}
+
+func (x *Filters) GetVerifiedEcoOnly() bool {
+	if x != nil {
+		return x.VerifiedEcoOnly
+	}
+	return false
+}
+
+func (x *Filters) GetAttributes() *structpb.Struct {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *Filters) GetExcludeTerms() []string {
+	if x != nil {
+		return x.ExcludeTerms
+	}
+	return nil
+}
+
+func (x *Filters) GetExcludeMaterials() []string {
+	if x != nil {
+		return x.ExcludeMaterials
+	}
+	return nil
+}
+
+func (x *Filters) GetShopperRegion() string {
+	if x != nil {
+		return x.ShopperRegion
+	}
+	return ""
+}
+
+func (x *Filters) GetLocalBoost() float64 {
+	if x != nil {
+		return x.LocalBoost
+	}
+	return 0
+}
+
+func (x *Filters) GetNewArrivals() bool {
+	if x != nil {
+		return x.NewArrivals
+	}
+	return false
+}
+
+func (x *Filters) GetRecencyBoost() float64 {
+	if x != nil && x.RecencyBoost != nil {
+		return *x.RecencyBoost
+	}
+	return 0
+}
+
+func (x *Filters) GetClearanceMode() string {
+	if x != nil {
+		return x.ClearanceMode
+	}
+	return ""
+}
+
+func (x *Filters) GetMinMarginPct() float64 {
+	if x != nil && x.MinMarginPct != nil {
+		return *x.MinMarginPct
+	}
+	return 0
+}
+
+func (x *Filters) GetRankWeights() *RankWeights {
+	if x != nil {
+		return x.RankWeights
+	}
+	return nil
+}
+
+func (x *Filters) GetMmrLambda() float64 {
+	if x != nil && x.MmrLambda != nil {
+		return *x.MmrLambda
+	}
+	return 0
+}
+
+func (x *Filters) GetIncludePreorder() bool {
+	if x != nil {
+		return x.IncludePreorder
+	}
+	return false
+}
+
+type RankWeights struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Semantic      *float64               `protobuf:"fixed64,1,opt,name=semantic,proto3,oneof" json:"semantic,omitempty"`
+	Eco           *float64               `protobuf:"fixed64,2,opt,name=eco,proto3,oneof" json:"eco,omitempty"`
+	PriceFit      *float64               `protobuf:"fixed64,3,opt,name=price_fit,json=priceFit,proto3,oneof" json:"price_fit,omitempty"`
+	Popularity    *float64               `protobuf:"fixed64,4,opt,name=popularity,proto3,oneof" json:"popularity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RankWeights) Reset() {
+	*x = RankWeights{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RankWeights) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RankWeights) ProtoMessage() {}
+
+func (x *RankWeights) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RankWeights.ProtoReflect.Descriptor instead.
+func (*RankWeights) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RankWeights) GetSemantic() float64 {
+	if x != nil && x.Semantic != nil {
+		return *x.Semantic
+	}
+	return 0
+}
+
+func (x *RankWeights) GetEco() float64 {
+	if x != nil && x.Eco != nil {
+		return *x.Eco
+	}
+	return 0
+}
+
+func (x *RankWeights) GetPriceFit() float64 {
+	if x != nil && x.PriceFit != nil {
+		return *x.PriceFit
+	}
+	return 0
+}
+
+func (x *RankWeights) GetPopularity() float64 {
+	if x != nil && x.Popularity != nil {
+		return *x.Popularity
+	}
+	return 0
+}
+
+type SearchRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Query            string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	Limit            int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	MaxPriceGbp      float64                `protobuf:"fixed64,3,opt,name=max_price_gbp,json=maxPriceGbp,proto3" json:"max_price_gbp,omitempty"`
+	MinEcoScore      int32                  `protobuf:"varint,4,opt,name=min_eco_score,json=minEcoScore,proto3" json:"min_eco_score,omitempty"`
+	Category         string                 `protobuf:"bytes,5,opt,name=category,proto3" json:"category,omitempty"`
+	SortBy           string                 `protobuf:"bytes,6,opt,name=sort_by,json=sortBy,proto3" json:"sort_by,omitempty"`
+	ExpandDuplicates bool                   `protobuf:"varint,7,opt,name=expand_duplicates,json=expandDuplicates,proto3" json:"expand_duplicates,omitempty"`
+	DistanceMetric   string                 `protobuf:"bytes,8,opt,name=distance_metric,json=distanceMetric,proto3" json:"distance_metric,omitempty"`
+	SearchQuality    string                 `protobuf:"bytes,9,opt,name=search_quality,json=searchQuality,proto3" json:"search_quality,omitempty"`
+	Within           string                 `protobuf:"bytes,10,opt,name=within,proto3" json:"within,omitempty"`
+	Filters          *Filters               `protobuf:"bytes,11,opt,name=filters,proto3" json:"filters,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *SearchRequest) Reset() {
+	*x = SearchRequest{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRequest) ProtoMessage() {}
+
+func (x *SearchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRequest.ProtoReflect.Descriptor instead.
+func (*SearchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SearchRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetMaxPriceGbp() float64 {
+	if x != nil {
+		return x.MaxPriceGbp
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetMinEcoScore() int32 {
+	if x != nil {
+		return x.MinEcoScore
+	}
+	return 0
+}
+
+func (x *SearchRequest) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetSortBy() string {
+	if x != nil {
+		return x.SortBy
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetExpandDuplicates() bool {
+	if x != nil {
+		return x.ExpandDuplicates
+	}
+	return false
+}
+
+func (x *SearchRequest) GetDistanceMetric() string {
+	if x != nil {
+		return x.DistanceMetric
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetSearchQuality() string {
+	if x != nil {
+		return x.SearchQuality
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetWithin() string {
+	if x != nil {
+		return x.Within
+	}
+	return ""
+}
+
+func (x *SearchRequest) GetFilters() *Filters {
+	if x != nil {
+		return x.Filters
+	}
+	return nil
+}
+
+type SearchResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Hits               []*Hit                 `protobuf:"bytes,1,rep,name=hits,proto3" json:"hits,omitempty"`
+	Intent             *QueryIntent           `protobuf:"bytes,2,opt,name=intent,proto3" json:"intent,omitempty"`
+	RoutedCategories   []string               `protobuf:"bytes,3,rep,name=routed_categories,json=routedCategories,proto3" json:"routed_categories,omitempty"`
+	ResponseId         string                 `protobuf:"bytes,4,opt,name=response_id,json=responseId,proto3" json:"response_id,omitempty"`
+	Meta               *ResponseMeta          `protobuf:"bytes,5,opt,name=meta,proto3" json:"meta,omitempty"`
+	Degraded           bool                   `protobuf:"varint,6,opt,name=degraded,proto3" json:"degraded,omitempty"`
+	SessionConstraints []*ConstraintRef       `protobuf:"bytes,7,rep,name=session_constraints,json=sessionConstraints,proto3" json:"session_constraints,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *SearchResponse) Reset() {
+	*x = SearchResponse{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchResponse) ProtoMessage() {}
+
+func (x *SearchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchResponse.ProtoReflect.Descriptor instead.
+func (*SearchResponse) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *SearchResponse) GetHits() []*Hit {
+	if x != nil {
+		return x.Hits
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetIntent() *QueryIntent {
+	if x != nil {
+		return x.Intent
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetRoutedCategories() []string {
+	if x != nil {
+		return x.RoutedCategories
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetResponseId() string {
+	if x != nil {
+		return x.ResponseId
+	}
+	return ""
+}
+
+func (x *SearchResponse) GetMeta() *ResponseMeta {
+	if x != nil {
+		return x.Meta
+	}
+	return nil
+}
+
+func (x *SearchResponse) GetDegraded() bool {
+	if x != nil {
+		return x.Degraded
+	}
+	return false
+}
+
+func (x *SearchResponse) GetSessionConstraints() []*ConstraintRef {
+	if x != nil {
+		return x.SessionConstraints
+	}
+	return nil
+}
+
+// A temporary session constraint applied to a response.
+type ConstraintRef struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Text          string                 `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	ExpiresAt     string                 `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // RFC 3339
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConstraintRef) Reset() {
+	*x = ConstraintRef{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConstraintRef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConstraintRef) ProtoMessage() {}
+
+func (x *ConstraintRef) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConstraintRef.ProtoReflect.Descriptor instead.
+func (*ConstraintRef) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ConstraintRef) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ConstraintRef) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *ConstraintRef) GetExpiresAt() string {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return ""
+}
+
+type QueryIntent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Route         string                 `protobuf:"bytes,1,opt,name=route,proto3" json:"route,omitempty"`
+	Category      string                 `protobuf:"bytes,2,opt,name=category,proto3" json:"category,omitempty"`
+	MaxPriceGbp   float64                `protobuf:"fixed64,3,opt,name=max_price_gbp,json=maxPriceGbp,proto3" json:"max_price_gbp,omitempty"`
+	Color         string                 `protobuf:"bytes,4,opt,name=color,proto3" json:"color,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryIntent) Reset() {
+	*x = QueryIntent{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryIntent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryIntent) ProtoMessage() {}
+
+func (x *QueryIntent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryIntent.ProtoReflect.Descriptor instead.
+func (*QueryIntent) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *QueryIntent) GetRoute() string {
+	if x != nil {
+		return x.Route
+	}
+	return ""
+}
+
+func (x *QueryIntent) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *QueryIntent) GetMaxPriceGbp() float64 {
+	if x != nil {
+		return x.MaxPriceGbp
+	}
+	return 0
+}
+
+func (x *QueryIntent) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+type Hit struct {
+	state                  protoimpl.MessageState `protogen:"open.v1"`
+	ProductId              string                 `protobuf:"bytes,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Title                  string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Thumbnail              string                 `protobuf:"bytes,3,opt,name=thumbnail,proto3" json:"thumbnail,omitempty"`
+	EcoScore               int32                  `protobuf:"varint,4,opt,name=eco_score,json=ecoScore,proto3" json:"eco_score,omitempty"`
+	PriceGbp               float64                `protobuf:"fixed64,5,opt,name=price_gbp,json=priceGbp,proto3" json:"price_gbp,omitempty"`
+	Distance               float64                `protobuf:"fixed64,6,opt,name=distance,proto3" json:"distance,omitempty"`
+	Similarity             float64                `protobuf:"fixed64,7,opt,name=similarity,proto3" json:"similarity,omitempty"`
+	Reason                 string                 `protobuf:"bytes,8,opt,name=reason,proto3" json:"reason,omitempty"`
+	StockQty               *int32                 `protobuf:"varint,9,opt,name=stock_qty,json=stockQty,proto3,oneof" json:"stock_qty,omitempty"`
+	Variants               []*VariantStock        `protobuf:"bytes,10,rep,name=variants,proto3" json:"variants,omitempty"`
+	SuggestedSize          string                 `protobuf:"bytes,11,opt,name=suggested_size,json=suggestedSize,proto3" json:"suggested_size,omitempty"`
+	FeedbackScore          *float64               `protobuf:"fixed64,12,opt,name=feedback_score,json=feedbackScore,proto3,oneof" json:"feedback_score,omitempty"`
+	EcoLabels              []*EcoLabel            `protobuf:"bytes,13,rep,name=eco_labels,json=ecoLabels,proto3" json:"eco_labels,omitempty"`
+	EcoScoreSource         string                 `protobuf:"bytes,14,opt,name=eco_score_source,json=ecoScoreSource,proto3" json:"eco_score_source,omitempty"`
+	OriginCountry          string                 `protobuf:"bytes,15,opt,name=origin_country,json=originCountry,proto3" json:"origin_country,omitempty"`
+	ShippingKm             *float64               `protobuf:"fixed64,16,opt,name=shipping_km,json=shippingKm,proto3,oneof" json:"shipping_km,omitempty"`
+	MadeLocally            bool                   `protobuf:"varint,17,opt,name=made_locally,json=madeLocally,proto3" json:"made_locally,omitempty"`
+	CarbonAdjustedEcoScore *int32                 `protobuf:"varint,18,opt,name=carbon_adjusted_eco_score,json=carbonAdjustedEcoScore,proto3,oneof" json:"carbon_adjusted_eco_score,omitempty"`
+	NewArrival             bool                   `protobuf:"varint,19,opt,name=new_arrival,json=newArrival,proto3" json:"new_arrival,omitempty"`
+	Clearance              bool                   `protobuf:"varint,20,opt,name=clearance,proto3" json:"clearance,omitempty"`
+	Scores                 *ScoreBreakdown        `protobuf:"bytes,21,opt,name=scores,proto3" json:"scores,omitempty"`
+	Duplicates             []*DuplicateHit        `protobuf:"bytes,22,rep,name=duplicates,proto3" json:"duplicates,omitempty"`
+	Lifecycle              string                 `protobuf:"bytes,23,opt,name=lifecycle,proto3" json:"lifecycle,omitempty"`
+	ShipsAt                string                 `protobuf:"bytes,24,opt,name=ships_at,json=shipsAt,proto3" json:"ships_at,omitempty"`
+	SimilarityMargin       *float64               `protobuf:"fixed64,25,opt,name=similarity_margin,json=similarityMargin,proto3,oneof" json:"similarity_margin,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
+}
+
+func (x *Hit) Reset() {
+	*x = Hit{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Hit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Hit) ProtoMessage() {}
+
+func (x *Hit) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Hit.ProtoReflect.Descriptor instead.
+func (*Hit) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Hit) GetProductId() string {
+	if x != nil {
+		return x.ProductId
+	}
+	return ""
+}
+
+func (x *Hit) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Hit) GetThumbnail() string {
+	if x != nil {
+		return x.Thumbnail
+	}
+	return ""
+}
+
+func (x *Hit) GetEcoScore() int32 {
+	if x != nil {
+		return x.EcoScore
+	}
+	return 0
+}
+
+func (x *Hit) GetPriceGbp() float64 {
+	if x != nil {
+		return x.PriceGbp
+	}
+	return 0
+}
+
+func (x *Hit) GetDistance() float64 {
+	if x != nil {
+		return x.Distance
+	}
+	return 0
+}
+
+func (x *Hit) GetSimilarity() float64 {
+	if x != nil {
+		return x.Similarity
+	}
+	return 0
+}
+
+func (x *Hit) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Hit) GetStockQty() int32 {
+	if x != nil && x.StockQty != nil {
+		return *x.StockQty
+	}
+	return 0
+}
+
+func (x *Hit) GetVariants() []*VariantStock {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+func (x *Hit) GetSuggestedSize() string {
+	if x != nil {
+		return x.SuggestedSize
+	}
+	return ""
+}
+
+func (x *Hit) GetFeedbackScore() float64 {
+	if x != nil && x.FeedbackScore != nil {
+		return *x.FeedbackScore
+	}
+	return 0
+}
+
+func (x *Hit) GetEcoLabels() []*EcoLabel {
+	if x != nil {
+		return x.EcoLabels
+	}
+	return nil
+}
+
+func (x *Hit) GetEcoScoreSource() string {
+	if x != nil {
+		return x.EcoScoreSource
+	}
+	return ""
+}
+
+func (x *Hit) GetOriginCountry() string {
+	if x != nil {
+		return x.OriginCountry
+	}
+	return ""
+}
+
+func (x *Hit) GetShippingKm() float64 {
+	if x != nil && x.ShippingKm != nil {
+		return *x.ShippingKm
+	}
+	return 0
+}
+
+func (x *Hit) GetMadeLocally() bool {
+	if x != nil {
+		return x.MadeLocally
+	}
+	return false
+}
+
+func (x *Hit) GetCarbonAdjustedEcoScore() int32 {
+	if x != nil && x.CarbonAdjustedEcoScore != nil {
+		return *x.CarbonAdjustedEcoScore
+	}
+	return 0
+}
+
+func (x *Hit) GetNewArrival() bool {
+	if x != nil {
+		return x.NewArrival
+	}
+	return false
+}
+
+func (x *Hit) GetClearance() bool {
+	if x != nil {
+		return x.Clearance
+	}
+	return false
+}
+
+func (x *Hit) GetScores() *ScoreBreakdown {
+	if x != nil {
+		return x.Scores
+	}
+	return nil
+}
+
+func (x *Hit) GetDuplicates() []*DuplicateHit {
+	if x != nil {
+		return x.Duplicates
+	}
+	return nil
+}
+
+func (x *Hit) GetLifecycle() string {
+	if x != nil {
+		return x.Lifecycle
+	}
+	return ""
+}
+
+func (x *Hit) GetShipsAt() string {
+	if x != nil {
+		return x.ShipsAt
+	}
+	return ""
+}
+
+func (x *Hit) GetSimilarityMargin() float64 {
+	if x != nil && x.SimilarityMargin != nil {
+		return *x.SimilarityMargin
+	}
+	return 0
+}
+
+type VariantStock struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	VariantId     string                 `protobuf:"bytes,1,opt,name=variant_id,json=variantId,proto3" json:"variant_id,omitempty"`
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Sku           string                 `protobuf:"bytes,3,opt,name=sku,proto3" json:"sku,omitempty"`
+	Quantity      *int32                 `protobuf:"varint,4,opt,name=quantity,proto3,oneof" json:"quantity,omitempty"`
+	InStock       bool                   `protobuf:"varint,5,opt,name=in_stock,json=inStock,proto3" json:"in_stock,omitempty"`
+	Size          string                 `protobuf:"bytes,6,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VariantStock) Reset() {
+	*x = VariantStock{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VariantStock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VariantStock) ProtoMessage() {}
+
+func (x *VariantStock) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VariantStock.ProtoReflect.Descriptor instead.
+func (*VariantStock) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *VariantStock) GetVariantId() string {
+	if x != nil {
+		return x.VariantId
+	}
+	return ""
+}
+
+func (x *VariantStock) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *VariantStock) GetSku() string {
+	if x != nil {
+		return x.Sku
+	}
+	return ""
+}
+
+func (x *VariantStock) GetQuantity() int32 {
+	if x != nil && x.Quantity != nil {
+		return *x.Quantity
+	}
+	return 0
+}
+
+func (x *VariantStock) GetInStock() bool {
+	if x != nil {
+		return x.InStock
+	}
+	return false
+}
+
+func (x *VariantStock) GetSize() string {
+	if x != nil {
+		return x.Size
+	}
+	return ""
+}
+
+type EcoLabel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EcoLabel) Reset() {
+	*x = EcoLabel{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EcoLabel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EcoLabel) ProtoMessage() {}
+
+func (x *EcoLabel) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EcoLabel.ProtoReflect.Descriptor instead.
+func (*EcoLabel) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *EcoLabel) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *EcoLabel) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type ScoreBreakdown struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Semantic      float64                `protobuf:"fixed64,1,opt,name=semantic,proto3" json:"semantic,omitempty"`
+	Eco           *float64               `protobuf:"fixed64,2,opt,name=eco,proto3,oneof" json:"eco,omitempty"`
+	PriceFit      *float64               `protobuf:"fixed64,3,opt,name=price_fit,json=priceFit,proto3,oneof" json:"price_fit,omitempty"`
+	Popularity    *float64               `protobuf:"fixed64,4,opt,name=popularity,proto3,oneof" json:"popularity,omitempty"`
+	Total         float64                `protobuf:"fixed64,5,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScoreBreakdown) Reset() {
+	*x = ScoreBreakdown{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScoreBreakdown) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScoreBreakdown) ProtoMessage() {}
+
+func (x *ScoreBreakdown) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScoreBreakdown.ProtoReflect.Descriptor instead.
+func (*ScoreBreakdown) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ScoreBreakdown) GetSemantic() float64 {
+	if x != nil {
+		return x.Semantic
+	}
+	return 0
+}
+
+func (x *ScoreBreakdown) GetEco() float64 {
+	if x != nil && x.Eco != nil {
+		return *x.Eco
+	}
+	return 0
+}
+
+func (x *ScoreBreakdown) GetPriceFit() float64 {
+	if x != nil && x.PriceFit != nil {
+		return *x.PriceFit
+	}
+	return 0
+}
+
+func (x *ScoreBreakdown) GetPopularity() float64 {
+	if x != nil && x.Popularity != nil {
+		return *x.Popularity
+	}
+	return 0
+}
+
+func (x *ScoreBreakdown) GetTotal() float64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type DuplicateHit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProductId     string                 `protobuf:"bytes,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Thumbnail     string                 `protobuf:"bytes,3,opt,name=thumbnail,proto3" json:"thumbnail,omitempty"`
+	PriceGbp      float64                `protobuf:"fixed64,4,opt,name=price_gbp,json=priceGbp,proto3" json:"price_gbp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DuplicateHit) Reset() {
+	*x = DuplicateHit{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DuplicateHit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DuplicateHit) ProtoMessage() {}
+
+func (x *DuplicateHit) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DuplicateHit.ProtoReflect.Descriptor instead.
+func (*DuplicateHit) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DuplicateHit) GetProductId() string {
+	if x != nil {
+		return x.ProductId
+	}
+	return ""
+}
+
+func (x *DuplicateHit) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *DuplicateHit) GetThumbnail() string {
+	if x != nil {
+		return x.Thumbnail
+	}
+	return ""
+}
+
+func (x *DuplicateHit) GetPriceGbp() float64 {
+	if x != nil {
+		return x.PriceGbp
+	}
+	return 0
+}
+
+type ResponseMeta struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Warnings      []string               `protobuf:"bytes,1,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResponseMeta) Reset() {
+	*x = ResponseMeta{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResponseMeta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResponseMeta) ProtoMessage() {}
+
+func (x *ResponseMeta) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResponseMeta.ProtoReflect.Descriptor instead.
+func (*ResponseMeta) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ResponseMeta) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+type CompleteOutfitRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Mission          string                 `protobuf:"bytes,1,opt,name=mission,proto3" json:"mission,omitempty"`
+	BudgetGbp        float64                `protobuf:"fixed64,2,opt,name=budget_gbp,json=budgetGbp,proto3" json:"budget_gbp,omitempty"`
+	MinEcoScore      int32                  `protobuf:"varint,3,opt,name=min_eco_score,json=minEcoScore,proto3" json:"min_eco_score,omitempty"`
+	CartSlots        []string               `protobuf:"bytes,4,rep,name=cart_slots,json=cartSlots,proto3" json:"cart_slots,omitempty"`
+	CartId           string                 `protobuf:"bytes,5,opt,name=cart_id,json=cartId,proto3" json:"cart_id,omitempty"`
+	LimitPerSlot     int32                  `protobuf:"varint,6,opt,name=limit_per_slot,json=limitPerSlot,proto3" json:"limit_per_slot,omitempty"`
+	PriceBands       bool                   `protobuf:"varint,7,opt,name=price_bands,json=priceBands,proto3" json:"price_bands,omitempty"`
+	Query            string                 `protobuf:"bytes,8,opt,name=query,proto3" json:"query,omitempty"`
+	ExpandDuplicates bool                   `protobuf:"varint,9,opt,name=expand_duplicates,json=expandDuplicates,proto3" json:"expand_duplicates,omitempty"`
+	DistanceMetric   string                 `protobuf:"bytes,10,opt,name=distance_metric,json=distanceMetric,proto3" json:"distance_metric,omitempty"`
+	Filters          *Filters               `protobuf:"bytes,11,opt,name=filters,proto3" json:"filters,omitempty"`
+	Gift             *GiftOptions           `protobuf:"bytes,12,opt,name=gift,proto3" json:"gift,omitempty"`
+	Weather          *WeatherRequest        `protobuf:"bytes,13,opt,name=weather,proto3" json:"weather,omitempty"`
+	Debug            bool                   `protobuf:"varint,14,opt,name=debug,proto3" json:"debug,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *CompleteOutfitRequest) Reset() {
+	*x = CompleteOutfitRequest{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompleteOutfitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompleteOutfitRequest) ProtoMessage() {}
+
+func (x *CompleteOutfitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompleteOutfitRequest.ProtoReflect.Descriptor instead.
+func (*CompleteOutfitRequest) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CompleteOutfitRequest) GetMission() string {
+	if x != nil {
+		return x.Mission
+	}
+	return ""
+}
+
+func (x *CompleteOutfitRequest) GetBudgetGbp() float64 {
+	if x != nil {
+		return x.BudgetGbp
+	}
+	return 0
+}
+
+func (x *CompleteOutfitRequest) GetMinEcoScore() int32 {
+	if x != nil {
+		return x.MinEcoScore
+	}
+	return 0
+}
+
+func (x *CompleteOutfitRequest) GetCartSlots() []string {
+	if x != nil {
+		return x.CartSlots
+	}
+	return nil
+}
+
+func (x *CompleteOutfitRequest) GetCartId() string {
+	if x != nil {
+		return x.CartId
+	}
+	return ""
+}
+
+func (x *CompleteOutfitRequest) GetLimitPerSlot() int32 {
+	if x != nil {
+		return x.LimitPerSlot
+	}
+	return 0
+}
+
+func (x *CompleteOutfitRequest) GetPriceBands() bool {
+	if x != nil {
+		return x.PriceBands
+	}
+	return false
+}
+
+func (x *CompleteOutfitRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *CompleteOutfitRequest) GetExpandDuplicates() bool {
+	if x != nil {
+		return x.ExpandDuplicates
+	}
+	return false
+}
+
+func (x *CompleteOutfitRequest) GetDistanceMetric() string {
+	if x != nil {
+		return x.DistanceMetric
+	}
+	return ""
+}
+
+func (x *CompleteOutfitRequest) GetFilters() *Filters {
+	if x != nil {
+		return x.Filters
+	}
+	return nil
+}
+
+func (x *CompleteOutfitRequest) GetGift() *GiftOptions {
+	if x != nil {
+		return x.Gift
+	}
+	return nil
+}
+
+func (x *CompleteOutfitRequest) GetWeather() *WeatherRequest {
+	if x != nil {
+		return x.Weather
+	}
+	return nil
+}
+
+func (x *CompleteOutfitRequest) GetDebug() bool {
+	if x != nil {
+		return x.Debug
+	}
+	return false
+}
+
+type GiftOptions struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Recipient     string                 `protobuf:"bytes,1,opt,name=recipient,proto3" json:"recipient,omitempty"`
+	Occasion      string                 `protobuf:"bytes,2,opt,name=occasion,proto3" json:"occasion,omitempty"`
+	WrapCostGbp   float64                `protobuf:"fixed64,3,opt,name=wrap_cost_gbp,json=wrapCostGbp,proto3" json:"wrap_cost_gbp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GiftOptions) Reset() {
+	*x = GiftOptions{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GiftOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GiftOptions) ProtoMessage() {}
+
+func (x *GiftOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GiftOptions.ProtoReflect.Descriptor instead.
+func (*GiftOptions) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GiftOptions) GetRecipient() string {
+	if x != nil {
+		return x.Recipient
+	}
+	return ""
+}
+
+func (x *GiftOptions) GetOccasion() string {
+	if x != nil {
+		return x.Occasion
+	}
+	return ""
+}
+
+func (x *GiftOptions) GetWrapCostGbp() float64 {
+	if x != nil {
+		return x.WrapCostGbp
+	}
+	return 0
+}
+
+type WeatherRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Location      string                 `protobuf:"bytes,1,opt,name=location,proto3" json:"location,omitempty"`
+	Date          string                 `protobuf:"bytes,2,opt,name=date,proto3" json:"date,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WeatherRequest) Reset() {
+	*x = WeatherRequest{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WeatherRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WeatherRequest) ProtoMessage() {}
+
+func (x *WeatherRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WeatherRequest.ProtoReflect.Descriptor instead.
+func (*WeatherRequest) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *WeatherRequest) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *WeatherRequest) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+type CompleteOutfitResponse struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Mission            string                 `protobuf:"bytes,1,opt,name=mission,proto3" json:"mission,omitempty"`
+	BudgetGbp          float64                `protobuf:"fixed64,2,opt,name=budget_gbp,json=budgetGbp,proto3" json:"budget_gbp,omitempty"`
+	MinEcoScore        int32                  `protobuf:"varint,3,opt,name=min_eco_score,json=minEcoScore,proto3" json:"min_eco_score,omitempty"`
+	CartSlots          []string               `protobuf:"bytes,4,rep,name=cart_slots,json=cartSlots,proto3" json:"cart_slots,omitempty"`
+	MissingSlots       []string               `protobuf:"bytes,5,rep,name=missing_slots,json=missingSlots,proto3" json:"missing_slots,omitempty"`
+	Results            []*SlotRecs            `protobuf:"bytes,6,rep,name=results,proto3" json:"results,omitempty"`
+	Gift               *GiftSummary           `protobuf:"bytes,7,opt,name=gift,proto3" json:"gift,omitempty"`
+	Cart               *CartSummary           `protobuf:"bytes,8,opt,name=cart,proto3" json:"cart,omitempty"`
+	Bundle             *BundleSummary         `protobuf:"bytes,9,opt,name=bundle,proto3" json:"bundle,omitempty"`
+	EcoGrade           *EcoGrade              `protobuf:"bytes,10,opt,name=eco_grade,json=ecoGrade,proto3" json:"eco_grade,omitempty"`
+	Intent             *OutfitIntent          `protobuf:"bytes,11,opt,name=intent,proto3" json:"intent,omitempty"`
+	Forecast           *Forecast              `protobuf:"bytes,12,opt,name=forecast,proto3" json:"forecast,omitempty"`
+	Cache              *OutfitCacheInfo       `protobuf:"bytes,13,opt,name=cache,proto3" json:"cache,omitempty"`
+	Meta               *ResponseMeta          `protobuf:"bytes,14,opt,name=meta,proto3" json:"meta,omitempty"`
+	OptionalSlots      []*OptionalSlot        `protobuf:"bytes,15,rep,name=optional_slots,json=optionalSlots,proto3" json:"optional_slots,omitempty"`
+	SessionConstraints []*ConstraintRef       `protobuf:"bytes,16,rep,name=session_constraints,json=sessionConstraints,proto3" json:"session_constraints,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *CompleteOutfitResponse) Reset() {
+	*x = CompleteOutfitResponse{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompleteOutfitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompleteOutfitResponse) ProtoMessage() {}
+
+func (x *CompleteOutfitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompleteOutfitResponse.ProtoReflect.Descriptor instead.
+func (*CompleteOutfitResponse) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *CompleteOutfitResponse) GetMission() string {
+	if x != nil {
+		return x.Mission
+	}
+	return ""
+}
+
+func (x *CompleteOutfitResponse) GetBudgetGbp() float64 {
+	if x != nil {
+		return x.BudgetGbp
+	}
+	return 0
+}
+
+func (x *CompleteOutfitResponse) GetMinEcoScore() int32 {
+	if x != nil {
+		return x.MinEcoScore
+	}
+	return 0
+}
+
+func (x *CompleteOutfitResponse) GetCartSlots() []string {
+	if x != nil {
+		return x.CartSlots
+	}
+	return nil
+}
+
+func (x *CompleteOutfitResponse) GetMissingSlots() []string {
+	if x != nil {
+		return x.MissingSlots
+	}
+	return nil
+}
+
+func (x *CompleteOutfitResponse) GetResults() []*SlotRecs {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *CompleteOutfitResponse) GetGift() *GiftSummary {
+	if x != nil {
+		return x.Gift
+	}
+	return nil
+}
+
+func (x *CompleteOutfitResponse) GetCart() *CartSummary {
+	if x != nil {
+		return x.Cart
+	}
+	return nil
+}
+
+func (x *CompleteOutfitResponse) GetBundle() *BundleSummary {
+	if x != nil {
+		return x.Bundle
+	}
+	return nil
+}
+
+func (x *CompleteOutfitResponse) GetEcoGrade() *EcoGrade {
+	if x != nil {
+		return x.EcoGrade
+	}
+	return nil
+}
+
+func (x *CompleteOutfitResponse) GetIntent() *OutfitIntent {
+	if x != nil {
+		return x.Intent
+	}
+	return nil
+}
+
+func (x *CompleteOutfitResponse) GetForecast() *Forecast {
+	if x != nil {
+		return x.Forecast
+	}
+	return nil
+}
+
+func (x *CompleteOutfitResponse) GetCache() *OutfitCacheInfo {
+	if x != nil {
+		return x.Cache
+	}
+	return nil
+}
+
+func (x *CompleteOutfitResponse) GetMeta() *ResponseMeta {
+	if x != nil {
+		return x.Meta
+	}
+	return nil
+}
+
+func (x *CompleteOutfitResponse) GetOptionalSlots() []*OptionalSlot {
+	if x != nil {
+		return x.OptionalSlots
+	}
+	return nil
+}
+
+func (x *CompleteOutfitResponse) GetSessionConstraints() []*ConstraintRef {
+	if x != nil {
+		return x.SessionConstraints
+	}
+	return nil
+}
+
+type SlotRecs struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Slot          string                 `protobuf:"bytes,1,opt,name=slot,proto3" json:"slot,omitempty"`
+	Hits          []*Hit                 `protobuf:"bytes,2,rep,name=hits,proto3" json:"hits,omitempty"`
+	Bands         []*PriceBand           `protobuf:"bytes,3,rep,name=bands,proto3" json:"bands,omitempty"`
+	Reason        string                 `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	Confidence    *SlotConfidence        `protobuf:"bytes,5,opt,name=confidence,proto3" json:"confidence,omitempty"`
+	Threshold     *SlotThreshold         `protobuf:"bytes,6,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	Optional      bool                   `protobuf:"varint,7,opt,name=optional,proto3" json:"optional,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SlotRecs) Reset() {
+	*x = SlotRecs{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SlotRecs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SlotRecs) ProtoMessage() {}
+
+func (x *SlotRecs) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SlotRecs.ProtoReflect.Descriptor instead.
+func (*SlotRecs) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *SlotRecs) GetSlot() string {
+	if x != nil {
+		return x.Slot
+	}
+	return ""
+}
+
+func (x *SlotRecs) GetHits() []*Hit {
+	if x != nil {
+		return x.Hits
+	}
+	return nil
+}
+
+func (x *SlotRecs) GetBands() []*PriceBand {
+	if x != nil {
+		return x.Bands
+	}
+	return nil
+}
+
+func (x *SlotRecs) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *SlotRecs) GetConfidence() *SlotConfidence {
+	if x != nil {
+		return x.Confidence
+	}
+	return nil
+}
+
+func (x *SlotRecs) GetThreshold() *SlotThreshold {
+	if x != nil {
+		return x.Threshold
+	}
+	return nil
+}
+
+func (x *SlotRecs) GetOptional() bool {
+	if x != nil {
+		return x.Optional
+	}
+	return false
+}
+
+type OptionalSlot struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Slot            string                 `protobuf:"bytes,1,opt,name=slot,proto3" json:"slot,omitempty"`
+	FillProbability float64                `protobuf:"fixed64,2,opt,name=fill_probability,json=fillProbability,proto3" json:"fill_probability,omitempty"`
+	Filled          bool                   `protobuf:"varint,3,opt,name=filled,proto3" json:"filled,omitempty"`
+	Value           float64                `protobuf:"fixed64,4,opt,name=value,proto3" json:"value,omitempty"`
+	ProductId       string                 `protobuf:"bytes,5,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Reason          string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *OptionalSlot) Reset() {
+	*x = OptionalSlot{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OptionalSlot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OptionalSlot) ProtoMessage() {}
+
+func (x *OptionalSlot) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OptionalSlot.ProtoReflect.Descriptor instead.
+func (*OptionalSlot) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *OptionalSlot) GetSlot() string {
+	if x != nil {
+		return x.Slot
+	}
+	return ""
+}
+
+func (x *OptionalSlot) GetFillProbability() float64 {
+	if x != nil {
+		return x.FillProbability
+	}
+	return 0
+}
+
+func (x *OptionalSlot) GetFilled() bool {
+	if x != nil {
+		return x.Filled
+	}
+	return false
+}
+
+func (x *OptionalSlot) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *OptionalSlot) GetProductId() string {
+	if x != nil {
+		return x.ProductId
+	}
+	return ""
+}
+
+func (x *OptionalSlot) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type SlotThreshold struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	MinSimilarity  float64                `protobuf:"fixed64,1,opt,name=min_similarity,json=minSimilarity,proto3" json:"min_similarity,omitempty"`
+	Source         string                 `protobuf:"bytes,2,opt,name=source,proto3" json:"source,omitempty"`
+	Dropped        int32                  `protobuf:"varint,3,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	BestSimilarity *float64               `protobuf:"fixed64,4,opt,name=best_similarity,json=bestSimilarity,proto3,oneof" json:"best_similarity,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SlotThreshold) Reset() {
+	*x = SlotThreshold{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SlotThreshold) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SlotThreshold) ProtoMessage() {}
+
+func (x *SlotThreshold) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SlotThreshold.ProtoReflect.Descriptor instead.
+func (*SlotThreshold) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *SlotThreshold) GetMinSimilarity() float64 {
+	if x != nil {
+		return x.MinSimilarity
+	}
+	return 0
+}
+
+func (x *SlotThreshold) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *SlotThreshold) GetDropped() int32 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+func (x *SlotThreshold) GetBestSimilarity() float64 {
+	if x != nil && x.BestSimilarity != nil {
+		return *x.BestSimilarity
+	}
+	return 0
+}
+
+type PriceBand struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Band          string                 `protobuf:"bytes,1,opt,name=band,proto3" json:"band,omitempty"`
+	MinGbp        *float64               `protobuf:"fixed64,2,opt,name=min_gbp,json=minGbp,proto3,oneof" json:"min_gbp,omitempty"`
+	MaxGbp        *float64               `protobuf:"fixed64,3,opt,name=max_gbp,json=maxGbp,proto3,oneof" json:"max_gbp,omitempty"`
+	Hits          []*Hit                 `protobuf:"bytes,4,rep,name=hits,proto3" json:"hits,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PriceBand) Reset() {
+	*x = PriceBand{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PriceBand) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PriceBand) ProtoMessage() {}
+
+func (x *PriceBand) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PriceBand.ProtoReflect.Descriptor instead.
+func (*PriceBand) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *PriceBand) GetBand() string {
+	if x != nil {
+		return x.Band
+	}
+	return ""
+}
+
+func (x *PriceBand) GetMinGbp() float64 {
+	if x != nil && x.MinGbp != nil {
+		return *x.MinGbp
+	}
+	return 0
+}
+
+func (x *PriceBand) GetMaxGbp() float64 {
+	if x != nil && x.MaxGbp != nil {
+		return *x.MaxGbp
+	}
+	return 0
+}
+
+func (x *PriceBand) GetHits() []*Hit {
+	if x != nil {
+		return x.Hits
+	}
+	return nil
+}
+
+type SlotConfidence struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Score         float64                `protobuf:"fixed64,1,opt,name=score,proto3" json:"score,omitempty"`
+	Low           bool                   `protobuf:"varint,2,opt,name=low,proto3" json:"low,omitempty"`
+	Match         float64                `protobuf:"fixed64,3,opt,name=match,proto3" json:"match,omitempty"`
+	Gap           float64                `protobuf:"fixed64,4,opt,name=gap,proto3" json:"gap,omitempty"`
+	Slack         float64                `protobuf:"fixed64,5,opt,name=slack,proto3" json:"slack,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SlotConfidence) Reset() {
+	*x = SlotConfidence{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SlotConfidence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SlotConfidence) ProtoMessage() {}
+
+func (x *SlotConfidence) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SlotConfidence.ProtoReflect.Descriptor instead.
+func (*SlotConfidence) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *SlotConfidence) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *SlotConfidence) GetLow() bool {
+	if x != nil {
+		return x.Low
+	}
+	return false
+}
+
+func (x *SlotConfidence) GetMatch() float64 {
+	if x != nil {
+		return x.Match
+	}
+	return 0
+}
+
+func (x *SlotConfidence) GetGap() float64 {
+	if x != nil {
+		return x.Gap
+	}
+	return 0
+}
+
+func (x *SlotConfidence) GetSlack() float64 {
+	if x != nil {
+		return x.Slack
+	}
+	return 0
+}
+
+type GiftSummary struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	WrapCostPerItemGbp  float64                `protobuf:"fixed64,1,opt,name=wrap_cost_per_item_gbp,json=wrapCostPerItemGbp,proto3" json:"wrap_cost_per_item_gbp,omitempty"`
+	WrapCostTotalGbp    float64                `protobuf:"fixed64,2,opt,name=wrap_cost_total_gbp,json=wrapCostTotalGbp,proto3" json:"wrap_cost_total_gbp,omitempty"`
+	BudgetForItemsGbp   float64                `protobuf:"fixed64,3,opt,name=budget_for_items_gbp,json=budgetForItemsGbp,proto3" json:"budget_for_items_gbp,omitempty"`
+	MessageSuggestion   string                 `protobuf:"bytes,4,opt,name=message_suggestion,json=messageSuggestion,proto3" json:"message_suggestion,omitempty"`
+	MessageFromTemplate bool                   `protobuf:"varint,5,opt,name=message_from_template,json=messageFromTemplate,proto3" json:"message_from_template,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *GiftSummary) Reset() {
+	*x = GiftSummary{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GiftSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GiftSummary) ProtoMessage() {}
+
+func (x *GiftSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GiftSummary.ProtoReflect.Descriptor instead.
+func (*GiftSummary) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *GiftSummary) GetWrapCostPerItemGbp() float64 {
+	if x != nil {
+		return x.WrapCostPerItemGbp
+	}
+	return 0
+}
+
+func (x *GiftSummary) GetWrapCostTotalGbp() float64 {
+	if x != nil {
+		return x.WrapCostTotalGbp
+	}
+	return 0
+}
+
+func (x *GiftSummary) GetBudgetForItemsGbp() float64 {
+	if x != nil {
+		return x.BudgetForItemsGbp
+	}
+	return 0
+}
+
+func (x *GiftSummary) GetMessageSuggestion() string {
+	if x != nil {
+		return x.MessageSuggestion
+	}
+	return ""
+}
+
+func (x *GiftSummary) GetMessageFromTemplate() bool {
+	if x != nil {
+		return x.MessageFromTemplate
+	}
+	return false
+}
+
+type CartSummary struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	CartId             string                 `protobuf:"bytes,1,opt,name=cart_id,json=cartId,proto3" json:"cart_id,omitempty"`
+	ProductIds         []string               `protobuf:"bytes,2,rep,name=product_ids,json=productIds,proto3" json:"product_ids,omitempty"`
+	SpentGbp           float64                `protobuf:"fixed64,3,opt,name=spent_gbp,json=spentGbp,proto3" json:"spent_gbp,omitempty"`
+	RemainingBudgetGbp float64                `protobuf:"fixed64,4,opt,name=remaining_budget_gbp,json=remainingBudgetGbp,proto3" json:"remaining_budget_gbp,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *CartSummary) Reset() {
+	*x = CartSummary{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CartSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CartSummary) ProtoMessage() {}
+
+func (x *CartSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CartSummary.ProtoReflect.Descriptor instead.
+func (*CartSummary) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *CartSummary) GetCartId() string {
+	if x != nil {
+		return x.CartId
+	}
+	return ""
+}
+
+func (x *CartSummary) GetProductIds() []string {
+	if x != nil {
+		return x.ProductIds
+	}
+	return nil
+}
+
+func (x *CartSummary) GetSpentGbp() float64 {
+	if x != nil {
+		return x.SpentGbp
+	}
+	return 0
+}
+
+func (x *CartSummary) GetRemainingBudgetGbp() float64 {
+	if x != nil {
+		return x.RemainingBudgetGbp
+	}
+	return 0
+}
+
+type BundleSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SubtotalGbp   float64                `protobuf:"fixed64,1,opt,name=subtotal_gbp,json=subtotalGbp,proto3" json:"subtotal_gbp,omitempty"`
+	DiscountGbp   float64                `protobuf:"fixed64,2,opt,name=discount_gbp,json=discountGbp,proto3" json:"discount_gbp,omitempty"`
+	TotalGbp      float64                `protobuf:"fixed64,3,opt,name=total_gbp,json=totalGbp,proto3" json:"total_gbp,omitempty"`
+	Promotion     *AppliedPromotion      `protobuf:"bytes,4,opt,name=promotion,proto3" json:"promotion,omitempty"`
+	Suggestion    *BundleSuggestion      `protobuf:"bytes,5,opt,name=suggestion,proto3" json:"suggestion,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BundleSummary) Reset() {
+	*x = BundleSummary{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BundleSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BundleSummary) ProtoMessage() {}
+
+func (x *BundleSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BundleSummary.ProtoReflect.Descriptor instead.
+func (*BundleSummary) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *BundleSummary) GetSubtotalGbp() float64 {
+	if x != nil {
+		return x.SubtotalGbp
+	}
+	return 0
+}
+
+func (x *BundleSummary) GetDiscountGbp() float64 {
+	if x != nil {
+		return x.DiscountGbp
+	}
+	return 0
+}
+
+func (x *BundleSummary) GetTotalGbp() float64 {
+	if x != nil {
+		return x.TotalGbp
+	}
+	return 0
+}
+
+func (x *BundleSummary) GetPromotion() *AppliedPromotion {
+	if x != nil {
+		return x.Promotion
+	}
+	return nil
+}
+
+func (x *BundleSummary) GetSuggestion() *BundleSuggestion {
+	if x != nil {
+		return x.Suggestion
+	}
+	return nil
+}
+
+type AppliedPromotion struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Code          string                 `protobuf:"bytes,2,opt,name=code,proto3" json:"code,omitempty"`
+	Automatic     bool                   `protobuf:"varint,3,opt,name=automatic,proto3" json:"automatic,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppliedPromotion) Reset() {
+	*x = AppliedPromotion{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppliedPromotion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppliedPromotion) ProtoMessage() {}
+
+func (x *AppliedPromotion) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppliedPromotion.ProtoReflect.Descriptor instead.
+func (*AppliedPromotion) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *AppliedPromotion) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *AppliedPromotion) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *AppliedPromotion) GetAutomatic() bool {
+	if x != nil {
+		return x.Automatic
+	}
+	return false
+}
+
+type BundleSuggestion struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Add           *Hit                   `protobuf:"bytes,1,opt,name=add,proto3" json:"add,omitempty"`
+	Promotion     *AppliedPromotion      `protobuf:"bytes,2,opt,name=promotion,proto3" json:"promotion,omitempty"`
+	DiscountGbp   float64                `protobuf:"fixed64,3,opt,name=discount_gbp,json=discountGbp,proto3" json:"discount_gbp,omitempty"`
+	TotalGbp      float64                `protobuf:"fixed64,4,opt,name=total_gbp,json=totalGbp,proto3" json:"total_gbp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BundleSuggestion) Reset() {
+	*x = BundleSuggestion{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BundleSuggestion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BundleSuggestion) ProtoMessage() {}
+
+func (x *BundleSuggestion) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BundleSuggestion.ProtoReflect.Descriptor instead.
+func (*BundleSuggestion) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *BundleSuggestion) GetAdd() *Hit {
+	if x != nil {
+		return x.Add
+	}
+	return nil
+}
+
+func (x *BundleSuggestion) GetPromotion() *AppliedPromotion {
+	if x != nil {
+		return x.Promotion
+	}
+	return nil
+}
+
+func (x *BundleSuggestion) GetDiscountGbp() float64 {
+	if x != nil {
+		return x.DiscountGbp
+	}
+	return 0
+}
+
+func (x *BundleSuggestion) GetTotalGbp() float64 {
+	if x != nil {
+		return x.TotalGbp
+	}
+	return 0
+}
+
+type EcoGrade struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Grade         string                 `protobuf:"bytes,1,opt,name=grade,proto3" json:"grade,omitempty"`
+	Score         float64                `protobuf:"fixed64,2,opt,name=score,proto3" json:"score,omitempty"`
+	Items         int32                  `protobuf:"varint,3,opt,name=items,proto3" json:"items,omitempty"`
+	Capped        bool                   `protobuf:"varint,4,opt,name=capped,proto3" json:"capped,omitempty"`
+	Rubric        string                 `protobuf:"bytes,5,opt,name=rubric,proto3" json:"rubric,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EcoGrade) Reset() {
+	*x = EcoGrade{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EcoGrade) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EcoGrade) ProtoMessage() {}
+
+func (x *EcoGrade) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EcoGrade.ProtoReflect.Descriptor instead.
+func (*EcoGrade) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *EcoGrade) GetGrade() string {
+	if x != nil {
+		return x.Grade
+	}
+	return ""
+}
+
+func (x *EcoGrade) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *EcoGrade) GetItems() int32 {
+	if x != nil {
+		return x.Items
+	}
+	return 0
+}
+
+func (x *EcoGrade) GetCapped() bool {
+	if x != nil {
+		return x.Capped
+	}
+	return false
+}
+
+func (x *EcoGrade) GetRubric() string {
+	if x != nil {
+		return x.Rubric
+	}
+	return ""
+}
+
+type OutfitIntent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	Mission       string                 `protobuf:"bytes,2,opt,name=mission,proto3" json:"mission,omitempty"`
+	Slots         []string               `protobuf:"bytes,3,rep,name=slots,proto3" json:"slots,omitempty"`
+	BudgetGbp     float64                `protobuf:"fixed64,4,opt,name=budget_gbp,json=budgetGbp,proto3" json:"budget_gbp,omitempty"`
+	MinEcoScore   int32                  `protobuf:"varint,5,opt,name=min_eco_score,json=minEcoScore,proto3" json:"min_eco_score,omitempty"`
+	CartSlots     []string               `protobuf:"bytes,6,rep,name=cart_slots,json=cartSlots,proto3" json:"cart_slots,omitempty"`
+	Color         string                 `protobuf:"bytes,7,opt,name=color,proto3" json:"color,omitempty"`
+	Material      string                 `protobuf:"bytes,8,opt,name=material,proto3" json:"material,omitempty"`
+	Size          string                 `protobuf:"bytes,9,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OutfitIntent) Reset() {
+	*x = OutfitIntent{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OutfitIntent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OutfitIntent) ProtoMessage() {}
+
+func (x *OutfitIntent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OutfitIntent.ProtoReflect.Descriptor instead.
+func (*OutfitIntent) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *OutfitIntent) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *OutfitIntent) GetMission() string {
+	if x != nil {
+		return x.Mission
+	}
+	return ""
+}
+
+func (x *OutfitIntent) GetSlots() []string {
+	if x != nil {
+		return x.Slots
+	}
+	return nil
+}
+
+func (x *OutfitIntent) GetBudgetGbp() float64 {
+	if x != nil {
+		return x.BudgetGbp
+	}
+	return 0
+}
+
+func (x *OutfitIntent) GetMinEcoScore() int32 {
+	if x != nil {
+		return x.MinEcoScore
+	}
+	return 0
+}
+
+func (x *OutfitIntent) GetCartSlots() []string {
+	if x != nil {
+		return x.CartSlots
+	}
+	return nil
+}
+
+func (x *OutfitIntent) GetColor() string {
+	if x != nil {
+		return x.Color
+	}
+	return ""
+}
+
+func (x *OutfitIntent) GetMaterial() string {
+	if x != nil {
+		return x.Material
+	}
+	return ""
+}
+
+func (x *OutfitIntent) GetSize() string {
+	if x != nil {
+		return x.Size
+	}
+	return ""
+}
+
+type Forecast struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Location      string                 `protobuf:"bytes,1,opt,name=location,proto3" json:"location,omitempty"`
+	Date          string                 `protobuf:"bytes,2,opt,name=date,proto3" json:"date,omitempty"`
+	TempMinC      float64                `protobuf:"fixed64,3,opt,name=temp_min_c,json=tempMinC,proto3" json:"temp_min_c,omitempty"`
+	TempMaxC      float64                `protobuf:"fixed64,4,opt,name=temp_max_c,json=tempMaxC,proto3" json:"temp_max_c,omitempty"`
+	PrecipMm      float64                `protobuf:"fixed64,5,opt,name=precip_mm,json=precipMm,proto3" json:"precip_mm,omitempty"`
+	PrecipChance  int32                  `protobuf:"varint,6,opt,name=precip_chance,json=precipChance,proto3" json:"precip_chance,omitempty"`
+	WindKph       float64                `protobuf:"fixed64,7,opt,name=wind_kph,json=windKph,proto3" json:"wind_kph,omitempty"`
+	Summary       string                 `protobuf:"bytes,8,opt,name=summary,proto3" json:"summary,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Forecast) Reset() {
+	*x = Forecast{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Forecast) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Forecast) ProtoMessage() {}
+
+func (x *Forecast) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Forecast.ProtoReflect.Descriptor instead.
+func (*Forecast) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *Forecast) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *Forecast) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *Forecast) GetTempMinC() float64 {
+	if x != nil {
+		return x.TempMinC
+	}
+	return 0
+}
+
+func (x *Forecast) GetTempMaxC() float64 {
+	if x != nil {
+		return x.TempMaxC
+	}
+	return 0
+}
+
+func (x *Forecast) GetPrecipMm() float64 {
+	if x != nil {
+		return x.PrecipMm
+	}
+	return 0
+}
+
+func (x *Forecast) GetPrecipChance() int32 {
+	if x != nil {
+		return x.PrecipChance
+	}
+	return 0
+}
+
+func (x *Forecast) GetWindKph() float64 {
+	if x != nil {
+		return x.WindKph
+	}
+	return 0
+}
+
+func (x *Forecast) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+type OutfitCacheInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AgeSeconds    int32                  `protobuf:"varint,1,opt,name=age_seconds,json=ageSeconds,proto3" json:"age_seconds,omitempty"`
+	Stale         bool                   `protobuf:"varint,2,opt,name=stale,proto3" json:"stale,omitempty"`
+	Revalidating  bool                   `protobuf:"varint,3,opt,name=revalidating,proto3" json:"revalidating,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OutfitCacheInfo) Reset() {
+	*x = OutfitCacheInfo{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OutfitCacheInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OutfitCacheInfo) ProtoMessage() {}
+
+func (x *OutfitCacheInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OutfitCacheInfo.ProtoReflect.Descriptor instead.
+func (*OutfitCacheInfo) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *OutfitCacheInfo) GetAgeSeconds() int32 {
+	if x != nil {
+		return x.AgeSeconds
+	}
+	return 0
+}
+
+func (x *OutfitCacheInfo) GetStale() bool {
+	if x != nil {
+		return x.Stale
+	}
+	return false
+}
+
+func (x *OutfitCacheInfo) GetRevalidating() bool {
+	if x != nil {
+		return x.Revalidating
+	}
+	return false
+}
+
+type ExplainOutfitRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// an outfit as CompleteOutfit returned it
+	Outfit        *CompleteOutfitResponse `protobuf:"bytes,1,opt,name=outfit,proto3" json:"outfit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExplainOutfitRequest) Reset() {
+	*x = ExplainOutfitRequest{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExplainOutfitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExplainOutfitRequest) ProtoMessage() {}
+
+func (x *ExplainOutfitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExplainOutfitRequest.ProtoReflect.Descriptor instead.
+func (*ExplainOutfitRequest) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ExplainOutfitRequest) GetOutfit() *CompleteOutfitResponse {
+	if x != nil {
+		return x.Outfit
+	}
+	return nil
+}
+
+type ExplainOutfitResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Bullets       []string               `protobuf:"bytes,1,rep,name=bullets,proto3" json:"bullets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExplainOutfitResponse) Reset() {
+	*x = ExplainOutfitResponse{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExplainOutfitResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExplainOutfitResponse) ProtoMessage() {}
+
+func (x *ExplainOutfitResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExplainOutfitResponse.ProtoReflect.Descriptor instead.
+func (*ExplainOutfitResponse) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ExplainOutfitResponse) GetBullets() []string {
+	if x != nil {
+		return x.Bullets
+	}
+	return nil
+}
+
+type IndexProductsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Mode          string                 `protobuf:"bytes,1,opt,name=mode,proto3" json:"mode,omitempty"` // full (default) or incremental
+	MaxTokens     int32                  `protobuf:"varint,2,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
+	MaxCostUsd    float64                `protobuf:"fixed64,3,opt,name=max_cost_usd,json=maxCostUsd,proto3" json:"max_cost_usd,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IndexProductsRequest) Reset() {
+	*x = IndexProductsRequest{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IndexProductsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IndexProductsRequest) ProtoMessage() {}
+
+func (x *IndexProductsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IndexProductsRequest.ProtoReflect.Descriptor instead.
+func (*IndexProductsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *IndexProductsRequest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *IndexProductsRequest) GetMaxTokens() int32 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
+}
+
+func (x *IndexProductsRequest) GetMaxCostUsd() float64 {
+	if x != nil {
+		return x.MaxCostUsd
+	}
+	return 0
+}
+
+type IndexProductsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Provider      string                 `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`
+	Mode          string                 `protobuf:"bytes,2,opt,name=mode,proto3" json:"mode,omitempty"`
+	Since         string                 `protobuf:"bytes,3,opt,name=since,proto3" json:"since,omitempty"` // RFC 3339; incremental runs only
+	Fetched       int32                  `protobuf:"varint,4,opt,name=fetched,proto3" json:"fetched,omitempty"`
+	Indexed       int32                  `protobuf:"varint,5,opt,name=indexed,proto3" json:"indexed,omitempty"`
+	Budget        *IndexBudget           `protobuf:"bytes,6,opt,name=budget,proto3" json:"budget,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IndexProductsResponse) Reset() {
+	*x = IndexProductsResponse{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IndexProductsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IndexProductsResponse) ProtoMessage() {}
+
+func (x *IndexProductsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IndexProductsResponse.ProtoReflect.Descriptor instead.
+func (*IndexProductsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *IndexProductsResponse) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *IndexProductsResponse) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *IndexProductsResponse) GetSince() string {
+	if x != nil {
+		return x.Since
+	}
+	return ""
+}
+
+func (x *IndexProductsResponse) GetFetched() int32 {
+	if x != nil {
+		return x.Fetched
+	}
+	return 0
+}
+
+func (x *IndexProductsResponse) GetIndexed() int32 {
+	if x != nil {
+		return x.Indexed
+	}
+	return 0
+}
+
+func (x *IndexProductsResponse) GetBudget() *IndexBudget {
+	if x != nil {
+		return x.Budget
+	}
+	return nil
+}
+
+type IndexBudget struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MaxTokens     int32                  `protobuf:"varint,1,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
+	Tokens        int32                  `protobuf:"varint,2,opt,name=tokens,proto3" json:"tokens,omitempty"`
+	CostUsd       float64                `protobuf:"fixed64,3,opt,name=cost_usd,json=costUsd,proto3" json:"cost_usd,omitempty"`
+	Remaining     int32                  `protobuf:"varint,4,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	RemainingIds  []string               `protobuf:"bytes,5,rep,name=remaining_ids,json=remainingIds,proto3" json:"remaining_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IndexBudget) Reset() {
+	*x = IndexBudget{}
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IndexBudget) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IndexBudget) ProtoMessage() {}
+
+func (x *IndexBudget) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_csa_v1_agent_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IndexBudget.ProtoReflect.Descriptor instead.
+func (*IndexBudget) Descriptor() ([]byte, []int) {
+	return file_proto_csa_v1_agent_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *IndexBudget) GetMaxTokens() int32 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
+}
+
+func (x *IndexBudget) GetTokens() int32 {
+	if x != nil {
+		return x.Tokens
+	}
+	return 0
+}
+
+func (x *IndexBudget) GetCostUsd() float64 {
+	if x != nil {
+		return x.CostUsd
+	}
+	return 0
+}
+
+func (x *IndexBudget) GetRemaining() int32 {
+	if x != nil {
+		return x.Remaining
+	}
+	return 0
+}
+
+func (x *IndexBudget) GetRemainingIds() []string {
+	if x != nil {
+		return x.RemainingIds
+	}
+	return nil
+}
+
+var File_proto_csa_v1_agent_proto protoreflect.FileDescriptor
+
+const file_proto_csa_v1_agent_proto_rawDesc = "" +
+	"\n" +
+	"\x18proto/csa/v1/agent.proto\x12\x06csa.v1\x1a\x1cgoogle/protobuf/struct.proto\"\x89\x06\n" +
+	"\aFilters\x12\x12\n" +
+	"\x04size\x18\x01 \x01(\tR\x04size\x12\x14\n" +
+	"\x05color\x18\x02 \x01(\tR\x05color\x12\x14\n" +
+	"\x05brand\x18\x03 \x01(\tR\x05brand\x12\x1a\n" +
+	"\bmaterial\x18\x04 \x01(\tR\bmaterial\x12!\n" +
+	"\fnearest_size\x18\x05 \x01(\bR\vnearestSize\x12&\n" +
+	"\x0ecertifications\x18\x06 \x03(\tR\x0ecertifications\x12*\n" +
+	"\x11verified_eco_only\x18\a \x01(\bR\x0fverifiedEcoOnly\x127\n" +
+	"\n" +
+	"attributes\x18\b \x01(\v2\x17.google.protobuf.StructR\n" +
+	"attributes\x12#\n" +
+	"\rexclude_terms\x18\t \x03(\tR\fexcludeTerms\x12+\n" +
+	"\x11exclude_materials\x18\n" +
+	" \x03(\tR\x10excludeMaterials\x12%\n" +
+	"\x0eshopper_region\x18\v \x01(\tR\rshopperRegion\x12\x1f\n" +
+	"\vlocal_boost\x18\f \x01(\x01R\n" +
+	"localBoost\x12!\n" +
+	"\fnew_arrivals\x18\r \x01(\bR\vnewArrivals\x12(\n" +
+	"\rrecency_boost\x18\x0e \x01(\x01H\x00R\frecencyBoost\x88\x01\x01\x12%\n" +
+	"\x0eclearance_mode\x18\x0f \x01(\tR\rclearanceMode\x12)\n" +
+	"\x0emin_margin_pct\x18\x10 \x01(\x01H\x01R\fminMarginPct\x88\x01\x01\x126\n" +
+	"\frank_weights\x18\x11 \x01(\v2\x13.csa.v1.RankWeightsR\vrankWeights\x12\"\n" +
+	"\n" +
+	"mmr_lambda\x18\x12 \x01(\x01H\x02R\tmmrLambda\x88\x01\x01\x12)\n" +
+	"\x10include_preorder\x18\x13 \x01(\bR\x0fincludePreorderB\x10\n" +
+	"\x0e_recency_boostB\x11\n" +
+	"\x0f_min_margin_pctB\r\n" +
+	"\v_mmr_lambda\"\xbe\x01\n" +
+	"\vRankWeights\x12\x1f\n" +
+	"\bsemantic\x18\x01 \x01(\x01H\x00R\bsemantic\x88\x01\x01\x12\x15\n" +
+	"\x03eco\x18\x02 \x01(\x01H\x01R\x03eco\x88\x01\x01\x12 \n" +
+	"\tprice_fit\x18\x03 \x01(\x01H\x02R\bpriceFit\x88\x01\x01\x12#\n" +
+	"\n" +
+	"popularity\x18\x04 \x01(\x01H\x03R\n" +
+	"popularity\x88\x01\x01B\v\n" +
+	"\t_semanticB\x06\n" +
+	"\x04_ecoB\f\n" +
+	"\n" +
+	"_price_fitB\r\n" +
+	"\v_popularity\"\xf8\x02\n" +
+	"\rSearchRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\"\n" +
+	"\rmax_price_gbp\x18\x03 \x01(\x01R\vmaxPriceGbp\x12\"\n" +
+	"\rmin_eco_score\x18\x04 \x01(\x05R\vminEcoScore\x12\x1a\n" +
+	"\bcategory\x18\x05 \x01(\tR\bcategory\x12\x17\n" +
+	"\asort_by\x18\x06 \x01(\tR\x06sortBy\x12+\n" +
+	"\x11expand_duplicates\x18\a \x01(\bR\x10expandDuplicates\x12'\n" +
+	"\x0fdistance_metric\x18\b \x01(\tR\x0edistanceMetric\x12%\n" +
+	"\x0esearch_quality\x18\t \x01(\tR\rsearchQuality\x12\x16\n" +
+	"\x06within\x18\n" +
+	" \x01(\tR\x06within\x12)\n" +
+	"\afilters\x18\v \x01(\v2\x0f.csa.v1.FiltersR\afilters\"\xba\x02\n" +
+	"\x0eSearchResponse\x12\x1f\n" +
+	"\x04hits\x18\x01 \x03(\v2\v.csa.v1.HitR\x04hits\x12+\n" +
+	"\x06intent\x18\x02 \x01(\v2\x13.csa.v1.QueryIntentR\x06intent\x12+\n" +
+	"\x11routed_categories\x18\x03 \x03(\tR\x10routedCategories\x12\x1f\n" +
+	"\vresponse_id\x18\x04 \x01(\tR\n" +
+	"responseId\x12(\n" +
+	"\x04meta\x18\x05 \x01(\v2\x14.csa.v1.ResponseMetaR\x04meta\x12\x1a\n" +
+	"\bdegraded\x18\x06 \x01(\bR\bdegraded\x12F\n" +
+	"\x13session_constraints\x18\a \x03(\v2\x15.csa.v1.ConstraintRefR\x12sessionConstraints\"R\n" +
+	"\rConstraintRef\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04text\x18\x02 \x01(\tR\x04text\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\tR\texpiresAt\"y\n" +
+	"\vQueryIntent\x12\x14\n" +
+	"\x05route\x18\x01 \x01(\tR\x05route\x12\x1a\n" +
+	"\bcategory\x18\x02 \x01(\tR\bcategory\x12\"\n" +
+	"\rmax_price_gbp\x18\x03 \x01(\x01R\vmaxPriceGbp\x12\x14\n" +
+	"\x05color\x18\x04 \x01(\tR\x05color\"\x8d\b\n" +
+	"\x03Hit\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x01 \x01(\tR\tproductId\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x1c\n" +
+	"\tthumbnail\x18\x03 \x01(\tR\tthumbnail\x12\x1b\n" +
+	"\teco_score\x18\x04 \x01(\x05R\becoScore\x12\x1b\n" +
+	"\tprice_gbp\x18\x05 \x01(\x01R\bpriceGbp\x12\x1a\n" +
+	"\bdistance\x18\x06 \x01(\x01R\bdistance\x12\x1e\n" +
+	"\n" +
+	"similarity\x18\a \x01(\x01R\n" +
+	"similarity\x12\x16\n" +
+	"\x06reason\x18\b \x01(\tR\x06reason\x12 \n" +
+	"\tstock_qty\x18\t \x01(\x05H\x00R\bstockQty\x88\x01\x01\x120\n" +
+	"\bvariants\x18\n" +
+	" \x03(\v2\x14.csa.v1.VariantStockR\bvariants\x12%\n" +
+	"\x0esuggested_size\x18\v \x01(\tR\rsuggestedSize\x12*\n" +
+	"\x0efeedback_score\x18\f \x01(\x01H\x01R\rfeedbackScore\x88\x01\x01\x12/\n" +
+	"\n" +
+	"eco_labels\x18\r \x03(\v2\x10.csa.v1.EcoLabelR\tecoLabels\x12(\n" +
+	"\x10eco_score_source\x18\x0e \x01(\tR\x0eecoScoreSource\x12%\n" +
+	"\x0eorigin_country\x18\x0f \x01(\tR\roriginCountry\x12$\n" +
+	"\vshipping_km\x18\x10 \x01(\x01H\x02R\n" +
+	"shippingKm\x88\x01\x01\x12!\n" +
+	"\fmade_locally\x18\x11 \x01(\bR\vmadeLocally\x12>\n" +
+	"\x19carbon_adjusted_eco_score\x18\x12 \x01(\x05H\x03R\x16carbonAdjustedEcoScore\x88\x01\x01\x12\x1f\n" +
+	"\vnew_arrival\x18\x13 \x01(\bR\n" +
+	"newArrival\x12\x1c\n" +
+	"\tclearance\x18\x14 \x01(\bR\tclearance\x12.\n" +
+	"\x06scores\x18\x15 \x01(\v2\x16.csa.v1.ScoreBreakdownR\x06scores\x124\n" +
+	"\n" +
+	"duplicates\x18\x16 \x03(\v2\x14.csa.v1.DuplicateHitR\n" +
+	"duplicates\x12\x1c\n" +
+	"\tlifecycle\x18\x17 \x01(\tR\tlifecycle\x12\x19\n" +
+	"\bships_at\x18\x18 \x01(\tR\ashipsAt\x120\n" +
+	"\x11similarity_margin\x18\x19 \x01(\x01H\x04R\x10similarityMargin\x88\x01\x01B\f\n" +
+	"\n" +
+	"_stock_qtyB\x11\n" +
+	"\x0f_feedback_scoreB\x0e\n" +
+	"\f_shipping_kmB\x1c\n" +
+	"\x1a_carbon_adjusted_eco_scoreB\x14\n" +
+	"\x12_similarity_margin\"\xb2\x01\n" +
+	"\fVariantStock\x12\x1d\n" +
+	"\n" +
+	"variant_id\x18\x01 \x01(\tR\tvariantId\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x10\n" +
+	"\x03sku\x18\x03 \x01(\tR\x03sku\x12\x1f\n" +
+	"\bquantity\x18\x04 \x01(\x05H\x00R\bquantity\x88\x01\x01\x12\x19\n" +
+	"\bin_stock\x18\x05 \x01(\bR\ainStock\x12\x12\n" +
+	"\x04size\x18\x06 \x01(\tR\x04sizeB\v\n" +
+	"\t_quantity\".\n" +
+	"\bEcoLabel\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"\xc5\x01\n" +
+	"\x0eScoreBreakdown\x12\x1a\n" +
+	"\bsemantic\x18\x01 \x01(\x01R\bsemantic\x12\x15\n" +
+	"\x03eco\x18\x02 \x01(\x01H\x00R\x03eco\x88\x01\x01\x12 \n" +
+	"\tprice_fit\x18\x03 \x01(\x01H\x01R\bpriceFit\x88\x01\x01\x12#\n" +
+	"\n" +
+	"popularity\x18\x04 \x01(\x01H\x02R\n" +
+	"popularity\x88\x01\x01\x12\x14\n" +
+	"\x05total\x18\x05 \x01(\x01R\x05totalB\x06\n" +
+	"\x04_ecoB\f\n" +
+	"\n" +
+	"_price_fitB\r\n" +
+	"\v_popularity\"~\n" +
+	"\fDuplicateHit\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x01 \x01(\tR\tproductId\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x1c\n" +
+	"\tthumbnail\x18\x03 \x01(\tR\tthumbnail\x12\x1b\n" +
+	"\tprice_gbp\x18\x04 \x01(\x01R\bpriceGbp\"*\n" +
+	"\fResponseMeta\x12\x1a\n" +
+	"\bwarnings\x18\x01 \x03(\tR\bwarnings\"\xfb\x03\n" +
+	"\x15CompleteOutfitRequest\x12\x18\n" +
+	"\amission\x18\x01 \x01(\tR\amission\x12\x1d\n" +
+	"\n" +
+	"budget_gbp\x18\x02 \x01(\x01R\tbudgetGbp\x12\"\n" +
+	"\rmin_eco_score\x18\x03 \x01(\x05R\vminEcoScore\x12\x1d\n" +
+	"\n" +
+	"cart_slots\x18\x04 \x03(\tR\tcartSlots\x12\x17\n" +
+	"\acart_id\x18\x05 \x01(\tR\x06cartId\x12$\n" +
+	"\x0elimit_per_slot\x18\x06 \x01(\x05R\flimitPerSlot\x12\x1f\n" +
+	"\vprice_bands\x18\a \x01(\bR\n" +
+	"priceBands\x12\x14\n" +
+	"\x05query\x18\b \x01(\tR\x05query\x12+\n" +
+	"\x11expand_duplicates\x18\t \x01(\bR\x10expandDuplicates\x12'\n" +
+	"\x0fdistance_metric\x18\n" +
+	" \x01(\tR\x0edistanceMetric\x12)\n" +
+	"\afilters\x18\v \x01(\v2\x0f.csa.v1.FiltersR\afilters\x12'\n" +
+	"\x04gift\x18\f \x01(\v2\x13.csa.v1.GiftOptionsR\x04gift\x120\n" +
+	"\aweather\x18\r \x01(\v2\x16.csa.v1.WeatherRequestR\aweather\x12\x14\n" +
+	"\x05debug\x18\x0e \x01(\bR\x05debug\"k\n" +
+	"\vGiftOptions\x12\x1c\n" +
+	"\trecipient\x18\x01 \x01(\tR\trecipient\x12\x1a\n" +
+	"\boccasion\x18\x02 \x01(\tR\boccasion\x12\"\n" +
+	"\rwrap_cost_gbp\x18\x03 \x01(\x01R\vwrapCostGbp\"@\n" +
+	"\x0eWeatherRequest\x12\x1a\n" +
+	"\blocation\x18\x01 \x01(\tR\blocation\x12\x12\n" +
+	"\x04date\x18\x02 \x01(\tR\x04date\"\xcf\x05\n" +
+	"\x16CompleteOutfitResponse\x12\x18\n" +
+	"\amission\x18\x01 \x01(\tR\amission\x12\x1d\n" +
+	"\n" +
+	"budget_gbp\x18\x02 \x01(\x01R\tbudgetGbp\x12\"\n" +
+	"\rmin_eco_score\x18\x03 \x01(\x05R\vminEcoScore\x12\x1d\n" +
+	"\n" +
+	"cart_slots\x18\x04 \x03(\tR\tcartSlots\x12#\n" +
+	"\rmissing_slots\x18\x05 \x03(\tR\fmissingSlots\x12*\n" +
+	"\aresults\x18\x06 \x03(\v2\x10.csa.v1.SlotRecsR\aresults\x12'\n" +
+	"\x04gift\x18\a \x01(\v2\x13.csa.v1.GiftSummaryR\x04gift\x12'\n" +
+	"\x04cart\x18\b \x01(\v2\x13.csa.v1.CartSummaryR\x04cart\x12-\n" +
+	"\x06bundle\x18\t \x01(\v2\x15.csa.v1.BundleSummaryR\x06bundle\x12-\n" +
+	"\teco_grade\x18\n" +
+	" \x01(\v2\x10.csa.v1.EcoGradeR\becoGrade\x12,\n" +
+	"\x06intent\x18\v \x01(\v2\x14.csa.v1.OutfitIntentR\x06intent\x12,\n" +
+	"\bforecast\x18\f \x01(\v2\x10.csa.v1.ForecastR\bforecast\x12-\n" +
+	"\x05cache\x18\r \x01(\v2\x17.csa.v1.OutfitCacheInfoR\x05cache\x12(\n" +
+	"\x04meta\x18\x0e \x01(\v2\x14.csa.v1.ResponseMetaR\x04meta\x12;\n" +
+	"\x0eoptional_slots\x18\x0f \x03(\v2\x14.csa.v1.OptionalSlotR\roptionalSlots\x12F\n" +
+	"\x13session_constraints\x18\x10 \x03(\v2\x15.csa.v1.ConstraintRefR\x12sessionConstraints\"\x89\x02\n" +
+	"\bSlotRecs\x12\x12\n" +
+	"\x04slot\x18\x01 \x01(\tR\x04slot\x12\x1f\n" +
+	"\x04hits\x18\x02 \x03(\v2\v.csa.v1.HitR\x04hits\x12'\n" +
+	"\x05bands\x18\x03 \x03(\v2\x11.csa.v1.PriceBandR\x05bands\x12\x16\n" +
+	"\x06reason\x18\x04 \x01(\tR\x06reason\x126\n" +
+	"\n" +
+	"confidence\x18\x05 \x01(\v2\x16.csa.v1.SlotConfidenceR\n" +
+	"confidence\x123\n" +
+	"\tthreshold\x18\x06 \x01(\v2\x15.csa.v1.SlotThresholdR\tthreshold\x12\x1a\n" +
+	"\boptional\x18\a \x01(\bR\boptional\"\xb2\x01\n" +
+	"\fOptionalSlot\x12\x12\n" +
+	"\x04slot\x18\x01 \x01(\tR\x04slot\x12)\n" +
+	"\x10fill_probability\x18\x02 \x01(\x01R\x0ffillProbability\x12\x16\n" +
+	"\x06filled\x18\x03 \x01(\bR\x06filled\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\x01R\x05value\x12\x1d\n" +
+	"\n" +
+	"product_id\x18\x05 \x01(\tR\tproductId\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\"\xaa\x01\n" +
+	"\rSlotThreshold\x12%\n" +
+	"\x0emin_similarity\x18\x01 \x01(\x01R\rminSimilarity\x12\x16\n" +
+	"\x06source\x18\x02 \x01(\tR\x06source\x12\x18\n" +
+	"\adropped\x18\x03 \x01(\x05R\adropped\x12,\n" +
+	"\x0fbest_similarity\x18\x04 \x01(\x01H\x00R\x0ebestSimilarity\x88\x01\x01B\x12\n" +
+	"\x10_best_similarity\"\x94\x01\n" +
+	"\tPriceBand\x12\x12\n" +
+	"\x04band\x18\x01 \x01(\tR\x04band\x12\x1c\n" +
+	"\amin_gbp\x18\x02 \x01(\x01H\x00R\x06minGbp\x88\x01\x01\x12\x1c\n" +
+	"\amax_gbp\x18\x03 \x01(\x01H\x01R\x06maxGbp\x88\x01\x01\x12\x1f\n" +
+	"\x04hits\x18\x04 \x03(\v2\v.csa.v1.HitR\x04hitsB\n" +
+	"\n" +
+	"\b_min_gbpB\n" +
+	"\n" +
+	"\b_max_gbp\"v\n" +
+	"\x0eSlotConfidence\x12\x14\n" +
+	"\x05score\x18\x01 \x01(\x01R\x05score\x12\x10\n" +
+	"\x03low\x18\x02 \x01(\bR\x03low\x12\x14\n" +
+	"\x05match\x18\x03 \x01(\x01R\x05match\x12\x10\n" +
+	"\x03gap\x18\x04 \x01(\x01R\x03gap\x12\x14\n" +
+	"\x05slack\x18\x05 \x01(\x01R\x05slack\"\x84\x02\n" +
+	"\vGiftSummary\x122\n" +
+	"\x16wrap_cost_per_item_gbp\x18\x01 \x01(\x01R\x12wrapCostPerItemGbp\x12-\n" +
+	"\x13wrap_cost_total_gbp\x18\x02 \x01(\x01R\x10wrapCostTotalGbp\x12/\n" +
+	"\x14budget_for_items_gbp\x18\x03 \x01(\x01R\x11budgetForItemsGbp\x12-\n" +
+	"\x12message_suggestion\x18\x04 \x01(\tR\x11messageSuggestion\x122\n" +
+	"\x15message_from_template\x18\x05 \x01(\bR\x13messageFromTemplate\"\x96\x01\n" +
+	"\vCartSummary\x12\x17\n" +
+	"\acart_id\x18\x01 \x01(\tR\x06cartId\x12\x1f\n" +
+	"\vproduct_ids\x18\x02 \x03(\tR\n" +
+	"productIds\x12\x1b\n" +
+	"\tspent_gbp\x18\x03 \x01(\x01R\bspentGbp\x120\n" +
+	"\x14remaining_budget_gbp\x18\x04 \x01(\x01R\x12remainingBudgetGbp\"\xe4\x01\n" +
+	"\rBundleSummary\x12!\n" +
+	"\fsubtotal_gbp\x18\x01 \x01(\x01R\vsubtotalGbp\x12!\n" +
+	"\fdiscount_gbp\x18\x02 \x01(\x01R\vdiscountGbp\x12\x1b\n" +
+	"\ttotal_gbp\x18\x03 \x01(\x01R\btotalGbp\x126\n" +
+	"\tpromotion\x18\x04 \x01(\v2\x18.csa.v1.AppliedPromotionR\tpromotion\x128\n" +
+	"\n" +
+	"suggestion\x18\x05 \x01(\v2\x18.csa.v1.BundleSuggestionR\n" +
+	"suggestion\"T\n" +
+	"\x10AppliedPromotion\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\tR\x04code\x12\x1c\n" +
+	"\tautomatic\x18\x03 \x01(\bR\tautomatic\"\xa9\x01\n" +
+	"\x10BundleSuggestion\x12\x1d\n" +
+	"\x03add\x18\x01 \x01(\v2\v.csa.v1.HitR\x03add\x126\n" +
+	"\tpromotion\x18\x02 \x01(\v2\x18.csa.v1.AppliedPromotionR\tpromotion\x12!\n" +
+	"\fdiscount_gbp\x18\x03 \x01(\x01R\vdiscountGbp\x12\x1b\n" +
+	"\ttotal_gbp\x18\x04 \x01(\x01R\btotalGbp\"|\n" +
+	"\bEcoGrade\x12\x14\n" +
+	"\x05grade\x18\x01 \x01(\tR\x05grade\x12\x14\n" +
+	"\x05score\x18\x02 \x01(\x01R\x05score\x12\x14\n" +
+	"\x05items\x18\x03 \x01(\x05R\x05items\x12\x16\n" +
+	"\x06capped\x18\x04 \x01(\bR\x06capped\x12\x16\n" +
+	"\x06rubric\x18\x05 \x01(\tR\x06rubric\"\xfa\x01\n" +
+	"\fOutfitIntent\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\x18\n" +
+	"\amission\x18\x02 \x01(\tR\amission\x12\x14\n" +
+	"\x05slots\x18\x03 \x03(\tR\x05slots\x12\x1d\n" +
+	"\n" +
+	"budget_gbp\x18\x04 \x01(\x01R\tbudgetGbp\x12\"\n" +
+	"\rmin_eco_score\x18\x05 \x01(\x05R\vminEcoScore\x12\x1d\n" +
+	"\n" +
+	"cart_slots\x18\x06 \x03(\tR\tcartSlots\x12\x14\n" +
+	"\x05color\x18\a \x01(\tR\x05color\x12\x1a\n" +
+	"\bmaterial\x18\b \x01(\tR\bmaterial\x12\x12\n" +
+	"\x04size\x18\t \x01(\tR\x04size\"\xed\x01\n" +
+	"\bForecast\x12\x1a\n" +
+	"\blocation\x18\x01 \x01(\tR\blocation\x12\x12\n" +
+	"\x04date\x18\x02 \x01(\tR\x04date\x12\x1c\n" +
+	"\n" +
+	"temp_min_c\x18\x03 \x01(\x01R\btempMinC\x12\x1c\n" +
+	"\n" +
+	"temp_max_c\x18\x04 \x01(\x01R\btempMaxC\x12\x1b\n" +
+	"\tprecip_mm\x18\x05 \x01(\x01R\bprecipMm\x12#\n" +
+	"\rprecip_chance\x18\x06 \x01(\x05R\fprecipChance\x12\x19\n" +
+	"\bwind_kph\x18\a \x01(\x01R\awindKph\x12\x18\n" +
+	"\asummary\x18\b \x01(\tR\asummary\"l\n" +
+	"\x0fOutfitCacheInfo\x12\x1f\n" +
+	"\vage_seconds\x18\x01 \x01(\x05R\n" +
+	"ageSeconds\x12\x14\n" +
+	"\x05stale\x18\x02 \x01(\bR\x05stale\x12\"\n" +
+	"\frevalidating\x18\x03 \x01(\bR\frevalidating\"N\n" +
+	"\x14ExplainOutfitRequest\x126\n" +
+	"\x06outfit\x18\x01 \x01(\v2\x1e.csa.v1.CompleteOutfitResponseR\x06outfit\"1\n" +
+	"\x15ExplainOutfitResponse\x12\x18\n" +
+	"\abullets\x18\x01 \x03(\tR\abullets\"k\n" +
+	"\x14IndexProductsRequest\x12\x12\n" +
+	"\x04mode\x18\x01 \x01(\tR\x04mode\x12\x1d\n" +
+	"\n" +
+	"max_tokens\x18\x02 \x01(\x05R\tmaxTokens\x12 \n" +
+	"\fmax_cost_usd\x18\x03 \x01(\x01R\n" +
+	"maxCostUsd\"\xbe\x01\n" +
+	"\x15IndexProductsResponse\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x12\n" +
+	"\x04mode\x18\x02 \x01(\tR\x04mode\x12\x14\n" +
+	"\x05since\x18\x03 \x01(\tR\x05since\x12\x18\n" +
+	"\afetched\x18\x04 \x01(\x05R\afetched\x12\x18\n" +
+	"\aindexed\x18\x05 \x01(\x05R\aindexed\x12+\n" +
+	"\x06budget\x18\x06 \x01(\v2\x13.csa.v1.IndexBudgetR\x06budget\"\xa2\x01\n" +
+	"\vIndexBudget\x12\x1d\n" +
+	"\n" +
+	"max_tokens\x18\x01 \x01(\x05R\tmaxTokens\x12\x16\n" +
+	"\x06tokens\x18\x02 \x01(\x05R\x06tokens\x12\x19\n" +
+	"\bcost_usd\x18\x03 \x01(\x01R\acostUsd\x12\x1c\n" +
+	"\tremaining\x18\x04 \x01(\x05R\tremaining\x12#\n" +
+	"\rremaining_ids\x18\x05 \x03(\tR\fremainingIds2\xb5\x02\n" +
+	"\rShoppingAgent\x127\n" +
+	"\x06Search\x12\x15.csa.v1.SearchRequest\x1a\x16.csa.v1.SearchResponse\x12O\n" +
+	"\x0eCompleteOutfit\x12\x1d.csa.v1.CompleteOutfitRequest\x1a\x1e.csa.v1.CompleteOutfitResponse\x12L\n" +
+	"\rExplainOutfit\x12\x1c.csa.v1.ExplainOutfitRequest\x1a\x1d.csa.v1.ExplainOutfitResponse\x12L\n" +
+	"\rIndexProducts\x12\x1c.csa.v1.IndexProductsRequest\x1a\x1d.csa.v1.IndexProductsResponseBNZLgithub.com/yourusername/contextual-shopping-agent/agent/internal/csapb;csapbb\x06proto3"
+
+var (
+	file_proto_csa_v1_agent_proto_rawDescOnce sync.Once
+	file_proto_csa_v1_agent_proto_rawDescData []byte
+)
+
+func file_proto_csa_v1_agent_proto_rawDescGZIP() []byte {
+	file_proto_csa_v1_agent_proto_rawDescOnce.Do(func() {
+		file_proto_csa_v1_agent_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_csa_v1_agent_proto_rawDesc), len(file_proto_csa_v1_agent_proto_rawDesc)))
+	})
+	return file_proto_csa_v1_agent_proto_rawDescData
+}
+
+var file_proto_csa_v1_agent_proto_msgTypes = make([]protoimpl.MessageInfo, 35)
+var file_proto_csa_v1_agent_proto_goTypes = []any{
+	(*Filters)(nil),                // 0: csa.v1.Filters
+	(*RankWeights)(nil),            // 1: csa.v1.RankWeights
+	(*SearchRequest)(nil),          // 2: csa.v1.SearchRequest
+	(*SearchResponse)(nil),         // 3: csa.v1.SearchResponse
+	(*ConstraintRef)(nil),          // 4: csa.v1.ConstraintRef
+	(*QueryIntent)(nil),            // 5: csa.v1.QueryIntent
+	(*Hit)(nil),                    // 6: csa.v1.Hit
+	(*VariantStock)(nil),           // 7: csa.v1.VariantStock
+	(*EcoLabel)(nil),               // 8: csa.v1.EcoLabel
+	(*ScoreBreakdown)(nil),         // 9: csa.v1.ScoreBreakdown
+	(*DuplicateHit)(nil),           // 10: csa.v1.DuplicateHit
+	(*ResponseMeta)(nil),           // 11: csa.v1.ResponseMeta
+	(*CompleteOutfitRequest)(nil),  // 12: csa.v1.CompleteOutfitRequest
+	(*GiftOptions)(nil),            // 13: csa.v1.GiftOptions
+	(*WeatherRequest)(nil),         // 14: csa.v1.WeatherRequest
+	(*CompleteOutfitResponse)(nil), // 15: csa.v1.CompleteOutfitResponse
+	(*SlotRecs)(nil),               // 16: csa.v1.SlotRecs
+	(*OptionalSlot)(nil),           // 17: csa.v1.OptionalSlot
+	(*SlotThreshold)(nil),          // 18: csa.v1.SlotThreshold
+	(*PriceBand)(nil),              // 19: csa.v1.PriceBand
+	(*SlotConfidence)(nil),         // 20: csa.v1.SlotConfidence
+	(*GiftSummary)(nil),            // 21: csa.v1.GiftSummary
+	(*CartSummary)(nil),            // 22: csa.v1.CartSummary
+	(*BundleSummary)(nil),          // 23: csa.v1.BundleSummary
+	(*AppliedPromotion)(nil),       // 24: csa.v1.AppliedPromotion
+	(*BundleSuggestion)(nil),       // 25: csa.v1.BundleSuggestion
+	(*EcoGrade)(nil),               // 26: csa.v1.EcoGrade
+	(*OutfitIntent)(nil),           // 27: csa.v1.OutfitIntent
+	(*Forecast)(nil),               // 28: csa.v1.Forecast
+	(*OutfitCacheInfo)(nil),        // 29: csa.v1.OutfitCacheInfo
+	(*ExplainOutfitRequest)(nil),   // 30: csa.v1.ExplainOutfitRequest
+	(*ExplainOutfitResponse)(nil),  // 31: csa.v1.ExplainOutfitResponse
+	(*IndexProductsRequest)(nil),   // 32: csa.v1.IndexProductsRequest
+	(*IndexProductsResponse)(nil),  // 33: csa.v1.IndexProductsResponse
+	(*IndexBudget)(nil),            // 34: csa.v1.IndexBudget
+	(*structpb.Struct)(nil),        // 35: google.protobuf.Struct
+}
+var file_proto_csa_v1_agent_proto_depIdxs = []int32{
+	35, // 0: csa.v1.Filters.attributes:type_name -> google.protobuf.Struct
+	1,  // 1: csa.v1.Filters.rank_weights:type_name -> csa.v1.RankWeights
+	0,  // 2: csa.v1.SearchRequest.filters:type_name -> csa.v1.Filters
+	6,  // 3: csa.v1.SearchResponse.hits:type_name -> csa.v1.Hit
+	5,  // 4: csa.v1.SearchResponse.intent:type_name -> csa.v1.QueryIntent
+	11, // 5: csa.v1.SearchResponse.meta:type_name -> csa.v1.ResponseMeta
+	4,  // 6: csa.v1.SearchResponse.session_constraints:type_name -> csa.v1.ConstraintRef
+	7,  // 7: csa.v1.Hit.variants:type_name -> csa.v1.VariantStock
+	8,  // 8: csa.v1.Hit.eco_labels:type_name -> csa.v1.EcoLabel
+	9,  // 9: csa.v1.Hit.scores:type_name -> csa.v1.ScoreBreakdown
+	10, // 10: csa.v1.Hit.duplicates:type_name -> csa.v1.DuplicateHit
+	0,  // 11: csa.v1.CompleteOutfitRequest.filters:type_name -> csa.v1.Filters
+	13, // 12: csa.v1.CompleteOutfitRequest.gift:type_name -> csa.v1.GiftOptions
+	14, // 13: csa.v1.CompleteOutfitRequest.weather:type_name -> csa.v1.WeatherRequest
+	16, // 14: csa.v1.CompleteOutfitResponse.results:type_name -> csa.v1.SlotRecs
+	21, // 15: csa.v1.CompleteOutfitResponse.gift:type_name -> csa.v1.GiftSummary
+	22, // 16: csa.v1.CompleteOutfitResponse.cart:type_name -> csa.v1.CartSummary
+	23, // 17: csa.v1.CompleteOutfitResponse.bundle:type_name -> csa.v1.BundleSummary
+	26, // 18: csa.v1.CompleteOutfitResponse.eco_grade:type_name -> csa.v1.EcoGrade
+	27, // 19: csa.v1.CompleteOutfitResponse.intent:type_name -> csa.v1.OutfitIntent
+	28, // 20: csa.v1.CompleteOutfitResponse.forecast:type_name -> csa.v1.Forecast
+	29, // 21: csa.v1.CompleteOutfitResponse.cache:type_name -> csa.v1.OutfitCacheInfo
+	11, // 22: csa.v1.CompleteOutfitResponse.meta:type_name -> csa.v1.ResponseMeta
+	17, // 23: csa.v1.CompleteOutfitResponse.optional_slots:type_name -> csa.v1.OptionalSlot
+	4,  // 24: csa.v1.CompleteOutfitResponse.session_constraints:type_name -> csa.v1.ConstraintRef
+	6,  // 25: csa.v1.SlotRecs.hits:type_name -> csa.v1.Hit
+	19, // 26: csa.v1.SlotRecs.bands:type_name -> csa.v1.PriceBand
+	20, // 27: csa.v1.SlotRecs.confidence:type_name -> csa.v1.SlotConfidence
+	18, // 28: csa.v1.SlotRecs.threshold:type_name -> csa.v1.SlotThreshold
+	6,  // 29: csa.v1.PriceBand.hits:type_name -> csa.v1.Hit
+	24, // 30: csa.v1.BundleSummary.promotion:type_name -> csa.v1.AppliedPromotion
+	25, // 31: csa.v1.BundleSummary.suggestion:type_name -> csa.v1.BundleSuggestion
+	6,  // 32: csa.v1.BundleSuggestion.add:type_name -> csa.v1.Hit
+	24, // 33: csa.v1.BundleSuggestion.promotion:type_name -> csa.v1.AppliedPromotion
+	15, // 34: csa.v1.ExplainOutfitRequest.outfit:type_name -> csa.v1.CompleteOutfitResponse
+	34, // 35: csa.v1.IndexProductsResponse.budget:type_name -> csa.v1.IndexBudget
+	2,  // 36: csa.v1.ShoppingAgent.Search:input_type -> csa.v1.SearchRequest
+	12, // 37: csa.v1.ShoppingAgent.CompleteOutfit:input_type -> csa.v1.CompleteOutfitRequest
+	30, // 38: csa.v1.ShoppingAgent.ExplainOutfit:input_type -> csa.v1.ExplainOutfitRequest
+	32, // 39: csa.v1.ShoppingAgent.IndexProducts:input_type -> csa.v1.IndexProductsRequest
+	3,  // 40: csa.v1.ShoppingAgent.Search:output_type -> csa.v1.SearchResponse
+	15, // 41: csa.v1.ShoppingAgent.CompleteOutfit:output_type -> csa.v1.CompleteOutfitResponse
+	31, // 42: csa.v1.ShoppingAgent.ExplainOutfit:output_type -> csa.v1.ExplainOutfitResponse
+	33, // 43: csa.v1.ShoppingAgent.IndexProducts:output_type -> csa.v1.IndexProductsResponse
+	40, // [40:44] is the sub-list for method output_type
+	36, // [36:40] is the sub-list for method input_type
+	36, // [36:36] is the sub-list for extension type_name
+	36, // [36:36] is the sub-list for extension extendee
+	0,  // [0:36] is the sub-list for field type_name
+}
+
+func init() { file_proto_csa_v1_agent_proto_init() }
+func file_proto_csa_v1_agent_proto_init() {
+	if File_proto_csa_v1_agent_proto != nil {
+		return
+	}
+	file_proto_csa_v1_agent_proto_msgTypes[0].OneofWrappers = []any{}
+	file_proto_csa_v1_agent_proto_msgTypes[1].OneofWrappers = []any{}
+	file_proto_csa_v1_agent_proto_msgTypes[6].OneofWrappers = []any{}
+	file_proto_csa_v1_agent_proto_msgTypes[7].OneofWrappers = []any{}
+	file_proto_csa_v1_agent_proto_msgTypes[9].OneofWrappers = []any{}
+	file_proto_csa_v1_agent_proto_msgTypes[18].OneofWrappers = []any{}
+	file_proto_csa_v1_agent_proto_msgTypes[19].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_csa_v1_agent_proto_rawDesc), len(file_proto_csa_v1_agent_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   35,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_csa_v1_agent_proto_goTypes,
+		DependencyIndexes: file_proto_csa_v1_agent_proto_depIdxs,
+		MessageInfos:      file_proto_csa_v1_agent_proto_msgTypes,
+	}.Build()
+	File_proto_csa_v1_agent_proto = out.File
+	file_proto_csa_v1_agent_proto_goTypes = nil
+	file_proto_csa_v1_agent_proto_depIdxs = nil
+}
@@ -0,0 +1,250 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v3.5.1-go
+// source: proto/csa/v1/agent.proto
+
+// gRPC interface of the contextual shopping agent. Each call runs the HTTP
+// endpoint named in its comment, with the same auth, validation, caching
+// and logging, so messages mirror the JSON bodies field for field. Fields
+// left out of a message are the ones gRPC callers haven't needed yet.
+//
+// Regenerate the Go code with `go generate ./...` from agent/.
+
+package csapb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ShoppingAgent_Search_FullMethodName         = "/csa.v1.ShoppingAgent/Search"
+	ShoppingAgent_CompleteOutfit_FullMethodName = "/csa.v1.ShoppingAgent/CompleteOutfit"
+	ShoppingAgent_ExplainOutfit_FullMethodName  = "/csa.v1.ShoppingAgent/ExplainOutfit"
+	ShoppingAgent_IndexProducts_FullMethodName  = "/csa.v1.ShoppingAgent/IndexProducts"
+)
+
+// ShoppingAgentClient is the client API for ShoppingAgent service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ShoppingAgentClient interface {
+	// POST /search
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error)
+	// POST /complete-outfit
+	CompleteOutfit(ctx context.Context, in *CompleteOutfitRequest, opts ...grpc.CallOption) (*CompleteOutfitResponse, error)
+	// POST /explain-outfit
+	ExplainOutfit(ctx context.Context, in *ExplainOutfitRequest, opts ...grpc.CallOption) (*ExplainOutfitResponse, error)
+	// POST /index-products
+	IndexProducts(ctx context.Context, in *IndexProductsRequest, opts ...grpc.CallOption) (*IndexProductsResponse, error)
+}
+
+type shoppingAgentClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewShoppingAgentClient(cc grpc.ClientConnInterface) ShoppingAgentClient {
+	return &shoppingAgentClient{cc}
+}
+
+func (c *shoppingAgentClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (*SearchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SearchResponse)
+	err := c.cc.Invoke(ctx, ShoppingAgent_Search_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shoppingAgentClient) CompleteOutfit(ctx context.Context, in *CompleteOutfitRequest, opts ...grpc.CallOption) (*CompleteOutfitResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CompleteOutfitResponse)
+	err := c.cc.Invoke(ctx, ShoppingAgent_CompleteOutfit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shoppingAgentClient) ExplainOutfit(ctx context.Context, in *ExplainOutfitRequest, opts ...grpc.CallOption) (*ExplainOutfitResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExplainOutfitResponse)
+	err := c.cc.Invoke(ctx, ShoppingAgent_ExplainOutfit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shoppingAgentClient) IndexProducts(ctx context.Context, in *IndexProductsRequest, opts ...grpc.CallOption) (*IndexProductsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IndexProductsResponse)
+	err := c.cc.Invoke(ctx, ShoppingAgent_IndexProducts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ShoppingAgentServer is the server API for ShoppingAgent service.
+// All implementations must embed UnimplementedShoppingAgentServer
+// for forward compatibility.
+type ShoppingAgentServer interface {
+	// POST /search
+	Search(context.Context, *SearchRequest) (*SearchResponse, error)
+	// POST /complete-outfit
+	CompleteOutfit(context.Context, *CompleteOutfitRequest) (*CompleteOutfitResponse, error)
+	// POST /explain-outfit
+	ExplainOutfit(context.Context, *ExplainOutfitRequest) (*ExplainOutfitResponse, error)
+	// POST /index-products
+	IndexProducts(context.Context, *IndexProductsRequest) (*IndexProductsResponse, error)
+	mustEmbedUnimplementedShoppingAgentServer()
+}
+
+// UnimplementedShoppingAgentServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedShoppingAgentServer struct{}
+
+func (UnimplementedShoppingAgentServer) Search(context.Context, *SearchRequest) (*SearchResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedShoppingAgentServer) CompleteOutfit(context.Context, *CompleteOutfitRequest) (*CompleteOutfitResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method CompleteOutfit not implemented")
+}
+func (UnimplementedShoppingAgentServer) ExplainOutfit(context.Context, *ExplainOutfitRequest) (*ExplainOutfitResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ExplainOutfit not implemented")
+}
+func (UnimplementedShoppingAgentServer) IndexProducts(context.Context, *IndexProductsRequest) (*IndexProductsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method IndexProducts not implemented")
+}
+func (UnimplementedShoppingAgentServer) mustEmbedUnimplementedShoppingAgentServer() {}
+func (UnimplementedShoppingAgentServer) testEmbeddedByValue()                       {}
+
+// UnsafeShoppingAgentServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ShoppingAgentServer will
+// result in compilation errors.
+type UnsafeShoppingAgentServer interface {
+	mustEmbedUnimplementedShoppingAgentServer()
+}
+
+func RegisterShoppingAgentServer(s grpc.ServiceRegistrar, srv ShoppingAgentServer) {
+	// If the following call panics, it indicates UnimplementedShoppingAgentServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ShoppingAgent_ServiceDesc, srv)
+}
+
+func _ShoppingAgent_Search_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShoppingAgentServer).Search(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShoppingAgent_Search_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShoppingAgentServer).Search(ctx, req.(*SearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShoppingAgent_CompleteOutfit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompleteOutfitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShoppingAgentServer).CompleteOutfit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShoppingAgent_CompleteOutfit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShoppingAgentServer).CompleteOutfit(ctx, req.(*CompleteOutfitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShoppingAgent_ExplainOutfit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExplainOutfitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShoppingAgentServer).ExplainOutfit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShoppingAgent_ExplainOutfit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShoppingAgentServer).ExplainOutfit(ctx, req.(*ExplainOutfitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShoppingAgent_IndexProducts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IndexProductsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShoppingAgentServer).IndexProducts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ShoppingAgent_IndexProducts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShoppingAgentServer).IndexProducts(ctx, req.(*IndexProductsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ShoppingAgent_ServiceDesc is the grpc.ServiceDesc for ShoppingAgent service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ShoppingAgent_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "csa.v1.ShoppingAgent",
+	HandlerType: (*ShoppingAgentServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Search",
+			Handler:    _ShoppingAgent_Search_Handler,
+		},
+		{
+			MethodName: "CompleteOutfit",
+			Handler:    _ShoppingAgent_CompleteOutfit_Handler,
+		},
+		{
+			MethodName: "ExplainOutfit",
+			Handler:    _ShoppingAgent_ExplainOutfit_Handler,
+		},
+		{
+			MethodName: "IndexProducts",
+			Handler:    _ShoppingAgent_IndexProducts_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/csa/v1/agent.proto",
+}
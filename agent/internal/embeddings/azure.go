@@ -0,0 +1,32 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Azure calls the embeddings endpoint of an Azure OpenAI deployment. The
+// deployment, not the request, decides the model.
+type Azure struct {
+	Endpoint   string // e.g. https://csa-eu.openai.azure.com
+	APIKey     string
+	APIVersion string
+	Deployment string
+	Client     *http.Client // nil = http.DefaultClient
+	// Usage is as OpenAI.Usage; model is "" when the response names none.
+	Usage func(ctx context.Context, model string, tokens int)
+}
+
+func (a Azure) Name() string { return "azure:" + a.Deployment }
+
+func (a Azure) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	u := fmt.Sprintf("%s/openai/deployments/%s/embeddings?api-version=%s",
+		a.Endpoint, url.PathEscape(a.Deployment), url.QueryEscape(a.APIVersion))
+	embs, err := postEmbeddings(ctx, a.Client, u, "api-key", a.APIKey, "", texts, a.Usage)
+	if err != nil {
+		return nil, fmt.Errorf("azure %w", err)
+	}
+	return embs, nil
+}
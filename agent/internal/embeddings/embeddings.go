@@ -0,0 +1,56 @@
+// Package embeddings turns product cards and queries into vectors: the
+// Provider interface the agent embeds through, an OpenAI-compatible HTTP
+// client, and the local ONNX model (built with -tags onnx). It holds no
+// configuration of its own; callers build providers from theirs.
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+)
+
+// Dim is the width of product_embeddings.embedding.
+const Dim = 1536
+
+// Provider embeds texts in one call; results are in input order.
+type Provider interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+	// Name identifies the model, for traces and logs.
+	Name() string
+}
+
+// Model is an in-process Provider that holds resources until closed.
+type Model interface {
+	Provider
+	Close()
+}
+
+// Pad zero-pads vectors narrower than Dim to the column width, in place.
+// Padding leaves L2 and cosine distances unchanged, so a 384-dim MiniLM
+// model works against the vector(1536) column and index. Vectors from
+// different models are still not comparable.
+func Pad(embs [][]float64) error {
+	for i, e := range embs {
+		if len(e) > Dim {
+			return fmt.Errorf("model produces %d dims; the embedding column holds %d", len(e), Dim)
+		}
+		padded := make([]float64, Dim)
+		copy(padded, e)
+		embs[i] = padded
+	}
+	return nil
+}
+
+// Literal formats v as a pgvector literal, e.g. [0.1,0.2].
+func Literal(v []float64) string {
+	buf := bytes.NewBufferString("[")
+	for i, x := range v {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(fmt.Sprintf("%g", x))
+	}
+	buf.WriteByte(']')
+	return buf.String()
+}
@@ -0,0 +1,52 @@
+package embeddings
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"regexp"
+	"strings"
+)
+
+var fakeWord = regexp.MustCompile(`[a-z]+(?:-[a-z]+)?`)
+
+// Fake hashes each word into one of the vector's dimensions, so texts
+// sharing words land close together. It is deterministic, free and needs no
+// network, which is all the sandbox catalog and tests need.
+type Fake struct{}
+
+func (Fake) Name() string { return "fake" }
+
+func (Fake) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	out := make([][]float64, len(texts))
+	for i, t := range texts {
+		out[i] = FakeVector(t)
+	}
+	return out, nil
+}
+
+// FakeVector is Fake's unit vector for text. Text without words gets the
+// first axis.
+func FakeVector(text string) []float64 {
+	v := make([]float64, Dim)
+	for _, w := range fakeWord.FindAllString(strings.ToLower(text), -1) {
+		h := fnv.New32a()
+		h.Write([]byte(w))
+		v[h.Sum32()%Dim]++
+	}
+	norm := 0.0
+	for _, x := range v {
+		norm += x * x
+	}
+	if norm == 0 {
+		v[0], norm = 1, 1
+	}
+	norm = math.Sqrt(norm)
+	for j := range v {
+		v[j] /= norm
+	}
+	return v
+}
@@ -0,0 +1,47 @@
+package embeddings
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func cosine(a, b []float64) float64 {
+	dot := 0.0
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return dot
+}
+
+func TestFakeVector(t *testing.T) {
+	for _, text := range []string{"linen chinos", "Navy wool-blend coat, size M", ""} {
+		v := FakeVector(text)
+		if len(v) != Dim {
+			t.Fatalf("%q: %d dims, want %d", text, len(v), Dim)
+		}
+		if n := math.Sqrt(cosine(v, v)); math.Abs(n-1) > 1e-9 {
+			t.Errorf("%q: norm %v, want 1", text, n)
+		}
+		if !reflect.DeepEqual(v, FakeVector(text)) {
+			t.Errorf("%q: not deterministic", text)
+		}
+	}
+
+	chinos, trousers, boots := FakeVector("linen chinos"), FakeVector("linen trousers"), FakeVector("rain boots")
+	if cosine(chinos, trousers) <= cosine(chinos, boots) {
+		t.Error("texts sharing a word should be closer than texts sharing none")
+	}
+	if !reflect.DeepEqual(FakeVector("Linen CHINOS!"), chinos) {
+		t.Error("case and punctuation should not change the vector")
+	}
+}
+
+func TestFakeHonoursCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := (Fake{}).Embed(ctx, []string{"tee"}); err == nil {
+		t.Error("Embed succeeded with a cancelled context")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !onnx
+
+package embeddings
+
+import (
+	"errors"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+)
+
+// NewLocal always fails here: the local backend needs cgo and the
+// onnxruntime library, so it is only compiled into binaries built with
+// -tags onnx.
+func NewLocal(config.Embed) (Model, error) {
+	return nil, errors.New("local embeddings need a binary built with -tags onnx")
+}
@@ -0,0 +1,124 @@
+//go:build onnx
+
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+)
+
+// Sequence limit for the local model; MiniLM was trained on 256 tokens and
+// product cards fit comfortably.
+const localEmbedMaxTokens = 256
+
+// onnxEmbedder runs a BERT-style SentenceTransformers export (inputs
+// input_ids, attention_mask, token_type_ids; output last_hidden_state) on
+// CPU and mean-pools the token vectors into one L2-normalized embedding.
+type onnxEmbedder struct {
+	model   string
+	tok     *wordPiece
+	session *ort.DynamicAdvancedSession
+
+	mu sync.Mutex // the session is not safe for concurrent Run calls
+}
+
+// NewLocal loads the ONNX model in c.ModelDir.
+func NewLocal(c config.Embed) (Model, error) {
+	tok, err := loadWordPiece(filepath.Join(c.ModelDir, "vocab.txt"), localEmbedMaxTokens)
+	if err != nil {
+		return nil, fmt.Errorf("local embed tokenizer: %w", err)
+	}
+
+	ort.SetSharedLibraryPath(c.RuntimeLib)
+	if !ort.IsInitialized() {
+		if err := ort.InitializeEnvironment(); err != nil {
+			return nil, fmt.Errorf("onnxruntime: %w", err)
+		}
+	}
+	opts, err := ort.NewSessionOptions()
+	if err != nil {
+		return nil, err
+	}
+	defer opts.Destroy()
+	if c.Threads > 0 {
+		if err := opts.SetIntraOpNumThreads(c.Threads); err != nil {
+			return nil, err
+		}
+	}
+
+	session, err := ort.NewDynamicAdvancedSession(filepath.Join(c.ModelDir, "model.onnx"),
+		[]string{"input_ids", "attention_mask", "token_type_ids"}, []string{"last_hidden_state"}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("load local embed model: %w", err)
+	}
+	return &onnxEmbedder{model: filepath.Base(c.ModelDir), tok: tok, session: session}, nil
+}
+
+func (e *onnxEmbedder) Name() string { return e.model }
+
+func (e *onnxEmbedder) Close() {
+	e.session.Destroy()
+	ort.DestroyEnvironment()
+}
+
+func (e *onnxEmbedder) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// pad every sequence to the longest one in the batch
+	encoded := make([][]int64, len(texts))
+	seqLen := 0
+	for i, t := range texts {
+		encoded[i] = e.tok.encode(t)
+		seqLen = max(seqLen, len(encoded[i]))
+	}
+	n := len(texts) * seqLen
+	ids, mask, types := make([]int64, n), make([]int64, n), make([]int64, n)
+	for i, enc := range encoded {
+		for j, id := range enc {
+			ids[i*seqLen+j] = id
+			mask[i*seqLen+j] = 1
+		}
+	}
+
+	shape := ort.NewShape(int64(len(texts)), int64(seqLen))
+	inputs := make([]ort.Value, 0, 3)
+	defer func() {
+		for _, v := range inputs {
+			v.Destroy()
+		}
+	}()
+	for _, data := range [][]int64{ids, mask, types} {
+		t, err := ort.NewTensor(shape, data)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, t)
+	}
+
+	outputs := []ort.Value{nil}
+	e.mu.Lock()
+	err := e.session.Run(inputs, outputs)
+	e.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("local embed: %w", err)
+	}
+	defer outputs[0].Destroy()
+
+	hidden, ok := outputs[0].(*ort.Tensor[float32])
+	if !ok {
+		return nil, fmt.Errorf("local embed: unexpected output type %T", outputs[0])
+	}
+	dims := hidden.GetShape()
+	if len(dims) != 3 {
+		return nil, fmt.Errorf("local embed: unexpected output shape %v", dims)
+	}
+	return meanPool(hidden.GetData(), mask, len(texts), seqLen, int(dims[2])), nil
+}
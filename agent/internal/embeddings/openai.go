@@ -0,0 +1,89 @@
+package embeddings
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// OpenAI calls the /embeddings endpoint of OpenAI or a compatible API.
+type OpenAI struct {
+	BaseURL string // e.g. https://api.openai.com/v1
+	APIKey  string
+	Model   string
+	Client  *http.Client // nil = http.DefaultClient
+	// Usage, when set, is told the tokens each call consumed, as the
+	// response's usage reports them.
+	Usage func(ctx context.Context, model string, tokens int)
+}
+
+func (o OpenAI) Name() string { return o.Model }
+
+func (o OpenAI) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	return postEmbeddings(ctx, o.Client, o.BaseURL+"/embeddings", "Authorization", "Bearer "+o.APIKey, o.Model, texts, o.Usage)
+}
+
+// postEmbeddings sends texts to an OpenAI-style /embeddings URL, with the
+// API key in header, and returns the vectors in input order. usage, when
+// not nil, gets the tokens the response reports.
+func postEmbeddings(ctx context.Context, client *http.Client, url, header, key, model string, texts []string, usage func(context.Context, string, int)) ([][]float64, error) {
+	body := map[string]any{"input": texts}
+	if model != "" {
+		body["model"] = model
+	}
+	b, _ := json.Marshal(body)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(header, key)
+	req.Header.Set("Content-Type", "application/json")
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		raw, _ := io.ReadAll(res.Body)
+		return nil, fmt.Errorf("openai error: %s", string(raw))
+	}
+
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+		Model string `json:"model"`
+		Usage struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+	if usage != nil && parsed.Usage.PromptTokens > 0 {
+		usage(ctx, cmp.Or(parsed.Model, model), parsed.Usage.PromptTokens)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(parsed.Data))
+	}
+
+	out := make([][]float64, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(out) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		out[d.Index] = d.Embedding
+	}
+	return out, nil
+}
@@ -0,0 +1,144 @@
+package embeddings
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// wordPiece is the uncased BERT tokenizer used by MiniLM-style
+// SentenceTransformers models: lowercase, strip accents, split on
+// whitespace and punctuation, then greedy longest-match against vocab.txt.
+type wordPiece struct {
+	vocab  map[string]int64
+	unk    int64
+	cls    int64
+	sep    int64
+	maxLen int // including [CLS] and [SEP]
+}
+
+const maxWordChars = 100
+
+func loadWordPiece(path string, maxLen int) (*wordPiece, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	wp := &wordPiece{vocab: map[string]int64{}, maxLen: maxLen}
+	sc := bufio.NewScanner(f)
+	for id := int64(0); sc.Scan(); id++ {
+		wp.vocab[strings.TrimRight(sc.Text(), "\r")] = id
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	for tok, dst := range map[string]*int64{"[UNK]": &wp.unk, "[CLS]": &wp.cls, "[SEP]": &wp.sep} {
+		id, ok := wp.vocab[tok]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing %s", path, tok)
+		}
+		*dst = id
+	}
+	return wp, nil
+}
+
+// encode returns token IDs framed by [CLS] ... [SEP], truncated to maxLen.
+func (wp *wordPiece) encode(text string) []int64 {
+	ids := []int64{wp.cls}
+	for _, word := range basicTokens(text) {
+		for _, id := range wp.pieces(word) {
+			if len(ids) == wp.maxLen-1 {
+				return append(ids, wp.sep)
+			}
+			ids = append(ids, id)
+		}
+	}
+	return append(ids, wp.sep)
+}
+
+func (wp *wordPiece) pieces(word string) []int64 {
+	runes := []rune(word)
+	if len(runes) > maxWordChars {
+		return []int64{wp.unk}
+	}
+	var out []int64
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		var id int64 = -1
+		for ; end > start; end-- {
+			sub := string(runes[start:end])
+			if start > 0 {
+				sub = "##" + sub
+			}
+			if v, ok := wp.vocab[sub]; ok {
+				id = v
+				break
+			}
+		}
+		if id < 0 {
+			return []int64{wp.unk}
+		}
+		out = append(out, id)
+		start = end
+	}
+	return out
+}
+
+// basicTokens lowercases, strips accents and splits text into words and
+// single punctuation marks; CJK ideographs become words of their own.
+func basicTokens(text string) []string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(text)) {
+		switch {
+		case r == 0 || r == unicode.ReplacementChar || (unicode.IsControl(r) && !unicode.IsSpace(r)):
+		case unicode.Is(unicode.Mn, r):
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.Is(unicode.Han, r):
+			b.WriteRune(' ')
+			b.WriteRune(r)
+			b.WriteRune(' ')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strings.Fields(b.String())
+}
+
+// meanPool averages each sequence's token vectors where mask is 1 and
+// L2-normalizes the result. hidden is the model output, n sequences of
+// seqLen tokens of dim values each.
+func meanPool(hidden []float32, mask []int64, n, seqLen, dim int) [][]float64 {
+	out := make([][]float64, n)
+	for i := range n {
+		vec := make([]float64, dim)
+		var count float64
+		for j := 0; j < seqLen; j++ {
+			if mask[i*seqLen+j] == 0 {
+				continue
+			}
+			row := hidden[(i*seqLen+j)*dim : (i*seqLen+j+1)*dim]
+			for k, v := range row {
+				vec[k] += float64(v)
+			}
+			count++
+		}
+		var norm float64
+		for k := range vec {
+			vec[k] /= count
+			norm += vec[k] * vec[k]
+		}
+		if norm = math.Sqrt(norm); norm > 0 {
+			for k := range vec {
+				vec[k] /= norm
+			}
+		}
+		out[i] = vec
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package embeddings
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testVocab has IDs in line order: [PAD]=0, [UNK]=1, [CLS]=2, [SEP]=3, ...
+var testVocab = []string{"[PAD]", "[UNK]", "[CLS]", "[SEP]", "linen", "shirt", "##s", "un", "##aff", "##able", ",", "!", "cafe", "£", "45", "中", "国", "runn", "##ing", "run"}
+
+func newTestWordPiece(t *testing.T, maxLen int) *wordPiece {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vocab.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(testVocab, "\r\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wp, err := loadWordPiece(path, maxLen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wp
+}
+
+func TestWordPieceEncode(t *testing.T) {
+	wp := newTestWordPiece(t, 16)
+	for _, tc := range []struct {
+		text string
+		want []int64
+	}{
+		{"", []int64{2, 3}},
+		{"Linen shirts", []int64{2, 4, 5, 6, 3}},                                                // lowercased; ## continuation
+		{"unaffable!", []int64{2, 7, 8, 9, 11, 3}},                                              // greedy longest match, then punctuation
+		{"Café, £45", []int64{2, 12, 10, 13, 14, 3}},                                            // accent stripped; symbols split off
+		{"中国", []int64{2, 15, 16, 3}},                                                           // each ideograph is a word
+		{"running run", []int64{2, 17, 18, 19, 3}},                                              // longest prefix first
+		{"shirtx linen", []int64{2, 1, 4, 3}},                                                   // an unmatched tail makes the word [UNK]
+		{"linen\x00\tshirt", []int64{2, 4, 5, 3}},                                               // control characters dropped
+		{strings.Repeat("a", 101), []int64{2, 1, 3}},                                            // over maxWordChars
+		{strings.Repeat("linen ", 20), []int64{2, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 3}}, // truncated to maxLen
+	} {
+		if got := wp.encode(tc.text); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("encode(%q) = %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestLoadWordPieceNeedsSpecialTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vocab.txt")
+	os.WriteFile(path, []byte("[PAD]\n[UNK]\n[CLS]\nlinen\n"), 0o644)
+	if _, err := loadWordPiece(path, 16); err == nil || !strings.Contains(err.Error(), "[SEP]") {
+		t.Errorf("err = %v, want missing [SEP]", err)
+	}
+}
+
+func TestMeanPool(t *testing.T) {
+	// two sequences of three tokens, dim 2; the second has one padding token
+	hidden := []float32{
+		1, 0, 3, 0, 2, 0,
+		0, 1, 0, 3, 100, 100,
+	}
+	mask := []int64{1, 1, 1, 1, 1, 0}
+	got := meanPool(hidden, mask, 2, 3, 2)
+	want := [][]float64{{1, 0}, {0, 1}} // means (2, 0) and (0, 2), normalized; padding ignored
+	for i := range want {
+		for k := range want[i] {
+			if math.Abs(got[i][k]-want[i][k]) > 1e-9 {
+				t.Fatalf("meanPool = %v, want %v", got, want)
+			}
+		}
+	}
+
+	zero := meanPool([]float32{0, 0}, []int64{1}, 1, 1, 2)
+	if zero[0][0] != 0 || zero[0][1] != 0 {
+		t.Errorf("zero vector = %v, want it left as is", zero[0])
+	}
+}
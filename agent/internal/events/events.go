@@ -0,0 +1,193 @@
+// Package events is the agent's domain event bus. Handlers publish what
+// happened (a product was indexed, recommendations were served, feedback
+// arrived) and features that react to it subscribe, instead of every
+// handler calling every feature.
+//
+// Delivery is asynchronous and best effort: Publish never blocks the
+// caller, and when the queue is full the event is dropped and counted.
+// Subscribers run one event at a time, in publish order. An optional
+// Forwarder also sends each event out of the process (NATS, Kafka) for
+// services that aren't part of the agent.
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event types.
+const (
+	ProductIndexed       = "product.indexed"
+	RecommendationServed = "recommendation.served"
+	FeedbackReceived     = "feedback.received"
+)
+
+// Event is one domain event. Data is the type's payload struct below.
+type Event struct {
+	ID     string    `json:"id"`
+	Type   string    `json:"type"`
+	Tenant string    `json:"tenant"`
+	Time   time.Time `json:"time"`
+	Data   any       `json:"data"`
+}
+
+// ProductIndexedData lists products written to the index in one batch.
+type ProductIndexedData struct {
+	ProductIDs []string `json:"product_ids"`
+}
+
+// RecommendationServedData lists products shown to a shopper, best first.
+type RecommendationServedData struct {
+	Source     string   `json:"source"`            // search | complete-outfit | substitutes | ...
+	Query      string   `json:"query,omitempty"`   // search text or free-text outfit request
+	Mission    string   `json:"mission,omitempty"` // outfits
+	Slot       string   `json:"slot,omitempty"`    // outfit slot
+	Product    string   `json:"product,omitempty"` // the product substitutes were found for
+	ProductIDs []string `json:"product_ids"`
+}
+
+// FeedbackReceivedData is one shopper signal from POST /feedback.
+type FeedbackReceivedData struct {
+	ProductID string `json:"product_id"`
+	Signal    string `json:"signal"` // up | down | add_to_cart
+	Query     string `json:"query,omitempty"`
+	Source    string `json:"source,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// Handler reacts to an event. It runs on the bus's goroutine, so slow work
+// holds up later events.
+type Handler func(ctx context.Context, e Event)
+
+// Forwarder sends events to an external broker.
+type Forwarder interface {
+	Name() string
+	Forward(ctx context.Context, e Event) error
+	Close() error
+}
+
+// forwardTimeout bounds one Forward call, so a broker outage stalls the bus
+// for at most this long per event.
+const forwardTimeout = 5 * time.Second
+
+// Bus fans events out to subscribers and the forwarder.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[string][]Handler
+
+	fwd Forwarder // nil = in-process only
+
+	qmu     sync.RWMutex // Close holds it to close queue under Publish
+	queue   chan Event
+	closed  bool
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+// New starts a bus holding up to buffer undelivered events. fwd may be nil.
+func New(fwd Forwarder, buffer int) *Bus {
+	b := &Bus{subs: map[string][]Handler{}, fwd: fwd, queue: make(chan Event, buffer), done: make(chan struct{})}
+	go b.run()
+	return b
+}
+
+// Subscribe calls h for every later event of type typ.
+func (b *Bus) Subscribe(typ string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[typ] = append(b.subs[typ], h)
+}
+
+// Publish queues an event of type typ with payload data. ID and Time are
+// filled in. It never blocks: with the queue full, or the bus closed, the
+// event is dropped.
+func (b *Bus) Publish(typ, tenant string, data any) {
+	if b == nil {
+		return
+	}
+	e := Event{ID: newID(), Type: typ, Tenant: tenant, Time: time.Now().UTC(), Data: data}
+	b.qmu.RLock()
+	defer b.qmu.RUnlock()
+	if b.closed {
+		return
+	}
+	select {
+	case b.queue <- e:
+	default:
+		if n := b.dropped.Add(1); n == 1 || n%1000 == 0 {
+			slog.Warn("events: queue full, dropping events", "type", typ, "dropped", n)
+		}
+	}
+}
+
+// Dropped is how many events were dropped because the queue was full.
+func (b *Bus) Dropped() int64 { return b.dropped.Load() }
+
+// Close stops accepting events and delivers the queued ones, until ctx is
+// done. It then closes the forwarder.
+func (b *Bus) Close(ctx context.Context) error {
+	b.qmu.Lock()
+	if b.closed {
+		b.qmu.Unlock()
+		return nil
+	}
+	b.closed = true
+	close(b.queue)
+	b.qmu.Unlock()
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return fmt.Errorf("events: %d undelivered: %w", len(b.queue), ctx.Err())
+	}
+	if b.fwd != nil {
+		return b.fwd.Close()
+	}
+	return nil
+}
+
+func (b *Bus) run() {
+	defer close(b.done)
+	for e := range b.queue {
+		b.deliver(e)
+	}
+}
+
+func (b *Bus) deliver(e Event) {
+	ctx := context.Background()
+	b.mu.RLock()
+	subs := b.subs[e.Type]
+	b.mu.RUnlock()
+	for _, h := range subs {
+		b.call(ctx, h, e)
+	}
+	if b.fwd == nil {
+		return
+	}
+	fctx, cancel := context.WithTimeout(ctx, forwardTimeout)
+	defer cancel()
+	if err := b.fwd.Forward(fctx, e); err != nil {
+		slog.Warn("events: forward failed", "forwarder", b.fwd.Name(), "type", e.Type, "id", e.ID, "err", err)
+	}
+}
+
+// call runs one subscriber; a panic is logged rather than stopping the bus.
+func (b *Bus) call(ctx context.Context, h Handler, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("events: subscriber panicked", "type", e.Type, "id", e.ID, "panic", r)
+		}
+	}()
+	h(ctx, e)
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+type recordingForwarder struct {
+	got    []string
+	closed bool
+}
+
+func (f *recordingForwarder) Name() string { return "recording" }
+
+func (f *recordingForwarder) Forward(_ context.Context, e Event) error {
+	f.got = append(f.got, e.Type)
+	return nil
+}
+
+func (f *recordingForwarder) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestCloseDeliversQueuedEvents(t *testing.T) {
+	fwd := &recordingForwarder{}
+	b := New(fwd, 10)
+	var ids [][]string
+	b.Subscribe(ProductIndexed, func(_ context.Context, e Event) {
+		ids = append(ids, e.Data.(ProductIndexedData).ProductIDs)
+	})
+	b.Publish(ProductIndexed, "t1", ProductIndexedData{ProductIDs: []string{"a"}})
+	b.Publish(FeedbackReceived, "t1", FeedbackReceivedData{ProductID: "a", Signal: "up"})
+	b.Publish(ProductIndexed, "t1", ProductIndexedData{ProductIDs: []string{"b", "c"}})
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := [][]string{{"a"}, {"b", "c"}}; !slices.EqualFunc(ids, want, slices.Equal) {
+		t.Errorf("subscriber got %v, want %v", ids, want)
+	}
+	if want := []string{ProductIndexed, FeedbackReceived, ProductIndexed}; !slices.Equal(fwd.got, want) {
+		t.Errorf("forwarded %v, want %v", fwd.got, want)
+	}
+	if !fwd.closed {
+		t.Error("forwarder not closed")
+	}
+	b.Publish(ProductIndexed, "t1", ProductIndexedData{}) // dropped, must not panic
+}
+
+func TestPublishDropsWhenFull(t *testing.T) {
+	block := make(chan struct{})
+	b := New(nil, 1)
+	b.Subscribe(ProductIndexed, func(context.Context, Event) { <-block })
+	for range 5 {
+		b.Publish(ProductIndexed, "", ProductIndexedData{})
+	}
+	// one event is being handled and one is queued, at most
+	if got := b.Dropped(); got < 3 {
+		t.Errorf("Dropped() = %d, want at least 3", got)
+	}
+	close(block)
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubscriberPanicKeepsBusRunning(t *testing.T) {
+	b := New(nil, 10)
+	var got int
+	b.Subscribe(FeedbackReceived, func(context.Context, Event) { panic("boom") })
+	b.Subscribe(FeedbackReceived, func(context.Context, Event) { got++ })
+	b.Publish(FeedbackReceived, "", FeedbackReceivedData{})
+	b.Publish(FeedbackReceived, "", FeedbackReceivedData{})
+	if err := b.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got != 2 {
+		t.Errorf("second subscriber ran %d times, want 2", got)
+	}
+}
+
+func TestCloseGivesUpAtDeadline(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	b := New(nil, 10)
+	b.Subscribe(ProductIndexed, func(context.Context, Event) { <-block })
+	b.Publish(ProductIndexed, "", ProductIndexedData{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close() = %v, want deadline exceeded", err)
+	}
+}
@@ -0,0 +1,42 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Kafka writes each event as JSON to one topic, keyed by tenant so a
+// tenant's events stay in order on one partition. The event type is also
+// in the "type" header, for consumers that filter without decoding.
+type Kafka struct {
+	w *kafka.Writer
+}
+
+// NewKafka writes to topic on brokers. The topic must exist unless the
+// cluster auto-creates topics.
+func NewKafka(brokers []string, topic string) *Kafka {
+	return &Kafka{w: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireOne,
+	}}
+}
+
+func (k *Kafka) Name() string { return "kafka" }
+
+func (k *Kafka) Forward(ctx context.Context, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return k.w.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(e.Tenant),
+		Value:   b,
+		Headers: []kafka.Header{{Key: "type", Value: []byte(e.Type)}},
+	})
+}
+
+func (k *Kafka) Close() error { return k.w.Close() }
@@ -0,0 +1,42 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATS publishes each event as JSON on <prefix>.<type>, e.g.
+// csa.product.indexed. The client reconnects on its own; events published
+// while it is disconnected are buffered by the client up to its limit.
+type NATS struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+// NewNATS connects to url.
+func NewNATS(url, prefix string) (*NATS, error) {
+	conn, err := nats.Connect(url, nats.Name("contextual-shopping-agent"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	return &NATS{conn: conn, prefix: prefix}, nil
+}
+
+func (n *NATS) Name() string { return "nats" }
+
+func (n *NATS) Forward(_ context.Context, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return n.conn.Publish(n.prefix+"."+e.Type, b)
+}
+
+// Close flushes pending events and disconnects.
+func (n *NATS) Close() error {
+	err := n.conn.Flush()
+	n.conn.Close()
+	return err
+}
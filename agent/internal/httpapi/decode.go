@@ -0,0 +1,136 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Decode decodes the JSON document raw into dst. Strict decoding rejects
+// fields dst doesn't declare, so a typo like "budget_gpb" fails loudly
+// instead of silently defaulting, and the error suggests the field meant.
+// Lenient decoding accepts them and returns their names for the caller to
+// log.
+func Decode(raw []byte, dst any, lenient bool) (unknown []string, err error) {
+	if lenient {
+		if err := json.Unmarshal(raw, dst); err != nil {
+			return nil, err
+		}
+		return unknownFields(raw, dst), nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		if field, ok := unknownFieldName(err); ok {
+			return nil, unknownFieldError(field, dst)
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+// unknownFieldName extracts the field from encoding/json's
+// `json: unknown field "x"` error.
+func unknownFieldName(err error) (string, bool) {
+	const prefix = "json: unknown field "
+	msg := err.Error()
+	if !strings.HasPrefix(msg, prefix) {
+		return "", false
+	}
+	return strings.Trim(strings.TrimPrefix(msg, prefix), `"`), true
+}
+
+func unknownFieldError(field string, dst any) error {
+	known := jsonFieldNames(dst)
+	if s := closestField(field, known); s != "" {
+		return InvalidField(field, "unknown field %q (did you mean %q?)", field, s)
+	}
+	return InvalidField(field, "unknown field %q; allowed fields: %s", field, strings.Join(known, ", "))
+}
+
+// unknownFields lists top-level keys in raw that dst does not declare.
+func unknownFields(raw []byte, dst any) []string {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil
+	}
+	known := map[string]bool{}
+	for _, f := range jsonFieldNames(dst) {
+		known[strings.ToLower(f)] = true
+	}
+	var out []string
+	for k := range m {
+		if !known[strings.ToLower(k)] {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// jsonFieldNames returns the top-level JSON names of the struct dst points to.
+func jsonFieldNames(dst any) []string {
+	t := reflect.TypeOf(dst)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			names = append(names, jsonFieldNames(reflect.New(f.Type).Interface())...)
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n, _, _ := strings.Cut(tag, ","); n != "" {
+				name = n
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// closestField returns the known field within a small edit distance of name.
+func closestField(name string, known []string) string {
+	best, bestDist := "", 3
+	for _, k := range known {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(k)); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
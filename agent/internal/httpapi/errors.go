@@ -0,0 +1,187 @@
+// Package httpapi holds the HTTP plumbing every handler shares: the JSON
+// error envelope, request body decoding and middleware chaining. It knows
+// nothing about products or outfits, so handlers in any package can use it.
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Every error response is a JSON envelope:
+//
+//	{"code": "invalid_request", "message": "...", "field_errors": [{"field": "limit", "message": "..."}]}
+//
+// code is stable and follows the status; message is for people and may
+// change. field_errors is only present when the request body was at fault,
+// with one entry per problem found, so a form can mark every bad field at
+// once.
+
+// ErrorResp is the body of every error response.
+type ErrorResp struct {
+	Code        string       `json:"code"`
+	Message     string       `json:"message"`
+	FieldErrors []FieldError `json:"field_errors,omitempty"`
+}
+
+// FieldError is one problem with one request field. Field is the JSON path,
+// e.g. "limit", "weather.date" or "items[2].product_id".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FieldErrors is a validation error naming the fields at fault. Its Error
+// is the messages joined, as the plain-text errors were.
+type FieldErrors []FieldError
+
+func (fe FieldErrors) Error() string {
+	msgs := make([]string, len(fe))
+	for i, e := range fe {
+		msgs[i] = e.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// InvalidField is a validation error for one field. The message names the
+// field itself, so it still reads well on its own.
+func InvalidField(field, format string, args ...any) error {
+	return FieldErrors{{Field: field, Message: fmt.Sprintf(format, args...)}}
+}
+
+// CollectFieldErrors merges validation errors, so a response lists every
+// problem rather than the first. Errors that name no field are kept with
+// an empty field. It returns nil when all of errs are nil.
+func CollectFieldErrors(errs ...error) error {
+	var out FieldErrors
+	for _, err := range errs {
+		var fe FieldErrors
+		switch {
+		case err == nil:
+		case errors.As(err, &fe):
+			out = append(out, fe...)
+		default:
+			out = append(out, FieldError{Message: err.Error()})
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// IsInvalid reports whether err is a validation error, as opposed to a
+// failure while validating, such as a database error.
+func IsInvalid(err error) bool {
+	var fe FieldErrors
+	return errors.As(err, &fe)
+}
+
+// ErrorCode is the envelope code for an HTTP status.
+func ErrorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusRequestEntityTooLarge:
+		return "too_large"
+	case http.StatusUnprocessableEntity:
+		return "unprocessable"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	case http.StatusBadGateway:
+		return "upstream_error"
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	}
+	if status >= 500 {
+		return "internal"
+	}
+	return "error"
+}
+
+// WriteError sends msg in the error envelope. It takes the arguments of
+// http.Error, which it replaces.
+func WriteError(w http.ResponseWriter, msg string, status int) {
+	WriteErrorResp(w, status, ErrorResp{Code: ErrorCode(status), Message: msg})
+}
+
+// WriteErrorResp sends resp, for errors that carry more than a message.
+func WriteErrorResp(w http.ResponseWriter, status int, resp ErrorResp) {
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// BadRequest answers 400 for a body that didn't decode or validate. Decoder
+// errors are reworded without encoding/json's Go type names, and point at
+// the field when they can.
+func BadRequest(w http.ResponseWriter, err error) {
+	resp := ErrorResp{Code: ErrorCode(http.StatusBadRequest), Message: err.Error()}
+	var (
+		fe     FieldErrors
+		syntax *json.SyntaxError
+		typ    *json.UnmarshalTypeError
+		tooBig *http.MaxBytesError
+	)
+	switch {
+	case errors.Is(err, io.EOF):
+		resp.Message = "request body is required"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		resp.Message = "request body is not valid JSON: unexpected end of input"
+	case errors.As(err, &syntax):
+		resp.Message = fmt.Sprintf("request body is not valid JSON at byte %d: %s", syntax.Offset, syntax.Error())
+	case errors.As(err, &typ):
+		field := typ.Field
+		if field == "" {
+			resp.Message = "request body must be a JSON object"
+			break
+		}
+		resp.Message = fmt.Sprintf("%s must be %s, not %s", field, jsonTypeName(typ.Type.Kind().String()), typ.Value)
+		resp.FieldErrors = []FieldError{{Field: field, Message: resp.Message}}
+	case errors.As(err, &tooBig):
+		resp.Code = ErrorCode(http.StatusRequestEntityTooLarge)
+		resp.Message = fmt.Sprintf("request body is larger than %d bytes", tooBig.Limit)
+		WriteErrorResp(w, http.StatusRequestEntityTooLarge, resp)
+		return
+	case errors.As(err, &fe):
+		resp.FieldErrors = fe
+		if len(fe) > 1 {
+			resp.Message = fmt.Sprintf("%d fields are invalid", len(fe))
+		}
+	}
+	WriteErrorResp(w, http.StatusBadRequest, resp)
+}
+
+// jsonTypeName names a Go kind the way a JSON client thinks of it.
+func jsonTypeName(kind string) string {
+	switch kind {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return "an integer"
+	case "float32", "float64":
+		return "a number"
+	case "bool":
+		return "true or false"
+	case "string":
+		return "a string"
+	case "slice", "array":
+		return "an array"
+	case "map", "struct":
+		return "an object"
+	}
+	return "a different type"
+}
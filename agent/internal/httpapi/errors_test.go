@@ -0,0 +1,94 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type searchReq struct {
+	Query  string  `json:"query"`
+	Budget float64 `json:"budget_gbp"`
+	Limit  int     `json:"limit"`
+}
+
+func TestBadRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantMsg    string
+		wantFields []string
+	}{
+		{"empty", "", 400, "request body is required", nil},
+		{"truncated", `{"query": "x"`, 400, "request body is not valid JSON: unexpected end of input", nil},
+		{"wrong type", `{"limit": "ten"}`, 400, "limit must be an integer, not string", []string{"limit"}},
+		{"not an object", `[1]`, 400, "request body must be a JSON object", nil},
+		{"typo", `{"budget_gpb": 10}`, 400, `unknown field "budget_gpb" (did you mean "budget_gbp"?)`, []string{"budget_gpb"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dst searchReq
+			err := io.EOF // what an empty body decodes to
+			if tt.body != "" {
+				_, err = Decode([]byte(tt.body), &dst, false)
+			}
+			rec := httptest.NewRecorder()
+			BadRequest(rec, err)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var resp ErrorResp
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Code != "invalid_request" || resp.Message != tt.wantMsg {
+				t.Errorf("got %q %q, want invalid_request %q", resp.Code, resp.Message, tt.wantMsg)
+			}
+			var fields []string
+			for _, fe := range resp.FieldErrors {
+				fields = append(fields, fe.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("field errors = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestBadRequestTooLarge(t *testing.T) {
+	rec := httptest.NewRecorder()
+	BadRequest(rec, &http.MaxBytesError{Limit: 1024})
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want 413", rec.Code)
+	}
+}
+
+func TestCollectFieldErrors(t *testing.T) {
+	err := CollectFieldErrors(nil, InvalidField("limit", "limit is too big"), errors.New("bad"), nil)
+	var fe FieldErrors
+	if !errors.As(err, &fe) || len(fe) != 2 || fe[0].Field != "limit" || fe[1].Field != "" {
+		t.Fatalf("CollectFieldErrors = %#v", err)
+	}
+	if !IsInvalid(err) {
+		t.Error("IsInvalid = false for field errors")
+	}
+	if CollectFieldErrors(nil, nil) != nil {
+		t.Error("CollectFieldErrors of nils is not nil")
+	}
+}
+
+func TestLenientDecode(t *testing.T) {
+	var dst searchReq
+	unknown, err := Decode([]byte(`{"query": "coat", "colour": "red", "Limit": 3}`), &dst, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(unknown, ",") != "colour" || dst.Query != "coat" || dst.Limit != 3 {
+		t.Errorf("Decode = %v, %+v", unknown, dst)
+	}
+}
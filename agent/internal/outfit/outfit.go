@@ -0,0 +1,125 @@
+// Package outfit holds the decisions that shape an outfit once each slot's
+// candidates are known: which optional slots are worth filling within the
+// budget left, how the budget is shared between slots and between a group's
+// people, and how each slot's picks are explained.
+// Like ranking, it is pure arithmetic over prices and scores, so it can be
+// tested without a database or a model.
+package outfit
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+)
+
+// Candidate is a product that could fill an optional slot.
+type Candidate struct {
+	Price float64
+	// Value is what the product adds to the outfit; see SlotValue.
+	Value float64
+}
+
+// SlotValue is what a product of the given similarity (0-100) is worth in
+// an optional slot a shopper wants with fillProbability.
+func SlotValue(fillProbability, similarity float64) float64 {
+	return fillProbability * similarity / 100
+}
+
+// Leftover is what budget leaves once the given picks are bought, to the
+// penny and never negative.
+func Leftover(budget float64, prices ...float64) float64 {
+	for _, p := range prices {
+		budget -= p
+	}
+	return math.Max(math.Round(budget*100)/100, 0)
+}
+
+// SlotBudgets shares items, what the outfit can spend on products, between
+// slots. When the outfit is budgeted, a slot never gets less than a penny:
+// gift wrapping may have taken the whole budget, or it may be under a penny
+// a slot, and 0 would mean no limit.
+func SlotBudgets(items float64, slots int, budgeted bool) []float64 {
+	out := ranking.SplitBudget(items, slots)
+	for i := range out {
+		if budgeted && out[i] <= 0 {
+			out[i] = 0.01
+		}
+	}
+	return out
+}
+
+// HitReason says why a product of the given eco score and price fills slot.
+func HitReason(slot string, ecoScore int, price, slotBudget float64) string {
+	return fmt.Sprintf("Matches slot=%s. Eco=%d. Price=£%.2f within slot budget £%.2f.",
+		slot, ecoScore, price, slotBudget)
+}
+
+// EmptyReason says why slot has no products when none pass its filters.
+func EmptyReason(slot string, slotBudget float64, minEcoScore int) string {
+	return fmt.Sprintf("No products satisfy constraints for slot=%s (slotBudget<=£%.2f, minEco=%d).",
+		slot, slotBudget, minEcoScore)
+}
+
+// ChooseOptional picks at most one candidate per optional slot (-1 = none)
+// to maximize the total value of the picks, each worth at least minValue,
+// with their prices fitting left when budgeted. Ties go to the cheaper set.
+// Missions have a handful of optional slots of a few candidates each, so
+// every combination is tried.
+func ChooseOptional(candidates [][]Candidate, minValue, left float64, budgeted bool) []int {
+	best := make([]int, len(candidates))
+	for i := range best {
+		best[i] = -1
+	}
+	bestValue, bestSpend := 0.0, 0.0
+	cur := slices.Clone(best)
+	var try func(i int, value, spend float64)
+	try = func(i int, value, spend float64) {
+		if i == len(candidates) {
+			if value > bestValue || (value == bestValue && value > 0 && spend < bestSpend) {
+				bestValue, bestSpend = value, spend
+				copy(best, cur)
+			}
+			return
+		}
+		cur[i] = -1
+		try(i+1, value, spend)
+		for j, c := range candidates[i] {
+			if c.Value < minValue || (budgeted && spend+c.Price > left+1e-9) {
+				continue
+			}
+			cur[i] = j
+			try(i+1, value+c.Value, spend+c.Price)
+		}
+		cur[i] = -1
+	}
+	try(0, 0, 0)
+	return best
+}
+
+// PersonBudgets gives each person with a fixed share (above 0) that share
+// and splits what is left of total evenly between everyone else, to the
+// penny. 0 means unconstrained.
+func PersonBudgets(total float64, fixed []float64) []float64 {
+	out := make([]float64, len(fixed))
+	remaining, flexible := total, 0
+	for i, f := range fixed {
+		if f > 0 {
+			out[i] = f
+			remaining -= f
+		} else {
+			flexible++
+		}
+	}
+	if total <= 0 || flexible == 0 {
+		return out
+	}
+	share := max(remaining, 0) / float64(flexible)
+	for i, f := range fixed {
+		if f <= 0 {
+			out[i] = math.Round(share*100) / 100
+		}
+	}
+	return out
+}
@@ -0,0 +1,130 @@
+package outfit
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+func TestChooseOptional(t *testing.T) {
+	belt := []Candidate{{Price: 30, Value: 0.5}, {Price: 10, Value: 0.4}}
+	scarf := []Candidate{{Price: 25, Value: 0.45}}
+	tests := []struct {
+		name       string
+		candidates [][]Candidate
+		minValue   float64
+		left       float64
+		budgeted   bool
+		want       []int
+	}{
+		{"unbudgeted takes the best of each", [][]Candidate{belt, scarf}, 0.3, 0, false, []int{0, 0}},
+		{"budget fits both with the cheaper belt", [][]Candidate{belt, scarf}, 0.3, 40, true, []int{1, 0}},
+		{"budget fits one", [][]Candidate{belt, scarf}, 0.3, 30, true, []int{0, -1}},
+		{"nothing fits", [][]Candidate{belt, scarf}, 0.3, 5, true, []int{-1, -1}},
+		{"under the minimum value", [][]Candidate{belt, scarf}, 0.6, 0, false, []int{-1, -1}},
+		{"ties go to the cheaper set", [][]Candidate{{{Price: 20, Value: 0.5}, {Price: 15, Value: 0.5}}}, 0.3, 0, false, []int{1}},
+		{"no slots", nil, 0.3, 100, true, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChooseOptional(tt.candidates, tt.minValue, tt.left, tt.budgeted); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChooseOptional() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChooseOptionalStaysWithinBudget(t *testing.T) {
+	f := func(prices [3][2]uint8, left uint8) bool {
+		candidates := make([][]Candidate, len(prices))
+		for i, ps := range prices {
+			for _, p := range ps {
+				candidates[i] = append(candidates[i], Candidate{Price: float64(p), Value: 0.5})
+			}
+		}
+		spend := 0.0
+		for i, j := range ChooseOptional(candidates, 0.3, float64(left), true) {
+			if j >= 0 {
+				spend += candidates[i][j].Price
+			}
+		}
+		return spend <= float64(left)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLeftover(t *testing.T) {
+	tests := []struct {
+		budget float64
+		prices []float64
+		want   float64
+	}{
+		{100, []float64{30.5, 20.25}, 49.25},
+		{50, []float64{60}, 0},
+		{10, nil, 10},
+		{0.3, []float64{0.1, 0.2}, 0},
+	}
+	for _, tt := range tests {
+		if got := Leftover(tt.budget, tt.prices...); got != tt.want {
+			t.Errorf("Leftover(%v, %v) = %v, want %v", tt.budget, tt.prices, got, tt.want)
+		}
+	}
+}
+
+func TestSlotBudgets(t *testing.T) {
+	tests := []struct {
+		items    float64
+		slots    int
+		budgeted bool
+		want     []float64
+	}{
+		{100, 3, true, []float64{33.34, 33.33, 33.33}},
+		{0, 2, false, []float64{0, 0}},
+		{0, 2, true, []float64{0.01, 0.01}},    // gift wrapping took the budget
+		{0.01, 2, true, []float64{0.01, 0.01}}, // under a penny a slot
+		{50, 0, true, nil},
+	}
+	for _, tt := range tests {
+		if got := SlotBudgets(tt.items, tt.slots, tt.budgeted); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SlotBudgets(%v, %d, %v) = %v, want %v", tt.items, tt.slots, tt.budgeted, got, tt.want)
+		}
+	}
+}
+
+func TestPersonBudgets(t *testing.T) {
+	tests := []struct {
+		total float64
+		fixed []float64
+		want  []float64
+	}{
+		{300, []float64{0, 0, 0}, []float64{100, 100, 100}},
+		{300, []float64{120, 0, 0}, []float64{120, 90, 90}},
+		{100, []float64{0, 0, 0}, []float64{33.33, 33.33, 33.33}},
+		{100, []float64{150, 0}, []float64{150, 0}},
+		{0, []float64{40, 0}, []float64{40, 0}},
+	}
+	for _, tt := range tests {
+		if got := PersonBudgets(tt.total, tt.fixed); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("PersonBudgets(%v, %v) = %v, want %v", tt.total, tt.fixed, got, tt.want)
+		}
+	}
+}
+
+func TestPersonBudgetsShareTheRest(t *testing.T) {
+	f := func(total uint16, people uint8) bool {
+		n := int(people%6) + 1
+		got := PersonBudgets(float64(total), make([]float64, n))
+		sum := 0.0
+		for _, b := range got {
+			sum += b
+		}
+		// each share is rounded to the penny
+		return math.Abs(sum-float64(total)) <= 0.005*float64(n)+1e-9
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
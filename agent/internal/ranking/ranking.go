@@ -0,0 +1,141 @@
+// Package ranking holds the pure arithmetic behind recommendations: how a
+// vector distance becomes a similarity score, how an outfit budget is shared
+// between slots, which slots an outfit still needs, and how the explanation
+// model's bullets are read back. Nothing here touches the database or the
+// network, so it can be tested exhaustively.
+package ranking
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Distance metrics pgvector can order by.
+const (
+	MetricL2           = "l2"            // Euclidean distance, <->
+	MetricCosine       = "cosine"        // 1 − cosine similarity, <=>
+	MetricInnerProduct = "inner_product" // negated inner product, <#>
+)
+
+// Metrics lists the supported distance metrics.
+var Metrics = []string{MetricL2, MetricCosine, MetricInnerProduct}
+
+// Operator is the pgvector operator for metric; unknown metrics use L2.
+func Operator(metric string) string {
+	switch metric {
+	case MetricCosine:
+		return "<=>"
+	case MetricInnerProduct:
+		return "<#>"
+	}
+	return "<->"
+}
+
+// OpClass is the index operator class that serves Operator(metric).
+func OpClass(metric string) string {
+	switch metric {
+	case MetricCosine:
+		return "vector_cosine_ops"
+	case MetricInnerProduct:
+		return "vector_ip_ops"
+	}
+	return "vector_l2_ops"
+}
+
+// Similarity maps an L2 embedding distance to a 0-100 score: 100 for an
+// exact match, falling off exponentially with distance.
+func Similarity(distance float64) float64 {
+	return math.Exp(-distance) * 100
+}
+
+// MetricSimilarity maps a distance under metric to a 0-100 score. Cosine
+// and inner product distances become the cosine (or, for unit vectors, the
+// equivalent inner product) as a percentage, floored at 0; L2 uses
+// Similarity.
+func MetricSimilarity(metric string, distance float64) float64 {
+	switch metric {
+	case MetricCosine:
+		return min(max(1-distance, 0), 1) * 100
+	case MetricInnerProduct:
+		return min(max(-distance, 0), 1) * 100
+	}
+	return Similarity(distance)
+}
+
+// SplitBudget shares total between slots as evenly as whole pence allow;
+// the first slots take any leftover penny, so the parts always add up to
+// total rounded to the penny. A total of 0 or less means no limit, and every
+// slot gets 0. It returns nil for no slots.
+func SplitBudget(total float64, slots int) []float64 {
+	if slots <= 0 {
+		return nil
+	}
+	out := make([]float64, slots)
+	if total <= 0 {
+		return out
+	}
+	pence := int64(math.Round(total * 100))
+	each, extra := pence/int64(slots), pence%int64(slots)
+	for i := range out {
+		p := each
+		if int64(i) < extra {
+			p++
+		}
+		out[i] = float64(p) / 100
+	}
+	return out
+}
+
+// MissingSlots lists the required slots not in present, in required order.
+func MissingSlots(required, present []string) []string {
+	set := map[string]bool{}
+	for _, s := range present {
+		set[s] = true
+	}
+	var missing []string
+	for _, r := range required {
+		if !set[r] {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
+
+// StripCodeFence removes a Markdown code fence some models wrap JSON in.
+func StripCodeFence(raw string) string {
+	s := strings.TrimSpace(raw)
+	if strings.HasPrefix(s, "```") {
+		// remove first line (``` or ```json)
+		if i := strings.Index(s, "\n"); i >= 0 {
+			s = s[i+1:]
+		}
+		// remove trailing ```
+		if j := strings.LastIndex(s, "```"); j >= 0 {
+			s = s[:j]
+		}
+		s = strings.TrimSpace(s)
+	}
+	return s
+}
+
+// ParseBullets reads explanation bullets as either a bare JSON array of
+// strings or {"bullets": [...]}, optionally inside a code fence.
+func ParseBullets(raw string) ([]string, error) {
+	s := StripCodeFence(raw)
+
+	var bullets []string
+	if err := json.Unmarshal([]byte(s), &bullets); err == nil && len(bullets) > 0 {
+		return bullets, nil
+	}
+
+	var wrap struct {
+		Bullets []string `json:"bullets"`
+	}
+	if err := json.Unmarshal([]byte(s), &wrap); err == nil && len(wrap.Bullets) > 0 {
+		return wrap.Bullets, nil
+	}
+
+	return nil, fmt.Errorf("invalid explain JSON: %s", raw)
+}
@@ -0,0 +1,212 @@
+package ranking
+
+import (
+	"math"
+	"reflect"
+	"slices"
+	"testing"
+	"testing/quick"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		distance float64
+		want     float64
+	}{
+		{0, 100},
+		{1, 36.7879},
+		{2, 13.5335},
+	}
+	for _, tt := range tests {
+		if got := Similarity(tt.distance); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("Similarity(%v) = %v, want %v", tt.distance, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarityDecreasesWithDistance(t *testing.T) {
+	f := func(a, b uint16) bool {
+		da, db := float64(a)/1000, float64(b)/1000 // 0-65.5
+		if da > db {
+			da, db = db, da
+		}
+		sa, sb := Similarity(da), Similarity(db)
+		return sa >= sb && sa <= 100 && sb >= 0
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMetricSimilarity(t *testing.T) {
+	tests := []struct {
+		metric   string
+		distance float64
+		want     float64
+	}{
+		{MetricL2, 0, 100},
+		{MetricL2, 1, 36.7879},
+		{MetricCosine, 0, 100},
+		{MetricCosine, 0.25, 75},
+		{MetricCosine, 1.5, 0}, // opposed vectors floor at 0
+		{MetricInnerProduct, -0.8, 80},
+		{MetricInnerProduct, 0.3, 0},
+		{"unknown", 1, 36.7879},
+	}
+	for _, tt := range tests {
+		if got := MetricSimilarity(tt.metric, tt.distance); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("MetricSimilarity(%q, %v) = %v, want %v", tt.metric, tt.distance, got, tt.want)
+		}
+	}
+}
+
+// Whatever the metric, a closer product never scores lower, and scores stay
+// within 0-100.
+func TestMetricSimilarityMonotonic(t *testing.T) {
+	for _, m := range Metrics {
+		f := func(a, b int16) bool {
+			da, db := float64(a)/10000, float64(b)/10000 // -3.3 to 3.3
+			if m == MetricL2 {                           // L2 distances are never negative
+				da, db = math.Abs(da), math.Abs(db)
+			}
+			if da > db {
+				da, db = db, da
+			}
+			sa, sb := MetricSimilarity(m, da), MetricSimilarity(m, db)
+			return sa >= sb && sa <= 100 && sb >= 0
+		}
+		if err := quick.Check(f, nil); err != nil {
+			t.Errorf("%s: %v", m, err)
+		}
+	}
+}
+
+func TestSplitBudget(t *testing.T) {
+	tests := []struct {
+		name  string
+		total float64
+		slots int
+		want  []float64
+	}{
+		{"even", 120, 3, []float64{40, 40, 40}},
+		{"leftover pence go first", 100, 3, []float64{33.34, 33.33, 33.33}},
+		{"one slot", 59.99, 1, []float64{59.99}},
+		{"no budget", 0, 2, []float64{0, 0}},
+		{"negative is no budget", -5, 2, []float64{0, 0}},
+		{"no slots", 50, 0, nil},
+		{"fewer pence than slots", 0.02, 3, []float64{0.01, 0.01, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitBudget(tt.total, tt.slots); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitBudget(%v, %d) = %v, want %v", tt.total, tt.slots, got, tt.want)
+			}
+		})
+	}
+}
+
+// Budget split properties: the parts add up to the total to the penny, and
+// no two differ by more than a penny.
+func TestSplitBudgetProperties(t *testing.T) {
+	f := func(pence uint32, n uint8) bool {
+		total := float64(pence%10_000_000) / 100 // up to £100,000
+		slots := int(n%8) + 1
+		parts := SplitBudget(total, slots)
+		if len(parts) != slots {
+			return false
+		}
+		var sum int64
+		for _, p := range parts {
+			sum += int64(math.Round(p * 100))
+		}
+		if sum != int64(math.Round(total*100)) {
+			return false
+		}
+		return math.Round((slices.Max(parts)-slices.Min(parts))*100) <= 1
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMissingSlots(t *testing.T) {
+	tests := []struct {
+		required, present, want []string
+	}{
+		{[]string{"top", "bottom", "shoes"}, []string{"top"}, []string{"bottom", "shoes"}},
+		{[]string{"top", "bottom"}, []string{"bottom", "top"}, nil},
+		{[]string{"top", "bottom"}, nil, []string{"top", "bottom"}},
+		{[]string{"top"}, []string{"hat"}, []string{"top"}},
+		{nil, []string{"top"}, nil},
+	}
+	for _, tt := range tests {
+		if got := MissingSlots(tt.required, tt.present); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MissingSlots(%v, %v) = %v, want %v", tt.required, tt.present, got, tt.want)
+		}
+	}
+}
+
+// Every required slot is either present or missing, never both, and missing
+// keeps the required order.
+func TestMissingSlotsProperties(t *testing.T) {
+	f := func(required, present []string) bool {
+		missing := MissingSlots(required, present)
+		for _, m := range missing {
+			if slices.Contains(present, m) {
+				return false
+			}
+		}
+		var want []string
+		for _, r := range required {
+			if !slices.Contains(present, r) {
+				want = append(want, r)
+			}
+		}
+		return slices.Equal(missing, want)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseBullets(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{"object", `{"bullets": ["a", "b"]}`, []string{"a", "b"}, false},
+		{"bare array", `["a"]`, []string{"a"}, false},
+		{"fenced", "```json\n{\"bullets\": [\"a\"]}\n```", []string{"a"}, false},
+		{"fence without language", "```\n[\"a\", \"b\"]\n```", []string{"a", "b"}, false},
+		{"surrounding space", "  \n[\"a\"]\n ", []string{"a"}, false},
+		{"empty list", `{"bullets": []}`, nil, true},
+		{"prose", "Here are your bullets: a, b", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBullets(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBullets(%q) err=%v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBullets(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripCodeFence(t *testing.T) {
+	tests := []struct{ raw, want string }{
+		{"```json\n{}\n```", "{}"},
+		{"{}", "{}"},
+		{"  {} ", "{}"},
+		{"```\n{\"a\": 1}\n```\n", `{"a": 1}`},
+	}
+	for _, tt := range tests {
+		if got := StripCodeFence(tt.raw); got != tt.want {
+			t.Errorf("StripCodeFence(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package search
+
+import "strings"
+
+// Optional filters are passed as NULL when unset, and the query skips a
+// NULL filter: ($3::int IS NULL OR eco_score >= $3).
+
+// NullInt is v, or NULL when v isn't positive.
+func NullInt(v int) any {
+	if v <= 0 {
+		return nil
+	}
+	return v
+}
+
+// NullNum is v, or NULL when v isn't positive.
+func NullNum(v float64) any {
+	if v <= 0 {
+		return nil
+	}
+	return v
+}
+
+// NullText is s, or NULL when s is empty.
+func NullText(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// NullList is s, or NULL when s is empty.
+func NullList(s []string) any {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
+
+// likeEscape makes LIKE treat s literally: % and _ stop being wildcards.
+var likeEscape = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// NullContains is a LIKE pattern matching text that contains s, or NULL
+// when s is empty.
+func NullContains(s string) any {
+	if s == "" {
+		return nil
+	}
+	return "%" + likeEscape.Replace(s) + "%"
+}
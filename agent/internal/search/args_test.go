@@ -0,0 +1,17 @@
+package search
+
+import "testing"
+
+func TestNullContains(t *testing.T) {
+	for in, want := range map[string]any{
+		"":           nil,
+		"wool":       "%wool%",
+		"100%":       `%100\%%`,
+		"a_b":        `%a\_b%`,
+		`back\slash`: `%back\\slash%`,
+	} {
+		if got := NullContains(in); got != want {
+			t.Errorf("NullContains(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package search
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+)
+
+// HitColumns selects what ScanRows reads from product_embeddings, with the
+// distance to the query vector $1 under metric.
+func HitColumns(metric string) string {
+	return `product_id, title, thumbnail, eco_score, price_gbp,
+       COALESCE(embedding ` + ranking.Operator(metric) + ` $1::vector, 0) AS distance,
+       stock_qty, variant_availability, COALESCE(eco_labels, '{}'),
+       COALESCE(origin_country, ''), COALESCE(eco_score_source, ''),
+       lifecycle, COALESCE(to_char(ships_at, 'YYYY-MM-DD'), '')`
+}
+
+// CardHitColumns is HitColumns for a search of product_cards joined to
+// product_embeddings: the distance is to the card, and the title is the
+// translated one where there is one.
+func CardHitColumns(metric string) string {
+	return `product_id, COALESCE(card_title, title), thumbnail, eco_score, price_gbp,
+       COALESCE(card_embedding ` + ranking.Operator(metric) + ` $1::vector, 0) AS distance,
+       stock_qty, variant_availability, COALESCE(eco_labels, '{}'),
+       COALESCE(origin_country, ''), COALESCE(eco_score_source, ''),
+       lifecycle, COALESCE(to_char(ships_at, 'YYYY-MM-DD'), '')`
+}
+
+// Filter is what varies between searches in their FROM and WHERE clauses.
+// The rest of the clause reads the arguments every search passes, in
+// order: $1 the query vector, $2 the limit, $3 the minimum eco score, $4
+// the maximum price, $5 the category, $6 sizes, $7 a color, $8 a brand,
+// $9 a material LIKE pattern, $10 eco labels, $11 gift only, $12 a palette,
+// $13 new arrivals only, $14 how new in days, $15 clearance only, $16 the
+// minimum margin and $17 merchant eco scores only.
+type Filter struct {
+	Table       string // product_embeddings, or it joined to product_cards
+	Sandbox     string // the caller's sandbox or live products
+	Lifecycle   string // the lifecycles shoppers can see
+	SizeInStock string // one of the $6 sizes is in stock
+	Attributes  string // further "AND ..." conditions, on arguments from $18
+}
+
+// SQL is the FROM and WHERE clauses of a search. Duplicate listings are
+// never hits themselves.
+func (f Filter) SQL() string {
+	return `FROM ` + f.Table + `
+WHERE embedding IS NOT NULL
+  AND ` + f.Sandbox + `
+  AND ` + f.Lifecycle + `
+  AND ($3::int IS NULL OR eco_score >= $3)
+  AND ($4::numeric IS NULL OR price_gbp <= $4)
+  AND ($5::text IS NULL OR category = $5)
+  AND ($6::text[] IS NULL OR ` + f.SizeInStock + `)
+  AND ($7::text IS NULL OR $7 = ANY(colors))
+  AND ($8::text IS NULL OR brand = $8)
+  AND ($9::text IS NULL OR material LIKE $9)
+  AND ($10::text[] IS NULL OR eco_labels @> $10)
+  AND (NOT $11::bool OR (gift_wrap AND NOT COALESCE(final_sale, false)))
+  AND ($12::text[] IS NULL OR colors && $12)
+  AND (NOT $13::bool OR first_indexed_at >= now() - make_interval(days => $14))
+  AND (NOT $15::bool OR clearance)
+  AND ($16::float8 IS NULL OR margin_pct IS NULL OR margin_pct >= $16)
+  AND (NOT $17::bool OR eco_score_source = 'merchant')
+  AND duplicate_of IS NULL` + f.Attributes
+}
+
+// Row is one product as HitColumns select it.
+type Row struct {
+	ProductID string
+	Title     string
+	Thumbnail string
+	EcoScore  int
+	PriceGBP  float64
+	// Distance is under the search's metric, to two decimal places;
+	// Similarity (0-100) comes from the unrounded distance.
+	Distance       float64
+	Similarity     float64
+	StockQty       *int
+	Variants       json.RawMessage // variant_availability; nil when untracked
+	EcoLabels      []string        // label ids
+	OriginCountry  string
+	EcoScoreSource string
+	Lifecycle      string
+	ShipsAt        string // YYYY-MM-DD, or empty
+}
+
+// ScanRows reads rows selecting HitColumns or CardHitColumns under metric.
+func ScanRows(rows pgx.Rows, metric string) ([]Row, error) {
+	var out []Row
+	for rows.Next() {
+		var r Row
+		if err := rows.Scan(
+			&r.ProductID,
+			&r.Title,
+			&r.Thumbnail,
+			&r.EcoScore,
+			&r.PriceGBP,
+			&r.Distance,
+			&r.StockQty,
+			&r.Variants,
+			&r.EcoLabels,
+			&r.OriginCountry,
+			&r.EcoScoreSource,
+			&r.Lifecycle,
+			&r.ShipsAt,
+		); err != nil {
+			return nil, err
+		}
+		r.Similarity = ranking.MetricSimilarity(metric, r.Distance)
+
+		// round distance for cleaner display
+		r.Distance = math.Round(r.Distance*100) / 100
+
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,29 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterSQL(t *testing.T) {
+	sql := Filter{
+		Table:       "product_embeddings",
+		Sandbox:     "NOT sandbox",
+		Lifecycle:   "lifecycle = 'active'",
+		SizeInStock: "sizes && $6",
+		Attributes:  "\n  AND fit = $18",
+	}.SQL()
+	for _, want := range []string{
+		"FROM product_embeddings\nWHERE embedding IS NOT NULL",
+		"AND NOT sandbox\n",
+		"AND lifecycle = 'active'\n",
+		"AND ($6::text[] IS NULL OR sizes && $6)",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("SQL() lacks %q:\n%s", want, sql)
+		}
+	}
+	if !strings.HasSuffix(sql, "AND duplicate_of IS NULL\n  AND fit = $18") {
+		t.Errorf("SQL() should end with the attributes after the duplicate check:\n%s", sql)
+	}
+}
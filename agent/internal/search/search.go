@@ -0,0 +1,109 @@
+// Package search builds the SQL behind vector search over
+// product_embeddings: which HNSW index serves a distance metric and vector
+// storage, the nearest-neighbour query with its quantized shortlist, how
+// hard the index looks at each search quality, the filters every search
+// applies, and the orders filter-only searches sort by. It also reads the
+// rows back into hits with their similarity; the agent runs the queries and
+// ranks what comes back.
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/embeddings"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+)
+
+// Storage is how embeddings are indexed: at full precision, or quantized
+// (CSA_VECTOR_STORAGE) with a shortlist RerankFactor times the limit
+// re-ranked at full precision.
+type Storage struct {
+	Kind         string // config.StorageFull, StorageHalfvec or StorageBinary
+	RerankFactor int
+}
+
+// Index names the HNSW index that serves the storage and metric, and gives
+// its column and operator class. Quantized indexes are over an expression of
+// the full-precision column, which stays for re-ranking.
+func (s Storage) Index(metric string) (name, column string) {
+	switch s.Kind {
+	case config.StorageHalfvec:
+		opclass := "halfvec" + strings.TrimPrefix(ranking.OpClass(metric), "vector")
+		return "idx_product_embeddings_embedding_halfvec_" + metric,
+			fmt.Sprintf("(embedding::halfvec(%d)) %s", embeddings.Dim, opclass)
+	case config.StorageBinary:
+		// Hamming distance between sign bits, whatever the metric
+		return "idx_product_embeddings_embedding_binary",
+			fmt.Sprintf("(binary_quantize(embedding)::bit(%d)) bit_hamming_ops", embeddings.Dim)
+	}
+	if metric == ranking.MetricL2 {
+		return "idx_product_embeddings_embedding", "embedding vector_l2_ops" // 00002_embedding_hnsw
+	}
+	return "idx_product_embeddings_embedding_" + metric, "embedding " + ranking.OpClass(metric)
+}
+
+// Order is the ORDER BY expression Index serves, against the query vector
+// in $1.
+func (s Storage) Order(metric string) string {
+	switch s.Kind {
+	case config.StorageHalfvec:
+		// $1 stays a full vector for the re-rank; only this comparison is halved
+		return fmt.Sprintf("embedding::halfvec(%d) %s $1::vector::halfvec(%d)", embeddings.Dim, ranking.Operator(metric), embeddings.Dim)
+	case config.StorageBinary:
+		return fmt.Sprintf("binary_quantize(embedding)::bit(%d) <~> binary_quantize($1::vector)", embeddings.Dim)
+	}
+	return "embedding " + ranking.Operator(metric) + " $1::vector"
+}
+
+// Rows is how many rows a search for limit hits asks the index for.
+func (s Storage) Rows(limit int) int {
+	if s.Kind == config.StorageFull {
+		return limit
+	}
+	return limit * s.RerankFactor
+}
+
+// NearestSQL selects cols from the rows of from ("FROM product_embeddings
+// WHERE ...") nearest the query vector in $1, at most $2 of them. With
+// quantized storage the index picks a shortlist of RerankFactor × $2 by
+// quantized distance, and only the shortlist is re-ranked at full precision.
+func (s Storage) NearestSQL(cols, from, metric string) string {
+	exact := Storage{Kind: config.StorageFull}.Order(metric)
+	if s.Kind == config.StorageFull {
+		return "SELECT " + cols + "\n" + from + "\nORDER BY " + exact + "\nLIMIT $2"
+	}
+	return fmt.Sprintf(`SELECT %s
+FROM (
+  SELECT * %s
+  ORDER BY %s
+  LIMIT $2 * %d
+) product_embeddings
+ORDER BY %s
+LIMIT $2`, cols, from, s.Order(metric), s.RerankFactor, exact)
+}
+
+// Effort is how hard an ANN search looks for the true nearest products.
+type Effort struct {
+	EfSearch int // HNSW candidates kept while walking the graph
+	Probes   int // IVFFlat lists scanned, should an ivfflat index be used
+}
+
+// Efforts maps each search quality to its effort.
+var Efforts = map[string]Effort{
+	config.QualityFast:     {EfSearch: 20, Probes: 1},
+	config.QualityBalanced: {EfSearch: 40, Probes: 10}, // pgvector's default ef_search
+	config.QualityHigh:     {EfSearch: 100, Probes: 40},
+	config.QualityMax:      {EfSearch: 400, Probes: 100},
+}
+
+// maxEfSearch is pgvector's upper bound for hnsw.ef_search.
+const maxEfSearch = 1000
+
+// EfSearchFor is the hnsw.ef_search for a query asking the index for rows.
+// HNSW returns at most ef_search rows, so it is raised to rows, whatever
+// the level.
+func (e Effort) EfSearchFor(rows int) int {
+	return min(max(e.EfSearch, rows), maxEfSearch)
+}
@@ -0,0 +1,43 @@
+package search
+
+// Sort orders for /search. Empty means relevance.
+const (
+	SortPriceAsc   = "price_asc"
+	SortPriceDesc  = "price_desc"
+	SortEcoDesc    = "eco_desc"
+	SortNewest     = "newest"
+	SortPopularity = "popularity" // times recommended in the last 7 days
+)
+
+// SortOrders lists the sort orders.
+var SortOrders = []string{SortPriceAsc, SortPriceDesc, SortEcoDesc, SortNewest, SortPopularity}
+
+// Semantic searches sort the best matches rather than the whole catalog, or
+// "cheapest" would mean "cheapest product of any kind". This many times the
+// limit are considered, up to maxSortCandidates.
+const (
+	sortCandidateFactor = 5
+	maxSortCandidates   = 200
+)
+
+// SortCandidates is how many of the best matches a sorted semantic search
+// for limit hits sorts.
+func SortCandidates(limit int) int {
+	return min(limit*sortCandidateFactor, maxSortCandidates)
+}
+
+// SortSQL is the ORDER BY for filter-only searches, which sort every
+// matching product since there is no relevance to bound them by.
+func SortSQL(sortBy string) string {
+	switch sortBy {
+	case SortPriceAsc:
+		return "price_gbp, product_id"
+	case SortPriceDesc:
+		return "price_gbp DESC, product_id"
+	case SortNewest:
+		return "first_indexed_at DESC NULLS LAST, product_id"
+	case SortPopularity:
+		return "(SELECT t.served FROM mv_trending_by_category t WHERE t.product_id = product_embeddings.product_id) DESC NULLS LAST, product_id"
+	}
+	return "eco_score DESC NULLS LAST, price_gbp, product_id"
+}
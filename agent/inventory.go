@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+)
+
+// VariantStock is the per-variant availability summary returned on each Hit.
+type VariantStock struct {
+	VariantID string `json:"variant_id"`
+	Title     string `json:"title,omitempty"`
+	SKU       string `json:"sku,omitempty"`
+	Quantity  *int   `json:"quantity,omitempty"` // nil when the catalog doesn't track inventory
+	InStock   bool   `json:"in_stock"`
+	Size      string `json:"size,omitempty"` // lowercased, like the size filter
+}
+
+// summarizeStock totals tracked variants and builds the availability summary.
+// The total is nil when no variant has managed inventory.
+func summarizeStock(variants []catalog.Variant) (*int, []VariantStock) {
+	var total *int
+	out := make([]VariantStock, 0, len(variants))
+	for _, v := range variants {
+		vs := VariantStock{VariantID: v.ID, Title: v.Title, SKU: v.SKU, InStock: true, Size: normalizeSize(v.Size)}
+		if v.ManageInventory {
+			q := max(v.InventoryQuantity, 0)
+			vs.Quantity = &q
+			vs.InStock = q > 0 || v.AllowBackorder
+			if total == nil {
+				total = new(int)
+			}
+			*total += q
+		}
+		out = append(out, vs)
+	}
+	return total, out
+}
+
+// soldOut reports whether none of a product's variants can be bought. A
+// variant that allows backorders can, whatever its quantity, and so can one
+// whose inventory isn't tracked.
+func soldOut(variants []VariantStock) bool {
+	for _, v := range variants {
+		if v.InStock {
+			return false
+		}
+	}
+	return len(variants) > 0
+}
+
+type SyncResult struct {
+	Updated     int
+	SoldOut     int
+	Invalidated int // cached responses dropped
+	Substituted int // saved-outfit items given a substitute
+}
+
+// syncInventory refreshes stock columns for already indexed products without
+// re-embedding them, then reacts to items that sold out or came back.
+func syncInventory(ctx context.Context, pool *pgxpool.Pool) (SyncResult, error) {
+	var res SyncResult
+	p := catalogProvider()
+	if err := p.Configured(); err != nil {
+		return res, err
+	}
+	stock, err := p.Stock(ctx)
+	if err != nil {
+		return res, fmt.Errorf("%s: %w", p.Name(), err)
+	}
+
+	var ch stockChanges
+	for id, variants := range stock {
+		qty, summary := summarizeStock(variants)
+		var prev []VariantStock
+		err := pool.QueryRow(ctx, `
+UPDATE product_embeddings p
+SET stock_qty=$2, variant_availability=$3, stock_synced_at=now()
+FROM (SELECT variant_availability FROM product_embeddings WHERE product_id=$1) old
+WHERE p.product_id=$1
+RETURNING old.variant_availability
+`, id, qty, summary).Scan(&prev)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue // not indexed yet
+		}
+		if err != nil {
+			return res, err
+		}
+		res.Updated++
+
+		wasOut, isOut := soldOut(prev), soldOut(summary)
+		switch {
+		case isOut && !wasOut:
+			ch.SoldOut = append(ch.SoldOut, id)
+		case wasOut && !isOut:
+			ch.Restocked = append(ch.Restocked, id)
+		}
+	}
+
+	res.SoldOut = len(ch.SoldOut)
+	res.Invalidated, res.Substituted = onStockChanges(ctx, pool, ch)
+	return res, nil
+}
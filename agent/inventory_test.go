@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+)
+
+func TestSoldOut(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		variants []catalog.Variant
+		want     bool
+	}{
+		{"none left", []catalog.Variant{{ID: "v1", ManageInventory: true}, {ID: "v2", ManageInventory: true, InventoryQuantity: -2}}, true},
+		{"one left", []catalog.Variant{{ID: "v1", ManageInventory: true}, {ID: "v2", ManageInventory: true, InventoryQuantity: 1}}, false},
+		{"none left but back-orderable", []catalog.Variant{{ID: "v1", ManageInventory: true, AllowBackorder: true}}, false},
+		{"untracked", []catalog.Variant{{ID: "v1", ManageInventory: true}, {ID: "v2"}}, false},
+		{"no variants", nil, false},
+	} {
+		qty, summary := summarizeStock(tc.variants)
+		if got := soldOut(summary); got != tc.want {
+			t.Errorf("%s: soldOut = %v, want %v", tc.name, got, tc.want)
+		}
+		if tc.name == "none left but back-orderable" && (qty == nil || *qty != 0) {
+			t.Errorf("%s: total = %v, want 0", tc.name, qty)
+		}
+	}
+}
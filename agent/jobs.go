@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Scheduled jobs run on every replica's timer but do their work on one:
+// each run is tied to a slot (its scheduled time), and runScheduled claims
+// the slot under a Postgres advisory lock before running it. Replicas whose
+// timers fire for a slot that already ran skip it. The lock is session
+// scoped, so a replica that dies mid-run releases it with its connection.
+
+// jobLockClass is the first key of the job advisory locks, keeping them
+// apart from goose's migration lock and anything else using advisory locks.
+const jobLockClass = 0x637361 // "csa"
+
+// jobHolder identifies this replica in job_runs.
+var jobHolder = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s:%d", host, os.Getpid())
+}()
+
+// JobRun is the last run of a scheduled job, for GET /admin/jobs.
+type JobRun struct {
+	Job        string     `json:"job"`
+	LastSlot   time.Time  `json:"last_slot"`
+	Holder     string     `json:"holder"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"` // unset while running
+	Error      string     `json:"error,omitempty"`
+}
+
+// runScheduled runs fn for job's slot unless another replica holds the job
+// or has already run this slot or a later one. ran reports whether fn ran;
+// err is fn's error, or the coordination's.
+func runScheduled(ctx context.Context, pool *pgxpool.Pool, job string, slot time.Time, fn func(context.Context) error) (ran bool, err error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1, hashtext($2))`, jobLockClass, job).Scan(&locked); err != nil {
+		return false, err
+	}
+	if !locked {
+		slog.DebugContext(ctx, "jobs: held by another replica", "job", job, "slot", slot)
+		return false, nil
+	}
+	defer func() {
+		// a fresh context: the unlock must happen even if ctx was cancelled
+		if _, err := conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1, hashtext($2))`, jobLockClass, job); err != nil {
+			slog.Warn("jobs: unlock failed", "job", job, "err", err)
+			conn.Conn().Close(context.Background()) // closing the session frees the lock
+		}
+	}()
+
+	// claim the slot; no row back means it already ran here or elsewhere
+	tag, err := conn.Exec(ctx, `
+INSERT INTO job_runs (job, last_slot, holder, started_at)
+VALUES ($1, $2, $3, now())
+ON CONFLICT (job) DO UPDATE
+SET last_slot = EXCLUDED.last_slot, holder = EXCLUDED.holder,
+    started_at = EXCLUDED.started_at, finished_at = NULL, error = NULL
+WHERE job_runs.last_slot < EXCLUDED.last_slot
+`, job, slot, jobHolder)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		slog.DebugContext(ctx, "jobs: slot already ran", "job", job, "slot", slot)
+		return false, nil
+	}
+
+	runErr := fn(ctx)
+	var msg *string
+	if runErr != nil {
+		s := runErr.Error()
+		msg = &s
+	}
+	if _, err := conn.Exec(context.Background(), `UPDATE job_runs SET finished_at = now(), error = $2 WHERE job = $1`, job, msg); err != nil {
+		slog.Warn("jobs: recording run failed", "job", job, "err", err)
+	}
+	return true, runErr
+}
+
+func listJobRuns(ctx context.Context, pool *pgxpool.Pool) ([]JobRun, error) {
+	rows, err := pool.Query(ctx, `SELECT job, last_slot, holder, started_at, finished_at, COALESCE(error, '') FROM job_runs ORDER BY job`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []JobRun{}
+	for rows.Next() {
+		var j JobRun
+		if err := rows.Scan(&j.Job, &j.LastSlot, &j.Holder, &j.StartedAt, &j.FinishedAt, &j.Error); err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// Relevance grades follow the usual graded-relevance scale used by nDCG.
+const (
+	gradeIrrelevant = 0
+	gradeHighly     = 3
+)
+
+// RelevanceJudgment is a human label for one (query, product) pair. The set
+// of judgments per tenant is the golden set offline evaluation runs against.
+type RelevanceJudgment struct {
+	ID        string    `json:"id"`
+	Query     string    `json:"query"`
+	ProductID string    `json:"product_id"`
+	Grade     int       `json:"grade"` // 0 irrelevant .. 3 highly relevant
+	Notes     string    `json:"notes,omitempty"`
+	Judge     string    `json:"judge,omitempty"` // API key ID that recorded it
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type JudgmentsReq struct {
+	Judgments []struct {
+		Query     string `json:"query"`
+		ProductID string `json:"product_id"`
+		Grade     int    `json:"grade"`
+		Notes     string `json:"notes,omitempty"`
+	} `json:"judgments"`
+}
+
+// JudgmentCandidate is a search hit shown in the labeling UI together with
+// its current label, if any.
+type JudgmentCandidate struct {
+	Hit
+	Grade *int `json:"grade"`
+}
+
+// GoldenSet maps each normalized query to its graded products.
+type GoldenSet struct {
+	Queries map[string]map[string]int `json:"queries"` // query -> product_id -> grade
+	Count   int                       `json:"count"`
+}
+
+const maxJudgmentsPerRequest = 500
+
+// normalizeQuery makes "Smart  Casual Top" and "smart casual top" one query.
+func normalizeQuery(q string) string {
+	return strings.ToLower(strings.Join(strings.Fields(q), " "))
+}
+
+func (r JudgmentsReq) validate() error {
+	if len(r.Judgments) == 0 {
+		return httpapi.InvalidField("judgments", "judgments is required")
+	}
+	if len(r.Judgments) > maxJudgmentsPerRequest {
+		return httpapi.InvalidField("judgments", "at most %d judgments per request", maxJudgmentsPerRequest)
+	}
+	for i, j := range r.Judgments {
+		if normalizeQuery(j.Query) == "" || j.ProductID == "" {
+			return httpapi.InvalidField(fmt.Sprintf("judgments[%d]", i), "judgments[%d] needs query and product_id", i)
+		}
+		if j.Grade < gradeIrrelevant || j.Grade > gradeHighly {
+			return httpapi.InvalidField(fmt.Sprintf("judgments[%d].grade", i), "judgments[%d].grade must be between %d and %d", i, gradeIrrelevant, gradeHighly)
+		}
+	}
+	return nil
+}
+
+// saveJudgments upserts labels; relabeling a pair replaces the old grade.
+func saveJudgments(ctx context.Context, pool *pgxpool.Pool, tenantID, judge string, req JudgmentsReq) ([]RelevanceJudgment, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	out := make([]RelevanceJudgment, 0, len(req.Judgments))
+	for _, in := range req.Judgments {
+		j := RelevanceJudgment{Query: normalizeQuery(in.Query), ProductID: in.ProductID, Grade: in.Grade, Notes: in.Notes, Judge: judge}
+		err := tx.QueryRow(ctx, `
+INSERT INTO relevance_judgments (tenant_id, query, product_id, grade, notes, judge)
+VALUES ($1,$2,$3,$4,$5,$6)
+ON CONFLICT (tenant_id, query, product_id) DO UPDATE
+SET grade=EXCLUDED.grade, notes=EXCLUDED.notes, judge=EXCLUDED.judge, updated_at=now()
+RETURNING id::text, updated_at
+`, tenantID, j.Query, j.ProductID, j.Grade, j.Notes, judge).Scan(&j.ID, &j.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	return out, tx.Commit(ctx)
+}
+
+func listJudgments(ctx context.Context, pool *pgxpool.Pool, tenantID, query string, limit int) ([]RelevanceJudgment, error) {
+	rows, err := pool.Query(ctx, `
+SELECT id::text, query, product_id, grade, notes, judge, updated_at
+FROM relevance_judgments
+WHERE tenant_id=$1 AND ($2::text IS NULL OR query=$2)
+ORDER BY query, grade DESC, product_id
+LIMIT $3
+`, tenantID, search.NullText(normalizeQuery(query)), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []RelevanceJudgment{}
+	for rows.Next() {
+		var j RelevanceJudgment
+		if err := rows.Scan(&j.ID, &j.Query, &j.ProductID, &j.Grade, &j.Notes, &j.Judge, &j.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+func deleteJudgment(ctx context.Context, pool *pgxpool.Pool, tenantID, id string) (bool, error) {
+	tag, err := pool.Exec(ctx, `DELETE FROM relevance_judgments WHERE id::text=$1 AND tenant_id=$2`, id, tenantID)
+	return tag.RowsAffected() > 0, err
+}
+
+// judgmentCandidates runs the live search for query and attaches existing
+// labels, so the dashboard can label exactly what shoppers would see.
+func judgmentCandidates(ctx context.Context, pool *pgxpool.Pool, tenantID, query string, limit int) ([]JudgmentCandidate, error) {
+	hits, err := searchHits(ctx, pool, searchParams{Query: query, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	labeled, err := listJudgments(ctx, pool, tenantID, query, maxJudgmentsPerRequest)
+	if err != nil {
+		return nil, err
+	}
+	grades := make(map[string]int, len(labeled))
+	for _, j := range labeled {
+		grades[j.ProductID] = j.Grade
+	}
+
+	out := make([]JudgmentCandidate, 0, len(hits))
+	for _, h := range hits {
+		c := JudgmentCandidate{Hit: h}
+		if g, ok := grades[h.ProductID]; ok {
+			c.Grade = &g
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func goldenSet(ctx context.Context, pool *pgxpool.Pool, tenantID string) (GoldenSet, error) {
+	gs := GoldenSet{Queries: map[string]map[string]int{}}
+	rows, err := pool.Query(ctx, `
+SELECT query, product_id, grade FROM relevance_judgments WHERE tenant_id=$1
+`, tenantID)
+	if err != nil {
+		return gs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var q, id string
+		var grade int
+		if err := rows.Scan(&q, &id, &grade); err != nil {
+			return gs, err
+		}
+		if gs.Queries[q] == nil {
+			gs.Queries[q] = map[string]int{}
+		}
+		gs.Queries[q][id] = grade
+		gs.Count++
+	}
+	return gs, rows.Err()
+}
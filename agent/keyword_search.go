@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Keyword fallback: when a query can't be embedded (the embedding API is
+// down, rate limiting, or has no endpoint in the tenant's residency zone),
+// /search matches the query's words against product text in Postgres
+// instead of failing, and flags the response "degraded". Filters, sorting
+// and the re-ranking that needs no vector still apply.
+
+var errEmbedUnavailable = errors.New("query embedding unavailable")
+
+// keywordDocSQL is the text a keyword search matches. Migration 00027
+// indexes exactly this expression, so change both together.
+const keywordDocSQL = `to_tsvector('english', coalesce(title, '') || ' ' || coalesce(brand, '') || ' ' || coalesce(category, '') || ' ' || coalesce(material, ''))`
+
+// maxKeywordTerms bounds the OR-ed terms of one search.
+const maxKeywordTerms = 12
+
+// minSubstringTerm is the shortest term also matched inside title words,
+// which catches partial words ("chino" in "Chinos") full-text stemming
+// misses without matching every title containing "an".
+const minSubstringTerm = 4
+
+var keywordWord = regexp.MustCompile(`[a-z0-9]+`)
+
+// keywordTerms are the query's distinct words, lowercased and without
+// filler, in order. They only contain [a-z0-9], so joining them into a
+// tsquery is safe.
+func keywordTerms(q string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, w := range keywordWord.FindAllString(strings.ToLower(q), -1) {
+		if len(w) < 2 || fillerWords[w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		terms = append(terms, w)
+		if len(terms) == maxKeywordTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// keywordSQL matches products containing any of the terms in parameter n
+// (a text[]), full-text or as a title substring, and ranks better matches
+// first. With no terms, every product matches and rank is constant.
+func keywordSQL(n int) (where, rank string) {
+	arg := "$" + strconv.Itoa(n) + "::text[]"
+	query := `to_tsquery('english', array_to_string(` + arg + `, ' | '))`
+	where = `(cardinality(` + arg + `) = 0
+       OR ` + keywordDocSQL + ` @@ ` + query + `
+       OR title ILIKE ANY (SELECT '%' || t || '%' FROM unnest(` + arg + `) t WHERE length(t) >= ` + strconv.Itoa(minSubstringTerm) + `))`
+	rank = `CASE WHEN cardinality(` + arg + `) = 0 THEN 0 ELSE ts_rank(` + keywordDocSQL + `, ` + query + `) END DESC`
+	return where, rank
+}
+
+// embedUnavailable wraps an embedding failure so /search can tell it from
+// a database error, unless the caller went away.
+func embedUnavailable(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %w", errEmbedUnavailable, err)
+}
+
+// degradedWarning tells the caller why the results look different.
+const degradedWarning = "semantic search is unavailable; results are keyword matches on product text"
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/embeddings"
+)
+
+func TestKeywordTerms(t *testing.T) {
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"Linen chinos", []string{"linen", "chinos"}},
+		{"show me the waterproof jacket, size 10!", []string{"waterproof", "jacket", "size", "10"}},
+		{"boots boots BOOTS", []string{"boots"}},
+		{"x'); DROP TABLE product_embeddings; --", []string{"drop", "table", "product", "embeddings"}},
+		{"please", nil},
+	} {
+		if got := keywordTerms(tc.query); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("keywordTerms(%q) = %q, want %q", tc.query, got, tc.want)
+		}
+	}
+	long := strings.Repeat("word ", 5) + "a b c d e f g h i j k l m n o p q r s t u v w x y z aa bb cc dd ee ff gg hh ii jj kk ll"
+	if n := len(keywordTerms(long)); n != maxKeywordTerms {
+		t.Errorf("%d terms, want at most %d", n, maxKeywordTerms)
+	}
+}
+
+// downEmbedder fails like an unreachable embedding API.
+type downEmbedder struct{}
+
+func (downEmbedder) Name() string { return "down" }
+func (downEmbedder) Embed(context.Context, []string) ([][]float64, error) {
+	return nil, errors.New("openai error: 503 service unavailable")
+}
+
+func TestEmbedQueryFailureIsUnavailable(t *testing.T) {
+	useTestConfig(t, nil)
+	embedBackends["fake"] = func() embeddings.Provider { return downEmbedder{} }
+
+	_, err := embedQuery(context.Background(), "waterproof hiking jacket")
+	if !errors.Is(err, errEmbedUnavailable) {
+		t.Errorf("embedQuery = %v, want errEmbedUnavailable", err)
+	}
+
+	// a caller that went away is not an outage
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := embedQuery(ctx, "waterproof hiking boots"); errors.Is(err, errEmbedUnavailable) {
+		t.Errorf("embedQuery with a cancelled context = %v, want a plain error", err)
+	}
+}
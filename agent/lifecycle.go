@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+)
+
+// Lifecycle states of an indexed product.
+const (
+	lifecycleDraft        = "draft"        // not for sale; never recommended
+	lifecycleActive       = "active"       // on sale
+	lifecyclePreorder     = "preorder"     // on sale, ships later; recommended on request
+	lifecycleDiscontinued = "discontinued" // not restocked; recommended while stock lasts
+)
+
+// LifecyclePrefs opt into products that can't ship yet.
+type LifecyclePrefs struct {
+	IncludePreorder bool `json:"include_preorder,omitempty"` // also recommend preorders, with their ships_at date
+}
+
+// lifecycleFromMedusa maps a Medusa product status to a lifecycle state.
+// Only published products are on sale; draft, proposed and rejected ones
+// count as drafts. Medusa has no preorder or discontinued status, so
+// merchants set those in metadata.lifecycle.
+func lifecycleFromMedusa(status string, meta map[string]any) string {
+	if status != "" && status != "published" {
+		return lifecycleDraft
+	}
+	return lifecycleFromMeta(meta)
+}
+
+// lifecycleFromShopify does the same for a Shopify product status. Archived
+// products are off the storefront, so they count as drafts too.
+func lifecycleFromShopify(status string, meta map[string]any) string {
+	if status != "" && status != "ACTIVE" {
+		return lifecycleDraft
+	}
+	return lifecycleFromMeta(meta)
+}
+
+// lifecycleFromMeta reads metadata.lifecycle, or metadata.preorder = true;
+// anything else is active.
+func lifecycleFromMeta(meta map[string]any) string {
+	switch s := strings.ToLower(strings.TrimSpace(catalog.MetaString(meta, "lifecycle"))); s {
+	case lifecycleDraft, lifecyclePreorder, lifecycleDiscontinued:
+		return s
+	}
+	if pre, _ := meta["preorder"].(bool); pre {
+		return lifecyclePreorder
+	}
+	return lifecycleActive
+}
+
+// shipsAtFromMeta reads metadata.ships_at, a preorder's expected ship date,
+// as YYYY-MM-DD or RFC 3339. It is nil when missing or unreadable.
+func shipsAtFromMeta(meta map[string]any) *time.Time {
+	s := strings.TrimSpace(catalog.MetaString(meta, "ships_at"))
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// lifecycleSQL keeps the products a shopper can be offered: active ones,
+// discontinued ones still in stock and, with includePreorder, preorders.
+func lifecycleSQL(alias string, includePreorder bool) string {
+	if alias != "" {
+		alias += "."
+	}
+	sql := "(" + alias + "lifecycle = 'active' OR (" + alias + "lifecycle = 'discontinued' AND COALESCE(" + alias + "stock_qty, 1) > 0)"
+	if includePreorder {
+		sql += " OR " + alias + "lifecycle = 'preorder'"
+	}
+	return sql + ")"
+}
+
+// preorderNote tells the shopper when a preorder pick ships, or is empty
+// for anything else.
+func preorderNote(h Hit) string {
+	switch {
+	case h.Lifecycle != lifecyclePreorder:
+		return ""
+	case h.ShipsAt == "":
+		return "It's a preorder."
+	default:
+		return fmt.Sprintf("It's a preorder, expected to ship on %s.", h.ShipsAt)
+	}
+}
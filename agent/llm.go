@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+)
+
+const systemPrompt = "You are a precise shopping assistant."
+
+// chatProvider is one LLM vendor's chat API. Providers translate a
+// chatRequest into their wire format; limits come from the caller.
+type chatProvider interface {
+	chat(ctx context.Context, req chatRequest) (chatResult, error)
+	defaultModel() string
+	configured() bool
+}
+
+type chatRequest struct {
+	Model       string
+	MaxTokens   int
+	Temperature float64
+	System      string
+	Prompt      string
+	// Schema, when set, asks for JSON matching it: OpenAI json_schema,
+	// Anthropic a forced tool call, Gemini responseSchema.
+	Schema *outputSchema
+}
+
+type outputSchema struct {
+	Name   string
+	Schema map[string]any
+}
+
+type chatResult struct {
+	Text             string // the JSON document when a schema was requested
+	PromptTokens     int
+	CompletionTokens int
+	Truncated        bool
+}
+
+var chatProviders = map[string]chatProvider{
+	config.ProviderOpenAI:    openAIProvider{},
+	config.ProviderAnthropic: anthropicProvider{},
+	config.ProviderGemini:    geminiProvider{},
+	config.ProviderAzure:     azureOpenAIProvider{},
+}
+
+// llmChat runs one completion for purpose on provider ("" = CSA_LLM_PROVIDER)
+// under that purpose's configured model, max_tokens and temperature. A
+// provider outside the tenant's residency zone is swapped for one inside
+// it; with none configured the call fails rather than leave the zone.
+func llmChat(ctx context.Context, provider, purpose, prompt string, schema *outputSchema) (string, error) {
+	if provider == "" {
+		provider = cfg().LLMProvider
+	}
+	if !sandboxFrom(ctx) {
+		var err error
+		if provider, err = residentChatProvider(residencyFrom(ctx), provider); err != nil {
+			return "", err
+		}
+	}
+	p, ok := chatProviders[provider]
+	if sandboxFrom(ctx) {
+		p, provider, ok = sandboxProvider{}, "sandbox", true
+	}
+	if !ok {
+		return "", fmt.Errorf("unknown LLM provider %q", provider)
+	}
+	if !p.configured() {
+		return "", fmt.Errorf("LLM provider %q is not configured", provider)
+	}
+	profile, ok := cfg().Profile(purpose)
+	if !ok {
+		return "", fmt.Errorf("no LLM profile for %q", purpose)
+	}
+	model := profile.Model(provider)
+	if model == "" {
+		model = p.defaultModel()
+	}
+
+	if err := checkUsageCap(ctx); err != nil {
+		return "", err
+	}
+
+	ctx, span := startSpan(ctx, "llm.chat", "llm.provider", provider, "llm.purpose", purpose, "llm.model", model)
+	defer span.End()
+
+	res, err := p.chat(ctx, chatRequest{
+		Model:       model,
+		MaxTokens:   profile.MaxTokens,
+		Temperature: profile.Temperature,
+		System:      systemPrompt,
+		Prompt:      prompt,
+		Schema:      schema,
+	})
+	call := map[string]any{"tool": "llm.chat", "provider": provider, "purpose": purpose, "model": model, "prompt": prompt}
+	if err != nil {
+		call["error"] = err.Error()
+		sessionFrom(ctx).record(ctx, eventToolCall, call)
+		return "", err
+	}
+	recordUsage(ctx, usageChat, model, res.PromptTokens, res.CompletionTokens)
+	call["output"], call["prompt_tokens"], call["completion_tokens"] = res.Text, res.PromptTokens, res.CompletionTokens
+	sessionFrom(ctx).record(ctx, eventToolCall, call)
+	logOutcome(ctx,
+		slog.String("llm_provider", provider),
+		slog.String("llm_purpose", purpose),
+		slog.String("llm_model", model),
+		slog.Int("llm_prompt_tokens", res.PromptTokens),
+		slog.Int("llm_completion_tokens", res.CompletionTokens))
+	if res.Truncated {
+		slog.WarnContext(ctx, "llm: output truncated at max_tokens", "provider", provider, "purpose", purpose, "max_tokens", profile.MaxTokens)
+	}
+	return res.Text, nil
+}
+
+// postJSON sends body and decodes a 2xx response into out.
+func postJSON(ctx context.Context, url string, headers map[string]string, body, out any) error {
+	b, _ := json.Marshal(body)
+	req, _ := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		raw, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("status %d: %s", res.StatusCode, string(raw))
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+type openAIProvider struct{}
+
+func (openAIProvider) configured() bool     { return cfg().OpenAI.APIKey != "" }
+func (openAIProvider) defaultModel() string { return cfg().OpenAI.ChatModel }
+
+func (openAIProvider) chat(ctx context.Context, req chatRequest) (chatResult, error) {
+	res, err := openAIChat(ctx, cfg().OpenAI.BaseURL+"/chat/completions",
+		map[string]string{"Authorization": "Bearer " + cfg().OpenAI.APIKey}, req)
+	if err != nil {
+		return chatResult{}, fmt.Errorf("openai error: %w", err)
+	}
+	return res, nil
+}
+
+// azureOpenAIProvider calls an Azure OpenAI deployment, which speaks the
+// OpenAI chat format; the model is the deployment name.
+type azureOpenAIProvider struct{}
+
+func (azureOpenAIProvider) configured() bool     { return cfg().Azure.Configured() }
+func (azureOpenAIProvider) defaultModel() string { return cfg().Azure.ChatDeployment }
+
+func (azureOpenAIProvider) chat(ctx context.Context, req chatRequest) (chatResult, error) {
+	a := cfg().Azure
+	u := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
+		a.Endpoint, url.PathEscape(req.Model), url.QueryEscape(a.APIVersion))
+	res, err := openAIChat(ctx, u, map[string]string{"api-key": a.APIKey}, req)
+	if err != nil {
+		return chatResult{}, fmt.Errorf("azure openai error: %w", err)
+	}
+	return res, nil
+}
+
+// openAIChat posts req in the OpenAI chat completions format to u.
+func openAIChat(ctx context.Context, u string, headers map[string]string, req chatRequest) (chatResult, error) {
+	body := map[string]any{
+		"model":       req.Model,
+		"max_tokens":  req.MaxTokens,
+		"temperature": req.Temperature,
+		"messages": []map[string]string{
+			{"role": "system", "content": req.System},
+			{"role": "user", "content": req.Prompt},
+		},
+	}
+	if req.Schema != nil {
+		body["response_format"] = map[string]any{
+			"type":        "json_schema",
+			"json_schema": map[string]any{"name": req.Schema.Name, "schema": req.Schema.Schema, "strict": true},
+		}
+	}
+
+	var parsed struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := postJSON(ctx, u, headers, body, &parsed); err != nil {
+		return chatResult{}, err
+	}
+	if len(parsed.Choices) == 0 {
+		return chatResult{}, fmt.Errorf("no completion returned")
+	}
+	return chatResult{
+		Text:             parsed.Choices[0].Message.Content,
+		PromptTokens:     parsed.Usage.PromptTokens,
+		CompletionTokens: parsed.Usage.CompletionTokens,
+		Truncated:        parsed.Choices[0].FinishReason == "length",
+	}, nil
+}
+
+// anthropicProvider calls the Messages API. Structured output is a forced
+// call to a single tool whose input_schema is the requested schema; the
+// tool input is returned as the JSON text.
+type anthropicProvider struct{}
+
+const anthropicVersion = "2023-06-01"
+
+func (anthropicProvider) configured() bool     { return cfg().Anthropic.APIKey != "" }
+func (anthropicProvider) defaultModel() string { return cfg().Anthropic.ChatModel }
+
+func (anthropicProvider) chat(ctx context.Context, req chatRequest) (chatResult, error) {
+	body := map[string]any{
+		"model":       req.Model,
+		"max_tokens":  req.MaxTokens,
+		"temperature": req.Temperature,
+		"system":      req.System,
+		"messages":    []map[string]string{{"role": "user", "content": req.Prompt}},
+	}
+	if req.Schema != nil {
+		body["tools"] = []map[string]any{{
+			"name":         req.Schema.Name,
+			"description":  "Return the answer in this structure.",
+			"input_schema": req.Schema.Schema,
+		}}
+		body["tool_choice"] = map[string]any{"type": "tool", "name": req.Schema.Name}
+	}
+
+	var parsed struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			Name  string          `json:"name"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+		Usage      struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	err := postJSON(ctx, cfg().Anthropic.BaseURL+"/v1/messages", map[string]string{
+		"x-api-key":         cfg().Anthropic.APIKey,
+		"anthropic-version": anthropicVersion,
+	}, body, &parsed)
+	if err != nil {
+		return chatResult{}, fmt.Errorf("anthropic error: %w", err)
+	}
+
+	res := chatResult{
+		PromptTokens:     parsed.Usage.InputTokens,
+		CompletionTokens: parsed.Usage.OutputTokens,
+		Truncated:        parsed.StopReason == "max_tokens",
+	}
+	var text strings.Builder
+	for _, c := range parsed.Content {
+		switch {
+		case c.Type == "tool_use" && req.Schema != nil && c.Name == req.Schema.Name:
+			res.Text = string(c.Input)
+			return res, nil
+		case c.Type == "text":
+			text.WriteString(c.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return chatResult{}, fmt.Errorf("no completion returned")
+	}
+	res.Text = text.String()
+	return res, nil
+}
+
+// geminiProvider calls generateContent. Structured output uses
+// responseMimeType application/json with a responseSchema.
+type geminiProvider struct{}
+
+func (geminiProvider) configured() bool     { return cfg().Gemini.APIKey != "" }
+func (geminiProvider) defaultModel() string { return cfg().Gemini.ChatModel }
+
+func (geminiProvider) chat(ctx context.Context, req chatRequest) (chatResult, error) {
+	gen := map[string]any{
+		"maxOutputTokens": req.MaxTokens,
+		"temperature":     req.Temperature,
+	}
+	if req.Schema != nil {
+		gen["responseMimeType"] = "application/json"
+		gen["responseSchema"] = geminiSchema(req.Schema.Schema)
+	}
+	body := map[string]any{
+		"systemInstruction": map[string]any{"parts": []map[string]string{{"text": req.System}}},
+		"contents":          []map[string]any{{"role": "user", "parts": []map[string]string{{"text": req.Prompt}}}},
+		"generationConfig":  gen,
+	}
+
+	var parsed struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"content"`
+			FinishReason string `json:"finishReason"`
+		} `json:"candidates"`
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+		} `json:"usageMetadata"`
+	}
+	url := fmt.Sprintf("%s/v1beta/models/%s:generateContent", cfg().Gemini.BaseURL, req.Model)
+	err := postJSON(ctx, url, map[string]string{"x-goog-api-key": cfg().Gemini.APIKey}, body, &parsed)
+	if err != nil {
+		return chatResult{}, fmt.Errorf("gemini error: %w", err)
+	}
+	if len(parsed.Candidates) == 0 {
+		return chatResult{}, fmt.Errorf("no completion returned")
+	}
+
+	var text strings.Builder
+	for _, p := range parsed.Candidates[0].Content.Parts {
+		text.WriteString(p.Text)
+	}
+	return chatResult{
+		Text:             text.String(),
+		PromptTokens:     parsed.UsageMetadata.PromptTokenCount,
+		CompletionTokens: parsed.UsageMetadata.CandidatesTokenCount,
+		Truncated:        parsed.Candidates[0].FinishReason == "MAX_TOKENS",
+	}, nil
+}
+
+// geminiSchema converts a JSON Schema to Gemini's OpenAPI subset, which
+// has no additionalProperties.
+func geminiSchema(s map[string]any) map[string]any {
+	out := make(map[string]any, len(s))
+	for k, v := range s {
+		switch k {
+		case "additionalProperties":
+			continue
+		case "properties":
+			props := map[string]any{}
+			for name, p := range v.(map[string]any) {
+				props[name] = geminiSchema(p.(map[string]any))
+			}
+			out[k] = props
+		case "items":
+			out[k] = geminiSchema(v.(map[string]any))
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+)
+
+func TestExplainOutfitWithCannedChat(t *testing.T) {
+	useTestConfig(t, nil)
+	ts := defaultTenantSettings(defaultTenant)
+	ctx := context.Background()
+
+	t.Run("model bullets", func(t *testing.T) {
+		chat := useCannedChat(t, &cannedChat{replies: map[string]string{
+			"explanation": `{"bullets": ["You still need a bottom and shoes.", "Linen Chinos keep it smart for £45."]}`,
+		}})
+		got, err := explainOutfitWithFallback(ctx, sampleOutfit(), ts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{"You still need a bottom and shoes.", "Linen Chinos keep it smart for £45."}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("bullets = %q, want %q", got, want)
+		}
+		calls := chat.calls()
+		if len(calls) != 1 || !strings.Contains(calls[0].Prompt, `"title":"Linen Chinos"`) || calls[0].Schema != explainSchema {
+			t.Errorf("chat requests = %+v", calls)
+		}
+	})
+
+	t.Run("model error falls back", func(t *testing.T) {
+		useCannedChat(t, &cannedChat{err: errors.New("rate limited")})
+		got, err := explainOutfitWithFallback(ctx, sampleOutfit(), ts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fallbackExplain(sampleOutfit(), ts.ExplainOptions); !reflect.DeepEqual(got, want) {
+			t.Errorf("bullets = %q, want the fallback %q", got, want)
+		}
+	})
+
+	t.Run("unreadable output falls back", func(t *testing.T) {
+		useCannedChat(t, &cannedChat{def: "Here are some thoughts!"})
+		got, _ := explainOutfitWithFallback(ctx, sampleOutfit(), ts)
+		if want := fallbackExplain(sampleOutfit(), ts.ExplainOptions); !reflect.DeepEqual(got, want) {
+			t.Errorf("bullets = %q, want the fallback %q", got, want)
+		}
+	})
+
+	t.Run("no hits skips the model", func(t *testing.T) {
+		chat := useCannedChat(t, &cannedChat{def: `{"bullets": ["unused"]}`})
+		resp := sampleOutfit()
+		for i := range resp.Results {
+			resp.Results[i].Hits = nil
+		}
+		if _, err := explainOutfitWithFallback(ctx, resp, ts); err != nil {
+			t.Fatal(err)
+		}
+		if n := len(chat.calls()); n != 0 {
+			t.Errorf("chat called %d times", n)
+		}
+	})
+}
+
+func TestClassifySlotWithCannedChat(t *testing.T) {
+	useTestConfig(t, map[string]string{"CSA_CATEGORY_CLASSIFIER": "llm"})
+	ctx := context.Background()
+
+	useCannedChat(t, &cannedChat{replies: map[string]string{"category": `{"category": "bottoms"}`}})
+	if got := classifySlot(ctx, catalog.Product{ID: "p1", Title: "Relaxed fit"}); got != "bottom" {
+		t.Errorf("classifySlot = %q, want bottom", got)
+	}
+
+	// an answer outside the category list, or a failed call, falls back to keywords
+	useCannedChat(t, &cannedChat{replies: map[string]string{"category": `{"category": "trousers"}`}})
+	if got := classifySlot(ctx, catalog.Product{ID: "p3", Title: "Relaxed fit"}); got != "" {
+		t.Errorf("classifySlot = %q, want no slot", got)
+	}
+	useCannedChat(t, &cannedChat{err: errors.New("timeout")})
+	if got := classifySlot(ctx, catalog.Product{ID: "p2", Title: "Waxed jacket"}); got != "outerwear" {
+		t.Errorf("classifySlot = %q, want outerwear", got)
+	}
+}
+
+func TestFakeEmbeddingBackend(t *testing.T) {
+	useTestConfig(t, nil)
+	ctx := context.Background()
+
+	a, err := embedText(ctx, "linen chinos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := embedText(ctx, "linen chinos")
+	if !reflect.DeepEqual(a, b) {
+		t.Error("the fake backend is not deterministic")
+	}
+	if p, _ := embedder(ctx); p.Name() != "fake" {
+		t.Errorf("embedder = %s, want fake", p.Name())
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+)
+
+// wireRequest is what a provider's fake API received.
+type wireRequest struct {
+	path   string
+	header http.Header
+	body   map[string]any
+}
+
+// fakeLLMAPI answers every POST with reply and keeps the last request.
+func fakeLLMAPI(t *testing.T, reply any) (*httptest.Server, *wireRequest) {
+	t.Helper()
+	got := &wireRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.path, got.header = r.URL.Path, r.Header.Clone()
+		if err := json.NewDecoder(r.Body).Decode(&got.body); err != nil {
+			t.Errorf("request body: %v", err)
+		}
+		writeFakeJSON(w, reply)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, got
+}
+
+var wireSchema = &outputSchema{Name: "answer", Schema: map[string]any{
+	"type":                 "object",
+	"properties":           map[string]any{"ok": map[string]any{"type": "boolean"}},
+	"required":             []string{"ok"},
+	"additionalProperties": false,
+}}
+
+// jsonAt reads a nested value of a decoded JSON body, e.g. jsonAt(b, "messages", 0, "role").
+func jsonAt(v any, keys ...any) any {
+	for _, k := range keys {
+		switch k := k.(type) {
+		case string:
+			m, _ := v.(map[string]any)
+			v = m[k]
+		case int:
+			a, _ := v.([]any)
+			if k >= len(a) {
+				return nil
+			}
+			v = a[k]
+		}
+	}
+	return v
+}
+
+func TestLLMWireFormats(t *testing.T) {
+	const models = "gpt-4o,anthropic=claude-3-5-sonnet-latest,gemini=gemini-1.5-pro"
+
+	t.Run("openai", func(t *testing.T) {
+		srv, got := fakeLLMAPI(t, map[string]any{
+			"choices": []any{map[string]any{"message": map[string]any{"content": `{"ok": true}`}, "finish_reason": "stop"}},
+			"usage":   map[string]any{"prompt_tokens": 12, "completion_tokens": 3},
+		})
+		useTestConfig(t, map[string]string{"OPENAI_BASE_URL": srv.URL, "CSA_LLM_EXPLAIN_MODEL": models})
+		text, err := llmChat(context.Background(), config.ProviderOpenAI, config.LLMExplain, "hello", wireSchema)
+		if err != nil || text != `{"ok": true}` {
+			t.Fatalf("chat = %q, %v", text, err)
+		}
+		if got.path != "/chat/completions" || got.header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("sent %s with Authorization %q", got.path, got.header.Get("Authorization"))
+		}
+		b := got.body
+		if b["model"] != "gpt-4o" || b["max_tokens"] != float64(300) || jsonAt(b, "messages", 0, "role") != "system" ||
+			jsonAt(b, "messages", 1, "content") != "hello" {
+			t.Errorf("body = %v", b)
+		}
+		if jsonAt(b, "response_format", "type") != "json_schema" || jsonAt(b, "response_format", "json_schema", "name") != "answer" ||
+			jsonAt(b, "response_format", "json_schema", "strict") != true {
+			t.Errorf("response_format = %v", b["response_format"])
+		}
+	})
+
+	t.Run("anthropic", func(t *testing.T) {
+		srv, got := fakeLLMAPI(t, map[string]any{
+			"content":     []any{map[string]any{"type": "tool_use", "name": "answer", "input": map[string]any{"ok": true}}},
+			"stop_reason": "tool_use",
+			"usage":       map[string]any{"input_tokens": 12, "output_tokens": 3},
+		})
+		useTestConfig(t, map[string]string{"ANTHROPIC_API_KEY": "ak-test", "ANTHROPIC_BASE_URL": srv.URL, "CSA_LLM_EXPLAIN_MODEL": models})
+		text, err := llmChat(context.Background(), config.ProviderAnthropic, config.LLMExplain, "hello", wireSchema)
+		if err != nil || text != `{"ok":true}` {
+			t.Fatalf("chat = %q, %v", text, err)
+		}
+		if got.path != "/v1/messages" || got.header.Get("x-api-key") != "ak-test" || got.header.Get("anthropic-version") != anthropicVersion {
+			t.Errorf("sent %s with headers %v", got.path, got.header)
+		}
+		b := got.body
+		if b["model"] != "claude-3-5-sonnet-latest" || b["system"] != systemPrompt || jsonAt(b, "messages", 0, "content") != "hello" {
+			t.Errorf("body = %v", b)
+		}
+		if jsonAt(b, "tools", 0, "name") != "answer" || jsonAt(b, "tools", 0, "input_schema", "type") != "object" ||
+			jsonAt(b, "tool_choice", "type") != "tool" || jsonAt(b, "tool_choice", "name") != "answer" {
+			t.Errorf("tools = %v, tool_choice = %v", b["tools"], b["tool_choice"])
+		}
+	})
+
+	t.Run("gemini", func(t *testing.T) {
+		srv, got := fakeLLMAPI(t, map[string]any{
+			"candidates":    []any{map[string]any{"content": map[string]any{"parts": []any{map[string]any{"text": `{"ok": true}`}}}, "finishReason": "STOP"}},
+			"usageMetadata": map[string]any{"promptTokenCount": 12, "candidatesTokenCount": 3},
+		})
+		useTestConfig(t, map[string]string{"GEMINI_API_KEY": "gk-test", "GEMINI_BASE_URL": srv.URL, "CSA_LLM_EXPLAIN_MODEL": models})
+		text, err := llmChat(context.Background(), config.ProviderGemini, config.LLMExplain, "hello", wireSchema)
+		if err != nil || text != `{"ok": true}` {
+			t.Fatalf("chat = %q, %v", text, err)
+		}
+		if got.path != "/v1beta/models/gemini-1.5-pro:generateContent" || got.header.Get("x-goog-api-key") != "gk-test" {
+			t.Errorf("sent %s with key %q", got.path, got.header.Get("x-goog-api-key"))
+		}
+		b := got.body
+		if jsonAt(b, "systemInstruction", "parts", 0, "text") != systemPrompt || jsonAt(b, "contents", 0, "parts", 0, "text") != "hello" {
+			t.Errorf("body = %v", b)
+		}
+		gen := jsonAt(b, "generationConfig")
+		if jsonAt(gen, "maxOutputTokens") != float64(300) || jsonAt(gen, "responseMimeType") != "application/json" {
+			t.Errorf("generationConfig = %v", gen)
+		}
+		if _, ok := jsonAt(gen, "responseSchema").(map[string]any)["additionalProperties"]; ok {
+			t.Error("responseSchema kept additionalProperties, which Gemini rejects")
+		}
+	})
+
+	t.Run("model overrides are per provider", func(t *testing.T) {
+		srv, got := fakeLLMAPI(t, map[string]any{
+			"content": []any{map[string]any{"type": "text", "text": "hi"}},
+			"usage":   map[string]any{"input_tokens": 1, "output_tokens": 1},
+		})
+		useTestConfig(t, map[string]string{"ANTHROPIC_API_KEY": "ak-test", "ANTHROPIC_BASE_URL": srv.URL, "CSA_LLM_EXPLAIN_MODEL": "gpt-4o"})
+		if _, err := llmChat(context.Background(), config.ProviderAnthropic, config.LLMExplain, "hello", nil); err != nil {
+			t.Fatal(err)
+		}
+		if got.body["model"] != cfg().Anthropic.ChatModel {
+			t.Errorf("anthropic was sent model %v, want its default %s", got.body["model"], cfg().Anthropic.ChatModel)
+		}
+	})
+}
+
+func TestLLMModelSettingValidation(t *testing.T) {
+	for _, v := range []string{"mistral=large", "anthropic=", "openai=a,openai=b"} {
+		t.Setenv("OPENAI_API_KEY", "sk-test")
+		t.Setenv("CSA_LLM_EXPLAIN_MODEL", v)
+		if _, err := config.Load(); err == nil {
+			t.Errorf("%q: want an error", v)
+		}
+	}
+}
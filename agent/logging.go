@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newLogger builds the process logger from CSA_LOG_FORMAT / CSA_LOG_LEVEL.
+// Every record logged with a request context carries req_id (and trace_id
+// when tracing is on) without call sites having to pass them.
+func newLogger() *slog.Logger {
+	logLevel.Set(cfg().LogLevel)
+	opts := &slog.HandlerOptions{Level: &logLevel}
+	var h slog.Handler
+	if cfg().LogFormat == "text" {
+		h = slog.NewTextHandler(os.Stdout, opts)
+	} else {
+		h = slog.NewJSONHandler(os.Stdout, opts)
+	}
+	return slog.New(ctxHandler{h})
+}
+
+type ctxHandler struct{ slog.Handler }
+
+func (h ctxHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if id := requestID(ctx); id != "" {
+		rec.AddAttrs(slog.String("req_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		rec.AddAttrs(slog.String("trace_id", sc.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h ctxHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return ctxHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h ctxHandler) WithGroup(name string) slog.Handler {
+	return ctxHandler{h.Handler.WithGroup(name)}
+}
+
+// fatal logs err and exits; used only during startup.
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
+
+// requestOutcome collects per-request facts (hit counts, cache use, ...)
+// that handlers report for the access log line written by withLogging.
+type requestOutcome struct {
+	mu       sync.Mutex
+	hits     int
+	count    bool
+	tokens   int // embedding and chat tokens used
+	attrs    []slog.Attr
+	warnings []string // returned to the caller in the response meta
+}
+
+type outcomeKey struct{}
+
+func withOutcome(ctx context.Context) (context.Context, *requestOutcome) {
+	o := &requestOutcome{}
+	return context.WithValue(ctx, outcomeKey{}, o), o
+}
+
+// countHits adds n recommended products to the request's access log.
+func countHits(ctx context.Context, n int) {
+	if o, ok := ctx.Value(outcomeKey{}).(*requestOutcome); ok {
+		o.mu.Lock()
+		o.hits += n
+		o.count = true
+		o.mu.Unlock()
+	}
+}
+
+// countTokens adds n embedding or chat tokens to the request's access log.
+func countTokens(ctx context.Context, n int) {
+	if o, ok := ctx.Value(outcomeKey{}).(*requestOutcome); ok {
+		o.mu.Lock()
+		o.tokens += n
+		o.mu.Unlock()
+	}
+}
+
+// logOutcome adds attributes to the request's access log.
+func logOutcome(ctx context.Context, attrs ...slog.Attr) {
+	if o, ok := ctx.Value(outcomeKey{}).(*requestOutcome); ok {
+		o.mu.Lock()
+		o.attrs = append(o.attrs, attrs...)
+		o.mu.Unlock()
+	}
+}
+
+func (o *requestOutcome) logAttrs() []slog.Attr {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := append([]slog.Attr(nil), o.attrs...)
+	if o.count {
+		out = append(out, slog.Int("hits", o.hits))
+	}
+	if o.tokens > 0 {
+		out = append(out, slog.Int("tokens", o.tokens))
+	}
+	return out
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errMedusaAuthNotConfigured = errors.New("medusa admin auth not configured: set MEDUSA_API_TOKEN, MEDUSA_ADMIN_EMAIL/MEDUSA_ADMIN_PASSWORD, or MEDUSA_SESSION_TOKEN")
+
+var errMedusaStoreNotConfigured = errors.New("medusa store API not configured: set MEDUSA_PUBLISHABLE_KEY")
+
+// medusaStatusError is a non-2xx answer from Medusa.
+type medusaStatusError struct {
+	Status int
+	Body   string
+}
+
+func (e *medusaStatusError) Error() string {
+	return fmt.Sprintf("medusa error: status %d: %s", e.Status, e.Body)
+}
+
+// refresh a login token this long before its exp claim
+const medusaRefreshMargin = 2 * time.Minute
+
+// medusaSession holds the admin JWT obtained by email/password login.
+type medusaSession struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time // zero when the token carries no exp claim
+}
+
+var medusa = &medusaSession{}
+
+func medusaLoginConfigured() bool {
+	return cfg().Medusa.AdminEmail != "" && cfg().Medusa.AdminPassword != ""
+}
+
+func medusaAuthConfigured() bool {
+	return cfg().Medusa.APIToken != "" || medusaLoginConfigured() || cfg().Medusa.SessionToken != ""
+}
+
+// authorization returns the Authorization header for the admin API, logging
+// in or refreshing first when the session is missing or about to expire.
+func (s *medusaSession) authorization(ctx context.Context) (string, error) {
+	switch {
+	case cfg().Medusa.APIToken != "":
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(cfg().Medusa.APIToken+":")), nil
+	case medusaLoginConfigured():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.token == "" || (!s.expires.IsZero() && time.Until(s.expires) < medusaRefreshMargin) {
+			if err := s.renewLocked(ctx); err != nil {
+				return "", err
+			}
+		}
+		return "Bearer " + s.token, nil
+	case cfg().Medusa.SessionToken != "":
+		return "Bearer " + cfg().Medusa.SessionToken, nil
+	}
+	return "", errMedusaAuthNotConfigured
+}
+
+// invalidate drops a token Medusa rejected so the next call logs in again.
+func (s *medusaSession) invalidate(rejected string) {
+	s.mu.Lock()
+	if "Bearer "+s.token == rejected {
+		s.token, s.expires = "", time.Time{}
+	}
+	s.mu.Unlock()
+}
+
+// renewLocked refreshes a live token, falling back to a fresh login.
+func (s *medusaSession) renewLocked(ctx context.Context) error {
+	if s.token != "" && time.Now().Before(s.expires) {
+		tok, err := medusaTokenCall(ctx, "/auth/token/refresh", "Bearer "+s.token, nil)
+		if err == nil {
+			s.setLocked(tok)
+			slog.InfoContext(ctx, "medusa: session refreshed", "expires", s.expires)
+			return nil
+		}
+		slog.WarnContext(ctx, "medusa: refresh failed, logging in again", "err", err)
+	}
+	tok, err := medusaTokenCall(ctx, "/auth/user/emailpass", "", map[string]string{
+		"email":    cfg().Medusa.AdminEmail,
+		"password": cfg().Medusa.AdminPassword,
+	})
+	if err != nil {
+		return fmt.Errorf("medusa login: %w", err)
+	}
+	s.setLocked(tok)
+	slog.InfoContext(ctx, "medusa: logged in", "expires", s.expires)
+	return nil
+}
+
+func (s *medusaSession) setLocked(tok string) {
+	s.token, s.expires = tok, jwtExpiry(tok)
+}
+
+func medusaTokenCall(ctx context.Context, path, auth string, body any) (string, error) {
+	var headers map[string]string
+	if auth != "" {
+		headers = map[string]string{"Authorization": auth}
+	}
+	if body == nil {
+		body = map[string]any{}
+	}
+	var out struct {
+		Token string `json:"token"`
+	}
+	if err := postJSON(ctx, cfg().Medusa.BaseURL+path, headers, body, &out); err != nil {
+		return "", err
+	}
+	if out.Token == "" {
+		return "", errors.New("no token in response")
+	}
+	return out.Token, nil
+}
+
+// jwtExpiry reads the exp claim without verifying the token; Medusa checks
+// the signature, we only need to know when to refresh.
+func jwtExpiry(tok string) time.Time {
+	parts := strings.Split(tok, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if json.Unmarshal(payload, &claims) != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// medusaGet calls the Medusa API with admin credentials. A 401 on a login
+// session (revoked or expired early) triggers one re-login and retry.
+// The caller closes the body; non-2xx responses are returned as errors.
+func medusaGet(ctx context.Context, path string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		auth, err := medusa.authorization(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req, _ := http.NewRequestWithContext(ctx, "GET", cfg().Medusa.BaseURL+path, nil)
+		req.Header.Set("Authorization", auth)
+		if cfg().Medusa.PublishableKey != "" {
+			req.Header.Set("x-publishable-api-key", cfg().Medusa.PublishableKey)
+		}
+
+		res, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode == http.StatusUnauthorized && attempt == 0 && medusaLoginConfigured() && cfg().Medusa.APIToken == "" {
+			res.Body.Close()
+			slog.WarnContext(ctx, "medusa: session rejected, re-authenticating", "path", path)
+			medusa.invalidate(auth)
+			continue
+		}
+		if res.StatusCode >= 300 {
+			return nil, medusaStatus(res)
+		}
+		return res, nil
+	}
+}
+
+// medusaStoreGet calls the Medusa store API with only the publishable key,
+// as a storefront would, for shopper-scoped resources such as carts. The
+// caller closes the body; non-2xx responses are returned as
+// *medusaStatusError.
+func medusaStoreGet(ctx context.Context, path string) (*http.Response, error) {
+	if cfg().Medusa.PublishableKey == "" {
+		return nil, errMedusaStoreNotConfigured
+	}
+	req, _ := http.NewRequestWithContext(ctx, "GET", cfg().Medusa.BaseURL+path, nil)
+	req.Header.Set("x-publishable-api-key", cfg().Medusa.PublishableKey)
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= 300 {
+		return nil, medusaStatus(res)
+	}
+	return res, nil
+}
+
+// medusaStatus reads and closes a non-2xx response.
+func medusaStatus(res *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(res.Body, 4<<10))
+	res.Body.Close()
+	return &medusaStatusError{Status: res.StatusCode, Body: string(raw)}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+func validateMetric(m string) error {
+	if m != "" && !slices.Contains(ranking.Metrics, m) {
+		return httpapi.InvalidField("distance_metric", "distance_metric must be one of %s", strings.Join(ranking.Metrics, ", "))
+	}
+	return nil
+}
+
+// metric is the distance metric the search compares embeddings with.
+func (p searchParams) metric() string {
+	if p.Metric != "" {
+		return p.Metric
+	}
+	return cfg().Embed.Metric
+}
+
+// ensureVectorIndex builds the HNSW index for CSA_DISTANCE_METRIC and
+// CSA_VECTOR_STORAGE if the migrations didn't: they only create the full
+// precision L2 one. An index serves one operator, so requests choosing
+// another metric fall back to a sequential scan. Building takes a while on a
+// large catalog, and blocks writes to product_embeddings until done.
+func ensureVectorIndex(ctx context.Context, pool *pgxpool.Pool) error {
+	storage, metric := cfg().Embed.Storage, cfg().Embed.Metric
+	name, column := search.Storage{Kind: storage}.Index(metric)
+	var exists bool
+	if err := pool.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, name).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	start := time.Now()
+	slog.Info("metric: building index", "metric", metric, "storage", storage, "index", name)
+	if _, err := pool.Exec(ctx, `CREATE INDEX IF NOT EXISTS `+name+`
+  ON product_embeddings USING hnsw (`+column+`)`); err != nil {
+		return err
+	}
+	slog.Info("metric: index built", "index", name, "elapsed", time.Since(start))
+	if storage != config.StorageFull {
+		full, _ := search.Storage{Kind: config.StorageFull}.Index(metric)
+		slog.Info("metric: search no longer uses the full-precision index; drop it, if present, to free memory", "index", full)
+	}
+	return nil
+}
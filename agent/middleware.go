@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+type ctxKey int
+
+const (
+	ctxRequestID ctxKey = iota
+	ctxTenant
+	ctxPrincipal
+	ctxZone // the tenant's residency zone; see residency.go
+)
+
+// withRequestID propagates the caller's X-Request-ID or generates one, and
+// echoes it on the response.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSpace(r.Header.Get("X-Request-ID"))
+		if id == "" || len(id) > 128 {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxRequestID, id)))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(ctxRequestID).(string)
+	return id
+}
+
+// withLogging writes one access log line per request, including whatever
+// outcome handlers reported via countHits / logOutcome.
+func withLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &httpapi.StatusRecorder{ResponseWriter: w}
+		ctx, outcome := withOutcome(r.Context())
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		if rec.Status == 0 {
+			rec.Status = http.StatusOK
+		}
+
+		level := slog.LevelInfo
+		if rec.Status >= 500 {
+			level = slog.LevelError
+		}
+		attrs := append([]slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.Status),
+			slog.Int("bytes", rec.Bytes),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+		}, outcome.logAttrs()...)
+		slog.LogAttrs(ctx, level, "http request", attrs...)
+	})
+}
+
+// withCORS allows the configured origins and answers preflight requests
+// before they reach the router.
+func withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && cfg().AllowsOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, x-publishable-api-key, X-Tenant-ID, X-Request-ID, X-API-Key, X-Session-ID, X-User-ID, Idempotency-Key, traceparent, tracestate")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, X-CSA-Signature, Idempotent-Replayed")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withAuth establishes who is calling. A presented API key must be valid and
+// fixes the tenant; anonymous callers may name a tenant via X-Tenant-ID.
+// Whether a route needs a key at all is decided per route by requireScope.
+func withAuth(pool *pgxpool.Pool) httpapi.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant := defaultTenant
+			if t := strings.TrimSpace(r.Header.Get("X-Tenant-ID")); t != "" {
+				tenant = t
+			}
+
+			var p *principal
+			if key := apiKeyFromRequest(r); key != "" {
+				var err error
+				p, err = authenticate(r.Context(), pool, r, key)
+				if errors.Is(err, errInvalidAPIKey) {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="csa"`)
+					httpapi.WriteError(w, err.Error(), http.StatusUnauthorized)
+					return
+				}
+				if err != nil {
+					httpapi.WriteError(w, "auth lookup: "+err.Error(), http.StatusInternalServerError)
+					return
+				}
+				tenant = p.TenantID
+			}
+
+			ctx := context.WithValue(r.Context(), ctxTenant, tenant)
+			ctx = context.WithValue(ctx, ctxPrincipal, p)
+			ctx, err := withResidency(ctx, pool, tenant)
+			if err != nil {
+				httpapi.WriteError(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
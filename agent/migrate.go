@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pressly/goose/v3"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/migrations"
+)
+
+type MigrationInfo struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	State     string     `json:"state"` // pending | applied | untracked
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type MigrateResp struct {
+	Applied    []MigrationInfo `json:"applied"`
+	Version    int64           `json:"version"`
+	Migrations []MigrationInfo `json:"migrations"`
+}
+
+// newMigrator migrates pool with the schema embedded in the binary.
+func newMigrator(pool *pgxpool.Pool) (*goose.Provider, error) {
+	return migrations.NewProvider(pool)
+}
+
+// migrate applies all pending migrations and reports the resulting state.
+func migrate(ctx context.Context, pool *pgxpool.Pool) (MigrateResp, error) {
+	var resp MigrateResp
+	p, err := newMigrator(pool)
+	if err != nil {
+		return resp, err
+	}
+	defer p.Close()
+
+	results, err := p.Up(ctx)
+	for _, r := range results {
+		if r.Error == nil {
+			resp.Applied = append(resp.Applied, MigrationInfo{Version: r.Source.Version, Name: migrationName(r.Source), State: string(goose.StateApplied)})
+			slog.InfoContext(ctx, "migrate: applied", "version", r.Source.Version, "name", migrationName(r.Source), "duration", r.Duration.String())
+		}
+	}
+	if err != nil {
+		return resp, err
+	}
+	if resp.Applied == nil {
+		resp.Applied = []MigrationInfo{}
+	}
+	return resp, fillStatus(ctx, p, &resp)
+}
+
+func migrationStatus(ctx context.Context, pool *pgxpool.Pool) (MigrateResp, error) {
+	resp := MigrateResp{Applied: []MigrationInfo{}}
+	p, err := newMigrator(pool)
+	if err != nil {
+		return resp, err
+	}
+	defer p.Close()
+	return resp, fillStatus(ctx, p, &resp)
+}
+
+func fillStatus(ctx context.Context, p *goose.Provider, resp *MigrateResp) error {
+	statuses, err := p.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		mi := MigrationInfo{Version: s.Source.Version, Name: migrationName(s.Source), State: string(s.State)}
+		if !s.AppliedAt.IsZero() {
+			mi.AppliedAt = &s.AppliedAt
+		}
+		resp.Migrations = append(resp.Migrations, mi)
+	}
+	resp.Version, err = p.GetDBVersion(ctx)
+	return err
+}
+
+func migrationName(s *goose.Source) string {
+	if s == nil {
+		return ""
+	}
+	return s.Path
+}
@@ -0,0 +1,165 @@
+-- Baseline: the schema previously applied by hand from db/init.sql. Every
+-- statement is idempotent so databases created from that file adopt it as-is.
+
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS vector;
+
+CREATE TABLE IF NOT EXISTS product_embeddings (
+  product_id TEXT PRIMARY KEY,
+  category   TEXT,
+  embedding  vector(1536),
+  eco_score  INT,
+  price_gbp  NUMERIC
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_embeddings_category ON product_embeddings(category);
+
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS title TEXT;
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS thumbnail TEXT;
+
+-- CLIP-style image embeddings of the thumbnail; dimension must match CSA_IMAGE_EMBED_DIM
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS image_embedding vector(512);
+CREATE INDEX IF NOT EXISTS idx_product_embeddings_image
+  ON product_embeddings USING hnsw (image_embedding vector_cosine_ops);
+
+-- stock from Medusa variants, refreshed at index time and by /sync-inventory
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS stock_qty INT;
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS variant_availability JSONB;
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS stock_synced_at TIMESTAMPTZ;
+
+-- every product shown by /search or /complete-outfit; feeds trending
+CREATE TABLE IF NOT EXISTS recommendation_events (
+  id         BIGSERIAL PRIMARY KEY,
+  product_id TEXT NOT NULL,
+  source     TEXT NOT NULL,
+  served_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_recommendation_events_served_at ON recommendation_events(served_at);
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS mv_trending_by_category AS
+SELECT category, product_id, served, rank
+FROM (
+  SELECT p.category, e.product_id, count(*)::int AS served,
+         row_number() OVER (PARTITION BY p.category ORDER BY count(*) DESC, e.product_id)::int AS rank
+  FROM recommendation_events e
+  JOIN product_embeddings p ON p.product_id = e.product_id
+  WHERE e.served_at > now() - interval '7 days'
+    AND p.category IS NOT NULL AND p.category <> ''
+  GROUP BY p.category, e.product_id
+) ranked
+WHERE rank <= 50;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mv_trending_by_category ON mv_trending_by_category(category, product_id);
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS mv_price_distribution AS
+SELECT category,
+       count(*)::int AS n,
+       min(price_gbp)::float8 AS min_price,
+       percentile_cont(0.25) WITHIN GROUP (ORDER BY price_gbp)::float8 AS p25,
+       percentile_cont(0.5)  WITHIN GROUP (ORDER BY price_gbp)::float8 AS median,
+       percentile_cont(0.75) WITHIN GROUP (ORDER BY price_gbp)::float8 AS p75,
+       max(price_gbp)::float8 AS max_price,
+       avg(price_gbp)::float8 AS avg_price
+FROM product_embeddings
+WHERE price_gbp > 0 AND category IS NOT NULL AND category <> ''
+GROUP BY category;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mv_price_distribution ON mv_price_distribution(category);
+
+CREATE TABLE IF NOT EXISTS mv_refresh_log (
+  view_name    TEXT PRIMARY KEY,
+  refreshed_at TIMESTAMPTZ NOT NULL
+);
+
+-- structured attributes extracted at index time (lowercased)
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS sizes TEXT[];
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS colors TEXT[];
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS brand TEXT;
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS material TEXT;
+CREATE INDEX IF NOT EXISTS idx_product_embeddings_sizes ON product_embeddings USING gin (sizes);
+CREATE INDEX IF NOT EXISTS idx_product_embeddings_colors ON product_embeddings USING gin (colors);
+CREATE INDEX IF NOT EXISTS idx_product_embeddings_brand ON product_embeddings(brand);
+
+-- per-merchant settings; tenants are identified by the X-Tenant-ID header
+CREATE TABLE IF NOT EXISTS tenant_settings (
+  tenant_id      TEXT PRIMARY KEY,
+  explain_engine TEXT NOT NULL DEFAULT 'llm', -- llm | template
+  updated_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+-- {max_bullets, priority, facts} for the deterministic fallback explanation
+ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS explain_options JSONB NOT NULL DEFAULT '{}';
+-- chat provider override (openai | anthropic | gemini); '' = CSA_LLM_PROVIDER
+ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS llm_provider TEXT NOT NULL DEFAULT '';
+
+-- eco label / certification IDs (recycled, organic, b_corp, ...) extracted at index time
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS eco_labels TEXT[];
+CREATE INDEX IF NOT EXISTS idx_product_embeddings_eco_labels ON product_embeddings USING gin (eco_labels);
+
+-- ISO 3166-1 alpha-2 country of manufacture, for the shipping-distance eco factor
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS origin_country TEXT;
+
+-- gift eligibility from metadata.gift_wrap / metadata.final_sale
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS gift_wrap BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS final_sale BOOLEAN NOT NULL DEFAULT false;
+
+-- owning tenant (NULL = default) and last (re)index time, used by /admin/purge
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS tenant_id TEXT;
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS indexed_at TIMESTAMPTZ;
+
+-- API keys are stored as sha256 hashes; the plaintext is shown once at creation
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+CREATE TABLE IF NOT EXISTS api_keys (
+  id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name         TEXT NOT NULL DEFAULT '',
+  tenant_id    TEXT NOT NULL,
+  scopes       TEXT[] NOT NULL,
+  key_hash     TEXT NOT NULL UNIQUE,
+  prefix       TEXT NOT NULL,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_used_at TIMESTAMPTZ,
+  revoked_at   TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant ON api_keys(tenant_id);
+
+-- outfits shoppers saved; inventory sync fills in substitutes for sold-out items
+CREATE TABLE IF NOT EXISTS saved_outfits (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id     TEXT NOT NULL,
+  name          TEXT NOT NULL DEFAULT '',
+  mission       TEXT NOT NULL,
+  min_eco_score INT NOT NULL DEFAULT 0,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE TABLE IF NOT EXISTS saved_outfit_items (
+  outfit_id      UUID NOT NULL REFERENCES saved_outfits(id) ON DELETE CASCADE,
+  slot           TEXT NOT NULL,
+  product_id     TEXT NOT NULL,
+  substitute     JSONB,
+  substituted_at TIMESTAMPTZ,
+  PRIMARY KEY (outfit_id, product_id)
+);
+CREATE INDEX IF NOT EXISTS idx_saved_outfit_items_product ON saved_outfit_items(product_id);
+
+-- human relevance labels per (query, product); the golden set for search evals
+CREATE TABLE IF NOT EXISTS relevance_judgments (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id  TEXT NOT NULL,
+  query      TEXT NOT NULL, -- lowercased, whitespace-collapsed
+  product_id TEXT NOT NULL,
+  grade      SMALLINT NOT NULL CHECK (grade BETWEEN 0 AND 3),
+  notes      TEXT NOT NULL DEFAULT '',
+  judge      TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (tenant_id, query, product_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS relevance_judgments;
+DROP TABLE IF EXISTS saved_outfit_items;
+DROP TABLE IF EXISTS saved_outfits;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS tenant_settings;
+DROP TABLE IF EXISTS mv_refresh_log;
+DROP MATERIALIZED VIEW IF EXISTS mv_price_distribution;
+DROP MATERIALIZED VIEW IF EXISTS mv_trending_by_category;
+DROP TABLE IF EXISTS recommendation_events;
+DROP TABLE IF EXISTS product_embeddings;
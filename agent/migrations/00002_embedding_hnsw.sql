@@ -0,0 +1,9 @@
+-- Text embeddings had no ANN index, so every search was a sequential scan.
+-- Searches order by L2 distance (<->), hence vector_l2_ops.
+
+-- +goose Up
+CREATE INDEX IF NOT EXISTS idx_product_embeddings_embedding
+  ON product_embeddings USING hnsw (embedding vector_l2_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_product_embeddings_embedding;
@@ -0,0 +1,19 @@
+-- Per-tenant keys for signing response bodies. secret is the HMAC key or the
+-- Ed25519 seed; public_key is set for ed25519 only. The newest unrevoked key
+-- of a tenant signs its responses.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS signing_keys (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id  TEXT NOT NULL,
+  alg        TEXT NOT NULL CHECK (alg IN ('hmac-sha256', 'ed25519')),
+  secret     BYTEA NOT NULL,
+  public_key BYTEA,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  revoked_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_signing_keys_tenant ON signing_keys(tenant_id, created_at DESC)
+  WHERE revoked_at IS NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS signing_keys;
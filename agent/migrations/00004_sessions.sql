@@ -0,0 +1,31 @@
+-- Shopper sessions named by the storefront via X-Session-ID, and what
+-- happened in them: request/response turns, LLM tool calls, products
+-- recommended, and feedback. Consent flags gate exports.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS sessions (
+  id               TEXT NOT NULL,
+  tenant_id        TEXT NOT NULL,
+  user_id          TEXT,
+  consent_qa       BOOLEAN NOT NULL DEFAULT false,
+  consent_training BOOLEAN NOT NULL DEFAULT false,
+  created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_seen_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (tenant_id, id)
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(tenant_id, user_id) WHERE user_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS session_events (
+  id         BIGSERIAL PRIMARY KEY,
+  tenant_id  TEXT NOT NULL,
+  session_id TEXT NOT NULL,
+  kind       TEXT NOT NULL CHECK (kind IN ('turn', 'tool_call', 'recommendation', 'feedback')),
+  payload    JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  FOREIGN KEY (tenant_id, session_id) REFERENCES sessions(tenant_id, id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_session_events_session ON session_events(tenant_id, session_id, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS session_events;
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,13 @@
+-- When a product was first indexed, for the new-arrivals filter and the
+-- recency boost. indexed_at moves on every reindex, so it can't serve.
+-- Existing rows take their last index time as the best available guess.
+
+-- +goose Up
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS first_indexed_at TIMESTAMPTZ;
+UPDATE product_embeddings SET first_indexed_at = COALESCE(indexed_at, now()) WHERE first_indexed_at IS NULL;
+ALTER TABLE product_embeddings ALTER COLUMN first_indexed_at SET DEFAULT now();
+CREATE INDEX IF NOT EXISTS idx_product_embeddings_first_indexed ON product_embeddings(first_indexed_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_product_embeddings_first_indexed;
+ALTER TABLE product_embeddings DROP COLUMN IF EXISTS first_indexed_at;
@@ -0,0 +1,13 @@
+-- Merchant-supplied merchandising data for clearance campaigns: a clearance
+-- flag and the product's gross margin. Neither comes from Medusa, so
+-- reindexing leaves them alone.
+
+-- +goose Up
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS clearance BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS margin_pct REAL;
+CREATE INDEX IF NOT EXISTS idx_product_embeddings_clearance ON product_embeddings(product_id) WHERE clearance;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_product_embeddings_clearance;
+ALTER TABLE product_embeddings DROP COLUMN IF EXISTS margin_pct;
+ALTER TABLE product_embeddings DROP COLUMN IF EXISTS clearance;
@@ -0,0 +1,13 @@
+-- Last successful catalog index per tenant and provider, the lower bound
+-- for incremental runs (POST /index-products?mode=incremental).
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS catalog_sync (
+  tenant_id      TEXT NOT NULL,
+  provider       TEXT NOT NULL, -- medusa | shopify
+  last_synced_at TIMESTAMPTZ NOT NULL,
+  PRIMARY KEY (tenant_id, provider)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS catalog_sync;
@@ -0,0 +1,11 @@
+-- Every product attribute known at index time (options, metadata and the
+-- extracted fields) as {"key": ["value", ...]}, lowercased, so /search can
+-- filter on attributes that don't have a column of their own.
+
+-- +goose Up
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS idx_product_embeddings_attributes ON product_embeddings USING gin (attributes);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_product_embeddings_attributes;
+ALTER TABLE product_embeddings DROP COLUMN IF EXISTS attributes;
@@ -0,0 +1,9 @@
+-- Where a product's eco_score came from: 'merchant' (catalog metadata) or
+-- 'estimated' (inferred by the LLM estimator). NULL means no score at all.
+
+-- +goose Up
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS eco_score_source TEXT;
+UPDATE product_embeddings SET eco_score_source = 'merchant' WHERE eco_score > 0 AND eco_score_source IS NULL;
+
+-- +goose Down
+ALTER TABLE product_embeddings DROP COLUMN IF EXISTS eco_score_source;
@@ -0,0 +1,16 @@
+-- Merchant-defined outfit missions. Built-in missions (smart_casual,
+-- business_casual, outdoor_rain) live in code; a row with the same name
+-- overrides one for its tenant.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS missions (
+  tenant_id  TEXT NOT NULL,
+  name       TEXT NOT NULL,
+  slots      TEXT[] NOT NULL,
+  query_hint TEXT NOT NULL DEFAULT '',
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (tenant_id, name)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS missions;
@@ -0,0 +1,13 @@
+-- Default constraints per mission, applied when an outfit request omits them.
+
+-- +goose Up
+ALTER TABLE missions
+  ADD COLUMN IF NOT EXISTS min_eco_score INT NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS budget_gbp NUMERIC NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS style TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE missions
+  DROP COLUMN IF EXISTS min_eco_score,
+  DROP COLUMN IF EXISTS budget_gbp,
+  DROP COLUMN IF EXISTS style;
@@ -0,0 +1,42 @@
+-- Sandbox API keys and the seeded sandbox catalog. Sandbox products share
+-- product_embeddings with the real catalog but are only visible to sandbox
+-- keys, and are left out of the price distribution.
+
+-- +goose Up
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS sandbox BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS sandbox BOOLEAN NOT NULL DEFAULT false;
+
+DROP MATERIALIZED VIEW IF EXISTS mv_price_distribution;
+CREATE MATERIALIZED VIEW mv_price_distribution AS
+SELECT category,
+       count(*)::int AS n,
+       min(price_gbp)::float8 AS min_price,
+       percentile_cont(0.25) WITHIN GROUP (ORDER BY price_gbp)::float8 AS p25,
+       percentile_cont(0.5)  WITHIN GROUP (ORDER BY price_gbp)::float8 AS median,
+       percentile_cont(0.75) WITHIN GROUP (ORDER BY price_gbp)::float8 AS p75,
+       max(price_gbp)::float8 AS max_price,
+       avg(price_gbp)::float8 AS avg_price
+FROM product_embeddings
+WHERE price_gbp > 0 AND category IS NOT NULL AND category <> '' AND NOT sandbox
+GROUP BY category;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mv_price_distribution ON mv_price_distribution(category);
+
+-- +goose Down
+DROP MATERIALIZED VIEW IF EXISTS mv_price_distribution;
+DELETE FROM product_embeddings WHERE sandbox;
+ALTER TABLE product_embeddings DROP COLUMN IF EXISTS sandbox;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS sandbox;
+
+CREATE MATERIALIZED VIEW mv_price_distribution AS
+SELECT category,
+       count(*)::int AS n,
+       min(price_gbp)::float8 AS min_price,
+       percentile_cont(0.25) WITHIN GROUP (ORDER BY price_gbp)::float8 AS p25,
+       percentile_cont(0.5)  WITHIN GROUP (ORDER BY price_gbp)::float8 AS median,
+       percentile_cont(0.75) WITHIN GROUP (ORDER BY price_gbp)::float8 AS p75,
+       max(price_gbp)::float8 AS max_price,
+       avg(price_gbp)::float8 AS avg_price
+FROM product_embeddings
+WHERE price_gbp > 0 AND category IS NOT NULL AND category <> ''
+GROUP BY category;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mv_price_distribution ON mv_price_distribution(category);
@@ -0,0 +1,15 @@
+-- Scheduled jobs shared by all replicas: the last slot each job ran for, so
+-- a slot runs on exactly one replica (see runScheduled).
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS job_runs (
+  job         TEXT PRIMARY KEY,      -- catalog_sync | refresh_views
+  last_slot   TIMESTAMPTZ NOT NULL,  -- scheduled time of the last run
+  holder      TEXT NOT NULL,         -- replica that ran it (host:pid)
+  started_at  TIMESTAMPTZ NOT NULL,
+  finished_at TIMESTAMPTZ,
+  error       TEXT
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS job_runs;
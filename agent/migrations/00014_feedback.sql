@@ -0,0 +1,36 @@
+-- Shopper feedback on recommended products (POST /feedback) and the
+-- per-product and per-query tallies ranking reads. The view covers the last
+-- 90 days; query_norm '' holds each product's tally across all queries.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS feedback (
+  id         BIGSERIAL PRIMARY KEY,
+  tenant_id  TEXT NOT NULL,
+  product_id TEXT NOT NULL,
+  signal     TEXT NOT NULL CHECK (signal IN ('up', 'down', 'add_to_cart')),
+  query      TEXT NOT NULL DEFAULT '',
+  query_norm TEXT NOT NULL DEFAULT '', -- lowercased, single-spaced
+  source     TEXT NOT NULL DEFAULT '', -- search | complete-outfit | ...
+  session_id TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_feedback_tenant_time ON feedback(tenant_id, created_at);
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS mv_feedback_scores AS
+SELECT tenant_id, query_norm, product_id,
+       count(*) FILTER (WHERE signal = 'up')::int          AS ups,
+       count(*) FILTER (WHERE signal = 'down')::int        AS downs,
+       count(*) FILTER (WHERE signal = 'add_to_cart')::int AS carts
+FROM (
+  SELECT tenant_id, query_norm, product_id, signal FROM feedback
+  WHERE query_norm <> '' AND created_at > now() - interval '90 days'
+  UNION ALL
+  SELECT tenant_id, '', product_id, signal FROM feedback
+  WHERE created_at > now() - interval '90 days'
+) f
+GROUP BY tenant_id, query_norm, product_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mv_feedback_scores ON mv_feedback_scores(tenant_id, query_norm, product_id);
+
+-- +goose Down
+DROP MATERIALIZED VIEW IF EXISTS mv_feedback_scores;
+DROP TABLE IF EXISTS feedback;
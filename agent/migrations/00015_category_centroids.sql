@@ -0,0 +1,14 @@
+-- Mean embedding of each category, used to route /search queries that name
+-- no garment type to the categories they are closest to. Sandbox products
+-- get their own centroids.
+
+-- +goose Up
+CREATE MATERIALIZED VIEW IF NOT EXISTS mv_category_centroids AS
+SELECT sandbox, category, avg(embedding) AS centroid, count(*)::int AS n
+FROM product_embeddings
+WHERE embedding IS NOT NULL AND category IS NOT NULL AND category <> ''
+GROUP BY sandbox, category;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mv_category_centroids ON mv_category_centroids(sandbox, category);
+
+-- +goose Down
+DROP MATERIALIZED VIEW IF EXISTS mv_category_centroids;
@@ -0,0 +1,11 @@
+-- Near-duplicate listings (the same product listed twice, or one listing
+-- per colour) found at indexing point at their group's canonical product.
+-- Search returns only canonical products.
+
+-- +goose Up
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS duplicate_of TEXT;
+CREATE INDEX IF NOT EXISTS idx_product_embeddings_duplicate_of ON product_embeddings(duplicate_of) WHERE duplicate_of IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_product_embeddings_duplicate_of;
+ALTER TABLE product_embeddings DROP COLUMN IF EXISTS duplicate_of;
@@ -0,0 +1,11 @@
+-- Product lifecycle from the catalog: draft products are never
+-- recommended, preorders only on request and with their expected ship date.
+-- Existing rows are treated as active until they are reindexed.
+
+-- +goose Up
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS lifecycle TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS ships_at DATE;
+
+-- +goose Down
+ALTER TABLE product_embeddings DROP COLUMN IF EXISTS ships_at;
+ALTER TABLE product_embeddings DROP COLUMN IF EXISTS lifecycle;
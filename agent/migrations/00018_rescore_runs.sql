@@ -0,0 +1,23 @@
+-- Re-scoring runs (POST /admin/rescore): recompute what ranking reads from
+-- precomputed data after weights or eco scoring change. Progress is kept
+-- here so any replica can report it, and so the others know to drop their
+-- cached outfits once a run finishes.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS rescore_runs (
+  id             BIGSERIAL PRIMARY KEY,
+  tenant_id      TEXT NOT NULL,
+  reestimate_eco BOOLEAN NOT NULL DEFAULT false,
+  step           TEXT NOT NULL DEFAULT 'eco', -- eco | views | caches | done
+  done           INT NOT NULL DEFAULT 0,      -- products re-estimated so far
+  total          INT NOT NULL DEFAULT 0,      -- products to re-estimate
+  failed         INT NOT NULL DEFAULT 0,      -- estimates that failed; those keep their score
+  started_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+  finished_at    TIMESTAMPTZ,
+  error          TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_rescore_runs_tenant ON rescore_runs(tenant_id, started_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS rescore_runs;
@@ -0,0 +1,21 @@
+-- Idempotency-Key support for mutating endpoints: the first request with a
+-- key claims it, and its response is stored so a retry gets the same answer
+-- instead of running the work twice.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  tenant_id    TEXT NOT NULL,
+  key          TEXT NOT NULL,
+  request_hash TEXT NOT NULL,  -- sha256 of method, path, query and body
+  status       INT,            -- NULL while the first request is running
+  content_type TEXT,
+  location     TEXT,
+  body         BYTEA,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  completed_at TIMESTAMPTZ,
+  PRIMARY KEY (tenant_id, key)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_completed ON idempotency_keys(completed_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS idempotency_keys;
@@ -0,0 +1,15 @@
+-- Language-specific product cards (CSA_CARD_LANGUAGES): one embedding per
+-- product and language, searched for shoppers whose locale asks for that
+-- language. HNSW indexes, one per language and metric, are built at startup.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS product_cards (
+  product_id     TEXT NOT NULL REFERENCES product_embeddings(product_id) ON DELETE CASCADE,
+  lang           TEXT NOT NULL,
+  card_title     TEXT,  -- NULL when the product has no translation; the main card's vector is reused
+  card_embedding vector(1536) NOT NULL,
+  PRIMARY KEY (product_id, lang)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS product_cards;
@@ -0,0 +1,16 @@
+-- /search result sets, kept for a while so a follow-up search can narrow
+-- them down ("of these, only blue ones") by the response_id it was given.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS search_results (
+  id          TEXT PRIMARY KEY,
+  tenant_id   TEXT NOT NULL,
+  sandbox     BOOLEAN NOT NULL DEFAULT false,
+  query       TEXT NOT NULL DEFAULT '',
+  product_ids TEXT[] NOT NULL,  -- the hits, best first
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_search_results_created ON search_results(created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS search_results;
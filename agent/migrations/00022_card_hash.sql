@@ -0,0 +1,9 @@
+-- Index budgets (CSA_INDEX_TOKEN_BUDGET, ?max_tokens=) embed changed
+-- products before unchanged ones. A hash of the text embedded for each
+-- product tells them apart without keeping the text.
+
+-- +goose Up
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS card_hash TEXT;
+
+-- +goose Down
+ALTER TABLE product_embeddings DROP COLUMN IF EXISTS card_hash;
@@ -0,0 +1,9 @@
+-- Per-slot similarity thresholds for tenant missions: slot -> 0-100, with
+-- "*" for every slot. Hits under the threshold are dropped, so a slot can
+-- come back with no good match instead of a barely related product.
+
+-- +goose Up
+ALTER TABLE missions ADD COLUMN IF NOT EXISTS min_similarity JSONB NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE missions DROP COLUMN IF EXISTS min_similarity;
@@ -0,0 +1,9 @@
+-- Optional slots for tenant missions: slot -> fill probability (0-1). An
+-- outfit gains an optional slot only when its best pick is worth it and
+-- fits the budget the required slots leave.
+
+-- +goose Up
+ALTER TABLE missions ADD COLUMN IF NOT EXISTS optional_slots JSONB NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE missions DROP COLUMN IF EXISTS optional_slots;
@@ -0,0 +1,40 @@
+-- Tenant webhooks and the outbox their deliveries go through. A domain
+-- event gets one outbox row per matching webhook; the delivery worker
+-- claims pending rows whose next_attempt_at has come (pushing it on by a
+-- lease, so a crashed replica's claims are retried) and records the
+-- outcome. Rows are kept for replay until CSA_OUTBOX_RETENTION.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS webhooks (
+  id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id   TEXT NOT NULL,
+  url         TEXT NOT NULL,
+  secret      BYTEA NOT NULL,
+  event_types TEXT[] NOT NULL DEFAULT '{}', -- empty = every type
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  revoked_at  TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_webhooks_tenant ON webhooks(tenant_id) WHERE revoked_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+  id              BIGSERIAL PRIMARY KEY,
+  webhook_id      UUID NOT NULL REFERENCES webhooks(id),
+  tenant_id       TEXT NOT NULL,
+  event_id        TEXT NOT NULL,
+  event_type      TEXT NOT NULL,
+  payload         JSONB NOT NULL,
+  status          TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed', 'cancelled')),
+  attempts        INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_status     INT,
+  last_error      TEXT,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  finished_at     TIMESTAMPTZ,
+  UNIQUE (webhook_id, event_id)
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_due ON webhook_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_tenant ON webhook_outbox(tenant_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS webhook_outbox;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,17 @@
+-- Data residency. tenant_settings.residency_zone is the zone a tenant's
+-- data is processed in ('' = CSA_DEFAULT_RESIDENCY_ZONE); rows written for
+-- a tenant record the zone they were written under, so data stored before
+-- a tenant moved zones can be found. Rows from before this migration are
+-- NULL (untagged).
+
+-- +goose Up
+ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS residency_zone TEXT NOT NULL DEFAULT '';
+ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS residency_zone TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS residency_zone TEXT;
+ALTER TABLE feedback ADD COLUMN IF NOT EXISTS residency_zone TEXT;
+
+-- +goose Down
+ALTER TABLE feedback DROP COLUMN IF EXISTS residency_zone;
+ALTER TABLE sessions DROP COLUMN IF EXISTS residency_zone;
+ALTER TABLE product_embeddings DROP COLUMN IF EXISTS residency_zone;
+ALTER TABLE tenant_settings DROP COLUMN IF EXISTS residency_zone;
@@ -0,0 +1,10 @@
+-- Full-text index for the keyword fallback /search uses when queries can't
+-- be embedded. The expression must match keywordDocSQL in
+-- keyword_search.go for the planner to use it.
+
+-- +goose Up
+CREATE INDEX IF NOT EXISTS idx_product_embeddings_keyword
+  ON product_embeddings USING gin (to_tsvector('english', coalesce(title, '') || ' ' || coalesce(brand, '') || ' ' || coalesce(category, '') || ' ' || coalesce(material, '')));
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_product_embeddings_keyword;
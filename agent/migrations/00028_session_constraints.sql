@@ -0,0 +1,22 @@
+-- Temporary constraints a shopper states for one session ("no laces
+-- today"), read into filters and applied until expires_at. They are
+-- deleted with the session and never copied to the user's profile.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS session_constraints (
+  id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id         TEXT NOT NULL,
+  session_id        TEXT NOT NULL,
+  text              TEXT NOT NULL,
+  slots             TEXT[] NOT NULL DEFAULT '{}',
+  exclude_terms     TEXT[] NOT NULL DEFAULT '{}',
+  exclude_materials TEXT[] NOT NULL DEFAULT '{}',
+  attributes        JSONB NOT NULL DEFAULT '{}',
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at        TIMESTAMPTZ NOT NULL,
+  FOREIGN KEY (tenant_id, session_id) REFERENCES sessions(tenant_id, id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_session_constraints_session ON session_constraints(tenant_id, session_id, expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS session_constraints;
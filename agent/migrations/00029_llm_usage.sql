@@ -0,0 +1,21 @@
+-- Tokens consumed by embedding and chat calls, as the providers reported
+-- them, summed per tenant, UTC day, kind and model. Each replica adds its
+-- counts every few seconds. tenant_settings.monthly_token_cap is the
+-- tenant's hard cap on the month's total; 0 = CSA_MONTHLY_TOKEN_CAP.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS llm_usage (
+  tenant_id         TEXT NOT NULL,
+  day               DATE NOT NULL,
+  kind              TEXT NOT NULL, -- embed | chat
+  model             TEXT NOT NULL,
+  calls             BIGINT NOT NULL DEFAULT 0,
+  prompt_tokens     BIGINT NOT NULL DEFAULT 0,
+  completion_tokens BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (tenant_id, day, kind, model)
+);
+ALTER TABLE tenant_settings ADD COLUMN IF NOT EXISTS monthly_token_cap BIGINT NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE tenant_settings DROP COLUMN IF EXISTS monthly_token_cap;
+DROP TABLE IF EXISTS llm_usage;
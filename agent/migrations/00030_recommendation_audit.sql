@@ -0,0 +1,28 @@
+-- Audit log of recommendation answers: the request as received, the
+-- filters and routing it ran under, every hit returned with its scores,
+-- and the explanation when there was one. product_ids is indexed, so a
+-- merchandiser can find every answer that showed a product.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS recommendation_audit (
+  id             BIGSERIAL PRIMARY KEY,
+  tenant_id      TEXT NOT NULL,
+  source         TEXT NOT NULL, -- search | complete-outfit | explain-outfit
+  request_id     TEXT NOT NULL DEFAULT '',
+  session_id     TEXT NOT NULL DEFAULT '',
+  key_id         TEXT NOT NULL DEFAULT '',
+  query          TEXT NOT NULL DEFAULT '',
+  request        JSONB NOT NULL DEFAULT '{}',
+  details        JSONB NOT NULL DEFAULT '{}',
+  hits           JSONB NOT NULL DEFAULT '[]',
+  product_ids    TEXT[] NOT NULL DEFAULT '{}',
+  explanation    TEXT[] NOT NULL DEFAULT '{}',
+  residency_zone TEXT,
+  created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_recommendation_audit_tenant ON recommendation_audit(tenant_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_recommendation_audit_products ON recommendation_audit USING gin (product_ids);
+CREATE INDEX IF NOT EXISTS idx_recommendation_audit_created ON recommendation_audit(created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS recommendation_audit;
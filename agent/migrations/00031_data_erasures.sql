@@ -0,0 +1,17 @@
+-- Receipts for DELETE /users/{id}/data. The user is stored as a sha256 of
+-- tenant and user ID, so a receipt proves an erasure happened without
+-- keeping the ID it erased.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS data_erasures (
+  id        UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  tenant_id TEXT NOT NULL,
+  user_hash TEXT NOT NULL,
+  key_id    TEXT NOT NULL DEFAULT '',
+  erased    JSONB NOT NULL, -- rows deleted per table
+  erased_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS idx_data_erasures_user ON data_erasures(tenant_id, user_hash);
+
+-- +goose Down
+DROP TABLE IF EXISTS data_erasures;
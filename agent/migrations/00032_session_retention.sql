@@ -0,0 +1,8 @@
+-- Sessions not seen for CSA_SESSION_RETENTION are swept hourly, with their
+-- events and constraints.
+
+-- +goose Up
+CREATE INDEX IF NOT EXISTS idx_sessions_last_seen ON sessions(last_seen_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_sessions_last_seen;
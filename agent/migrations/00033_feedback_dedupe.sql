@@ -0,0 +1,15 @@
+-- One reaction per session, product and signal: repeats from the same
+-- session are dropped, so a script can't stuff ranking through one session.
+-- Existing repeats keep their first row.
+
+-- +goose Up
+DELETE FROM feedback f USING feedback g
+WHERE f.session_id IS NOT NULL
+  AND f.tenant_id = g.tenant_id AND f.session_id = g.session_id
+  AND f.product_id = g.product_id AND f.signal = g.signal
+  AND f.id > g.id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_feedback_session_once
+  ON feedback(tenant_id, session_id, product_id, signal) WHERE session_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_feedback_session_once;
@@ -0,0 +1,15 @@
+-- Erasure also reaches the stored responses of idempotent requests and the
+-- webhook deliveries made for a shopper's sessions: idempotency keys note
+-- the X-Session-ID they were claimed with, and outbox payloads are found by
+-- their data.session_id.
+
+-- +goose Up
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS session_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_session ON idempotency_keys(tenant_id, session_id) WHERE session_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_session ON webhook_outbox(tenant_id, (payload->'data'->>'session_id'))
+  WHERE payload->'data' ? 'session_id';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_webhook_outbox_session;
+DROP INDEX IF EXISTS idx_idempotency_keys_session;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS session_id;
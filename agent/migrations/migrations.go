@@ -0,0 +1,27 @@
+// Package migrations holds the schema as numbered goose SQL files, embedded
+// so the agent and the csa CLI migrate the same way. Add a new file for
+// every schema change; never edit one that has been released.
+package migrations
+
+import (
+	"embed"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// NewProvider returns a migrator for pool. It takes a Postgres advisory
+// lock, so replicas starting together apply migrations exactly once.
+func NewProvider(pool *pgxpool.Pool) (*goose.Provider, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, err
+	}
+	return goose.NewProvider(goose.DialectPostgres, stdlib.OpenDBFromPool(pool), files,
+		goose.WithSessionLocker(locker))
+}
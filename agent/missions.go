@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// defaultMission is used when a request names no mission or an unknown one.
+const defaultMission = "smart_casual"
+
+// defaultQueryHint reproduces the original per-slot query, e.g. "smart_casual top".
+const defaultQueryHint = "{mission} {slot}"
+
+const (
+	maxMissionSlots     = 8
+	maxOptionalSlots    = 4
+	maxMissionQueryHint = 200
+	maxMissionStyle     = 5  // style descriptors
+	maxStyleDescriptor  = 40 // characters each
+	maxMissionBudgetGBP = 10000
+)
+
+var (
+	missionNamePattern = regexp.MustCompile(`^[a-z0-9_]{1,40}$`)
+	slotNamePattern    = regexp.MustCompile(`^[a-z_]{1,30}$`)
+)
+
+// Mission is an outfit brief: the slots a complete outfit needs, slots it
+// may add, how each slot's search query is phrased, and defaults for
+// constraints the request leaves out.
+type Mission struct {
+	Name      string   `json:"name"`
+	Slots     []string `json:"slots"`
+	QueryHint string   `json:"query_hint"` // {mission}, {slot} and {style} are substituted
+
+	MinEcoScore int      `json:"min_eco_score,omitempty"` // used when the request sends none
+	BudgetGBP   float64  `json:"budget_gbp,omitempty"`    // used when the request sends none
+	Style       []string `json:"style,omitempty"`         // descriptors added to every slot query, e.g. "tailored"
+	// similarity (0-100) a hit needs, by slot or "*" for all; see thresholds.go
+	MinSimilarity map[string]float64 `json:"min_similarity,omitempty"`
+	// slots the outfit may add, with how likely each is wanted (0-1); see
+	// optional_slots.go
+	OptionalSlots map[string]float64 `json:"optional_slots,omitempty"`
+
+	Builtin   bool       `json:"builtin"` // true = defined in code, not by the tenant
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+var builtinMissions = map[string]Mission{
+	"smart_casual":    {Name: "smart_casual", Slots: []string{"top", "bottom", "shoes"}},
+	"business_casual": {Name: "business_casual", Slots: []string{"top", "bottom", "shoes"}, OptionalSlots: map[string]float64{"belt": 0.6}},
+	"outdoor_rain":    {Name: "outdoor_rain", Slots: []string{"outerwear", "bottom", "shoes"}},
+}
+
+const missionColumns = `name, slots, query_hint, min_eco_score, budget_gbp::float8, style, min_similarity, optional_slots, updated_at`
+
+func builtinMission(name string) (Mission, bool) {
+	m, ok := builtinMissions[name]
+	m.Builtin, m.QueryHint = true, defaultQueryHint
+	return m, ok
+}
+
+// query is the search text for one of the mission's slots. Style
+// descriptors go where the hint has {style}, or at the end.
+func (m Mission) query(slot string) string {
+	hint := m.QueryHint
+	if hint == "" {
+		hint = defaultQueryHint
+	}
+	style := strings.Join(m.Style, " ")
+	if style != "" && !strings.Contains(hint, "{style}") {
+		hint += " {style}"
+	}
+	q := strings.NewReplacer("{mission}", m.Name, "{slot}", slot, "{style}", style).Replace(hint)
+	return strings.Join(strings.Fields(q), " ")
+}
+
+// applyDefaults fills in the constraints req leaves out from the mission.
+func (m Mission) applyDefaults(req *CompleteOutfitReq) {
+	if req.BudgetGBP <= 0 {
+		req.BudgetGBP = m.BudgetGBP
+	}
+	if req.MinEcoScore <= 0 {
+		req.MinEcoScore = m.MinEcoScore
+	}
+}
+
+func (m Mission) validate() error {
+	if !missionNamePattern.MatchString(m.Name) {
+		return httpapi.InvalidField("name", "name must be 1-40 lowercase letters, digits or underscores")
+	}
+	if len(m.Slots) == 0 || len(m.Slots) > maxMissionSlots {
+		return httpapi.InvalidField("slots", "slots must list 1-%d slots", maxMissionSlots)
+	}
+	seen := map[string]bool{}
+	for _, s := range m.Slots {
+		if !slotNamePattern.MatchString(s) {
+			return httpapi.InvalidField("slots", "invalid slot %q: use the category names products are indexed with, e.g. top", s)
+		}
+		if seen[s] {
+			return httpapi.InvalidField("slots", "slot %q listed twice", s)
+		}
+		seen[s] = true
+	}
+	if len(m.QueryHint) > maxMissionQueryHint {
+		return httpapi.InvalidField("query_hint", "query_hint must be at most %d characters", maxMissionQueryHint)
+	}
+	if m.QueryHint != "" && !strings.Contains(m.QueryHint, "{slot}") {
+		return httpapi.InvalidField("query_hint", "query_hint must contain {slot}")
+	}
+	if m.MinEcoScore < 0 || m.MinEcoScore > 100 {
+		return httpapi.InvalidField("min_eco_score", "min_eco_score must be 0-100")
+	}
+	if m.BudgetGBP < 0 || m.BudgetGBP > maxMissionBudgetGBP {
+		return httpapi.InvalidField("budget_gbp", "budget_gbp must be 0-%d", maxMissionBudgetGBP)
+	}
+	if len(m.Style) > maxMissionStyle {
+		return httpapi.InvalidField("style", "style must list at most %d descriptors", maxMissionStyle)
+	}
+	for _, d := range m.Style {
+		if d = strings.TrimSpace(d); d == "" || len(d) > maxStyleDescriptor || strings.ContainsAny(d, "{}") {
+			return httpapi.InvalidField("style", "style descriptors must be 1-%d characters without braces", maxStyleDescriptor)
+		}
+	}
+	if len(m.OptionalSlots) > maxOptionalSlots {
+		return httpapi.InvalidField("optional_slots", "optional_slots must list at most %d slots", maxOptionalSlots)
+	}
+	for slot, p := range m.OptionalSlots {
+		if !slotNamePattern.MatchString(slot) {
+			return httpapi.InvalidField("optional_slots", "invalid slot %q: use the category names products are indexed with, e.g. belt", slot)
+		}
+		if seen[slot] {
+			return httpapi.InvalidField("optional_slots", "slot %q is already a required slot", slot)
+		}
+		if p <= 0 || p > 1 {
+			return httpapi.InvalidField("optional_slots", "fill probabilities must be above 0 and at most 1")
+		}
+	}
+	for slot, v := range m.MinSimilarity {
+		if _, optional := m.OptionalSlots[slot]; slot != "*" && !seen[slot] && !optional {
+			return httpapi.InvalidField("min_similarity", "min_similarity names slot %q, which the mission doesn't have; use its slots or *", slot)
+		}
+		if v < 0 || v > 100 {
+			return httpapi.InvalidField("min_similarity", "min_similarity values must be 0-100")
+		}
+	}
+	return nil
+}
+
+// loadMission returns the tenant's mission by name, falling back to the
+// built-in ones; ok is false when neither has it.
+func loadMission(ctx context.Context, pool *pgxpool.Pool, tenantID, name string) (Mission, bool, error) {
+	m := Mission{Name: name}
+	var updated time.Time
+	err := pool.QueryRow(ctx, `
+SELECT `+missionColumns+` FROM missions WHERE tenant_id=$1 AND name=$2
+`, tenantID, name).Scan(&m.Name, &m.Slots, &m.QueryHint, &m.MinEcoScore, &m.BudgetGBP, &m.Style, &m.MinSimilarity, &m.OptionalSlots, &updated)
+	if errors.Is(err, pgx.ErrNoRows) {
+		b, ok := builtinMission(name)
+		return b, ok, nil
+	}
+	if err != nil {
+		return Mission{}, false, err
+	}
+	m.UpdatedAt = &updated
+	return m, true, nil
+}
+
+// resolveMission is the mission an outfit request runs with. Handlers
+// reject unknown missions with checkMission; one deleted in between gets
+// smart_casual's slots and a meta warning.
+func resolveMission(ctx context.Context, pool *pgxpool.Pool, name string) (Mission, error) {
+	if name == "" {
+		name = defaultMission
+	}
+	m, ok, err := loadMission(ctx, pool, tenantFromContext(ctx), name)
+	if err != nil || ok {
+		return m, err
+	}
+	addWarnings(ctx, fmt.Sprintf("unknown mission %q; using %s slots", name, defaultMission))
+	m, _ = builtinMission(defaultMission)
+	m.Name = name // the query still names what the shopper asked for
+	return m, nil
+}
+
+// listMissions returns the built-in missions merged with the tenant's own,
+// sorted by name.
+func listMissions(ctx context.Context, pool *pgxpool.Pool, tenantID string) ([]Mission, error) {
+	byName := map[string]Mission{}
+	for name := range builtinMissions {
+		byName[name], _ = builtinMission(name)
+	}
+	rows, err := pool.Query(ctx, `SELECT `+missionColumns+` FROM missions WHERE tenant_id=$1`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m Mission
+		var updated time.Time
+		if err := rows.Scan(&m.Name, &m.Slots, &m.QueryHint, &m.MinEcoScore, &m.BudgetGBP, &m.Style, &m.MinSimilarity, &m.OptionalSlots, &updated); err != nil {
+			return nil, err
+		}
+		m.UpdatedAt = &updated
+		byName[m.Name] = m
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]Mission, 0, len(byName))
+	for _, m := range byName {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func saveMission(ctx context.Context, pool *pgxpool.Pool, tenantID string, m Mission) (Mission, error) {
+	if m.Style == nil {
+		m.Style = []string{} // the column is NOT NULL
+	}
+	if m.MinSimilarity == nil {
+		m.MinSimilarity = map[string]float64{}
+	}
+	if m.OptionalSlots == nil {
+		m.OptionalSlots = map[string]float64{}
+	}
+	var updated time.Time
+	err := pool.QueryRow(ctx, `
+INSERT INTO missions (tenant_id, name, slots, query_hint, min_eco_score, budget_gbp, style, min_similarity, optional_slots, updated_at)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,now())
+ON CONFLICT (tenant_id, name) DO UPDATE
+SET slots=EXCLUDED.slots, query_hint=EXCLUDED.query_hint, min_eco_score=EXCLUDED.min_eco_score,
+    budget_gbp=EXCLUDED.budget_gbp, style=EXCLUDED.style, min_similarity=EXCLUDED.min_similarity,
+    optional_slots=EXCLUDED.optional_slots, updated_at=EXCLUDED.updated_at
+RETURNING updated_at
+`, tenantID, m.Name, m.Slots, m.QueryHint, m.MinEcoScore, m.BudgetGBP, m.Style, m.MinSimilarity, m.OptionalSlots).Scan(&updated)
+	m.Builtin, m.UpdatedAt = false, &updated
+	return m, err
+}
+
+// deleteMission removes the tenant's mission; a built-in one of the same
+// name applies again afterwards.
+func deleteMission(ctx context.Context, pool *pgxpool.Pool, tenantID, name string) (bool, error) {
+	tag, err := pool.Exec(ctx, `DELETE FROM missions WHERE tenant_id=$1 AND name=$2`, tenantID, name)
+	return tag.RowsAffected() > 0, err
+}
@@ -0,0 +1,483 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// GET /openapi.json describes the API as OpenAPI 3.1, for generating
+// frontend and partner clients. Schemas are built by reflection from the
+// same request and response types the handlers decode and encode, so they
+// can't drift from the JSON on the wire; apiRoutes below is the one list to
+// keep in step with the routes registered in routes_*.go.
+
+// apiRoute documents one route.
+type apiRoute struct {
+	method, path string
+	scope        string // "" = no API key needed
+	summary      string
+	query        []apiParam
+	req          any    // zero value of the JSON body; nil = none
+	resp         any    // zero value of the JSON response; a string is an example of a text one; nil = no body
+	contentType  string // of a text response; default text/plain
+	status       int    // success status; default 200
+	idempotent   bool
+	rateLimited  bool // may answer 429 under CSA_RATE_LIMIT_QPS
+}
+
+type apiParam struct {
+	name, typ, doc string
+}
+
+// apiObject is a response wrapping values under fixed keys, e.g.
+// {"missions": [...]}. Its values are zero values of their types.
+type apiObject map[string]any
+
+var apiRoutes = []apiRoute{
+	{method: "POST", path: "/complete-outfit", scope: scopeRead, summary: "Complete an outfit around the cart's slots",
+		req: CompleteOutfitReq{}, resp: CompleteOutfitResp{}, rateLimited: true},
+	{method: "POST", path: "/group-outfits", scope: scopeRead, summary: "Coordinated outfits for a group sharing a palette",
+		req: GroupOutfitReq{}, resp: GroupOutfitResp{}},
+	{method: "POST", path: "/saved-outfits", scope: scopeRead, summary: "Save an outfit for later",
+		req: SaveOutfitReq{}, resp: SavedOutfit{}, status: http.StatusCreated, idempotent: true},
+	{method: "GET", path: "/saved-outfits/{id}", scope: scopeRead, summary: "A saved outfit with current stock and substitutes",
+		resp: SavedOutfit{}},
+	{method: "GET", path: "/health", summary: "Liveness", resp: "ok"},
+	{method: "GET", path: "/healthz/ready", summary: "Readiness of the service and its dependencies",
+		resp: ReadinessResp{}},
+	{method: "GET", path: "/db-check", scope: scopeRead, summary: "Database and pgvector check", resp: "db ok; vector ext=0.8.0"},
+	{method: "POST", path: "/embed-product", scope: scopeWrite, summary: "Embed and store one product; JSON only when neighbours is set",
+		req: EmbedReq{}, resp: EmbedResp{}, idempotent: true},
+	{method: "POST", path: "/search", scope: scopeRead, summary: "Semantic product search with structured filters",
+		req: SearchReq{}, resp: SearchResp{}, rateLimited: true},
+	{method: "POST", path: "/search-by-image", scope: scopeRead, summary: "Visually similar products; also accepts multipart with an image field",
+		req: ImageSearchReq{}, resp: SearchResp{}},
+	{method: "GET", path: "/home-feed", scope: scopeRead, summary: "Trending products per category",
+		query: []apiParam{{"limit", "integer", "products per category"}}, resp: HomeFeedResp{}},
+	{method: "GET", path: "/products/{id}/substitutes", scope: scopeRead, summary: "Similar products to offer instead",
+		query: []apiParam{
+			{"limit", "integer", "1-50, default 5"},
+			{"price_tolerance", "number", "allowed price difference as a fraction, default 0.1"},
+			{"in_stock", "boolean", "false includes sold-out products"},
+		}, resp: SubstitutesResp{}},
+	{method: "GET", path: "/stats/price-distribution", scope: scopeRead, summary: "Price quartiles per slot",
+		resp: apiObject{"slots": []PriceDistribution{}, "refreshed_at": time.Time{}}},
+	{method: "GET", path: "/index-stats", scope: scopeRead, summary: "Catalog coverage for the tenant", resp: IndexStats{}},
+	{method: "GET", path: "/medusa-products-count", scope: scopeAdmin, summary: "Products visible in the Medusa store",
+		resp: apiObject{"count": 0}},
+	{method: "POST", path: "/index-products", scope: scopeWrite, summary: "Index the configured catalog",
+		query: []apiParam{{"mode", "string", "full (default) or incremental"},
+			{"max_tokens", "integer", "embedding token budget; default CSA_INDEX_TOKEN_BUDGET"},
+			{"max_cost_usd", "number", "embedding budget in USD, instead of max_tokens"}},
+		resp: IndexResult{}, idempotent: true},
+	{method: "POST", path: "/index-medusa-products", scope: scopeWrite, summary: "Full index of the configured catalog",
+		resp: "indexed 120 products", idempotent: true},
+	{method: "GET", path: "/export-embeddings", scope: scopeAdmin, summary: "JSONL dump of the tenant's vectors",
+		query: []apiParam{{"category", "string", "limit to one category"}, {"images", "boolean", "include image vectors"}},
+		resp:  "{\"product_id\": \"...\", \"metadata\": {}, \"vector\": []}\n"},
+	{method: "POST", path: "/import-catalog", scope: scopeWrite, summary: "Load a CSV or JSONL catalog from the body or a multipart file field",
+		query: []apiParam{{"format", "string", "csv or jsonl"}}, resp: ImportResult{}, idempotent: true},
+	{method: "DELETE", path: "/products/{id}", scope: scopeWrite, summary: "Delete a product", status: http.StatusNoContent},
+	{method: "POST", path: "/admin/purge", scope: scopeAdmin, summary: "Bulk removal of the tenant's products",
+		req: PurgeReq{}, resp: PurgeResp{}},
+	{method: "POST", path: "/merchandising", scope: scopeWrite, summary: "Set clearance flags and margins",
+		req: MerchandisingReq{}, resp: MerchandisingResp{}},
+	{method: "POST", path: "/sync-inventory", scope: scopeWrite, summary: "Refresh stock from the catalog",
+		resp: "synced stock for 120 products; 3 sold out, 2 cached responses invalidated, 1 saved-outfit substitutes", idempotent: true},
+	{method: "POST", path: "/explain-outfit", scope: scopeRead, summary: "Explain a /complete-outfit response",
+		req: CompleteOutfitResp{}, resp: apiObject{"bullets": []string{}}, rateLimited: true},
+	{method: "GET", path: "/ws", scope: scopeRead, summary: "Styling session over a WebSocket; see README",
+		status: http.StatusSwitchingProtocols},
+	{method: "POST", path: "/parse-intent", scope: scopeRead, summary: "Map free text to an outfit request",
+		req: apiObject{"text": ""}, resp: OutfitIntent{}},
+	{method: "GET", path: "/missions", scope: scopeRead, summary: "Built-in and tenant missions",
+		resp: apiObject{"missions": []Mission{}}},
+	{method: "GET", path: "/missions/{name}", scope: scopeRead, summary: "One mission", resp: Mission{}},
+	{method: "PUT", path: "/missions/{name}", scope: scopeWrite, summary: "Create or replace a tenant mission",
+		req: Mission{}, resp: Mission{}},
+	{method: "DELETE", path: "/missions/{name}", scope: scopeWrite, summary: "Delete a tenant mission", status: http.StatusNoContent},
+	{method: "GET", path: "/admin/tenant-settings", scope: scopeAdmin, summary: "The tenant's settings", resp: TenantSettings{}},
+	{method: "PUT", path: "/admin/tenant-settings", scope: scopeAdmin, summary: "Replace the tenant's settings",
+		req: TenantSettings{}, resp: TenantSettings{}},
+	{method: "GET", path: "/admin/residency", scope: scopeAdmin, summary: "The tenant's residency zone and the zones its stored rows were written under",
+		resp: ResidencyReport{}},
+	{method: "GET", path: "/admin/jobs", scope: scopeAdmin, summary: "Recent scheduled job runs",
+		resp: apiObject{"jobs": []JobRun{}}},
+	{method: "POST", path: "/admin/rescore", scope: scopeAdmin, summary: "Recompute precomputed ranking inputs in the background",
+		req: RescoreReq{}, resp: RescoreRun{}, status: http.StatusAccepted, idempotent: true},
+	{method: "GET", path: "/admin/rescore/{id}", scope: scopeAdmin, summary: "Progress of a rescore", resp: RescoreRun{}},
+	{method: "POST", path: "/admin/reload", scope: scopeAdmin, summary: "Reload reloadable settings (bootstrap key only)",
+		resp: ReloadResult{}},
+	{method: "GET", path: "/admin/migrations", scope: scopeAdmin, summary: "Schema migration status", resp: MigrateResp{}},
+	{method: "POST", path: "/admin/migrate", scope: scopeAdmin, summary: "Apply pending migrations (bootstrap key only)", resp: MigrateResp{}},
+	{method: "POST", path: "/admin/api-keys", scope: scopeAdmin, summary: "Create an API key",
+		req: CreateAPIKeyReq{}, resp: CreateAPIKeyResp{}, status: http.StatusCreated},
+	{method: "GET", path: "/admin/api-keys", scope: scopeAdmin, summary: "The tenant's API keys",
+		resp: apiObject{"keys": []APIKey{}}},
+	{method: "DELETE", path: "/admin/api-keys/{id}", scope: scopeAdmin, summary: "Revoke an API key", status: http.StatusNoContent},
+	{method: "POST", path: "/admin/signing-keys", scope: scopeAdmin, summary: "Create a response signing key",
+		req: CreateSigningKeyReq{}, resp: CreateSigningKeyResp{}, status: http.StatusCreated},
+	{method: "GET", path: "/admin/signing-keys", scope: scopeAdmin, summary: "The tenant's signing keys",
+		resp: apiObject{"keys": []SigningKey{}}},
+	{method: "DELETE", path: "/admin/signing-keys/{id}", scope: scopeAdmin, summary: "Revoke a signing key", status: http.StatusNoContent},
+	{method: "POST", path: "/admin/webhooks", scope: scopeAdmin, summary: "Register a webhook for domain events",
+		req: CreateWebhookReq{}, resp: CreateWebhookResp{}, status: http.StatusCreated},
+	{method: "GET", path: "/admin/webhooks", scope: scopeAdmin, summary: "The tenant's webhooks",
+		resp: apiObject{"webhooks": []Webhook{}}},
+	{method: "DELETE", path: "/admin/webhooks/{id}", scope: scopeAdmin, summary: "Delete a webhook and cancel its pending deliveries", status: http.StatusNoContent},
+	{method: "GET", path: "/admin/webhooks/deliveries", scope: scopeAdmin, summary: "Webhook deliveries in the outbox, newest first",
+		query: []apiParam{
+			{"status", "string", "pending, delivered, failed or cancelled"},
+			{"webhook_id", "string", "only this webhook's"},
+			{"event_type", "string", "only this event type"},
+			{"since", "string", "created at or after (RFC 3339)"},
+			{"until", "string", "created before (RFC 3339)"},
+			{"limit", "integer", "1-1000, default 100"},
+		},
+		resp: apiObject{"deliveries": []Delivery{}}},
+	{method: "POST", path: "/admin/webhooks/deliveries/replay", scope: scopeAdmin, summary: "Send deliveries again",
+		req: ReplayDeliveriesReq{}, resp: apiObject{"replayed": 0}},
+	{method: "GET", path: "/signing-keys", scope: scopeRead, summary: "Active keys for verifying signed responses",
+		resp: apiObject{"keys": []SigningKey{}}},
+	{method: "PUT", path: "/sessions/{id}/consent", scope: scopeWrite, summary: "Record the shopper's consent for a session",
+		req: Consent{}, status: http.StatusNoContent},
+	{method: "POST", path: "/sessions/{id}/constraints", scope: scopeRead, summary: "Add a temporary constraint from the shopper's words to a session",
+		req: ConstraintReq{}, resp: SessionConstraint{}, status: http.StatusCreated},
+	{method: "GET", path: "/sessions/{id}/constraints", scope: scopeRead, summary: "A session's unexpired constraints",
+		resp: apiObject{"constraints": []SessionConstraint{}}},
+	{method: "DELETE", path: "/sessions/{id}/constraints/{cid}", scope: scopeRead, summary: "Remove a session constraint",
+		status: http.StatusNoContent},
+	{method: "POST", path: "/feedback", scope: scopeRead, summary: "Thumbs up/down or add-to-cart on a recommended product",
+		req: FeedbackReq{}, status: http.StatusAccepted},
+	{method: "GET", path: "/admin/usage", scope: scopeAdmin, summary: "Embedding and chat tokens used per day, and the month against its cap",
+		query: []apiParam{{"days", "integer", "1-366, default 30"}}, resp: UsageReport{}},
+	{method: "GET", path: "/admin/audit", scope: scopeAdmin, summary: "Recorded recommendation answers with their filters, scores and explanations, newest first",
+		query: []apiParam{
+			{"source", "string", "search, complete-outfit or explain-outfit"},
+			{"product_id", "string", "answers that showed this product"},
+			{"session_id", "string", "answers in this session"},
+			{"q", "string", "text in the query"},
+			{"since", "string", "RFC 3339 time"},
+			{"until", "string", "RFC 3339 time"},
+			{"limit", "integer", "1-100, default 20"},
+			{"cursor", "string", "next_cursor of the previous page"},
+		}, resp: AuditPage{}},
+	{method: "GET", path: "/admin/feedback", scope: scopeAdmin, summary: "Feedback report",
+		query: []apiParam{{"days", "integer", "1-365, default 30"}, {"limit", "integer", "1-500, default 50"}}, resp: FeedbackReport{}},
+	{method: "GET", path: "/users/{id}/history", scope: scopeRead, summary: "What a user was shown across sessions, newest first",
+		query: []apiParam{
+			{"source", "string", "search, complete-outfit, group-outfits or substitutes"},
+			{"product_id", "string", "entries that showed this product"},
+			{"q", "string", "text in the query or mission"},
+			{"since", "string", "RFC 3339 time"},
+			{"until", "string", "RFC 3339 time"},
+			{"cursor", "integer", "next_cursor of the previous page"},
+			{"limit", "integer", "page size"},
+		}, resp: HistoryPage{}},
+	{method: "DELETE", path: "/users/{id}/data", scope: scopeWrite, summary: "Erase a user's sessions, history, feedback and audit entries; returns a receipt",
+		resp: ErasureReceipt{}},
+	{method: "GET", path: "/admin/sessions/{id}/export", scope: scopeAdmin, summary: "Session bundle for QA review or training",
+		query: []apiParam{{"purpose", "string", "qa or training"}}, resp: SessionExport{}},
+	{method: "POST", path: "/admin/relevance-judgments", scope: scopeAdmin, summary: "Record graded relevance judgments",
+		req: JudgmentsReq{}, resp: apiObject{"judgments": []RelevanceJudgment{}}},
+	{method: "GET", path: "/admin/relevance-judgments", scope: scopeAdmin, summary: "Recorded judgments",
+		query: []apiParam{{"query", "string", "one query's judgments"}, {"limit", "integer", "1-1000, default 100"}},
+		resp:  apiObject{"judgments": []RelevanceJudgment{}}},
+	{method: "GET", path: "/admin/relevance-judgments/candidates", scope: scopeAdmin, summary: "Live hits for a query with their grades",
+		query: []apiParam{{"query", "string", "required"}, {"limit", "integer", "1-100, default 20"}},
+		resp:  apiObject{"query": "", "candidates": []JudgmentCandidate{}}},
+	{method: "GET", path: "/admin/relevance-judgments/golden-set", scope: scopeAdmin, summary: "The judgments as an evaluation set",
+		resp: GoldenSet{}},
+	{method: "POST", path: "/admin/embedding-benchmark", scope: scopeAdmin, summary: "Score the embedding model against the judgments",
+		query: []apiParam{{"k", "integer", "cutoff, 1-100, default 10"}}, resp: EmbedBenchResp{}},
+	{method: "DELETE", path: "/admin/relevance-judgments/{id}", scope: scopeAdmin, summary: "Delete a judgment", status: http.StatusNoContent},
+	{method: "GET", path: "/openapi.json", summary: "This document"},
+	{method: "GET", path: "/docs", summary: "Browsable API reference for this document", resp: "<!doctype html>...", contentType: "text/html"},
+}
+
+// apiEnums lists the allowed values of fields validated against a fixed
+// set, by declaring type and JSON name.
+var apiEnums = map[string][]string{
+	"SearchReq.sort_by":                 search.SortOrders,
+	"SearchReq.distance_metric":         ranking.Metrics,
+	"CompleteOutfitReq.distance_metric": ranking.Metrics,
+	"SearchReq.search_quality":          config.SearchQualities,
+	"ClearancePrefs.clearance_mode":     {clearancePrefer, clearanceOnly},
+	"FeedbackReq.signal":                feedbackSignals,
+	"FeedbackReq.source":                historySources,
+	"CreateSigningKeyReq.alg":           {signHMAC, signEd25519},
+	"TenantSettings.explain_engine":     {explainEngineLLM, explainEngineTemplate},
+	"ExplainOptions.priority":           {explainPriorityEco, explainPriorityBudget},
+}
+
+var openAPIDoc = sync.OnceValue(buildOpenAPI)
+
+// openAPIHandler serves the document, built once.
+func openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDoc())
+}
+
+// apiDocsPage renders /openapi.json with Redoc. Only the browser fetches
+// the script, so the service itself needs no outbound access.
+const apiDocsPage = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Contextual Shopping Agent API</title>
+<meta name="viewport" content="width=device-width, initial-scale=1">
+</head>
+<body>
+<redoc spec-url="openapi.json"></redoc>
+<script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`
+
+// apiDocsHandler serves the API reference page.
+func apiDocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(apiDocsPage))
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+func buildOpenAPI() []byte {
+	s := &schemaSet{defs: map[string]any{}}
+	s.ref(reflect.TypeOf(httpapi.ErrorResp{}))
+
+	paths := map[string]map[string]any{}
+	for _, rt := range apiRoutes {
+		op := map[string]any{
+			"summary":     rt.summary,
+			"operationId": operationID(rt.method, rt.path),
+			"tags":        []string{apiTag(rt.path)},
+		}
+		var params []any
+		for _, m := range pathParamPattern.FindAllStringSubmatch(rt.path, -1) {
+			params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+		}
+		for _, q := range rt.query {
+			params = append(params, map[string]any{"name": q.name, "in": "query", "description": q.doc, "schema": map[string]any{"type": q.typ}})
+		}
+		if rt.idempotent {
+			params = append(params, map[string]any{"name": "Idempotency-Key", "in": "header",
+				"description": "retries with the same key replay the first response", "schema": map[string]any{"type": "string", "maxLength": maxIdempotencyKey}})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+		if rt.req != nil {
+			op["requestBody"] = map[string]any{"required": true, "content": map[string]any{
+				"application/json": map[string]any{"schema": s.schemaOf(rt.req)}}}
+		}
+
+		status := rt.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		ok := map[string]any{"description": http.StatusText(status)}
+		switch v := rt.resp.(type) {
+		case nil:
+		case string:
+			ct := rt.contentType
+			if ct == "" {
+				ct = "text/plain"
+			}
+			ok["content"] = map[string]any{ct: map[string]any{"schema": map[string]any{"type": "string"}, "example": v}}
+		default:
+			ok["content"] = map[string]any{"application/json": map[string]any{"schema": s.schemaOf(v)}}
+		}
+		errContent := map[string]any{
+			"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/httpapi.ErrorResp"}}}
+		responses := map[string]any{
+			strconv.Itoa(status): ok,
+			"default":            map[string]any{"description": "error", "content": errContent},
+		}
+		if rt.rateLimited {
+			responses["429"] = map[string]any{"description": "the caller's rate limit is used up", "content": errContent,
+				"headers": map[string]any{"Retry-After": map[string]any{"description": "seconds until a request would be let through",
+					"schema": map[string]any{"type": "integer"}}}}
+		}
+		op["responses"] = responses
+		if rt.scope != "" {
+			op["security"] = []any{map[string]any{"bearer": []string{}}, map[string]any{"apiKey": []string{}}}
+			op["x-scope"] = rt.scope
+			if rt.scope == scopeRead {
+				op["description"] = "Needs an API key with read scope only when CSA_REQUIRE_READ_AUTH is set."
+			} else {
+				op["description"] = "Needs an API key with " + rt.scope + " scope."
+			}
+		}
+		if paths[rt.path] == nil {
+			paths[rt.path] = map[string]any{}
+		}
+		paths[rt.path][strings.ToLower(rt.method)] = op
+	}
+
+	doc := map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "Contextual Shopping Agent",
+			"version": "1",
+			"description": "Constraint-aware product search and outfit completion. Errors use the httpapi.ErrorResp envelope. " +
+				"X-Tenant-ID names the tenant for anonymous callers; X-Session-ID and X-User-ID tag session transcripts.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": s.defs,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+				"apiKey": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		panic(err) // maps of strings and slices always marshal
+	}
+	return b
+}
+
+// operationID turns "GET /missions/{name}" into "getMissionsName".
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '_' || r == '.' || r == '{' || r == '}' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// apiTag groups operations by their first path segment.
+func apiTag(path string) string {
+	seg, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if seg == "admin" {
+		return "admin"
+	}
+	return strings.TrimSuffix(seg, ".json")
+}
+
+// schemaSet collects the named struct schemas a document refers to.
+type schemaSet struct {
+	defs map[string]any
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf is the JSON Schema of v's type. apiObject values become inline
+// objects; named structs become components.
+func (s *schemaSet) schemaOf(v any) map[string]any {
+	if obj, ok := v.(apiObject); ok {
+		props := map[string]any{}
+		for k, fv := range obj {
+			props[k] = s.schemaOf(fv)
+		}
+		return map[string]any{"type": "object", "properties": props}
+	}
+	return s.schema(reflect.TypeOf(v))
+}
+
+func (s *schemaSet) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		return s.ref(t)
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		return s.object(t)
+	}
+	return map[string]any{} // any
+}
+
+// ref adds t's schema to the components, once, and refers to it.
+func (s *schemaSet) ref(t reflect.Type) map[string]any {
+	name := t.Name()
+	if _, ok := s.defs[name]; !ok {
+		s.defs[name] = nil // placeholder, so recursive types terminate
+		s.defs[name] = s.object(t)
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// object is the schema of a struct's JSON fields, with embedded structs
+// flattened the way encoding/json does: a field of the outer struct wins
+// over an embedded one with the same name.
+func (s *schemaSet) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	s.addFields(t, props)
+	return map[string]any{"type": "object", "properties": props}
+}
+
+func (s *schemaSet) addFields(t reflect.Type, props map[string]any) {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = s.schema(f.Type)
+		if enum, ok := apiEnums[t.Name()+"."+name]; ok {
+			props[name] = map[string]any{"type": "string", "enum": enum}
+		}
+	}
+	for _, et := range embedded {
+		inner := map[string]any{}
+		s.addFields(et, inner)
+		for name, v := range inner {
+			if _, ok := props[name]; !ok {
+				props[name] = v
+			}
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/outfit"
+)
+
+// A mission can list optional slots, each with a fill probability: how
+// likely a shopper on that mission wants one (a belt with business_casual,
+// say). Once the required slots are picked, the optional ones are searched
+// within the budget they leave, and each candidate is worth its slot's
+// fill probability times its similarity. The outfit gains the set of
+// optional picks worth the most in total that fits the budget, counting
+// only picks worth at least CSA_OPTIONAL_SLOT_MIN_VALUE; an optional slot
+// is left out when no pick earns its place.
+
+// OptionalSlot reports what was decided for one optional slot.
+type OptionalSlot struct {
+	Slot            string  `json:"slot"`
+	FillProbability float64 `json:"fill_probability"`
+	Filled          bool    `json:"filled"`
+	// fill probability × similarity of the best candidate (the pick, when
+	// filled), 0-1
+	Value     float64 `json:"value"`
+	ProductID string  `json:"product_id,omitempty"` // the pick
+	Reason    string  `json:"reason"`
+}
+
+// optionalSlots lists the mission's optional slots that present doesn't
+// already have, by name.
+func (m Mission) optionalSlots(present []string) []string {
+	var out []string
+	for slot := range m.OptionalSlots {
+		if !slices.Contains(present, slot) && !slices.Contains(m.Slots, slot) {
+			out = append(out, slot)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// leftoverBudget is what budget leaves after the top pick of each slot.
+func leftoverBudget(budget float64, results []SlotRecs) float64 {
+	var prices []float64
+	for _, r := range results {
+		if len(r.Hits) > 0 {
+			prices = append(prices, r.Hits[0].PriceGBP)
+		}
+	}
+	return outfit.Leftover(budget, prices...)
+}
+
+// fillOptionalSlots decides the mission's optional slots for an outfit whose
+// required picks leave left to spend (budgeted = false: no limit). It
+// returns the filled slots' recommendations, the pick first, and a
+// decision for every slot.
+func fillOptionalSlots(ctx context.Context, pool *pgxpool.Pool, req CompleteOutfitReq, mission Mission, slots []string,
+	left float64, budgeted bool, style []float64) ([]SlotRecs, []OptionalSlot, error) {
+	decisions := make([]OptionalSlot, len(slots))
+	for i, slot := range slots {
+		decisions[i] = OptionalSlot{Slot: slot, FillProbability: mission.OptionalSlots[slot]}
+	}
+	switch {
+	case req.Gift != nil:
+		return nil, skipAll(decisions, "Optional slots aren't added to gift bundles."), nil
+	case budgeted && left < 0.01:
+		return nil, skipAll(decisions, "The required slots use the whole budget."), nil
+	}
+
+	minValue := cfg().OptionalSlotMinValue
+	candidates := make([][]Hit, len(slots))
+	thresholds := make([]*SlotThreshold, len(slots))
+	for i, slot := range slots {
+		maxPrice := 0.0
+		if budgeted {
+			maxPrice = left
+		}
+		slotCtx, span := startSpan(ctx, "complete-outfit.optional-slot", "slot", slot, "mission", req.Mission)
+		hits, err := searchHits(slotCtx, pool, req.slotSearch(slot, mission.query(slot), req.LimitPerSlot, maxPrice, style))
+		span.End()
+		if err != nil {
+			return nil, nil, err
+		}
+		if minSim, source := mission.minSimilarity(slot); minSim > 0 || req.Debug {
+			hits, thresholds[i] = applyThreshold(hits, minSim, source, req.Debug)
+		}
+		candidates[i] = hits
+
+		d := &decisions[i]
+		switch {
+		case len(hits) == 0 && thresholds[i] != nil && thresholds[i].BestSimilarity != nil:
+			d.Reason = noGoodMatch(slot, thresholds[i])
+		case len(hits) == 0 && budgeted:
+			d.Reason = fmt.Sprintf("No products for slot=%s within the £%.2f left.", slot, left)
+		case len(hits) == 0:
+			d.Reason = fmt.Sprintf("No products for slot=%s.", slot)
+		default:
+			best := 0.0
+			for _, h := range hits {
+				best = max(best, outfit.SlotValue(d.FillProbability, h.Similarity))
+			}
+			d.Value = round2(best)
+			if best < minValue {
+				d.Reason = fmt.Sprintf("The best pick is worth %.2f (fill probability %.2f × similarity), under the %.2f needed.",
+					d.Value, d.FillProbability, minValue)
+			}
+		}
+	}
+
+	options := make([][]outfit.Candidate, len(candidates))
+	for i, hits := range candidates {
+		for _, h := range hits {
+			options[i] = append(options[i], outfit.Candidate{Price: h.PriceGBP, Value: outfit.SlotValue(decisions[i].FillProbability, h.Similarity)})
+		}
+	}
+	picks := outfit.ChooseOptional(options, minValue, left, budgeted)
+	var recs []SlotRecs
+	for i, p := range picks {
+		d := &decisions[i]
+		if p < 0 {
+			if d.Reason == "" {
+				d.Reason = fmt.Sprintf("Left out so better optional picks fit the £%.2f left.", left)
+			}
+			continue
+		}
+		hits := candidates[i]
+		pick := hits[p]
+		hits = append([]Hit{pick}, append(hits[:p:p], hits[p+1:]...)...)
+		d.Filled, d.ProductID = true, pick.ProductID
+		d.Value = round2(outfit.SlotValue(d.FillProbability, pick.Similarity))
+		d.Reason = fmt.Sprintf("Adds %s: worth %.2f (fill probability %.2f × similarity %.0f%%) for £%.2f.",
+			d.Slot, d.Value, d.FillProbability, pick.Similarity, pick.PriceGBP)
+		for j := range hits {
+			hits[j].Reason = fmt.Sprintf("Optional slot=%s. Eco=%d. Price=£%.2f.", d.Slot, hits[j].EcoScore, hits[j].PriceGBP)
+			if budgeted {
+				hits[j].Reason += fmt.Sprintf(" Within the £%.2f the outfit leaves.", left)
+			}
+		}
+		threshold := thresholds[i]
+		if !req.Debug {
+			threshold = nil
+		}
+		recs = append(recs, SlotRecs{Slot: d.Slot, Hits: hits, Optional: true, Threshold: threshold})
+	}
+
+	var filled []string
+	for _, d := range decisions {
+		if d.Filled {
+			filled = append(filled, d.Slot)
+		}
+	}
+	if len(filled) > 0 {
+		logOutcome(ctx, slog.String("optional_slots_filled", strings.Join(filled, ",")))
+	}
+	return recs, decisions, nil
+}
+
+func skipAll(decisions []OptionalSlot, reason string) []OptionalSlot {
+	for i := range decisions {
+		decisions[i].Reason = reason
+	}
+	return decisions
+}
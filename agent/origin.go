@@ -0,0 +1,128 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// OriginPrefs enable the shipping-distance eco factor. Both fields are
+// optional; without ShopperRegion hits are returned unchanged.
+type OriginPrefs struct {
+	ShopperRegion string  `json:"shopper_region,omitempty"` // ISO 3166-1 alpha-2, e.g. "GB"
+	LocalBoost    float64 `json:"local_boost,omitempty"`    // 0-1 weight of the distance factor in ranking
+}
+
+const (
+	// distance at which the shipping factor bottoms out (roughly half the globe)
+	maxShippingKm = 20000.0
+	// anything closer than this (or same country) counts as made locally
+	localShippingKm = 800.0
+	// eco points removed for the longest possible shipping leg
+	maxShippingPenalty = 15.0
+)
+
+// countryCentroids are approximate country centroids (lat, lon) keyed by
+// ISO alpha-2. Good enough for a carbon signal, not for logistics.
+var countryCentroids = map[string][2]float64{
+	"GB": {54.0, -2.0}, "IE": {53.4, -8.2}, "FR": {46.6, 2.2}, "DE": {51.2, 10.4},
+	"NL": {52.1, 5.3}, "BE": {50.6, 4.6}, "LU": {49.8, 6.1}, "DK": {56.0, 10.0},
+	"SE": {62.0, 15.0}, "NO": {61.0, 8.0}, "FI": {64.0, 26.0}, "IS": {64.9, -18.6},
+	"ES": {40.4, -3.7}, "PT": {39.6, -8.0}, "IT": {42.8, 12.6}, "CH": {46.8, 8.2},
+	"AT": {47.6, 14.1}, "PL": {52.1, 19.4}, "CZ": {49.8, 15.5}, "SK": {48.7, 19.7},
+	"HU": {47.2, 19.5}, "RO": {45.9, 24.9}, "BG": {42.7, 25.5}, "GR": {39.1, 21.8},
+	"HR": {45.1, 15.2}, "SI": {46.1, 14.8}, "RS": {44.0, 21.0}, "LT": {55.2, 23.9},
+	"LV": {56.9, 24.6}, "EE": {58.6, 25.0}, "UA": {48.4, 31.2}, "TR": {39.0, 35.2},
+	"MA": {31.8, -7.1}, "TN": {33.9, 9.5}, "EG": {26.8, 30.8}, "ET": {9.1, 40.5},
+	"KE": {0.0, 37.9}, "ZA": {-30.6, 22.9}, "MU": {-20.3, 57.6}, "MG": {-18.8, 46.9},
+	"US": {39.8, -98.6}, "CA": {56.1, -106.3}, "MX": {23.6, -102.6}, "GT": {15.8, -90.2},
+	"HN": {15.2, -86.2}, "SV": {13.8, -88.9}, "NI": {12.9, -85.2}, "BR": {-14.2, -51.9},
+	"PE": {-9.2, -75.0}, "CO": {4.6, -74.3}, "AR": {-38.4, -63.6}, "CL": {-35.7, -71.5},
+	"CN": {35.9, 104.2}, "HK": {22.3, 114.2}, "TW": {23.7, 121.0}, "JP": {36.2, 138.3},
+	"KR": {35.9, 127.8}, "IN": {20.6, 79.0}, "PK": {30.4, 69.3}, "BD": {23.7, 90.4},
+	"LK": {7.9, 80.8}, "NP": {28.4, 84.1}, "MM": {21.9, 95.9}, "TH": {15.9, 100.9},
+	"KH": {12.6, 104.9}, "VN": {14.1, 108.3}, "LA": {19.9, 102.5}, "MY": {4.2, 102.0},
+	"SG": {1.35, 103.8}, "ID": {-0.8, 113.9}, "PH": {12.9, 121.8}, "AU": {-25.3, 133.8},
+	"NZ": {-40.9, 174.9}, "AE": {23.4, 53.8}, "SA": {23.9, 45.1}, "IL": {31.0, 34.9},
+	"JO": {30.6, 36.2},
+}
+
+func normalizeCountry(c string) string {
+	return strings.ToUpper(strings.TrimSpace(c))
+}
+
+func (o OriginPrefs) validate() error {
+	if o.ShopperRegion != "" {
+		if _, ok := countryCentroids[normalizeCountry(o.ShopperRegion)]; !ok {
+			return httpapi.InvalidField("shopper_region", "unknown shopper_region %q (use an ISO 3166-1 alpha-2 code like GB)", o.ShopperRegion)
+		}
+	}
+	if o.LocalBoost < 0 || o.LocalBoost > 1 {
+		return httpapi.InvalidField("local_boost", "local_boost must be between 0 and 1")
+	}
+	return nil
+}
+
+// shippingKm is the great-circle distance between two countries' centroids.
+func shippingKm(from, to string) (float64, bool) {
+	from, to = normalizeCountry(from), normalizeCountry(to)
+	if from == to {
+		return 0, from != ""
+	}
+	a, ok1 := countryCentroids[from]
+	b, ok2 := countryCentroids[to]
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+
+	const earthRadiusKm = 6371.0
+	lat1, lat2 := a[0]*math.Pi/180, b[0]*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b[1] - a[1]) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h)), true
+}
+
+// shippingFactor maps distance to 1 (local) .. 0 (other side of the world).
+func shippingFactor(km float64) float64 {
+	return math.Max(0, 1-km/maxShippingKm)
+}
+
+// applyOrigin annotates hits with shipping distance and a carbon-adjusted
+// eco score, then re-ranks by LocalBoost and trims to limit. Hits without a
+// known origin keep their eco score and get a neutral 0.5 factor.
+func applyOrigin(hits []Hit, prefs OriginPrefs, limit int) []Hit {
+	region := normalizeCountry(prefs.ShopperRegion)
+	if region == "" {
+		return hits
+	}
+
+	factors := make(map[string]float64, len(hits))
+	for i := range hits {
+		h := &hits[i]
+		factors[h.ProductID] = 0.5
+		km, ok := shippingKm(h.OriginCountry, region)
+		if !ok {
+			continue
+		}
+		km = math.Round(km)
+		f := shippingFactor(km)
+		adjusted := h.EcoScore - int(math.Round(maxShippingPenalty*(1-f)))
+		h.ShippingKm = &km
+		h.MadeLocally = km <= localShippingKm
+		h.CarbonEcoScore = &adjusted
+		factors[h.ProductID] = f
+	}
+
+	if prefs.LocalBoost > 0 {
+		b := prefs.LocalBoost
+		score := func(h Hit) float64 { return (1-b)*h.rankScore() + b*factors[h.ProductID]*100 }
+		sort.SliceStable(hits, func(i, j int) bool { return score(hits[i]) > score(hits[j]) })
+	}
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
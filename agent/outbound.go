@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Outbound timeouts: a hung OpenAI or Medusa call would otherwise stall the
+// request waiting on it for as long as the caller's connection lasts.
+// Connecting is bounded by CSA_HTTP_CONNECT_TIMEOUT and each call, reading
+// its body included, by the timeout of the dependency it goes to. Both are
+// read per call, so a reload applies to the next one. A call that times
+// out counts against the dependency's circuit breaker.
+
+// outboundTransport dials with the configured connect timeout; the rest is
+// http.DefaultTransport's pooling, proxy and TLS handshake settings.
+func outboundTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		d := net.Dialer{Timeout: cfg().HTTPConnectTimeout, KeepAlive: 30 * time.Second}
+		return d.DialContext(ctx, network, addr)
+	}
+	return t
+}
+
+// callTimeout is how long a call to host may take.
+func callTimeout(host string) time.Duration {
+	switch dependencyFor(host) {
+	case depOpenAI, depAzureOpenAI:
+		return cfg().OpenAITimeout
+	case depMedusa:
+		return cfg().MedusaTimeout
+	}
+	return cfg().HTTPTimeout
+}
+
+// timeoutTransport gives each request a deadline of callTimeout, lasting
+// until its response body is closed.
+type timeoutTransport struct {
+	next http.RoundTripper
+}
+
+func (t timeoutTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	d := callTimeout(r.URL.Host)
+	ctx, cancel := context.WithTimeout(r.Context(), d)
+	res, err := t.next.RoundTrip(r.WithContext(ctx))
+	if err != nil {
+		cancel()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && r.Context().Err() == nil {
+			err = fmt.Errorf("%s: no answer within %s: %w", r.URL.Host, d, err)
+		}
+		return nil, err
+	}
+	res.Body = cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
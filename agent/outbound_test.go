@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutboundTimeouts(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow-body" {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	c := useTestConfig(t, map[string]string{"MEDUSA_BASE_URL": srv.URL, "CSA_MEDUSA_TIMEOUT": "50ms"})
+
+	start := time.Now()
+	_, err := httpClient.Get(srv.URL + "/admin/products")
+	if err == nil || !strings.Contains(err.Error(), "no answer within 50ms") {
+		t.Fatalf("hung Medusa call: err = %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("took %s", time.Since(start))
+	}
+	if st := breakers[depMedusa].status(); st.ConsecutiveFailures != 1 {
+		t.Errorf("a timeout should count against the breaker: %+v", st)
+	}
+
+	// the deadline covers reading the body too
+	res, err := httpClient.Get(srv.URL + "/slow-body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(res.Body)
+	res.Body.Close()
+	if err == nil {
+		t.Error("reading a hung body: want an error")
+	}
+
+	// the caller cancelling isn't the dependency's fault
+	resetBreakers()
+	c.MedusaTimeout = time.Minute
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/admin/products", nil)
+	if _, err := httpClient.Do(req); err == nil || strings.Contains(err.Error(), "no answer within") {
+		t.Errorf("cancelled call: err = %v", err)
+	}
+	if st := breakers[depMedusa].status(); st.ConsecutiveFailures != 0 {
+		t.Errorf("cancelled call counted against the breaker: %+v", st)
+	}
+}
+
+func TestCallTimeoutPerDependency(t *testing.T) {
+	useTestConfig(t, map[string]string{
+		"MEDUSA_BASE_URL": "http://medusa.internal:9000", "CSA_OPENAI_TIMEOUT": "90s",
+		"CSA_MEDUSA_TIMEOUT": "15s", "CSA_HTTP_TIMEOUT": "5s",
+	})
+	for host, want := range map[string]time.Duration{
+		"openai.invalid":       90 * time.Second,
+		"medusa.internal:9000": 15 * time.Second,
+		"api.open-meteo.com":   5 * time.Second,
+	} {
+		if got := callTimeout(host); got != want {
+			t.Errorf("callTimeout(%q) = %s, want %s", host, got, want)
+		}
+	}
+}
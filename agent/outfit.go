@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/outfit"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+)
+
+type CompleteOutfitReq struct {
+	Mission      string   `json:"mission"`    // smart_casual | business_casual | outdoor_rain | a tenant's own, see /missions
+	BudgetGBP    float64  `json:"budget_gbp"` // budget for add-ons; with cart_id, for the whole outfit
+	MinEcoScore  int      `json:"min_eco_score"`
+	CartSlots    []string `json:"cart_slots"`            // e.g. ["top"] or ["top","outerwear"]
+	CartID       string   `json:"cart_id,omitempty"`     // Medusa cart; replaces cart_slots
+	LimitPerSlot int      `json:"limit_per_slot"`        // default 3
+	PriceBands   bool     `json:"price_bands,omitempty"` // also group each slot's hits into budget/mid/premium
+	Query        string   `json:"query,omitempty"`       // free text, e.g. "something for a rainy hike"; explicit fields win
+	// list other listings of each hit (other colours, relists) in duplicates
+	ExpandDuplicates bool `json:"expand_duplicates,omitempty"`
+	// l2 | cosine | inner_product; default CSA_DISTANCE_METRIC
+	DistanceMetric string `json:"distance_metric,omitempty"`
+	AttrFilters
+	OriginPrefs
+	FreshnessPrefs
+	ClearancePrefs
+	RankingPrefs
+	DiversityPrefs
+	LifecyclePrefs
+	Gift    *GiftOptions `json:"gift,omitempty"`    // gift mode when set
+	Weather *WeatherReq  `json:"weather,omitempty"` // outdoor missions: adapt to the forecast
+	// adds each slot's similarity threshold and each hit's margin over it
+	Debug bool `json:"debug,omitempty"`
+
+	palette []string      // shared colors, set by /group-outfits
+	cart    *outfitCart   // resolved from CartID by the handler
+	mission *Mission      // resolved from Mission; runCompleteOutfit loads it if nil
+	intent  *OutfitIntent // parsed from Query by the handler
+
+	constraints []SessionConstraint // the session's, loaded by the handler
+}
+
+// resolveCart loads req.CartID, if set, into req.cart.
+func (req *CompleteOutfitReq) resolveCart(ctx context.Context, pool *pgxpool.Pool) error {
+	if req.CartID == "" {
+		return nil
+	}
+	c, err := loadCart(ctx, pool, req.CartID)
+	if err != nil {
+		return err
+	}
+	req.cart = c
+	return nil
+}
+
+type SlotRecs struct {
+	Slot       string          `json:"slot"`
+	Hits       []Hit           `json:"hits"`
+	Bands      []PriceBand     `json:"bands,omitempty"` // with price_bands
+	Reason     string          `json:"reason,omitempty"`
+	Confidence *SlotConfidence `json:"confidence,omitempty"` // how sure the top pick is; see confidence.go
+	Threshold  *SlotThreshold  `json:"threshold,omitempty"`  // with debug; see thresholds.go
+	Optional   bool            `json:"optional,omitempty"`   // an optional mission slot the outfit gained
+}
+
+type CompleteOutfitResp struct {
+	Mission      string           `json:"mission,omitempty"`
+	BudgetGBP    float64          `json:"budget_gbp,omitempty"`
+	MinEcoScore  int              `json:"min_eco_score,omitempty"`
+	CartSlots    []string         `json:"cart_slots,omitempty"`
+	MissingSlots []string         `json:"missing_slots"`
+	Results      []SlotRecs       `json:"results"`
+	Optional     []OptionalSlot   `json:"optional_slots,omitempty"` // what was decided for the mission's optional slots
+	Gift         *GiftSummary     `json:"gift,omitempty"`
+	Cart         *CartSummary     `json:"cart,omitempty"`
+	Bundle       *BundleSummary   `json:"bundle,omitempty"`
+	EcoGrade     *EcoGrade        `json:"eco_grade,omitempty"`
+	Intent       *OutfitIntent    `json:"intent,omitempty"`   // what was read from query
+	Forecast     *Forecast        `json:"forecast,omitempty"` // with weather, for outdoor missions
+	Cache        *OutfitCacheInfo `json:"cache,omitempty"`    // set when served from the cache
+	// the session's temporary constraints, applied to the slots they name
+	SessionConstraints []ConstraintRef `json:"session_constraints,omitempty"`
+	Meta               *ResponseMeta   `json:"meta,omitempty"`
+}
+
+// completeOutfit answers a /complete-outfit request the cache can't: it
+// reads the free-text query, if any, then builds the outfit.
+func completeOutfit(ctx context.Context, pool *pgxpool.Pool, req CompleteOutfitReq) (CompleteOutfitResp, error) {
+	if q := strings.TrimSpace(req.Query); q != "" {
+		in, err := parseOutfitIntent(ctx, pool, q)
+		if err != nil {
+			slog.WarnContext(ctx, "complete-outfit: intent parsing failed", "err", err)
+			addWarnings(ctx, "could not interpret query; using the explicit fields only")
+		} else {
+			in.apply(&req)
+		}
+	}
+	return runCompleteOutfit(ctx, pool, req)
+}
+
+// slotSearch is the search for one outfit slot: the request's filters and
+// preferences, with the slot's query, size and price cap.
+func (req CompleteOutfitReq) slotSearch(slot, query string, limit int, maxPrice float64, style []float64) searchParams {
+	return searchParams{
+		Query:            query,
+		Limit:            limit,
+		MaxPriceGBP:      maxPrice,
+		MinEcoScore:      req.MinEcoScore,
+		Category:         slot,
+		Attrs:            constrain(req.AttrFilters, req.constraints, slot),
+		Origin:           req.OriginPrefs,
+		Fresh:            req.FreshnessPrefs,
+		Clearance:        req.ClearancePrefs,
+		Ranking:          req.RankingPrefs,
+		Diversity:        req.DiversityPrefs,
+		Lifecycle:        req.LifecyclePrefs,
+		ExpandDuplicates: req.ExpandDuplicates,
+		Metric:           req.DistanceMetric,
+		GiftOnly:         req.Gift != nil,
+		Palette:          req.palette,
+		Style:            style,
+	}
+}
+
+func runCompleteOutfit(ctx context.Context, pool *pgxpool.Pool, req CompleteOutfitReq) (CompleteOutfitResp, error) {
+	if req.LimitPerSlot <= 0 {
+		req.LimitPerSlot = 3
+	}
+	mission := req.mission
+	if mission == nil {
+		m, err := resolveMission(ctx, pool, req.Mission)
+		if err != nil {
+			return CompleteOutfitResp{}, err
+		}
+		mission = &m
+	}
+	mission.applyDefaults(&req)
+	forecast := forecastFor(ctx, mission.Name, req.Weather)
+
+	present := req.CartSlots
+	budget := req.BudgetGBP
+	var cartSummary *CartSummary
+	var style []float64
+	if req.cart != nil {
+		// with a cart, budget_gbp is the whole outfit's budget
+		s := req.cart.Summary
+		cartSummary, present, style = &s, req.cart.Slots, req.cart.Style
+		if budget > 0 {
+			s.RemainingBudgetGBP = outfit.Leftover(budget, s.SpentGBP)
+			budget = max(s.RemainingBudgetGBP, 0.01) // 0 would mean no limit
+		}
+	}
+
+	missing := ranking.MissingSlots(mission.Slots, present)
+
+	// gift mode: wrapping for each added item comes out of the budget first
+	itemsBudget := budget
+	var gift *GiftSummary
+	if req.Gift != nil {
+		itemsBudget, gift = giftBudget(budget, len(missing), req.Gift)
+	}
+
+	slotBudgets := outfit.SlotBudgets(itemsBudget, len(missing), budget > 0)
+
+	fetch := req.LimitPerSlot
+	var bounds map[string]priceBounds
+	if req.PriceBands {
+		var err error
+		if bounds, err = loadPriceBounds(ctx, pool); err != nil {
+			return CompleteOutfitResp{}, err
+		}
+		fetch *= priceBandCandidateFactor
+	}
+
+	results := make([]SlotRecs, 0, len(missing))
+	var lowConfidence, noMatch []string
+
+	for i, slot := range missing {
+		perSlotBudget := slotBudgets[i]
+		q := mission.query(slot)
+		var weatherWords []string
+		if forecast != nil {
+			weatherWords = forecast.descriptors(slot)
+			q = strings.Join(append([]string{q}, weatherWords...), " ")
+		}
+
+		slotCtx, span := startSpan(ctx, "complete-outfit.slot", "slot", slot, "mission", req.Mission)
+		hits, err := searchHits(slotCtx, pool, req.slotSearch(slot, q, fetch, perSlotBudget, style))
+		span.End()
+		if err != nil {
+			return CompleteOutfitResp{}, err
+		}
+		if hits == nil {
+			hits = []Hit{} // never return null
+		}
+		var threshold *SlotThreshold
+		if minSim, source := mission.minSimilarity(slot); minSim > 0 || req.Debug {
+			hits, threshold = applyThreshold(hits, minSim, source, req.Debug)
+		}
+
+		reason := ""
+		if len(hits) == 0 && threshold != nil && threshold.BestSimilarity != nil {
+			reason = noGoodMatch(slot, threshold)
+			noMatch = append(noMatch, slot)
+		} else if len(hits) == 0 {
+			reason = outfit.EmptyReason(slot, perSlotBudget, req.MinEcoScore)
+		} else {
+			for i := range hits {
+				hits[i].Reason = outfit.HitReason(slot, hits[i].EcoScore, hits[i].PriceGBP, perSlotBudget)
+				if len(weatherWords) > 0 {
+					hits[i].Reason += fmt.Sprintf(" Searched for %s for %s.", strings.Join(weatherWords, ", "), forecast.Summary)
+				}
+			}
+		}
+
+		conf := slotConfidence(hits, perSlotBudget, req.MinEcoScore)
+		if conf != nil && conf.Low {
+			lowConfidence = append(lowConfidence, slot)
+		}
+
+		var bands []PriceBand
+		if req.PriceBands {
+			if b, ok := bounds[slot]; ok {
+				bands = bandHits(hits, b, req.LimitPerSlot)
+			} else {
+				addWarnings(ctx, fmt.Sprintf("no price distribution for slot=%s yet; bands omitted", slot))
+			}
+			hits = hits[:min(len(hits), req.LimitPerSlot)]
+		}
+
+		if !req.Debug {
+			threshold = nil
+		}
+		results = append(results, SlotRecs{Slot: slot, Hits: hits, Bands: bands, Reason: reason, Confidence: conf, Threshold: threshold})
+	}
+	if len(lowConfidence) > 0 {
+		logOutcome(ctx, slog.Any("low_confidence_slots", lowConfidence))
+	}
+	if len(noMatch) > 0 {
+		logOutcome(ctx, slog.Any("no_good_match_slots", noMatch))
+	}
+
+	var optional []OptionalSlot
+	if slots := mission.optionalSlots(present); len(slots) > 0 {
+		recs, decisions, err := fillOptionalSlots(ctx, pool, req, *mission, slots, leftoverBudget(itemsBudget, results), budget > 0, style)
+		if err != nil {
+			return CompleteOutfitResp{}, err
+		}
+		results, optional = append(results, recs...), decisions
+	}
+
+	resp := CompleteOutfitResp{
+		Mission:      req.Mission,
+		BudgetGBP:    req.BudgetGBP,
+		MinEcoScore:  req.MinEcoScore,
+		CartSlots:    present,
+		MissingSlots: missing,
+		Results:      results,
+		Optional:     optional,
+		Gift:         gift,
+		Cart:         cartSummary,
+		Intent:       req.intent,
+		Forecast:     forecast,
+	}
+	if gift != nil {
+		gift.MessageSuggestion, gift.MessageFromTemplate = giftMessage(ctx, pool, req.Gift, resp)
+	}
+	resp.Bundle = bundleDeal(ctx, pool, resp, budget)
+	resp.EcoGrade = outfitEcoGrade(resp)
+	return resp, nil
+}
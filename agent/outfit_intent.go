@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+)
+
+// maxIntentText caps free-text outfit requests.
+const maxIntentText = 500
+
+// OutfitIntent is an outfit request read from the shopper's own words,
+// e.g. "I need something for a rainy hike this weekend, under £150".
+type OutfitIntent struct {
+	Text        string   `json:"text"`
+	Mission     string   `json:"mission"`
+	Slots       []string `json:"slots"` // the mission's slots
+	BudgetGBP   float64  `json:"budget_gbp,omitempty"`
+	MinEcoScore int      `json:"min_eco_score,omitempty"`
+	CartSlots   []string `json:"cart_slots,omitempty"` // slots the shopper says they already have
+	Color       string   `json:"color,omitempty"`
+	Material    string   `json:"material,omitempty"`
+	Size        string   `json:"size,omitempty"`
+}
+
+func intentSchema(missions, slots []string) *outputSchema {
+	nullable := func(t string) map[string]any { return map[string]any{"type": []string{t, "null"}} }
+	return &outputSchema{Name: "outfit_intent", Schema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"mission":       map[string]any{"type": "string", "enum": missions},
+			"budget_gbp":    nullable("number"),
+			"min_eco_score": nullable("integer"),
+			"owned_slots":   map[string]any{"type": "array", "items": map[string]any{"type": "string", "enum": slots}},
+			"color":         nullable("string"),
+			"material":      nullable("string"),
+			"size":          nullable("string"),
+		},
+		"required":             []string{"mission", "budget_gbp", "min_eco_score", "owned_slots", "color", "material", "size"},
+		"additionalProperties": false,
+	}}
+}
+
+// parseOutfitIntent maps free text to one of the tenant's missions and the
+// constraints the shopper stated. Anything not stated is left empty so the
+// mission's defaults apply.
+func parseOutfitIntent(ctx context.Context, pool *pgxpool.Pool, text string) (OutfitIntent, error) {
+	tenantID := tenantFromContext(ctx)
+	missions, err := listMissions(ctx, pool, tenantID)
+	if err != nil {
+		return OutfitIntent{}, err
+	}
+	var names, slots, lines []string
+	for _, m := range missions {
+		names = append(names, m.Name)
+		lines = append(lines, fmt.Sprintf("- %s: %s", m.Name, strings.Join(m.Slots, ", ")))
+		for _, s := range m.Slots {
+			if !slices.Contains(slots, s) {
+				slots = append(slots, s)
+			}
+		}
+	}
+
+	prompt := fmt.Sprintf(`Read a shopper's request for an outfit and map it to a structured search.
+Missions (name: slots an outfit needs):
+%s
+
+Rules:
+- mission: the closest mission; use %s if none fits.
+- budget_gbp: only if the shopper states a total budget, in GBP.
+- min_eco_score (0-100): 60 if they ask for sustainable or eco-friendly items, higher if they insist; otherwise null.
+- owned_slots: slots the shopper says they already have.
+- color, material, size: only if stated, as a single lowercase word or size label.
+Use null for anything not stated. Return only the JSON object.
+
+Request: %q`, strings.Join(lines, "\n"), defaultMission, text)
+
+	provider := ""
+	if ts, err := loadTenantSettings(ctx, pool, tenantID); err == nil {
+		provider = ts.LLMProvider
+	}
+	raw, err := llmChat(ctx, provider, config.LLMIntent, prompt, intentSchema(names, slots))
+	if err != nil {
+		return OutfitIntent{}, err
+	}
+	var out struct {
+		Mission     string   `json:"mission"`
+		BudgetGBP   *float64 `json:"budget_gbp"`
+		MinEcoScore *int     `json:"min_eco_score"`
+		OwnedSlots  []string `json:"owned_slots"`
+		Color       *string  `json:"color"`
+		Material    *string  `json:"material"`
+		Size        *string  `json:"size"`
+	}
+	if err := json.Unmarshal([]byte(ranking.StripCodeFence(raw)), &out); err != nil {
+		return OutfitIntent{}, fmt.Errorf("parse intent: %w", err)
+	}
+
+	// models don't always honour the enums, so check everything
+	in := OutfitIntent{Text: text, Mission: defaultMission}
+	for _, m := range missions {
+		if m.Name == out.Mission {
+			in.Mission = m.Name
+		}
+		if m.Name == in.Mission {
+			in.Slots = m.Slots
+		}
+	}
+	if out.BudgetGBP != nil && *out.BudgetGBP > 0 {
+		in.BudgetGBP = *out.BudgetGBP
+	}
+	if out.MinEcoScore != nil {
+		in.MinEcoScore = min(max(*out.MinEcoScore, 0), 100)
+	}
+	for _, s := range out.OwnedSlots {
+		if slices.Contains(in.Slots, s) && !slices.Contains(in.CartSlots, s) {
+			in.CartSlots = append(in.CartSlots, s)
+		}
+	}
+	word := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return strings.ToLower(strings.TrimSpace(*s))
+	}
+	in.Color, in.Material, in.Size = word(out.Color), word(out.Material), word(out.Size)
+	return in, nil
+}
+
+// apply fills in what req leaves out; explicit request fields win.
+func (in OutfitIntent) apply(req *CompleteOutfitReq) {
+	if req.Mission == "" {
+		req.Mission = in.Mission
+	}
+	if req.BudgetGBP <= 0 {
+		req.BudgetGBP = in.BudgetGBP
+	}
+	if req.MinEcoScore <= 0 {
+		req.MinEcoScore = in.MinEcoScore
+	}
+	if req.CartID == "" && len(req.CartSlots) == 0 {
+		req.CartSlots = in.CartSlots
+	}
+	if req.Color == "" {
+		req.Color = in.Color
+	}
+	if req.Material == "" {
+		req.Material = in.Material
+	}
+	if req.Size == "" {
+		req.Size = in.Size
+	}
+	req.intent = &in
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"math"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Price bands for "good/better/best" columns.
+const (
+	bandBudget  = "budget"  // at or below the category's 25th percentile price
+	bandMid     = "mid"     // between the 25th and 75th percentiles
+	bandPremium = "premium" // above the 75th percentile
+)
+
+// Banded slots search this many times limit_per_slot so each band can fill.
+const priceBandCandidateFactor = 4
+
+// PriceBand is one column of a slot's banded results.
+type PriceBand struct {
+	Band   string   `json:"band"`
+	MinGBP *float64 `json:"min_gbp,omitempty"` // exclusive
+	MaxGBP *float64 `json:"max_gbp,omitempty"` // inclusive
+	Hits   []Hit    `json:"hits"`
+}
+
+type priceBounds struct{ p25, p75 float64 }
+
+// loadPriceBounds reads each category's quartiles from mv_price_distribution.
+func loadPriceBounds(ctx context.Context, pool *pgxpool.Pool) (map[string]priceBounds, error) {
+	rows, err := pool.Query(ctx, `SELECT category, p25, p75 FROM mv_price_distribution`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]priceBounds{}
+	for rows.Next() {
+		var cat string
+		var b priceBounds
+		if err := rows.Scan(&cat, &b.p25, &b.p75); err != nil {
+			return nil, err
+		}
+		out[cat] = b
+	}
+	return out, rows.Err()
+}
+
+// bandHits splits hits, in rank order, into budget/mid/premium bands of at
+// most limit each. Every band is returned, empty or not, so columns line up.
+func bandHits(hits []Hit, b priceBounds, limit int) []PriceBand {
+	p25, p75 := math.Round(b.p25*100)/100, math.Round(b.p75*100)/100
+	bands := []PriceBand{
+		{Band: bandBudget, MaxGBP: &p25, Hits: []Hit{}},
+		{Band: bandMid, MinGBP: &p25, MaxGBP: &p75, Hits: []Hit{}},
+		{Band: bandPremium, MinGBP: &p75, Hits: []Hit{}},
+	}
+	for _, h := range hits {
+		i := 1
+		switch {
+		case h.PriceGBP <= b.p25:
+			i = 0
+		case h.PriceGBP > b.p75:
+			i = 2
+		}
+		if len(bands[i].Hits) < limit {
+			bands[i].Hits = append(bands[i].Hits, h)
+		}
+	}
+	return bands
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+)
+
+// The index stores prices in GBP (price_gbp), so only GBP prices are used.
+const priceCurrency = "gbp"
+
+// medusaProductFields asks the admin API for stock and variant prices.
+const medusaProductFields = medusaInventoryFields + ",%2Bvariants.prices"
+
+// medusaPrice is one variant price. Medusa v2 amounts are in major units
+// (12.5 = £12.50). Region prices carry a region_id rule; tiered prices a
+// min_quantity.
+type medusaPrice struct {
+	Amount       float64         `json:"amount"`
+	CurrencyCode string          `json:"currency_code"`
+	MinQuantity  *float64        `json:"min_quantity"`
+	Rules        json.RawMessage `json:"rules"`
+}
+
+func (p medusaPrice) regionID() string {
+	var rules map[string]any
+	if json.Unmarshal(p.Rules, &rules) != nil {
+		return ""
+	}
+	id, _ := rules["region_id"].(string)
+	return id
+}
+
+// variantPriceGBP returns the lowest single-unit GBP price across variants,
+// the "from" price a shopper sees. Per variant, a price for
+// CSA_MEDUSA_REGION_ID wins over the currency's base price (no rules).
+// ok is false when no variant has a GBP price.
+func variantPriceGBP(variants [][]medusaPrice) (price float64, ok bool) {
+	price = math.Inf(1)
+	for _, prices := range variants {
+		var base, region *medusaPrice
+		for i, p := range prices {
+			if !strings.EqualFold(p.CurrencyCode, priceCurrency) || (p.MinQuantity != nil && *p.MinQuantity > 1) {
+				continue
+			}
+			switch rid := p.regionID(); {
+			case rid != "" && rid == cfg().Medusa.RegionID:
+				region = &prices[i]
+			case rid == "" && base == nil:
+				base = &prices[i]
+			}
+		}
+		chosen := base
+		if region != nil {
+			chosen = region
+		}
+		if chosen != nil && chosen.Amount < price {
+			price, ok = chosen.Amount, true
+		}
+	}
+	if !ok {
+		return 0, false
+	}
+	return price, true
+}
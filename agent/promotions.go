@@ -0,0 +1,452 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// Promotions change rarely; each replica refetches them this often.
+const promotionsCacheTTL = 5 * time.Minute
+
+// after a failed fetch, wait this long before asking Medusa again
+const promotionsRetryAfter = 30 * time.Second
+
+// candidate additions checked per promotion when suggesting one
+const maxSuggestionCandidates = 10
+
+// BundleSummary prices the outfit's top picks with the best Medusa
+// promotion they qualify for, or suggests the cheapest addition that would
+// unlock one.
+type BundleSummary struct {
+	SubtotalGBP float64           `json:"subtotal_gbp"`
+	DiscountGBP float64           `json:"discount_gbp"`
+	TotalGBP    float64           `json:"total_gbp"`
+	Promotion   *AppliedPromotion `json:"promotion,omitempty"`
+	Suggestion  *BundleSuggestion `json:"suggestion,omitempty"`
+}
+
+type AppliedPromotion struct {
+	ID        string `json:"id"`
+	Code      string `json:"code,omitempty"`
+	Automatic bool   `json:"automatic"` // false = the shopper enters Code at checkout
+}
+
+// BundleSuggestion is an item that, added to the outfit, qualifies it for
+// a promotion, and what the bundle would then cost.
+type BundleSuggestion struct {
+	Add         Hit              `json:"add"`
+	Promotion   AppliedPromotion `json:"promotion"`
+	DiscountGBP float64          `json:"discount_gbp"`
+	TotalGBP    float64          `json:"total_gbp"`
+}
+
+// Medusa v2 promotion as returned by /admin/promotions.
+type medusaPromotion struct {
+	ID          string `json:"id"`
+	Code        string `json:"code"`
+	Type        string `json:"type"` // standard | buyget
+	IsAutomatic bool   `json:"is_automatic"`
+	Status      string `json:"status"` // draft | active | inactive; empty on older Medusa
+	Campaign    *struct {
+		StartsAt *time.Time `json:"starts_at"`
+		EndsAt   *time.Time `json:"ends_at"`
+	} `json:"campaign"`
+	Rules             []promotionRule `json:"rules"`
+	ApplicationMethod struct {
+		Type         string          `json:"type"`        // percentage | fixed
+		TargetType   string          `json:"target_type"` // items | order | shipping_methods
+		Allocation   string          `json:"allocation"`  // each | across
+		Value        float64         `json:"value"`
+		CurrencyCode string          `json:"currency_code"`
+		MaxQuantity  *int            `json:"max_quantity"`
+		ApplyToQty   *int            `json:"apply_to_quantity"`
+		BuyMinQty    *int            `json:"buy_rules_min_quantity"`
+		TargetRules  []promotionRule `json:"target_rules"`
+		BuyRules     []promotionRule `json:"buy_rules"`
+	} `json:"application_method"`
+}
+
+type promotionRule struct {
+	Attribute string `json:"attribute"`
+	Operator  string `json:"operator"`
+	Values    []struct {
+		Value string `json:"value"`
+	} `json:"values"`
+}
+
+// productIDs returns the product IDs a rule set allows, nil for "any
+// product", and ok=false when a rule can't be evaluated from the index
+// (customer groups, categories, ...), in which case the promotion is skipped.
+func productIDs(rules []promotionRule) (ids []string, ok bool) {
+	for _, r := range rules {
+		if r.Attribute != "items.product.id" && r.Attribute != "product_id" {
+			return nil, false
+		}
+		if r.Operator != "in" && r.Operator != "eq" {
+			return nil, false
+		}
+		var vals []string
+		for _, v := range r.Values {
+			vals = append(vals, v.Value)
+		}
+		if ids == nil {
+			ids = vals
+		} else {
+			// rules are ANDed
+			ids = slices.DeleteFunc(ids, func(id string) bool { return !slices.Contains(vals, id) })
+			if ids == nil {
+				ids = []string{}
+			}
+		}
+	}
+	return ids, true
+}
+
+func matching(items []Hit, ids []string) []Hit {
+	if ids == nil {
+		return items
+	}
+	var out []Hit
+	for _, h := range items {
+		if slices.Contains(ids, h.ProductID) {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// bundlePromotion is a promotion the agent can evaluate against a bundle.
+type bundlePromotion struct {
+	medusaPromotion
+	targets []string // nil = any product
+	buys    []string // buyget only
+}
+
+func (p bundlePromotion) applied() AppliedPromotion {
+	return AppliedPromotion{ID: p.ID, Code: p.Code, Automatic: p.IsAutomatic}
+}
+
+// discount is what the promotion takes off items (one unit each), or 0
+// when the bundle doesn't qualify.
+func (p bundlePromotion) discount(items []Hit) float64 {
+	am := p.ApplicationMethod
+	off := func(price float64) float64 {
+		if am.Type == "percentage" {
+			return price * am.Value / 100
+		}
+		return min(am.Value, price)
+	}
+
+	targets := slices.Clone(matching(items, p.targets))
+	// cheapest first: that's what buy-X-get-Y discounts
+	sort.Slice(targets, func(i, j int) bool { return targets[i].PriceGBP < targets[j].PriceGBP })
+
+	switch {
+	case p.Type == "buyget":
+		buyMin, applyTo := 1, 1
+		if am.BuyMinQty != nil {
+			buyMin = *am.BuyMinQty
+		}
+		if am.ApplyToQty != nil {
+			applyTo = *am.ApplyToQty
+		}
+		buys := matching(items, p.buys)
+		// the discounted items can't also count towards the ones bought
+		free := min(applyTo, len(targets))
+		for free > 0 && len(buys)-countShared(buys, targets[:free]) < buyMin {
+			free--
+		}
+		total := 0.0
+		for _, h := range targets[:free] {
+			total += off(h.PriceGBP)
+		}
+		return total
+	case am.TargetType == "order":
+		sum := 0.0
+		for _, h := range items {
+			sum += h.PriceGBP
+		}
+		return off(sum)
+	case am.TargetType == "items":
+		if len(targets) == 0 {
+			return 0
+		}
+		if am.MaxQuantity != nil && *am.MaxQuantity > 0 && len(targets) > *am.MaxQuantity {
+			// Medusa discounts the most expensive eligible units first
+			targets = targets[len(targets)-*am.MaxQuantity:]
+		}
+		if am.Type == "fixed" && am.Allocation == "across" {
+			sum := 0.0
+			for _, h := range targets {
+				sum += h.PriceGBP
+			}
+			return min(am.Value, sum)
+		}
+		total := 0.0
+		for _, h := range targets {
+			total += off(h.PriceGBP)
+		}
+		return total
+	}
+	return 0 // shipping promotions don't change the bundle price
+}
+
+// countShared counts items present in both lists.
+func countShared(a, b []Hit) int {
+	n := 0
+	for _, x := range a {
+		for _, y := range b {
+			if x.ProductID == y.ProductID {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+var promoCache struct {
+	sync.Mutex
+	promos  []bundlePromotion
+	err     error // the last fetch's, served until expires
+	expires time.Time
+	loading chan struct{} // closed when the fetch in flight finishes
+}
+
+// activePromotions returns the store's evaluable GBP-or-percentage
+// promotions that are live now, cached for promotionsCacheTTL. One caller
+// fetches while the others wait for its answer, and a failed fetch is
+// answered from the cache for promotionsRetryAfter so a Medusa outage isn't
+// hit by every outfit request.
+func activePromotions(ctx context.Context) ([]bundlePromotion, error) {
+	promoCache.Lock()
+	for promoCache.loading != nil && !time.Now().Before(promoCache.expires) {
+		wait := promoCache.loading
+		promoCache.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		promoCache.Lock()
+	}
+	if time.Now().Before(promoCache.expires) {
+		defer promoCache.Unlock()
+		return promoCache.promos, promoCache.err
+	}
+	done := make(chan struct{})
+	promoCache.loading = done
+	promoCache.Unlock()
+
+	// the waiters share the answer, so it mustn't die with this request
+	promos, err := fetchPromotions(context.WithoutCancel(ctx))
+
+	promoCache.Lock()
+	defer promoCache.Unlock()
+	promoCache.promos, promoCache.err, promoCache.loading = promos, err, nil
+	if err != nil {
+		promoCache.expires = time.Now().Add(promotionsRetryAfter)
+	} else {
+		promoCache.expires = time.Now().Add(promotionsCacheTTL)
+	}
+	close(done)
+	return promos, err
+}
+
+// fetchPromotions reads and filters the promotions from Medusa.
+func fetchPromotions(ctx context.Context) ([]bundlePromotion, error) {
+	res, err := medusaGet(ctx, "/admin/promotions?limit=100&fields=id,code,type,is_automatic,status,*campaign,*rules,*rules.values,"+
+		"*application_method,*application_method.target_rules,*application_method.target_rules.values,"+
+		"*application_method.buy_rules,*application_method.buy_rules.values")
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	var payload struct {
+		Promotions []medusaPromotion `json:"promotions"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var out []bundlePromotion
+	for _, mp := range payload.Promotions {
+		if mp.Status != "" && mp.Status != "active" {
+			continue
+		}
+		if c := mp.Campaign; c != nil && ((c.StartsAt != nil && now.Before(*c.StartsAt)) || (c.EndsAt != nil && now.After(*c.EndsAt))) {
+			continue
+		}
+		am := mp.ApplicationMethod
+		if am.Type == "fixed" && !strings.EqualFold(am.CurrencyCode, priceCurrency) {
+			continue
+		}
+		if _, ok := productIDs(mp.Rules); !ok {
+			continue // cart-level conditions we can't check
+		}
+		bp := bundlePromotion{medusaPromotion: mp}
+		var ok bool
+		if bp.targets, ok = productIDs(am.TargetRules); !ok {
+			continue
+		}
+		if bp.buys, ok = productIDs(am.BuyRules); !ok {
+			continue
+		}
+		out = append(out, bp)
+	}
+	slog.InfoContext(ctx, "promotions: loaded", "active", len(out), "total", len(payload.Promotions))
+	return out, nil
+}
+
+// bundleDeal prices the outfit's top picks against active promotions. It is
+// best effort: without Medusa or on errors the outfit simply has no bundle.
+func bundleDeal(ctx context.Context, pool *pgxpool.Pool, resp CompleteOutfitResp, budget float64) *BundleSummary {
+	if cfg().CatalogProvider != config.CatalogMedusa || !medusaAuthConfigured() || sandboxFrom(ctx) {
+		return nil
+	}
+	var picks []Hit
+	for _, r := range resp.Results {
+		if len(r.Hits) > 0 {
+			picks = append(picks, r.Hits[0])
+		}
+	}
+	if len(picks) == 0 {
+		return nil
+	}
+	promos, err := activePromotions(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "promotions: fetch failed", "err", err)
+		return nil
+	}
+	if len(promos) == 0 {
+		return nil
+	}
+
+	b := &BundleSummary{}
+	for _, h := range picks {
+		b.SubtotalGBP += h.PriceGBP
+	}
+	var best *bundlePromotion
+	for i, p := range promos {
+		if d := p.discount(picks); d > b.DiscountGBP {
+			b.DiscountGBP, best = d, &promos[i]
+		}
+	}
+	if best != nil {
+		ap := best.applied()
+		b.Promotion = &ap
+	} else {
+		b.Suggestion = suggestAddition(ctx, pool, picks, promos, b.SubtotalGBP, budget, resp.MissingSlots)
+		if b.Suggestion == nil {
+			return nil
+		}
+	}
+	b.SubtotalGBP = roundPence(b.SubtotalGBP)
+	b.DiscountGBP = roundPence(b.DiscountGBP)
+	b.TotalGBP = roundPence(b.SubtotalGBP - b.DiscountGBP)
+	return b
+}
+
+// suggestAddition finds the addition with the lowest net cost (price minus
+// the discount it unlocks) that keeps the bundle within budget.
+func suggestAddition(ctx context.Context, pool *pgxpool.Pool, picks []Hit, promos []bundlePromotion, subtotal, budget float64, slots []string) *BundleSuggestion {
+	var inBundle []string
+	for _, h := range picks {
+		inBundle = append(inBundle, h.ProductID)
+	}
+	var best *BundleSuggestion
+	bestNet := math.Inf(1)
+	for _, p := range promos {
+		ids := p.targets
+		if p.Type == "buyget" && p.buys != nil && ids != nil {
+			ids = append(slices.Clone(ids), p.buys...)
+		}
+		cands, err := additionCandidates(ctx, pool, ids, inBundle, slots)
+		if err != nil {
+			slog.WarnContext(ctx, "promotions: candidate lookup failed", "promotion", p.ID, "err", err)
+			continue
+		}
+		for _, c := range cands {
+			d := p.discount(append(slices.Clone(picks), c))
+			if d <= 0 {
+				continue
+			}
+			total := subtotal + c.PriceGBP - d
+			if budget > 0 && total > budget {
+				continue
+			}
+			if net := c.PriceGBP - d; net < bestNet {
+				bestNet = net
+				best = &BundleSuggestion{Add: c, Promotion: p.applied(), DiscountGBP: roundPence(d), TotalGBP: roundPence(total)}
+			}
+		}
+	}
+	return best
+}
+
+// additionCandidates lists the cheapest in-stock products among ids (any
+// product in slots when ids is nil) that aren't already in the bundle.
+func additionCandidates(ctx context.Context, pool *pgxpool.Pool, ids, exclude, slots []string) ([]Hit, error) {
+	rows, err := pool.Query(ctx, `
+SELECT product_id, title, thumbnail, eco_score, price_gbp, 0::float8 AS distance,
+       stock_qty, variant_availability, COALESCE(eco_labels, '{}'),
+       COALESCE(origin_country, ''), COALESCE(eco_score_source, ''),
+       lifecycle, COALESCE(to_char(ships_at, 'YYYY-MM-DD'), '')
+FROM product_embeddings
+WHERE ($1::text[] IS NULL OR product_id = ANY($1))
+  AND `+sandboxSQL(ctx, "")+`
+  AND `+lifecycleSQL("", false)+`
+  AND ($1::text[] IS NOT NULL OR category = ANY($3))
+  AND NOT (product_id = ANY($2))
+  AND price_gbp > 0
+  AND (stock_qty IS NULL OR stock_qty > 0)
+ORDER BY price_gbp
+LIMIT $4
+`, search.NullList(ids), exclude, slots, maxSuggestionCandidates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	hits, err := scanHits(rows, cfg().Embed.Metric)
+	for i := range hits {
+		hits[i].Similarity = 0 // not a similarity match
+	}
+	return hits, err
+}
+
+func roundPence(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// bundleSentence states the bundle deal for explanations, or "".
+func bundleSentence(b *BundleSummary) string {
+	switch {
+	case b == nil:
+		return ""
+	case b.Promotion != nil:
+		how := "applied automatically"
+		if !b.Promotion.Automatic {
+			how = fmt.Sprintf("with code %s", b.Promotion.Code)
+		}
+		return fmt.Sprintf("Bought together, the picks qualify for a promotion (%s): £%.2f off, £%.2f in total.", how, b.DiscountGBP, b.TotalGBP)
+	case b.Suggestion != nil:
+		s := b.Suggestion
+		return fmt.Sprintf("Adding %s (£%.2f) unlocks a promotion worth £%.2f, bringing the bundle to £%.2f.",
+			s.Add.Title, s.Add.PriceGBP, s.DiscountGBP, s.TotalGBP)
+	}
+	return ""
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func promo(typ, target, method string, value float64, targets, buys []string) bundlePromotion {
+	p := bundlePromotion{targets: targets, buys: buys}
+	p.Type = typ
+	p.ApplicationMethod.TargetType = target
+	p.ApplicationMethod.Type = method
+	p.ApplicationMethod.Value = value
+	return p
+}
+
+func TestBundlePromotionDiscount(t *testing.T) {
+	shirt := Hit{ProductID: "shirt", PriceGBP: 40}
+	shoes := Hit{ProductID: "shoes", PriceGBP: 60}
+	belt := Hit{ProductID: "belt", PriceGBP: 20}
+	outfit := []Hit{shirt, shoes, belt}
+	ptr := func(n int) *int { return &n }
+
+	maxOne := promo("standard", "items", "percentage", 50, nil, nil)
+	maxOne.ApplicationMethod.MaxQuantity = ptr(1)
+	across := promo("standard", "items", "fixed", 25, []string{"shirt", "belt"}, nil)
+	across.ApplicationMethod.Allocation = "across"
+	acrossBig := promo("standard", "items", "fixed", 100, []string{"shirt", "belt"}, nil)
+	acrossBig.ApplicationMethod.Allocation = "across"
+	beltFree := promo("buyget", "items", "percentage", 100, []string{"belt"}, []string{"shirt"})
+	twoForOne := promo("buyget", "items", "percentage", 100, nil, nil)
+	twoForOne.ApplicationMethod.BuyMinQty = ptr(2)
+
+	for _, tc := range []struct {
+		name  string
+		p     bundlePromotion
+		items []Hit
+		want  float64
+	}{
+		{"percentage off every item", promo("standard", "items", "percentage", 10, nil, nil), outfit, 12},
+		{"percentage off targets only", promo("standard", "items", "percentage", 10, []string{"shoes"}, nil), outfit, 6},
+		{"fixed off each target", promo("standard", "items", "fixed", 5, []string{"shirt", "belt"}, nil), outfit, 10},
+		{"fixed capped at the price", promo("standard", "items", "fixed", 50, []string{"belt"}, nil), outfit, 20},
+		{"no target in the bundle", promo("standard", "items", "percentage", 10, []string{"coat"}, nil), outfit, 0},
+		{"max quantity takes the dearest", maxOne, outfit, 30},
+		{"fixed across targets", across, outfit, 25},
+		{"fixed across capped at their sum", acrossBig, outfit, 60},
+		{"order percentage", promo("standard", "order", "percentage", 20, nil, nil), outfit, 24},
+		{"order fixed capped at the total", promo("standard", "order", "fixed", 500, nil, nil), outfit, 120},
+		{"shipping changes nothing", promo("standard", "shipping_methods", "fixed", 5, nil, nil), outfit, 0},
+		{"buy the shirt, belt free", beltFree, outfit, 20},
+		{"belt free needs the shirt", beltFree, []Hit{shoes, belt}, 0},
+		{"buy two get the cheapest free", twoForOne, outfit, 20},
+		// the free item can't count towards the two bought
+		{"two items aren't enough", twoForOne, []Hit{shirt, belt}, 0},
+		{"empty bundle", promo("standard", "order", "percentage", 20, nil, nil), nil, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.p.discount(tc.items); math.Abs(got-tc.want) > 1e-9 {
+				t.Errorf("discount = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// fakePromotions serves /admin/promotions, counting calls; it fails with 503
+// while fail is set.
+func fakePromotions(t *testing.T) (calls *atomic.Int32, fail *atomic.Bool) {
+	t.Helper()
+	calls, fail = &atomic.Int32{}, &atomic.Bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond) // let concurrent callers pile up
+		if fail.Load() {
+			http.Error(w, `{"message":"down"}`, http.StatusServiceUnavailable)
+			return
+		}
+		writeFakeJSON(w, map[string]any{"promotions": []map[string]any{{
+			"id": "promo_1", "type": "standard", "is_automatic": true, "status": "active",
+			"application_method": map[string]any{"type": "percentage", "target_type": "order", "value": 10},
+		}}})
+	}))
+	t.Cleanup(srv.Close)
+	useTestConfig(t, map[string]string{"MEDUSA_BASE_URL": srv.URL, "MEDUSA_API_TOKEN": "tok"})
+	resetBreakers()
+
+	reset := func() {
+		promoCache.Lock()
+		promoCache.promos, promoCache.err, promoCache.expires, promoCache.loading = nil, nil, time.Time{}, nil
+		promoCache.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+	return calls, fail
+}
+
+func TestActivePromotionsSharesOneFetch(t *testing.T) {
+	calls, _ := fakePromotions(t)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			promos, err := activePromotions(context.Background())
+			if err != nil || len(promos) != 1 || promos[0].ID != "promo_1" {
+				t.Errorf("activePromotions = %v, %v", promos, err)
+			}
+		})
+	}
+	wg.Wait()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fetched %d times, want 1", n)
+	}
+}
+
+func TestActivePromotionsBacksOffAfterError(t *testing.T) {
+	calls, fail := fakePromotions(t)
+	fail.Store(true)
+	ctx := context.Background()
+
+	if _, err := activePromotions(ctx); err == nil {
+		t.Fatal("want the Medusa error")
+	}
+	if _, err := activePromotions(ctx); err == nil {
+		t.Fatal("want the cached error during the backoff")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fetched %d times during the backoff, want 1", n)
+	}
+
+	// once the backoff is over Medusa is asked again
+	fail.Store(false)
+	promoCache.Lock()
+	promoCache.expires = time.Now().Add(-time.Second)
+	promoCache.Unlock()
+	if promos, err := activePromotions(ctx); err != nil || len(promos) != 1 {
+		t.Errorf("after the backoff: %v, %v", promos, err)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("fetched %d times, want 2", n)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// PurgeReq removes indexed products in bulk. At least one of Category or
+// IndexedBefore is required so an empty body can't wipe a tenant's catalog;
+// set All to do exactly that.
+type PurgeReq struct {
+	TenantID      string     `json:"tenant_id,omitempty"` // defaults to the caller's tenant
+	Category      string     `json:"category,omitempty"`
+	IndexedBefore *time.Time `json:"indexed_before,omitempty"` // e.g. products not seen by the last reindex
+	All           bool       `json:"all,omitempty"`
+	DryRun        bool       `json:"dry_run,omitempty"`
+}
+
+type PurgeResp struct {
+	Matched    int      `json:"matched"`
+	Deleted    int      `json:"deleted"`
+	DryRun     bool     `json:"dry_run,omitempty"`
+	ProductIDs []string `json:"product_ids"`
+}
+
+func (p PurgeReq) validate() error {
+	if p.Category == "" && p.IndexedBefore == nil && !p.All {
+		return errors.New("set category, indexed_before, or all=true")
+	}
+	return nil
+}
+
+// deleteProduct removes one of tenantID's products and returns its ID if it
+// existed. Products indexed before tenants existed belong to the default
+// tenant.
+func deleteProduct(ctx context.Context, pool *pgxpool.Pool, tenantID, productID string) ([]string, error) {
+	return deleteWhere(ctx, pool, `product_id=$1 AND COALESCE(tenant_id, $3)=$2`, productID, tenantID, defaultTenant)
+}
+
+func purgeProducts(ctx context.Context, pool *pgxpool.Pool, req PurgeReq) (PurgeResp, error) {
+	// products indexed before tenants existed belong to the default tenant
+	where := `COALESCE(tenant_id, $4)=$1
+  AND ($2::text IS NULL OR category=$2)
+  AND ($3::timestamptz IS NULL OR COALESCE(indexed_at, '-infinity') < $3)`
+	args := []any{req.TenantID, search.NullText(catalog.NormalizeCategory(req.Category)), req.IndexedBefore, defaultTenant}
+
+	resp := PurgeResp{DryRun: req.DryRun}
+	if req.DryRun {
+		rows, err := pool.Query(ctx, `SELECT product_id FROM product_embeddings WHERE `+where+` ORDER BY product_id`, args...)
+		if err != nil {
+			return resp, err
+		}
+		defer rows.Close()
+		resp.ProductIDs = []string{}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				return resp, err
+			}
+			resp.ProductIDs = append(resp.ProductIDs, id)
+		}
+		resp.Matched = len(resp.ProductIDs)
+		return resp, rows.Err()
+	}
+
+	ids, err := deleteWhere(ctx, pool, where, args...)
+	if err != nil {
+		return resp, err
+	}
+	resp.ProductIDs = ids
+	resp.Matched, resp.Deleted = len(ids), len(ids)
+	return resp, nil
+}
+
+// deleteWhere deletes matching products and drops cached responses that
+// recommend them, so removals take effect immediately.
+func deleteWhere(ctx context.Context, pool *pgxpool.Pool, where string, args ...any) ([]string, error) {
+	rows, err := pool.Query(ctx, `DELETE FROM product_embeddings WHERE `+where+` RETURNING product_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	outfits.invalidate(ids)
+	if len(ids) > 0 {
+		invalidateSearchCache(ctx)
+	}
+	return ids, nil
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// vectorStorage is CSA_VECTOR_STORAGE with its re-rank factor.
+func vectorStorage() search.Storage {
+	return search.Storage{Kind: cfg().Embed.Storage, RerankFactor: cfg().Embed.RerankFactor}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// maxRankWeight bounds a per-request weight; only ratios matter, so larger
+// values add nothing.
+const maxRankWeight = 10
+
+// RankingPrefs override CSA_RANK_WEIGHTS for one request. Omitted weights
+// keep the deployment's value.
+type RankingPrefs struct {
+	RankWeights *RankWeightsReq `json:"rank_weights,omitempty"`
+}
+
+type RankWeightsReq struct {
+	Semantic   *float64 `json:"semantic,omitempty"`
+	Eco        *float64 `json:"eco,omitempty"`
+	PriceFit   *float64 `json:"price_fit,omitempty"`
+	Popularity *float64 `json:"popularity,omitempty"`
+}
+
+func (r RankingPrefs) validate() error {
+	if r.RankWeights == nil {
+		return nil
+	}
+	for _, w := range []*float64{r.RankWeights.Semantic, r.RankWeights.Eco, r.RankWeights.PriceFit, r.RankWeights.Popularity} {
+		if w != nil && (*w < 0 || *w > maxRankWeight) {
+			return httpapi.InvalidField("rank_weights", "rank_weights must each be between 0 and 10")
+		}
+	}
+	if w := r.weights(); w.Semantic+w.Eco+w.PriceFit+w.Popularity == 0 {
+		return httpapi.InvalidField("rank_weights", "rank_weights: at least one weight must be positive")
+	}
+	return nil
+}
+
+// weights are the request's weights over the deployment defaults.
+func (r RankingPrefs) weights() config.RankWeights {
+	w := cfg().RankWeights
+	if o := r.RankWeights; o != nil {
+		for _, f := range []struct {
+			src *float64
+			dst *float64
+		}{{o.Semantic, &w.Semantic}, {o.Eco, &w.Eco}, {o.PriceFit, &w.PriceFit}, {o.Popularity, &w.Popularity}} {
+			if f.src != nil {
+				*f.dst = *f.src
+			}
+		}
+	}
+	return w
+}
+
+// ScoreBreakdown shows how a hit's ranking score was reached. Components are
+// 0-100; those with no weight are omitted. Total is their weighted mean and
+// is what the hit was ranked by before any boosts.
+type ScoreBreakdown struct {
+	Semantic   float64  `json:"semantic"`
+	Eco        *float64 `json:"eco,omitempty"`
+	PriceFit   *float64 `json:"price_fit,omitempty"`
+	Popularity *float64 `json:"popularity,omitempty"`
+	Total      float64  `json:"total"`
+}
+
+// rankScore is what re-rankers blend their boosts into: the weighted total
+// when the hit was scored, else its similarity.
+func (h Hit) rankScore() float64 {
+	if h.Scores != nil {
+		return h.Scores.Total
+	}
+	return h.Similarity
+}
+
+// applyRanking scores hits on semantic similarity, eco score, price fit and
+// popularity and sorts them by the weighted total. Price fit is against the
+// budget when there is one, else against the cheapest and dearest
+// candidates. Popularity is recent serves from mv_trending_by_category,
+// log-scaled against the most served candidate, and is only loaded when it
+// carries weight.
+func applyRanking(ctx context.Context, pool *pgxpool.Pool, hits []Hit, prefs RankingPrefs, budget float64) ([]Hit, error) {
+	if len(hits) == 0 {
+		return hits, nil
+	}
+	w := prefs.weights()
+
+	var served map[string]float64
+	if w.Popularity > 0 {
+		var err error
+		if served, err = sortKeys(ctx, pool, hits, search.SortPopularity); err != nil {
+			return nil, err
+		}
+	}
+	maxServed := 0.0
+	for _, n := range served {
+		maxServed = max(maxServed, n)
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, h := range hits {
+		lo, hi = min(lo, h.PriceGBP), max(hi, h.PriceGBP)
+	}
+
+	round := func(v float64) *float64 {
+		v = math.Round(v*100) / 100
+		return &v
+	}
+	total := w.Semantic + w.Eco + w.PriceFit + w.Popularity
+	for i := range hits {
+		h := &hits[i]
+		s := &ScoreBreakdown{Semantic: math.Round(h.Similarity*100) / 100}
+		sum := w.Semantic * h.Similarity
+		if w.Eco > 0 {
+			v := float64(h.EcoScore)
+			s.Eco = round(v)
+			sum += w.Eco * v
+		}
+		if w.PriceFit > 0 {
+			var v float64
+			switch {
+			case budget > 0:
+				v = 100 * (1 - h.PriceGBP/budget)
+			case hi > lo:
+				v = 100 * (hi - h.PriceGBP) / (hi - lo)
+			default:
+				v = 100
+			}
+			v = min(max(v, 0), 100)
+			s.PriceFit = round(v)
+			sum += w.PriceFit * v
+		}
+		if w.Popularity > 0 {
+			var v float64
+			if maxServed > 0 {
+				v = 100 * math.Log1p(served[h.ProductID]) / math.Log1p(maxServed)
+			}
+			s.Popularity = round(v)
+			sum += w.Popularity * v
+		}
+		s.Total = sum / total
+		h.Scores = s
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Scores.Total > hits[j].Scores.Total })
+	return hits, nil
+}
+
+// weightsBeyondSemantic reports whether anything but similarity is ranked
+// on, in which case searches over-fetch so other signals can lift hits.
+func (r RankingPrefs) weightsBeyondSemantic() bool {
+	w := r.weights()
+	return w.Eco > 0 || w.PriceFit > 0 || w.Popularity > 0
+}
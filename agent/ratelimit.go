@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// Rate limiting: with CSA_RATE_LIMIT_QPS set, each caller has a token
+// bucket holding up to CSA_RATE_LIMIT_BURST requests and refilling at that
+// rate, shared by the routes that do the expensive work (embedding, LLM
+// calls, outfit planning). The caller is the API key when one was
+// presented, else the client's IP: the peer's address, or behind a proxy in
+// CSA_TRUSTED_PROXIES the last X-Forwarded-For entry not added by one. A
+// request finding the bucket empty gets
+// 429 with Retry-After, the seconds until it would be let through. Buckets
+// are per replica.
+
+// limiterIdle is how long an unused bucket is kept; by then it is full
+// again, so dropping it changes nothing.
+const limiterIdle = 10 * time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+var limiter = &rateLimiter{buckets: map[string]*bucket{}}
+
+// take spends one of caller's tokens. When none is left it returns false
+// and how long until one is.
+func (l *rateLimiter) take(caller string, qps float64, burst int, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.swept) > limiterIdle {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > limiterIdle {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[caller]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		l.buckets[caller] = b
+	}
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*qps, float64(burst))
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / qps * float64(time.Second))
+}
+
+// rateCaller identifies who a request counts against.
+func rateCaller(r *http.Request) string {
+	if p := principalFrom(r.Context()); p != nil {
+		return "key:" + p.KeyID
+	}
+	return "ip:" + clientIP(r)
+}
+
+// clientIP is the address of the client that sent r. Proxies append the
+// address they got a request from to X-Forwarded-For, so reading it from the
+// right, the first entry not from a trusted proxy is the client; entries
+// left of it are whatever the client sent and can't be believed.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !trustedProxy(host) {
+		return host
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			break // garbled: stop at the last address we could read
+		}
+		host = hop
+		if !trustedProxy(hop) {
+			break
+		}
+	}
+	return host
+}
+
+func trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range cfg().TrustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimited answers 429 to callers over CSA_RATE_LIMIT_QPS before h runs.
+func rateLimited(h http.Handler) http.Handler {
+	return limitedBy(limiter, func() (float64, int) { return cfg().RateLimitQPS, cfg().RateLimitBurst }, h)
+}
+
+// feedbackLimiter keeps POST /feedback's buckets apart from the search
+// routes', so browsing doesn't use up a shopper's feedback and a script
+// posting feedback is stopped even when search isn't limited.
+var feedbackLimiter = &rateLimiter{buckets: map[string]*bucket{}}
+
+// feedbackRateLimited answers 429 to callers over
+// CSA_FEEDBACK_RATE_LIMIT_QPS before h runs.
+func feedbackRateLimited(h http.Handler) http.Handler {
+	return limitedBy(feedbackLimiter, func() (float64, int) { return cfg().FeedbackRateLimitQPS, cfg().FeedbackRateLimitBurst }, h)
+}
+
+// limitedBy paces callers with l at the rate and burst limits reads from
+// the live config.
+func limitedBy(l *rateLimiter, limits func() (qps float64, burst int), h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		qps, burst := limits()
+		if qps <= 0 {
+			h.ServeHTTP(w, r)
+			return
+		}
+		caller := rateCaller(r)
+		ok, wait := l.take(caller, qps, burst, time.Now())
+		if !ok {
+			secs := max(int(math.Ceil(wait.Seconds())), 1)
+			logOutcome(r.Context(), slog.Bool("rate_limited", true))
+			slog.DebugContext(r.Context(), "ratelimit: rejected", "caller", caller, "retry_after", secs)
+			w.Header().Set("Retry-After", strconv.Itoa(secs))
+			httpapi.WriteError(w, fmt.Sprintf("rate limit of %g requests per second exceeded; retry in %ds", qps, secs), http.StatusTooManyRequests)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRefills(t *testing.T) {
+	l := &rateLimiter{buckets: map[string]*bucket{}}
+	now := time.Now()
+	for i := range 3 {
+		if ok, _ := l.take("k", 2, 3, now); !ok {
+			t.Fatalf("request %d within the burst refused", i+1)
+		}
+	}
+	ok, wait := l.take("k", 2, 3, now)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("over the burst: ok = %v, wait = %s", ok, wait)
+	}
+	if ok, _ := l.take("k", 2, 3, now.Add(500*time.Millisecond)); !ok {
+		t.Error("refused after refilling a token")
+	}
+	if ok, _ := l.take("other", 2, 3, now); !ok {
+		t.Error("another caller shares the bucket")
+	}
+
+	// idle buckets are dropped once full again
+	l.take("k", 2, 3, now.Add(limiterIdle+time.Minute))
+	if len(l.buckets) != 1 {
+		t.Errorf("buckets after the idle sweep = %d", len(l.buckets))
+	}
+}
+
+func TestRateLimitedAnswers429(t *testing.T) {
+	prev := limiter
+	limiter = &rateLimiter{buckets: map[string]*bucket{}}
+	t.Cleanup(func() { limiter = prev })
+	useTestConfig(t, map[string]string{"CSA_RATE_LIMIT_QPS": "0.5", "CSA_RATE_LIMIT_BURST": "2"})
+	h := rateLimited(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	call := func(addr string, p *principal) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/search", nil)
+		r.RemoteAddr = addr
+		if p != nil {
+			r = r.WithContext(context.WithValue(r.Context(), ctxPrincipal, p))
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	for range 2 {
+		if w := call("10.0.0.1:5000", nil); w.Code != http.StatusOK {
+			t.Fatalf("within the burst: %d", w.Code)
+		}
+	}
+	w := call("10.0.0.1:5001", nil) // same client, another port
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("over the limit: %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := call("10.0.0.2:5000", nil); w.Code != http.StatusOK {
+		t.Errorf("another IP: %d", w.Code)
+	}
+	// a key is limited on its own, wherever it calls from
+	key := &principal{KeyID: "k1", TenantID: "t", Scopes: []string{scopeRead}}
+	if w := call("10.0.0.1:5002", key); w.Code != http.StatusOK {
+		t.Errorf("keyed caller on a limited IP: %d", w.Code)
+	}
+}
+
+func TestFeedbackRateLimitIsSeparate(t *testing.T) {
+	prevSearch, prevFeedback := limiter, feedbackLimiter
+	limiter, feedbackLimiter = &rateLimiter{buckets: map[string]*bucket{}}, &rateLimiter{buckets: map[string]*bucket{}}
+	t.Cleanup(func() { limiter, feedbackLimiter = prevSearch, prevFeedback })
+	// search isn't limited; feedback is by default
+	useTestConfig(t, map[string]string{"CSA_FEEDBACK_RATE_LIMIT_BURST": "2"})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	search, feedback := rateLimited(ok), feedbackRateLimited(ok)
+
+	call := func(h http.Handler) int {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.RemoteAddr = "10.0.0.1:5000"
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+	for range 5 {
+		if code := call(search); code != http.StatusOK {
+			t.Fatalf("search = %d", code)
+		}
+	}
+	for range 2 {
+		if code := call(feedback); code != http.StatusOK {
+			t.Fatalf("feedback within the burst = %d", code)
+		}
+	}
+	if code := call(feedback); code != http.StatusTooManyRequests {
+		t.Errorf("feedback over the burst = %d, want 429", code)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	useTestConfig(t, map[string]string{"CSA_TRUSTED_PROXIES": "10.0.0.0/8, 192.0.2.7"})
+	cases := []struct {
+		name, peer string
+		xff        []string
+		want       string
+	}{
+		{"direct", "203.0.113.5:4000", nil, "203.0.113.5"},
+		{"untrusted peer's header is ignored", "203.0.113.5:4000", []string{"198.51.100.1"}, "203.0.113.5"},
+		{"behind a proxy", "10.1.2.3:4000", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"client-supplied entries are skipped", "10.1.2.3:4000", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"proxy chain", "10.1.2.3:4000", []string{"198.51.100.1, 192.0.2.7", "10.9.9.9"}, "198.51.100.1"},
+		{"only proxies", "10.1.2.3:4000", []string{"10.2.2.2"}, "10.2.2.2"},
+		{"no header", "10.1.2.3:4000", nil, "10.1.2.3"},
+		{"garbled entry", "10.1.2.3:4000", []string{"nonsense"}, "10.1.2.3"},
+		{"ipv6", "[2001:db8::1]:4000", []string{"198.51.100.1"}, "2001:db8::1"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/search", nil)
+		r.RemoteAddr = c.peer
+		for _, v := range c.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		if got := clientIP(r); got != c.want {
+			t.Errorf("%s: clientIP = %q, want %q", c.name, got, c.want)
+		}
+	}
+
+	// with none configured the header is never believed
+	useTestConfig(t, map[string]string{"CSA_TRUSTED_PROXIES": ""})
+	r := httptest.NewRequest("GET", "/search", nil)
+	r.RemoteAddr = "10.1.2.3:4000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if got := clientIP(r); got != "10.1.2.3" {
+		t.Errorf("no trusted proxies: clientIP = %q", got)
+	}
+}
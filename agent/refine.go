@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Every /search response carries a response_id naming its hits. A later
+// search with "within": that ID searches only those products, so a shopper
+// can narrow what they were shown ("of these, only blue ones under £40")
+// with the usual filters. The refined response has its own response_id, so
+// refinements chain. Result sets are kept for CSA_REFINE_TTL.
+
+// maxResponseID bounds SearchReq.Within.
+const maxResponseID = 64
+
+// searchResultSweepInterval is how often expired result sets are deleted.
+const searchResultSweepInterval = time.Hour
+
+// searchResult is a stored /search result set.
+type searchResult struct {
+	query      string   // the text the hits were ranked by
+	productIDs []string // best first
+}
+
+func newResponseID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "sr_" + hex.EncodeToString(b)
+}
+
+// saveSearchResult stores hits for refinement and returns their response
+// ID. A failure is logged and returns "": the search itself still answers,
+// just without a response_id.
+func saveSearchResult(ctx context.Context, pool *pgxpool.Pool, tenantID, query string, hits []Hit) string {
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ProductID
+	}
+	id := newResponseID()
+	if _, err := pool.Exec(ctx, `
+INSERT INTO search_results (id, tenant_id, sandbox, query, product_ids) VALUES ($1, $2, $3, $4, $5)
+`, id, tenantID, sandboxFrom(ctx), query, ids); err != nil {
+		slog.WarnContext(ctx, "refine: saving result set failed", "err", err)
+		return ""
+	}
+	return id
+}
+
+// loadSearchResult returns the tenant's result set id; ok is false when it
+// is unknown, expired, or from the other catalog (sandbox or real).
+func loadSearchResult(ctx context.Context, pool *pgxpool.Pool, tenantID, id string) (searchResult, bool, error) {
+	var res searchResult
+	err := pool.QueryRow(ctx, `
+SELECT query, product_ids FROM search_results
+WHERE id = $1 AND tenant_id = $2 AND sandbox = $3
+  AND created_at >= now() - make_interval(secs => $4)
+`, id, tenantID, sandboxFrom(ctx), cfg().RefineTTL.Seconds()).Scan(&res.query, &res.productIDs)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return res, false, nil
+	}
+	return res, err == nil, err
+}
+
+// searchResultSweepLoop deletes expired result sets every hour until ctx is
+// cancelled.
+func searchResultSweepLoop(ctx context.Context, pool *pgxpool.Pool) {
+	t := time.NewTicker(searchResultSweepInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		slot := time.Now().Truncate(searchResultSweepInterval)
+		if _, err := runScheduled(ctx, pool, "search_results_sweep", slot, func(ctx context.Context) error {
+			_, err := pool.Exec(ctx, `DELETE FROM search_results WHERE created_at < now() - make_interval(secs => $1)`,
+				cfg().RefineTTL.Seconds())
+			return err
+		}); err != nil {
+			slog.ErrorContext(ctx, "refine: sweep", "err", err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/joho/godotenv"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+)
+
+// logLevel is the handler level, so CSA_LOG_LEVEL can change on reload.
+var logLevel slog.LevelVar
+
+// processEnv names the variables set in the environment before .env was
+// loaded. Those keep their value on reload, as they did at startup; the
+// rest are read from .env again.
+var processEnv = map[string]bool{}
+
+// rememberProcessEnv records processEnv; call it before godotenv.Load.
+func rememberProcessEnv() {
+	for _, kv := range os.Environ() {
+		k, _, _ := strings.Cut(kv, "=")
+		processEnv[k] = true
+	}
+}
+
+// ReloadResult is POST /admin/reload and what SIGHUP logs.
+type ReloadResult struct {
+	Changed         []string `json:"changed"`          // reloadable settings now in effect
+	RestartRequired []string `json:"restart_required"` // changed, but only read at startup
+}
+
+var reloadMu sync.Mutex
+
+// reloadConfig re-reads .env and swaps in the reloadable settings: ranking
+// weights and boosts, CORS origins, feature flags, LLM providers and
+// profiles, log level. An invalid value rejects the whole reload and the
+// running settings stay as they were.
+func reloadConfig() (ReloadResult, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	file, err := godotenv.Read()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return ReloadResult{}, err
+	}
+	lookup := func(k string) (string, bool) {
+		if processEnv[k] {
+			return os.LookupEnv(k)
+		}
+		v, ok := file[k]
+		return v, ok
+	}
+	next, changed, restart, err := config.Reload(cfg(), lookup)
+	if err != nil {
+		return ReloadResult{}, err
+	}
+	liveConfig.Store(next)
+	logLevel.Set(next.LogLevel)
+	if len(changed) > 0 {
+		outfits.reset() // cached outfits were ranked under the old settings
+	}
+
+	res := ReloadResult{Changed: changed, RestartRequired: restart}
+	if res.Changed == nil {
+		res.Changed = []string{}
+	}
+	if res.RestartRequired == nil {
+		res.RestartRequired = []string{}
+	}
+	slog.Info("config: reloaded", "changed", res.Changed, "restart_required", res.RestartRequired)
+	return res, nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// Ranking reads some values that are computed ahead of time: estimated eco
+// scores, and the popularity, feedback and category views. A rescore
+// recomputes them after ranking weights or eco scoring change, then drops
+// the cached outfits and searches ranked with the old ones.
+
+// Rescore steps, in order.
+const (
+	rescoreStepEco    = "eco"    // re-estimating LLM eco scores
+	rescoreStepViews  = "views"  // refreshing the materialized views
+	rescoreStepCaches = "caches" // dropping cached outfits
+	rescoreStepDone   = "done"
+)
+
+// rescoreStale is how long a run may go without progress before it counts
+// as abandoned (its replica died) and another may start.
+const rescoreStale = 5 * time.Minute
+
+// rescoreProgressEvery is how many eco estimates go between progress updates.
+const rescoreProgressEvery = 20
+
+// rescoreWatchInterval is how often replicas check for finished runs, so
+// their cached outfits are dropped within a minute of one.
+const rescoreWatchInterval = time.Minute
+
+var errRescoreRunning = errors.New("a rescore is already running for this tenant")
+
+type RescoreReq struct {
+	// also re-estimate eco scores the LLM estimator set; one chat call per product
+	ReestimateEco bool `json:"reestimate_eco,omitempty"`
+}
+
+func (r RescoreReq) validate() error {
+	if r.ReestimateEco && cfg().EcoEstimator != config.EcoEstimatorLLM {
+		return httpapi.InvalidField("reestimate_eco", "reestimate_eco needs CSA_ECO_ESTIMATOR=llm")
+	}
+	return nil
+}
+
+// RescoreRun is a rescore's progress, for POST and GET /admin/rescore.
+type RescoreRun struct {
+	ID            int64      `json:"id"`
+	TenantID      string     `json:"tenant_id"`
+	ReestimateEco bool       `json:"reestimate_eco"`
+	Step          string     `json:"step"`   // eco | views | caches | done
+	Done          int        `json:"done"`   // eco scores re-estimated
+	Total         int        `json:"total"`  // eco scores to re-estimate
+	Failed        int        `json:"failed"` // estimates that failed; the old score stays
+	StartedAt     time.Time  `json:"started_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	Error         string     `json:"error,omitempty"`
+}
+
+const rescoreRunCols = `id, tenant_id, reestimate_eco, step, done, total, failed, started_at, updated_at, finished_at, COALESCE(error, '')`
+
+func scanRescoreRun(row pgx.Row) (RescoreRun, error) {
+	var r RescoreRun
+	err := row.Scan(&r.ID, &r.TenantID, &r.ReestimateEco, &r.Step, &r.Done, &r.Total, &r.Failed,
+		&r.StartedAt, &r.UpdatedAt, &r.FinishedAt, &r.Error)
+	return r, err
+}
+
+// startRescore records a run for tenantID and starts it in the background.
+// It fails with errRescoreRunning while another run for the tenant is
+// making progress.
+func startRescore(ctx context.Context, pool *pgxpool.Pool, tenantID string, req RescoreReq) (RescoreRun, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return RescoreRun{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	// serialise starts per tenant, across replicas
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, hashtext('rescore:' || $2))`, jobLockClass, tenantID); err != nil {
+		return RescoreRun{}, err
+	}
+	var running bool
+	if err := tx.QueryRow(ctx, `
+SELECT EXISTS (SELECT 1 FROM rescore_runs
+               WHERE tenant_id = $1 AND finished_at IS NULL AND updated_at > now() - make_interval(secs => $2))
+`, tenantID, rescoreStale.Seconds()).Scan(&running); err != nil {
+		return RescoreRun{}, err
+	}
+	if running {
+		return RescoreRun{}, errRescoreRunning
+	}
+	step := rescoreStepViews
+	if req.ReestimateEco {
+		step = rescoreStepEco
+	}
+	run, err := scanRescoreRun(tx.QueryRow(ctx, `
+INSERT INTO rescore_runs (tenant_id, reestimate_eco, step) VALUES ($1, $2, $3)
+RETURNING `+rescoreRunCols, tenantID, req.ReestimateEco, step))
+	if err != nil {
+		return RescoreRun{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return RescoreRun{}, err
+	}
+
+	// the run outlives the request, but keeps its tenant and log attributes
+	go runRescore(context.WithoutCancel(ctx), pool, run)
+	return run, nil
+}
+
+// loadRescoreRun returns the tenant's run id; ok is false if there is none.
+func loadRescoreRun(ctx context.Context, pool *pgxpool.Pool, tenantID string, id int64) (RescoreRun, bool, error) {
+	run, err := scanRescoreRun(pool.QueryRow(ctx, `SELECT `+rescoreRunCols+` FROM rescore_runs WHERE id = $1 AND tenant_id = $2`, id, tenantID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return RescoreRun{}, false, nil
+	}
+	return run, err == nil, err
+}
+
+func runRescore(ctx context.Context, pool *pgxpool.Pool, run RescoreRun) {
+	start := time.Now()
+	slog.InfoContext(ctx, "rescore: started", "id", run.ID, "tenant", run.TenantID, "reestimate_eco", run.ReestimateEco)
+	err := rescore(ctx, pool, &run)
+	var msg *string
+	if err != nil {
+		s := err.Error()
+		msg = &s
+		slog.ErrorContext(ctx, "rescore: failed", "id", run.ID, "step", run.Step, "err", err)
+	} else {
+		run.Step = rescoreStepDone
+		slog.InfoContext(ctx, "rescore: finished", "id", run.ID, "reestimated", run.Done, "failed", run.Failed,
+			"took", time.Since(start).Round(time.Millisecond))
+	}
+	if _, err := pool.Exec(ctx, `
+UPDATE rescore_runs SET step = $2, done = $3, failed = $4, error = $5, updated_at = now(), finished_at = now()
+WHERE id = $1
+`, run.ID, run.Step, run.Done, run.Failed, msg); err != nil {
+		slog.WarnContext(ctx, "rescore: recording run failed", "id", run.ID, "err", err)
+	}
+}
+
+func rescore(ctx context.Context, pool *pgxpool.Pool, run *RescoreRun) error {
+	if run.ReestimateEco {
+		if err := reestimateEco(ctx, pool, run); err != nil {
+			return fmt.Errorf("eco: %w", err)
+		}
+	}
+	if err := rescoreProgress(ctx, pool, run, rescoreStepViews); err != nil {
+		return err
+	}
+	// the views cover every tenant; refreshing them for one is harmless
+	if err := refreshViews(ctx, pool); err != nil {
+		return fmt.Errorf("views: %w", err)
+	}
+	if err := rescoreProgress(ctx, pool, run, rescoreStepCaches); err != nil {
+		return err
+	}
+	resetRankingCaches()
+	invalidateSearchCache(ctx)
+	return nil
+}
+
+func rescoreProgress(ctx context.Context, pool *pgxpool.Pool, run *RescoreRun, step string) error {
+	run.Step = step
+	_, err := pool.Exec(ctx, `
+UPDATE rescore_runs SET step = $2, done = $3, total = $4, failed = $5, updated_at = now() WHERE id = $1
+`, run.ID, run.Step, run.Done, run.Total, run.Failed)
+	return err
+}
+
+// reestimateEco re-runs the eco estimator on the tenant's products whose
+// score it set. Descriptions aren't stored, so the estimate goes on title,
+// material, eco labels and origin. A failed estimate keeps the old score.
+func reestimateEco(ctx context.Context, pool *pgxpool.Pool, run *RescoreRun) error {
+	rows, err := pool.Query(ctx, `
+SELECT product_id, COALESCE(title, ''), COALESCE(material, ''), COALESCE(eco_labels, '{}'), COALESCE(origin_country, '')
+FROM product_embeddings
+WHERE eco_score_source = 'estimated' AND COALESCE(tenant_id, $2) = $1
+ORDER BY product_id
+`, run.TenantID, defaultTenant)
+	if err != nil {
+		return err
+	}
+	type estimated struct {
+		p      catalog.Product
+		attrs  productAttrs
+		origin string
+	}
+	var products []estimated
+	for rows.Next() {
+		var e estimated
+		if err := rows.Scan(&e.p.ID, &e.p.Title, &e.attrs.Material, &e.attrs.EcoLabels, &e.origin); err != nil {
+			rows.Close()
+			return err
+		}
+		products = append(products, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	run.Total = len(products)
+	if err := rescoreProgress(ctx, pool, run, rescoreStepEco); err != nil {
+		return err
+	}
+	for i, e := range products {
+		score, err := estimateEcoScore(ctx, e.p, e.attrs, e.origin)
+		if err != nil {
+			slog.WarnContext(ctx, "rescore: eco estimate failed", "product_id", e.p.ID, "err", err)
+			run.Failed++
+		} else if _, err := pool.Exec(ctx, `
+UPDATE product_embeddings SET eco_score = $2 WHERE product_id = $1 AND eco_score_source = 'estimated'
+`, e.p.ID, score); err != nil { // a score the merchant set meanwhile stays
+			return err
+		}
+		run.Done++
+		if (i+1)%rescoreProgressEvery == 0 {
+			if err := rescoreProgress(ctx, pool, run, rescoreStepEco); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resetRankingCaches drops this replica's cached outfits and category
+// centroids.
+func resetRankingCaches() {
+	outfits.reset()
+	centroids.Lock()
+	clear(centroids.sets)
+	centroids.Unlock()
+}
+
+// rescoreWatchLoop drops this replica's ranking caches when a rescore
+// finishes on another, until ctx is cancelled.
+func rescoreWatchLoop(ctx context.Context, pool *pgxpool.Pool) {
+	seen := time.Now()
+	t := time.NewTicker(rescoreWatchInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		var last *time.Time
+		if err := pool.QueryRow(ctx, `SELECT max(finished_at) FROM rescore_runs WHERE error IS NULL`).Scan(&last); err != nil {
+			slog.WarnContext(ctx, "rescore: checking for finished runs failed", "err", err)
+			continue
+		}
+		if last != nil && last.After(seen) {
+			resetRankingCaches()
+			seen = *last
+			slog.InfoContext(ctx, "rescore: dropped cached outfits after a rescore", "finished_at", *last)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+)
+
+// Data residency: every tenant belongs to a zone (config.Zones), set in its
+// settings or defaulting to CSA_DEFAULT_RESIDENCY_ZONE. Prompts and texts to
+// embed only go to endpoints in the tenant's zone, and the rows written for
+// it record the zone in residency_zone.
+
+var errNoZoneEndpoint = errors.New("no endpoint configured for residency zone")
+
+// zoneCacheTTL bounds how long other replicas keep routing a tenant to its
+// old zone after the setting changes.
+const zoneCacheTTL = time.Minute
+
+type cachedZone struct {
+	zone    string // as stored; "" = the deployment default
+	expires time.Time
+}
+
+var zoneCache sync.Map // tenant ID -> cachedZone
+
+// tenantZone returns the residency zone of tenantID.
+func tenantZone(ctx context.Context, pool *pgxpool.Pool, tenantID string) (string, error) {
+	if c, ok := zoneCache.Load(tenantID); ok && time.Now().Before(c.(cachedZone).expires) {
+		return effectiveZone(c.(cachedZone).zone), nil
+	}
+	var zone string
+	err := pool.QueryRow(ctx, `SELECT residency_zone FROM tenant_settings WHERE tenant_id=$1`, tenantID).Scan(&zone)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return "", err
+	}
+	zoneCache.Store(tenantID, cachedZone{zone: zone, expires: time.Now().Add(zoneCacheTTL)})
+	return effectiveZone(zone), nil
+}
+
+// forgetTenantZone drops the cached zone after the tenant's settings change.
+func forgetTenantZone(tenantID string) {
+	zoneCache.Delete(tenantID)
+}
+
+func effectiveZone(zone string) string {
+	if zone == "" {
+		return cfg().ResidencyZone
+	}
+	return zone
+}
+
+// withResidency records tenantID's zone in ctx for the provider layer.
+func withResidency(ctx context.Context, pool *pgxpool.Pool, tenantID string) (context.Context, error) {
+	zone, err := tenantZone(ctx, pool, tenantID)
+	if err != nil {
+		return ctx, fmt.Errorf("residency zone: %w", err)
+	}
+	return context.WithValue(ctx, ctxZone, zone), nil
+}
+
+// residencyFrom is the zone the request's data must stay in.
+func residencyFrom(ctx context.Context) string {
+	if z, ok := ctx.Value(ctxZone).(string); ok && z != "" {
+		return z
+	}
+	return cfg().ResidencyZone
+}
+
+// chatProviderZone is where a chat provider processes prompts; "" for
+// providers that send nothing off the host.
+func chatProviderZone(provider string) string {
+	switch provider {
+	case config.ProviderOpenAI:
+		return cfg().OpenAI.Zone
+	case config.ProviderAzure:
+		return cfg().Azure.Zone
+	case config.ProviderAnthropic, config.ProviderGemini:
+		return config.ZoneUS
+	}
+	return ""
+}
+
+// embedBackendZone is where an embedding backend sends texts; "" for the
+// local model.
+func embedBackendZone(backend string) string {
+	switch backend {
+	case config.EmbedOpenAI:
+		return cfg().OpenAI.Zone
+	case config.EmbedAzureOpenAI:
+		return cfg().Azure.Zone
+	}
+	return ""
+}
+
+// residentChatProvider returns provider when it serves zone, else the
+// first configured provider that does, so a tenant's prompts never leave
+// its zone even when the deployment default is elsewhere.
+func residentChatProvider(zone, provider string) (string, error) {
+	if z := chatProviderZone(provider); z == "" || z == zone {
+		return provider, nil
+	}
+	for _, name := range config.LLMProviders {
+		if p, ok := chatProviders[name]; ok && chatProviderZone(name) == zone && p.configured() {
+			slog.Debug("residency: chat routed to zone provider", "zone", zone, "requested", provider, "provider", name)
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%w %s: chat provider %q processes data in %s", errNoZoneEndpoint, zone, provider, chatProviderZone(provider))
+}
+
+// residentEmbedBackend is the same for embeddings: CSA_EMBED_BACKEND when
+// it serves zone, else the OpenAI or Azure OpenAI endpoint that does.
+func residentEmbedBackend(zone string) (string, error) {
+	backend := cfg().Embed.Backend
+	if z := embedBackendZone(backend); z == "" || z == zone {
+		return backend, nil
+	}
+	switch {
+	case cfg().OpenAI.Zone == zone:
+		return config.EmbedOpenAI, nil
+	case cfg().Azure.Configured() && cfg().Azure.Zone == zone:
+		return config.EmbedAzureOpenAI, nil
+	}
+	return "", fmt.Errorf("%w %s: no embedding endpoint (set AZURE_OPENAI_ENDPOINT in that zone or use the local backend)", errNoZoneEndpoint, zone)
+}
+
+// ZoneRows counts a tenant's stored rows of one table written under one
+// zone. Zone is "" for rows from before residency tagging.
+type ZoneRows struct {
+	Table string `json:"table"`
+	Zone  string `json:"zone"`
+	Rows  int64  `json:"rows"`
+}
+
+// ResidencyReport shows where a tenant's stored data was written.
+type ResidencyReport struct {
+	TenantID string     `json:"tenant_id"`
+	Zone     string     `json:"zone"`
+	Stored   []ZoneRows `json:"stored"`
+	// OutOfZone counts rows tagged with another zone, e.g. written before
+	// the tenant moved; untagged rows are not included.
+	OutOfZone int64 `json:"out_of_zone"`
+}
+
+// residencyTables are the tables whose rows carry residency_zone.
+var residencyTables = []string{"product_embeddings", "sessions", "feedback", "recommendation_audit"}
+
+func residencyReport(ctx context.Context, pool *pgxpool.Pool, tenantID string) (ResidencyReport, error) {
+	rep := ResidencyReport{TenantID: tenantID, Stored: []ZoneRows{}}
+	zone, err := tenantZone(ctx, pool, tenantID)
+	if err != nil {
+		return rep, err
+	}
+	rep.Zone = zone
+	for _, table := range residencyTables {
+		rows, err := pool.Query(ctx, fmt.Sprintf(`
+SELECT COALESCE(residency_zone, ''), count(*) FROM %s WHERE tenant_id=$1 GROUP BY 1 ORDER BY 1
+`, table), tenantID)
+		if err != nil {
+			return rep, err
+		}
+		for rows.Next() {
+			zr := ZoneRows{Table: table}
+			if err := rows.Scan(&zr.Zone, &zr.Rows); err != nil {
+				rows.Close()
+				return rep, err
+			}
+			if zr.Zone != "" && zr.Zone != zone {
+				rep.OutOfZone += zr.Rows
+			}
+			rep.Stored = append(rep.Stored, zr)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return rep, err
+		}
+	}
+	return rep, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// fakeAzure records the paths called on an Azure OpenAI resource and answers
+// chat and embedding requests.
+func fakeAzure(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		if r.Header.Get("api-key") != "az-key" || r.URL.Query().Get("api-version") == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/openai/deployments/gpt-4o-mini/chat/completions":
+			writeFakeJSON(w, map[string]any{"choices": []map[string]any{{"message": map[string]string{"content": "from the EU"}}}})
+		case "/openai/deployments/text-embedding-3-small/embeddings":
+			writeFakeJSON(w, map[string]any{"data": []map[string]any{{"index": 0, "embedding": []float64{1, 0}}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), paths...)
+	}
+}
+
+func inZone(zone string) context.Context {
+	return context.WithValue(context.Background(), ctxZone, zone)
+}
+
+func TestResidencyRoutesToZoneEndpoint(t *testing.T) {
+	az, calls := fakeAzure(t)
+	c := useTestConfig(t, map[string]string{"AZURE_OPENAI_ENDPOINT": az.URL, "AZURE_OPENAI_API_KEY": "az-key"})
+	c.Embed.Backend = config.EmbedOpenAI
+
+	// the US default provider and backend are swapped for the EU resource
+	text, err := llmChat(inZone(config.ZoneEU), config.ProviderOpenAI, config.LLMExplain, "hi", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "from the EU" {
+		t.Errorf("chat = %q", text)
+	}
+	p, err := embedder(inZone(config.ZoneEU))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Embed(context.Background(), []string{"linen"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := calls(); len(got) != 2 {
+		t.Errorf("azure calls = %v, want chat and embeddings", got)
+	}
+
+	// US tenants keep the configured ones
+	if name, _ := residentChatProvider(config.ZoneUS, config.ProviderOpenAI); name != config.ProviderOpenAI {
+		t.Errorf("US chat provider = %q", name)
+	}
+	if name, _ := residentEmbedBackend(config.ZoneUS); name != config.EmbedOpenAI {
+		t.Errorf("US embed backend = %q", name)
+	}
+}
+
+func TestResidencyFailsClosed(t *testing.T) {
+	c := useTestConfig(t, nil)
+	c.Embed.Backend = config.EmbedOpenAI
+
+	if _, err := llmChat(inZone(config.ZoneEU), config.ProviderOpenAI, config.LLMExplain, "hi", nil); !errors.Is(err, errNoZoneEndpoint) {
+		t.Errorf("chat without an EU provider = %v, want errNoZoneEndpoint", err)
+	}
+	if _, err := embedder(inZone(config.ZoneEU)); !errors.Is(err, errNoZoneEndpoint) {
+		t.Errorf("embedder without an EU endpoint = %v, want errNoZoneEndpoint", err)
+	}
+
+	// the local model keeps texts on the host, so it serves every zone
+	c.Embed.Backend = config.EmbedLocal
+	if _, err := embedder(inZone(config.ZoneEU)); err != nil {
+		t.Errorf("local embedder in the EU: %v", err)
+	}
+}
+
+func TestTenantSettingsResidency(t *testing.T) {
+	az, _ := fakeAzure(t)
+	useTestConfig(t, map[string]string{
+		"AZURE_OPENAI_ENDPOINT": az.URL, "AZURE_OPENAI_API_KEY": "az-key", "ANTHROPIC_API_KEY": "sk-ant",
+	})
+
+	for _, tc := range []struct {
+		zone, provider string
+		field          string // "" = valid
+	}{
+		{zone: config.ZoneEU},
+		{zone: config.ZoneEU, provider: config.ProviderAzure},
+		{zone: config.ZoneEU, provider: config.ProviderAnthropic, field: "llm_provider"},
+		{zone: "", provider: config.ProviderAnthropic},
+		{zone: "apac", field: "residency_zone"},
+	} {
+		ts := defaultTenantSettings("t1")
+		ts.ResidencyZone, ts.LLMProvider = tc.zone, tc.provider
+		err := ts.validate()
+		var fe httpapi.FieldErrors
+		switch {
+		case tc.field == "" && err != nil:
+			t.Errorf("%s/%s: %v", tc.zone, tc.provider, err)
+		case tc.field != "" && (!errors.As(err, &fe) || fe[0].Field != tc.field):
+			t.Errorf("%s/%s: got %v, want an error on %s", tc.zone, tc.provider, err, tc.field)
+		}
+	}
+}
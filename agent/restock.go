@@ -0,0 +1,315 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// outfitCache keeps recent /complete-outfit responses per tenant and request.
+// It also indexes which products each response recommends, so inventory sync
+// can drop every cached response that still shows a sold-out item. The cache
+// is per process; other replicas age their entries out via the TTL.
+//
+// Entries are fresh for CSA_OUTFIT_CACHE_TTL, then stale for
+// CSA_OUTFIT_CACHE_STALE: stale entries are still served, while one
+// background refresh per key replaces them. Past that an entry is dropped
+// by the next lookup or sweep, and beyond outfitCacheSize entries the
+// oldest go first.
+type outfitCache struct {
+	mu         sync.Mutex
+	entries    map[string]cachedOutfit
+	order      *list.List                     // keys, oldest stored at the front
+	byProduct  map[string]map[string]struct{} // product_id -> cache keys
+	refreshing map[string]bool
+}
+
+type cachedOutfit struct {
+	resp    CompleteOutfitResp
+	stored  time.Time
+	expires time.Time // fresh until
+	el      *list.Element
+}
+
+// outfitCacheSize bounds the cache. Keys include free-form request fields,
+// so without a bound distinct requests would grow it without limit; a
+// response is tens of KB, so this is tens of MB at most.
+const outfitCacheSize = 1024
+
+// outfitSweepInterval is how often entries past their stale window are
+// dropped.
+const outfitSweepInterval = time.Minute
+
+// OutfitCacheInfo annotates a response served from the cache.
+type OutfitCacheInfo struct {
+	AgeSeconds   int  `json:"age_seconds"`
+	Stale        bool `json:"stale,omitempty"`        // past the TTL
+	Revalidating bool `json:"revalidating,omitempty"` // a fresh copy is being computed
+}
+
+// outfitRevalidateTimeout bounds a background refresh.
+const outfitRevalidateTimeout = time.Minute
+
+var outfits = &outfitCache{
+	entries:    map[string]cachedOutfit{},
+	order:      list.New(),
+	byProduct:  map[string]map[string]struct{}{},
+	refreshing: map[string]bool{},
+}
+
+func outfitCacheKey(ctx context.Context, tenant string, req CompleteOutfitReq) string {
+	key := struct {
+		Req         CompleteOutfitReq
+		Cart        *CartSummary        `json:",omitempty"` // cart contents, so edits miss the cache
+		Lang        string              `json:",omitempty"` // card language, from Accept-Language
+		Constraints []SessionConstraint `json:",omitempty"` // the session's temporary ones
+	}{Req: req, Lang: cardLanguage(ctx), Constraints: req.constraints}
+	if req.cart != nil {
+		key.Cart = &req.cart.Summary
+	}
+	b, _ := json.Marshal(key)
+	sum := sha256.Sum256(append([]byte(tenant+"\x00"), b...))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns the cached response and how old it is. Stale responses come
+// with Cache.Stale set; entries past the stale window are dropped.
+func (c *outfitCache) get(key string) (CompleteOutfitResp, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return CompleteOutfitResp{}, false
+	}
+	now := time.Now()
+	if now.After(e.expires.Add(cfg().OutfitCacheStale)) {
+		c.dropLocked(key)
+		return CompleteOutfitResp{}, false
+	}
+	resp := e.resp
+	resp.Cache = &OutfitCacheInfo{AgeSeconds: int(now.Sub(e.stored).Seconds()), Stale: now.After(e.expires)}
+	return resp, true
+}
+
+// revalidate recomputes a stale entry in the background unless a refresh
+// for key is already running. The response's Cache annotation is updated
+// to say so. ctx should carry the request's values but not its deadline.
+func (c *outfitCache) revalidate(ctx context.Context, key string, resp *CompleteOutfitResp, refresh func(context.Context) (CompleteOutfitResp, error)) {
+	if resp.Cache == nil || !resp.Cache.Stale {
+		return
+	}
+	resp.Cache.Revalidating = true
+	c.mu.Lock()
+	if c.refreshing[key] {
+		c.mu.Unlock()
+		return
+	}
+	c.refreshing[key] = true
+	c.mu.Unlock()
+
+	go func() {
+		defer func() {
+			c.mu.Lock()
+			delete(c.refreshing, key)
+			c.mu.Unlock()
+		}()
+		ctx, cancel := context.WithTimeout(ctx, outfitRevalidateTimeout)
+		defer cancel()
+		fresh, err := refresh(ctx)
+		if err != nil {
+			slog.WarnContext(ctx, "outfit cache: revalidation failed", "err", err)
+			return
+		}
+		c.put(key, fresh)
+	}()
+}
+
+func (c *outfitCache) put(key string, resp CompleteOutfitResp) {
+	if cfg().OutfitCacheTTL <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dropLocked(key)
+	resp.Cache, resp.Meta = nil, nil
+	now := time.Now()
+	c.entries[key] = cachedOutfit{resp: resp, stored: now, expires: now.Add(cfg().OutfitCacheTTL), el: c.order.PushBack(key)}
+	for c.order.Len() > outfitCacheSize {
+		c.dropLocked(c.order.Front().Value.(string))
+	}
+	for _, sr := range resp.Results {
+		for _, h := range sr.Hits {
+			if c.byProduct[h.ProductID] == nil {
+				c.byProduct[h.ProductID] = map[string]struct{}{}
+			}
+			c.byProduct[h.ProductID][key] = struct{}{}
+		}
+	}
+}
+
+// invalidate drops every cached response recommending one of productIDs and
+// returns how many were dropped.
+func (c *outfitCache) invalidate(productIDs []string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, id := range productIDs {
+		for key := range c.byProduct[id] {
+			if _, ok := c.entries[key]; ok {
+				n++
+			}
+			c.dropLocked(key)
+		}
+		delete(c.byProduct, id)
+	}
+	return n
+}
+
+// reset drops every cached response, for changes that could affect any of them.
+func (c *outfitCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+	c.order.Init()
+	clear(c.byProduct)
+}
+
+// sweep drops entries past their stale window and returns how many.
+func (c *outfitCache) sweep(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for key, e := range c.entries {
+		if now.After(e.expires.Add(cfg().OutfitCacheStale)) {
+			c.dropLocked(key)
+			n++
+		}
+	}
+	return n
+}
+
+// outfitCacheSweepLoop sweeps this process's cache until ctx is done.
+func outfitCacheSweepLoop(ctx context.Context) {
+	t := time.NewTicker(outfitSweepInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			if n := outfits.sweep(now); n > 0 {
+				slog.DebugContext(ctx, "outfit cache: swept expired entries", "dropped", n)
+			}
+		}
+	}
+}
+
+func (c *outfitCache) dropLocked(key string) {
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+	c.order.Remove(e.el)
+	for _, sr := range e.resp.Results {
+		for _, h := range sr.Hits {
+			if keys := c.byProduct[h.ProductID]; keys != nil {
+				delete(keys, key)
+				if len(keys) == 0 {
+					delete(c.byProduct, h.ProductID)
+				}
+			}
+		}
+	}
+}
+
+// stockChanges lists products whose stock crossed zero during a sync.
+type stockChanges struct {
+	SoldOut   []string
+	Restocked []string
+}
+
+// onStockChanges invalidates cached responses showing sold-out items and
+// suggests in-stock substitutes for saved outfits that contain them. When an
+// original item is restocked its substitute suggestion is withdrawn.
+// Failures are logged: the stock update itself has already been applied.
+func onStockChanges(ctx context.Context, pool *pgxpool.Pool, ch stockChanges) (invalidated, substituted int) {
+	if len(ch.Restocked) > 0 {
+		if _, err := pool.Exec(ctx, `
+UPDATE saved_outfit_items SET substitute=NULL, substituted_at=NULL
+WHERE product_id = ANY($1) AND substitute IS NOT NULL
+`, ch.Restocked); err != nil {
+			slog.ErrorContext(ctx, "restock: clear substitutes", "err", err)
+		}
+	}
+	if len(ch.SoldOut) > 0 || len(ch.Restocked) > 0 {
+		invalidateSearchCache(ctx)
+	}
+	if len(ch.SoldOut) == 0 {
+		return 0, 0
+	}
+
+	invalidated = outfits.invalidate(ch.SoldOut)
+
+	rows, err := pool.Query(ctx, `
+SELECT outfit_id::text, slot, product_id
+FROM saved_outfit_items
+WHERE product_id = ANY($1)
+`, ch.SoldOut)
+	if err != nil {
+		slog.ErrorContext(ctx, "restock: find saved outfits", "err", err)
+		return invalidated, 0
+	}
+	type affected struct{ outfitID, slot, productID string }
+	var items []affected
+	for rows.Next() {
+		var a affected
+		if err := rows.Scan(&a.outfitID, &a.slot, &a.productID); err != nil {
+			rows.Close()
+			slog.ErrorContext(ctx, "restock: scan saved outfit", "err", err)
+			return invalidated, 0
+		}
+		items = append(items, a)
+	}
+	rows.Close()
+
+	for _, a := range items {
+		sub, err := findSubstitute(ctx, pool, a.productID)
+		if err != nil {
+			slog.ErrorContext(ctx, "restock: find substitute", "product_id", a.productID, "outfit_id", a.outfitID, "err", err)
+			continue
+		}
+		if sub == nil {
+			slog.InfoContext(ctx, "restock: no substitute", "product_id", a.productID, "slot", a.slot, "outfit_id", a.outfitID)
+			continue
+		}
+		if _, err := pool.Exec(ctx, `
+UPDATE saved_outfit_items SET substitute=$3, substituted_at=now()
+WHERE outfit_id::text=$1 AND product_id=$2
+`, a.outfitID, a.productID, sub); err != nil {
+			slog.ErrorContext(ctx, "restock: save substitute", "outfit_id", a.outfitID, "err", err)
+			continue
+		}
+		substituted++
+	}
+	return invalidated, substituted
+}
+
+// findSubstitute returns the closest in-stock item in the same slot that
+// costs no more and is at least as eco-friendly as the one it replaces.
+func findSubstitute(ctx context.Context, pool *pgxpool.Pool, soldOut string) (*Hit, error) {
+	subs, err := findSubstitutes(ctx, pool, soldOut, substituteOpts{Limit: 1, InStockOnly: true})
+	if err != nil || subs == nil || len(subs.Substitutes) == 0 {
+		return nil, err
+	}
+	h := subs.Substitutes[0]
+	h.Reason = "Suggested because " + soldOut + " sold out. " + h.Reason
+	return &h, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOutfitCacheBoundAndSweep(t *testing.T) {
+	useTestConfig(t, map[string]string{"CSA_OUTFIT_CACHE_TTL": "1m", "CSA_OUTFIT_CACHE_STALE": "1m"})
+	c := &outfitCache{entries: map[string]cachedOutfit{}, order: list.New(), byProduct: map[string]map[string]struct{}{}, refreshing: map[string]bool{}}
+	resp := func(id string) CompleteOutfitResp {
+		return CompleteOutfitResp{Results: []SlotRecs{{Slot: "top", Hits: []Hit{{ProductID: id}}}}}
+	}
+	for i := range outfitCacheSize + 10 {
+		c.put(fmt.Sprint("k", i), resp(fmt.Sprint("p", i)))
+	}
+	if len(c.entries) != outfitCacheSize || c.order.Len() != outfitCacheSize {
+		t.Fatalf("entries = %d, order = %d, want %d", len(c.entries), c.order.Len(), outfitCacheSize)
+	}
+	if _, ok := c.get("k0"); ok {
+		t.Error("oldest entry was kept")
+	}
+	if _, ok := c.byProduct["p0"]; ok {
+		t.Error("evicted entry left in the product index")
+	}
+	if _, ok := c.get(fmt.Sprint("k", outfitCacheSize+9)); !ok {
+		t.Error("newest entry was evicted")
+	}
+
+	if n := c.sweep(time.Now().Add(90 * time.Second)); n != 0 {
+		t.Errorf("swept %d stale entries still in their window", n)
+	}
+	if n := c.sweep(time.Now().Add(3 * time.Minute)); n != outfitCacheSize {
+		t.Errorf("swept %d, want %d", n, outfitCacheSize)
+	}
+	if len(c.entries) != 0 || c.order.Len() != 0 || len(c.byProduct) != 0 {
+		t.Errorf("left after the sweep: %d entries, %d products", len(c.entries), len(c.byProduct))
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+)
+
+// With CSA_REDIS_URL set, query embeddings and /search responses are also
+// cached in Redis, so every replica reuses what any of them computed. The
+// in-process embedding LRU still answers first; Redis is the second level.
+//
+// Search entries are keyed by tenant, the catalog generation and a hash of
+// the request. Every tenant searches the same product table, so reindexing,
+// deleting products, stock changes or rescoring by any tenant bumps the one
+// generation, which orphans every entry at once; the orphans expire with
+// their TTL. Redis errors never fail a request: the cache is
+// skipped and the work done as if it were off.
+var redisCache *redis.Client
+
+// redisTimeout bounds one cache call, so a slow Redis costs a search at most
+// this much before it goes to Postgres.
+const redisTimeout = 100 * time.Millisecond
+
+// initRedisCache connects to CSA_REDIS_URL, if set. An unreachable server is
+// only logged; the client keeps reconnecting and the cache is skipped until
+// it answers.
+func initRedisCache(ctx context.Context) error {
+	if cfg().RedisURL == "" {
+		return nil
+	}
+	opts, err := redis.ParseURL(cfg().RedisURL)
+	if err != nil {
+		return err
+	}
+	redisCache = redis.NewClient(opts)
+	pctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	if err := redisCache.Ping(pctx).Err(); err != nil {
+		slog.Warn("redis cache: not reachable, caching in-process only until it is", "err", err)
+	}
+	return nil
+}
+
+// embedCacheKey names a query embedding. The model is part of it because
+// cached vectors outlive deploys that switch models.
+func embedCacheKey(ctx context.Context, text string) string {
+	model := cfg().Embed.Backend + ":"
+	switch {
+	case sandboxFrom(ctx):
+		model = "sandbox:"
+	case cfg().Embed.Backend == config.EmbedOpenAI:
+		model += cfg().OpenAI.EmbedModel
+	default:
+		model += cfg().Embed.ModelDir
+	}
+	sum := sha256.Sum256([]byte(model + "\x00" + residencyFrom(ctx) + "\x00" + text))
+	return "csa:embed:" + hex.EncodeToString(sum[:])
+}
+
+// cachedQueryEmbedding returns the vector stored for text, if any.
+func cachedQueryEmbedding(ctx context.Context, text string) ([]float64, bool) {
+	if redisCache == nil || cfg().QueryEmbedCacheTTL <= 0 {
+		return nil, false
+	}
+	b, err := redisGet(ctx, embedCacheKey(ctx, text))
+	if err != nil || len(b)%4 != 0 {
+		return nil, false
+	}
+	// float32 is the precision the embedding APIs return
+	vec := make([]float64, len(b)/4)
+	for i := range vec {
+		vec[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:])))
+	}
+	return vec, true
+}
+
+func storeQueryEmbedding(ctx context.Context, text string, vec []float64) {
+	if redisCache == nil || cfg().QueryEmbedCacheTTL <= 0 {
+		return
+	}
+	b := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(b[i*4:], math.Float32bits(float32(v)))
+	}
+	redisSet(ctx, embedCacheKey(ctx, text), b, cfg().QueryEmbedCacheTTL)
+}
+
+// searchGenerationKey holds the catalog's search cache generation.
+const searchGenerationKey = "csa:search-gen"
+
+// searchCacheKey names the cached response to req for tenant. ok is false
+// when the search cache is off or Redis is unavailable.
+func searchCacheKey(ctx context.Context, tenant string, req SearchReq, constraints []SessionConstraint) (key string, ok bool) {
+	if redisCache == nil || cfg().SearchCacheTTL <= 0 {
+		return "", false
+	}
+	gen, err := redisGet(ctx, searchGenerationKey)
+	if err != nil && !errors.Is(err, redis.Nil) { // no generation yet is generation ""
+		return "", false
+	}
+	k := struct {
+		Req         SearchReq
+		Sandbox     bool
+		Lang        string
+		Constraints []SessionConstraint `json:",omitempty"`
+	}{req, sandboxFrom(ctx), cardLanguage(ctx), constraints}
+	b, _ := json.Marshal(k)
+	sum := sha256.Sum256(b)
+	return "csa:search:" + tenant + ":" + string(gen) + ":" + hex.EncodeToString(sum[:]), true
+}
+
+// cachedSearch returns the response stored under key, if any.
+func cachedSearch(ctx context.Context, key string) (SearchResp, bool) {
+	var resp SearchResp
+	b, err := redisGet(ctx, key)
+	if err != nil {
+		return resp, false
+	}
+	if err := json.Unmarshal(b, &resp); err != nil {
+		slog.WarnContext(ctx, "redis cache: undecodable search entry", "err", err)
+		return resp, false
+	}
+	return resp, true
+}
+
+// storeSearch caches resp under key. Meta and the response ID are left
+// out: the warnings are about the request body, and a hit gets its own of
+// both.
+func storeSearch(ctx context.Context, key string, resp SearchResp) {
+	resp.Meta, resp.ResponseID = nil, ""
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	redisSet(ctx, key, b, cfg().SearchCacheTTL)
+}
+
+// invalidateSearchCache orphans every cached search, of all tenants, after
+// the catalog changed.
+func invalidateSearchCache(ctx context.Context) {
+	if redisCache == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	if err := redisCache.Incr(ctx, searchGenerationKey).Err(); err != nil {
+		// cached searches now live out their TTL
+		slog.WarnContext(ctx, "redis cache: invalidating searches failed", "err", err)
+	}
+}
+
+// redisGet fetches key; a miss is redis.Nil.
+func redisGet(ctx context.Context, key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	b, err := redisCache.Get(ctx, key).Bytes()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		slog.DebugContext(ctx, "redis cache: get failed", "err", err)
+	}
+	return b, err
+}
+
+func redisSet(ctx context.Context, key string, b []byte, ttl time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	if err := redisCache.Set(ctx, key, b, ttl).Err(); err != nil {
+		slog.DebugContext(ctx, "redis cache: set failed", "err", err)
+	}
+}
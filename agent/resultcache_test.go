@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSearchCacheInvalidatesEveryTenant(t *testing.T) {
+	useTestConfig(t, map[string]string{"CSA_SEARCH_CACHE_TTL": "2m"})
+	useFakeRedis(t)
+	ctx := context.Background()
+	req := SearchReq{Query: "linen shirt", Limit: 5}
+
+	keyA, okA := searchCacheKey(ctx, "a", req, nil)
+	keyB, okB := searchCacheKey(ctx, "b", req, nil)
+	if !okA || !okB || keyA == keyB {
+		t.Fatalf("keys = %q %v, %q %v", keyA, okA, keyB, okB)
+	}
+	storeSearch(ctx, keyB, SearchResp{Hits: []Hit{{ProductID: "p1"}}})
+	if _, ok := cachedSearch(ctx, keyB); !ok {
+		t.Fatal("stored search not found")
+	}
+
+	// tenant a changes the catalog b searches too
+	invalidateSearchCache(context.WithValue(ctx, ctxTenant, "a"))
+	after, _ := searchCacheKey(ctx, "b", req, nil)
+	if after == keyB {
+		t.Fatal("b's search key survived a's catalog change")
+	}
+	if _, ok := cachedSearch(ctx, after); ok {
+		t.Error("b is served the search cached before the change")
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// registerAdminRoutes adds tenant settings, jobs, migrations, reloads and
+// the usage, audit and feedback reports.
+func registerAdminRoutes(mux *http.ServeMux, pool *pgxpool.Pool) {
+	// Per-tenant settings for the calling tenant
+	mux.Handle("GET /admin/tenant-settings", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		ts, err := loadTenantSettings(r.Context(), pool, tenantFromRequest(r))
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ts)
+	}))
+
+	mux.Handle("PUT /admin/tenant-settings", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		tenantID := tenantFromRequest(r)
+		ts := defaultTenantSettings(tenantID)
+		if err := decodeJSON(w, r, &ts); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		ts.TenantID = tenantID
+		if err := ts.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := saveTenantSettings(r.Context(), pool, ts); err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ts)
+	}))
+
+	// Where the tenant's stored data was written, to find rows left in
+	// another zone after it moved
+	mux.Handle("GET /admin/residency", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		rep, err := residencyReport(r.Context(), pool, tenantFromRequest(r))
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rep)
+	}))
+
+	// Last run of each scheduled job, across replicas
+	mux.Handle("GET /admin/jobs", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		runs, err := listJobRuns(r.Context(), pool)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"jobs": runs})
+	}))
+
+	// Recompute precomputed ranking inputs after weights or eco scoring
+	// change; runs in the background, poll GET /admin/rescore/{id}
+	mux.Handle("POST /admin/rescore", requireScope(scopeAdmin, idempotent(pool, func(w http.ResponseWriter, r *http.Request) {
+		var req RescoreReq
+		if err := decodeJSON(w, r, &req); err != nil && !errors.Is(err, io.EOF) {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		run, err := startRescore(r.Context(), pool, tenantFromRequest(r), req)
+		if errors.Is(err, errRescoreRunning) {
+			httpapi.WriteError(w, err.Error(), 409)
+			return
+		}
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", fmt.Sprintf("/admin/rescore/%d", run.ID))
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(run)
+	})))
+
+	mux.Handle("GET /admin/rescore/{id}", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			httpapi.WriteError(w, "invalid id", 400)
+			return
+		}
+		run, ok, err := loadRescoreRun(r.Context(), pool, tenantFromRequest(r), id)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		if !ok {
+			httpapi.WriteError(w, "rescore not found", 404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(run)
+	}))
+
+	// Re-read reloadable settings from .env, like SIGHUP; affects every
+	// tenant, so bootstrap key only
+	mux.Handle("POST /admin/reload", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		if !principalFrom(r.Context()).bootstrap() {
+			httpapi.WriteError(w, "reloading settings requires the bootstrap admin key", 403)
+			return
+		}
+		res, err := reloadConfig()
+		if err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	}))
+
+	// Embedded schema migrations; also applied at startup unless CSA_AUTO_MIGRATE=false
+	mux.Handle("GET /admin/migrations", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		resp, err := migrationStatus(r.Context(), pool)
+		if err != nil {
+			httpapi.WriteError(w, "migration status: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	// The schema is shared by every tenant, so bootstrap key only
+	mux.Handle("POST /admin/migrate", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		if !principalFrom(r.Context()).bootstrap() {
+			httpapi.WriteError(w, "migrating requires the bootstrap admin key", 403)
+			return
+		}
+		resp, err := migrate(r.Context(), pool)
+		if err != nil {
+			httpapi.WriteError(w, "migrate: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	// Embedding and chat tokens used per day, and the month against its cap
+	mux.Handle("GET /admin/usage", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		days := 30
+		if v := r.URL.Query().Get("days"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 366 {
+				httpapi.BadRequest(w, httpapi.InvalidField("days", "days must be between 1 and 366"))
+				return
+			}
+			days = n
+		}
+		rep, err := usageReport(r.Context(), pool, tenantFromRequest(r), days)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rep)
+	}))
+
+	// Recorded recommendation answers, to review why products were shown
+	mux.Handle("GET /admin/audit", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		f, err := parseAuditFilter(r.URL.Query())
+		if err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		page, err := auditLog(r.Context(), pool, tenantFromRequest(r), f)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+	}))
+
+	mux.Handle("GET /admin/feedback", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		days, limit := 30, 50
+		if n, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && n > 0 && n <= 365 {
+			days = n
+		}
+		if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 && n <= 500 {
+			limit = n
+		}
+		rep, err := feedbackReport(r.Context(), pool, tenantFromRequest(r), time.Now().AddDate(0, 0, -days), limit)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rep)
+	}))
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// registerCatalogRoutes adds indexing, import, export and upkeep of the
+// tenant's catalog.
+func registerCatalogRoutes(mux *http.ServeMux, pool *pgxpool.Pool) {
+	// Embed + store product
+	mux.Handle("POST /embed-product", requireScope(scopeWrite, idempotent(pool, func(w http.ResponseWriter, r *http.Request) {
+		var req EmbedReq
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+
+		vec, err := embedProduct(r.Context(), pool, tenantFromRequest(r), req)
+		if err != nil {
+			httpapi.WriteError(w, err.Error(), 500)
+			return
+		}
+		if req.Neighbours > 0 {
+			hits, err := productNeighbours(r.Context(), pool, req.ProductID, vec, req.Neighbours)
+			if err != nil {
+				httpapi.WriteError(w, "query error: "+err.Error(), 500)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(EmbedResp{ProductID: req.ProductID, Neighbours: hits})
+			return
+		}
+
+		w.Write([]byte("ok"))
+	})))
+
+	// Catalog coverage for the caller's tenant: counts and gaps in the index
+	mux.Handle("GET /index-stats", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		st, err := indexStats(r.Context(), pool, tenantFromRequest(r))
+		if err != nil {
+			httpapi.WriteError(w, "query error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(st)
+	}))
+
+	mux.Handle("GET /medusa-products-count", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		if cfg().Medusa.PublishableKey == "" {
+			httpapi.WriteError(w, "MEDUSA_PUBLISHABLE_KEY not set", 500)
+			return
+		}
+
+		res, err := medusaGet(r.Context(), "/store/products?limit=100")
+		if err != nil {
+			httpapi.WriteError(w, err.Error(), 500)
+			return
+		}
+		defer res.Body.Close()
+
+		var payload struct {
+			Products []struct {
+				ID          string         `json:"id"`
+				Title       string         `json:"title"`
+				Thumbnail   string         `json:"thumbnail"`
+				Description string         `json:"description"`
+				Metadata    map[string]any `json:"metadata"`
+				Categories  []struct {
+					Name string `json:"name"`
+				} `json:"categories"`
+			} `json:"products"`
+		}
+
+		if err := json.NewDecoder(res.Body).Decode(&payload); err != nil {
+			httpapi.WriteError(w, err.Error(), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"count": len(payload.Products),
+		})
+	}))
+
+	// Index products from the configured catalog (CSA_CATALOG_PROVIDER);
+	// ?mode=incremental only fetches products updated since the last run
+	mux.Handle("POST /index-products", requireScope(scopeWrite, idempotent(pool, func(w http.ResponseWriter, r *http.Request) {
+		mode := r.URL.Query().Get("mode")
+		switch mode {
+		case "":
+			mode = indexFull
+		case indexFull, indexIncremental:
+		default:
+			httpapi.WriteError(w, "mode must be full or incremental", 400)
+			return
+		}
+		maxTokens, err := indexBudgetFromQuery(r.URL.Query())
+		if err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+
+		res, err := indexCatalog(r.Context(), pool, tenantFromRequest(r), mode, maxTokens)
+		if err != nil {
+			httpapi.WriteError(w, err.Error(), indexErrorStatus(err))
+			return
+		}
+		logOutcome(r.Context(), slog.String("catalog", res.Provider), slog.Int("indexed", res.Indexed))
+		if res.Budget != nil {
+			logOutcome(r.Context(), slog.Int("embed_tokens", res.Budget.Tokens), slog.Int("remaining", res.Budget.Remaining))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	})))
+
+	// Legacy full reindex; kept for existing cron jobs
+	mux.Handle("POST /index-medusa-products", requireScope(scopeWrite, idempotent(pool, func(w http.ResponseWriter, r *http.Request) {
+		res, err := indexCatalog(r.Context(), pool, tenantFromRequest(r), indexFull, cfg().IndexTokenBudget)
+		if err != nil {
+			httpapi.WriteError(w, err.Error(), indexErrorStatus(err))
+			return
+		}
+
+		w.Write([]byte(fmt.Sprintf("indexed %d products", res.Indexed)))
+	})))
+
+	// JSONL dump of the tenant's vectors for backups or other vector stores
+	mux.Handle("GET /export-embeddings", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		opts := exportOpts{Tenant: tenantFromRequest(r), Category: q.Get("category"), Images: q.Get("images") == "true"}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="embeddings.jsonl"`)
+		n, err := exportEmbeddings(r.Context(), pool, opts, w)
+		if err != nil {
+			// headers are gone once rows have streamed; a truncated file is all we can signal
+			slog.ErrorContext(r.Context(), "export: failed", "rows", n, "err", err)
+			if n == 0 {
+				httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			}
+			return
+		}
+		logOutcome(r.Context(), slog.Int("exported", n))
+	}))
+
+	// Catalogs outside Medusa/Shopify: CSV or JSONL upload
+	mux.Handle("POST /import-catalog", requireScope(scopeWrite, idempotent(pool, func(w http.ResponseWriter, r *http.Request) {
+		body, format, err := readImportUpload(w, r)
+		if err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		recs, rejected, err := parseImport(body, format)
+		if err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+
+		res := ImportResult{Format: format, Rows: len(recs) + len(rejected)}
+		indexed, taken, err := importCatalog(r.Context(), pool, tenantFromRequest(r), recs)
+		if err != nil {
+			httpapi.WriteError(w, err.Error(), 500)
+			return
+		}
+		res.Indexed = indexed
+		res.Rejected = append(rejected, taken...)
+		slices.SortStableFunc(res.Rejected, func(a, b ImportError) int { return a.Line - b.Line })
+		logOutcome(r.Context(), slog.String("format", format), slog.Int("indexed", res.Indexed), slog.Int("rejected", len(res.Rejected)))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(res)
+	})))
+
+	// Remove a discontinued product so it stops appearing in recommendations
+	mux.Handle("DELETE /products/{id}", requireScope(scopeWrite, func(w http.ResponseWriter, r *http.Request) {
+		ids, err := deleteProduct(r.Context(), pool, tenantFromRequest(r), r.PathValue("id"))
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		if len(ids) == 0 {
+			httpapi.WriteError(w, "product not indexed", 404)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	mux.Handle("POST /admin/purge", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		var req PurgeReq
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if req.TenantID == "" {
+			req.TenantID = tenantFromRequest(r)
+		}
+		if req.TenantID != tenantFromRequest(r) && !principalFrom(r.Context()).bootstrap() {
+			httpapi.WriteError(w, "cannot purge another tenant's products", 403)
+			return
+		}
+
+		resp, err := purgeProducts(r.Context(), pool, req)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	// Clearance flags and margins for inventory-reduction campaigns
+	mux.Handle("POST /merchandising", requireScope(scopeWrite, func(w http.ResponseWriter, r *http.Request) {
+		var req MerchandisingReq
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+
+		resp, err := saveMerchandising(r.Context(), pool, tenantFromRequest(r), req)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	// Refresh stock from the catalog without re-embedding
+	mux.Handle("POST /sync-inventory", requireScope(scopeWrite, idempotent(pool, func(w http.ResponseWriter, r *http.Request) {
+		res, err := syncInventory(r.Context(), pool)
+		if err != nil {
+			httpapi.WriteError(w, "inventory sync: "+err.Error(), 500)
+			return
+		}
+
+		w.Write([]byte(fmt.Sprintf("synced stock for %d products; %d sold out, %d cached responses invalidated, %d saved-outfit substitutes",
+			res.Updated, res.SoldOut, res.Invalidated, res.Substituted)))
+	})))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// registerHealthRoutes adds the API description, liveness and readiness
+// routes.
+func registerHealthRoutes(mux *http.ServeMux, pool *pgxpool.Pool) {
+	// The API description, as JSON and as browsable docs
+	mux.HandleFunc("GET /openapi.json", openAPIHandler)
+	mux.HandleFunc("GET /docs", apiDocsHandler)
+
+	// Health check
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	// Readiness: per-dependency status, 503 when a critical dependency is down
+	mux.HandleFunc("GET /healthz/ready", func(w http.ResponseWriter, r *http.Request) {
+		resp := readiness(r.Context(), pool)
+		w.Header().Set("Content-Type", "application/json")
+		if resp.Status == "fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	// DB sanity check
+	mux.Handle("GET /db-check", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+		defer cancel()
+
+		var ext string
+		err := pool.QueryRow(ctx,
+			"SELECT extname FROM pg_extension WHERE extname='vector'").Scan(&ext)
+		if err != nil {
+			httpapi.WriteError(w, "pgvector missing: "+err.Error(), 500)
+			return
+		}
+
+		w.Write([]byte("db ok; vector ext=" + ext))
+	}))
+}
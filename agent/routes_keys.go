@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// registerKeyRoutes adds API key, signing key and webhook management.
+func registerKeyRoutes(mux *http.ServeMux, pool *pgxpool.Pool) {
+	// API key management for the caller's tenant
+	mux.Handle("POST /admin/api-keys", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		var req CreateAPIKeyReq
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := validateScopes(req.Scopes); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if req.Sandbox {
+			if !cfg().Sandbox {
+				httpapi.WriteError(w, "sandbox keys need CSA_SANDBOX=true", 400)
+				return
+			}
+			if slices.ContainsFunc(req.Scopes, func(s string) bool { return s != scopeRead }) {
+				httpapi.WriteError(w, "sandbox keys are read-only", 400)
+				return
+			}
+		}
+		if req.TenantID == "" {
+			req.TenantID = tenantFromRequest(r)
+		}
+		if req.TenantID != tenantFromRequest(r) && !principalFrom(r.Context()).bootstrap() {
+			httpapi.WriteError(w, "cannot create keys for another tenant", 403)
+			return
+		}
+
+		resp, err := createAPIKey(r.Context(), pool, req)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	mux.Handle("GET /admin/api-keys", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		keys, err := listAPIKeys(r.Context(), pool, tenantFromRequest(r))
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+
+	mux.Handle("DELETE /admin/api-keys/{id}", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		ok, err := revokeAPIKey(r.Context(), pool, tenantFromRequest(r), r.PathValue("id"))
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		if !ok {
+			httpapi.WriteError(w, "api key not found", 404)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// Response signing keys for the caller's tenant
+	mux.Handle("POST /admin/signing-keys", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		var req CreateSigningKeyReq
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if req.Alg != signHMAC && req.Alg != signEd25519 {
+			httpapi.BadRequest(w, httpapi.InvalidField("alg", "alg must be %s or %s", signHMAC, signEd25519))
+			return
+		}
+		resp, err := createSigningKey(r.Context(), pool, tenantFromRequest(r), req.Alg)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	mux.Handle("GET /admin/signing-keys", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		keys, err := listSigningKeys(r.Context(), pool, tenantFromRequest(r), false)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+
+	mux.Handle("DELETE /admin/signing-keys/{id}", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		ok, err := revokeSigningKey(r.Context(), pool, tenantFromRequest(r), r.PathValue("id"))
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		if !ok {
+			httpapi.WriteError(w, "signing key not found", 404)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// Webhooks for the caller's tenant, delivered through the outbox; see
+	// webhooks.go
+	mux.Handle("POST /admin/webhooks", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		var req CreateWebhookReq
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		resp, err := createWebhook(r.Context(), pool, tenantFromRequest(r), req)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	mux.Handle("GET /admin/webhooks", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		hooks, err := listWebhooks(r.Context(), pool, tenantFromRequest(r))
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"webhooks": hooks})
+	}))
+
+	mux.Handle("DELETE /admin/webhooks/{id}", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		ok, err := revokeWebhook(r.Context(), pool, tenantFromRequest(r), r.PathValue("id"))
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		if !ok {
+			httpapi.WriteError(w, "webhook not found", 404)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	mux.Handle("GET /admin/webhooks/deliveries", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		f, err := deliveryFilterFromQuery(r.URL.Query())
+		if err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		deliveries, err := listDeliveries(r.Context(), pool, tenantFromRequest(r), f)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"deliveries": deliveries})
+	}))
+
+	mux.Handle("POST /admin/webhooks/deliveries/replay", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		var req ReplayDeliveriesReq
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		n, err := replayDeliveries(r.Context(), pool, tenantFromRequest(r), req)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		logOutcome(r.Context(), slog.Int64("replayed", n))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"replayed": n})
+	}))
+
+	// Public Ed25519 keys so downstream services can verify X-CSA-Signature
+	mux.Handle("GET /signing-keys", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		keys, err := listSigningKeys(r.Context(), pool, tenantFromRequest(r), true)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		public := []SigningKey{}
+		for _, k := range keys {
+			if k.Alg == signEd25519 {
+				public = append(public, k)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"keys": public})
+	}))
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// registerOutfitRoutes adds outfit building, explanations, saved outfits,
+// intent parsing and missions. handler is the whole chain, which styling
+// sessions call back into.
+func registerOutfitRoutes(mux *http.ServeMux, pool *pgxpool.Pool, handler func() http.Handler) {
+	mux.Handle("POST /complete-outfit", rateLimited(requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		var req CompleteOutfitReq
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.checkMarginFloor(r.Context()); err != nil {
+			httpapi.WriteError(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err := checkMission(r.Context(), pool, req.Mission); err != nil {
+			if httpapi.IsInvalid(err) {
+				httpapi.BadRequest(w, err)
+			} else {
+				httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			}
+			return
+		}
+		if sandboxFrom(r.Context()) {
+			if req.CartID != "" {
+				httpapi.BadRequest(w, httpapi.InvalidField("cart_id", "cart_id is not available with sandbox keys; use cart_slots"))
+				return
+			}
+			if err := req.applySandboxDefaults(r.Context(), pool); err != nil {
+				httpapi.WriteError(w, "db error: "+err.Error(), 500)
+				return
+			}
+		}
+		if err := req.resolveCart(r.Context(), pool); err != nil {
+			switch {
+			case errors.Is(err, errCartNotFound):
+				httpapi.WriteError(w, "cart "+req.CartID+" not found", 404)
+			case httpapi.IsInvalid(err):
+				httpapi.BadRequest(w, err)
+			default:
+				httpapi.WriteError(w, err.Error(), 502)
+			}
+			return
+		}
+
+		req.constraints = activeConstraints(r.Context(), pool)
+		key := outfitCacheKey(r.Context(), tenantFromRequest(r), req)
+		resp, ok := outfits.get(key)
+		logOutcome(r.Context(), slog.Bool("cache_hit", ok), slog.Bool("cache_stale", ok && resp.Cache.Stale))
+		if !ok {
+			var err error
+			resp, err = completeOutfit(r.Context(), pool, req)
+			if err != nil {
+				httpapi.WriteError(w, err.Error(), 500)
+				return
+			}
+			outfits.put(key, resp)
+		} else {
+			// the refresh outlives this request and isn't part of its transcript
+			bg, _ := withOutcome(context.WithoutCancel(r.Context()))
+			bg = context.WithValue(bg, sessionKey{}, (*session)(nil))
+			outfits.revalidate(bg, key, &resp, func(ctx context.Context) (CompleteOutfitResp, error) {
+				return completeOutfit(ctx, pool, req)
+			})
+			w.Header().Set("Age", strconv.Itoa(resp.Cache.AgeSeconds))
+		}
+		resp.SessionConstraints = constraintRefs(req.constraints)
+		resp.Meta = responseMeta(r.Context())
+		for _, sr := range resp.Results {
+			recordServed(r.Context(), pool, "complete-outfit", servedDetail{Query: req.Query, Mission: resp.Mission, Slot: sr.Slot}, sr.Hits)
+		}
+		recordAudit(r.Context(), pool, outfitAudit(req, resp, ok))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})))
+
+	mux.Handle("POST /group-outfits", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		var req GroupOutfitReq
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := checkMission(r.Context(), pool, req.Mission); err != nil {
+			if httpapi.IsInvalid(err) {
+				httpapi.BadRequest(w, err)
+			} else {
+				httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			}
+			return
+		}
+
+		resp, err := runGroupOutfits(r.Context(), pool, req)
+		if err != nil {
+			httpapi.WriteError(w, err.Error(), 500)
+			return
+		}
+		resp.Meta = responseMeta(r.Context())
+		for _, p := range resp.People {
+			for _, sr := range p.Outfit.Results {
+				recordServed(r.Context(), pool, "group-outfits", servedDetail{Mission: resp.Mission, Slot: sr.Slot}, sr.Hits)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	mux.Handle("POST /saved-outfits", requireScope(scopeRead, idempotent(pool, func(w http.ResponseWriter, r *http.Request) {
+		var req SaveOutfitReq
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+
+		o, err := saveOutfit(r.Context(), pool, tenantFromRequest(r), req)
+		if err != nil {
+			httpapi.WriteError(w, "save outfit: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(o)
+	})))
+
+	mux.Handle("GET /saved-outfits/{id}", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		o, err := loadSavedOutfit(r.Context(), pool, tenantFromRequest(r), r.PathValue("id"))
+		if err != nil {
+			httpapi.WriteError(w, "load outfit: "+err.Error(), 500)
+			return
+		}
+		if o == nil {
+			httpapi.WriteError(w, "saved outfit not found", 404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(o)
+	}))
+
+	mux.Handle("POST /explain-outfit", rateLimited(requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		var resp CompleteOutfitResp
+		if err := decodeJSON(w, r, &resp); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+
+		ts, err := loadTenantSettings(r.Context(), pool, tenantFromRequest(r))
+		if err != nil {
+			httpapi.WriteError(w, "tenant settings: "+err.Error(), 500)
+			return
+		}
+
+		var bullets []string
+		if ts.ExplainEngine == explainEngineTemplate {
+			bullets = templateExplain(resp)
+		} else {
+			bullets, err = explainOutfitWithFallback(r.Context(), resp, ts)
+		}
+		if err != nil {
+			httpapi.WriteError(w, err.Error(), 500)
+			return
+		}
+		recordAudit(r.Context(), pool, explainAudit(resp, ts.ExplainEngine, bullets))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"bullets": bullets,
+		})
+	})))
+
+	// Styling session: outfits and explanations streamed over a WebSocket as
+	// the shopper refines the request
+	mux.Handle("GET /ws", requireScope(scopeRead, stylingSocket(handler)))
+
+	// Map a free-text outfit request to a mission and constraints
+	mux.Handle("POST /parse-intent", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Text string `json:"text"`
+		}
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		req.Text = strings.TrimSpace(req.Text)
+		if req.Text == "" || len(req.Text) > maxIntentText {
+			httpapi.BadRequest(w, httpapi.InvalidField("text", "text must be 1-%d characters", maxIntentText))
+			return
+		}
+		in, err := parseOutfitIntent(r.Context(), pool, req.Text)
+		if err != nil {
+			httpapi.WriteError(w, "intent parsing failed: "+err.Error(), 502)
+			return
+		}
+		logOutcome(r.Context(), slog.String("mission", in.Mission))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(in)
+	}))
+
+	// Outfit missions: built-ins plus the calling tenant's own
+	mux.Handle("GET /missions", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		missions, err := listMissions(r.Context(), pool, tenantFromRequest(r))
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"missions": missions})
+	}))
+
+	mux.Handle("GET /missions/{name}", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		m, ok, err := loadMission(r.Context(), pool, tenantFromRequest(r), r.PathValue("name"))
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		if !ok {
+			httpapi.WriteError(w, "mission not found", 404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(m)
+	}))
+
+	mux.Handle("PUT /missions/{name}", requireScope(scopeWrite, func(w http.ResponseWriter, r *http.Request) {
+		var m Mission
+		if err := decodeJSON(w, r, &m); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		m.Name = r.PathValue("name")
+		if err := m.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		m, err := saveMission(r.Context(), pool, tenantFromRequest(r), m)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		outfits.reset() // cached outfits may have used the old definition
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(m)
+	}))
+
+	// Deleting a tenant's override of a built-in mission restores the built-in
+	mux.Handle("DELETE /missions/{name}", requireScope(scopeWrite, func(w http.ResponseWriter, r *http.Request) {
+		ok, err := deleteMission(r.Context(), pool, tenantFromRequest(r), r.PathValue("name"))
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		if !ok {
+			httpapi.WriteError(w, "mission not found", 404)
+			return
+		}
+		outfits.reset()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
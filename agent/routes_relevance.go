@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// registerRelevanceRoutes adds relevance judgments and the embedding
+// benchmark run against them.
+func registerRelevanceRoutes(mux *http.ServeMux, pool *pgxpool.Pool) {
+	// Relevance labels from the merchandiser dashboard; the golden set for evals
+	mux.Handle("POST /admin/relevance-judgments", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		var req JudgmentsReq
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+
+		saved, err := saveJudgments(r.Context(), pool, tenantFromRequest(r), principalFrom(r.Context()).KeyID, req)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"judgments": saved})
+	}))
+
+	mux.Handle("GET /admin/relevance-judgments", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		limit := 100
+		if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 && n <= 1000 {
+			limit = n
+		}
+		js, err := listJudgments(r.Context(), pool, tenantFromRequest(r), r.URL.Query().Get("query"), limit)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"judgments": js})
+	}))
+
+	mux.Handle("GET /admin/relevance-judgments/candidates", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("query")
+		if strings.TrimSpace(q) == "" {
+			httpapi.WriteError(w, "query is required", 400)
+			return
+		}
+		limit := 20
+		if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 && n <= 100 {
+			limit = n
+		}
+		cands, err := judgmentCandidates(r.Context(), pool, tenantFromRequest(r), q, limit)
+		if err != nil {
+			httpapi.WriteError(w, "query error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"query": normalizeQuery(q), "candidates": cands})
+	}))
+
+	mux.Handle("GET /admin/relevance-judgments/golden-set", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		gs, err := goldenSet(r.Context(), pool, tenantFromRequest(r))
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(gs)
+	}))
+
+	// Compare OpenAI and local embeddings on the golden set; costs one
+	// OpenAI embeddings call per CSA_INDEX_BATCH_SIZE judged products/queries
+	mux.Handle("POST /admin/embedding-benchmark", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		k := 10
+		if n, err := strconv.Atoi(r.URL.Query().Get("k")); err == nil && n > 0 && n <= 100 {
+			k = n
+		}
+		resp, err := embeddingBenchmark(r.Context(), pool, tenantFromRequest(r), k)
+		switch {
+		case errors.Is(err, errBenchNoJudgments):
+			httpapi.WriteError(w, "benchmark: "+err.Error(), 400)
+			return
+		case errors.Is(err, errBenchNoModel):
+			httpapi.WriteError(w, "benchmark: "+err.Error(), 503)
+			return
+		case errors.Is(err, errBenchBackends):
+			httpapi.WriteError(w, "benchmark: "+err.Error(), 502)
+			return
+		case err != nil:
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	mux.Handle("DELETE /admin/relevance-judgments/{id}", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		ok, err := deleteJudgment(r.Context(), pool, tenantFromRequest(r), r.PathValue("id"))
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		if !ok {
+			httpapi.WriteError(w, "judgment not found", 404)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
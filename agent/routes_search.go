@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// registerSearchRoutes adds product search: by text, by image, trending,
+// substitutes and price distributions.
+func registerSearchRoutes(mux *http.ServeMux, pool *pgxpool.Pool) {
+	// Vector search
+	mux.Handle("POST /search", rateLimited(requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		var req SearchReq
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.checkMarginFloor(r.Context()); err != nil {
+			httpapi.WriteError(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if err := checkCategory(r.Context(), pool, req.Category); err != nil {
+			if httpapi.IsInvalid(err) {
+				httpapi.BadRequest(w, err)
+			} else {
+				httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			}
+			return
+		}
+
+		if req.Limit <= 0 {
+			req.Limit = 5
+		}
+		query := req.Query
+		var within []string
+		if req.Within != "" {
+			prev, ok, err := loadSearchResult(r.Context(), pool, tenantFromRequest(r), req.Within)
+			if err != nil {
+				httpapi.WriteError(w, "db error: "+err.Error(), 500)
+				return
+			}
+			if !ok {
+				httpapi.BadRequest(w, httpapi.InvalidField("within", "response %q is unknown or has expired; search again", req.Within))
+				return
+			}
+			within = prev.productIDs
+			if query == "" {
+				query = prev.query // keep ranking by what the shopper first asked for
+			}
+			logOutcome(r.Context(), slog.Int("refined_from", len(within)))
+		}
+
+		constraints := activeConstraints(r.Context(), pool)
+		cacheKey, cacheable := searchCacheKey(r.Context(), tenantFromRequest(r), req, constraints)
+		if cacheable {
+			if resp, ok := cachedSearch(r.Context(), cacheKey); ok {
+				logOutcome(r.Context(), slog.Bool("cache_hit", true))
+				recordServed(r.Context(), pool, "search", servedDetail{Query: query}, resp.Hits)
+				resp.ResponseID = saveSearchResult(r.Context(), pool, tenantFromRequest(r), query, resp.Hits)
+				resp.Meta = responseMeta(r.Context())
+				recordAudit(r.Context(), pool, searchAudit(req, query, resp, true))
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(resp)
+				return
+			}
+			logOutcome(r.Context(), slog.Bool("cache_hit", false))
+		}
+
+		params := searchParams{
+			Query:            query,
+			Limit:            req.Limit,
+			MaxPriceGBP:      req.MaxPriceGBP,
+			MinEcoScore:      req.MinEcoScore,
+			Category:         req.Category,
+			Attrs:            req.AttrFilters,
+			Origin:           req.OriginPrefs,
+			Fresh:            req.FreshnessPrefs,
+			Clearance:        req.ClearancePrefs,
+			Ranking:          req.RankingPrefs,
+			Diversity:        req.DiversityPrefs,
+			Lifecycle:        req.LifecyclePrefs,
+			ExpandDuplicates: req.ExpandDuplicates,
+			Metric:           req.DistanceMetric,
+			Quality:          req.SearchQuality,
+			SortBy:           req.SortBy,
+			Within:           within,
+		}
+		// simple filter-style queries skip the embedding call entirely
+		var intent *QueryIntent
+		if cfg().IntentRouter {
+			route := routeSemantic
+			if in := classifyQuery(query); applyIntent(in, &params) {
+				intent, route = &in, in.Route
+			}
+			logOutcome(r.Context(), slog.String("intent_route", route))
+		}
+		params.Attrs = constrain(params.Attrs, constraints, params.Category)
+
+		hits, routed, err := searchRouted(r.Context(), pool, params)
+		degraded := false
+		if errors.Is(err, errEmbedUnavailable) && cfg().KeywordFallback {
+			slog.WarnContext(r.Context(), "search: embeddings unavailable, falling back to keyword search", "err", err)
+			params.Keyword, degraded = true, true
+			addWarnings(r.Context(), degradedWarning)
+			logOutcome(r.Context(), slog.Bool("degraded", true))
+			hits, routed, err = searchRouted(r.Context(), pool, params)
+		}
+		if err != nil {
+			httpapi.WriteError(w, "query error: "+err.Error(), 500)
+			return
+		}
+		if hits == nil {
+			hits = []Hit{}
+		}
+		if routed != nil {
+			logOutcome(r.Context(), slog.Any("routed_categories", routed))
+		}
+		recordServed(r.Context(), pool, "search", servedDetail{Query: query}, hits)
+
+		resp := SearchResp{Hits: hits, Intent: intent, RoutedCategories: routed, Degraded: degraded,
+			SessionConstraints: constraintRefs(constraints), Meta: responseMeta(r.Context())}
+		if cacheable && !degraded { // a later search may get semantic results
+			storeSearch(r.Context(), cacheKey, resp)
+		}
+		resp.ResponseID = saveSearchResult(r.Context(), pool, tenantFromRequest(r), query, hits)
+		recordAudit(r.Context(), pool, searchAudit(req, query, resp, false))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})))
+
+	// Visual similarity search by image URL or upload
+	mux.Handle("POST /search-by-image", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		if sandboxFrom(r.Context()) {
+			httpapi.WriteError(w, "image search is not available with sandbox keys", 400)
+			return
+		}
+		req, img, err := parseImageSearch(w, r)
+		if err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if req.Limit <= 0 {
+			req.Limit = 5
+		}
+
+		emb, err := imageEmbed(r.Context(), req.ImageURL, img)
+		if err != nil {
+			httpapi.WriteError(w, err.Error(), 500)
+			return
+		}
+
+		hits, err := searchByImage(r.Context(), pool, emb, req.Limit, req.Category)
+		if err != nil {
+			httpapi.WriteError(w, "query error: "+err.Error(), 500)
+			return
+		}
+		countHits(r.Context(), len(hits))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(SearchResp{Hits: hits})
+	}))
+
+	// Trending products per category, served from a materialized view
+	mux.Handle("GET /home-feed", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		perCategory := 8
+		if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+			perCategory = n
+		}
+
+		resp, err := homeFeed(r.Context(), pool, perCategory)
+		if err != nil {
+			httpapi.WriteError(w, "query error: "+err.Error(), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	mux.Handle("GET /products/{id}/substitutes", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		opts := substituteOpts{Limit: 5, PriceTolerance: 0.1, InStockOnly: q.Get("in_stock") != "false"}
+		if v := q.Get("limit"); v != "" {
+			opts.Limit, _ = strconv.Atoi(v)
+		}
+		if v := q.Get("price_tolerance"); v != "" {
+			var err error
+			if opts.PriceTolerance, err = strconv.ParseFloat(v, 64); err != nil {
+				httpapi.WriteError(w, "price_tolerance must be a number", 400)
+				return
+			}
+		}
+		if err := opts.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+
+		resp, err := findSubstitutes(r.Context(), pool, r.PathValue("id"), opts)
+		if err != nil {
+			httpapi.WriteError(w, "query error: "+err.Error(), 500)
+			return
+		}
+		if resp == nil {
+			httpapi.WriteError(w, "product not indexed", 404)
+			return
+		}
+		recordServed(r.Context(), pool, "substitutes", servedDetail{Product: resp.ProductID}, resp.Substitutes)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+
+	mux.Handle("GET /stats/price-distribution", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		dist, err := priceDistribution(r.Context(), pool)
+		if err != nil {
+			httpapi.WriteError(w, "query error: "+err.Error(), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"slots":        dist,
+			"refreshed_at": viewRefreshedAt(r.Context(), pool, "mv_price_distribution"),
+		})
+	}))
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// registerShopperRoutes adds what a storefront sends and reads about a
+// shopper: consent, session constraints, feedback, history and erasure.
+func registerShopperRoutes(mux *http.ServeMux, pool *pgxpool.Pool) {
+	// Shopper consent for using a session's transcript; sent by the storefront
+	mux.Handle("PUT /sessions/{id}/consent", requireScope(scopeWrite, func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if !sessionIDPattern.MatchString(id) {
+			httpapi.WriteError(w, "invalid session id", 400)
+			return
+		}
+		var c Consent
+		if err := decodeJSON(w, r, &c); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := setSessionConsent(r.Context(), pool, tenantFromRequest(r), id, c); err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// Temporary constraints a shopper states in chat ("no laces today");
+	// applied to the session's searches and outfits until they expire
+	mux.Handle("POST /sessions/{id}/constraints", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if !sessionIDPattern.MatchString(id) {
+			httpapi.WriteError(w, "invalid session id", 400)
+			return
+		}
+		var req ConstraintReq
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		c, err := newConstraint(r.Context(), pool, tenantFromRequest(r), id, req)
+		if err != nil {
+			if httpapi.IsInvalid(err) {
+				httpapi.BadRequest(w, err)
+			} else {
+				httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			}
+			return
+		}
+		logOutcome(r.Context(), slog.String("constraint", c.ID), slog.Time("expires_at", c.ExpiresAt))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(c)
+	}))
+
+	mux.Handle("GET /sessions/{id}/constraints", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if !sessionIDPattern.MatchString(id) {
+			httpapi.WriteError(w, "invalid session id", 400)
+			return
+		}
+		cs, err := listConstraints(r.Context(), pool, tenantFromRequest(r), id)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"constraints": cs})
+	}))
+
+	mux.Handle("DELETE /sessions/{id}/constraints/{cid}", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if !sessionIDPattern.MatchString(id) {
+			httpapi.WriteError(w, "invalid session id", 400)
+			return
+		}
+		ok, err := deleteConstraint(r.Context(), pool, tenantFromRequest(r), id, r.PathValue("cid"))
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		if !ok {
+			httpapi.WriteError(w, "constraint not found", 404)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// Thumbs up/down and add-to-cart on a recommended product; feeds ranking
+	mux.Handle("POST /feedback", feedbackRateLimited(requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		var req FeedbackReq
+		if err := decodeJSON(w, r, &req); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if err := req.validate(); err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		if !sandboxFrom(r.Context()) { // accepted but not kept, so sandbox traffic can't skew ranking
+			if err := saveFeedback(r.Context(), pool, tenantFromRequest(r), req); err != nil {
+				if httpapi.IsInvalid(err) {
+					httpapi.BadRequest(w, err)
+				} else {
+					httpapi.WriteError(w, "db error: "+err.Error(), 500)
+				}
+				return
+			}
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})))
+
+	// What a shopper was shown across their sessions (X-User-ID), newest first
+	mux.Handle("GET /users/{id}/history", requireScope(scopeRead, func(w http.ResponseWriter, r *http.Request) {
+		if principalFrom(r.Context()) == nil {
+			// personal data: needs a key even when read routes are open
+			w.Header().Set("WWW-Authenticate", `Bearer realm="csa"`)
+			httpapi.WriteError(w, "API key required", http.StatusUnauthorized)
+			return
+		}
+		id := r.PathValue("id")
+		if !sessionIDPattern.MatchString(id) {
+			httpapi.WriteError(w, "invalid user id", 400)
+			return
+		}
+		f, err := parseHistoryFilter(r.URL.Query())
+		if err != nil {
+			httpapi.BadRequest(w, err)
+			return
+		}
+		page, err := userHistory(r.Context(), pool, tenantFromRequest(r), id, f)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+	}))
+
+	// Erase everything stored about a shopper (right to erasure) and return a receipt
+	mux.Handle("DELETE /users/{id}/data", requireScope(scopeWrite, func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if !sessionIDPattern.MatchString(id) {
+			httpapi.WriteError(w, "invalid user id", 400)
+			return
+		}
+		rec, err := eraseUserData(r.Context(), pool, tenantFromRequest(r), id)
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		logOutcome(r.Context(), slog.String("receipt_id", rec.ReceiptID))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rec)
+	}))
+
+	// Full session bundle for QA review (purpose=qa) or training datasets (purpose=training)
+	mux.Handle("GET /admin/sessions/{id}/export", requireScope(scopeAdmin, func(w http.ResponseWriter, r *http.Request) {
+		purpose := r.URL.Query().Get("purpose")
+		if purpose != exportPurposeQA && purpose != exportPurposeTraining {
+			httpapi.WriteError(w, "purpose must be qa or training", 400)
+			return
+		}
+		ex, err := exportSession(r.Context(), pool, tenantFromRequest(r), r.PathValue("id"), purpose)
+		if errors.Is(err, errNoConsent) {
+			httpapi.WriteError(w, err.Error(), 403)
+			return
+		}
+		if err != nil {
+			httpapi.WriteError(w, "db error: "+err.Error(), 500)
+			return
+		}
+		if ex == nil {
+			httpapi.WriteError(w, "session not found", 404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="session-%s.json"`, ex.Session.ID))
+		json.NewEncoder(w).Encode(ex)
+	}))
+}
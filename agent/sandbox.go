@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+)
+
+// Sandbox keys (CSA_SANDBOX=true) let integrators try the API without a
+// catalog or model accounts. Their requests run as sandboxTenant against a
+// small seeded catalog, with fake embedding and chat providers and the
+// defaults the old /demo endpoint hard-coded. Sandbox keys are read-only.
+const sandboxTenant = "sandbox"
+
+// sandboxBudgetGBP is the outfit budget when neither the request nor the
+// mission sets one.
+const sandboxBudgetGBP = 120
+
+var errSandboxDisabled = errors.New("sandbox keys are disabled (CSA_SANDBOX=false)")
+
+// sandboxFrom reports whether the request was made with a sandbox key.
+func sandboxFrom(ctx context.Context) bool {
+	p := principalFrom(ctx)
+	return p != nil && p.Sandbox
+}
+
+// sandboxSQL restricts a product_embeddings query to the sandbox catalog for
+// sandbox requests and to the real catalog otherwise. alias is the table
+// alias, if the query uses one.
+func sandboxSQL(ctx context.Context, alias string) string {
+	if alias != "" {
+		alias += "."
+	}
+	if sandboxFrom(ctx) {
+		return alias + "sandbox"
+	}
+	return "NOT " + alias + "sandbox"
+}
+
+// applySandboxDefaults fills in what a sandbox outfit request leaves out:
+// the default mission, a top already in the cart, and a budget.
+func (req *CompleteOutfitReq) applySandboxDefaults(ctx context.Context, pool *pgxpool.Pool) error {
+	if req.Mission == "" {
+		req.Mission = defaultMission
+	}
+	if req.CartID == "" && len(req.CartSlots) == 0 {
+		req.CartSlots = []string{"top"}
+	}
+	mission, err := resolveMission(ctx, pool, req.Mission)
+	if err != nil {
+		return err
+	}
+	mission.applyDefaults(req)
+	req.mission = &mission
+	if req.BudgetGBP <= 0 {
+		req.BudgetGBP = sandboxBudgetGBP
+	}
+	return nil
+}
+
+// sandboxProvider answers chat calls without a model: structured requests
+// get the simplest document their schema allows, the rest a fixed line.
+type sandboxProvider struct{}
+
+const sandboxChatText = "Sandbox response: no model was called."
+
+func (sandboxProvider) chat(ctx context.Context, req chatRequest) (chatResult, error) {
+	if req.Schema == nil {
+		return chatResult{Text: sandboxChatText}, nil
+	}
+	b, err := json.Marshal(schemaStub(req.Schema.Schema))
+	return chatResult{Text: string(b)}, err
+}
+
+func (sandboxProvider) defaultModel() string { return "sandbox" }
+func (sandboxProvider) configured() bool     { return true }
+
+// schemaStub builds a value matching a JSON schema: the first enum value,
+// null where allowed, the minimum for numbers, empty arrays.
+func schemaStub(s map[string]any) any {
+	if enum, ok := s["enum"].([]string); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	t, ok := s["type"].(string)
+	if !ok {
+		return nil // ["string", "null"] and friends
+	}
+	switch t {
+	case "object":
+		out := map[string]any{}
+		props, _ := s["properties"].(map[string]any)
+		for k, v := range props {
+			if ps, ok := v.(map[string]any); ok {
+				out[k] = schemaStub(ps)
+			}
+		}
+		return out
+	case "array":
+		return []any{} // callers treat "nothing" as "use the fallback"
+	case "string":
+		return sandboxChatText
+	case "integer", "number":
+		if m, ok := s["minimum"]; ok {
+			return m
+		}
+		return 0
+	case "boolean":
+		return false
+	}
+	return nil
+}
+
+// sandboxCatalog is the seeded sandbox catalog: enough of each slot for
+// every built-in mission to return a full outfit.
+var sandboxCatalog = []struct {
+	id, title, slot, color, material, description string
+	price                                         float64
+	eco                                           int
+}{
+	{"sandbox-oxford-shirt", "Organic Cotton Oxford Shirt", "top", "white", "organic cotton", "A smart casual button-down shirt for the office or the weekend.", 45, 82},
+	{"sandbox-merino-jumper", "Merino Crew Neck Jumper", "top", "navy", "merino wool", "A fine-knit jumper that layers over shirts for business casual days.", 65, 74},
+	{"sandbox-linen-tee", "Linen Blend T-Shirt", "top", "beige", "linen", "A relaxed tee for warm weather and casual outfits.", 25, 68},
+	{"sandbox-polo", "Recycled Pique Polo", "top", "green", "recycled polyester", "A smart casual polo shirt with a structured collar.", 35, 61},
+	{"sandbox-chinos", "Stretch Chinos", "bottom", "beige", "cotton", "Slim chinos that work for smart casual and business casual looks.", 50, 58},
+	{"sandbox-wool-trousers", "Tailored Wool Trousers", "bottom", "grey", "wool", "Tailored trousers for business casual and formal occasions.", 80, 66},
+	{"sandbox-jeans", "Organic Denim Jeans", "bottom", "blue", "organic cotton", "Straight-leg jeans for everyday casual wear.", 60, 77},
+	{"sandbox-hiking-trousers", "Water-Resistant Hiking Trousers", "bottom", "black", "recycled nylon", "Quick-drying trousers for outdoor walks in the rain.", 55, 63},
+	{"sandbox-leather-trainers", "Minimal Leather Trainers", "shoes", "white", "leather", "Clean trainers that dress up or down for smart casual outfits.", 70, 52},
+	{"sandbox-loafers", "Suede Loafers", "shoes", "brown", "suede", "Classic loafers for business casual offices.", 85, 48},
+	{"sandbox-walking-boots", "Waterproof Walking Boots", "shoes", "brown", "recycled polyester", "Waterproof boots with grip for rainy outdoor hikes.", 95, 60},
+	{"sandbox-rain-jacket", "Recycled Rain Jacket", "outerwear", "green", "recycled nylon", "A packable waterproof jacket for outdoor rain.", 90, 79},
+	{"sandbox-wool-coat", "Wool Overcoat", "outerwear", "grey", "wool", "A tailored overcoat for smart and business outfits.", 150, 64},
+	{"sandbox-denim-jacket", "Organic Denim Jacket", "outerwear", "blue", "organic cotton", "A casual jacket for mild days.", 70, 72},
+}
+
+// seedSandbox upserts the sandbox catalog. It runs at startup when sandbox
+// keys are enabled and is idempotent.
+func seedSandbox(ctx context.Context, pool *pgxpool.Pool) error {
+	ctx = context.WithValue(ctx, ctxPrincipal, &principal{KeyID: "sandbox-seed", TenantID: sandboxTenant, Scopes: []string{scopeRead}, Sandbox: true})
+	rows := make([]productRow, 0, len(sandboxCatalog))
+	for _, s := range sandboxCatalog {
+		p := catalog.Product{
+			ID: s.id, Title: s.title, Description: s.description, Material: s.material,
+			Options:  []catalog.Option{{Title: "Color", Values: []string{s.color}}, {Title: "Size", Values: []string{"S", "M", "L"}}},
+			Metadata: map[string]any{"slot": s.slot, "eco_score": float64(s.eco)},
+			PriceGBP: s.price, HasPrice: true,
+		}
+		row := productRowFor(ctx, p, sandboxTenant)
+		row.Sandbox = true
+		rows = append(rows, row)
+	}
+	n, err := indexProducts(ctx, pool, rows)
+	if err != nil {
+		return err
+	}
+	slog.Info("sandbox: catalog seeded", "products", n)
+	return nil
+}
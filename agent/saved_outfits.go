@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/catalog"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// SavedOutfit is an outfit a shopper kept for later. When one of its items
+// sells out, inventory sync fills in a substitute for that item.
+type SavedOutfit struct {
+	ID          string            `json:"id"`
+	TenantID    string            `json:"tenant_id"`
+	Name        string            `json:"name"`
+	Mission     string            `json:"mission"`
+	MinEcoScore int               `json:"min_eco_score"`
+	Items       []SavedOutfitItem `json:"items"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+type SavedOutfitItem struct {
+	Slot          string     `json:"slot"`
+	ProductID     string     `json:"product_id"`
+	Substitute    *Hit       `json:"substitute,omitempty"`
+	SubstitutedAt *time.Time `json:"substituted_at,omitempty"`
+}
+
+type SaveOutfitReq struct {
+	Name        string `json:"name"`
+	Mission     string `json:"mission"`
+	MinEcoScore int    `json:"min_eco_score"`
+	Items       []struct {
+		Slot      string `json:"slot"`
+		ProductID string `json:"product_id"`
+	} `json:"items"`
+}
+
+func (r SaveOutfitReq) validate() error {
+	if r.Mission == "" {
+		return httpapi.InvalidField("mission", "mission is required")
+	}
+	if len(r.Items) == 0 {
+		return httpapi.InvalidField("items", "items is required")
+	}
+	for i, it := range r.Items {
+		if it.Slot == "" || it.ProductID == "" {
+			return httpapi.InvalidField(fmt.Sprintf("items[%d]", i), "items[%d] needs slot and product_id", i)
+		}
+	}
+	return nil
+}
+
+func saveOutfit(ctx context.Context, pool *pgxpool.Pool, tenantID string, req SaveOutfitReq) (SavedOutfit, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return SavedOutfit{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	o := SavedOutfit{TenantID: tenantID, Name: req.Name, Mission: req.Mission, MinEcoScore: req.MinEcoScore}
+	err = tx.QueryRow(ctx, `
+INSERT INTO saved_outfits (tenant_id, name, mission, min_eco_score)
+VALUES ($1,$2,$3,$4)
+RETURNING id::text, created_at
+`, tenantID, req.Name, req.Mission, req.MinEcoScore).Scan(&o.ID, &o.CreatedAt)
+	if err != nil {
+		return SavedOutfit{}, err
+	}
+
+	for _, it := range req.Items {
+		if _, err := tx.Exec(ctx, `
+INSERT INTO saved_outfit_items (outfit_id, slot, product_id) VALUES ($1::uuid,$2,$3)
+ON CONFLICT DO NOTHING
+`, o.ID, catalog.NormalizeCategory(it.Slot), it.ProductID); err != nil {
+			return SavedOutfit{}, err
+		}
+		o.Items = append(o.Items, SavedOutfitItem{Slot: catalog.NormalizeCategory(it.Slot), ProductID: it.ProductID})
+	}
+	return o, tx.Commit(ctx)
+}
+
+// loadSavedOutfit returns nil when the outfit doesn't exist for tenantID.
+func loadSavedOutfit(ctx context.Context, pool *pgxpool.Pool, tenantID, id string) (*SavedOutfit, error) {
+	o := SavedOutfit{Items: []SavedOutfitItem{}}
+	err := pool.QueryRow(ctx, `
+SELECT id::text, tenant_id, name, mission, min_eco_score, created_at
+FROM saved_outfits WHERE id::text=$1 AND tenant_id=$2
+`, id, tenantID).Scan(&o.ID, &o.TenantID, &o.Name, &o.Mission, &o.MinEcoScore, &o.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pool.Query(ctx, `
+SELECT slot, product_id, substitute, substituted_at
+FROM saved_outfit_items WHERE outfit_id::text=$1
+ORDER BY slot, product_id
+`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var it SavedOutfitItem
+		if err := rows.Scan(&it.Slot, &it.ProductID, &it.Substitute, &it.SubstitutedAt); err != nil {
+			return nil, err
+		}
+		o.Items = append(o.Items, it)
+	}
+	return &o, rows.Err()
+}
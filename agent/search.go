@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/embeddings"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+type SearchReq struct {
+	Query       string  `json:"query"`
+	Limit       int     `json:"limit"`
+	MaxPriceGBP float64 `json:"max_price_gbp"`
+	MinEcoScore int     `json:"min_eco_score"`
+	Category    string  `json:"category"`
+	SortBy      string  `json:"sort_by,omitempty"` // price_asc | price_desc | eco_desc | newest | popularity; default relevance
+	// list other listings of each hit (other colours, relists) in duplicates
+	ExpandDuplicates bool `json:"expand_duplicates,omitempty"`
+	// l2 | cosine | inner_product; default CSA_DISTANCE_METRIC
+	DistanceMetric string `json:"distance_metric,omitempty"`
+	// fast | balanced | high | max: recall vs latency; default CSA_SEARCH_QUALITY
+	SearchQuality string `json:"search_quality,omitempty"`
+	// response_id of an earlier search: only its hits are searched, see refine.go
+	Within string `json:"within,omitempty"`
+	AttrFilters
+	OriginPrefs
+	FreshnessPrefs
+	ClearancePrefs
+	RankingPrefs
+	DiversityPrefs
+	LifecyclePrefs
+}
+
+type Hit struct {
+	ProductID  string  `json:"product_id"`
+	Title      string  `json:"title"`
+	Thumbnail  string  `json:"thumbnail"`
+	EcoScore   int     `json:"eco_score"`
+	PriceGBP   float64 `json:"price_gbp"`
+	Distance   float64 `json:"distance"`
+	Similarity float64 `json:"similarity"`
+	Reason     string  `json:"reason"`
+	// with debug on /complete-outfit: similarity over the slot's threshold
+	SimilarityMargin *float64 `json:"similarity_margin,omitempty"`
+
+	StockQty *int           `json:"stock_qty,omitempty"` // total across tracked variants
+	Variants []VariantStock `json:"variants,omitempty"`
+	// set with nearest_size when the requested size is sold out
+	SuggestedSize string `json:"suggested_size,omitempty"`
+	// shopper feedback, -1..1, when the product has any; see feedback.go
+	FeedbackScore *float64 `json:"feedback_score,omitempty"`
+
+	EcoLabels      []EcoLabel `json:"eco_labels,omitempty"`
+	EcoScoreSource string     `json:"eco_score_source,omitempty"` // merchant | estimated
+
+	// set when the request includes shopper_region
+	OriginCountry  string   `json:"origin_country,omitempty"`
+	ShippingKm     *float64 `json:"shipping_km,omitempty"`
+	MadeLocally    bool     `json:"made_locally,omitempty"`
+	CarbonEcoScore *int     `json:"carbon_adjusted_eco_score,omitempty"` // eco_score minus a shipping-distance penalty
+
+	// set when the request uses new_arrivals or a recency boost
+	NewArrival bool `json:"new_arrival,omitempty"`
+	// set when the request uses a clearance_mode
+	Clearance bool `json:"clearance,omitempty"`
+	// the weighted ranking components; see ranking.go
+	Scores *ScoreBreakdown `json:"scores,omitempty"`
+	// with expand_duplicates: other listings grouped with this one at indexing
+	Duplicates []DuplicateHit `json:"duplicates,omitempty"`
+	// preorder | discontinued; empty for active products
+	Lifecycle string `json:"lifecycle,omitempty"`
+	ShipsAt   string `json:"ships_at,omitempty"` // preorders: expected ship date, YYYY-MM-DD
+}
+
+type SearchResp struct {
+	Hits   []Hit        `json:"hits"`
+	Intent *QueryIntent `json:"intent,omitempty"` // set when the query was answered without vector search
+	// set when an ambiguous query was searched in its two nearest categories
+	RoutedCategories []string `json:"routed_categories,omitempty"`
+	// pass as "within" to search only these hits; empty if they couldn't be kept
+	ResponseID string `json:"response_id,omitempty"`
+	// set when the query couldn't be embedded and hits are keyword matches
+	Degraded bool `json:"degraded,omitempty"`
+	// the session's temporary constraints, applied where their slots match
+	SessionConstraints []ConstraintRef `json:"session_constraints,omitempty"`
+	Meta               *ResponseMeta   `json:"meta,omitempty"`
+}
+
+// searchParams are the constraints shared by /search and each outfit slot.
+type searchParams struct {
+	Query       string
+	Limit       int
+	MaxPriceGBP float64
+	MinEcoScore int
+	Category    string
+	Attrs       AttrFilters
+	Origin      OriginPrefs
+	Fresh       FreshnessPrefs
+	Clearance   ClearancePrefs
+	Ranking     RankingPrefs
+	Diversity   DiversityPrefs
+	Lifecycle   LifecyclePrefs
+	// list each hit's duplicate listings; they are never hits themselves
+	ExpandDuplicates bool
+	Metric           string    // distance metric; empty = CSA_DISTANCE_METRIC
+	Quality          string    // ANN search quality; empty = CSA_SEARCH_QUALITY
+	GiftOnly         bool      // gift wrap available and not final sale
+	Palette          []string  // any of these colors
+	Structured       bool      // filters only: no embedding, ranked by eco score then price
+	Keyword          bool      // embeddings unavailable: match the query's words; see keyword_search.go
+	Style            []float64 // mean embedding of the shopper's cart, blended into the query
+	SortBy           string    // empty = relevance
+	Within           []string  // only these products, when refining a result set
+}
+
+// vectorless reports whether the search runs without a query embedding.
+func (p searchParams) vectorless() bool { return p.Structured || p.Keyword }
+
+func searchHits(ctx context.Context, pool *pgxpool.Pool, p searchParams) ([]Hit, error) {
+	var qVec any
+	metric := p.metric()
+	if !p.vectorless() {
+		qEmb, err := embedQuery(ctx, p.Query)
+		if err != nil {
+			return nil, err
+		}
+		qVec = embeddings.Literal(blendStyle(qEmb, p.Style))
+	}
+
+	// over-fetch when re-ranking by shipping distance, newness, clearance,
+	// ranking weights or diversity so local, new, clearance, well-scored and
+	// different-looking items can surface
+	fetch := p.Limit
+	if (p.Origin.LocalBoost > 0 && p.Origin.ShopperRegion != "") || p.Fresh.boost() > 0 ||
+		p.Clearance.ClearanceMode == clearancePrefer || (!p.vectorless() && p.Ranking.weightsBeyondSemantic()) ||
+		(!p.vectorless() && p.SortBy == "" && p.Diversity.lambda() < 1) {
+		fetch *= 3
+	}
+	if p.SortBy != "" && !p.vectorless() {
+		fetch = max(fetch, search.SortCandidates(p.Limit))
+	}
+	if p.Attrs.hasExclusions() && cfg().ExclusionCheck == config.ExclusionCheckLLM {
+		fetch *= 2 // the check may drop some
+	}
+
+	attrSQL, attrArgs := p.Attrs.attributesSQL(18)
+	lang := ""
+	if !p.vectorless() {
+		lang = cardLanguage(ctx)
+	}
+	cols, filter := search.HitColumns(metric), search.Filter{
+		Table:       "product_embeddings",
+		Sandbox:     sandboxSQL(ctx, ""),
+		Lifecycle:   lifecycleSQL("", p.Lifecycle.IncludePreorder),
+		SizeInStock: sizeInStockSQL,
+		Attributes:  attrSQL,
+	}
+	if lang != "" {
+		cols, filter.Table = search.CardHitColumns(metric), "product_embeddings JOIN product_cards USING (product_id)"
+	}
+	from := filter.SQL()
+	args := append(append(append([]any{qVec, fetch, search.NullInt(p.MinEcoScore), search.NullNum(p.MaxPriceGBP), search.NullText(p.Category)}, p.Attrs.sqlArgs()...),
+		p.GiftOnly, search.NullList(p.Palette), p.Fresh.NewArrivals, cfg().NewArrivalDays,
+		p.Clearance.ClearanceMode == clearanceOnly, p.Clearance.MinMarginPct, p.Attrs.VerifiedEcoOnly), attrArgs...)
+	if p.Within != nil {
+		args = append(args, p.Within)
+		from += "\n  AND product_id = ANY($" + strconv.Itoa(len(args)) + ")"
+		if lang != "" {
+			from += "\n  AND lang = '" + lang + "'"
+		}
+	}
+	order := search.SortSQL(p.SortBy)
+	if p.Keyword {
+		args = append(args, keywordTerms(p.Query))
+		where, rank := keywordSQL(len(args))
+		from += "\n  AND " + where
+		if p.SortBy == "" {
+			order = rank + ", " + order
+		}
+	}
+
+	var hits []Hit
+	var err error
+	if p.vectorless() {
+		var rows pgx.Rows
+		if rows, err = pool.Query(ctx, "SELECT "+cols+"\n"+from+"\nORDER BY "+order+"\nLIMIT $2", args...); err == nil {
+			hits, err = scanHits(rows, metric)
+			rows.Close()
+		}
+	} else if p.Within != nil {
+		// a known handful of products: rank them all exactly, not through the index
+		var rows pgx.Rows
+		if rows, err = pool.Query(ctx, "SELECT "+cols+"\n"+from+"\nORDER BY distance\nLIMIT $2", args...); err == nil {
+			hits, err = scanHits(rows, metric)
+			rows.Close()
+		}
+	} else if lang != "" {
+		hits, err = queryNearest(ctx, pool, p.quality(), fetch, metric, cardNearestSQL(cols, from, metric, lang), args...)
+	} else {
+		hits, err = queryNearest(ctx, pool, p.quality(), vectorStorage().Rows(fetch), metric, vectorStorage().NearestSQL(cols, from, metric), args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.vectorless() {
+		// nothing was compared, so there is no similarity to report
+		for i := range hits {
+			hits[i].Similarity = 0
+		}
+	} else if hits, err = applyRanking(ctx, pool, hits, p.Ranking, p.MaxPriceGBP); err != nil {
+		return nil, err
+	}
+	hits = applySize(hits, p.Attrs)
+	hits = checkExclusions(ctx, pool, hits, p.Attrs)
+	if hits, err = applyFeedback(ctx, pool, hits, p.Query); err != nil {
+		return nil, err
+	}
+	if hits, err = applyFreshness(ctx, pool, hits, p.Fresh); err != nil {
+		return nil, err
+	}
+	if hits, err = applyClearance(ctx, pool, hits, p.Clearance); err != nil {
+		return nil, err
+	}
+	originLimit := p.Limit
+	if p.SortBy != "" {
+		originLimit = 0 // sort the whole candidate set
+	}
+	hits = applyOrigin(hits, p.Origin, originLimit)
+	if !p.vectorless() && p.SortBy == "" {
+		if hits, err = diversify(ctx, pool, hits, p.Diversity, p.Limit); err != nil {
+			return nil, err
+		}
+	}
+	if hits, err = applySort(ctx, pool, hits, p.SortBy); err != nil {
+		return nil, err
+	}
+	if len(hits) > p.Limit {
+		hits = hits[:p.Limit]
+	}
+	if p.ExpandDuplicates {
+		if err := attachDuplicates(ctx, pool, hits); err != nil {
+			return nil, err
+		}
+	}
+	return hits, nil
+}
+
+// scanHits reads rows selecting search.HitColumns or search.CardHitColumns
+// into hits. Distances are under metric.
+func scanHits(rows pgx.Rows, metric string) ([]Hit, error) {
+	found, err := search.ScanRows(rows, metric)
+	if err != nil {
+		return nil, err
+	}
+	var hits []Hit
+	for _, r := range found {
+		h := Hit{
+			ProductID:      r.ProductID,
+			Title:          r.Title,
+			Thumbnail:      r.Thumbnail,
+			EcoScore:       r.EcoScore,
+			PriceGBP:       r.PriceGBP,
+			Distance:       r.Distance,
+			Similarity:     r.Similarity,
+			StockQty:       r.StockQty,
+			EcoLabels:      ecoLabelsFromIDs(r.EcoLabels),
+			OriginCountry:  r.OriginCountry,
+			EcoScoreSource: r.EcoScoreSource,
+			Lifecycle:      r.Lifecycle,
+			ShipsAt:        r.ShipsAt,
+		}
+		if r.Variants != nil {
+			if err := json.Unmarshal(r.Variants, &h.Variants); err != nil {
+				return nil, err
+			}
+		}
+		if h.Lifecycle == lifecycleActive {
+			h.Lifecycle = "" // only the exceptions are reported
+		}
+		hits = append(hits, h)
+	}
+	return hits, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+func validateSearchQuality(q string) error {
+	if q != "" && !slices.Contains(config.SearchQualities, q) {
+		return httpapi.InvalidField("search_quality", "search_quality must be one of %s", strings.Join(config.SearchQualities, ", "))
+	}
+	return nil
+}
+
+// quality is the search's quality level.
+func (p searchParams) quality() string {
+	if p.Quality != "" {
+		return p.Quality
+	}
+	return cfg().Embed.SearchQuality
+}
+
+// queryNearest runs a nearest-neighbour query at the given quality level
+// and scans its hits, rows being the most it asks the index for. The index
+// settings only last for the query's transaction.
+func queryNearest(ctx context.Context, pool *pgxpool.Pool, quality string, rows int, metric, query string, args ...any) ([]Hit, error) {
+	effort := search.Efforts[quality]
+
+	var hits []Hit
+	err := pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `SELECT set_config('hnsw.ef_search', $1, true), set_config('ivfflat.probes', $2, true)`,
+			strconv.Itoa(effort.EfSearchFor(rows)), strconv.Itoa(effort.Probes)); err != nil {
+			return err
+		}
+		rs, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rs.Close()
+		hits, err = scanHits(rs, metric)
+		return err
+	})
+	return hits, err
+}
@@ -0,0 +1,444 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+)
+
+// Session constraints are temporary needs a shopper mentions in chat ("I'm
+// on crutches today, slip-on shoes only"). Each is read into filters that
+// apply to every /search and /complete-outfit sent with the session's
+// X-Session-ID until it expires. They are kept in session_constraints, go
+// with the session, and never reach the user's profile or history.
+
+// Limits on session constraints.
+const (
+	maxSessionConstraints = 5 // active per session
+	maxConstraintText     = 500
+	maxConstraintTTL      = 7 * 24 * time.Hour
+)
+
+// constraintSweepInterval is how often expired constraints are deleted.
+const constraintSweepInterval = time.Hour
+
+// SessionConstraint is one temporary constraint and the filters read from it.
+type SessionConstraint struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	// the outfit slots it applies to, e.g. ["shoes"]; empty = every search
+	Slots            []string            `json:"slots,omitempty"`
+	ExcludeTerms     []string            `json:"exclude_terms,omitempty"`
+	ExcludeMaterials []string            `json:"exclude_materials,omitempty"`
+	Attributes       map[string][]string `json:"attributes,omitempty"` // any of the values, e.g. {"closure": ["slip-on"]}
+	CreatedAt        time.Time           `json:"created_at"`
+	ExpiresAt        time.Time           `json:"expires_at"`
+}
+
+// ConstraintReq is the body of POST /sessions/{id}/constraints.
+type ConstraintReq struct {
+	Text string `json:"text"` // the shopper's words
+	// how long it lasts; default: what the shopper said, else CSA_SESSION_CONSTRAINT_TTL
+	Hours int `json:"hours,omitempty"`
+}
+
+func (r ConstraintReq) validate() error {
+	var errs httpapi.FieldErrors
+	if t := strings.TrimSpace(r.Text); t == "" || len(t) > maxConstraintText {
+		errs = append(errs, httpapi.FieldError{Field: "text", Message: fmt.Sprintf("text must be 1-%d characters", maxConstraintText)})
+	}
+	if r.Hours < 0 || time.Duration(r.Hours)*time.Hour > maxConstraintTTL {
+		errs = append(errs, httpapi.FieldError{Field: "hours", Message: fmt.Sprintf("hours must be 0-%d", int(maxConstraintTTL.Hours()))})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ConstraintRef names a constraint applied to a response.
+type ConstraintRef struct {
+	ID        string    `json:"id"`
+	Text      string    `json:"text"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func constraintRefs(cs []SessionConstraint) []ConstraintRef {
+	if len(cs) == 0 {
+		return nil
+	}
+	refs := make([]ConstraintRef, len(cs))
+	for i, c := range cs {
+		refs[i] = ConstraintRef{ID: c.ID, Text: c.Text, ExpiresAt: c.ExpiresAt}
+	}
+	return refs
+}
+
+func (c SessionConstraint) empty() bool {
+	return len(c.ExcludeTerms) == 0 && len(c.ExcludeMaterials) == 0 && len(c.Attributes) == 0
+}
+
+// appliesTo reports whether c constrains a search in category; slot-bound
+// constraints only apply once the search has a category.
+func (c SessionConstraint) appliesTo(category string) bool {
+	return len(c.Slots) == 0 || slices.Contains(c.Slots, category)
+}
+
+// constrain returns f with the constraints for category added: exclusions
+// are appended and attributes fill the keys f doesn't set, so what the
+// request asks for explicitly wins. f itself is not modified.
+func constrain(f AttrFilters, cs []SessionConstraint, category string) AttrFilters {
+	for _, c := range cs {
+		if !c.appliesTo(category) {
+			continue
+		}
+		f.ExcludeTerms = slices.Concat(f.ExcludeTerms, c.ExcludeTerms)
+		f.ExcludeMaterials = slices.Concat(f.ExcludeMaterials, c.ExcludeMaterials)
+		if len(c.Attributes) == 0 {
+			continue
+		}
+		attrs := make(map[string]any, len(f.Attributes)+len(c.Attributes))
+		set := map[string]bool{}
+		for k, v := range f.Attributes {
+			attrs[k], set[attributeKey(k)] = v, true
+		}
+		for k, vals := range c.Attributes {
+			if set[k] {
+				continue
+			}
+			list := make([]any, len(vals))
+			for i, v := range vals {
+				list[i] = v
+			}
+			attrs[k] = list
+		}
+		f.Attributes = attrs
+	}
+	return f
+}
+
+func constraintSchema(slots, attrKeys []string) *outputSchema {
+	strs := map[string]any{"type": "array", "items": map[string]any{"type": "string"}}
+	return &outputSchema{Name: "session_constraint", Schema: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"slots":             map[string]any{"type": "array", "items": map[string]any{"type": "string", "enum": slots}},
+			"exclude_terms":     strs,
+			"exclude_materials": strs,
+			"attributes": map[string]any{"type": "array", "items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"name":   map[string]any{"type": "string", "enum": attrKeys},
+					"values": strs,
+				},
+				"required":             []string{"name", "values"},
+				"additionalProperties": false,
+			}},
+			"hours": map[string]any{"type": []string{"integer", "null"}},
+		},
+		"required":             []string{"slots", "exclude_terms", "exclude_materials", "attributes", "hours"},
+		"additionalProperties": false,
+	}}
+}
+
+// knownAttributes lists the tenant's most common indexed attributes with a
+// sample of their values, for the extraction prompt.
+func knownAttributes(ctx context.Context, pool *pgxpool.Pool, tenantID string) (map[string][]string, error) {
+	rows, err := pool.Query(ctx, `
+SELECT a.key, (array_agg(DISTINCT lower(v.val)))[1:15]
+FROM product_embeddings p, jsonb_each(p.attributes) a, jsonb_array_elements_text(a.value) v(val)
+WHERE p.tenant_id = $1 AND jsonb_typeof(a.value) = 'array'
+GROUP BY a.key ORDER BY count(*) DESC LIMIT 30
+`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	known := map[string][]string{}
+	for rows.Next() {
+		var key string
+		var vals []string
+		if err := rows.Scan(&key, &vals); err != nil {
+			return nil, err
+		}
+		known[key] = vals
+	}
+	return known, rows.Err()
+}
+
+// extractConstraint reads filters from a shopper's words. The model's
+// answer is checked against the catalog's attributes; if the model fails,
+// plain "no X" / "without X" phrases are still understood. hours is what
+// the shopper said about how long, 0 if nothing.
+func extractConstraint(ctx context.Context, pool *pgxpool.Pool, text string) (c SessionConstraint, hours int, err error) {
+	tenantID := tenantFromContext(ctx)
+	known, err := knownAttributes(ctx, pool, tenantID)
+	if err != nil {
+		return c, 0, err
+	}
+	keys := make([]string, 0, len(known))
+	for k := range known {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = fmt.Sprintf("- %s: %s", k, strings.Join(known[k], ", "))
+	}
+	if len(keys) == 0 {
+		keys = []string{"none"} // an enum can't be empty
+	}
+
+	prompt := fmt.Sprintf(`A shopper mentioned a temporary need while shopping. Turn it into product filters.
+Outfit slots: %s
+Catalog attributes (name: some values):
+%s
+
+Rules:
+- slots: the slots the need is about, e.g. shoes for "no laces"; empty if it is about everything.
+- exclude_terms: lowercase words a product title must not contain, with their variants, e.g. "laces", "lace-up" for no laces.
+- exclude_materials: materials to avoid, e.g. "wool".
+- attributes: only catalog attributes above, with values from their lists, that products must have, e.g. a slip-on closure.
+- hours: how long the need lasts if the shopper says, e.g. 24 for "today"; otherwise null.
+Leave out anything not implied. Return only the JSON object.
+
+Shopper: %q`, strings.Join(classifierSlots, ", "), strings.Join(lines, "\n"), text)
+
+	provider := ""
+	if ts, err := loadTenantSettings(ctx, pool, tenantID); err == nil {
+		provider = ts.LLMProvider
+	}
+	c.Text = text
+	raw, err := llmChat(ctx, provider, config.LLMConstraints, prompt, constraintSchema(classifierSlots, keys))
+	if err == nil {
+		var out struct {
+			Slots            []string `json:"slots"`
+			ExcludeTerms     []string `json:"exclude_terms"`
+			ExcludeMaterials []string `json:"exclude_materials"`
+			Attributes       []struct {
+				Name   string   `json:"name"`
+				Values []string `json:"values"`
+			} `json:"attributes"`
+			Hours *int `json:"hours"`
+		}
+		if err = json.Unmarshal([]byte(ranking.StripCodeFence(raw)), &out); err == nil {
+			// models don't always honour the enums, so check everything
+			for _, s := range normalizeValues(out.Slots) {
+				if slices.Contains(classifierSlots, s) {
+					c.Slots = append(c.Slots, s)
+				}
+			}
+			c.ExcludeTerms = exclusionList(out.ExcludeTerms)
+			c.ExcludeMaterials = exclusionList(out.ExcludeMaterials)
+			for _, a := range out.Attributes {
+				key := attributeKey(a.Name)
+				for _, v := range normalizeValues(a.Values) {
+					if slices.Contains(known[key], v) {
+						if c.Attributes == nil {
+							c.Attributes = map[string][]string{}
+						}
+						c.Attributes[key] = append(c.Attributes[key], v)
+					}
+				}
+			}
+			if out.Hours != nil && *out.Hours > 0 {
+				hours = min(*out.Hours, int(maxConstraintTTL.Hours()))
+			}
+		}
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "constraints: llm extraction failed, using rules", "err", err)
+		c = ruleConstraint(text)
+	}
+	return c, hours, nil
+}
+
+// exclusionList keeps the entries validateExclusions would accept.
+func exclusionList(in []string) []string {
+	var out []string
+	for _, t := range normalizeValues(in) {
+		if len(t) <= maxExclusionLength && len(out) < maxExclusions {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+var negatedWord = regexp.MustCompile(`\b(?:no|without|not|avoid|avoiding)\s+(?:any\s+)?([a-z][a-z-]+)`)
+
+// ruleConstraint is the fallback reading of text: words after "no",
+// "without" or "avoid" are excluded, also in the singular, and the
+// constraint is bound to the slots the text names.
+func ruleConstraint(text string) SessionConstraint {
+	c := SessionConstraint{Text: text}
+	lower := strings.ToLower(text)
+	var terms []string
+	for _, m := range negatedWord.FindAllStringSubmatch(lower, -1) {
+		w := m[1]
+		if fillerWords[w] {
+			continue
+		}
+		terms = append(terms, w)
+		if s := strings.TrimSuffix(w, "s"); s != w && len(s) > 2 {
+			terms = append(terms, s)
+		}
+	}
+	c.ExcludeTerms = exclusionList(terms)
+	for _, w := range keywordWord.FindAllString(lower, -1) {
+		if s := categoryWords[w]; s != "" && !slices.Contains(c.Slots, s) {
+			c.Slots = append(c.Slots, s)
+		}
+	}
+	return c
+}
+
+// addConstraint stores c on the session for ttl, creating the session row if
+// the storefront hasn't sent a request with it yet.
+func addConstraint(ctx context.Context, pool *pgxpool.Pool, tenantID, sessionID string, c *SessionConstraint, ttl time.Duration) error {
+	return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `
+INSERT INTO sessions (id, tenant_id, residency_zone) VALUES ($1,$2,$3)
+ON CONFLICT (tenant_id, id) DO UPDATE SET last_seen_at=now()
+`, sessionID, tenantID, residencyFrom(ctx)); err != nil {
+			return err
+		}
+		var active int
+		if err := tx.QueryRow(ctx, `
+SELECT count(*) FROM session_constraints WHERE tenant_id=$1 AND session_id=$2 AND expires_at > now()
+`, tenantID, sessionID).Scan(&active); err != nil {
+			return err
+		}
+		if active >= maxSessionConstraints {
+			return httpapi.InvalidField("text", "the session already has %d active constraints; remove one first", maxSessionConstraints)
+		}
+		attrs := []byte("{}")
+		if len(c.Attributes) > 0 {
+			attrs, _ = json.Marshal(c.Attributes)
+		}
+		return tx.QueryRow(ctx, `
+INSERT INTO session_constraints (tenant_id, session_id, text, slots, exclude_terms, exclude_materials, attributes, expires_at)
+VALUES ($1,$2,$3,$4,$5,$6,$7, now() + make_interval(secs => $8))
+RETURNING id, created_at, expires_at
+`, tenantID, sessionID, c.Text, nonNil(c.Slots), nonNil(c.ExcludeTerms), nonNil(c.ExcludeMaterials), attrs, ttl.Seconds()).
+			Scan(&c.ID, &c.CreatedAt, &c.ExpiresAt)
+	})
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// listConstraints returns the session's unexpired constraints, oldest first.
+func listConstraints(ctx context.Context, pool *pgxpool.Pool, tenantID, sessionID string) ([]SessionConstraint, error) {
+	rows, err := pool.Query(ctx, `
+SELECT id, text, slots, exclude_terms, exclude_materials, attributes, created_at, expires_at
+FROM session_constraints
+WHERE tenant_id=$1 AND session_id=$2 AND expires_at > now()
+ORDER BY created_at
+`, tenantID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cs := []SessionConstraint{}
+	for rows.Next() {
+		var c SessionConstraint
+		var attrs []byte
+		if err := rows.Scan(&c.ID, &c.Text, &c.Slots, &c.ExcludeTerms, &c.ExcludeMaterials, &attrs, &c.CreatedAt, &c.ExpiresAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(attrs, &c.Attributes); err != nil {
+			return nil, fmt.Errorf("constraint %s attributes: %w", c.ID, err)
+		}
+		cs = append(cs, c)
+	}
+	return cs, rows.Err()
+}
+
+// deleteConstraint removes one of the session's constraints; false if it
+// doesn't exist.
+func deleteConstraint(ctx context.Context, pool *pgxpool.Pool, tenantID, sessionID, id string) (bool, error) {
+	tag, err := pool.Exec(ctx, `
+DELETE FROM session_constraints WHERE tenant_id=$1 AND session_id=$2 AND id::text=$3
+`, tenantID, sessionID, id)
+	return tag.RowsAffected() > 0, err
+}
+
+// activeConstraints are the constraints of the request's session, if it
+// has one. A lookup failure is logged and reported as a warning: the
+// request is still answered, only without them.
+func activeConstraints(ctx context.Context, pool *pgxpool.Pool) []SessionConstraint {
+	s := sessionFrom(ctx)
+	if s == nil {
+		return nil
+	}
+	cs, err := listConstraints(ctx, pool, s.TenantID, s.ID)
+	if err != nil {
+		slog.WarnContext(ctx, "constraints: lookup failed", "err", err)
+		addWarnings(ctx, "session constraints could not be loaded and were not applied")
+		return nil
+	}
+	if len(cs) > 0 {
+		logOutcome(ctx, slog.Int("session_constraints", len(cs)))
+	}
+	return cs
+}
+
+// newConstraint reads req into a constraint and stores it on the session.
+func newConstraint(ctx context.Context, pool *pgxpool.Pool, tenantID, sessionID string, req ConstraintReq) (SessionConstraint, error) {
+	text := strings.TrimSpace(req.Text)
+	c, hours, err := extractConstraint(ctx, pool, text)
+	if err != nil {
+		return c, err
+	}
+	if c.empty() {
+		return c, httpapi.InvalidField("text", "no product constraint found in %q", text)
+	}
+	ttl := cfg().SessionConstraintTTL
+	switch {
+	case req.Hours > 0:
+		ttl = time.Duration(req.Hours) * time.Hour
+	case hours > 0:
+		ttl = time.Duration(hours) * time.Hour
+	}
+	if err := addConstraint(ctx, pool, tenantID, sessionID, &c, ttl); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// constraintSweepLoop deletes expired constraints every hour until ctx is
+// cancelled.
+func constraintSweepLoop(ctx context.Context, pool *pgxpool.Pool) {
+	t := time.NewTicker(constraintSweepInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		slot := time.Now().Truncate(constraintSweepInterval)
+		if _, err := runScheduled(ctx, pool, "session_constraints_sweep", slot, func(ctx context.Context) error {
+			_, err := pool.Exec(ctx, `DELETE FROM session_constraints WHERE expires_at <= now()`)
+			return err
+		}); err != nil {
+			slog.ErrorContext(ctx, "constraints: sweep", "err", err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestConstrain(t *testing.T) {
+	noLaces := SessionConstraint{ID: "c1", Slots: []string{"shoes"}, ExcludeTerms: []string{"laces", "lace-up"},
+		Attributes: map[string][]string{"closure": {"slip-on"}}}
+	noWool := SessionConstraint{ID: "c2", ExcludeMaterials: []string{"wool"}}
+	req := AttrFilters{ExcludeTerms: []string{"pink"}, Attributes: map[string]any{"Closure": "zip"}}
+	cs := []SessionConstraint{noLaces, noWool}
+
+	shoes := constrain(req, cs, "shoes")
+	if want := []string{"pink", "laces", "lace-up"}; !reflect.DeepEqual(shoes.ExcludeTerms, want) {
+		t.Errorf("shoes exclude_terms = %v, want %v", shoes.ExcludeTerms, want)
+	}
+	if !reflect.DeepEqual(shoes.ExcludeMaterials, []string{"wool"}) {
+		t.Errorf("shoes exclude_materials = %v", shoes.ExcludeMaterials)
+	}
+	// the request's own closure wins
+	if len(shoes.Attributes) != 1 || shoes.Attributes["Closure"] != "zip" {
+		t.Errorf("shoes attributes = %v", shoes.Attributes)
+	}
+
+	tops := constrain(AttrFilters{}, cs, "top")
+	if len(tops.ExcludeTerms) != 0 || len(tops.Attributes) != 0 || !reflect.DeepEqual(tops.ExcludeMaterials, []string{"wool"}) {
+		t.Errorf("top filters = %+v, want only the unscoped constraint", tops)
+	}
+	if got := constrain(AttrFilters{}, []SessionConstraint{noLaces}, "shoes").Attributes["closure"]; !reflect.DeepEqual(got, []any{"slip-on"}) {
+		t.Errorf("closure = %v", got)
+	}
+
+	// the request's filters are left alone
+	if len(req.ExcludeTerms) != 1 || len(req.Attributes) != 1 {
+		t.Errorf("request filters modified: %+v", req)
+	}
+}
+
+func TestRuleConstraint(t *testing.T) {
+	c := ruleConstraint("I'm on crutches today, shoes without laces and no wool please")
+	if want := []string{"lace", "laces", "wool"}; !reflect.DeepEqual(c.ExcludeTerms, want) {
+		t.Errorf("exclude_terms = %v, want %v", c.ExcludeTerms, want)
+	}
+	if !slices.Equal(c.Slots, []string{"shoes"}) {
+		t.Errorf("slots = %v", c.Slots)
+	}
+	if c := ruleConstraint("I'm on crutches today"); !c.empty() {
+		t.Errorf("nothing to exclude, got %+v", c)
+	}
+}
+
+func TestConstraintReqValidate(t *testing.T) {
+	for _, tc := range []struct {
+		req ConstraintReq
+		ok  bool
+	}{
+		{ConstraintReq{Text: "no laces"}, true},
+		{ConstraintReq{Text: "no laces", Hours: 168}, true},
+		{ConstraintReq{Text: "  "}, false},
+		{ConstraintReq{Text: "no laces", Hours: 169}, false},
+		{ConstraintReq{Text: "no laces", Hours: -1}, false},
+	} {
+		if err := tc.req.validate(); (err == nil) != tc.ok {
+			t.Errorf("%+v: err = %v", tc.req, err)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+// Session event kinds stored in session_events.
+const (
+	eventTurn           = "turn"           // one request/response exchange
+	eventToolCall       = "tool_call"      // an LLM call made while answering
+	eventRecommendation = "recommendation" // products shown
+	eventFeedback       = "feedback"       // shopper reaction to a hit
+)
+
+// Export purposes; each needs the matching consent flag on the session.
+const (
+	exportPurposeQA       = "qa"
+	exportPurposeTraining = "training"
+)
+
+// request and response bodies above this are stored truncated
+const maxTranscriptBody = 256 << 10
+
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,128}$`)
+
+// session is the shopper session a request belongs to, if the storefront
+// sent X-Session-ID.
+type session struct {
+	ID       string
+	TenantID string
+}
+
+type sessionKey struct{}
+
+func sessionFrom(ctx context.Context) *session {
+	s, _ := ctx.Value(sessionKey{}).(*session)
+	return s
+}
+
+type SessionInfo struct {
+	ID         string    `json:"id"`
+	TenantID   string    `json:"tenant_id"`
+	UserID     string    `json:"user_id,omitempty"`
+	Consent    Consent   `json:"consent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// Consent records what the shopper agreed their session may be used for.
+type Consent struct {
+	QA       bool `json:"qa"`       // human QA review
+	Training bool `json:"training"` // model fine-tuning datasets
+}
+
+type SessionEvent struct {
+	At      time.Time       `json:"at"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// SessionExport is the bundle returned by /admin/sessions/{id}/export.
+type SessionExport struct {
+	Session         SessionInfo    `json:"session"`
+	Purpose         string         `json:"purpose"`
+	ExportedAt      time.Time      `json:"exported_at"`
+	Turns           []SessionEvent `json:"turns"`
+	ToolCalls       []SessionEvent `json:"tool_calls"`
+	Recommendations []SessionEvent `json:"recommendations"`
+	Feedback        []SessionEvent `json:"feedback"`
+}
+
+var errNoConsent = errors.New("session has no consent for this purpose")
+
+// recordsTranscript reports whether turns on a route belong in the
+// transcript: shopper-facing routes only, not admin or probes.
+func recordsTranscript(pattern string) bool {
+	_, path, _ := strings.Cut(pattern, " ")
+	return path != "" && !strings.HasPrefix(path, "/admin/") && !strings.HasPrefix(path, "/health") &&
+		!strings.HasPrefix(path, "/sessions/") && !strings.HasPrefix(path, "/users/") &&
+		path != "/ws" // its turns are transcribed one by one
+}
+
+// withSession attaches the X-Session-ID session to the request and records
+// the exchange as a transcript turn. It must run directly in front of
+// withRouteName so the matched route is visible after the handler returns.
+func withSession(pool *pgxpool.Pool) httpapi.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := strings.TrimSpace(r.Header.Get("X-Session-ID"))
+			if id == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !sessionIDPattern.MatchString(id) {
+				httpapi.WriteError(w, "X-Session-ID must be 1-128 characters of A-Z a-z 0-9 _ . : -", 400)
+				return
+			}
+
+			// /users/{id}/history and erasure trust the user a session is
+			// tagged with, so only a key may tag one, and only once
+			userID := strings.TrimSpace(r.Header.Get("X-User-ID"))
+			if userID != "" && principalFrom(r.Context()) == nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="csa"`)
+				httpapi.WriteError(w, "X-User-ID requires an API key", http.StatusUnauthorized)
+				return
+			}
+
+			s := &session{ID: id, TenantID: tenantFromRequest(r)}
+			var bound *string
+			err := pool.QueryRow(r.Context(), `
+INSERT INTO sessions (id, tenant_id, user_id, residency_zone) VALUES ($1,$2,$3,$4)
+ON CONFLICT (tenant_id, id) DO UPDATE
+SET last_seen_at=now(), user_id=COALESCE(sessions.user_id, EXCLUDED.user_id)
+RETURNING user_id
+`, s.ID, s.TenantID, search.NullText(userID), residencyFrom(r.Context())).Scan(&bound)
+			if err != nil {
+				// transcripts are best effort; never fail the shopper's request
+				slog.WarnContext(r.Context(), "sessions: upsert failed", "err", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if userID != "" && (bound == nil || *bound != userID) {
+				httpapi.WriteError(w, "X-Session-ID belongs to another user", http.StatusConflict)
+				return
+			}
+
+			reqBody, _ := io.ReadAll(io.LimitReader(r.Body, maxTranscriptBody+1))
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(reqBody), r.Body))
+
+			start := time.Now()
+			rec := &teeRecorder{ResponseWriter: w}
+			sr := r.WithContext(context.WithValue(r.Context(), sessionKey{}, s))
+			next.ServeHTTP(rec, sr)
+
+			if !recordsTranscript(sr.Pattern) {
+				return
+			}
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			s.record(sr.Context(), eventTurn, map[string]any{
+				"route":       sr.Pattern,
+				"query":       r.URL.RawQuery,
+				"request":     transcriptBody(reqBody),
+				"status":      rec.status,
+				"response":    transcriptBody(rec.body.Bytes()),
+				"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+			})
+		})
+	}
+}
+
+// teeRecorder copies the first maxTranscriptBody bytes of the response.
+type teeRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (t *teeRecorder) WriteHeader(code int) {
+	t.status = code
+	t.ResponseWriter.WriteHeader(code)
+}
+
+func (t *teeRecorder) Write(b []byte) (int, error) {
+	if room := maxTranscriptBody + 1 - t.body.Len(); room > 0 {
+		t.body.Write(b[:min(len(b), room)])
+	}
+	return t.ResponseWriter.Write(b)
+}
+
+func (t *teeRecorder) Flush() {
+	http.NewResponseController(t.ResponseWriter).Flush()
+}
+
+func (t *teeRecorder) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
+
+// transcriptBody keeps JSON bodies as JSON and anything else as a string.
+func transcriptBody(b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+	if len(b) > maxTranscriptBody {
+		return map[string]any{"truncated": true, "prefix": string(b[:4096])}
+	}
+	if json.Valid(b) {
+		return json.RawMessage(b)
+	}
+	return string(b)
+}
+
+// record appends an event to the session. It is queued for
+// sessionFlushLoop, so recording costs the request no database round trip.
+func (s *session) record(ctx context.Context, kind string, payload any) {
+	if s == nil {
+		return
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		slog.WarnContext(ctx, "sessions: record event failed", "kind", kind, "err", err)
+		return
+	}
+	sessionLog.add(pendingEvent{tenantID: s.TenantID, sessionID: s.ID, kind: kind, payload: b, at: time.Now()})
+}
+
+// sessionEventLog holds recorded events until they are written, every
+// sessionFlushInterval and at shutdown. Past maxPendingEvents (the database
+// is down, say) new events are dropped and counted.
+type sessionEventLog struct {
+	mu      sync.Mutex
+	pending []pendingEvent
+	dropped int
+}
+
+type pendingEvent struct {
+	tenantID, sessionID, kind string
+	payload                   []byte
+	at                        time.Time
+}
+
+const (
+	sessionFlushInterval = time.Second
+	maxPendingEvents     = 10000
+	// sessionSweepInterval is how often sessions past CSA_SESSION_RETENTION
+	// are deleted.
+	sessionSweepInterval = time.Hour
+)
+
+var sessionLog = &sessionEventLog{}
+
+func (l *sessionEventLog) add(e pendingEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.pending) >= maxPendingEvents {
+		l.dropped++
+		return
+	}
+	l.pending = append(l.pending, e)
+}
+
+// flush writes the pending events in the order they were recorded. Events
+// of sessions deleted meanwhile (erased, say) are skipped. On failure the
+// events are put back for the next flush.
+func (l *sessionEventLog) flush(ctx context.Context, pool *pgxpool.Pool) error {
+	l.mu.Lock()
+	pending, dropped := l.pending, l.dropped
+	l.pending, l.dropped = nil, 0
+	l.mu.Unlock()
+	if dropped > 0 {
+		slog.WarnContext(ctx, "sessions: event queue full, events dropped", "dropped", dropped)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	b := &pgx.Batch{}
+	for _, e := range pending {
+		b.Queue(`
+INSERT INTO session_events (tenant_id, session_id, kind, payload, created_at)
+SELECT $1, $2, $3, $4, $5
+WHERE EXISTS (SELECT 1 FROM sessions WHERE tenant_id=$1 AND id=$2)
+`, e.tenantID, e.sessionID, e.kind, e.payload, e.at)
+	}
+	if err := pool.SendBatch(ctx, b).Close(); err != nil {
+		l.mu.Lock()
+		l.pending = append(pending, l.pending...)
+		if over := len(l.pending) - maxPendingEvents; over > 0 {
+			l.pending, l.dropped = l.pending[over:], l.dropped+over
+		}
+		l.mu.Unlock()
+		return fmt.Errorf("save session events: %w", err)
+	}
+	return nil
+}
+
+// sessionFlushLoop writes recorded events until ctx is done; main flushes
+// the rest once in-flight requests have finished.
+func sessionFlushLoop(ctx context.Context, pool *pgxpool.Pool) {
+	t := time.NewTicker(sessionFlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if err := sessionLog.flush(ctx, pool); err != nil {
+			slog.ErrorContext(ctx, "sessions: flush", "err", err)
+		}
+	}
+}
+
+// sessionSweepLoop deletes sessions not seen for CSA_SESSION_RETENTION,
+// with their transcripts and constraints, on one replica per interval.
+func sessionSweepLoop(ctx context.Context, pool *pgxpool.Pool) {
+	t := time.NewTicker(sessionSweepInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		slot := time.Now().Truncate(sessionSweepInterval)
+		if _, err := runScheduled(ctx, pool, "session_sweep", slot, func(ctx context.Context) error {
+			_, err := pool.Exec(ctx, `DELETE FROM sessions WHERE last_seen_at < now() - make_interval(secs => $1)`,
+				cfg().SessionRetention.Seconds())
+			return err
+		}); err != nil {
+			slog.ErrorContext(ctx, "sessions: sweep", "err", err)
+		}
+	}
+}
+
+func setSessionConsent(ctx context.Context, pool *pgxpool.Pool, tenantID, id string, c Consent) error {
+	_, err := pool.Exec(ctx, `
+INSERT INTO sessions (id, tenant_id, consent_qa, consent_training, residency_zone) VALUES ($1,$2,$3,$4,$5)
+ON CONFLICT (tenant_id, id) DO UPDATE
+SET consent_qa=EXCLUDED.consent_qa, consent_training=EXCLUDED.consent_training
+`, id, tenantID, c.QA, c.Training, residencyFrom(ctx))
+	return err
+}
+
+// exportSession bundles a session for QA or training. It returns nil when
+// the session doesn't exist and errNoConsent when the purpose isn't allowed.
+func exportSession(ctx context.Context, pool *pgxpool.Pool, tenantID, id, purpose string) (*SessionExport, error) {
+	ex := &SessionExport{
+		Purpose:         purpose,
+		ExportedAt:      time.Now().UTC(),
+		Turns:           []SessionEvent{},
+		ToolCalls:       []SessionEvent{},
+		Recommendations: []SessionEvent{},
+		Feedback:        []SessionEvent{},
+	}
+	si := &ex.Session
+	var userID *string
+	err := pool.QueryRow(ctx, `
+SELECT id, tenant_id, user_id, consent_qa, consent_training, created_at, last_seen_at
+FROM sessions WHERE tenant_id=$1 AND id=$2
+`, tenantID, id).Scan(&si.ID, &si.TenantID, &userID, &si.Consent.QA, &si.Consent.Training, &si.CreatedAt, &si.LastSeenAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if userID != nil {
+		si.UserID = *userID
+	}
+	switch purpose {
+	case exportPurposeQA:
+		if !si.Consent.QA {
+			return nil, errNoConsent
+		}
+	case exportPurposeTraining:
+		if !si.Consent.Training {
+			return nil, errNoConsent
+		}
+	default:
+		return nil, fmt.Errorf("purpose must be %s or %s", exportPurposeQA, exportPurposeTraining)
+	}
+
+	rows, err := pool.Query(ctx, `
+SELECT kind, payload, created_at FROM session_events
+WHERE tenant_id=$1 AND session_id=$2
+ORDER BY id
+`, tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind string
+		var ev SessionEvent
+		if err := rows.Scan(&kind, &ev.Payload, &ev.At); err != nil {
+			return nil, err
+		}
+		switch kind {
+		case eventTurn:
+			ex.Turns = append(ex.Turns, ev)
+		case eventToolCall:
+			ex.ToolCalls = append(ex.ToolCalls, ev)
+		case eventRecommendation:
+			ex.Recommendations = append(ex.Recommendations, ev)
+		case eventFeedback:
+			ex.Feedback = append(ex.Feedback, ev)
+		}
+	}
+	return ex, rows.Err()
+}
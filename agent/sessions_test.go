@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecordQueuesEventsUpToCap(t *testing.T) {
+	old := sessionLog
+	sessionLog = &sessionEventLog{}
+	t.Cleanup(func() { sessionLog = old })
+
+	s := &session{ID: "s1", TenantID: "t1"}
+	for range maxPendingEvents + 3 {
+		s.record(context.Background(), "search", map[string]string{"q": "boots"})
+	}
+	if n := len(sessionLog.pending); n != maxPendingEvents {
+		t.Errorf("pending = %d, want %d", n, maxPendingEvents)
+	}
+	if sessionLog.dropped != 3 {
+		t.Errorf("dropped = %d, want 3", sessionLog.dropped)
+	}
+	e := sessionLog.pending[0]
+	if e.tenantID != "t1" || e.sessionID != "s1" || e.kind != "search" || string(e.payload) != `{"q":"boots"}` {
+		t.Errorf("first event = %+v", e)
+	}
+
+	var nilSession *session
+	nilSession.record(context.Background(), "search", nil) // no session, no event
+}
+
+func TestTeeRecorderFlushes(t *testing.T) {
+	w := httptest.NewRecorder()
+	var rw http.ResponseWriter = &teeRecorder{ResponseWriter: w}
+	if err := http.NewResponseController(rw).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Flushed {
+		t.Error("underlying writer not flushed")
+	}
+}
+
+func TestWithSessionUserNeedsKey(t *testing.T) {
+	reached := false
+	h := withSession(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { reached = true }))
+	r := httptest.NewRequest("POST", "/search", nil)
+	r.Header.Set("X-Session-ID", "s1")
+	r.Header.Set("X-User-ID", "u1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized || reached {
+		t.Errorf("X-User-ID without a key = %d, handler reached %v", w.Code, reached)
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// Response signing algorithms. HMAC needs the secret shared with each
+// verifier; Ed25519 lets anyone verify with the published public key.
+const (
+	signHMAC    = "hmac-sha256"
+	signEd25519 = "ed25519"
+)
+
+// signatureHeader carries "t=<unix>,kid=<key id>,alg=<alg>,sig=<base64>".
+// The signed message is
+//
+//	v1\n<t>\n<METHOD> <request URI>\n<body>
+//
+// so a signed body can't be replayed as the answer to a different request.
+const signatureHeader = "X-CSA-Signature"
+
+type SigningKey struct {
+	ID        string     `json:"id"`
+	TenantID  string     `json:"tenant_id"`
+	Alg       string     `json:"alg"`
+	PublicKey string     `json:"public_key,omitempty"` // base64, ed25519 only
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CreateSigningKeyReq struct {
+	Alg string `json:"alg"`
+}
+
+type CreateSigningKeyResp struct {
+	SigningKey
+	Secret string `json:"secret,omitempty"` // base64 HMAC key, only ever returned once
+}
+
+// activeSigner is the key currently signing a tenant's responses.
+type activeSigner struct {
+	id     string
+	alg    string
+	secret []byte
+}
+
+// signerSet holds every tenant's active key, loaded in one query and
+// reused for keyCacheTTL. A tenant missing from it has signing off, so
+// X-Tenant-ID values that name no signing tenant cost neither a query nor
+// memory. Like keyCache, rotations made on other replicas apply within a
+// minute.
+type signerSet struct {
+	mu       sync.Mutex
+	byTenant map[string]*activeSigner
+	expires  time.Time
+	loading  bool // one caller reloads; the others use the previous set
+}
+
+// signerRetry is how long a failed load is kept before trying again.
+const signerRetry = 5 * time.Second
+
+var signers = &signerSet{}
+
+// invalidate makes the next lookup reload, after this replica changed keys.
+func (c *signerSet) invalidate() {
+	c.mu.Lock()
+	c.expires = time.Time{}
+	c.mu.Unlock()
+}
+
+func (c *signerSet) get(ctx context.Context, pool *pgxpool.Pool, tenantID string) (*activeSigner, error) {
+	c.mu.Lock()
+	if c.byTenant != nil && (c.loading || time.Now().Before(c.expires)) {
+		s := c.byTenant[tenantID]
+		c.mu.Unlock()
+		return s, nil
+	}
+	c.loading = true
+	c.mu.Unlock()
+
+	loaded, err := loadSigners(ctx, pool)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loading = false
+	if err != nil {
+		if c.byTenant == nil {
+			c.byTenant = map[string]*activeSigner{}
+		}
+		c.expires = time.Now().Add(signerRetry)
+		return c.byTenant[tenantID], err
+	}
+	c.byTenant, c.expires = loaded, time.Now().Add(keyCacheTTL)
+	return loaded[tenantID], nil
+}
+
+// loadSigners reads the newest unrevoked key of every tenant that has one.
+func loadSigners(ctx context.Context, pool *pgxpool.Pool) (map[string]*activeSigner, error) {
+	rows, err := pool.Query(ctx, `
+SELECT DISTINCT ON (tenant_id) tenant_id, id::text, alg, secret FROM signing_keys
+WHERE revoked_at IS NULL
+ORDER BY tenant_id, created_at DESC
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]*activeSigner{}
+	for rows.Next() {
+		var tenant string
+		s := &activeSigner{}
+		if err := rows.Scan(&tenant, &s.id, &s.alg, &s.secret); err != nil {
+			return nil, err
+		}
+		out[tenant] = s
+	}
+	return out, rows.Err()
+}
+
+func createSigningKey(ctx context.Context, pool *pgxpool.Pool, tenantID, alg string) (CreateSigningKeyResp, error) {
+	resp := CreateSigningKeyResp{SigningKey: SigningKey{TenantID: tenantID, Alg: alg}}
+	var secret, pub []byte
+	switch alg {
+	case signHMAC:
+		secret = make([]byte, 32)
+		rand.Read(secret)
+		resp.Secret = base64.StdEncoding.EncodeToString(secret)
+	case signEd25519:
+		pk, sk, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return resp, err
+		}
+		secret, pub = sk.Seed(), pk
+		resp.PublicKey = base64.StdEncoding.EncodeToString(pk)
+	default:
+		return resp, fmt.Errorf("alg must be %s or %s", signHMAC, signEd25519)
+	}
+
+	err := pool.QueryRow(ctx, `
+INSERT INTO signing_keys (tenant_id, alg, secret, public_key)
+VALUES ($1,$2,$3,$4)
+RETURNING id::text, created_at
+`, tenantID, alg, secret, pub).Scan(&resp.ID, &resp.CreatedAt)
+	if err == nil {
+		signers.invalidate()
+	}
+	return resp, err
+}
+
+// listSigningKeys returns a tenant's keys without secrets, newest first.
+func listSigningKeys(ctx context.Context, pool *pgxpool.Pool, tenantID string, activeOnly bool) ([]SigningKey, error) {
+	rows, err := pool.Query(ctx, `
+SELECT id::text, tenant_id, alg, public_key, created_at, revoked_at
+FROM signing_keys
+WHERE tenant_id=$1 AND (NOT $2 OR revoked_at IS NULL)
+ORDER BY created_at DESC
+`, tenantID, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []SigningKey{}
+	for rows.Next() {
+		var k SigningKey
+		var pub []byte
+		if err := rows.Scan(&k.ID, &k.TenantID, &k.Alg, &pub, &k.CreatedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		if len(pub) > 0 {
+			k.PublicKey = base64.StdEncoding.EncodeToString(pub)
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+func revokeSigningKey(ctx context.Context, pool *pgxpool.Pool, tenantID, id string) (bool, error) {
+	tag, err := pool.Exec(ctx, `
+UPDATE signing_keys SET revoked_at=now()
+WHERE id::text=$1 AND tenant_id=$2 AND revoked_at IS NULL
+`, id, tenantID)
+	if err != nil {
+		return false, err
+	}
+	signers.invalidate()
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *activeSigner) sign(ts int64, method, uri string, body []byte) string {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "v1\n%d\n%s %s\n", ts, method, uri)
+	msg.Write(body)
+
+	var sig []byte
+	switch s.alg {
+	case signEd25519:
+		sig = ed25519.Sign(ed25519.NewKeyFromSeed(s.secret), msg.Bytes())
+	default:
+		mac := hmac.New(sha256.New, s.secret)
+		mac.Write(msg.Bytes())
+		sig = mac.Sum(nil)
+	}
+	return "t=" + strconv.FormatInt(ts, 10) + ",kid=" + s.id + ",alg=" + s.alg +
+		",sig=" + base64.StdEncoding.EncodeToString(sig)
+}
+
+// bufferedResponse holds a response until its signature can be computed.
+// A handler that flushes is streaming, so the body can't be held until it
+// ends: the first Flush sends what was buffered unsigned and passes the
+// rest straight through.
+type bufferedResponse struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	streamed bool
+}
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	if b.streamed {
+		return
+	}
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.streamed {
+		return b.ResponseWriter.Write(p)
+	}
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) Flush() {
+	if !b.streamed {
+		b.streamed = true
+		if b.status == 0 {
+			b.status = http.StatusOK
+		}
+		b.ResponseWriter.WriteHeader(b.status)
+		b.ResponseWriter.Write(b.body.Bytes())
+		b.body = bytes.Buffer{}
+	}
+	http.NewResponseController(b.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (b *bufferedResponse) Unwrap() http.ResponseWriter {
+	return b.ResponseWriter
+}
+
+// withSigning signs every response body for tenants that have a signing
+// key. Tenants without one get responses untouched and unbuffered, and
+// streamed responses go out unsigned.
+func withSigning(pool *pgxpool.Pool) httpapi.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			signer, err := signers.get(r.Context(), pool, tenantFromRequest(r))
+			if err != nil {
+				// serve unsigned rather than failing health checks with the DB;
+				// verifiers reject unsigned responses anyway
+				slog.ErrorContext(r.Context(), "signing: key lookup failed", "err", err)
+			}
+			// a WebSocket has no response body to sign, and the buffer
+			// would hide the connection from the upgrade
+			if signer == nil || r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			buf := &bufferedResponse{ResponseWriter: w}
+			next.ServeHTTP(buf, r)
+			if buf.streamed {
+				slog.DebugContext(r.Context(), "signing: streamed response sent unsigned")
+				return
+			}
+			if buf.status == 0 {
+				buf.status = http.StatusOK
+			}
+			w.Header().Set(signatureHeader, signer.sign(time.Now().Unix(), r.Method, r.URL.RequestURI(), buf.body.Bytes()))
+			w.WriteHeader(buf.status)
+			w.Write(buf.body.Bytes())
+		})
+	}
+}
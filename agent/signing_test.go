@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useSigners gives the test a loaded signer set, so no lookup reaches the
+// database.
+func useSigners(t *testing.T, byTenant map[string]*activeSigner) {
+	t.Helper()
+	prev := signers
+	signers = &signerSet{byTenant: byTenant, expires: time.Now().Add(time.Hour)}
+	t.Cleanup(func() { signers = prev })
+}
+
+func signedRequest(tenant string, h http.HandlerFunc) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", "/export-embeddings", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ctxTenant, tenant))
+	w := httptest.NewRecorder()
+	withSigning(nil)(h).ServeHTTP(w, r)
+	return w
+}
+
+func TestSigningBuffersUnlessStreamed(t *testing.T) {
+	useSigners(t, map[string]*activeSigner{"acme": {id: "k1", alg: signHMAC, secret: []byte("secret")}})
+
+	w := signedRequest("acme", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ok":true}`))
+	})
+	if w.Code != http.StatusCreated || w.Body.String() != `{"ok":true}` || !strings.Contains(w.Header().Get(signatureHeader), "kid=k1") {
+		t.Errorf("buffered: %d %q, signature %q", w.Code, w.Body.String(), w.Header().Get(signatureHeader))
+	}
+
+	w = signedRequest("acme", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("line 1\n"))
+		http.NewResponseController(w).Flush()
+		w.Write([]byte("line 2\n"))
+	})
+	if w.Body.String() != "line 1\nline 2\n" || !w.Flushed || w.Header().Get(signatureHeader) != "" {
+		t.Errorf("streamed: %q flushed=%v signature %q", w.Body.String(), w.Flushed, w.Header().Get(signatureHeader))
+	}
+
+	// tenants without a key, real or made up, are neither signed nor looked up
+	w = signedRequest("made-up-tenant", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("plain"))
+	})
+	if w.Body.String() != "plain" || w.Header().Get(signatureHeader) != "" {
+		t.Errorf("unsigned tenant: %q signature %q", w.Body.String(), w.Header().Get(signatureHeader))
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A size filter matches products with an in-stock variant in that size,
+// read from the variant's size option at indexing and inventory sync.
+// Products indexed before variants carried sizes fall back to the sizes
+// column, which lists every size the product comes in, sold out or not.
+const sizeInStockSQL = `CASE WHEN jsonb_path_exists(COALESCE(variant_availability, '[]'), '$[*].size')
+       THEN EXISTS (SELECT 1 FROM jsonb_array_elements(variant_availability) v
+                    WHERE (v->>'in_stock')::bool AND v->>'size' = ANY($6))
+       ELSE sizes && $6 END`
+
+// letterSizes are letter sizes from smallest to largest.
+var letterSizes = []string{"xxxs", "xxs", "xs", "s", "m", "l", "xl", "xxl", "xxxl"}
+
+var sizeAliases = map[string]string{
+	"3xs": "xxxs", "2xs": "xxs", "2xl": "xxl", "3xl": "xxxl",
+	"extra small": "xs", "small": "s", "medium": "m", "large": "l",
+	"x-large": "xl", "extra large": "xl", "xx-large": "xxl",
+}
+
+func isSizeOption(title string) bool {
+	switch strings.ToLower(strings.TrimSpace(title)) {
+	case "size", "sizes", "shoe size":
+		return true
+	}
+	return false
+}
+
+// normalizeSize lowercases a size and spells letter sizes one way, so
+// "Medium", "M" and "m" all match.
+func normalizeSize(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if a, ok := sizeAliases[s]; ok {
+		return a
+	}
+	return s
+}
+
+// nearestSizes lists the sizes next to s, closest first: one letter size
+// up, then down; for numeric sizes, half and whole steps and the two-step
+// gaps of waist sizes. Other size schemes have no neighbours.
+func nearestSizes(s string) []string {
+	s = normalizeSize(s)
+	if i := slices.Index(letterSizes, s); i >= 0 {
+		var out []string
+		if i+1 < len(letterSizes) {
+			out = append(out, letterSizes[i+1])
+		}
+		if i > 0 {
+			out = append(out, letterSizes[i-1])
+		}
+		return out
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n <= 0 {
+		return nil
+	}
+	var out []string
+	for _, d := range []float64{0.5, 1, 2} {
+		for _, v := range []float64{n + d, n - d} {
+			if v > 0 {
+				out = append(out, strconv.FormatFloat(v, 'f', -1, 64))
+			}
+		}
+	}
+	return out
+}
+
+// acceptedSizes are the sizes a hit may be in: the requested one, and with
+// NearestSize its neighbours.
+func (f AttrFilters) acceptedSizes() []string {
+	if strings.TrimSpace(f.Size) == "" {
+		return nil
+	}
+	out := []string{normalizeSize(f.Size)}
+	if f.NearestSize {
+		out = append(out, nearestSizes(f.Size)...)
+	}
+	return out
+}
+
+// applySize marks hits only available in a neighbouring size with the
+// closest one in stock and moves them after the exact-size hits.
+func applySize(hits []Hit, f AttrFilters) []Hit {
+	if !f.NearestSize || strings.TrimSpace(f.Size) == "" {
+		return hits
+	}
+	want := normalizeSize(f.Size)
+	for i := range hits {
+		inStock := map[string]bool{}
+		for _, v := range hits[i].Variants {
+			if v.Size != "" && v.InStock {
+				inStock[v.Size] = true
+			}
+		}
+		if len(inStock) == 0 || inStock[want] {
+			continue // exact, or no per-variant sizes to tell
+		}
+		for _, s := range nearestSizes(want) {
+			if inStock[s] {
+				hits[i].SuggestedSize = s
+				break
+			}
+		}
+	}
+	slices.SortStableFunc(hits, func(a, b Hit) int {
+		switch {
+		case a.SuggestedSize == "" && b.SuggestedSize != "":
+			return -1
+		case a.SuggestedSize != "" && b.SuggestedSize == "":
+			return 1
+		}
+		return 0
+	})
+	return hits
+}
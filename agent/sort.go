@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/search"
+)
+
+func validateSortBy(s string) error {
+	for _, o := range search.SortOrders {
+		if s == o {
+			return nil
+		}
+	}
+	if s == "" {
+		return nil
+	}
+	return httpapi.InvalidField("sort_by", "sort_by must be one of %v", search.SortOrders)
+}
+
+// applySort orders hits by sortBy, breaking ties by ranking score and then
+// product ID so the order is stable across requests.
+func applySort(ctx context.Context, pool *pgxpool.Pool, hits []Hit, sortBy string) ([]Hit, error) {
+	if sortBy == "" || len(hits) < 2 {
+		return hits, nil
+	}
+
+	var key func(h Hit) float64 // larger sorts first
+	switch sortBy {
+	case search.SortPriceAsc:
+		key = func(h Hit) float64 { return -h.PriceGBP }
+	case search.SortPriceDesc:
+		key = func(h Hit) float64 { return h.PriceGBP }
+	case search.SortEcoDesc:
+		key = func(h Hit) float64 { return float64(h.EcoScore) }
+	case search.SortNewest, search.SortPopularity:
+		keys, err := sortKeys(ctx, pool, hits, sortBy)
+		if err != nil {
+			return nil, err
+		}
+		key = func(h Hit) float64 { return keys[h.ProductID] }
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if ka, kb := key(a), key(b); ka != kb {
+			return ka > kb
+		}
+		if a.rankScore() != b.rankScore() {
+			return a.rankScore() > b.rankScore()
+		}
+		return a.ProductID < b.ProductID
+	})
+	return hits, nil
+}
+
+// sortKeys loads first-index times or recent serve counts for hits.
+// Products without one sort last.
+func sortKeys(ctx context.Context, pool *pgxpool.Pool, hits []Hit, sortBy string) (map[string]float64, error) {
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ProductID
+	}
+	query := `SELECT product_id, first_indexed_at FROM product_embeddings WHERE product_id = ANY($1) AND first_indexed_at IS NOT NULL`
+	if sortBy == search.SortPopularity {
+		query = `SELECT product_id, served FROM mv_trending_by_category WHERE product_id = ANY($1)`
+	}
+	rows, err := pool.Query(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[string]float64, len(hits))
+	for rows.Next() {
+		var id string
+		if sortBy == search.SortPopularity {
+			var served int
+			if err := rows.Scan(&id, &served); err != nil {
+				return nil, err
+			}
+			keys[id] = float64(served)
+			continue
+		}
+		var first time.Time
+		if err := rows.Scan(&id, &first); err != nil {
+			return nil, err
+		}
+		keys[id] = float64(first.Unix())
+	}
+	return keys, rows.Err()
+}
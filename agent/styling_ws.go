@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// GET /ws holds a styling session open over a WebSocket. The client sends
+// an outfit request and then refinements to it; after each, the agent
+// sends the updated outfit and, if asked, its explanation. Every turn runs
+// POST /complete-outfit (and /explain-outfit) in-process through the full
+// middleware chain, with the headers the socket was opened with, so it is
+// authorized, validated, cached, logged and transcribed like a plain HTTP
+// request.
+//
+// Client messages:
+//
+//	{"type": "outfit", "request": {...}, "explain": true}  start over with a /complete-outfit body
+//	{"type": "refine", "changes": {...}, "explain": true}  merge changes into the current request; null removes a field
+//	{"type": "constraint", "text": "no laces today"}       add a session constraint and redo the current outfit
+//
+// Constraints need the socket to be opened with X-Session-ID; see
+// session_constraints.go.
+//
+// Agent messages carry the turn they answer:
+//
+//	{"type": "outfit", "turn": 2, "request": {...}, "outfit": {...}}
+//	{"type": "constraint", "turn": 2, "constraint": {...}}
+//	{"type": "explanation", "turn": 2, "bullets": [...]}
+//	{"type": "error", "turn": 2, "error": {"code": ..., "message": ...}}
+//
+// A message that arrives while a turn is running supersedes it: the turn
+// is cancelled and nothing more is sent for it.
+
+// maxStylingMessage bounds one client message, as maxJSONBody does a request.
+const maxStylingMessage = maxJSONBody
+
+// stylingWriteTimeout closes the socket of a client that stops reading.
+const stylingWriteTimeout = 10 * time.Second
+
+type stylingClientMsg struct {
+	Type    string          `json:"type"` // outfit | refine | constraint
+	Request json.RawMessage `json:"request,omitempty"`
+	Changes json.RawMessage `json:"changes,omitempty"`
+	Text    string          `json:"text,omitempty"`
+	Explain bool            `json:"explain,omitempty"`
+}
+
+type stylingAgentMsg struct {
+	Type       string              `json:"type"` // outfit | constraint | explanation | error
+	Turn       int                 `json:"turn"`
+	Request    json.RawMessage     `json:"request,omitempty"` // the request this outfit answers, refinements applied
+	Outfit     *CompleteOutfitResp `json:"outfit,omitempty"`
+	Constraint *SessionConstraint  `json:"constraint,omitempty"`
+	Bullets    []string            `json:"bullets,omitempty"`
+	Error      *httpapi.ErrorResp  `json:"error,omitempty"`
+}
+
+// stylingSocket serves /ws. api is the whole HTTP handler each turn runs
+// through; it is a func because the handler is built after the routes.
+func stylingSocket(api func() http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: wsOriginPatterns()})
+		if err != nil {
+			return // Accept has answered
+		}
+		defer conn.CloseNow()
+		conn.SetReadLimit(maxStylingMessage)
+
+		s := &stylingSession{conn: conn, api: api(), upgrade: r}
+		err = s.run(r.Context())
+		logOutcome(r.Context(), slog.Int("turns", s.turn))
+		switch websocket.CloseStatus(err) {
+		case websocket.StatusNormalClosure, websocket.StatusGoingAway:
+			conn.Close(websocket.StatusNormalClosure, "")
+		default:
+			if errors.Is(err, errStylingIdle) {
+				conn.Close(websocket.StatusPolicyViolation, "idle timeout")
+				return
+			}
+			slog.DebugContext(r.Context(), "styling: session ended", "err", err)
+			conn.Close(websocket.StatusInternalError, "")
+		}
+	}
+}
+
+// wsOriginPatterns lets browsers on CSA_CORS_ORIGINS open sockets; the
+// same host is always allowed.
+func wsOriginPatterns() []string {
+	var out []string
+	for _, o := range cfg().CORSOrigins {
+		if o == "*" {
+			return []string{"*"}
+		}
+		if u, err := url.Parse(o); err == nil && u.Host != "" {
+			out = append(out, u.Host)
+		}
+	}
+	return out
+}
+
+var errStylingIdle = errors.New("styling: idle timeout")
+
+type stylingSession struct {
+	conn    *websocket.Conn
+	api     http.Handler
+	upgrade *http.Request // headers for every turn
+
+	ctx     context.Context // the socket's; a write cancelled midway closes it
+	turn    int
+	request map[string]any // the current /complete-outfit body
+}
+
+// run reads client messages until the socket closes, starting a turn for
+// each and cancelling the one it supersedes.
+func (s *stylingSession) run(ctx context.Context) error {
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	s.ctx = ctx
+	msgs := make(chan stylingClientMsg)
+	readErr := make(chan error, 1)
+	go func() {
+		for {
+			var m stylingClientMsg
+			if err := wsjson.Read(ctx, s.conn, &m); err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case msgs <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	stopTurn := func() {} // cancels the running turn and waits for it
+	defer func() { stopTurn() }()
+	idle := time.NewTimer(cfg().StylingIdleTimeout)
+	defer idle.Stop()
+	for {
+		select {
+		case m := <-msgs:
+			idle.Reset(cfg().StylingIdleTimeout)
+			stopTurn() // a superseded turn sends nothing more
+			s.turn++
+			turnCtx, cancel := context.WithCancel(ctx)
+			done := make(chan struct{})
+			stopTurn = func() {
+				cancel()
+				<-done
+			}
+			go func(turn int) {
+				defer close(done)
+				s.play(turnCtx, turn, m)
+			}(s.turn)
+		case err := <-readErr:
+			return err
+		case <-idle.C:
+			return errStylingIdle
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// play answers one client message.
+func (s *stylingSession) play(ctx context.Context, turn int, m stylingClientMsg) {
+	if m.Type == "constraint" {
+		if !s.addConstraint(ctx, turn, m.Text) || s.request == nil {
+			return
+		}
+		m.Type, m.Changes = "refine", json.RawMessage("{}") // the same request, now constrained
+	}
+	body, err := s.nextRequest(m)
+	if err != nil {
+		s.send(ctx, stylingAgentMsg{Type: "error", Turn: turn,
+			Error: &httpapi.ErrorResp{Code: httpapi.ErrorCode(http.StatusBadRequest), Message: err.Error()}})
+		return
+	}
+	status, out := s.call(ctx, "/complete-outfit", body)
+	if ctx.Err() != nil {
+		return
+	}
+	if status != http.StatusOK {
+		s.sendError(ctx, turn, status, out)
+		return
+	}
+	var outfit CompleteOutfitResp
+	if err := json.Unmarshal(out, &outfit); err != nil {
+		s.send(ctx, stylingAgentMsg{Type: "error", Turn: turn,
+			Error: &httpapi.ErrorResp{Code: httpapi.ErrorCode(http.StatusInternalServerError), Message: "decoding outfit: " + err.Error()}})
+		return
+	}
+	if !s.send(ctx, stylingAgentMsg{Type: "outfit", Turn: turn, Request: body, Outfit: &outfit}) || !m.Explain {
+		return
+	}
+
+	status, out = s.call(ctx, "/explain-outfit", out)
+	if ctx.Err() != nil {
+		return
+	}
+	if status != http.StatusOK {
+		s.sendError(ctx, turn, status, out)
+		return
+	}
+	var expl struct {
+		Bullets []string `json:"bullets"`
+	}
+	json.Unmarshal(out, &expl)
+	s.send(ctx, stylingAgentMsg{Type: "explanation", Turn: turn, Bullets: expl.Bullets})
+}
+
+// addConstraint adds text as a constraint on the socket's session and
+// sends it back; it reports whether it was added.
+func (s *stylingSession) addConstraint(ctx context.Context, turn int, text string) bool {
+	id := s.upgrade.Header.Get("X-Session-ID")
+	if id == "" {
+		s.send(ctx, stylingAgentMsg{Type: "error", Turn: turn, Error: &httpapi.ErrorResp{
+			Code: httpapi.ErrorCode(http.StatusBadRequest), Message: "constraints need the socket to be opened with X-Session-ID"}})
+		return false
+	}
+	body, _ := json.Marshal(ConstraintReq{Text: text})
+	status, out := s.call(ctx, "/sessions/"+url.PathEscape(id)+"/constraints", body)
+	if ctx.Err() != nil {
+		return false
+	}
+	if status != http.StatusCreated {
+		s.sendError(ctx, turn, status, out)
+		return false
+	}
+	var c SessionConstraint
+	if err := json.Unmarshal(out, &c); err != nil {
+		s.send(ctx, stylingAgentMsg{Type: "error", Turn: turn,
+			Error: &httpapi.ErrorResp{Code: httpapi.ErrorCode(http.StatusInternalServerError), Message: "decoding constraint: " + err.Error()}})
+		return false
+	}
+	return s.send(ctx, stylingAgentMsg{Type: "constraint", Turn: turn, Constraint: &c})
+}
+
+// nextRequest applies m to the session's request and returns the new body.
+// A failed refinement leaves the request as it was.
+func (s *stylingSession) nextRequest(m stylingClientMsg) (json.RawMessage, error) {
+	var next map[string]any
+	switch m.Type {
+	case "outfit":
+		if err := json.Unmarshal(m.Request, &next); err != nil || next == nil {
+			return nil, httpapi.InvalidField("request", "request must be a /complete-outfit body")
+		}
+	case "refine":
+		if s.request == nil {
+			return nil, httpapi.InvalidField("type", `send an "outfit" message before refining it`)
+		}
+		var changes map[string]any
+		if err := json.Unmarshal(m.Changes, &changes); err != nil || changes == nil {
+			return nil, httpapi.InvalidField("changes", "changes must be an object of /complete-outfit fields")
+		}
+		next = mergePatch(s.request, changes)
+	default:
+		return nil, httpapi.InvalidField("type", "type must be outfit, refine or constraint")
+	}
+	body, err := json.Marshal(next)
+	if err != nil {
+		return nil, err
+	}
+	s.request = next
+	return body, nil
+}
+
+// mergePatch applies a JSON merge patch (RFC 7386) to a copy of doc: null
+// removes a field, objects merge, anything else replaces.
+func mergePatch(doc, patch map[string]any) map[string]any {
+	out := make(map[string]any, len(doc))
+	for k, v := range doc {
+		out[k] = v
+	}
+	for k, v := range patch {
+		switch v := v.(type) {
+		case nil:
+			delete(out, k)
+		case map[string]any:
+			prev, _ := out[k].(map[string]any)
+			out[k] = mergePatch(prev, v)
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// call POSTs body to path through the HTTP handler with the socket's
+// headers.
+func (s *stylingSession) call(ctx context.Context, path string, body []byte) (int, []byte) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return http.StatusInternalServerError, nil
+	}
+	r.Header = s.upgrade.Header.Clone()
+	for _, h := range []string{"Connection", "Upgrade", "Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions", "Sec-Websocket-Protocol", "X-Request-Id"} {
+		r.Header.Del(h) // each turn gets its own request ID
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.RemoteAddr = s.upgrade.RemoteAddr
+	rec := httptest.NewRecorder()
+	s.api.ServeHTTP(rec, r)
+	return rec.Code, rec.Body.Bytes()
+}
+
+func (s *stylingSession) sendError(ctx context.Context, turn, status int, body []byte) {
+	var e httpapi.ErrorResp
+	if err := json.Unmarshal(body, &e); err != nil || e.Message == "" {
+		e = httpapi.ErrorResp{Code: httpapi.ErrorCode(status), Message: string(bytes.TrimSpace(body))}
+	}
+	s.send(ctx, stylingAgentMsg{Type: "error", Turn: turn, Error: &e})
+}
+
+// send writes m unless the turn was superseded; it reports whether it did.
+// The write itself isn't cancelled with the turn, since that would close
+// the socket.
+func (s *stylingSession) send(ctx context.Context, m stylingAgentMsg) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	wctx, cancel := context.WithTimeout(s.ctx, stylingWriteTimeout)
+	defer cancel()
+	if err := wsjson.Write(wctx, s.conn, m); err != nil {
+		slog.DebugContext(ctx, "styling: write failed", "err", err)
+		return false
+	}
+	return true
+}
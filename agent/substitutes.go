@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/ranking"
+)
+
+// SubstitutesResp lists near-identical alternatives for one product, for
+// "out of stock, here's a close option" UX.
+type SubstitutesResp struct {
+	ProductID   string  `json:"product_id"`
+	Slot        string  `json:"slot"`
+	PriceGBP    float64 `json:"price_gbp"`
+	EcoScore    int     `json:"eco_score"`
+	Substitutes []Hit   `json:"substitutes"`
+}
+
+type substituteOpts struct {
+	Limit          int
+	PriceTolerance float64 // allowed price increase as a fraction, 0.1 = up to 10% dearer
+	InStockOnly    bool
+}
+
+const maxPriceTolerance = 0.5
+
+func (o substituteOpts) validate() error {
+	if o.Limit < 1 || o.Limit > 50 {
+		return httpapi.InvalidField("limit", "limit must be between 1 and 50")
+	}
+	if o.PriceTolerance < 0 || o.PriceTolerance > maxPriceTolerance {
+		return httpapi.InvalidField("price_tolerance", "price_tolerance must be between 0 and %g", maxPriceTolerance)
+	}
+	return nil
+}
+
+// findSubstitutes ranks products in the same slot by embedding distance to
+// productID, keeping those priced no higher than the original (plus the
+// tolerance) with an equal or better eco score. A product without a price
+// or eco score isn't held to it; one without a category has no slot, so no
+// substitutes. It returns nil when productID isn't indexed.
+func findSubstitutes(ctx context.Context, pool *pgxpool.Pool, productID string, o substituteOpts) (*SubstitutesResp, error) {
+	resp := SubstitutesResp{ProductID: productID}
+	var slot *string
+	var price *float64
+	var eco *int
+	err := pool.QueryRow(ctx, `
+SELECT category, price_gbp, eco_score FROM product_embeddings
+WHERE product_id=$1 AND embedding IS NOT NULL AND `+sandboxSQL(ctx, "")+`
+`, productID).Scan(&slot, &price, &eco)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if slot != nil {
+		resp.Slot = *slot
+	}
+	if price != nil {
+		resp.PriceGBP = *price
+	}
+	if eco != nil {
+		resp.EcoScore = *eco
+	}
+
+	op := ranking.Operator(cfg().Embed.Metric)
+	rows, err := pool.Query(ctx, `
+WITH src AS (SELECT embedding FROM product_embeddings WHERE product_id=$1)
+SELECT p.product_id, p.title, p.thumbnail, p.eco_score, p.price_gbp,
+       (p.embedding `+op+` src.embedding) AS distance,
+       p.stock_qty, p.variant_availability, COALESCE(p.eco_labels, '{}'),
+       COALESCE(p.origin_country, ''), COALESCE(p.eco_score_source, ''),
+       p.lifecycle, COALESCE(to_char(p.ships_at, 'YYYY-MM-DD'), '')
+FROM product_embeddings p, src
+WHERE p.product_id <> $1
+  AND p.embedding IS NOT NULL
+  AND `+sandboxSQL(ctx, "p")+`
+  AND `+lifecycleSQL("p", false)+`
+  AND p.category = $2
+  AND ($3::float8 IS NULL OR p.price_gbp <= $3 * (1 + $4::float8))
+  AND ($5::int IS NULL OR p.eco_score >= $5)
+  AND (NOT $6::bool OR p.stock_qty IS NULL OR p.stock_qty > 0)
+ORDER BY p.embedding `+op+` src.embedding
+LIMIT $7
+`, productID, slot, price, o.PriceTolerance, eco, o.InStockOnly, o.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits, err := scanHits(rows, cfg().Embed.Metric)
+	if err != nil {
+		return nil, err
+	}
+	resp.Substitutes = make([]Hit, 0, len(hits))
+	for _, h := range hits {
+		h.Reason = fmt.Sprintf("Same slot=%s. Price £%.2f vs £%.2f. Eco=%d vs %d.",
+			resp.Slot, h.PriceGBP, resp.PriceGBP, h.EcoScore, resp.EcoScore)
+		resp.Substitutes = append(resp.Substitutes, h)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+const defaultTenant = "default"
+
+// Explanation engines selectable per tenant.
+const (
+	explainEngineLLM      = "llm"      // tenant's LLM provider with deterministic fallback
+	explainEngineTemplate = "template" // deterministic only, no LLM copy
+)
+
+// Facts the deterministic fallback explanation can include.
+const (
+	explainFactMissingSlots = "missing_slots"
+	explainFactMethod       = "method"
+	explainFactBudget       = "budget"
+	explainFactEco          = "eco"
+	explainFactPicks        = "picks"
+	explainFactBundle       = "bundle"   // only when a promotion applies or is within reach
+	explainFactForecast     = "forecast" // only when the outfit followed a forecast
+)
+
+// Which of budget/eco the fallback explanation states first.
+const (
+	explainPriorityEco    = "eco"
+	explainPriorityBudget = "budget"
+)
+
+// TenantSettings are per-merchant knobs. Tenants without a row get defaults.
+type TenantSettings struct {
+	TenantID       string         `json:"tenant_id"`
+	ExplainEngine  string         `json:"explain_engine"`
+	ExplainOptions ExplainOptions `json:"explain_options"`
+	LLMProvider    string         `json:"llm_provider,omitempty"` // empty = CSA_LLM_PROVIDER
+	// ResidencyZone is where the tenant's data is processed and stored;
+	// empty = CSA_DEFAULT_RESIDENCY_ZONE.
+	ResidencyZone string `json:"residency_zone,omitempty"`
+	// MonthlyTokenCap is the most embedding and chat tokens the tenant may
+	// use in a calendar month; 0 = CSA_MONTHLY_TOKEN_CAP.
+	MonthlyTokenCap int64 `json:"monthly_token_cap,omitempty"`
+}
+
+// ExplainOptions shape the deterministic fallback explanation. Zero values
+// mean "use the default", which reproduces the original fixed behaviour.
+type ExplainOptions struct {
+	MaxBullets int      `json:"max_bullets,omitempty"` // default 5
+	Priority   string   `json:"priority,omitempty"`    // eco | budget, default eco
+	Facts      []string `json:"facts,omitempty"`       // default missing_slots, method, forecast, bundle, picks
+}
+
+func defaultTenantSettings(tenantID string) TenantSettings {
+	return TenantSettings{TenantID: tenantID, ExplainEngine: explainEngineLLM, ExplainOptions: ExplainOptions{}.withDefaults()}
+}
+
+func (o ExplainOptions) withDefaults() ExplainOptions {
+	if o.MaxBullets <= 0 {
+		o.MaxBullets = 5
+	}
+	if o.Priority == "" {
+		o.Priority = explainPriorityEco
+	}
+	if len(o.Facts) == 0 {
+		o.Facts = []string{explainFactMissingSlots, explainFactMethod, explainFactForecast, explainFactBundle, explainFactPicks}
+	}
+	return o
+}
+
+// factOrder is the order facts are emitted in; Priority decides whether the
+// budget or eco summary comes first.
+func (o ExplainOptions) factOrder() []string {
+	first, second := explainFactEco, explainFactBudget
+	if o.Priority == explainPriorityBudget {
+		first, second = second, first
+	}
+	return []string{explainFactMissingSlots, explainFactMethod, explainFactForecast, first, second, explainFactBundle, explainFactPicks}
+}
+
+func (o ExplainOptions) validate() error {
+	if o.MaxBullets < 0 || o.MaxBullets > 10 {
+		return httpapi.InvalidField("explain_options.max_bullets", "explain_options.max_bullets must be between 1 and 10")
+	}
+	switch o.Priority {
+	case "", explainPriorityEco, explainPriorityBudget:
+	default:
+		return httpapi.InvalidField("explain_options.priority", "explain_options.priority must be %q or %q", explainPriorityEco, explainPriorityBudget)
+	}
+	for _, f := range o.Facts {
+		switch f {
+		case explainFactMissingSlots, explainFactMethod, explainFactBudget, explainFactEco, explainFactBundle, explainFactPicks, explainFactForecast:
+		default:
+			return httpapi.InvalidField("explain_options.facts", "explain_options.facts: unknown fact %q", f)
+		}
+	}
+	return nil
+}
+
+// tenantFromRequest returns the calling merchant established by withAuth.
+func tenantFromRequest(r *http.Request) string {
+	return tenantFromContext(r.Context())
+}
+
+func tenantFromContext(ctx context.Context) string {
+	if t, ok := ctx.Value(ctxTenant).(string); ok && t != "" {
+		return t
+	}
+	return defaultTenant
+}
+
+func loadTenantSettings(ctx context.Context, pool *pgxpool.Pool, tenantID string) (TenantSettings, error) {
+	ts := defaultTenantSettings(tenantID)
+	var opts ExplainOptions
+	err := pool.QueryRow(ctx, `
+SELECT explain_engine, explain_options, llm_provider, residency_zone, monthly_token_cap FROM tenant_settings WHERE tenant_id=$1
+`, tenantID).Scan(&ts.ExplainEngine, &opts, &ts.LLMProvider, &ts.ResidencyZone, &ts.MonthlyTokenCap)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ts, nil
+	}
+	ts.ExplainOptions = opts.withDefaults()
+	return ts, err
+}
+
+func saveTenantSettings(ctx context.Context, pool *pgxpool.Pool, ts TenantSettings) error {
+	_, err := pool.Exec(ctx, `
+INSERT INTO tenant_settings (tenant_id, explain_engine, explain_options, llm_provider, residency_zone, monthly_token_cap, updated_at)
+VALUES ($1,$2,$3,$4,$5,$6,now())
+ON CONFLICT (tenant_id) DO UPDATE
+SET explain_engine=EXCLUDED.explain_engine,
+    explain_options=EXCLUDED.explain_options,
+    llm_provider=EXCLUDED.llm_provider,
+    residency_zone=EXCLUDED.residency_zone,
+    monthly_token_cap=EXCLUDED.monthly_token_cap,
+    updated_at=EXCLUDED.updated_at
+`, ts.TenantID, ts.ExplainEngine, ts.ExplainOptions, ts.LLMProvider, ts.ResidencyZone, ts.MonthlyTokenCap)
+	forgetTenantZone(ts.TenantID)
+	if err == nil {
+		usage.setCap(ts.TenantID, ts.MonthlyTokenCap)
+	}
+	return err
+}
+
+func (ts TenantSettings) validate() error {
+	switch ts.ExplainEngine {
+	case explainEngineLLM, explainEngineTemplate:
+	default:
+		return httpapi.InvalidField("explain_engine", "explain_engine must be %q or %q", explainEngineLLM, explainEngineTemplate)
+	}
+	if ts.MonthlyTokenCap < 0 {
+		return httpapi.InvalidField("monthly_token_cap", "monthly_token_cap must be 0 (the deployment default) or more")
+	}
+	if ts.ResidencyZone != "" && !slices.Contains(config.Zones, ts.ResidencyZone) {
+		return httpapi.InvalidField("residency_zone", "residency_zone must be one of %s", strings.Join(config.Zones, ", "))
+	}
+	zone := effectiveZone(ts.ResidencyZone)
+	if _, err := residentEmbedBackend(zone); err != nil {
+		return httpapi.InvalidField("residency_zone", "residency_zone %q is not served by this deployment: %v", zone, err)
+	}
+	if ts.LLMProvider != "" {
+		p, ok := chatProviders[ts.LLMProvider]
+		if !ok {
+			return httpapi.InvalidField("llm_provider", "llm_provider must be one of %s", strings.Join(config.LLMProviders, ", "))
+		}
+		if !p.configured() {
+			return httpapi.InvalidField("llm_provider", "llm_provider %q has no API key configured on this deployment", ts.LLMProvider)
+		}
+		if z := chatProviderZone(ts.LLMProvider); z != "" && z != zone {
+			return httpapi.InvalidField("llm_provider", "llm_provider %q processes data in %s, outside the tenant's residency zone %s", ts.LLMProvider, z, zone)
+		}
+	}
+	return ts.ExplainOptions.validate()
+}
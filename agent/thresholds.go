@@ -0,0 +1,74 @@
+package main
+
+import "fmt"
+
+// A slot can require a minimum similarity (0-100, as in Hit.Similarity) of
+// its hits, so that when the catalog has nothing close it answers "no good
+// match" instead of the least unrelated product. A mission's
+// min_similarity wins over CSA_SLOT_MIN_SIMILARITY; in each, the slot's own
+// value wins over "*".
+
+// Where a slot's threshold came from.
+const (
+	thresholdMission = "mission"
+	thresholdConfig  = "config"
+)
+
+// SlotThreshold is debug output: the threshold a slot's hits were held to
+// and what it dropped.
+type SlotThreshold struct {
+	MinSimilarity float64 `json:"min_similarity"`   // 0 = none
+	Source        string  `json:"source,omitempty"` // mission | config
+	Dropped       int     `json:"dropped"`          // hits under the threshold
+	// the closest hit dropped, when all were
+	BestSimilarity *float64 `json:"best_similarity,omitempty"`
+}
+
+// minSimilarity is the threshold for slot and where it came from; 0 and ""
+// when there is none.
+func (m Mission) minSimilarity(slot string) (float64, string) {
+	for _, set := range []struct {
+		vals   map[string]float64
+		source string
+	}{{m.MinSimilarity, thresholdMission}, {cfg().SlotMinSimilarity, thresholdConfig}} {
+		if v, ok := set.vals[slot]; ok {
+			return v, set.source
+		}
+		if v, ok := set.vals["*"]; ok {
+			return v, set.source
+		}
+	}
+	return 0, ""
+}
+
+// applyThreshold drops hits under minSim, keeping their order. With debug,
+// kept hits carry their margin over minSim.
+func applyThreshold(hits []Hit, minSim float64, source string, debug bool) ([]Hit, *SlotThreshold) {
+	t := &SlotThreshold{MinSimilarity: minSim, Source: source}
+	kept := hits[:0]
+	var best float64
+	for _, h := range hits {
+		if h.Similarity < minSim {
+			t.Dropped++
+			best = max(best, h.Similarity)
+			continue
+		}
+		if debug {
+			margin := round2(h.Similarity - minSim)
+			h.SimilarityMargin = &margin
+		}
+		kept = append(kept, h)
+	}
+	if len(kept) == 0 && t.Dropped > 0 {
+		b := round2(best)
+		t.BestSimilarity = &b
+	}
+	return kept, t
+}
+
+// noGoodMatch is the reason given for a slot whose hits all fell under
+// its threshold.
+func noGoodMatch(slot string, t *SlotThreshold) string {
+	return fmt.Sprintf("No good match for slot=%s: the closest product is %.0f%% similar, under the %.0f%% threshold.",
+		slot, *t.BestSimilarity, t.MinSimilarity)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/yourusername/contextual-shopping-agent/agent")
+
+// httpClient is used for every outbound call (OpenAI, Medusa, image
+// embeddings) so each one becomes a child span with trace headers attached.
+// Calls to OpenAI and Medusa also go through their circuit breakers, and
+// every call is bounded by the outbound timeouts.
+var httpClient = &http.Client{
+	Transport: otelhttp.NewTransport(breakerTransport{next: timeoutTransport{next: outboundTransport()}},
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return fmt.Sprintf("%s %s%s", r.Method, r.URL.Host, r.URL.Path)
+		})),
+}
+
+// initTracing exports spans over OTLP/HTTP when an endpoint is configured
+// (Jaeger and Tempo both accept OTLP). Without one, the global no-op tracer
+// stays in place. The returned func flushes pending spans.
+func initTracing(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg().Tracing.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exp, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg().Tracing.Endpoint))
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg().Tracing.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg().Tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	slog.Info("tracing: exporting spans", "endpoint", cfg().Tracing.Endpoint, "service", cfg().Tracing.ServiceName)
+	return tp.Shutdown, nil
+}
+
+// withTracing starts the server span for each request, continuing any trace
+// the caller propagated via traceparent.
+func withTracing(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "http.request")
+}
+
+// withRouteName runs directly in front of the mux and renames the server span
+// after the matched route pattern (e.g. "POST /complete-outfit"), which is
+// only known once the mux has routed the request.
+func withRouteName(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if r.Pattern != "" {
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Pattern)
+			span.SetAttributes(semconv.HTTPRoute(r.Pattern))
+		}
+	})
+}
+
+// startSpan is a shorthand for internal spans with string attributes.
+func startSpan(ctx context.Context, name string, kv ...string) (context.Context, trace.Span) {
+	attrs := make([]attribute.KeyValue, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		attrs = append(attrs, attribute.String(kv[i], kv[i+1]))
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/events"
+)
+
+type TrendingItem struct {
+	ProductID string  `json:"product_id"`
+	Title     string  `json:"title"`
+	Thumbnail string  `json:"thumbnail"`
+	EcoScore  int     `json:"eco_score"`
+	PriceGBP  float64 `json:"price_gbp"`
+	Served    int     `json:"served_7d"`
+}
+
+type HomeFeedSection struct {
+	Category string         `json:"category"`
+	Items    []TrendingItem `json:"items"`
+}
+
+type HomeFeedResp struct {
+	Sections    []HomeFeedSection `json:"sections"`
+	RefreshedAt *time.Time        `json:"refreshed_at,omitempty"`
+}
+
+type PriceDistribution struct {
+	Category string  `json:"category"`
+	Count    int     `json:"count"`
+	Min      float64 `json:"min"`
+	P25      float64 `json:"p25"`
+	Median   float64 `json:"median"`
+	P75      float64 `json:"p75"`
+	Max      float64 `json:"max"`
+	Avg      float64 `json:"avg"`
+}
+
+// Views refreshed by refreshViewsLoop. Each has a unique index so it can be
+// refreshed CONCURRENTLY without blocking readers.
+var materializedViews = []string{"mv_trending_by_category", "mv_price_distribution", "mv_feedback_scores", "mv_category_centroids"}
+
+// servedDetail says what a set of served hits answered, for user history.
+type servedDetail struct {
+	Query   string // search text or free-text outfit request
+	Mission string
+	Slot    string
+	Product string // the product substitutes were found for
+}
+
+// recordServed logs which products were shown so trending can be computed.
+// Failures are logged, never surfaced: this must not break recommendations.
+func recordServed(ctx context.Context, pool *pgxpool.Pool, source string, d servedDetail, hits []Hit) {
+	countHits(ctx, len(hits))
+	if len(hits) == 0 || sandboxFrom(ctx) {
+		return // sandbox traffic would skew trending
+	}
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ProductID
+	}
+	_, err := pool.Exec(ctx, `
+INSERT INTO recommendation_events (product_id, source)
+SELECT unnest($1::text[]), $2
+`, ids, source)
+	if err != nil {
+		slog.WarnContext(ctx, "trending: record served", "source", source, "err", err)
+	}
+	payload := map[string]any{"source": source, "product_ids": ids}
+	for k, v := range map[string]string{"query": d.Query, "mission": d.Mission, "slot": d.Slot, "product_id": d.Product} {
+		if v != "" {
+			payload[k] = v
+		}
+	}
+	sessionFrom(ctx).record(ctx, eventRecommendation, payload)
+	bus.Publish(events.RecommendationServed, tenantFromContext(ctx), events.RecommendationServedData{
+		Source: source, Query: d.Query, Mission: d.Mission, Slot: d.Slot, Product: d.Product, ProductIDs: ids,
+	})
+}
+
+func refreshViews(ctx context.Context, pool *pgxpool.Pool) error {
+	for _, v := range materializedViews {
+		if _, err := pool.Exec(ctx, "REFRESH MATERIALIZED VIEW CONCURRENTLY "+v); err != nil {
+			return err
+		}
+	}
+	_, err := pool.Exec(ctx, `
+INSERT INTO mv_refresh_log (view_name, refreshed_at)
+SELECT unnest($1::text[]), now()
+ON CONFLICT (view_name) DO UPDATE SET refreshed_at=EXCLUDED.refreshed_at
+`, materializedViews)
+	return err
+}
+
+// refreshViewsLoop refreshes the materialized views every
+// CSA_MV_REFRESH_INTERVAL until ctx is cancelled. Every replica ticks, but
+// each interval's refresh runs on only one of them.
+func refreshViewsLoop(ctx context.Context, pool *pgxpool.Pool) {
+	t := time.NewTicker(cfg().MVRefreshInterval)
+	defer t.Stop()
+	for {
+		slot := time.Now().Truncate(cfg().MVRefreshInterval)
+		if _, err := runScheduled(ctx, pool, "refresh_views", slot, func(ctx context.Context) error {
+			return refreshViews(ctx, pool)
+		}); err != nil {
+			slog.ErrorContext(ctx, "trending: refresh views", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func homeFeed(ctx context.Context, pool *pgxpool.Pool, perCategory int) (HomeFeedResp, error) {
+	rows, err := pool.Query(ctx, `
+SELECT t.category, t.product_id, COALESCE(p.title,''), COALESCE(p.thumbnail,''),
+       COALESCE(p.eco_score,0), COALESCE(p.price_gbp,0), t.served
+FROM mv_trending_by_category t
+JOIN product_embeddings p ON p.product_id = t.product_id
+WHERE t.rank <= $1
+ORDER BY t.category, t.rank
+`, perCategory)
+	if err != nil {
+		return HomeFeedResp{}, err
+	}
+	defer rows.Close()
+
+	resp := HomeFeedResp{Sections: []HomeFeedSection{}}
+	for rows.Next() {
+		var cat string
+		var it TrendingItem
+		if err := rows.Scan(&cat, &it.ProductID, &it.Title, &it.Thumbnail, &it.EcoScore, &it.PriceGBP, &it.Served); err != nil {
+			return HomeFeedResp{}, err
+		}
+		n := len(resp.Sections)
+		if n == 0 || resp.Sections[n-1].Category != cat {
+			resp.Sections = append(resp.Sections, HomeFeedSection{Category: cat})
+			n++
+		}
+		resp.Sections[n-1].Items = append(resp.Sections[n-1].Items, it)
+	}
+	if err := rows.Err(); err != nil {
+		return HomeFeedResp{}, err
+	}
+
+	resp.RefreshedAt = viewRefreshedAt(ctx, pool, "mv_trending_by_category")
+	return resp, nil
+}
+
+func priceDistribution(ctx context.Context, pool *pgxpool.Pool) ([]PriceDistribution, error) {
+	rows, err := pool.Query(ctx, `
+SELECT category, n, min_price, p25, median, p75, max_price, avg_price
+FROM mv_price_distribution
+ORDER BY category
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []PriceDistribution{}
+	for rows.Next() {
+		var d PriceDistribution
+		if err := rows.Scan(&d.Category, &d.Count, &d.Min, &d.P25, &d.Median, &d.P75, &d.Max, &d.Avg); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func viewRefreshedAt(ctx context.Context, pool *pgxpool.Pool, view string) *time.Time {
+	var t time.Time
+	if err := pool.QueryRow(ctx, "SELECT refreshed_at FROM mv_refresh_log WHERE view_name=$1", view).Scan(&t); err != nil {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Token usage: every embedding and chat call records the tokens its
+// provider reported against the calling tenant, the UTC day, the kind of
+// call and the model. Each replica sums its calls in memory and adds them
+// to llm_usage every usageFlushInterval, then reloads the month's totals of
+// all replicas, which is what monthly caps are checked against. A tenant
+// at its cap (tenant_settings.monthly_token_cap, else CSA_MONTHLY_TOKEN_CAP)
+// gets errUsageCapExceeded instead of new LLM work until the month ends:
+// /search falls back to keyword matching, explanations to the template and
+// index runs fail with 429. Sandbox keys and the local model are free and
+// neither counted nor capped.
+//
+// Calls are metered against the tenant of the request's API key. Anyone
+// can send X-Tenant-ID, so requests without a key share one anonymous
+// budget instead of spending (or dodging) a tenant's.
+
+var errUsageCapExceeded = errors.New("monthly token cap reached")
+
+// Kinds of metered call.
+const (
+	usageEmbed = "embed"
+	usageChat  = "chat"
+)
+
+// usageFlushInterval bounds both how stale /admin/usage is for calls on
+// other replicas and how far past its cap a tenant can go.
+const usageFlushInterval = 10 * time.Second
+
+type usageKey struct {
+	tenant, day, kind, model string
+}
+
+type usageCounts struct {
+	calls, prompt, completion int64
+}
+
+type usageMeter struct {
+	flushMu sync.Mutex // held by flush, so saves and reloads don't interleave
+
+	mu       sync.Mutex
+	pending  map[usageKey]usageCounts // recorded here, not yet in llm_usage
+	flushing map[usageKey]usageCounts // being added to llm_usage by flush
+	month    string                   // "2006-01" that monthly covers
+	monthly  map[string]int64         // tenant -> tokens in llm_usage this month
+	caps     map[string]int64         // tenant -> monthly_token_cap, where set
+}
+
+var usage = &usageMeter{pending: map[usageKey]usageCounts{}}
+
+// anonymousUsage is what requests without an API key are metered as. It
+// is capped by CSA_MONTHLY_TOKEN_CAP like a tenant.
+const anonymousUsage = "(anonymous)"
+
+// usageTenant is who ctx's calls are metered and capped against: the key's
+// tenant, anonymousUsage for a request without a key, else the tenant a job
+// outside a request (an index run, cache warming) set.
+func usageTenant(ctx context.Context) string {
+	p, inRequest := ctx.Value(ctxPrincipal).(*principal) // withAuth sets it, nil without a key
+	switch {
+	case p != nil:
+		return p.TenantID
+	case inRequest:
+		return anonymousUsage
+	}
+	return tenantFromContext(ctx)
+}
+
+// recordUsage counts one call's tokens for usageTenant and adds
+// them to the request's access log.
+func recordUsage(ctx context.Context, kind, model string, prompt, completion int) {
+	if sandboxFrom(ctx) || prompt+completion == 0 {
+		return
+	}
+	countTokens(ctx, prompt+completion)
+	k := usageKey{usageTenant(ctx), time.Now().UTC().Format(time.DateOnly), kind, model}
+	usage.mu.Lock()
+	c := usage.pending[k]
+	c.calls++
+	c.prompt += int64(prompt)
+	c.completion += int64(completion)
+	usage.pending[k] = c
+	usage.mu.Unlock()
+}
+
+// recordEmbedUsage is the embeddings clients' usage hook.
+func recordEmbedUsage(ctx context.Context, model string, tokens int) {
+	if model == "" {
+		model = cfg().Azure.EmbedDeployment
+	}
+	recordUsage(ctx, usageEmbed, model, tokens, 0)
+}
+
+// checkUsageCap fails with errUsageCapExceeded when usageTenant has used
+// its monthly tokens.
+func checkUsageCap(ctx context.Context) error {
+	if sandboxFrom(ctx) {
+		return nil
+	}
+	tenant := usageTenant(ctx)
+	used, limit := usage.monthToDate(tenant)
+	if limit > 0 && used >= limit {
+		return fmt.Errorf("%w: tenant %s has used %d of its %d tokens for %s", errUsageCapExceeded, tenant, used, limit, time.Now().UTC().Format("2006-01"))
+	}
+	return nil
+}
+
+// monthToDate is tenant's tokens this month, as of the last reload plus
+// this replica's unflushed calls, and its cap (0 = none).
+func (m *usageMeter) monthToDate(tenant string) (used, limit int64) {
+	month := time.Now().UTC().Format("2006-01")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	limit = m.caps[tenant]
+	if limit == 0 {
+		limit = int64(cfg().MonthlyTokenCap)
+	}
+	if m.month == month {
+		used = m.monthly[tenant]
+	}
+	for _, counts := range []map[usageKey]usageCounts{m.pending, m.flushing} {
+		for k, c := range counts {
+			if k.tenant == tenant && strings.HasPrefix(k.day, month) {
+				used += c.prompt + c.completion
+			}
+		}
+	}
+	return used, limit
+}
+
+// setCap updates tenant's cap after its settings change, so this replica
+// enforces it before the next reload.
+func (m *usageMeter) setCap(tenant string, limit int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.caps == nil {
+		m.caps = map[string]int64{}
+	}
+	if limit > 0 {
+		m.caps[tenant] = limit
+	} else {
+		delete(m.caps, tenant)
+	}
+}
+
+// flush adds the pending counts to llm_usage and reloads the month's
+// totals and caps. Counts that fail to save stay pending for the next one.
+func (m *usageMeter) flush(ctx context.Context, pool *pgxpool.Pool) error {
+	// one at a time, so a reload can't miss counts another flush just saved
+	m.flushMu.Lock()
+	defer m.flushMu.Unlock()
+	if err := m.save(func(pending map[usageKey]usageCounts) error { return saveUsage(ctx, pool, pending) }); err != nil {
+		return err
+	}
+	return m.reload(ctx, pool)
+}
+
+// save hands the pending counts to write. Until write returns they are
+// still counted against their caps, as flushing; then they move to the
+// month's totals, or back to pending if write failed.
+func (m *usageMeter) save(write func(map[usageKey]usageCounts) error) error {
+	m.mu.Lock()
+	m.flushing, m.pending = m.pending, map[usageKey]usageCounts{}
+	flushing := m.flushing
+	m.mu.Unlock()
+	if len(flushing) == 0 {
+		return nil
+	}
+
+	err := write(flushing)
+	month := time.Now().UTC().Format("2006-01")
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flushing = nil
+	for k, c := range flushing {
+		switch {
+		case err != nil:
+			p := m.pending[k]
+			m.pending[k] = usageCounts{p.calls + c.calls, p.prompt + c.prompt, p.completion + c.completion}
+		case m.month == month && strings.HasPrefix(k.day, month):
+			// saved: count them in the month until the next reload
+			m.monthly[k.tenant] += c.prompt + c.completion
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("save usage: %w", err)
+	}
+	return nil
+}
+
+func saveUsage(ctx context.Context, pool *pgxpool.Pool, counts map[usageKey]usageCounts) error {
+	b := &pgx.Batch{}
+	for k, c := range counts {
+		b.Queue(`
+INSERT INTO llm_usage (tenant_id, day, kind, model, calls, prompt_tokens, completion_tokens)
+VALUES ($1,$2,$3,$4,$5,$6,$7)
+ON CONFLICT (tenant_id, day, kind, model) DO UPDATE
+SET calls = llm_usage.calls + EXCLUDED.calls,
+    prompt_tokens = llm_usage.prompt_tokens + EXCLUDED.prompt_tokens,
+    completion_tokens = llm_usage.completion_tokens + EXCLUDED.completion_tokens
+`, k.tenant, k.day, k.kind, k.model, c.calls, c.prompt, c.completion)
+	}
+	return pool.SendBatch(ctx, b).Close()
+}
+
+func (m *usageMeter) reload(ctx context.Context, pool *pgxpool.Pool) error {
+	month := time.Now().UTC().Format("2006-01")
+	monthly := map[string]int64{}
+	rows, err := pool.Query(ctx, `
+SELECT tenant_id, sum(prompt_tokens + completion_tokens)::bigint
+FROM llm_usage WHERE day >= $1::date
+GROUP BY tenant_id
+`, month+"-01")
+	if err != nil {
+		return fmt.Errorf("load usage: %w", err)
+	}
+	for rows.Next() {
+		var tenant string
+		var tokens int64
+		if err := rows.Scan(&tenant, &tokens); err != nil {
+			rows.Close()
+			return fmt.Errorf("load usage: %w", err)
+		}
+		monthly[tenant] = tokens
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("load usage: %w", err)
+	}
+
+	caps := map[string]int64{}
+	rows, err = pool.Query(ctx, `SELECT tenant_id, monthly_token_cap FROM tenant_settings WHERE monthly_token_cap > 0`)
+	if err != nil {
+		return fmt.Errorf("load token caps: %w", err)
+	}
+	for rows.Next() {
+		var tenant string
+		var limit int64
+		if err := rows.Scan(&tenant, &limit); err != nil {
+			rows.Close()
+			return fmt.Errorf("load token caps: %w", err)
+		}
+		caps[tenant] = limit
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("load token caps: %w", err)
+	}
+
+	m.mu.Lock()
+	m.month, m.monthly, m.caps = month, monthly, caps
+	m.mu.Unlock()
+	return nil
+}
+
+// usageFlushLoop flushes this replica's counts until ctx ends; main
+// flushes once more after draining requests.
+func usageFlushLoop(ctx context.Context, pool *pgxpool.Pool) {
+	// nothing is pending yet, so this just loads the month
+	if err := usage.flush(ctx, pool); err != nil {
+		slog.ErrorContext(ctx, "usage: load", "err", err)
+	}
+	t := time.NewTicker(usageFlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if err := usage.flush(ctx, pool); err != nil {
+			slog.ErrorContext(ctx, "usage: flush", "err", err)
+		}
+	}
+}
+
+// UsageReport is a tenant's token usage for GET /admin/usage.
+type UsageReport struct {
+	TenantID string       `json:"tenant_id"`
+	From     string       `json:"from"` // first UTC day covered
+	Days     []UsageDay   `json:"days"` // newest first
+	Month    MonthToDate  `json:"month"`
+	Prices   UsagePricing `json:"prices"`
+}
+
+// UsageDay is one day's calls of one kind to one model.
+type UsageDay struct {
+	Day              string  `json:"day"`
+	Kind             string  `json:"kind"` // embed | chat
+	Model            string  `json:"model"`
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// MonthToDate totals the current calendar month (UTC) against the cap.
+type MonthToDate struct {
+	Month      string  `json:"month"` // e.g. 2026-10
+	Tokens     int64   `json:"tokens"`
+	CostUSD    float64 `json:"cost_usd"`
+	Cap        int64   `json:"cap,omitempty"`       // 0 = no cap
+	Remaining  *int64  `json:"remaining,omitempty"` // with a cap
+	CapReached bool    `json:"cap_reached"`
+}
+
+// UsagePricing is the USD per million tokens costs are computed at.
+type UsagePricing struct {
+	Embed      float64 `json:"embed"`
+	Chat       float64 `json:"chat"`
+	ChatOutput float64 `json:"chat_output"`
+}
+
+// usageCost prices a row at the configured rates, to the micro-dollar.
+func usageCost(kind string, prompt, completion int64) float64 {
+	var micros float64
+	switch kind {
+	case usageEmbed:
+		micros = float64(prompt) * cfg().EmbedCostPerMTok
+	case usageChat:
+		micros = float64(prompt)*cfg().ChatCostPerMTok + float64(completion)*cfg().ChatOutputCostPerMTok
+	}
+	return math.Round(micros) / 1e6
+}
+
+// usageReport lists tenantID's usage over the last days UTC days, this
+// replica's latest calls included.
+func usageReport(ctx context.Context, pool *pgxpool.Pool, tenantID string, days int) (UsageReport, error) {
+	if err := usage.flush(ctx, pool); err != nil {
+		return UsageReport{}, err
+	}
+	now := time.Now().UTC()
+	rep := UsageReport{
+		TenantID: tenantID,
+		From:     now.AddDate(0, 0, 1-days).Format(time.DateOnly),
+		Days:     []UsageDay{},
+		Month:    MonthToDate{Month: now.Format("2006-01")},
+		Prices:   UsagePricing{Embed: cfg().EmbedCostPerMTok, Chat: cfg().ChatCostPerMTok, ChatOutput: cfg().ChatOutputCostPerMTok},
+	}
+	rows, err := pool.Query(ctx, `
+SELECT to_char(day, 'YYYY-MM-DD'), kind, model, calls, prompt_tokens, completion_tokens
+FROM llm_usage
+WHERE tenant_id=$1 AND day >= LEAST($2::date, $3::date)
+ORDER BY day DESC, kind, model
+`, tenantID, rep.From, rep.Month.Month+"-01")
+	if err != nil {
+		return rep, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var d UsageDay
+		if err := rows.Scan(&d.Day, &d.Kind, &d.Model, &d.Calls, &d.PromptTokens, &d.CompletionTokens); err != nil {
+			return rep, err
+		}
+		d.CostUSD = usageCost(d.Kind, d.PromptTokens, d.CompletionTokens)
+		if strings.HasPrefix(d.Day, rep.Month.Month) {
+			rep.Month.Tokens += d.PromptTokens + d.CompletionTokens
+			rep.Month.CostUSD += d.CostUSD
+		}
+		if d.Day >= rep.From {
+			rep.Days = append(rep.Days, d)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return rep, err
+	}
+	rep.Month.CostUSD = math.Round(rep.Month.CostUSD*1e6) / 1e6
+	if _, limit := usage.monthToDate(tenantID); limit > 0 {
+		left := max(limit-rep.Month.Tokens, 0)
+		rep.Month.Cap, rep.Month.Remaining, rep.Month.CapReached = limit, &left, left == 0
+	}
+	return rep, nil
+}
+
+// indexErrorStatus answers a failed index run: 429 when the tenant's cap
+// stopped it, else 500.
+func indexErrorStatus(err error) int {
+	if errors.Is(err, errUsageCapExceeded) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
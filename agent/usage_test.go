@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/config"
+)
+
+// useFreshUsage gives the test an empty usage meter.
+func useFreshUsage(t *testing.T) {
+	t.Helper()
+	prev := usage
+	usage = &usageMeter{pending: map[usageKey]usageCounts{}}
+	t.Cleanup(func() { usage = prev })
+}
+
+func tenantCtx(tenant string) (context.Context, *requestOutcome) {
+	return withOutcome(context.WithValue(context.Background(), ctxTenant, tenant))
+}
+
+func TestUsageCapRejectsLLMWork(t *testing.T) {
+	useFreshUsage(t)
+	useTestConfig(t, map[string]string{"CSA_MONTHLY_TOKEN_CAP": "50"})
+	useCannedChat(t, &cannedChat{def: "ok"}) // too short to cost a completion token
+	prompt := strings.Repeat("word ", 40)    // 50 tokens to the canned provider
+
+	ctx, outcome := tenantCtx("t1")
+	if _, err := llmChat(ctx, "", config.LLMExplain, prompt, nil); err != nil {
+		t.Fatalf("under the cap: %v", err)
+	}
+	if _, err := llmChat(ctx, "", config.LLMExplain, prompt, nil); !errors.Is(err, errUsageCapExceeded) {
+		t.Errorf("at the cap: err = %v, want errUsageCapExceeded", err)
+	}
+	if _, err := embedTexts(ctx, []string{"linen"}); !errors.Is(err, errUsageCapExceeded) {
+		t.Errorf("embedding at the cap: err = %v", err)
+	}
+	var logged int64
+	for _, a := range outcome.logAttrs() {
+		if a.Key == "tokens" && a.Value.Kind() == slog.KindInt64 {
+			logged = a.Value.Int64()
+		}
+	}
+	if logged != 50 {
+		t.Errorf("request log tokens = %d, want 50", logged)
+	}
+
+	// caps are per tenant, and a tenant's own setting wins
+	other, _ := tenantCtx("t2")
+	if _, err := llmChat(other, "", config.LLMExplain, prompt, nil); err != nil {
+		t.Errorf("another tenant: %v", err)
+	}
+	usage.setCap("t3", 1000)
+	big, _ := tenantCtx("t3")
+	for range 3 {
+		if _, err := llmChat(big, "", config.LLMExplain, prompt, nil); err != nil {
+			t.Fatalf("tenant with a higher cap: %v", err)
+		}
+	}
+	if used, limit := usage.monthToDate("t3"); used != 150 || limit != 1000 {
+		t.Errorf("t3 month = %d of %d", used, limit)
+	}
+}
+
+func TestUsageMetersKeyTenant(t *testing.T) {
+	useFreshUsage(t)
+	useTestConfig(t, map[string]string{"CSA_MONTHLY_TOKEN_CAP": "50"})
+	useCannedChat(t, &cannedChat{def: "ok"})
+	prompt := strings.Repeat("word ", 40)
+
+	// a request without a key that names t1 in X-Tenant-ID
+	anon, _ := withOutcome(context.WithValue(context.WithValue(context.Background(), ctxTenant, "t1"), ctxPrincipal, (*principal)(nil)))
+	if _, err := llmChat(anon, "", config.LLMExplain, prompt, nil); err != nil {
+		t.Fatal(err)
+	}
+	if used, _ := usage.monthToDate("t1"); used != 0 {
+		t.Errorf("t1 charged %d tokens for a keyless request", used)
+	}
+	if used, _ := usage.monthToDate(anonymousUsage); used != 50 {
+		t.Errorf("anonymous used %d, want 50", used)
+	}
+	if _, err := llmChat(anon, "", config.LLMExplain, prompt, nil); !errors.Is(err, errUsageCapExceeded) {
+		t.Errorf("anonymous at the cap: err = %v", err)
+	}
+
+	// a key is metered as its tenant whatever the header says
+	keyed, _ := withOutcome(context.WithValue(context.WithValue(context.Background(), ctxTenant, "t2"), ctxPrincipal, &principal{KeyID: "k1", TenantID: "t1"}))
+	if _, err := llmChat(keyed, "", config.LLMExplain, prompt, nil); err != nil {
+		t.Errorf("t1 is under its cap: %v", err)
+	}
+	if used, _ := usage.monthToDate("t1"); used != 50 {
+		t.Errorf("t1 used %d, want 50", used)
+	}
+}
+
+func TestEmbeddingUsageFromResponse(t *testing.T) {
+	useFreshUsage(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Input []string }
+		json.NewDecoder(r.Body).Decode(&body)
+		data := make([]map[string]any, len(body.Input))
+		for i := range data {
+			data[i] = map[string]any{"index": i, "embedding": []float64{1, 0}}
+		}
+		writeFakeJSON(w, map[string]any{"data": data, "model": "text-embedding-3-small", "usage": map[string]int{"prompt_tokens": 7, "total_tokens": 7}})
+	}))
+	defer srv.Close()
+	c := useTestConfig(t, map[string]string{"OPENAI_BASE_URL": srv.URL})
+	c.Embed.Backend = config.EmbedOpenAI
+
+	ctx, _ := tenantCtx("t1")
+	if _, err := embedTexts(ctx, []string{"linen shirt", "wool coat"}); err != nil {
+		t.Fatal(err)
+	}
+	got := usage.pending
+	if len(got) != 1 {
+		t.Fatalf("pending = %v", got)
+	}
+	for k, n := range got {
+		if k.tenant != "t1" || k.kind != usageEmbed || k.model != "text-embedding-3-small" || n != (usageCounts{calls: 1, prompt: 7}) {
+			t.Errorf("recorded %+v: %+v", k, n)
+		}
+	}
+}
+
+func TestUsageCost(t *testing.T) {
+	c := useTestConfig(t, nil)
+	c.EmbedCostPerMTok, c.ChatCostPerMTok, c.ChatOutputCostPerMTok = 0.02, 0.15, 0.6
+	if got := usageCost(usageEmbed, 1_000_000, 0); got != 0.02 {
+		t.Errorf("embed cost = %v", got)
+	}
+	if got := usageCost(usageChat, 2000, 1000); got != 0.0009 {
+		t.Errorf("chat cost = %v", got)
+	}
+}
+
+func TestUsageCapHoldsDuringFlush(t *testing.T) {
+	for _, writeErr := range []error{nil, errors.New("db down")} {
+		useFreshUsage(t)
+		useTestConfig(t, map[string]string{"CSA_MONTHLY_TOKEN_CAP": "50"})
+		usage.month, usage.monthly = time.Now().UTC().Format("2006-01"), map[string]int64{}
+		ctx, _ := tenantCtx("t1")
+		recordUsage(ctx, usageChat, "canned", 50, 0)
+
+		writing, release, done := make(chan struct{}), make(chan struct{}), make(chan error)
+		go func() {
+			done <- usage.save(func(map[usageKey]usageCounts) error {
+				close(writing)
+				<-release
+				return writeErr
+			})
+		}()
+		<-writing
+		if err := checkUsageCap(ctx); !errors.Is(err, errUsageCapExceeded) {
+			t.Errorf("while saving: err = %v, want errUsageCapExceeded", err)
+		}
+		close(release)
+		if err := <-done; (err != nil) != (writeErr != nil) {
+			t.Errorf("save = %v, write returned %v", err, writeErr)
+		}
+		// saved counts move to the month, failed ones back to pending
+		if used, _ := usage.monthToDate("t1"); used != 50 {
+			t.Errorf("after saving (write error %v): used = %d, want 50", writeErr, used)
+		}
+	}
+}
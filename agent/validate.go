@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// Limits on /search and /complete-outfit request fields.
+const (
+	maxSearchLimit  = 100
+	maxLimitPerSlot = 20
+	maxCartSlots    = maxMissionSlots
+)
+
+// validate checks every field of a /search request and reports all the
+// problems it finds, not just the first.
+func (req SearchReq) validate() error {
+	var errs []error
+	if len(req.Query) > maxIntentText {
+		errs = append(errs, httpapi.InvalidField("query", "query must be at most %d characters", maxIntentText))
+	}
+	if req.Limit < 0 || req.Limit > maxSearchLimit {
+		errs = append(errs, httpapi.InvalidField("limit", "limit must be between 1 and %d", maxSearchLimit))
+	}
+	if req.MaxPriceGBP < 0 {
+		errs = append(errs, httpapi.InvalidField("max_price_gbp", "max_price_gbp must not be negative"))
+	}
+	if req.MinEcoScore < 0 || req.MinEcoScore > 100 {
+		errs = append(errs, httpapi.InvalidField("min_eco_score", "min_eco_score must be between 0 and 100"))
+	}
+	if len(req.Within) > maxResponseID {
+		errs = append(errs, httpapi.InvalidField("within", "within must be a response_id of at most %d characters", maxResponseID))
+	}
+	return httpapi.CollectFieldErrors(append(errs,
+		req.AttrFilters.validate(),
+		req.OriginPrefs.validate(),
+		req.FreshnessPrefs.validate(),
+		req.ClearancePrefs.validate(),
+		req.RankingPrefs.validate(),
+		req.DiversityPrefs.validate(),
+		validateMetric(req.DistanceMetric),
+		validateSearchQuality(req.SearchQuality),
+		validateSortBy(req.SortBy),
+	)...)
+}
+
+// validate checks every field of a /complete-outfit request and reports all
+// the problems it finds. Whether the mission exists is checkMission's job.
+func (req CompleteOutfitReq) validate() error {
+	var errs []error
+	if req.Mission != "" && !missionNamePattern.MatchString(req.Mission) {
+		errs = append(errs, httpapi.InvalidField("mission", "mission must be 1-40 lowercase letters, digits or underscores"))
+	}
+	if req.BudgetGBP < 0 {
+		errs = append(errs, httpapi.InvalidField("budget_gbp", "budget_gbp must not be negative"))
+	}
+	if req.MinEcoScore < 0 || req.MinEcoScore > 100 {
+		errs = append(errs, httpapi.InvalidField("min_eco_score", "min_eco_score must be between 0 and 100"))
+	}
+	if req.LimitPerSlot < 0 || req.LimitPerSlot > maxLimitPerSlot {
+		errs = append(errs, httpapi.InvalidField("limit_per_slot", "limit_per_slot must be between 1 and %d", maxLimitPerSlot))
+	}
+	if len(req.CartSlots) > maxCartSlots {
+		errs = append(errs, httpapi.InvalidField("cart_slots", "cart_slots must list at most %d slots", maxCartSlots))
+	}
+	for i, s := range req.CartSlots {
+		if !slotNamePattern.MatchString(s) {
+			errs = append(errs, httpapi.InvalidField(fmt.Sprintf("cart_slots[%d]", i),
+				"invalid slot %q: use the category names products are indexed with, e.g. top", s))
+		}
+	}
+	if req.CartID != "" && !cartIDPattern.MatchString(req.CartID) {
+		errs = append(errs, httpapi.InvalidField("cart_id", "cart_id must be 1-128 characters of A-Z a-z 0-9 _ -"))
+	}
+	if req.CartID != "" && len(req.CartSlots) > 0 {
+		errs = append(errs, httpapi.InvalidField("cart_id", "send cart_id or cart_slots, not both"))
+	}
+	if len(req.Query) > maxIntentText {
+		errs = append(errs, httpapi.InvalidField("query", "query must be at most %d characters", maxIntentText))
+	}
+	return httpapi.CollectFieldErrors(append(errs,
+		req.AttrFilters.validate(),
+		req.OriginPrefs.validate(),
+		req.FreshnessPrefs.validate(),
+		req.ClearancePrefs.validate(),
+		req.RankingPrefs.validate(),
+		req.DiversityPrefs.validate(),
+		validateMetric(req.DistanceMetric),
+		req.Gift.validate(),
+		req.Weather.validate(),
+	)...)
+}
+
+// checkMission fails with a field error naming the tenant's missions when
+// name is not one of them. Built-in names always exist, so they cost no
+// query.
+func checkMission(ctx context.Context, pool *pgxpool.Pool, name string) error {
+	if _, ok := builtinMissions[name]; ok || name == "" {
+		return nil
+	}
+	if _, ok, err := loadMission(ctx, pool, tenantFromContext(ctx), name); err != nil || ok {
+		return err
+	}
+	ms, err := listMissions(ctx, pool, tenantFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(ms))
+	for i, m := range ms {
+		names[i] = m.Name
+	}
+	return httpapi.InvalidField("mission", "unknown mission %q; available: %s", name, strings.Join(names, ", "))
+}
+
+// checkCategory fails with a field error naming the catalog's categories
+// when no indexed product has category. Categories come from the
+// catalog, not a fixed list, so the centroids answer first and the table
+// is asked only about a name they lack, e.g. one indexed since the last
+// view refresh.
+func checkCategory(ctx context.Context, pool *pgxpool.Pool, category string) error {
+	if category == "" {
+		return nil
+	}
+	list, err := loadCentroids(ctx, pool)
+	if err != nil {
+		return err
+	}
+	known := make([]string, 0, len(list))
+	for _, c := range list {
+		if c.category == category {
+			return nil
+		}
+		known = append(known, c.category)
+	}
+	var exists bool
+	if err := pool.QueryRow(ctx, `
+SELECT EXISTS (SELECT 1 FROM product_embeddings WHERE category = $1 AND `+sandboxSQL(ctx, "")+`)
+`, category).Scan(&exists); err != nil || exists {
+		return err
+	}
+	if len(known) == 0 {
+		return httpapi.InvalidField("category", "no indexed product has category %q", category)
+	}
+	sort.Strings(known)
+	return httpapi.InvalidField("category", "unknown category %q; indexed: %s", category, strings.Join(known, ", "))
+}
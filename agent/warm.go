@@ -0,0 +1,124 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// queryEmbedCacheSize bounds the query embedding cache. Shoppers repeat a
+// small set of queries (mission and slot phrases above all), so a few
+// thousand vectors cover most traffic for ~12 MB.
+const queryEmbedCacheSize = 1024
+
+// queryEmbeds caches search query embeddings, least recently used first out.
+// Product cards are not cached: they are embedded once, at indexing.
+var queryEmbeds = &embedCache{entries: map[string]*list.Element{}, order: list.New()}
+
+type embedCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // front is most recently used
+}
+
+type embedCacheEntry struct {
+	key string
+	vec []float64
+}
+
+func (c *embedCache) get(key string) ([]float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*embedCacheEntry).vec, true
+}
+
+func (c *embedCache) put(key string, vec []float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*embedCacheEntry).vec = vec
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&embedCacheEntry{key: key, vec: vec})
+	for c.order.Len() > queryEmbedCacheSize {
+		el := c.order.Back()
+		c.order.Remove(el)
+		delete(c.entries, el.Value.(*embedCacheEntry).key)
+	}
+}
+
+// embedQuery embeds a search query, reusing the vector from an earlier
+// identical query, in this process or, with Redis, any replica. The key includes the backend and sandbox flag since their
+// vectors are not comparable, and the residency zone so a zone only reuses
+// vectors computed there.
+func embedQuery(ctx context.Context, text string) ([]float64, error) {
+	key := cfg().Embed.Backend + "\x00" + residencyFrom(ctx) + "\x00" + text
+	if sandboxFrom(ctx) {
+		key = "sandbox\x00" + text
+	}
+	if v, ok := queryEmbeds.get(key); ok {
+		return v, nil
+	}
+	if v, ok := cachedQueryEmbedding(ctx, text); ok {
+		queryEmbeds.put(key, v)
+		return v, nil
+	}
+	v, err := embedText(ctx, text)
+	if err != nil {
+		return nil, embedUnavailable(ctx, err)
+	}
+	queryEmbeds.put(key, v)
+	storeQueryEmbedding(ctx, text, v)
+	return v, nil
+}
+
+// cachesWarm is set once startup warming finishes or gives up; until then
+// /healthz/ready reports the cache_warm check as failing.
+var cachesWarm atomic.Bool
+
+// warmCaches runs the plain outfit request for every mission of the
+// CSA_CACHE_WARM_TENANTS tenants, which embeds each mission and slot query
+// and primes the outfit cache with the results. A failing mission is logged
+// and skipped; the rest are still warmed.
+func warmCaches(ctx context.Context, pool *pgxpool.Pool) {
+	start := time.Now()
+	warmed := 0
+	for _, t := range cfg().CacheWarmTenants {
+		tctx, err := withResidency(context.WithValue(ctx, ctxTenant, t), pool, t)
+		if err != nil {
+			slog.Warn("warm: tenant lookup failed", "tenant", t, "err", err)
+			continue
+		}
+		missions, err := listMissions(tctx, pool, t)
+		if err != nil {
+			slog.Warn("warm: list missions failed", "tenant", t, "err", err)
+			continue
+		}
+		for _, m := range missions {
+			if ctx.Err() != nil {
+				slog.Warn("warm: timed out", "warmed", warmed, "elapsed", time.Since(start))
+				return
+			}
+			req := CompleteOutfitReq{Mission: m.Name}
+			resp, err := runCompleteOutfit(tctx, pool, req)
+			if err != nil {
+				slog.Warn("warm: mission failed", "tenant", t, "mission", m.Name, "err", err)
+				continue
+			}
+			outfits.put(outfitCacheKey(tctx, t, req), resp)
+			warmed++
+		}
+	}
+	slog.Info("warm: caches primed", "missions", warmed, "elapsed", time.Since(start))
+}
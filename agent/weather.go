@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// weatherMissions are the missions whose slot queries follow the forecast.
+var weatherMissions = []string{"outdoor_rain"}
+
+// forecastDays is how far ahead the forecast API reaches.
+const forecastDays = 16
+
+// forecastTTL is how long a location's forecast is reused.
+const forecastTTL = 30 * time.Minute
+
+// WeatherReq asks for an outfit suited to the forecast at a place and day.
+type WeatherReq struct {
+	Location string `json:"location"`       // place name ("Keswick") or "lat,lon"
+	Date     string `json:"date,omitempty"` // YYYY-MM-DD, default today; up to 15 days ahead
+}
+
+// Forecast is the day's forecast an outfit was chosen for.
+type Forecast struct {
+	Location     string  `json:"location"`
+	Date         string  `json:"date"`
+	TempMinC     float64 `json:"temp_min_c"`
+	TempMaxC     float64 `json:"temp_max_c"`
+	PrecipMM     float64 `json:"precip_mm"`
+	PrecipChance int     `json:"precip_chance"` // percent
+	WindKPH      float64 `json:"wind_kph"`
+	Summary      string  `json:"summary"`
+}
+
+func (w *WeatherReq) validate() error {
+	if w == nil {
+		return nil
+	}
+	w.Location = strings.TrimSpace(w.Location)
+	if w.Location == "" || len(w.Location) > 100 {
+		return httpapi.InvalidField("weather.location", "weather.location is required, at most 100 characters")
+	}
+	if w.Date != "" {
+		d, err := time.Parse(time.DateOnly, w.Date)
+		if err != nil {
+			return httpapi.InvalidField("weather.date", "weather.date must be YYYY-MM-DD")
+		}
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		if d.Before(today.AddDate(0, 0, -1)) || !d.Before(today.AddDate(0, 0, forecastDays)) {
+			return httpapi.InvalidField("weather.date", "weather.date must be within the next %d days", forecastDays-1)
+		}
+	}
+	return nil
+}
+
+var (
+	forecastMu    sync.Mutex
+	forecastCache = map[string]cachedForecast{}
+)
+
+type cachedForecast struct {
+	f       Forecast
+	expires time.Time
+}
+
+// loadForecast resolves the location and fetches the day's forecast.
+// Sandbox requests get a fixed wet, cool day without calling the API.
+func loadForecast(ctx context.Context, w WeatherReq) (Forecast, error) {
+	date := w.Date
+	if date == "" {
+		date = time.Now().UTC().Format(time.DateOnly)
+	}
+	if sandboxFrom(ctx) {
+		f := Forecast{Location: w.Location, Date: date, TempMinC: 7, TempMaxC: 12, PrecipMM: 6.5, PrecipChance: 80, WindKPH: 25}
+		f.Summary = f.summary()
+		return f, nil
+	}
+	if cfg().Weather.ForecastURL == "" {
+		return Forecast{}, fmt.Errorf("weather is disabled (CSA_WEATHER_URL is empty)")
+	}
+
+	key := strings.ToLower(w.Location) + "\x00" + date
+	forecastMu.Lock()
+	c, ok := forecastCache[key]
+	forecastMu.Unlock()
+	if ok && time.Now().Before(c.expires) {
+		return c.f, nil
+	}
+
+	ctx, span := startSpan(ctx, "weather.forecast", "weather.date", date)
+	defer span.End()
+
+	name, lat, lon, err := geocode(ctx, w.Location)
+	if err != nil {
+		return Forecast{}, err
+	}
+	q := url.Values{
+		"latitude":   {strconv.FormatFloat(lat, 'f', 4, 64)},
+		"longitude":  {strconv.FormatFloat(lon, 'f', 4, 64)},
+		"daily":      {"temperature_2m_min,temperature_2m_max,precipitation_sum,precipitation_probability_max,wind_speed_10m_max"},
+		"timezone":   {"auto"},
+		"start_date": {date},
+		"end_date":   {date},
+	}
+	var out struct {
+		Daily struct {
+			TempMin      []float64 `json:"temperature_2m_min"`
+			TempMax      []float64 `json:"temperature_2m_max"`
+			Precip       []float64 `json:"precipitation_sum"`
+			PrecipChance []float64 `json:"precipitation_probability_max"`
+			Wind         []float64 `json:"wind_speed_10m_max"`
+		} `json:"daily"`
+	}
+	if err := getJSON(ctx, cfg().Weather.ForecastURL+"?"+q.Encode(), &out); err != nil {
+		return Forecast{}, fmt.Errorf("forecast: %w", err)
+	}
+	d := out.Daily
+	if len(d.TempMin) == 0 || len(d.TempMax) == 0 || len(d.Precip) == 0 || len(d.PrecipChance) == 0 || len(d.Wind) == 0 {
+		return Forecast{}, fmt.Errorf("forecast: no data for %s", date)
+	}
+	f := Forecast{
+		Location: name, Date: date,
+		TempMinC: d.TempMin[0], TempMaxC: d.TempMax[0],
+		PrecipMM: d.Precip[0], PrecipChance: int(math.Round(d.PrecipChance[0])),
+		WindKPH: d.Wind[0],
+	}
+	f.Summary = f.summary()
+
+	forecastMu.Lock()
+	for k, c := range forecastCache {
+		if time.Now().After(c.expires) {
+			delete(forecastCache, k)
+		}
+	}
+	forecastCache[key] = cachedForecast{f: f, expires: time.Now().Add(forecastTTL)}
+	forecastMu.Unlock()
+	return f, nil
+}
+
+// geocode turns "lat,lon" or a place name into coordinates.
+func geocode(ctx context.Context, location string) (name string, lat, lon float64, err error) {
+	if a, b, ok := strings.Cut(location, ","); ok {
+		lat, errLat := strconv.ParseFloat(strings.TrimSpace(a), 64)
+		lon, errLon := strconv.ParseFloat(strings.TrimSpace(b), 64)
+		if errLat == nil && errLon == nil {
+			if math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+				return "", 0, 0, fmt.Errorf("weather.location: coordinates out of range")
+			}
+			return location, lat, lon, nil
+		}
+	}
+	q := url.Values{"name": {location}, "count": {"1"}, "language": {"en"}}
+	var out struct {
+		Results []struct {
+			Name      string  `json:"name"`
+			Country   string  `json:"country"`
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+		} `json:"results"`
+	}
+	if err := getJSON(ctx, cfg().Weather.GeocodeURL+"?"+q.Encode(), &out); err != nil {
+		return "", 0, 0, fmt.Errorf("geocode: %w", err)
+	}
+	if len(out.Results) == 0 {
+		return "", 0, 0, fmt.Errorf("geocode: no place called %q", location)
+	}
+	r := out.Results[0]
+	name = r.Name
+	if r.Country != "" {
+		name += ", " + r.Country
+	}
+	return name, r.Latitude, r.Longitude, nil
+}
+
+func getJSON(ctx context.Context, u string, dst any) error {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(dst)
+}
+
+func (f Forecast) rainy() bool     { return f.PrecipChance >= 50 || f.PrecipMM >= 1 }
+func (f Forecast) heavyRain() bool { return f.PrecipMM >= 10 }
+func (f Forecast) cold() bool      { return f.TempMaxC < 10 }
+func (f Forecast) freezing() bool  { return f.TempMinC <= 0 }
+func (f Forecast) warm() bool      { return f.TempMaxC >= 20 }
+func (f Forecast) windy() bool     { return f.WindKPH >= 40 }
+
+// summary reads like "80% chance of rain (6.5 mm), 7-12°C".
+func (f Forecast) summary() string {
+	var parts []string
+	switch {
+	case f.heavyRain():
+		parts = append(parts, fmt.Sprintf("heavy rain (%.1f mm)", f.PrecipMM))
+	case f.rainy():
+		parts = append(parts, fmt.Sprintf("%d%% chance of rain (%.1f mm)", f.PrecipChance, f.PrecipMM))
+	default:
+		parts = append(parts, "mostly dry")
+	}
+	if f.windy() {
+		parts = append(parts, fmt.Sprintf("wind up to %.0f km/h", f.WindKPH))
+	}
+	parts = append(parts, fmt.Sprintf("%.0f-%.0f°C", f.TempMinC, f.TempMaxC))
+	return strings.Join(parts, ", ")
+}
+
+// descriptors are the words added to a slot's query for the forecast: a
+// waterproof rating for rain, warmth for cold, and so on. Slots the weather
+// doesn't bear on get none.
+func (f Forecast) descriptors(slot string) []string {
+	var out []string
+	add := func(ok bool, words ...string) {
+		if ok {
+			out = append(out, words...)
+		}
+	}
+	switch slot {
+	case "outerwear":
+		add(f.heavyRain(), "fully waterproof", "taped seams", "high hydrostatic head")
+		add(f.rainy() && !f.heavyRain(), "waterproof")
+		add(f.windy(), "windproof")
+		add(f.cold(), "insulated", "warm")
+		add(f.warm(), "lightweight", "packable")
+	case "shoes":
+		add(f.rainy(), "waterproof")
+		add(f.freezing(), "insulated", "winter grip")
+	case "bottom":
+		add(f.rainy(), "water-resistant", "quick-drying")
+		add(f.cold(), "thermal lined")
+		add(f.warm(), "lightweight")
+	case "top":
+		add(f.cold(), "warm", "thermal base layer")
+		add(f.warm(), "breathable", "lightweight")
+	}
+	return out
+}
+
+// weatherSentence states the forecast for explanations, or "".
+func weatherSentence(f *Forecast) string {
+	if f == nil {
+		return ""
+	}
+	return fmt.Sprintf("The forecast for %s on %s is %s, so the picks favour gear for those conditions.", f.Location, f.Date, f.Summary)
+}
+
+// forecastFor loads the forecast for a weather-aware mission. Failures and
+// missions that ignore the weather are reported as warnings, and the outfit
+// is chosen without it.
+func forecastFor(ctx context.Context, mission string, w *WeatherReq) *Forecast {
+	if w == nil {
+		return nil
+	}
+	if !slices.Contains(weatherMissions, mission) {
+		addWarnings(ctx, fmt.Sprintf("weather is only used by the %s missions; ignored", strings.Join(weatherMissions, ", ")))
+		return nil
+	}
+	f, err := loadForecast(ctx, *w)
+	if err != nil {
+		slog.WarnContext(ctx, "weather: forecast failed", "err", err)
+		addWarnings(ctx, "forecast unavailable; outfit chosen without the weather")
+		return nil
+	}
+	return &f
+}
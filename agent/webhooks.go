@@ -0,0 +1,507 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/events"
+	"github.com/yourusername/contextual-shopping-agent/agent/internal/httpapi"
+)
+
+// Tenants register webhooks for domain events. Every event becomes one row
+// per matching webhook in the webhook_outbox table, and a worker on each
+// replica delivers due rows, retrying failures with exponential backoff.
+// Deliveries therefore survive restarts and receiver outages, and tenants
+// can list them by status and replay any they missed.
+//
+// Each delivery POSTs the event JSON ({id, type, tenant, time, data}) with
+// X-CSA-Event, X-CSA-Event-ID, X-CSA-Delivery and X-CSA-Signature:
+// "t=<unix>,kid=<webhook id>,alg=hmac-sha256,sig=<base64>" over
+//
+//	v1\n<t>\n<body>
+//
+// with the secret returned when the webhook was created. The event ID is
+// the same on every attempt and replay, so receivers can deduplicate.
+
+// Delivery states.
+const (
+	deliveryPending   = "pending"
+	deliveryDelivered = "delivered"
+	deliveryFailed    = "failed"    // gave up after CSA_WEBHOOK_MAX_ATTEMPTS
+	deliveryCancelled = "cancelled" // the webhook was deleted first
+)
+
+const (
+	outboxBatch        = 20               // deliveries claimed per round
+	outboxPollInterval = 5 * time.Second  // between rounds when idle
+	outboxLease        = 2 * time.Minute  // before a claimed delivery is retried by anyone
+	webhookMaxBackoff  = time.Hour        // between attempts
+	webhookFirstRetry  = 30 * time.Second // doubles each attempt
+	maxWebhookURL      = 2048
+	maxDeliveryList    = 1000
+)
+
+// webhookEventTypes are the events webhooks can subscribe to.
+var webhookEventTypes = []string{events.ProductIndexed, events.RecommendationServed, events.FeedbackReceived}
+
+type Webhook struct {
+	ID         string     `json:"id"`
+	TenantID   string     `json:"tenant_id"`
+	URL        string     `json:"url"`
+	EventTypes []string   `json:"event_types"` // empty = every type
+	CreatedAt  time.Time  `json:"created_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+type CreateWebhookReq struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types,omitempty"`
+}
+
+type CreateWebhookResp struct {
+	Webhook
+	Secret string `json:"secret"` // base64 HMAC key, only ever returned once
+}
+
+// Delivery is one event on its way to one webhook.
+type Delivery struct {
+	ID            int64           `json:"id"`
+	WebhookID     string          `json:"webhook_id"`
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Status        string          `json:"status"` // pending | delivered | failed | cancelled
+	Attempts      int             `json:"attempts"`
+	LastStatus    *int            `json:"last_status,omitempty"` // HTTP status of the last attempt
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"` // pending only
+	FinishedAt    *time.Time      `json:"finished_at,omitempty"`
+	Event         json.RawMessage `json:"event"`
+}
+
+// ReplayDeliveriesReq selects deliveries to send again: by ID, or every
+// one created in [since, until) that matches the other fields.
+type ReplayDeliveriesReq struct {
+	IDs       []int64    `json:"ids,omitempty"`
+	WebhookID string     `json:"webhook_id,omitempty"`
+	EventType string     `json:"event_type,omitempty"`
+	Status    []string   `json:"status,omitempty"` // default failed and cancelled
+	Since     *time.Time `json:"since,omitempty"`
+	Until     *time.Time `json:"until,omitempty"`
+}
+
+func (req CreateWebhookReq) validate() error {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(req.URL) > maxWebhookURL {
+		return httpapi.InvalidField("url", "url must be an absolute http(s) URL of at most %d characters", maxWebhookURL)
+	}
+	for _, t := range req.EventTypes {
+		if !slices.Contains(webhookEventTypes, t) {
+			return httpapi.InvalidField("event_types", "unknown event type %q; use %v", t, webhookEventTypes)
+		}
+	}
+	return nil
+}
+
+func (req ReplayDeliveriesReq) validate() error {
+	if len(req.IDs) == 0 && req.Since == nil {
+		return httpapi.InvalidField("since", "send ids, or since to replay a time range")
+	}
+	if len(req.IDs) > maxDeliveryList {
+		return httpapi.InvalidField("ids", "at most %d ids", maxDeliveryList)
+	}
+	for _, s := range req.Status {
+		if s != deliveryDelivered && s != deliveryFailed && s != deliveryCancelled {
+			return httpapi.InvalidField("status", "status must be delivered, failed or cancelled")
+		}
+	}
+	if req.Since != nil && req.Until != nil && !req.Until.After(*req.Since) {
+		return httpapi.InvalidField("until", "until must be after since")
+	}
+	return nil
+}
+
+func createWebhook(ctx context.Context, pool *pgxpool.Pool, tenantID string, req CreateWebhookReq) (CreateWebhookResp, error) {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	types := req.EventTypes
+	if types == nil {
+		types = []string{}
+	}
+	resp := CreateWebhookResp{
+		Webhook: Webhook{TenantID: tenantID, URL: req.URL, EventTypes: types},
+		Secret:  base64.StdEncoding.EncodeToString(secret),
+	}
+	err := pool.QueryRow(ctx, `
+INSERT INTO webhooks (tenant_id, url, secret, event_types)
+VALUES ($1,$2,$3,$4)
+RETURNING id::text, created_at
+`, tenantID, req.URL, secret, types).Scan(&resp.ID, &resp.CreatedAt)
+	return resp, err
+}
+
+// listWebhooks returns a tenant's webhooks without secrets, newest first.
+func listWebhooks(ctx context.Context, pool *pgxpool.Pool, tenantID string) ([]Webhook, error) {
+	rows, err := pool.Query(ctx, `
+SELECT id::text, tenant_id, url, event_types, created_at, revoked_at
+FROM webhooks WHERE tenant_id=$1
+ORDER BY created_at DESC
+`, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Webhook{}
+	for rows.Next() {
+		var h Webhook
+		if err := rows.Scan(&h.ID, &h.TenantID, &h.URL, &h.EventTypes, &h.CreatedAt, &h.RevokedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// revokeWebhook stops a webhook and cancels its pending deliveries.
+func revokeWebhook(ctx context.Context, pool *pgxpool.Pool, tenantID, id string) (bool, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, `
+UPDATE webhooks SET revoked_at=now()
+WHERE id::text=$1 AND tenant_id=$2 AND revoked_at IS NULL
+`, id, tenantID)
+	if err != nil || tag.RowsAffected() == 0 {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, `
+UPDATE webhook_outbox SET status=$2, finished_at=now()
+WHERE webhook_id::text=$1 AND status=$3
+`, id, deliveryCancelled, deliveryPending); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// deliveryFilter reads GET /admin/webhooks/deliveries' query.
+type deliveryFilter struct {
+	WebhookID, EventType, Status string
+	Since, Until                 *time.Time
+	Limit                        int
+}
+
+func deliveryFilterFromQuery(q url.Values) (deliveryFilter, error) {
+	f := deliveryFilter{WebhookID: q.Get("webhook_id"), EventType: q.Get("event_type"), Status: q.Get("status"), Limit: 100}
+	switch f.Status {
+	case "", deliveryPending, deliveryDelivered, deliveryFailed, deliveryCancelled:
+	default:
+		return f, httpapi.InvalidField("status", "status must be pending, delivered, failed or cancelled")
+	}
+	for _, t := range []struct {
+		name string
+		dst  **time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if v := q.Get(t.name); v != "" {
+			ts, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, httpapi.InvalidField(t.name, "%s must be an RFC 3339 time", t.name)
+			}
+			*t.dst = &ts
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDeliveryList {
+			return f, httpapi.InvalidField("limit", "limit must be between 1 and %d", maxDeliveryList)
+		}
+		f.Limit = n
+	}
+	return f, nil
+}
+
+// listDeliveries returns a tenant's deliveries matching f, newest first.
+func listDeliveries(ctx context.Context, pool *pgxpool.Pool, tenantID string, f deliveryFilter) ([]Delivery, error) {
+	rows, err := pool.Query(ctx, `
+SELECT id, webhook_id::text, event_id, event_type, status, attempts, last_status, COALESCE(last_error, ''),
+       created_at, CASE WHEN status = 'pending' THEN next_attempt_at END, finished_at, payload
+FROM webhook_outbox
+WHERE tenant_id=$1
+  AND ($2 = '' OR webhook_id::text = $2)
+  AND ($3 = '' OR event_type = $3)
+  AND ($4 = '' OR status = $4)
+  AND ($5::timestamptz IS NULL OR created_at >= $5)
+  AND ($6::timestamptz IS NULL OR created_at < $6)
+ORDER BY created_at DESC, id DESC
+LIMIT $7
+`, tenantID, f.WebhookID, f.EventType, f.Status, f.Since, f.Until, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.LastStatus, &d.LastError,
+			&d.CreatedAt, &d.NextAttemptAt, &d.FinishedAt, &d.Event); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// replayDeliveries makes the selected deliveries of live webhooks pending
+// again, with their attempts reset, and reports how many.
+func replayDeliveries(ctx context.Context, pool *pgxpool.Pool, tenantID string, req ReplayDeliveriesReq) (int64, error) {
+	status := req.Status
+	if len(status) == 0 {
+		status = []string{deliveryFailed, deliveryCancelled}
+	}
+	ids := req.IDs
+	if ids == nil {
+		ids = []int64{}
+	}
+	tag, err := pool.Exec(ctx, `
+UPDATE webhook_outbox o
+SET status='pending', attempts=0, next_attempt_at=now(), last_status=NULL, last_error=NULL, finished_at=NULL
+FROM webhooks w
+WHERE w.id = o.webhook_id AND w.revoked_at IS NULL
+  AND o.tenant_id=$1 AND o.status <> 'pending'
+  AND (cardinality($2::bigint[]) > 0 AND o.id = ANY($2)
+       OR cardinality($2::bigint[]) = 0 AND o.status = ANY($3)
+          AND o.created_at >= $4 AND ($5::timestamptz IS NULL OR o.created_at < $5))
+  AND ($6 = '' OR o.webhook_id::text = $6)
+  AND ($7 = '' OR o.event_type = $7)
+`, tenantID, ids, status, req.Since, req.Until, req.WebhookID, req.EventType)
+	if err != nil {
+		return 0, err
+	}
+	if tag.RowsAffected() > 0 {
+		wakeOutbox()
+	}
+	return tag.RowsAffected(), nil
+}
+
+// outboxWake nudges this replica's worker when deliveries are queued.
+var outboxWake = make(chan struct{}, 1)
+
+func wakeOutbox() {
+	select {
+	case outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// subscribeWebhooks queues every domain event for the tenant's matching
+// webhooks. Events still on the in-process bus when the process dies are
+// lost; once in the outbox they are not.
+func subscribeWebhooks(b *events.Bus, pool *pgxpool.Pool) {
+	for _, typ := range webhookEventTypes {
+		b.Subscribe(typ, func(ctx context.Context, e events.Event) {
+			if err := enqueueWebhooks(ctx, pool, e); err != nil {
+				slog.ErrorContext(ctx, "webhooks: enqueue failed", "type", e.Type, "id", e.ID, "err", err)
+			}
+		})
+	}
+}
+
+func enqueueWebhooks(ctx context.Context, pool *pgxpool.Pool, e events.Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tag, err := pool.Exec(ctx, `
+INSERT INTO webhook_outbox (webhook_id, tenant_id, event_id, event_type, payload)
+SELECT id, tenant_id, $3::text, $2::text, $4::jsonb FROM webhooks
+WHERE tenant_id=$1 AND revoked_at IS NULL AND (cardinality(event_types) = 0 OR $2 = ANY(event_types))
+ON CONFLICT (webhook_id, event_id) DO NOTHING
+`, e.Tenant, e.Type, e.ID, payload)
+	if err == nil && tag.RowsAffected() > 0 {
+		wakeOutbox()
+	}
+	return err
+}
+
+// claimedDelivery is a due delivery this replica holds for outboxLease.
+type claimedDelivery struct {
+	id        int64
+	webhookID string
+	eventID   string
+	eventType string
+	url       string
+	secret    []byte
+	payload   []byte
+	attempts  int // including this one
+}
+
+// outboxLoop delivers due webhook deliveries until ctx is done. Replicas
+// share the work: each claims different rows.
+func outboxLoop(ctx context.Context, pool *pgxpool.Pool) {
+	t := time.NewTicker(outboxPollInterval)
+	defer t.Stop()
+	for {
+		n, err := deliverDue(ctx, pool)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "webhooks: delivery round failed", "err", err)
+		}
+		if n == outboxBatch {
+			continue // more may be due
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		case <-outboxWake:
+		}
+	}
+}
+
+// deliverDue claims up to outboxBatch due deliveries and attempts each.
+func deliverDue(ctx context.Context, pool *pgxpool.Pool) (int, error) {
+	rows, err := pool.Query(ctx, `
+UPDATE webhook_outbox o
+SET next_attempt_at = now() + make_interval(secs => $2), attempts = o.attempts + 1
+FROM webhooks w
+WHERE w.id = o.webhook_id AND o.id IN (
+  SELECT id FROM webhook_outbox
+  WHERE status='pending' AND next_attempt_at <= now()
+  ORDER BY next_attempt_at
+  LIMIT $1
+  FOR UPDATE SKIP LOCKED)
+RETURNING o.id, o.webhook_id::text, o.event_id, o.event_type, w.url, w.secret, o.payload::text, o.attempts
+`, outboxBatch, outboxLease.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	claimed, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (claimedDelivery, error) {
+		var d claimedDelivery
+		var payload string
+		err := row.Scan(&d.id, &d.webhookID, &d.eventID, &d.eventType, &d.url, &d.secret, &payload, &d.attempts)
+		d.payload = []byte(payload)
+		return d, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range claimed {
+		if ctx.Err() != nil {
+			break // the lease runs out and another attempt picks it up
+		}
+		code, err := postWebhook(ctx, d)
+		if err := recordAttempt(ctx, pool, d, code, err); err != nil {
+			slog.ErrorContext(ctx, "webhooks: recording attempt failed", "delivery", d.id, "err", err)
+		}
+	}
+	return len(claimed), nil
+}
+
+// postWebhook sends one delivery. err is set unless the receiver answered
+// 2xx; code is its status, 0 when there was none.
+func postWebhook(ctx context.Context, d claimedDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, cfg().WebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(d.payload))
+	if err != nil {
+		return 0, err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, d.secret)
+	fmt.Fprintf(mac, "v1\n%s\n", ts)
+	mac.Write(d.payload)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "csa-webhooks/1")
+	req.Header.Set("X-CSA-Event", d.eventType)
+	req.Header.Set("X-CSA-Event-ID", d.eventID)
+	req.Header.Set("X-CSA-Delivery", strconv.FormatInt(d.id, 10))
+	req.Header.Set(signatureHeader, fmt.Sprintf("t=%s,kid=%s,alg=%s,sig=%s",
+		ts, d.webhookID, signHMAC, base64.StdEncoding.EncodeToString(mac.Sum(nil))))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16)) // lets the connection be reused
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// recordAttempt stores an attempt's outcome: delivered, due again after a
+// backoff, or failed for good.
+func recordAttempt(ctx context.Context, pool *pgxpool.Pool, d claimedDelivery, code int, deliverErr error) error {
+	var lastStatus *int
+	if code != 0 {
+		lastStatus = &code
+	}
+	if deliverErr == nil {
+		_, err := pool.Exec(ctx, `
+UPDATE webhook_outbox SET status=$2, last_status=$3, last_error=NULL, finished_at=now() WHERE id=$1
+`, d.id, deliveryDelivered, lastStatus)
+		return err
+	}
+	status := deliveryPending
+	if d.attempts >= cfg().WebhookMaxAttempts {
+		status = deliveryFailed
+		slog.WarnContext(ctx, "webhooks: delivery failed", "delivery", d.id, "webhook", d.webhookID, "attempts", d.attempts, "err", deliverErr)
+	}
+	_, err := pool.Exec(ctx, `
+UPDATE webhook_outbox
+SET status=$2, last_status=$3, last_error=$4, next_attempt_at=now() + make_interval(secs => $5),
+    finished_at=CASE WHEN $2 = 'failed' THEN now() END
+WHERE id=$1
+`, d.id, status, lastStatus, deliverErr.Error(), webhookBackoff(d.attempts).Seconds())
+	return err
+}
+
+// webhookBackoff is the wait after a delivery's nth failed attempt.
+func webhookBackoff(attempts int) time.Duration {
+	if attempts > 10 {
+		return webhookMaxBackoff
+	}
+	return min(webhookFirstRetry<<(attempts-1), webhookMaxBackoff)
+}
+
+// outboxSweepInterval is how often finished deliveries past
+// CSA_OUTBOX_RETENTION are deleted.
+const outboxSweepInterval = time.Hour
+
+func outboxSweepLoop(ctx context.Context, pool *pgxpool.Pool) {
+	t := time.NewTicker(outboxSweepInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		slot := time.Now().Truncate(outboxSweepInterval)
+		if _, err := runScheduled(ctx, pool, "webhook_outbox_sweep", slot, func(ctx context.Context) error {
+			_, err := pool.Exec(ctx, `
+DELETE FROM webhook_outbox WHERE status <> 'pending' AND finished_at < now() - make_interval(secs => $1)
+`, cfg().OutboxRetention.Seconds())
+			return err
+		}); err != nil {
+			slog.ErrorContext(ctx, "webhooks: sweep", "err", err)
+		}
+	}
+}